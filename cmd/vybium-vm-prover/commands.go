@@ -0,0 +1,571 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-starks-vm/pkg/vybium-starks-vm"
+)
+
+// VerifyOutput is the JSON verdict printed by verify
+type VerifyOutput struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// RunOutput is the JSON result printed by run
+//
+// Output is the public output a claim about the execution has to state,
+// starting with the program digest (TIP-0006).
+type RunOutput struct {
+	Output     []uint64 `json:"output"`
+	CycleCount int      `json:"cycle_count"`
+}
+
+// ProfileOutput is the profile printed by profile -json
+type ProfileOutput struct {
+	Functions    []FunctionProfileOutput `json:"functions"`
+	TableHeights map[string]int          `json:"table_heights"` // Before padding
+	PaddedHeight int                     `json:"padded_height"`
+	TallestTable string                  `json:"tallest_table"` // Decides the padded height
+}
+
+// FunctionProfileOutput is the cost of one function in a ProfileOutput
+type FunctionProfileOutput struct {
+	Name    string            `json:"name"`
+	Address int               `json:"address"`
+	Calls   int               `json:"calls"`
+	Self    ProfileCostOutput `json:"self"`  // Its own instructions
+	Total   ProfileCostOutput `json:"total"` // Including the functions it calls
+}
+
+// ProfileCostOutput counts the rows of the tables that grow with execution
+type ProfileCostOutput struct {
+	Cycles      int `json:"cycles"`
+	OpStackRows int `json:"op_stack_rows"`
+	RAMRows     int `json:"ram_rows"`
+	HashRows    int `json:"hash_rows"`
+	U32Rows     int `json:"u32_rows"`
+}
+
+// DebugOutput is the machine state printed by debug at every stop
+type DebugOutput struct {
+	IP          int               `json:"ip"`
+	Cycle       int               `json:"cycle"`
+	Instruction string            `json:"instruction,omitempty"` // The next instruction
+	OpStack     []uint64          `json:"op_stack"`              // st0 first
+	JumpStack   [][2]int          `json:"jump_stack"`            // (origin, destination), innermost last
+	RAM         map[uint64]uint64 `json:"ram"`
+	Sponge      []uint64          `json:"sponge,omitempty"`
+	Halted      bool              `json:"halted"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// proveCommand reads the prover input from stdin and writes the proof
+func proveCommand(args []string) int {
+	flags := newFlagSet("prove", "[-workers N] [-max-memory BYTES] [-progress]",
+		"Reads five JSON lines from stdin and writes the binary proof to stdout.")
+	workers := flags.Int("workers", 0, "goroutines every phase of the proof runs at most at once, 0 for one per CPU")
+	maxMemory := flags.Uint64("max-memory", 0, "fail before proving if the proof needs more bytes of memory, 0 for no limit")
+	progress := flags.Bool("progress", false, "log every completed step of every phase to stderr")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	config := proofConfig()
+	config.ProverOptions = vybiumstarksvm.ProverOptions{NumWorkers: *workers, MaxMemoryBytes: *maxMemory}
+	if *progress {
+		config.ProverOptions.Progress = func(progress vybiumstarksvm.ProverProgress) {
+			if progress.Done == progress.Total {
+				logStderr(fmt.Sprintf("%s: %d of %d done", progress.Phase, progress.Done, progress.Total))
+			}
+		}
+	}
+
+	input, err := readProverInput(os.Stdin)
+	if err != nil {
+		return fail(err)
+	}
+	trace, err := execute(input)
+	if err != nil {
+		return fail(err)
+	}
+
+	if input.maxPaddedHeight != nil {
+		logStderr(fmt.Sprintf("Max log2 padded height: %d", *input.maxPaddedHeight))
+	}
+
+	logStderr("Creating prover...")
+	prover, err := vybiumstarksvm.NewProver(config)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create prover: %w", err))
+	}
+
+	logStderr("Generating proof...")
+	proof, err := prover.GenerateProof(trace)
+	if err != nil {
+		return fail(fmt.Errorf("proof generation failed: %w", err))
+	}
+
+	logStderr("Proof generated successfully")
+
+	// Serialize proof in the versioned binary format
+	proofBytes, err := proof.MarshalBinary()
+	if err != nil {
+		return fail(fmt.Errorf("failed to serialize proof: %w", err))
+	}
+
+	// Write proof to stdout (like Triton VM)
+	if _, err := os.Stdout.Write(proofBytes); err != nil {
+		return fail(fmt.Errorf("failed to write proof: %w", err))
+	}
+	return exitSuccess
+}
+
+// verifyCommand verifies a proof file against a claim file
+//
+// The verdict is printed as JSON; the exit code is exitSuccess only if the
+// proof is valid. A proof file that cannot be decoded is an invalid proof.
+func verifyCommand(args []string) int {
+	flags := newFlagSet("verify", "-claim FILE -proof FILE",
+		"Verifies a binary proof against a JSON claim and prints a JSON verdict.")
+	claimPath := flags.String("claim", "", "JSON claim with program_digest, version, input and output")
+	proofPath := flags.String("proof", "", "binary proof written by prove")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if *claimPath == "" || *proofPath == "" {
+		logStderr("verify needs both -claim and -proof")
+		flags.Usage()
+		return exitUsage
+	}
+
+	claim, err := readClaim(*claimPath)
+	if err != nil {
+		return fail(err)
+	}
+	proofBytes, err := os.ReadFile(*proofPath)
+	if err != nil {
+		return fail(fmt.Errorf("failed to read proof: %w", err))
+	}
+
+	verdict := VerifyOutput{}
+	proof := &vybiumstarksvm.Proof{}
+	if err := proof.UnmarshalBinary(proofBytes); err != nil {
+		verdict.Error = fmt.Sprintf("malformed proof: %v", err)
+	} else {
+		verifier, err := vybiumstarksvm.NewVerifier(proofConfig())
+		if err != nil {
+			return fail(fmt.Errorf("failed to create verifier: %w", err))
+		}
+		result, err := verifier.VerifyProof(proof, claim)
+		if err != nil {
+			return fail(fmt.Errorf("verification failed: %w", err))
+		}
+		verdict.Valid = result.Valid
+		verdict.Error = result.Error
+	}
+
+	if err := writeJSON(verdict); err != nil {
+		return fail(err)
+	}
+	if !verdict.Valid {
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// runCommand executes a program without proving it
+func runCommand(args []string) int {
+	flags := newFlagSet("run", "",
+		"Reads the same five JSON lines as prove from stdin, executes the program\n"+
+			"and prints its public output and cycle count as JSON.")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	input, err := readProverInput(os.Stdin)
+	if err != nil {
+		return fail(err)
+	}
+	trace, err := execute(input)
+	if err != nil {
+		return fail(err)
+	}
+
+	output := RunOutput{
+		Output:     make([]uint64, len(trace.PublicOutput)),
+		CycleCount: trace.CycleCount,
+	}
+	for i, elem := range trace.PublicOutput {
+		output.Output[i] = elem.Big().Uint64()
+	}
+	if err := writeJSON(output); err != nil {
+		return fail(err)
+	}
+	return exitSuccess
+}
+
+// digestCommand prints the digest of a program in the claim's hex format
+func digestCommand(args []string) int {
+	flags := newFlagSet("digest", "[-program FILE]",
+		"Prints the hex digest of a program, as expected in a claim's program_digest.")
+	programPath := flags.String("program", "-", "JSON program in the prover's input format, - for stdin")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	data, err := readFileOrStdin(*programPath)
+	if err != nil {
+		return fail(fmt.Errorf("failed to read program: %w", err))
+	}
+	var programInput ProgramInput
+	if err := json.Unmarshal(data, &programInput); err != nil {
+		return fail(fmt.Errorf("failed to parse program: %w", err))
+	}
+	program, err := convertProgram(programInput)
+	if err != nil {
+		return fail(fmt.Errorf("failed to convert program: %w", err))
+	}
+
+	digest, err := vybiumstarksvm.ProgramDigest(program)
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Println(formatDigest(digest))
+	return exitSuccess
+}
+
+// debugCommand executes a program and prints the machine state at every
+// breakpoint and where execution ended
+func debugCommand(args []string) int {
+	flags := newFlagSet("debug", "[-break ADDRESS|LABEL]...",
+		"Reads the same five JSON lines as prove from stdin, executes the program and\n"+
+			"prints the machine state as a JSON line at every breakpoint, set here or in\n"+
+			"the program's debug_information, and where execution ended.")
+	var breaks []string
+	flags.Func("break", "stop before the instruction at an address or label (repeatable)", func(s string) error {
+		breaks = append(breaks, s)
+		return nil
+	})
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	input, err := readProverInput(os.Stdin)
+	if err != nil {
+		return fail(err)
+	}
+	exec, err := convertExecution(input)
+	if err != nil {
+		return fail(err)
+	}
+	debugger, err := vybiumstarksvm.NewDebugger(vybiumstarksvm.DefaultVMConfig(),
+		exec.program, exec.publicInput, exec.nonDeterminism, exec.options)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load program: %w", err))
+	}
+	for _, b := range breaks {
+		if address, err := strconv.ParseUint(b, 10, 64); err == nil {
+			err = debugger.SetBreakpoint(address)
+		} else {
+			err = debugger.SetBreakpointAtLabel(b)
+		}
+		if err != nil {
+			return fail(fmt.Errorf("invalid breakpoint %s: %w", b, err))
+		}
+	}
+
+	if debugger.AtBreakpoint() {
+		if err := writeJSON(debugOutput(debugger, nil)); err != nil {
+			return fail(err)
+		}
+	}
+	for {
+		runErr := debugger.Continue()
+		if err := writeJSON(debugOutput(debugger, runErr)); err != nil {
+			return fail(err)
+		}
+		if runErr != nil {
+			return exitFailure
+		}
+		if debugger.Halted() {
+			return exitSuccess
+		}
+	}
+}
+
+// profileCommand executes a program and prints how many rows of each table
+// its functions caused
+func profileCommand(args []string) int {
+	flags := newFlagSet("profile", "[-json]",
+		"Reads the same five JSON lines as prove from stdin, executes the program and\n"+
+			"prints the cycles and table rows of every function, and the table that\n"+
+			"decides the padded height.")
+	asJSON := flags.Bool("json", false, "print the profile as JSON instead of a text table")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	input, err := readProverInput(os.Stdin)
+	if err != nil {
+		return fail(err)
+	}
+	exec, err := convertExecution(input)
+	if err != nil {
+		return fail(err)
+	}
+	profile, err := vybiumstarksvm.ProfileExecution(vybiumstarksvm.DefaultVMConfig(),
+		exec.program, exec.publicInput, exec.nonDeterminism, exec.options)
+	if err != nil {
+		return fail(err)
+	}
+
+	if *asJSON {
+		err = writeJSON(profileOutput(profile))
+	} else {
+		err = writeProfileTable(os.Stdout, profile)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return exitSuccess
+}
+
+// profileOutput converts a profile for printing as JSON
+func profileOutput(profile *vybiumstarksvm.Profile) ProfileOutput {
+	cost := func(c vybiumstarksvm.ProfileCost) ProfileCostOutput {
+		return ProfileCostOutput{
+			Cycles:      c.Cycles,
+			OpStackRows: c.OpStackRows,
+			RAMRows:     c.RAMRows,
+			HashRows:    c.HashRows,
+			U32Rows:     c.U32Rows,
+		}
+	}
+	output := ProfileOutput{
+		Functions:    make([]FunctionProfileOutput, len(profile.Functions)),
+		TableHeights: make(map[string]int, len(profile.TableHeights)),
+		PaddedHeight: profile.PaddedHeight,
+		TallestTable: profile.TallestTable.String(),
+	}
+	for i, function := range profile.Functions {
+		output.Functions[i] = FunctionProfileOutput{
+			Name:    function.Name,
+			Address: function.Address,
+			Calls:   function.Calls,
+			Self:    cost(function.Self),
+			Total:   cost(function.Total),
+		}
+	}
+	for table, height := range profile.TableHeights {
+		output.TableHeights[table.String()] = height
+	}
+	return output
+}
+
+// writeProfileTable prints a profile as a text table of the total rows of
+// every function, its own rows in parentheses, followed by the table that
+// decides the padded height and the function that adds the most rows to it
+func writeProfileTable(w io.Writer, profile *vybiumstarksvm.Profile) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "function\taddress\tcalls\tcycles\top stack\tRAM\thash\tu32\t")
+	for _, f := range profile.Functions {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d (%d)\t%d (%d)\t%d (%d)\t%d (%d)\t%d (%d)\t\n",
+			f.Name, f.Address, f.Calls,
+			f.Total.Cycles, f.Self.Cycles,
+			f.Total.OpStackRows, f.Self.OpStackRows,
+			f.Total.RAMRows, f.Self.RAMRows,
+			f.Total.HashRows, f.Self.HashRows,
+			f.Total.U32Rows, f.Self.U32Rows)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	tallest := profile.TallestTable
+	fmt.Fprintf(w, "\npadded height %d, decided by the %s table with %d rows\n",
+		profile.PaddedHeight, tallest, profile.TableHeights[tallest])
+	var driver *vybiumstarksvm.FunctionProfile
+	for i, f := range profile.Functions {
+		if f.Self.Rows(tallest) > 0 && (driver == nil || f.Self.Rows(tallest) > driver.Self.Rows(tallest)) {
+			driver = &profile.Functions[i]
+		}
+	}
+	if driver != nil {
+		_, err := fmt.Fprintf(w, "most of its rows come from %s: %d\n", driver.Name, driver.Self.Rows(tallest))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "its height does not depend on which code runs\n")
+	return err
+}
+
+// debugOutput converts the debugger's state for printing
+func debugOutput(debugger *vybiumstarksvm.Debugger, err error) DebugOutput {
+	state := debugger.State()
+	output := DebugOutput{
+		IP:        state.InstructionPointer,
+		Cycle:     state.CycleCount,
+		OpStack:   make([]uint64, len(state.OpStack)),
+		JumpStack: make([][2]int, len(state.JumpStack)),
+		RAM:       make(map[uint64]uint64, len(state.RAM)),
+		Halted:    state.Halted,
+	}
+	if inst := state.NextInstruction; inst != nil {
+		output.Instruction = inst.Opcode.String()
+		if inst.Argument != nil {
+			output.Instruction += " " + inst.Argument.Big().String()
+		}
+	}
+	for i, elem := range state.OpStack {
+		output.OpStack[i] = elem.Big().Uint64()
+	}
+	for i, frame := range state.JumpStack {
+		output.JumpStack[i] = [2]int{frame.Origin, frame.Destination}
+	}
+	for address, value := range state.RAM {
+		output.RAM[address] = value.Big().Uint64()
+	}
+	for _, elem := range state.Sponge {
+		output.Sponge = append(output.Sponge, elem.Big().Uint64())
+	}
+	if err != nil {
+		output.Error = err.Error()
+	}
+	return output
+}
+
+// readClaim reads a JSON claim and converts it for the verifier
+func readClaim(path string) (*vybiumstarksvm.Claim, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read claim: %w", err)
+	}
+	var input ClaimInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("failed to parse claim: %w", err)
+	}
+
+	digest, err := parseDigest(input.ProgramDigest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid claim program_digest: %w", err)
+	}
+	publicInput, err := canonicalElements(input.Input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid claim input: %w", err)
+	}
+	publicOutput, err := canonicalElements(input.Output)
+	if err != nil {
+		return nil, fmt.Errorf("invalid claim output: %w", err)
+	}
+
+	return &vybiumstarksvm.Claim{
+		ProgramDigest: digest,
+		Version:       input.Version,
+		PublicInput:   publicInput,
+		PublicOutput:  publicOutput,
+	}, nil
+}
+
+// formatDigest encodes a digest as hex, 8 little-endian bytes per element
+func formatDigest(digest []field.Element) string {
+	data := make([]byte, 0, 8*len(digest))
+	for _, elem := range digest {
+		data = binary.LittleEndian.AppendUint64(data, elem.Value())
+	}
+	return hex.EncodeToString(data)
+}
+
+// parseDigest is the inverse of formatDigest
+func parseDigest(s string) ([]field.Element, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 8*digestLen {
+		return nil, fmt.Errorf("expected %d hex characters, got %d", 16*digestLen, len(s))
+	}
+	values := make([]uint64, digestLen)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(data[8*i:])
+	}
+	return canonicalElements(values)
+}
+
+// digestLen is the number of field elements in a program digest (TIP-0006)
+const digestLen = 5
+
+// canonicalElements converts values to field elements, rejecting values
+// that are not reduced modulo the field's prime
+func canonicalElements(values []uint64) ([]field.Element, error) {
+	elems := make([]field.Element, len(values))
+	for i, value := range values {
+		if value >= field.P {
+			return nil, fmt.Errorf("value %d at index %d is not a field element", value, i)
+		}
+		elems[i] = field.New(value)
+	}
+	return elems, nil
+}
+
+// newFlagSet creates the flag set of a subcommand with its usage message
+func newFlagSet(name, synopsis, description string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: vybium-vm-prover %s %s\n\n%s\n", name, synopsis, description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses a subcommand's flags, which take no positional
+// arguments. It returns the exit code to stop with if parsing did not
+// succeed.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitSuccess, false
+		}
+		return exitUsage, false
+	}
+	if flags.NArg() > 0 {
+		logStderr(fmt.Sprintf("unexpected arguments: %v", flags.Args()))
+		flags.Usage()
+		return exitUsage, false
+	}
+	return exitSuccess, true
+}
+
+// readFileOrStdin reads the named file, or stdin for "-"
+func readFileOrStdin(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// writeJSON prints v as a line of JSON to stdout
+func writeJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	if _, err := fmt.Println(string(data)); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// fail logs an error and returns the exit code of a failed command
+func fail(err error) int {
+	logStderr("ERROR: " + err.Error())
+	return exitFailure
+}
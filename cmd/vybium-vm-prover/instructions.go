@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/assembler"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+	"github.com/vybium/vybium-starks-vm/pkg/vybium-starks-vm"
+)
+
+// mnemonics maps the normalized name of every instruction to its opcode,
+// see normalizeMnemonic
+var mnemonics = func() map[string]vm.Instruction {
+	result := make(map[string]vm.Instruction, len(vm.AllInstructions))
+	for opcode, info := range vm.AllInstructions {
+		result[normalizeMnemonic(info.Name)] = opcode
+	}
+	return result
+}()
+
+// normalizeMnemonic makes Triton's variant names ("ReadIo", "AddI") and
+// the ISA's mnemonics ("read_io", "addi") compare equal
+func normalizeMnemonic(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// labelAddresses maps labels to the word addresses they stand for
+//
+// In JSON it is accepted in both orientations: Triton's address_to_label
+// ({"12": "loop"}) and label to address ({"loop": 12}).
+type labelAddresses map[string]uint64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *labelAddresses) UnmarshalJSON(data []byte) error {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	result := make(labelAddresses, len(entries))
+	for key, value := range entries {
+		var label string
+		if err := json.Unmarshal(value, &label); err == nil {
+			address, err := strconv.ParseUint(key, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid address %q of label %q", key, label)
+			}
+			result[label] = address
+			continue
+		}
+		var address uint64
+		if err := json.Unmarshal(value, &address); err != nil {
+			return fmt.Errorf("label %q: expected a label name or an address, got %s", key, value)
+		}
+		result[key] = address
+	}
+
+	*l = result
+	return nil
+}
+
+// parsedInstruction is an instruction whose argument may still be a label
+type parsedInstruction struct {
+	opcode vm.Instruction
+	arg    string // empty if the instruction takes no argument
+}
+
+// convertProgram parses the program's instructions
+//
+// Every entry is one instruction, either in Triton's form ("Push(42)",
+// "Call(loop)", "Dup(ST3)", "Pop(N2)") or as mnemonic and argument
+// ("push 42", "call loop", "push -1"), or a label definition ("loop:").
+// Labels resolve to word addresses, from the definitions in the program
+// and from address_to_label.
+func convertProgram(input ProgramInput) (*vybiumstarksvm.Program, error) {
+	labels := make(labelAddresses, len(input.AddressToLabel))
+	for label, address := range input.AddressToLabel {
+		labels[label] = address
+	}
+
+	// First pass: parse the instructions and assign addresses to labels
+	parsed := make([]parsedInstruction, 0, len(input.Instructions))
+	address := uint64(0)
+	for i, text := range input.Instructions {
+		text = strings.TrimSpace(text)
+		if label, ok := strings.CutSuffix(text, ":"); ok {
+			if !assembler.IsLabel(label) {
+				return nil, fmt.Errorf("instruction %d: invalid label %q", i, label)
+			}
+			if defined, ok := labels[label]; ok && defined != address {
+				return nil, fmt.Errorf("instruction %d: label %q defined at %d and %d", i, label, defined, address)
+			}
+			labels[label] = address
+			continue
+		}
+
+		inst, err := parseInstruction(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse instruction %d (%s): %w", i, text, err)
+		}
+		parsed = append(parsed, inst)
+		address += uint64(inst.opcode.Size())
+	}
+
+	// Second pass: resolve the arguments
+	instructions := make([]vybiumstarksvm.Instruction, len(parsed))
+	for i, inst := range parsed {
+		instructions[i].Opcode = inst.opcode
+		if inst.arg == "" {
+			continue
+		}
+		value, err := resolveArgument(inst, labels)
+		if err != nil {
+			return nil, fmt.Errorf("instruction %s: %w", inst.opcode, err)
+		}
+		instructions[i].Argument = convertFieldElement(value)
+	}
+
+	program := &vybiumstarksvm.Program{
+		Instructions: instructions,
+		Labels:       labels,
+	}
+	if input.DebugInfo != nil {
+		program.Breakpoints = input.DebugInfo.Breakpoints
+	}
+	return program, nil
+}
+
+// parseInstruction parses one instruction without resolving its argument
+func parseInstruction(text string) (parsedInstruction, error) {
+	var name, arg string
+	if open := strings.IndexByte(text, '('); open >= 0 {
+		if !strings.HasSuffix(text, ")") {
+			return parsedInstruction{}, fmt.Errorf("missing closing parenthesis")
+		}
+		name = strings.TrimSpace(text[:open])
+		arg = strings.TrimSpace(text[open+1 : len(text)-1])
+	} else {
+		fields := strings.Fields(text)
+		switch len(fields) {
+		case 0:
+			return parsedInstruction{}, fmt.Errorf("empty instruction")
+		case 1:
+			name = fields[0]
+		case 2:
+			name, arg = fields[0], fields[1]
+		default:
+			return parsedInstruction{}, fmt.Errorf("too many arguments")
+		}
+	}
+
+	opcode, ok := mnemonics[normalizeMnemonic(name)]
+	if !ok {
+		return parsedInstruction{}, fmt.Errorf("unknown instruction: %s", name)
+	}
+	switch {
+	case opcode.HasArgument() && arg == "":
+		return parsedInstruction{}, fmt.Errorf("instruction %s requires an argument", opcode)
+	case !opcode.HasArgument() && arg != "":
+		return parsedInstruction{}, fmt.Errorf("instruction %s does not take an argument", opcode)
+	}
+
+	return parsedInstruction{opcode: opcode, arg: arg}, nil
+}
+
+// resolveArgument returns the value of an instruction's argument
+//
+// Arguments are decimal or 0x-prefixed integers, negated modulo the field's
+// prime if they start with '-'. Triton writes stack positions and word
+// counts as ST3 and N2. Call also takes a label.
+func resolveArgument(inst parsedInstruction, labels labelAddresses) (uint64, error) {
+	arg := inst.arg
+	if inst.opcode == vm.Call && assembler.IsLabel(arg) {
+		address, ok := labels[arg]
+		if !ok {
+			return 0, fmt.Errorf("undefined label %q", arg)
+		}
+		return address, nil
+	}
+	if trimmed, ok := strings.CutPrefix(arg, "ST"); ok {
+		arg = trimmed
+	} else if trimmed, ok := strings.CutPrefix(arg, "N"); ok {
+		arg = trimmed
+	}
+
+	value, err := assembler.ParseArgument(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid argument: %s", inst.arg)
+	}
+	return value.Value(), nil
+}
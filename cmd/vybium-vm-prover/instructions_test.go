@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+// tritonName returns Triton's variant name of an instruction, e.g. ReadIo
+func tritonName(name string) string {
+	parts := strings.Split(name, "_")
+	for i, part := range parts {
+		parts[i] = strings.ToUpper(part[:1]) + part[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func TestParseInstructionCoversISA(t *testing.T) {
+	if len(mnemonics) != len(vm.AllInstructions) {
+		t.Fatalf("%d mnemonics for %d instructions", len(mnemonics), len(vm.AllInstructions))
+	}
+
+	for opcode, info := range vm.AllInstructions {
+		forms := []string{info.Name, tritonName(info.Name)}
+		if info.HasArg {
+			forms = []string{info.Name + " 1", tritonName(info.Name) + "(1)"}
+		}
+		for _, text := range forms {
+			inst, err := parseInstruction(text)
+			if err != nil {
+				t.Errorf("%s: %v", text, err)
+				continue
+			}
+			if inst.opcode != opcode {
+				t.Errorf("%s: opcode %d, want %d", text, inst.opcode, opcode)
+			}
+		}
+	}
+}
+
+func TestConvertProgram(t *testing.T) {
+	var input ProgramInput
+	err := json.Unmarshal([]byte(`{
+		"instructions": ["Push(-1)", "call double", "Call(end)", "halt",
+			"double:", "Dup(ST0)", "add", "return", "pop N1"],
+		"address_to_label": {"9": "end"},
+		"debug_information": {"breakpoints": [false, true, false, false, false, false, false, false]}
+	}`), &input)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+
+	program, err := convertProgram(input)
+	if err != nil {
+		t.Fatalf("convertProgram failed: %v", err)
+	}
+	want := []struct {
+		opcode vm.Instruction
+		arg    uint64
+	}{
+		{vm.Push, field.P - 1},
+		{vm.Call, 7}, // push and both calls take two words, halt one
+		{vm.Call, 9},
+		{vm.Halt, 0},
+		{vm.Dup, 0},
+		{vm.Add, 0},
+		{vm.Return, 0},
+		{vm.Pop, 1},
+	}
+	if len(program.Instructions) != len(want) {
+		t.Fatalf("got %d instructions, want %d", len(program.Instructions), len(want))
+	}
+	for i, w := range want {
+		inst := program.Instructions[i]
+		if inst.Opcode != w.opcode {
+			t.Errorf("instruction %d: opcode %d, want %d", i, inst.Opcode, w.opcode)
+		}
+		if w.opcode.HasArgument() && inst.Argument.Big().Uint64() != w.arg {
+			t.Errorf("instruction %d: argument %s, want %d", i, inst.Argument.Big(), w.arg)
+		}
+	}
+
+	if program.Labels["double"] != 7 || program.Labels["end"] != 9 {
+		t.Errorf("labels = %v, want double at 7 and end at 9", program.Labels)
+	}
+	if len(program.Breakpoints) != 8 || !program.Breakpoints[1] {
+		t.Errorf("breakpoints = %v, want the debug information's", program.Breakpoints)
+	}
+
+	for _, instructions := range [][]string{
+		{"frobnicate"},
+		{"push"},
+		{"halt 1"},
+		{"push 18446744069414584321"},
+		{"call nowhere"},
+		{"Push(1"},
+		{"twice:", "halt", "twice:"},
+	} {
+		if _, err := convertProgram(ProgramInput{Instructions: instructions}); err == nil {
+			t.Errorf("%q: expected an error", instructions)
+		}
+	}
+}
@@ -3,11 +3,14 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"os"
-	"strings"
+	"strconv"
 
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 	"github.com/vybium/vybium-starks-vm/pkg/vybium-starks-vm"
 )
@@ -21,211 +24,256 @@ type ClaimInput struct {
 }
 
 type ProgramInput struct {
-	Instructions   []string               `json:"instructions"` // String format like "Halt", "Push(42)"
-	AddressToLabel map[string]uint64      `json:"address_to_label,omitempty"`
-	DebugInfo      map[string]interface{} `json:"debug_information,omitempty"`
+	Instructions   []string          `json:"instructions"` // String format like "Halt", "Push(42)" or "push 42"
+	AddressToLabel labelAddresses    `json:"address_to_label,omitempty"`
+	DebugInfo      *DebugInformation `json:"debug_information,omitempty"`
+}
+
+// DebugInformation is Triton's debug information of a program; only the
+// breakpoints are used, by debug
+type DebugInformation struct {
+	Breakpoints []bool `json:"breakpoints"` // One entry per word, or per instruction
 }
 
 type NonDeterminismInput struct {
 	IndividualTokens []uint64          `json:"individual_tokens"`
-	Digests          []string          `json:"digests"`
-	Ram              map[string]uint64 `json:"ram"`
+	Digests          []string          `json:"digests"` // Hex strings in the program_digest format
+	Ram              map[string]uint64 `json:"ram"`     // Decimal address -> value
 }
 
 func main() {
-	// Read JSON lines from stdin (like Triton VM prover)
-	scanner := bufio.NewScanner(os.Stdin)
+	os.Exit(run(os.Args[1:]))
+}
 
-	// Line 1: Claim
-	if !scanner.Scan() {
-		fatal("Failed to read claim")
-	}
-	var claimInput ClaimInput
-	if err := json.Unmarshal(scanner.Bytes(), &claimInput); err != nil {
-		fatal(fmt.Sprintf("Failed to parse claim: %v", err))
+// run dispatches to the subcommand named by the first argument and returns
+// the process exit code
+//
+// Without a subcommand the prover behaves like `prove`, so existing
+// pipelines that pipe the five input lines into the binary keep working.
+func run(args []string) int {
+	if len(args) == 0 {
+		return proveCommand(args)
 	}
 
-	// Line 2: Program
-	if !scanner.Scan() {
-		fatal("Failed to read program")
-	}
-	var programInput ProgramInput
-	if err := json.Unmarshal(scanner.Bytes(), &programInput); err != nil {
-		fatal(fmt.Sprintf("Failed to parse program: %v", err))
+	switch args[0] {
+	case "prove":
+		return proveCommand(args[1:])
+	case "verify":
+		return verifyCommand(args[1:])
+	case "run":
+		return runCommand(args[1:])
+	case "digest":
+		return digestCommand(args[1:])
+	case "debug":
+		return debugCommand(args[1:])
+	case "profile":
+		return profileCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return exitSuccess
+	default:
+		logStderr(fmt.Sprintf("unknown subcommand %q", args[0]))
+		printUsage(os.Stderr)
+		return exitUsage
 	}
+}
 
-	// Line 3: NonDeterminism
-	if !scanner.Scan() {
-		fatal("Failed to read non_determinism")
-	}
-	var nonDetInput NonDeterminismInput
-	if err := json.Unmarshal(scanner.Bytes(), &nonDetInput); err != nil {
-		fatal(fmt.Sprintf("Failed to parse non_determinism: %v", err))
-	}
+// Exit codes of all subcommands
+const (
+	exitSuccess = 0
+	exitFailure = 1 // the command failed, or the proof was rejected
+	exitUsage   = 2 // the command line was malformed
+)
 
-	// Line 4: Max padded height (optional)
-	if !scanner.Scan() {
-		fatal("Failed to read max_log2_padded_height")
-	}
-	var maxPaddedHeight *uint8
-	if err := json.Unmarshal(scanner.Bytes(), &maxPaddedHeight); err != nil {
-		fatal(fmt.Sprintf("Failed to parse max_log2_padded_height: %v", err))
-	}
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, `Usage: vybium-vm-prover <command> [flags]
+
+Commands:
+  prove    read claim, program, non-determinism, max log2 padded height and
+           environment variables as five JSON lines from stdin and write the
+           binary proof to stdout (the default without a command)
+  verify   verify a proof against a claim and print a JSON verdict
+  run      execute a program without proving and print its output and cycle count
+  digest   print the digest of a program
+  debug    execute a program and print the machine state at every breakpoint
+  profile  execute a program and print the cycles and table rows of every function
+
+Run 'vybium-vm-prover <command> -h' for the flags of a command.
+`)
+}
 
-	// Line 5: Environment variables
-	if !scanner.Scan() {
-		fatal("Failed to read env_variables")
+// proverInput holds the five JSON lines read from stdin by prove and run
+type proverInput struct {
+	claim           ClaimInput
+	program         ProgramInput
+	nonDeterminism  NonDeterminismInput
+	maxPaddedHeight *uint8
+	envVars         map[string]interface{}
+}
+
+// readProverInput reads the prover's input lines (like Triton VM prover)
+func readProverInput(r io.Reader) (*proverInput, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxInputLineLen)
+
+	input := &proverInput{}
+	lines := []struct {
+		name   string
+		target interface{}
+	}{
+		{"claim", &input.claim},
+		{"program", &input.program},
+		{"non_determinism", &input.nonDeterminism},
+		{"max_log2_padded_height", &input.maxPaddedHeight},
+		{"env_variables", &input.envVars},
 	}
-	var envVars map[string]interface{}
-	if err := json.Unmarshal(scanner.Bytes(), &envVars); err != nil {
-		fatal(fmt.Sprintf("Failed to parse env_variables: %v", err))
+	for _, line := range lines {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", line.name, err)
+			}
+			return nil, fmt.Errorf("failed to read %s", line.name)
+		}
+		if err := json.Unmarshal(scanner.Bytes(), line.target); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", line.name, err)
+		}
 	}
+	return input, nil
+}
 
-	// Convert inputs to Vybium STARKs VM format
-	program, err := convertProgram(programInput)
-	if err != nil {
-		fatal(fmt.Sprintf("Failed to convert program: %v", err))
-	}
+// maxInputLineLen bounds a single JSON input line
+const maxInputLineLen = 64 * 1024 * 1024
 
-	publicInput := convertFieldElements(claimInput.Input)
-	secretInput := convertFieldElements(nonDetInput.IndividualTokens)
+// proofConfig returns the STARK parameters shared by prove and verify
+func proofConfig() *vybiumstarksvm.Config {
+	config := vybiumstarksvm.DefaultConfig()
+	config.FRIQueries = 80 // 128-bit security requires SecurityLevel/3 = 240/3 = 80
+	return config
+}
 
-	// Create VM and execute
-	logStderr("Creating Riva VM...")
-	vm, err := vybiumstarksvm.NewVM(vybiumstarksvm.DefaultVMConfig())
+// execution is the prover input converted for the VM
+type execution struct {
+	program        *vybiumstarksvm.Program
+	publicInput    []*vybiumstarksvm.FieldElement
+	nonDeterminism *vybiumstarksvm.NonDeterminism
+	options        vybiumstarksvm.ExecutionOptions
+}
+
+// convertExecution converts the program, inputs and limits of the prover input
+func convertExecution(input *proverInput) (*execution, error) {
+	program, err := convertProgram(input.program)
 	if err != nil {
-		fatal(fmt.Sprintf("Failed to create VM: %v", err))
+		return nil, fmt.Errorf("failed to convert program: %w", err)
 	}
 
-	// Execute program
-	logStderr("Executing program...")
-	trace, err := vm.Execute(program, publicInput, secretInput)
+	nonDeterminism, err := convertNonDeterminism(input.nonDeterminism)
 	if err != nil {
-		fatal(fmt.Sprintf("Execution failed: %v", err))
+		return nil, fmt.Errorf("invalid non_determinism: %w", err)
 	}
 
-	logStderr(fmt.Sprintf("Execution completed in %d cycles", trace.CycleCount))
-
-	// Create prover with proper security parameters
-	config := vybiumstarksvm.DefaultConfig()
-	config.FRIQueries = 80 // 128-bit security requires SecurityLevel/3 = 240/3 = 80
+	// Triton's limit is the log2 of the padded height
+	options := vybiumstarksvm.DefaultExecutionOptions()
+	if log2 := input.maxPaddedHeight; log2 != nil {
+		if *log2 >= 63 {
+			return nil, fmt.Errorf("invalid max_log2_padded_height %d: at most 62 is supported", *log2)
+		}
+		options.MaxPaddedHeight = 1 << *log2
+	}
 
-	// Adjust config based on padded height if needed
-	if maxPaddedHeight != nil {
-		logStderr(fmt.Sprintf("Max log2 padded height: %d", *maxPaddedHeight))
+	publicInput, err := canonicalElements(input.claim.Input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid claim input: %w", err)
 	}
 
-	logStderr("Creating prover...")
-	prover, err := vybiumstarksvm.NewProver(config)
+	return &execution{
+		program:        program,
+		publicInput:    convertFieldElements(publicInput),
+		nonDeterminism: nonDeterminism,
+		options:        options,
+	}, nil
+}
+
+// execute converts the prover input and runs the program on the VM
+func execute(input *proverInput) (*vybiumstarksvm.ExecutionTrace, error) {
+	exec, err := convertExecution(input)
 	if err != nil {
-		fatal(fmt.Sprintf("Failed to create prover: %v", err))
+		return nil, err
 	}
 
-	// Generate proof
-	logStderr("Generating proof...")
-	proof, err := prover.GenerateProof(trace)
+	// A program that cannot execute is rejected before spending a prover run
+	diagnostics, err := vybiumstarksvm.AnalyzeProgram(exec.program)
 	if err != nil {
-		fatal(fmt.Sprintf("Proof generation failed: %v", err))
+		return nil, err
+	}
+	var errs []error
+	for _, d := range diagnostics {
+		if d.Severity == vybiumstarksvm.SeverityError {
+			errs = append(errs, d)
+		} else {
+			logStderr(d.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid program: %w", errors.Join(errs...))
 	}
 
-	logStderr("Proof generated successfully")
+	logStderr("Creating Riva VM...")
+	vm, err := vybiumstarksvm.NewVM(vybiumstarksvm.DefaultVMConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VM: %w", err)
+	}
 
-	// Serialize proof
-	proofBytes, err := json.Marshal(proof)
+	logStderr("Executing program...")
+	trace, err := vm.ExecuteWithOptions(exec.program, exec.publicInput, exec.nonDeterminism, exec.options)
 	if err != nil {
-		fatal(fmt.Sprintf("Failed to serialize proof: %v", err))
+		return nil, fmt.Errorf("execution failed: %w", err)
 	}
 
-	// Write proof to stdout (like Triton VM)
-	os.Stdout.Write(proofBytes)
-	os.Stdout.Write([]byte("\n"))
+	logStderr(fmt.Sprintf("Execution completed in %d cycles", trace.CycleCount))
+	return trace, nil
 }
 
-func convertProgram(input ProgramInput) (*vybiumstarksvm.Program, error) {
-	instructions := make([]vybiumstarksvm.Instruction, len(input.Instructions))
-
-	for i, instStr := range input.Instructions {
-		opcode, arg, err := parseInstruction(instStr)
+// convertNonDeterminism decodes the prover's secret input: individual
+// tokens, hex digests and the initial RAM
+func convertNonDeterminism(input NonDeterminismInput) (*vybiumstarksvm.NonDeterminism, error) {
+	tokens, err := canonicalElements(input.IndividualTokens)
+	if err != nil {
+		return nil, fmt.Errorf("individual_tokens: %w", err)
+	}
+	result := &vybiumstarksvm.NonDeterminism{
+		IndividualTokens: convertFieldElements(tokens),
+		Digests:          make([][]*vybiumstarksvm.FieldElement, len(input.Digests)),
+		RAM:              make(map[uint64]*vybiumstarksvm.FieldElement, len(input.Ram)),
+	}
+	for i, hexDigest := range input.Digests {
+		digest, err := parseDigest(hexDigest)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse instruction %d (%s): %w", i, instStr, err)
+			return nil, fmt.Errorf("digest %d: %w", i, err)
 		}
-
-		instructions[i] = vybiumstarksvm.Instruction{
-			Opcode:   opcode,
-			Argument: arg,
+		result.Digests[i] = make([]*vybiumstarksvm.FieldElement, len(digest))
+		for j, elem := range digest {
+			result.Digests[i][j] = convertFieldElement(elem.Value())
 		}
 	}
 
-	return &vybiumstarksvm.Program{
-		Instructions: instructions,
-	}, nil
-}
-
-func parseInstruction(instStr string) (byte, *vybiumstarksvm.FieldElement, error) {
-	// Parse Triton VM instruction format: "Halt", "Push(42)", "ReadIo(1)", etc.
-
-	// Simple instructions without arguments
-	switch instStr {
-	case "Halt":
-		return 0, nil, nil
-	case "Divine":
-		return 2, nil, nil
-	case "Return":
-		return 8, nil, nil
-	case "Dup":
-		return 5, nil, nil
-	case "Eq":
-		return 6, nil, nil
-	case "Skiz":
-		return 7, nil, nil
-	case "Recurse":
-		return 10, nil, nil
-	case "Add":
-		return 42, nil, nil
-	case "Mul":
-		return 43, nil, nil
-	}
-
-	// Instructions with arguments: "Push(42)", "ReadIo(1)", etc.
-	if strings.Contains(instStr, "(") {
-		parts := strings.Split(instStr, "(")
-		if len(parts) != 2 {
-			return 0, nil, fmt.Errorf("invalid instruction format: %s", instStr)
+	for key, value := range input.Ram {
+		address, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || address >= field.P {
+			return nil, fmt.Errorf("invalid RAM address %q", key)
 		}
-
-		opName := parts[0]
-		argStr := strings.TrimSuffix(parts[1], ")")
-
-		// Parse argument as uint64
-		var argVal uint64
-		_, err := fmt.Sscanf(argStr, "%d", &argVal)
-		if err != nil {
-			return 0, nil, fmt.Errorf("invalid argument: %s", argStr)
-		}
-
-		arg := convertFieldElement(argVal)
-
-		switch opName {
-		case "Push":
-			return 1, arg, nil
-		case "ReadIo":
-			return 3, arg, nil
-		case "WriteIo":
-			return 4, arg, nil
-		case "Call":
-			return 9, arg, nil
-		default:
-			return 0, nil, fmt.Errorf("unknown instruction: %s", opName)
+		if value >= field.P {
+			return nil, fmt.Errorf("RAM value %d at address %d is not a field element", value, address)
 		}
+		result.RAM[address] = convertFieldElement(value)
 	}
 
-	return 0, nil, fmt.Errorf("unknown instruction: %s", instStr)
+	return result, nil
 }
 
-func convertFieldElements(values []uint64) []*vybiumstarksvm.FieldElement {
-	result := make([]*vybiumstarksvm.FieldElement, len(values))
-	for i, val := range values {
-		result[i] = convertFieldElement(val)
+func convertFieldElements(elems []field.Element) []*vybiumstarksvm.FieldElement {
+	result := make([]*vybiumstarksvm.FieldElement, len(elems))
+	for i, elem := range elems {
+		result[i] = convertFieldElement(elem.Value())
 	}
 	return result
 }
@@ -250,5 +298,5 @@ func logStderr(msg string) {
 
 func fatal(msg string) {
 	logStderr("ERROR: " + msg)
-	os.Exit(1)
+	os.Exit(exitFailure)
 }
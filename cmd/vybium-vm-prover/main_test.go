@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+)
+
+func TestConvertNonDeterminism(t *testing.T) {
+	var input NonDeterminismInput
+	err := json.Unmarshal([]byte(`{
+		"individual_tokens": [1, 2],
+		"digests": ["01000000000000000200000000000000030000000000000004000000000000000500000000000000"],
+		"ram": {"1000": 77}
+	}`), &input)
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+
+	nonDeterminism, err := convertNonDeterminism(input)
+	if err != nil {
+		t.Fatalf("convertNonDeterminism failed: %v", err)
+	}
+	if len(nonDeterminism.IndividualTokens) != 2 {
+		t.Errorf("got %d individual tokens, want 2", len(nonDeterminism.IndividualTokens))
+	}
+	if len(nonDeterminism.Digests) != 1 || len(nonDeterminism.Digests[0]) != digestLen {
+		t.Fatalf("got digests %v, want one of %d elements", nonDeterminism.Digests, digestLen)
+	}
+	for i, elem := range nonDeterminism.Digests[0] {
+		if elem.Big().Uint64() != uint64(i+1) {
+			t.Errorf("digest element %d = %s, want %d", i, elem.Big(), i+1)
+		}
+	}
+	if value, ok := nonDeterminism.RAM[1000]; !ok || value.Big().Uint64() != 77 {
+		t.Errorf("RAM[1000] = %v, want 77", value)
+	}
+
+	for _, bad := range []NonDeterminismInput{
+		{IndividualTokens: []uint64{18446744069414584321}},
+		{Digests: []string{"0102"}},
+		{Ram: map[string]uint64{"0x10": 1}},
+		{Ram: map[string]uint64{"1": 18446744069414584321}},
+	} {
+		if _, err := convertNonDeterminism(bad); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+}
+
+func TestConvertExecution(t *testing.T) {
+	newInput := func(log2 uint8) *proverInput {
+		return &proverInput{
+			program:         ProgramInput{Instructions: []string{"halt"}},
+			maxPaddedHeight: &log2,
+		}
+	}
+
+	exec, err := convertExecution(newInput(10))
+	if err != nil {
+		t.Fatalf("convertExecution failed: %v", err)
+	}
+	if exec.options.MaxPaddedHeight != 1<<10 {
+		t.Errorf("max padded height = %d, want %d", exec.options.MaxPaddedHeight, 1<<10)
+	}
+
+	input := newInput(10)
+	input.claim.Input = []uint64{field.P - 1}
+	if exec, err := convertExecution(input); err != nil || exec.publicInput[0].Big().Uint64() != field.P-1 {
+		t.Errorf("public input P-1: got %v, %v", exec, err)
+	}
+	input.claim.Input = []uint64{field.P}
+	if _, err := convertExecution(input); err == nil {
+		t.Error("accepted a public input that is not a field element")
+	}
+
+	// A limit that does not fit the options must not lift the limit
+	for _, log2 := range []uint8{63, 64, 255} {
+		if _, err := convertExecution(newInput(log2)); err == nil {
+			t.Errorf("max_log2_padded_height %d: expected an error", log2)
+		}
+	}
+}
@@ -55,7 +55,7 @@ func main() {
 	fmt.Printf("  - Halted: %v\n", vmState.Halting)
 
 	if vmState.StackPointer > 0 {
-		fmt.Printf("  - Top of stack: %s\n", vmState.Stack[vmState.StackPointer-1].String())
+		fmt.Printf("  - Top of stack: %s\n", vmState.Stack[0].String())
 	}
 
 	fmt.Printf("\nAET (Algebraic Execution Trace):\n")
@@ -55,7 +55,11 @@ func main() {
 		log.Fatalf("Invalid STARK parameters: %v", err)
 	}
 
-	prover, err := protocols.NewProver(params)
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		log.Fatalf("Failed to create AIR: %v", err)
+	}
+	prover, err := protocols.NewProver(params, air)
 	if err != nil {
 		log.Fatalf("Failed to create prover: %v", err)
 	}
@@ -82,7 +86,7 @@ func main() {
 		log.Fatalf("Failed to create field: %v", err)
 	}
 
-	verifier, err := protocols.NewVerifier(coreField, params)
+	verifier, err := protocols.NewVerifier(coreField, params, air)
 	if err != nil {
 		log.Fatalf("Failed to create verifier: %v", err)
 	}
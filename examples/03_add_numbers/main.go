@@ -95,7 +95,11 @@ func main() {
 
 	// Step 2: Create prover
 	fmt.Println("\n[2/5] Creating prover...")
-	prover, err := protocols.NewProver(params)
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		log.Fatalf("Failed to create AIR: %v", err)
+	}
+	prover, err := protocols.NewProver(params, air)
 	if err != nil {
 		log.Fatalf("Failed to create prover: %v", err)
 	}
@@ -130,7 +134,7 @@ func main() {
 		log.Fatalf("Failed to create field: %v", err)
 	}
 
-	verifier, err := protocols.NewVerifier(coreField, params)
+	verifier, err := protocols.NewVerifier(coreField, params, air)
 	if err != nil {
 		log.Fatalf("Failed to create verifier: %v", err)
 	}
@@ -60,7 +60,7 @@ func main() {
 
 	// Check top of stack
 	if vmState.StackPointer > 0 {
-		result := vmState.Stack[vmState.StackPointer-1]
+		result := vmState.Stack[0]
 		expected := field.New(56)
 		fmt.Printf("✓ Result on stack: %s\n", result.String())
 		if result.Equal(expected) {
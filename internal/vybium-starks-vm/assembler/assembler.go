@@ -0,0 +1,329 @@
+// Package assembler translates between Vybium STARKs VM programs and their
+// textual assembly, a format modelled on Triton's tasm
+//
+// A source is a sequence of whitespace-separated tokens:
+//
+//	// Comments run to the end of the line
+//	.const ANSWER 42          // a named argument
+//	.macro double             // a macro without parameters, up to .endm
+//	    dup 0 add
+//	.endm
+//
+//	    push ANSWER
+//	    call twice
+//	    halt
+//	twice:                    // a label, the word address of what follows
+//	    double double
+//	    push -1 add           // -1 is the field element p - 1
+//	    return
+//
+// Mnemonics are those of vm.AllInstructions; an instruction that takes an
+// argument is followed by it. Arguments are decimal or 0x-prefixed integers,
+// optionally negative, or constants; call also takes a label. Labels may be
+// used before their definition, constants and macros only after theirs.
+// break marks the next instruction as a breakpoint.
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+// Assembled is a program together with the debug information of its source
+type Assembled struct {
+	Program *vm.Program
+
+	// Labels maps every label to its word address
+	Labels map[string]int
+
+	// Lines holds the 1-based source line of every instruction; that of the
+	// invocation for instructions from a macro
+	Lines []int
+
+	// Breakpoints marks the instructions after a break, one entry per word
+	// address as in Triton's debug information
+	Breakpoints []bool
+}
+
+// Error is an assembly error at a line of the source
+type Error struct {
+	Line    int
+	Message string
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// mnemonics maps the name of every instruction to its opcode
+var mnemonics = func() map[string]vm.Instruction {
+	result := make(map[string]vm.Instruction, len(vm.AllInstructions))
+	for opcode, info := range vm.AllInstructions {
+		result[info.Name] = opcode
+	}
+	return result
+}()
+
+// LookupMnemonic returns the instruction named by a mnemonic such as "read_io"
+func LookupMnemonic(name string) (vm.Instruction, bool) {
+	opcode, ok := mnemonics[name]
+	return opcode, ok
+}
+
+// token is a word of the source and the line it is on
+type token struct {
+	text string
+	line int
+}
+
+// pendingInstruction is an instruction whose argument may still be a label
+type pendingInstruction struct {
+	opcode     vm.Instruction
+	arg        string // empty if the instruction takes no argument
+	line       int
+	breakpoint bool
+}
+
+// assembler holds the state of one Assemble call
+type assembler struct {
+	constants    map[string]field.Element
+	macros       map[string][]token
+	labels       map[string]int
+	instructions []pendingInstruction
+	address      int
+	breakNext    bool
+}
+
+// Assemble translates assembly source into a program
+//
+// Errors are of type *Error and name the offending line.
+func Assemble(source string) (*Assembled, error) {
+	a := &assembler{
+		constants: make(map[string]field.Element),
+		macros:    make(map[string][]token),
+		labels:    make(map[string]int),
+	}
+	if err := a.run(tokenize(source), nil, 0); err != nil {
+		return nil, err
+	}
+
+	result := &Assembled{
+		Program:     vm.NewProgram(),
+		Labels:      a.labels,
+		Lines:       make([]int, len(a.instructions)),
+		Breakpoints: make([]bool, 0, a.address),
+	}
+	for i, pending := range a.instructions {
+		var arg *field.Element
+		if pending.arg != "" {
+			value, err := a.resolve(pending)
+			if err != nil {
+				return nil, &Error{Line: pending.line, Message: err.Error()}
+			}
+			arg = &value
+		}
+		inst, err := vm.NewEncodedInstruction(pending.opcode, arg)
+		if err != nil {
+			return nil, &Error{Line: pending.line, Message: err.Error()}
+		}
+		result.Program.AddInstruction(inst)
+		result.Lines[i] = pending.line
+		for w := 0; w < pending.opcode.Size(); w++ {
+			result.Breakpoints = append(result.Breakpoints, pending.breakpoint)
+		}
+	}
+	return result, nil
+}
+
+// tokenize splits the source into tokens, dropping comments
+func tokenize(source string) []token {
+	var tokens []token
+	for i, line := range strings.Split(source, "\n") {
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+		for _, text := range strings.Fields(line) {
+			tokens = append(tokens, token{text: text, line: i + 1})
+		}
+	}
+	return tokens
+}
+
+// run processes tokens, expanding macros
+//
+// expanding lists the macros being expanded, to reject recursion, and line
+// is the line of their invocation, or zero outside of macros.
+func (a *assembler) run(tokens []token, expanding []string, line int) error {
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if line != 0 {
+			tok.line = line
+		}
+		fail := func(format string, args ...interface{}) error {
+			return &Error{Line: tok.line, Message: fmt.Sprintf(format, args...)}
+		}
+
+		switch {
+		case tok.text == ".const":
+			if i+2 >= len(tokens) {
+				return fail(".const needs a name and a value")
+			}
+			name, value := tokens[i+1].text, tokens[i+2].text
+			if err := a.checkName(name); err != nil {
+				return fail("%v", err)
+			}
+			elem, err := a.argument(value)
+			if err != nil {
+				return fail("constant %s: %v", name, err)
+			}
+			a.constants[name] = elem
+			i += 2
+
+		case tok.text == ".macro":
+			if len(expanding) > 0 {
+				return fail("macro defined inside macro %s", expanding[len(expanding)-1])
+			}
+			if i+1 >= len(tokens) {
+				return fail(".macro needs a name")
+			}
+			name := tokens[i+1].text
+			if err := a.checkName(name); err != nil {
+				return fail("%v", err)
+			}
+			end := i + 2
+			for end < len(tokens) && tokens[end].text != ".endm" {
+				if text := tokens[end].text; strings.HasPrefix(text, ".") || strings.HasSuffix(text, ":") {
+					return &Error{Line: tokens[end].line, Message: fmt.Sprintf("%s is not allowed in macro %s", text, name)}
+				}
+				end++
+			}
+			if end == len(tokens) {
+				return fail("macro %s has no .endm", name)
+			}
+			a.macros[name] = tokens[i+2 : end]
+			i = end
+
+		case strings.HasPrefix(tok.text, "."):
+			return fail("unknown directive %s", tok.text)
+
+		case strings.HasSuffix(tok.text, ":"):
+			label := strings.TrimSuffix(tok.text, ":")
+			if err := a.checkName(label); err != nil {
+				return fail("%v", err)
+			}
+			a.labels[label] = a.address
+
+		case tok.text == "break":
+			a.breakNext = true
+
+		default:
+			if body, ok := a.macros[tok.text]; ok {
+				for _, name := range expanding {
+					if name == tok.text {
+						return fail("macro %s expands itself", name)
+					}
+				}
+				if err := a.run(body, append(expanding, tok.text), tok.line); err != nil {
+					return err
+				}
+				continue
+			}
+
+			opcode, ok := mnemonics[tok.text]
+			if !ok {
+				return fail("unknown instruction %s", tok.text)
+			}
+			pending := pendingInstruction{opcode: opcode, line: tok.line, breakpoint: a.breakNext}
+			if opcode.HasArgument() {
+				if i+1 >= len(tokens) {
+					return fail("instruction %s requires an argument", tok.text)
+				}
+				i++
+				pending.arg = tokens[i].text
+			}
+			a.instructions = append(a.instructions, pending)
+			a.address += opcode.Size()
+			a.breakNext = false
+		}
+	}
+	return nil
+}
+
+// checkName rejects names that are malformed or already in use
+func (a *assembler) checkName(name string) error {
+	if !IsLabel(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	if _, ok := mnemonics[name]; ok || name == "break" {
+		return fmt.Errorf("%s is an instruction", name)
+	}
+	if _, ok := a.labels[name]; ok {
+		return fmt.Errorf("%s is already a label", name)
+	}
+	if _, ok := a.constants[name]; ok {
+		return fmt.Errorf("%s is already a constant", name)
+	}
+	if _, ok := a.macros[name]; ok {
+		return fmt.Errorf("%s is already a macro", name)
+	}
+	return nil
+}
+
+// resolve returns the argument of an instruction; labels are known once all
+// tokens are processed
+func (a *assembler) resolve(pending pendingInstruction) (field.Element, error) {
+	if pending.opcode == vm.Call {
+		if address, ok := a.labels[pending.arg]; ok {
+			return field.New(uint64(address)), nil
+		}
+	}
+	elem, err := a.argument(pending.arg)
+	if err != nil && IsLabel(pending.arg) {
+		return field.Zero, fmt.Errorf("undefined label or constant %s", pending.arg)
+	}
+	return elem, err
+}
+
+// argument returns the value of a number or constant
+func (a *assembler) argument(s string) (field.Element, error) {
+	if value, ok := a.constants[s]; ok {
+		return value, nil
+	}
+	return ParseArgument(s)
+}
+
+// ParseArgument parses a decimal or 0x-prefixed integer below the field's
+// prime, negated in the field if it starts with '-'
+func ParseArgument(s string) (field.Element, error) {
+	negative := strings.HasPrefix(s, "-")
+	value, err := strconv.ParseUint(strings.TrimPrefix(s, "-"), 0, 64)
+	if err != nil || value >= field.P {
+		return field.Zero, fmt.Errorf("invalid argument: %s", s)
+	}
+	if negative {
+		return field.New(value).Neg(), nil
+	}
+	return field.New(value), nil
+}
+
+// IsLabel reports whether s can name a label, constant or macro: a letter
+// or underscore followed by letters, digits, underscores, dashes or dots
+func IsLabel(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z'):
+		case i > 0 && (r == '-' || r == '.' || ('0' <= r && r <= '9')):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package assembler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+const example = `// Computes 4·42 - 1 in a function
+.const ANSWER 42
+.macro double
+    dup 0 add
+.endm
+
+    push ANSWER
+    call twice
+    halt
+twice:
+    break
+    double double
+    push -1 add   // field negation
+    return
+`
+
+func TestAssemble(t *testing.T) {
+	assembled, err := Assemble(example)
+	if err != nil {
+		t.Fatalf("Assemble failed: %v", err)
+	}
+
+	want := []vm.Instruction{vm.Push, vm.Call, vm.Halt, vm.Dup, vm.Add, vm.Dup, vm.Add, vm.Push, vm.Add, vm.Return}
+	program := assembled.Program
+	if len(program.Instructions) != len(want) {
+		t.Fatalf("assembled %d instructions, want %d", len(program.Instructions), len(want))
+	}
+	for i, inst := range program.Instructions {
+		if inst.Instruction != want[i] {
+			t.Errorf("instruction %d is %s, want %s", i, inst.Instruction, want[i])
+		}
+	}
+	if got := assembled.Labels["twice"]; got != 5 {
+		t.Errorf("twice is at %d, want 5", got)
+	}
+	if got := program.Instructions[1].Argument.Value(); got != 5 {
+		t.Errorf("call argument is %d, want 5", got)
+	}
+	if got := *program.Instructions[7].Argument; !got.Equal(field.New(1).Neg()) {
+		t.Errorf("push -1 pushes %v", got)
+	}
+	if assembled.Lines[0] != 7 || assembled.Lines[3] != 12 || assembled.Lines[6] != 12 || assembled.Lines[7] != 13 {
+		t.Errorf("lines = %v", assembled.Lines)
+	}
+	if len(assembled.Breakpoints) != program.Length || !assembled.Breakpoints[5] ||
+		assembled.Breakpoints[7] {
+		t.Errorf("breakpoints = %v, want only word 5", assembled.Breakpoints)
+	}
+
+	state := vm.NewVMState(program, nil, nil)
+	if err := state.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if top, _ := state.StackPeek(0); top.Value() != 167 {
+		t.Errorf("st0 = %d, want 167", top.Value())
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+	}{
+		{"UnknownInstruction", "push 1\nfrobnicate", 2},
+		{"MissingArgument", "push 1\n\npush", 3},
+		{"InvalidArgument", "push 18446744069414584321", 1},
+		{"UndefinedLabel", "call nowhere", 1},
+		{"DuplicateLabel", "a:\nhalt\na:", 3},
+		{"LabelNamedAfterInstruction", "add:", 1},
+		{"IncompleteConstant", "halt\n.const X", 2},
+		{"UnterminatedMacro", ".macro m\nadd", 1},
+		{"RecursiveMacro", ".macro m\nhalt\n.endm\n.macro n m n .endm\nn", 5},
+		{"LabelInMacro", ".macro m\nloop:\n.endm", 2},
+		{"UnknownDirective", ".include x", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Assemble(tt.source)
+			var asmErr *Error
+			if !errors.As(err, &asmErr) {
+				t.Fatalf("expected an assembly error, got %v", err)
+			}
+			if asmErr.Line != tt.line {
+				t.Errorf("error on line %d, want %d: %v", asmErr.Line, tt.line, err)
+			}
+		})
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	assembled, err := Assemble(example)
+	if err != nil {
+		t.Fatalf("Assemble failed: %v", err)
+	}
+
+	text := Disassemble(assembled.Program, assembled.Labels)
+	for _, want := range []string{"call twice\n", "twice:\n", "push -1\n", "push 42\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("disassembly lacks %q:\n%s", want, text)
+		}
+	}
+
+	for _, labels := range []map[string]int{assembled.Labels, nil} {
+		again, err := Assemble(Disassemble(assembled.Program, labels))
+		if err != nil {
+			t.Fatalf("reassembling failed: %v", err)
+		}
+		got, want := again.Program.ToWords(), assembled.Program.ToWords()
+		if len(got) != len(want) {
+			t.Fatalf("round trip has %d words, want %d", len(got), len(want))
+		}
+		for i := range want {
+			if !got[i].Equal(want[i]) {
+				t.Errorf("word %d is %v after the round trip, want %v", i, got[i], want[i])
+			}
+		}
+	}
+}
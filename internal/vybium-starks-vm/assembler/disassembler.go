@@ -0,0 +1,70 @@
+package assembler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+// Disassemble renders a program as assembly that Assemble translates back
+// into the same program
+//
+// labels, which may be nil, names word addresses; labels at addresses where
+// no instruction starts are left out. Calls to a labelled address name the
+// label, and arguments above p/2 are written as negative numbers.
+func Disassemble(program *vm.Program, labels map[string]int) string {
+	starts := make(map[int]bool, len(program.Instructions)+1)
+	address := 0
+	for _, inst := range program.Instructions {
+		starts[address] = true
+		address += inst.Instruction.Size()
+	}
+	starts[address] = true
+
+	names := make(map[int][]string)
+	for name, address := range labels {
+		if starts[address] && IsLabel(name) {
+			names[address] = append(names[address], name)
+		}
+	}
+	for _, list := range names {
+		sort.Strings(list)
+	}
+
+	var b strings.Builder
+	address = 0
+	for _, inst := range program.Instructions {
+		for _, name := range names[address] {
+			fmt.Fprintf(&b, "%s:\n", name)
+		}
+		b.WriteString("    ")
+		b.WriteString(inst.Instruction.String())
+		if inst.Argument != nil {
+			b.WriteByte(' ')
+			target := names[int(inst.Argument.Value())]
+			if inst.Instruction == vm.Call && len(target) > 0 {
+				b.WriteString(target[0])
+			} else {
+				b.WriteString(formatArgument(*inst.Argument))
+			}
+		}
+		b.WriteByte('\n')
+		address += inst.Instruction.Size()
+	}
+	for _, name := range names[address] {
+		fmt.Fprintf(&b, "%s:\n", name)
+	}
+	return b.String()
+}
+
+// formatArgument writes elements above p/2 as their negation, so that
+// push -1 reads as it was written
+func formatArgument(e field.Element) string {
+	if value := e.Value(); value > field.P/2 {
+		return fmt.Sprintf("-%d", field.P-value)
+	}
+	return fmt.Sprintf("%d", e.Value())
+}
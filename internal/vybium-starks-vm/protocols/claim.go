@@ -2,6 +2,7 @@ package protocols
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
@@ -82,6 +83,69 @@ func (c *Claim) Hash() (field.Element, error) {
 	return digest[0], nil
 }
 
+// Encode encodes the claim for the Fiat-Shamir transcript: the program
+// digest, the version, then the public input and output, each prefixed with
+// its length
+//
+// The prover and verifier absorb the claim before anything else, so every
+// challenge depends on it. Implements BFieldCodec.
+func (c *Claim) Encode() ([]field.Element, error) {
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid claim: %w", err)
+	}
+
+	encoded := make([]field.Element, 0, len(c.ProgramDigest)+3+len(c.PublicInput)+len(c.PublicOutput))
+	encoded = append(encoded, c.ProgramDigest...)
+	encoded = append(encoded, field.New(uint64(c.Version)))
+	encoded = append(encoded, field.New(uint64(len(c.PublicInput))))
+	encoded = append(encoded, c.PublicInput...)
+	encoded = append(encoded, field.New(uint64(len(c.PublicOutput))))
+	encoded = append(encoded, c.PublicOutput...)
+	return encoded, nil
+}
+
+// Decode reconstructs the claim from its encoding, the inverse of Encode
+func (c *Claim) Decode(data []field.Element) error {
+	const digestLen = 5
+	if len(data) < digestLen+2 {
+		return fmt.Errorf("claim encoding too short: %d elements", len(data))
+	}
+	version := data[digestLen].Value()
+	if version > math.MaxUint32 {
+		return fmt.Errorf("claim version %d out of range", version)
+	}
+
+	// list reads a length-prefixed list starting at data[offset]
+	list := func(offset int) ([]field.Element, int, error) {
+		if offset >= len(data) {
+			return nil, 0, fmt.Errorf("claim encoding truncated")
+		}
+		length := data[offset].Value()
+		if length > uint64(len(data)-offset-1) {
+			return nil, 0, fmt.Errorf("claim list length %d exceeds the encoding", length)
+		}
+		end := offset + 1 + int(length)
+		return append([]field.Element{}, data[offset+1:end]...), end, nil
+	}
+	input, next, err := list(digestLen + 1)
+	if err != nil {
+		return err
+	}
+	output, next, err := list(next)
+	if err != nil {
+		return err
+	}
+	if next != len(data) {
+		return fmt.Errorf("claim encoding has %d trailing elements", len(data)-next)
+	}
+
+	c.ProgramDigest = append([]field.Element{}, data[:digestLen]...)
+	c.Version = uint32(version)
+	c.PublicInput = input
+	c.PublicOutput = output
+	return nil
+}
+
 // CurrentVersion is the version of the Vybium STARKs VM ISA and STARK proof system
 // This changes whenever either the ISA or proof system changes
 const CurrentVersion uint32 = 0
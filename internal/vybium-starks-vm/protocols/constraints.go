@@ -2,11 +2,10 @@ package protocols
 
 import (
 	"fmt"
-	"os"
-	"sync"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/polynomial"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 )
 
 // AIRConstraints defines the Algebraic Intermediate Representation constraints
@@ -30,6 +29,38 @@ type AIRConstraints struct {
 
 	// Terminal constraints (applied to last row)
 	terminalConstraints []*ConstraintPolynomial
+
+	// Number of trace columns the evaluators read
+	numColumns int
+
+	// Periodic columns, appended to every row after the trace columns
+	periodicColumns []*PeriodicColumn
+
+	// Number of auxiliary trace columns, which are committed in a second
+	// round because they depend on challenges sampled after the main trace
+	// commitment
+	numAuxColumns int
+
+	// Number of challenges the auxiliary columns are built from
+	numChallenges int
+
+	// Number of challenges derived from the claim, and how
+	numClaimChallenges int
+	deriveChallenges   func(claim *Claim, challenges []xfield.XFieldElement) ([]xfield.XFieldElement, error)
+}
+
+// PeriodicColumn is a column that is not committed but repeats a fixed
+// sequence of values down the trace
+//
+// Row i of the trace holds Values[i mod len(Values)]. Prover and verifier
+// evaluate the column at any point directly, so it can supply constants such
+// as hash round constants to the constraints.
+type PeriodicColumn struct {
+	// Name for debugging
+	Name string
+
+	// Values over one period; the period must be a power of two
+	Values []field.Element
 }
 
 // ConstraintPolynomial represents a constraint over a single row
@@ -42,7 +73,10 @@ type ConstraintPolynomial struct {
 
 	// Evaluator function: takes a row of values and returns the constraint value
 	// The constraint is satisfied if this evaluates to zero
-	Evaluator func(row []field.Element) field.Element
+	//
+	// Values are extension field elements: main columns are base field
+	// elements on the trace, but the out-of-domain point is not.
+	Evaluator func(row []xfield.XFieldElement) xfield.XFieldElement
 }
 
 // TransitionConstraintPolynomial represents a constraint over two consecutive rows
@@ -55,7 +89,7 @@ type TransitionConstraintPolynomial struct {
 
 	// Evaluator function: takes current and next rows, returns the constraint value
 	// The constraint is satisfied if this evaluates to zero
-	Evaluator func(currentRow, nextRow []field.Element) field.Element
+	Evaluator func(currentRow, nextRow []xfield.XFieldElement) xfield.XFieldElement
 }
 
 // NewAIRConstraints creates a new AIR constraint system
@@ -68,9 +102,130 @@ func NewAIRConstraints() *AIRConstraints {
 	}
 }
 
+// SetNumColumns sets the number of trace columns the constraint evaluators read
+func (air *AIRConstraints) SetNumColumns(numColumns int) {
+	air.numColumns = numColumns
+}
+
+// NumColumns returns the number of trace columns the constraint evaluators read
+//
+// Rows passed to the evaluators must have at least this many columns.
+func (air *AIRConstraints) NumColumns() int {
+	return air.numColumns
+}
+
+// AddPeriodicColumn adds a periodic column and returns its index in the rows
+// passed to the evaluators
+//
+// Periodic columns follow the NumColumns() trace columns in the order they
+// were added, so SetNumColumns must be called first. The period must be a
+// power of two that divides the trace length.
+func (air *AIRConstraints) AddPeriodicColumn(name string, values []field.Element) (int, error) {
+	if !isPowerOfTwo(len(values)) {
+		return 0, fmt.Errorf("periodic column %s has period %d, which is not a power of two", name, len(values))
+	}
+	air.periodicColumns = append(air.periodicColumns, &PeriodicColumn{
+		Name:   name,
+		Values: values,
+	})
+	return air.numColumns + len(air.periodicColumns) - 1, nil
+}
+
+// NumPeriodicColumns returns the number of periodic columns
+func (air *AIRConstraints) NumPeriodicColumns() int {
+	return len(air.periodicColumns)
+}
+
+// SetNumAuxColumns sets the number of auxiliary trace columns
+//
+// Auxiliary columns are built by the trace from the challenges (see
+// AuxiliaryTrace) and committed after the main trace.
+func (air *AIRConstraints) SetNumAuxColumns(numAuxColumns int) {
+	air.numAuxColumns = numAuxColumns
+}
+
+// NumAuxColumns returns the number of auxiliary trace columns
+func (air *AIRConstraints) NumAuxColumns() int {
+	return air.numAuxColumns
+}
+
+// SetNumChallenges sets the number of challenges sampled after the main
+// trace commitment
+func (air *AIRConstraints) SetNumChallenges(numChallenges int) {
+	air.numChallenges = numChallenges
+}
+
+// NumChallenges returns the number of challenges sampled after the main
+// trace commitment
+func (air *AIRConstraints) NumChallenges() int {
+	return air.numChallenges
+}
+
+// SetClaimChallenges sets the number of challenges derived from the claim
+// and the sampled challenges, and the function deriving them
+//
+// They follow the sampled challenges in the rows passed to the evaluators,
+// so constraints can compare the trace with the claim's program digest and
+// public input and output, which the verifier only knows from the claim.
+func (air *AIRConstraints) SetClaimChallenges(
+	numClaimChallenges int,
+	derive func(claim *Claim, challenges []xfield.XFieldElement) ([]xfield.XFieldElement, error),
+) {
+	air.numClaimChallenges = numClaimChallenges
+	air.deriveChallenges = derive
+}
+
+// NumClaimChallenges returns the number of challenges derived from the claim
+func (air *AIRConstraints) NumClaimChallenges() int {
+	return air.numClaimChallenges
+}
+
+// ClaimChallenges returns the NumChallenges() sampled challenges followed by
+// the challenges derived from them and the claim
+//
+// Prover and verifier both pass the result to the evaluators, so a claim
+// the trace does not match violates a constraint.
+func (air *AIRConstraints) ClaimChallenges(claim *Claim, challenges []xfield.XFieldElement) ([]xfield.XFieldElement, error) {
+	if len(challenges) != air.numChallenges {
+		return nil, fmt.Errorf("expected %d challenges, got %d", air.numChallenges, len(challenges))
+	}
+	if air.numClaimChallenges == 0 {
+		return challenges, nil
+	}
+	derived, err := air.deriveChallenges(claim, challenges)
+	if err != nil {
+		return nil, fmt.Errorf("claim does not fit the AIR: %w", err)
+	}
+	if len(derived) != air.numClaimChallenges {
+		return nil, fmt.Errorf("derived %d claim challenges, AIR needs %d", len(derived), air.numClaimChallenges)
+	}
+	all := make([]xfield.XFieldElement, 0, len(challenges)+len(derived))
+	all = append(all, challenges...)
+	return append(all, derived...), nil
+}
+
+// AuxColumnIndex returns the index of auxiliary column k in the rows passed
+// to the evaluators
+//
+// Rows hold the trace columns, the periodic columns, the auxiliary columns
+// and the challenges, in that order, so all periodic columns must have been
+// added before the index is taken.
+func (air *AIRConstraints) AuxColumnIndex(k int) int {
+	return air.numColumns + len(air.periodicColumns) + k
+}
+
+// ChallengeIndex returns the index of challenge k in the rows passed to the
+// evaluators
+//
+// Like AuxColumnIndex, it depends on the number of periodic columns. Claim
+// challenge k has index ChallengeIndex(NumChallenges() + k).
+func (air *AIRConstraints) ChallengeIndex(k int) int {
+	return air.AuxColumnIndex(air.numAuxColumns) + k
+}
+
 // AddInitialConstraint adds an initial (boundary) constraint
 func (air *AIRConstraints) AddInitialConstraint(name string, degree int,
-	eval func(row []field.Element) field.Element,
+	eval func(row []xfield.XFieldElement) xfield.XFieldElement,
 ) {
 	air.initialConstraints = append(air.initialConstraints, &ConstraintPolynomial{
 		Name:      name,
@@ -81,7 +236,7 @@ func (air *AIRConstraints) AddInitialConstraint(name string, degree int,
 
 // AddConsistencyConstraint adds a consistency constraint
 func (air *AIRConstraints) AddConsistencyConstraint(name string, degree int,
-	eval func(row []field.Element) field.Element,
+	eval func(row []xfield.XFieldElement) xfield.XFieldElement,
 ) {
 	air.consistencyConstraints = append(air.consistencyConstraints, &ConstraintPolynomial{
 		Name:      name,
@@ -92,7 +247,7 @@ func (air *AIRConstraints) AddConsistencyConstraint(name string, degree int,
 
 // AddTransitionConstraint adds a transition constraint
 func (air *AIRConstraints) AddTransitionConstraint(name string, degree int,
-	eval func(currentRow, nextRow []field.Element) field.Element,
+	eval func(currentRow, nextRow []xfield.XFieldElement) xfield.XFieldElement,
 ) {
 	air.transitionConstraints = append(air.transitionConstraints, &TransitionConstraintPolynomial{
 		Name:      name,
@@ -103,7 +258,7 @@ func (air *AIRConstraints) AddTransitionConstraint(name string, degree int,
 
 // AddTerminalConstraint adds a terminal (final row) constraint
 func (air *AIRConstraints) AddTerminalConstraint(name string, degree int,
-	eval func(row []field.Element) field.Element,
+	eval func(row []xfield.XFieldElement) xfield.XFieldElement,
 ) {
 	air.terminalConstraints = append(air.terminalConstraints, &ConstraintPolynomial{
 		Name:      name,
@@ -112,78 +267,343 @@ func (air *AIRConstraints) AddTerminalConstraint(name string, degree int,
 	})
 }
 
-// EvaluateComposition evaluates the composition polynomial over all constraints
+// EvaluateQuotientAt evaluates the combined quotient at a single point
 //
-// The composition polynomial is a weighted linear combination of all constraints:
-// h(X) = Σ α_i · constraint_i(X)
+// The combined quotient is a weighted linear combination of every constraint
+// divided by the zerofier of the rows it applies to:
 //
-// where α_i are random challenges from the verifier (via Fiat-Shamir).
-func (air *AIRConstraints) EvaluateComposition(
-	traceTable [][]field.Element,
-	domain *ArithmeticDomain,
-	challenges []field.Element,
-) ([]field.Element, error) {
-	numRows := len(traceTable[0])
-	composition := make([]field.Element, numRows)
-
-	// Initialize to zero
-	for i := 0; i < numRows; i++ {
-		composition[i] = field.Zero
-	}
-
-	// Challenge index
-	challengeIdx := 0
-
-	// Evaluate initial constraints (only on first row)
-	if len(traceTable) > 0 {
-		firstRow := air.extractRow(traceTable, 0)
-		for _, constraint := range air.initialConstraints {
-			value := constraint.Evaluator(firstRow)
-			weighted := value.Mul(challenges[challengeIdx%len(challenges)])
-			composition[0] = composition[0].Add(weighted)
-			challengeIdx++
+//	Q(X) = Σ w_i · C_i(X) / Z_i(X)
+//
+// with Z_i = X - 1 for initial constraints, X^n - 1 for consistency constraints,
+// (X^n - 1)/(X - ω^-1) for transition constraints and X - ω^-1 for terminal
+// constraints, where ω generates the trace domain of length n.
+//
+// currentRow holds every column evaluated at point, nextRow every column
+// evaluated at ω·point; the last NumAuxColumns() of them are the auxiliary
+// columns. challenges are the NumChallenges() challenges the auxiliary
+// columns were built from, followed by the claim challenges (see
+// ClaimChallenges). The weights are consumed in the order initial,
+// consistency, transition, terminal and must number NumConstraints().
+//
+// The verifier calls this once, at the out-of-domain point. The prover
+// evaluates the same sum over the whole quotient domain in
+// ComputeQuotientCodeword, with the zerofiers and periodic columns
+// precomputed for all points.
+func (air *AIRConstraints) EvaluateQuotientAt(
+	point xfield.XFieldElement,
+	currentRow, nextRow []xfield.XFieldElement,
+	challenges []xfield.XFieldElement,
+	weights []xfield.XFieldElement,
+	traceDomain *ArithmeticDomain,
+) (xfield.XFieldElement, error) {
+	if len(weights) != air.NumConstraints() {
+		return xfield.Zero, fmt.Errorf("expected %d constraint weights, got %d", air.NumConstraints(), len(weights))
+	}
+	if air.needsRowLayout() {
+		currentPeriodic, err := air.periodicValuesAt(point, traceDomain)
+		if err != nil {
+			return xfield.Zero, err
+		}
+		nextPeriodic, err := air.periodicValuesAt(point.MulConst(traceDomain.Generator), traceDomain)
+		if err != nil {
+			return xfield.Zero, err
+		}
+		currentRow, err = air.evaluatorRow(currentRow, currentPeriodic, challenges)
+		if err != nil {
+			return xfield.Zero, err
+		}
+		nextRow, err = air.evaluatorRow(nextRow, nextPeriodic, challenges)
+		if err != nil {
+			return xfield.Zero, err
 		}
 	}
 
-	// Evaluate consistency constraints (on all rows)
-	for rowIdx := 0; rowIdx < numRows; rowIdx++ {
-		row := air.extractRow(traceTable, rowIdx)
-		localChallenge := challengeIdx
-		for _, constraint := range air.consistencyConstraints {
-			value := constraint.Evaluator(row)
-			weighted := value.Mul(challenges[localChallenge%len(challenges)])
-			composition[rowIdx] = composition[rowIdx].Add(weighted)
-			localChallenge++
+	lastRowPoint := traceDomain.Generator.Inverse()
+	traceZerofier := traceDomain.zerofierAtXField(point)
+	if traceZerofier.IsZero() {
+		return xfield.Zero, fmt.Errorf("point lies in the trace domain")
+	}
+
+	// The trace zerofier vanishes on the first and last row too, so none of
+	// the denominators is zero
+	inverses, err := BatchInverse([]xfield.XFieldElement{
+		point.Sub(xfield.One),
+		traceZerofier,
+		point.SubConst(lastRowPoint),
+	})
+	if err != nil {
+		return xfield.Zero, err
+	}
+	transitionZerofierInv := point.SubConst(lastRowPoint).Mul(inverses[1])
+
+	sums := air.weightedConstraintSums(currentRow, nextRow, weights)
+	quotient := sums[0].Mul(inverses[0]).
+		Add(sums[1].Mul(inverses[1])).
+		Add(sums[2].Mul(transitionZerofierInv)).
+		Add(sums[3].Mul(inverses[2]))
+	return quotient, nil
+}
+
+// weightedConstraintSums evaluates every constraint on the evaluator rows
+// and returns the weighted sums of the initial, consistency, transition and
+// terminal constraints
+//
+// The constraints of each kind share a zerofier, so the caller divides each
+// sum once.
+func (air *AIRConstraints) weightedConstraintSums(
+	currentRow, nextRow []xfield.XFieldElement,
+	weights []xfield.XFieldElement,
+) [4]xfield.XFieldElement {
+	var sums [4]xfield.XFieldElement
+	weightIdx := 0
+	for _, constraint := range air.initialConstraints {
+		sums[0] = sums[0].Add(constraint.Evaluator(currentRow).Mul(weights[weightIdx]))
+		weightIdx++
+	}
+	for _, constraint := range air.consistencyConstraints {
+		sums[1] = sums[1].Add(constraint.Evaluator(currentRow).Mul(weights[weightIdx]))
+		weightIdx++
+	}
+	for _, constraint := range air.transitionConstraints {
+		sums[2] = sums[2].Add(constraint.Evaluator(currentRow, nextRow).Mul(weights[weightIdx]))
+		weightIdx++
+	}
+	for _, constraint := range air.terminalConstraints {
+		sums[3] = sums[3].Add(constraint.Evaluator(currentRow).Mul(weights[weightIdx]))
+		weightIdx++
+	}
+	return sums
+}
+
+// CheckTrace evaluates every constraint on the rows of a trace and returns an
+// error naming the first one that does not hold
+//
+// auxColumns were built from challenges, which the claim challenges are
+// derived from as in proving. The prover has no use for this, since a
+// violated constraint only shows up as a failed proof, but it pinpoints the
+// constraint when debugging a trace.
+func (air *AIRConstraints) CheckTrace(
+	claim *Claim,
+	mainColumns [][]field.Element,
+	auxColumns [][]xfield.XFieldElement,
+	challenges []xfield.XFieldElement,
+) error {
+	challenges, err := air.ClaimChallenges(claim, challenges)
+	if err != nil {
+		return err
+	}
+	if len(mainColumns) < air.numColumns || len(auxColumns) != air.numAuxColumns {
+		return fmt.Errorf("trace has %d main and %d auxiliary columns, AIR needs %d and %d",
+			len(mainColumns), len(auxColumns), air.numColumns, air.numAuxColumns)
+	}
+	if len(mainColumns) == 0 || len(mainColumns[0]) == 0 {
+		return nil
+	}
+	height := len(mainColumns[0])
+	for i, column := range mainColumns {
+		if len(column) != height {
+			return fmt.Errorf("trace column %d has length %d, expected %d", i, len(column), height)
+		}
+	}
+	for i, column := range auxColumns {
+		if len(column) != height {
+			return fmt.Errorf("auxiliary column %d has length %d, expected %d", i, len(column), height)
+		}
+	}
+
+	periodic := make([]xfield.XFieldElement, len(air.periodicColumns))
+	traceRow := make([]xfield.XFieldElement, len(mainColumns)+len(auxColumns))
+	row := func(idx int) ([]xfield.XFieldElement, error) {
+		for col, column := range mainColumns {
+			traceRow[col] = xfield.NewConst(column[idx])
+		}
+		for col, column := range auxColumns {
+			traceRow[len(mainColumns)+col] = column[idx]
+		}
+		for i, column := range air.periodicColumns {
+			periodic[i] = xfield.NewConst(column.Values[idx%len(column.Values)])
+		}
+		return air.evaluatorRow(traceRow, periodic, challenges)
+	}
+
+	first, err := row(0)
+	if err != nil {
+		return err
+	}
+	last, err := row(height - 1)
+	if err != nil {
+		return err
+	}
+
+	for _, constraint := range air.initialConstraints {
+		if !constraint.Evaluator(first).IsZero() {
+			return fmt.Errorf("initial constraint %s does not hold", constraint.Name)
+		}
+	}
+	for _, constraint := range air.terminalConstraints {
+		if !constraint.Evaluator(last).IsZero() {
+			return fmt.Errorf("terminal constraint %s does not hold", constraint.Name)
 		}
 	}
-	challengeIdx += len(air.consistencyConstraints)
 
-	// Evaluate transition constraints (on consecutive rows)
-	for rowIdx := 0; rowIdx < numRows-1; rowIdx++ {
-		currentRow := air.extractRow(traceTable, rowIdx)
-		nextRow := air.extractRow(traceTable, rowIdx+1)
-		localChallenge := challengeIdx
+	current := first
+	for idx := 0; idx < height; idx++ {
+		for _, constraint := range air.consistencyConstraints {
+			if !constraint.Evaluator(current).IsZero() {
+				return fmt.Errorf("consistency constraint %s does not hold in row %d", constraint.Name, idx)
+			}
+		}
+		if idx == height-1 {
+			break
+		}
+		next, err := row(idx + 1)
+		if err != nil {
+			return err
+		}
 		for _, constraint := range air.transitionConstraints {
-			value := constraint.Evaluator(currentRow, nextRow)
-			weighted := value.Mul(challenges[localChallenge%len(challenges)])
-			composition[rowIdx] = composition[rowIdx].Add(weighted)
-			localChallenge++
+			if !constraint.Evaluator(current, next).IsZero() {
+				return fmt.Errorf("transition constraint %s does not hold in rows %d and %d",
+					constraint.Name, idx, idx+1)
+			}
 		}
+		current = next
+	}
+
+	return nil
+}
+
+// needsRowLayout reports whether the rows passed to the evaluators differ
+// from the committed rows, i.e. whether there are periodic columns,
+// auxiliary columns or challenges to arrange
+func (air *AIRConstraints) needsRowLayout() bool {
+	return len(air.periodicColumns) > 0 || air.numAuxColumns > 0 || air.numChallenges+air.numClaimChallenges > 0
+}
+
+// evaluatorRow arranges a committed row (main columns, then auxiliary
+// columns) into the row passed to the evaluators: the first NumColumns()
+// main columns, the periodic column values, the auxiliary columns and the
+// challenges
+func (air *AIRConstraints) evaluatorRow(
+	row []xfield.XFieldElement,
+	periodic []xfield.XFieldElement,
+	challenges []xfield.XFieldElement,
+) ([]xfield.XFieldElement, error) {
+	if len(row) < air.numColumns+air.numAuxColumns {
+		return nil, fmt.Errorf("row has %d columns, AIR needs %d", len(row), air.numColumns+air.numAuxColumns)
+	}
+	numChallenges := air.numChallenges + air.numClaimChallenges
+	if len(challenges) != numChallenges {
+		return nil, fmt.Errorf("expected %d challenges, got %d", numChallenges, len(challenges))
 	}
-	challengeIdx += len(air.transitionConstraints)
 
-	// Evaluate terminal constraints (only on last row)
-	if numRows > 0 {
-		lastRow := air.extractRow(traceTable, numRows-1)
-		for _, constraint := range air.terminalConstraints {
-			value := constraint.Evaluator(lastRow)
-			weighted := value.Mul(challenges[challengeIdx%len(challenges)])
-			composition[numRows-1] = composition[numRows-1].Add(weighted)
-			challengeIdx++
+	values := make([]xfield.XFieldElement, 0, air.ChallengeIndex(numChallenges))
+	values = append(values, row[:air.numColumns]...)
+	values = append(values, periodic...)
+	values = append(values, row[len(row)-air.numAuxColumns:]...)
+	values = append(values, challenges...)
+	return values, nil
+}
+
+// periodicValuesAt evaluates every periodic column at point
+//
+// A column with period m over a trace domain of length n is the polynomial
+// P(X^(n/m)), where P interpolates the values over the subgroup of order m
+// generated by ω^(n/m). P is evaluated with the barycentric formula
+//
+//	P(y) = (y^m - 1)/m · Σ_k v_k·g^k / (y - g^k)
+//
+// and the denominators are shared by all columns of the same period.
+func (air *AIRConstraints) periodicValuesAt(
+	point xfield.XFieldElement,
+	traceDomain *ArithmeticDomain,
+) ([]xfield.XFieldElement, error) {
+	values := make([]xfield.XFieldElement, 0, len(air.periodicColumns))
+
+	// Per-period scaled inverses (y^m - 1)/m · g^k / (y - g^k)
+	coefficients := make(map[int][]xfield.XFieldElement)
+	for _, column := range air.periodicColumns {
+		period := len(column.Values)
+		if traceDomain.Length%period != 0 {
+			return nil, fmt.Errorf("periodic column %s has period %d, which does not divide trace length %d",
+				column.Name, period, traceDomain.Length)
+		}
+
+		lagrange, ok := coefficients[period]
+		if !ok {
+			stride := uint64(traceDomain.Length / period)
+			y := point.Pow(stride)
+			g := traceDomain.Generator.ModPow(stride)
+			vanishing := y.Pow(uint64(period)).Sub(xfield.One)
+			if vanishing.IsZero() {
+				return nil, fmt.Errorf("point lies in the trace domain")
+			}
+			scale := vanishing.MulConst(field.New(uint64(period)).Inverse())
+
+			denominators := make([]xfield.XFieldElement, period)
+			gk := field.One
+			for k := range denominators {
+				denominators[k] = y.SubConst(gk)
+				gk = gk.Mul(g)
+			}
+			inverses, err := BatchInverse(denominators)
+			if err != nil {
+				return nil, err
+			}
+
+			lagrange = make([]xfield.XFieldElement, period)
+			gk = field.One
+			for k := range lagrange {
+				lagrange[k] = scale.MulConst(gk).Mul(inverses[k])
+				gk = gk.Mul(g)
+			}
+			coefficients[period] = lagrange
+		}
+
+		value := xfield.Zero
+		for k, v := range column.Values {
+			value = value.Add(lagrange[k].MulConst(v))
 		}
+		values = append(values, value)
 	}
 
-	return composition, nil
+	return values, nil
+}
+
+// periodicCodewords evaluates every periodic column on a domain, a coset of
+// a subgroup that contains the trace domain
+//
+// A column with period m is P(X^s) with s = n/m (see periodicValuesAt). On
+// the coset offset·⟨ω⟩ of length N, X^s runs through the coset
+// offset^s·⟨ω^s⟩ of length N/s, so P is evaluated there with one NTT. The
+// returned codeword has that length and repeats down the domain.
+func (air *AIRConstraints) periodicCodewords(
+	domain *ArithmeticDomain,
+	traceDomain *ArithmeticDomain,
+) ([][]field.Element, error) {
+	codewords := make([][]field.Element, len(air.periodicColumns))
+	for i, column := range air.periodicColumns {
+		period := len(column.Values)
+		if traceDomain.Length%period != 0 {
+			return nil, fmt.Errorf("periodic column %s has period %d, which does not divide trace length %d",
+				column.Name, period, traceDomain.Length)
+		}
+		stride := uint64(traceDomain.Length / period)
+
+		subgroup := &ArithmeticDomain{Offset: field.One, Generator: traceDomain.Generator.ModPow(stride), Length: period}
+		coefficients, err := subgroup.interpolateValues(column.Values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpolate periodic column %s: %w", column.Name, err)
+		}
+		repeating := &ArithmeticDomain{
+			Offset:    domain.Offset.ModPow(stride),
+			Generator: domain.Generator.ModPow(stride),
+			Length:    domain.Length / int(stride),
+		}
+		if codewords[i], err = repeating.evaluateCoefficients(coefficients); err != nil {
+			return nil, fmt.Errorf("failed to evaluate periodic column %s: %w", column.Name, err)
+		}
+	}
+	return codewords, nil
 }
 
 // extractRow extracts a single row from the trace table
@@ -244,13 +664,16 @@ func (air *AIRConstraints) NumConstraints() int {
 func CreateProcessorConstraints() *AIRConstraints {
 	air := NewAIRConstraints()
 
+	// clk, ip, ci, nia, ib0, ib1, ib2
+	air.SetNumColumns(7)
+
 	// Initial constraints: first row should have clock = 0, IP = 0
-	air.AddInitialConstraint("clock_starts_at_0", 1, func(row []field.Element) field.Element {
+	air.AddInitialConstraint("clock_starts_at_0", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
 		// Assuming Clock is at index 0
 		return row[0] // Should be 0
 	})
 
-	air.AddInitialConstraint("ip_starts_at_0", 1, func(row []field.Element) field.Element {
+	air.AddInitialConstraint("ip_starts_at_0", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
 		// Assuming IP is at index 1
 		return row[1] // Should be 0
 	})
@@ -258,89 +681,218 @@ func CreateProcessorConstraints() *AIRConstraints {
 	// Consistency constraints: instruction bits must be binary
 	for i := 0; i < 3; i++ {
 		bitIdx := i
-		air.AddConsistencyConstraint(fmt.Sprintf("ib%d_is_bit", i), 2, func(row []field.Element) field.Element {
+		air.AddConsistencyConstraint(fmt.Sprintf("ib%d_is_bit", i), 2, func(row []xfield.XFieldElement) xfield.XFieldElement {
 			// Assuming instruction bits start at index 4
 			// Constraint: bit * (bit - 1) = 0
 			bit := row[4+bitIdx]
-			one := field.One
+			one := xfield.One
 			return bit.Mul(bit.Sub(one))
 		})
 	}
 
 	// Transition constraints: clock increments by 1
-	air.AddTransitionConstraint("clock_increments", 1, func(current, next []field.Element) field.Element {
+	air.AddTransitionConstraint("clock_increments", 1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
 		// next.clock - current.clock - 1 = 0
-		one := field.One
+		one := xfield.One
 		return next[0].Sub(current[0]).Sub(one)
 	})
 
 	return air
 }
 
-// ComputeQuotientPolynomials computes the quotient polynomials from constraints
+// ComputeQuotientCodeword evaluates the combined quotient over the quotient domain
 //
-// Following the STARK protocol:
-// 1. Evaluate composition polynomial h(X) over quotient domain
-// 2. Compute vanishing polynomial Z(X) for the trace domain
-// 3. Divide: q(X) = h(X) / Z(X)
-// 4. The quotient q(X) should be a polynomial (no remainder)
-func ComputeQuotientPolynomials(
+// mainColumns and auxColumns are the low-degree extensions of the main and
+// auxiliary trace columns on the quotient domain, and challenges are the
+// challenges the auxiliary columns were built from. Because the trace domain
+// generator is a power of the quotient domain generator, the "next row" of
+// point i is simply point i + len/n of the same codewords.
+//
+// The zerofiers are base field values on the quotient domain and are
+// inverted all at once; the periodic columns are evaluated with NTTs.
+//
+// The result is a codeword of a polynomial of degree at most the quotient
+// degree bound if and only if all constraints hold on the trace.
+func ComputeQuotientCodeword(
 	air *AIRConstraints,
-	traceTable [][]field.Element,
+	mainColumns [][]field.Element,
+	auxColumns [][]xfield.XFieldElement,
+	challenges []xfield.XFieldElement,
 	domains *ProverDomains,
-	challenges []field.Element,
-) ([]*polynomial.Polynomial, error) {
-	// Step 1: Evaluate composition polynomial over randomized trace domain
-	compositionValues, err := air.EvaluateComposition(traceTable, domains.RandomizedTrace, challenges)
+	weights []xfield.XFieldElement,
+) ([]xfield.XFieldElement, error) {
+	return computeQuotientCodeword(nil, air, mainColumns, auxColumns, challenges, domains, weights)
+}
+
+// computeQuotientCodeword is ComputeQuotientCodeword with the rows of the
+// quotient domain distributed over the pool's workers
+func computeQuotientCodeword(
+	pool *workerPool,
+	air *AIRConstraints,
+	mainColumns [][]field.Element,
+	auxColumns [][]xfield.XFieldElement,
+	challenges []xfield.XFieldElement,
+	domains *ProverDomains,
+	weights []xfield.XFieldElement,
+) ([]xfield.XFieldElement, error) {
+	quotientDomain := domains.Quotient
+	if quotientDomain.Length%domains.Trace.Length != 0 {
+		return nil, fmt.Errorf("quotient domain length %d is not a multiple of trace length %d",
+			quotientDomain.Length, domains.Trace.Length)
+	}
+	if len(mainColumns) < air.NumColumns() || len(auxColumns) < air.NumAuxColumns() {
+		return nil, fmt.Errorf("trace has %d main and %d auxiliary columns, AIR needs %d and %d",
+			len(mainColumns), len(auxColumns), air.NumColumns(), air.NumAuxColumns())
+	}
+	for i, col := range mainColumns {
+		if len(col) != quotientDomain.Length {
+			return nil, fmt.Errorf("extended column %d has length %d, expected %d", i, len(col), quotientDomain.Length)
+		}
+	}
+	for i, col := range auxColumns {
+		if len(col) != quotientDomain.Length {
+			return nil, fmt.Errorf("extended auxiliary column %d has length %d, expected %d",
+				i, len(col), quotientDomain.Length)
+		}
+	}
+
+	if len(weights) != air.NumConstraints() {
+		return nil, fmt.Errorf("expected %d constraint weights, got %d", air.NumConstraints(), len(weights))
+	}
+
+	numRows := quotientDomain.Length
+	unitDistance := numRows / domains.Trace.Length
+	zerofiers, err := newQuotientZerofiers(quotientDomain, domains.Trace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate composition: %w", err)
+		return nil, err
+	}
+	periodic, err := air.periodicCodewords(quotientDomain, domains.Trace)
+	if err != nil {
+		return nil, err
 	}
+	codeword := make([]xfield.XFieldElement, numRows)
+
+	numMain := len(mainColumns)
+	err = pool.parallelFor(PhaseQuotient, numRows, func(start, end int) error {
+		// Row buffers are reused across the rows of a chunk
+		currentRow := make([]xfield.XFieldElement, numMain+len(auxColumns))
+		nextRow := make([]xfield.XFieldElement, numMain+len(auxColumns))
+		currentPeriodic := make([]xfield.XFieldElement, len(periodic))
+		nextPeriodic := make([]xfield.XFieldElement, len(periodic))
+		for i := start; i < end; i++ {
+			next := (i + unitDistance) % numRows
+			for col, column := range mainColumns {
+				currentRow[col] = xfield.NewConst(column[i])
+				nextRow[col] = xfield.NewConst(column[next])
+			}
+			for col, column := range auxColumns {
+				currentRow[numMain+col] = column[i]
+				nextRow[numMain+col] = column[next]
+			}
 
-	// Step 2: Interpolate composition to get polynomial h(X)
-	domainPoints := domains.RandomizedTrace.Elements()
-	points := make([][2]field.Element, len(domainPoints))
-	for i := range domainPoints {
-		points[i] = [2]field.Element{domainPoints[i], compositionValues[i]}
+			evaluatorCurrent, evaluatorNext := currentRow, nextRow
+			if air.needsRowLayout() {
+				for col, column := range periodic {
+					currentPeriodic[col] = xfield.NewConst(column[i%len(column)])
+					nextPeriodic[col] = xfield.NewConst(column[next%len(column)])
+				}
+				var err error
+				if evaluatorCurrent, err = air.evaluatorRow(currentRow, currentPeriodic, challenges); err != nil {
+					return fmt.Errorf("failed to evaluate quotient at row %d: %w", i, err)
+				}
+				if evaluatorNext, err = air.evaluatorRow(nextRow, nextPeriodic, challenges); err != nil {
+					return fmt.Errorf("failed to evaluate quotient at row %d: %w", i, err)
+				}
+			}
+
+			codeword[i] = zerofiers.divide(air.weightedConstraintSums(evaluatorCurrent, evaluatorNext, weights), i)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	compositionPoly := polynomial.Interpolate(points)
 
-	// Step 3: Compute vanishing polynomial Z(X) for the trace domain
-	// Z(X) = X^n - 1 where n is the trace domain size
-	vanishingPoly := computeVanishingPolynomial(domains.Trace.Length)
+	return codeword, nil
+}
+
+// quotientZerofiers holds the inverses of the four zerofiers (see
+// EvaluateQuotientAt) at every point of the quotient domain
+//
+// The quotient domain is a base field coset, so the zerofiers are base field
+// values and are inverted together, once.
+type quotientZerofiers struct {
+	initial     []field.Element
+	consistency []field.Element
+	transition  []field.Element
+	terminal    []field.Element
+}
 
-	// Step 4: Divide composition by vanishing polynomial
-	// This should yield a polynomial (i.e., no remainder)
-	quotientPoly, remainder := compositionPoly.Divide(vanishingPoly)
+// newQuotientZerofiers computes the zerofier inverses over the quotient domain
+func newQuotientZerofiers(quotientDomain, traceDomain *ArithmeticDomain) (*quotientZerofiers, error) {
+	numRows := quotientDomain.Length
+	lastRowPoint := traceDomain.Generator.Inverse()
+	points := quotientDomain.Elements()
+
+	// The points' n-th powers repeat with period N/n, and so does X^n - 1
+	period := numRows / traceDomain.Length
+	denominators := make([]field.Element, 0, 2*numRows+period)
+	for _, x := range points {
+		denominators = append(denominators, x.Sub(field.One))
+	}
+	for _, x := range points {
+		denominators = append(denominators, x.Sub(lastRowPoint))
+	}
+	for _, x := range points[:period] {
+		denominators = append(denominators, traceDomain.ZerofierAt(x))
+	}
+	inverses, err := batchInverseField(denominators)
+	if err != nil {
+		return nil, fmt.Errorf("quotient domain intersects the trace domain: %w", err)
+	}
 
-	// Verify no remainder (for debugging)
-	// A non-zero remainder indicates a constraint violation
-	// In production, this should ideally be zero, but we log warnings for debugging
-	// rather than failing hard, as minor numerical precision issues can occur
-	if !remainder.IsZero() {
-		// Log the warning for debugging (helps identify constraint issues during development)
-		fmt.Fprintf(os.Stderr, "Warning: non-zero remainder in quotient division (degree: %d)\n", remainder.Degree())
-		// NOTE: In Triton VM's implementation, this is also logged rather than causing failure
-		// The FRI protocol will catch any actual constraint violations during verification
+	zerofiers := &quotientZerofiers{
+		initial:     inverses[:numRows],
+		terminal:    inverses[numRows : 2*numRows],
+		consistency: make([]field.Element, numRows),
+		transition:  make([]field.Element, numRows),
 	}
+	for i, x := range points {
+		zerofiers.consistency[i] = inverses[2*numRows+i%period]
+		zerofiers.transition[i] = x.Sub(lastRowPoint).Mul(zerofiers.consistency[i])
+	}
+	return zerofiers, nil
+}
 
-	// Return single quotient (in practice, we might split into multiple for efficiency)
-	return []*polynomial.Polynomial{quotientPoly}, nil
+// divide divides the weighted constraint sums at row i by their zerofiers
+// and adds them up
+func (z *quotientZerofiers) divide(sums [4]xfield.XFieldElement, i int) xfield.XFieldElement {
+	return sums[0].MulConst(z.initial[i]).
+		Add(sums[1].MulConst(z.consistency[i])).
+		Add(sums[2].MulConst(z.transition[i])).
+		Add(sums[3].MulConst(z.terminal[i]))
 }
 
-// computeVanishingPolynomial computes Z(X) = X^n - 1
-func computeVanishingPolynomial(domainSize int) *polynomial.Polynomial {
-	// Z(X) = X^n - 1
-	// Coefficients: [-1, 0, 0, ..., 0, 1]
-	coeffs := make([]field.Element, domainSize+1)
-	for i := 0; i < domainSize+1; i++ {
-		coeffs[i] = field.Zero
+// batchInverseField inverts base field values with a single inversion, like
+// BatchInverse
+func batchInverseField(values []field.Element) ([]field.Element, error) {
+	prefix := make([]field.Element, len(values))
+	product := field.One
+	for i, value := range values {
+		if value.IsZero() {
+			return nil, fmt.Errorf("cannot invert zero at index %d", i)
+		}
+		prefix[i] = product
+		product = product.Mul(value)
 	}
-	// constant term: -1 (represented as P - 1 in the field)
-	coeffs[0] = field.New(field.P - 1)
-	// X^n term: 1
-	coeffs[domainSize] = field.One
 
-	return polynomial.New(coeffs)
+	inverses := make([]field.Element, len(values))
+	inverse := product.Inverse()
+	for i := len(values) - 1; i >= 0; i-- {
+		inverses[i] = prefix[i].Mul(inverse)
+		inverse = inverse.Mul(values[i])
+	}
+	return inverses, nil
 }
 
 // EvaluateQuotientsAtPoint evaluates all quotient polynomials at a given point
@@ -368,37 +920,25 @@ func VerifyQuotientDegree(
 	return nil
 }
 
-// ParallelEvaluateQuotients evaluates multiple quotients over a domain in parallel
+// ParallelEvaluateQuotients evaluates multiple quotients over a domain in parallel,
+// on one worker per CPU
 func ParallelEvaluateQuotients(
 	quotients []*polynomial.Polynomial,
 	domain *ArithmeticDomain,
 ) ([][]field.Element, error) {
-	numQuotients := len(quotients)
-	results := make([][]field.Element, numQuotients)
-
-	var wg sync.WaitGroup
-	errors := make(chan error, numQuotients)
-
-	for i := 0; i < numQuotients; i++ {
-		wg.Add(1)
-		go func(idx int) {
-			defer wg.Done()
-
+	results := make([][]field.Element, len(quotients))
+	var pool *workerPool // the default options
+	err := pool.parallelFor(PhaseQuotient, len(quotients), func(start, end int) error {
+		for idx := start; idx < end; idx++ {
 			values, err := domain.Evaluate(quotients[idx])
 			if err != nil {
-				errors <- fmt.Errorf("failed to evaluate quotient %d: %w", idx, err)
-				return
+				return fmt.Errorf("failed to evaluate quotient %d: %w", idx, err)
 			}
-
 			results[idx] = values
-		}(i)
-	}
-
-	wg.Wait()
-	close(errors)
-
-	// Check for errors
-	if err := <-errors; err != nil {
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -12,6 +12,24 @@ func isPowerOfTwo(n int) bool {
 	return n > 0 && (n&(n-1)) == 0
 }
 
+// MaxLog2DomainLength is the two-adicity of the Goldilocks field: the
+// longest domain with a root of unity has 2^32 elements
+const MaxLog2DomainLength = 32
+
+// primitiveRootOfUnity returns a primitive n-th root of unity for a power-of-two n.
+//
+// vybium-crypto's field.PrimitiveRootOfUnity wraps the canonical values of its
+// root table with NewFromRaw, which reinterprets them as Montgomery form and
+// yields elements that are not roots of unity at all. The table itself is
+// correct, so the values are lifted with field.New instead.
+func primitiveRootOfUnity(n int) field.Element {
+	root, ok := field.PrimitiveRoots[uint64(n)]
+	if !ok {
+		return field.Zero
+	}
+	return field.New(root)
+}
+
 func nextPowerOfTwo(n int) int {
 	if n <= 1 {
 		return 1
@@ -22,6 +40,7 @@ func nextPowerOfTwo(n int) int {
 	n |= n >> 4
 	n |= n >> 8
 	n |= n >> 16
+	n |= n >> 32
 	n++
 	return n
 }
@@ -46,9 +65,11 @@ func NewArithmeticDomain(length int) (*ArithmeticDomain, error) {
 	if !isPowerOfTwo(length) {
 		return nil, fmt.Errorf("domain length must be a power of 2, got %d", length)
 	}
+	if length > 1<<MaxLog2DomainLength {
+		return nil, fmt.Errorf("domain length %d exceeds the maximum 2^%d", length, MaxLog2DomainLength)
+	}
 
-	// Get primitive root of unity for this length from vybium-crypto
-	generator := field.PrimitiveRootOfUnity(uint64(length))
+	generator := primitiveRootOfUnity(length)
 
 	return &ArithmeticDomain{
 		Offset:    field.One,
@@ -89,9 +110,12 @@ func (d *ArithmeticDomain) Halve() (*ArithmeticDomain, error) {
 // Double returns a domain with double the length
 func (d *ArithmeticDomain) Double() (*ArithmeticDomain, error) {
 	doubleLength := d.Length * 2
+	if doubleLength > 1<<MaxLog2DomainLength {
+		return nil, fmt.Errorf("domain length %d exceeds the maximum 2^%d", doubleLength, MaxLog2DomainLength)
+	}
 
 	// Get generator for double length
-	generator := field.PrimitiveRootOfUnity(uint64(doubleLength))
+	generator := primitiveRootOfUnity(doubleLength)
 
 	return &ArithmeticDomain{
 		Offset:    d.Offset,
@@ -112,16 +136,29 @@ func (d *ArithmeticDomain) Elements() []field.Element {
 }
 
 // Evaluate evaluates a polynomial (in coefficient form) over the entire domain
+//
+// This is one NTT over the coset (see evaluateCoefficients).
 func (d *ArithmeticDomain) Evaluate(poly *polynomial.Polynomial) ([]field.Element, error) {
-	// Use direct evaluation (NTT would be more efficient but requires implementation)
-	domainElements := d.Elements()
-	values := make([]field.Element, len(domainElements))
+	return d.evaluateCoefficients(poly.Coefficients())
+}
 
-	for i, x := range domainElements {
-		values[i] = poly.Evaluate(x)
+// Interpolate returns the unique polynomial of degree < length that takes the
+// given values on the domain, i.e. poly(offset * generator^i) = values[i].
+//
+// This is an inverse NTT over the coset followed by undoing the offset.
+func (d *ArithmeticDomain) Interpolate(values []field.Element) (*polynomial.Polynomial, error) {
+	coefficients, err := d.interpolateValues(values)
+	if err != nil {
+		return nil, err
 	}
+	return polynomial.New(coefficients), nil
+}
 
-	return values, nil
+// ZerofierAt evaluates the domain's vanishing polynomial
+// (X - offset*g^0)...(X - offset*g^(n-1)) = X^n - offset^n at the given point.
+func (d *ArithmeticDomain) ZerofierAt(point field.Element) field.Element {
+	n := uint64(d.Length)
+	return point.ModPow(n).Sub(d.Offset.ModPow(n))
 }
 
 // String returns a human-readable representation
@@ -138,69 +175,72 @@ func (d *ArithmeticDomain) String() string {
 // - quotient: for computing constraint quotients
 // - fri: for the FRI low-degree test
 type ProverDomains struct {
-	// Trace domain: dictated by the AET height
+	// Trace domain: dictated by the AET height, row i lives at generator^i
 	Trace *ArithmeticDomain
 
-	// Randomized trace domain: includes trace randomizers for zero-knowledge
-	// Must be exactly 2x the trace domain length
+	// Randomized trace domain: large enough to hold the trace plus its
+	// randomizers; its length bounds the degree of every randomized column
 	RandomizedTrace *ArithmeticDomain
 
-	// Quotient domain: large enough for constraint computations
+	// Quotient domain: where constraint quotients are evaluated. Its length
+	// is the number of quotient segments times the randomized trace length,
+	// enough to interpolate the combined quotient. It shares the FRI domain's
+	// offset, so one of the two is a subgroup coset of the other
 	Quotient *ArithmeticDomain
 
-	// FRI domain: for the FRI protocol
+	// FRI domain: for the FRI protocol. A coset disjoint from the trace domain
 	FRI *ArithmeticDomain
 }
 
 // DeriveProverDomains computes all domains needed for proving
 //
 // Domain derivation follows standard STARK practices:
-// 1. Compute randomized trace length (padded_height + num_randomizers, rounded to power of 2)
-// 2. Trace domain is half of randomized trace domain (CRITICAL: must be derived, not created directly)
-// 3. Quotient domain length is next power of 2 >= max_degree
-// 4. FRI domain is provided by FRI parameters
+// 1. Trace domain is the subgroup of order padded_height
+// 2. Randomized trace length is padded_height + num_randomizers, rounded to a power of 2
+// 3. FRI domain is provided by FRI parameters and must exceed the randomized trace
+// 4. Quotient domain is num_quotient_segments times the randomized trace, on the FRI offset
 func DeriveProverDomains(
 	paddedHeight int,
 	numTraceRandomizers int,
 	friDomain *ArithmeticDomain,
-	maxDegree int,
+	numQuotientSegments int,
 ) (*ProverDomains, error) {
+	traceDomain, err := NewArithmeticDomain(paddedHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace domain: %w", err)
+	}
+
 	// Randomized trace domain includes both trace and randomizers
-	// This must be computed first, then trace domain is derived from it
-	randomizedTraceLen := paddedHeight + numTraceRandomizers
-	randomizedTraceLen = nextPowerOfTwo(randomizedTraceLen)
+	randomizedTraceLen := nextPowerOfTwo(paddedHeight + numTraceRandomizers)
 	randomizedTraceDomain, err := NewArithmeticDomain(randomizedTraceLen)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create randomized trace domain: %w", err)
 	}
 
-	// Trace domain is derived by halving the randomized trace domain
-	// This ensures proper algebraic relationship between domains
-	// Note: trace domain length may be > padded height if numTraceRandomizers > paddedHeight
-
-	traceDomain, err := randomizedTraceDomain.Halve()
-	if err != nil {
-		return nil, fmt.Errorf("failed to halve randomized trace domain: %w", err)
+	if friDomain.Length <= randomizedTraceLen {
+		return nil, fmt.Errorf("FRI domain length %d too small for randomized trace length %d",
+			friDomain.Length, randomizedTraceLen)
 	}
 
-	// Quotient domain length is next power of 2 >= max_degree
-	quotientDomainLen := nextPowerOfTwo(maxDegree)
-	quotientDomain, err := NewArithmeticDomain(quotientDomainLen)
+	quotientDomain, err := NewArithmeticDomain(numQuotientSegments * randomizedTraceLen)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create quotient domain: %w", err)
 	}
 
-	// Apply FRI offset to quotient domain (important for soundness)
-	quotientDomain = quotientDomain.WithOffset(friDomain.Offset)
-
 	return &ProverDomains{
 		Trace:           traceDomain,
 		RandomizedTrace: randomizedTraceDomain,
-		Quotient:        quotientDomain,
+		Quotient:        quotientDomain.WithOffset(friDomain.Offset),
 		FRI:             friDomain,
 	}, nil
 }
 
+// NumQuotientSegments returns the number of segments the combined quotient
+// is split into
+func (pd *ProverDomains) NumQuotientSegments() int {
+	return pd.Quotient.Length / pd.RandomizedTrace.Length
+}
+
 // String returns a human-readable representation of all domains
 func (pd *ProverDomains) String() string {
 	return fmt.Sprintf(`ProverDomains{
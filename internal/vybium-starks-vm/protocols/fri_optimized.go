@@ -3,8 +3,6 @@ package protocols
 
 import (
 	"fmt"
-	"runtime"
-	"sync"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
@@ -13,7 +11,7 @@ import (
 // OptimizedFRIProtocol implements the FRI protocol with performance optimizations
 type OptimizedFRIProtocol struct {
 	*FRIProtocol // Embed base FRI protocol
-	numWorkers   int
+	pool         *workerPool
 }
 
 // NewOptimizedFRIProtocol creates a new optimized FRI protocol
@@ -26,14 +24,20 @@ func NewOptimizedFRIProtocol(
 
 	return &OptimizedFRIProtocol{
 		FRIProtocol: baseFRI,
-		numWorkers:  runtime.NumCPU(),
+		pool:        newWorkerPool(DefaultProverOptions()),
 	}
 }
 
-// SetNumWorkers sets the number of parallel workers (default: NumCPU)
+// SetOptions sets the workers and progress callback, as for a Prover
+func (ofri *OptimizedFRIProtocol) SetOptions(options ProverOptions) {
+	ofri.pool = newWorkerPool(options)
+}
+
+// SetNumWorkers sets the number of parallel workers (default: NumCPU),
+// keeping the progress callback
 func (ofri *OptimizedFRIProtocol) SetNumWorkers(n int) {
 	if n > 0 {
-		ofri.numWorkers = n
+		ofri.pool.numWorkers = n
 	}
 }
 
@@ -59,67 +63,47 @@ func (ofri *OptimizedFRIProtocol) ParallelFoldFunction(
 		return nil, fmt.Errorf("batch inversion failed: %w", err)
 	}
 
-	// Parallel processing using goroutines
-	chunkSize := (n/2 + ofri.numWorkers - 1) / ofri.numWorkers
+	// Parallel processing on the worker pool
 	result := make([]*core.FieldElement, n/2)
 
-	var wg sync.WaitGroup
-	errChan := make(chan error, ofri.numWorkers)
-
-	for w := 0; w < ofri.numWorkers; w++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-
-			start := workerID * chunkSize
-			if start >= n/2 {
-				return
-			}
-			end := min(start+chunkSize, n/2)
-
-			// Precompute constants for this worker
-			one := ofri.field.One()
-			two := ofri.field.NewElementFromInt64(2)
-			twoInv, err := two.Inv()
-			if err != nil {
-				errChan <- fmt.Errorf("worker %d: failed to compute 2^(-1): %w", workerID, err)
-				return
-			}
-
-			// Optimized folding formula from TR17-134
-			// result[i] = (f[i] + f[n/2+i])/2 + challenge * (f[i] - f[n/2+i])/(2*domain[i])
-			//
-			// Rewritten as:
-			// scaled_offset_inv = challenge * domain[i]^(-1)
-			// left_coeff = 1 + scaled_offset_inv
-			// right_coeff = 1 - scaled_offset_inv
-			// result[i] = (left_coeff * f[i] + right_coeff * f[n/2+i]) / 2
-
-			for i := start; i < end; i++ {
-				// Compute scaled_offset_inv = challenge * domain_inverses[i]
-				scaledOffsetInv := challenge.Mul(domainInverses[i])
+	err = ofri.pool.parallelFor(PhaseFRI, n/2, func(start, end int) error {
+		// Precompute constants for this chunk
+		one := ofri.field.One()
+		two := ofri.field.NewElementFromInt64(2)
+		twoInv, err := two.Inv()
+		if err != nil {
+			return fmt.Errorf("failed to compute 2^(-1): %w", err)
+		}
 
-				// Compute left coefficient: (1 + scaled_offset_inv)
-				leftCoeff := one.Add(scaledOffsetInv)
+		// Optimized folding formula from TR17-134
+		// result[i] = (f[i] + f[n/2+i])/2 + challenge * (f[i] - f[n/2+i])/(2*domain[i])
+		//
+		// Rewritten as:
+		// scaled_offset_inv = challenge * domain[i]^(-1)
+		// left_coeff = 1 + scaled_offset_inv
+		// right_coeff = 1 - scaled_offset_inv
+		// result[i] = (left_coeff * f[i] + right_coeff * f[n/2+i]) / 2
 
-				// Compute right coefficient: (1 - scaled_offset_inv)
-				rightCoeff := one.Sub(scaledOffsetInv)
+		for i := start; i < end; i++ {
+			// Compute scaled_offset_inv = challenge * domain_inverses[i]
+			scaledOffsetInv := challenge.Mul(domainInverses[i])
 
-				// Compute weighted sum
-				leftSummand := leftCoeff.Mul(function[i])
-				rightSummand := rightCoeff.Mul(function[n/2+i])
+			// Compute left coefficient: (1 + scaled_offset_inv)
+			leftCoeff := one.Add(scaledOffsetInv)
 
-				// Divide by 2
-				result[i] = leftSummand.Add(rightSummand).Mul(twoInv)
-			}
-		}(w)
-	}
+			// Compute right coefficient: (1 - scaled_offset_inv)
+			rightCoeff := one.Sub(scaledOffsetInv)
 
-	wg.Wait()
-	close(errChan)
+			// Compute weighted sum
+			leftSummand := leftCoeff.Mul(function[i])
+			rightSummand := rightCoeff.Mul(function[n/2+i])
 
-	// Check for errors
-	if err := <-errChan; err != nil {
+			// Divide by 2
+			result[i] = leftSummand.Add(rightSummand).Mul(twoInv)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -212,28 +196,16 @@ func (ofri *OptimizedFRIProtocol) parallelMerkleCommitment(
 	leaves := make([][]byte, n)
 
 	// Parallel conversion
-	chunkSize := (n + ofri.numWorkers - 1) / ofri.numWorkers
-	var wg sync.WaitGroup
-
-	for w := 0; w < ofri.numWorkers; w++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-
-			start := workerID * chunkSize
-			if start >= n {
-				return
-			}
-			end := min(start+chunkSize, n)
-
-			for i := start; i < end; i++ {
-				leaves[i] = codeword[i].Bytes()
-			}
-		}(w)
+	err := ofri.pool.parallelFor(PhaseFRI, n, func(start, end int) error {
+		for i := start; i < end; i++ {
+			leaves[i] = codeword[i].Bytes()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	wg.Wait()
-
 	// Create Merkle tree (this uses parallel construction internally)
 	tree, err := core.NewMerkleTree(leaves)
 	if err != nil {
@@ -263,40 +235,21 @@ func (ofri *OptimizedFRIProtocol) OptimizedQuery(
 	responses := make([]FRIQueryResponse, len(queryIndices))
 
 	// Parallel query processing
-	chunkSize := (len(queryIndices) + ofri.numWorkers - 1) / ofri.numWorkers
-	var wg sync.WaitGroup
-	errChan := make(chan error, ofri.numWorkers)
-
-	for w := 0; w < ofri.numWorkers; w++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-
-			start := workerID * chunkSize
-			if start >= len(queryIndices) {
-				return
-			}
-			end := min(start+chunkSize, len(queryIndices))
-
-			for i := start; i < end; i++ {
-				queryIdx := queryIndices[i]
+	err := ofri.pool.parallelFor(PhaseFRI, len(queryIndices), func(start, end int) error {
+		for i := start; i < end; i++ {
+			queryIdx := queryIndices[i]
 
-				// Generate response for this query
-				response, err := ofri.generateQueryResponse(layers, queryIdx)
-				if err != nil {
-					errChan <- fmt.Errorf("query %d failed: %w", queryIdx, err)
-					return
-				}
-
-				responses[i] = response
+			// Generate response for this query
+			response, err := ofri.generateQueryResponse(layers, queryIdx)
+			if err != nil {
+				return fmt.Errorf("query %d failed: %w", queryIdx, err)
 			}
-		}(w)
-	}
 
-	wg.Wait()
-	close(errChan)
-
-	if err := <-errChan; err != nil {
+			responses[i] = response
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -347,37 +300,17 @@ func (ofri *OptimizedFRIProtocol) OptimizedVerify(
 ) (bool, error) {
 	// Parallel verification of query responses
 	results := make([]bool, len(responses))
-	chunkSize := (len(responses) + ofri.numWorkers - 1) / ofri.numWorkers
-
-	var wg sync.WaitGroup
-	errChan := make(chan error, ofri.numWorkers)
-
-	for w := 0; w < ofri.numWorkers; w++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-
-			start := workerID * chunkSize
-			if start >= len(responses) {
-				return
-			}
-			end := min(start+chunkSize, len(responses))
-
-			for i := start; i < end; i++ {
-				valid, err := ofri.verifyQueryResponse(layers, responses[i])
-				if err != nil {
-					errChan <- fmt.Errorf("verification of query %d failed: %w", i, err)
-					return
-				}
-				results[i] = valid
+	err := ofri.pool.parallelFor(PhaseFRI, len(responses), func(start, end int) error {
+		for i := start; i < end; i++ {
+			valid, err := ofri.verifyQueryResponse(layers, responses[i])
+			if err != nil {
+				return fmt.Errorf("verification of query %d failed: %w", i, err)
 			}
-		}(w)
-	}
-
-	wg.Wait()
-	close(errChan)
-
-	if err := <-errChan; err != nil {
+			results[i] = valid
+		}
+		return nil
+	})
+	if err != nil {
 		return false, err
 	}
 
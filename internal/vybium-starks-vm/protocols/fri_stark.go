@@ -0,0 +1,450 @@
+package protocols
+
+import (
+	"fmt"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/merkle"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+)
+
+// FRI is the Goldilocks-native FRI low-degree test used by the STARK prover and verifier
+//
+// Following Triton VM's FRI, the prover commits to a sequence of codewords,
+// each one half the length of its predecessor, folding with a challenge that
+// is drawn from the ProofStream sponge after the predecessor's Merkle root has
+// been enqueued. The last codeword is sent in the clear together with its
+// interpolant, whose degree the verifier checks directly. The verifier then
+// samples query indices from the sponge and checks collinearity of the
+// revealed values in every round. Codewords and folding challenges are
+// extension field elements over the base field FRI domain.
+//
+// Proof items, in order:
+//  1. ProofItemMerkleRoot for every round's codeword, followed by the sampled
+//     folding challenge (not sent), and one more root for the last codeword
+//  2. ProofItemFRICodeword holding the last codeword
+//  3. ProofItemFRIPolynomial holding the last codeword's interpolant
+//  4. ProofItemFRIResponse for every folding round
+type FRI struct {
+	domain                *ArithmeticDomain
+	expansionFactor       int
+	numCollinearityChecks int
+
+	// Workers folding and hashing runs on; nil uses the default options
+	pool *workerPool
+}
+
+// FRIResponse is the prover's answer to the FRI queries of one round
+//
+// For every query index i, the two values that fold together,
+// codeword[i mod n/2] and codeword[i mod n/2 + n/2], are revealed in that
+// order, each with a Merkle authentication path.
+type FRIResponse struct {
+	// AuthenticationPaths holds one authentication path per revealed leaf
+	AuthenticationPaths [][]hash.Digest
+
+	// RevealedLeaves are the codeword values at the revealed indices
+	RevealedLeaves []xfield.XFieldElement
+}
+
+// NewFRI creates a FRI instance over the given domain
+func NewFRI(domain *ArithmeticDomain, expansionFactor, numCollinearityChecks int) (*FRI, error) {
+	if domain == nil {
+		return nil, fmt.Errorf("FRI domain cannot be nil")
+	}
+	if expansionFactor < 2 || !isPowerOfTwo(expansionFactor) {
+		return nil, fmt.Errorf("expansion factor must be a power of 2 >= 2, got %d", expansionFactor)
+	}
+	if domain.Length < expansionFactor {
+		return nil, fmt.Errorf("FRI domain length %d smaller than expansion factor %d", domain.Length, expansionFactor)
+	}
+	if numCollinearityChecks < 1 {
+		return nil, fmt.Errorf("number of collinearity checks must be at least 1, got %d", numCollinearityChecks)
+	}
+
+	return &FRI{
+		domain:                domain,
+		expansionFactor:       expansionFactor,
+		numCollinearityChecks: numCollinearityChecks,
+	}, nil
+}
+
+// NumRounds returns the number of folding rounds
+//
+// Folding stops before the Merkle authentication paths of a round cost more
+// than sending the codeword outright, as in Triton VM.
+func (fri *FRI) NumRounds() int {
+	maxNumRounds := ilog2(fri.domain.Length) - ilog2(fri.expansionFactor)
+	numRoundsCheckingMostLocations := ilog2(fri.numCollinearityChecks) - 1
+	if numRoundsCheckingMostLocations < 0 {
+		numRoundsCheckingMostLocations = 0
+	}
+	if maxNumRounds <= numRoundsCheckingMostLocations {
+		return 0
+	}
+	return maxNumRounds - numRoundsCheckingMostLocations
+}
+
+// LastRoundMaxDegree returns the maximal degree of the last codeword's interpolant
+func (fri *FRI) LastRoundMaxDegree() int {
+	lastLength := fri.domain.Length >> fri.NumRounds()
+	return lastLength/fri.expansionFactor - 1
+}
+
+// Prove runs FRI on the codeword, enqueuing all items into the proof stream
+//
+// Returns the indices queried in the first round, at which the prover must
+// open everything the codeword was derived from.
+func (fri *FRI) Prove(codeword []xfield.XFieldElement, proofStream *ProofStream) ([]int, error) {
+	if len(codeword) != fri.domain.Length {
+		return nil, fmt.Errorf("codeword length %d doesn't match FRI domain length %d", len(codeword), fri.domain.Length)
+	}
+
+	// Commit phase
+	numRounds := fri.NumRounds()
+	codewords := make([][]xfield.XFieldElement, 0, numRounds+1)
+	trees := make([]*merkle.MerkleTree, 0, numRounds+1)
+	domain := fri.domain
+	for round := 0; ; round++ {
+		tree, err := commitCodeword(fri.pool, codeword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to commit to FRI round %d: %w", round, err)
+		}
+		if err := proofStream.Enqueue(ProofItem{Type: ProofItemMerkleRoot, Data: digestToBytes(tree.Root())}); err != nil {
+			return nil, fmt.Errorf("failed to enqueue FRI root %d: %w", round, err)
+		}
+		codewords = append(codewords, codeword)
+		trees = append(trees, tree)
+
+		if round == numRounds {
+			break
+		}
+
+		challenge, err := sampleFoldingChallenge(proofStream)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample folding challenge %d: %w", round, err)
+		}
+		if codeword, err = foldCodeword(fri.pool, codeword, domain, challenge); err != nil {
+			return nil, fmt.Errorf("failed to fold FRI round %d: %w", round, err)
+		}
+		domain, err = domain.Halve()
+		if err != nil {
+			return nil, fmt.Errorf("failed to halve FRI domain: %w", err)
+		}
+	}
+
+	// Send the last codeword and its interpolant
+	lastCodeword := codewords[numRounds]
+	lastPolynomial, err := domain.interpolateXField(lastCodeword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to interpolate last FRI codeword: %w", err)
+	}
+	if err := proofStream.Enqueue(ProofItem{Type: ProofItemFRICodeword, Data: lastCodeword}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue last FRI codeword: %w", err)
+	}
+	coefficients := lastPolynomialCoefficients(lastPolynomial, fri.LastRoundMaxDegree())
+	if err := proofStream.Enqueue(ProofItem{Type: ProofItemFRIPolynomial, Data: coefficients}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue last FRI polynomial: %w", err)
+	}
+
+	// Query phase
+	indices, err := proofStream.SampleIndices(fri.domain.Length, fri.numCollinearityChecks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample FRI query indices: %w", err)
+	}
+	for round := 0; round < numRounds; round++ {
+		response, err := fri.queryRound(codewords[round], trees[round], indices)
+		if err != nil {
+			return nil, fmt.Errorf("failed to answer FRI queries of round %d: %w", round, err)
+		}
+		if err := proofStream.Enqueue(ProofItem{Type: ProofItemFRIResponse, Data: response}); err != nil {
+			return nil, fmt.Errorf("failed to enqueue FRI response %d: %w", round, err)
+		}
+	}
+
+	return indices, nil
+}
+
+// queryRound reveals both folding partners of every query index in one round
+func (fri *FRI) queryRound(codeword []xfield.XFieldElement, tree *merkle.MerkleTree, indices []int) (*FRIResponse, error) {
+	half := len(codeword) / 2
+	response := &FRIResponse{
+		AuthenticationPaths: make([][]hash.Digest, 0, 2*len(indices)),
+		RevealedLeaves:      make([]xfield.XFieldElement, 0, 2*len(indices)),
+	}
+	for _, index := range indices {
+		low := index % half
+		for _, leaf := range []int{low, low + half} {
+			path, err := tree.AuthenticationPath(uint64(leaf))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get authentication path for leaf %d: %w", leaf, err)
+			}
+			response.AuthenticationPaths = append(response.AuthenticationPaths, path)
+			response.RevealedLeaves = append(response.RevealedLeaves, codeword[leaf])
+		}
+	}
+	return response, nil
+}
+
+// Verify checks a FRI proof read from the proof stream
+//
+// Returns the indices queried in the first round and the first-round codeword
+// values revealed at them, which the caller must link to the codeword it
+// expects FRI to be about.
+func (fri *FRI) Verify(proofStream *ProofStream) ([]int, []xfield.XFieldElement, error) {
+	numRounds := fri.NumRounds()
+
+	// Commit phase: read roots and replay the folding challenges
+	roots := make([]hash.Digest, 0, numRounds+1)
+	challenges := make([]xfield.XFieldElement, 0, numRounds)
+	for round := 0; round <= numRounds; round++ {
+		item, err := dequeueItem(proofStream, ProofItemMerkleRoot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read FRI root %d: %w", round, err)
+		}
+		rootBytes, _ := item.Data.([]byte)
+		root, err := digestFromBytes(rootBytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid FRI root %d: %w", round, err)
+		}
+		roots = append(roots, root)
+
+		if round < numRounds {
+			challenge, err := sampleFoldingChallenge(proofStream)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to sample folding challenge %d: %w", round, err)
+			}
+			challenges = append(challenges, challenge)
+		}
+	}
+
+	// Last codeword must be committed to by the last root
+	lastDomain := fri.domain
+	for round := 0; round < numRounds; round++ {
+		var err error
+		if lastDomain, err = lastDomain.Halve(); err != nil {
+			return nil, nil, fmt.Errorf("failed to halve FRI domain: %w", err)
+		}
+	}
+	item, err := dequeueItem(proofStream, ProofItemFRICodeword)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read last FRI codeword: %w", err)
+	}
+	lastCodeword, ok := item.Data.([]xfield.XFieldElement)
+	if !ok || len(lastCodeword) != lastDomain.Length {
+		return nil, nil, fmt.Errorf("last FRI codeword must have %d elements", lastDomain.Length)
+	}
+	lastTree, err := commitCodeword(fri.pool, lastCodeword)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to commit to last FRI codeword: %w", err)
+	}
+	if !lastTree.Root().Equal(roots[numRounds]) {
+		return nil, nil, fmt.Errorf("last FRI codeword does not match its Merkle root")
+	}
+
+	// Last codeword must be a low-degree polynomial's evaluations
+	item, err = dequeueItem(proofStream, ProofItemFRIPolynomial)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read last FRI polynomial: %w", err)
+	}
+	coefficients, ok := item.Data.([]xfield.XFieldElement)
+	if !ok || len(coefficients) != fri.LastRoundMaxDegree()+1 {
+		return nil, nil, fmt.Errorf("last FRI polynomial must have exactly %d coefficients", fri.LastRoundMaxDegree()+1)
+	}
+	lastEvaluations, err := lastDomain.evaluateXField(coefficients)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to evaluate last FRI polynomial: %w", err)
+	}
+	for i := range lastCodeword {
+		if !lastEvaluations[i].Equal(lastCodeword[i]) {
+			return nil, nil, fmt.Errorf("last FRI codeword is not the evaluation of the last FRI polynomial")
+		}
+	}
+
+	// Query phase
+	indices, err := proofStream.SampleIndices(fri.domain.Length, fri.numCollinearityChecks)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sample FRI query indices: %w", err)
+	}
+
+	var firstRoundValues []xfield.XFieldElement
+	domain := fri.domain
+	for round := 0; round < numRounds; round++ {
+		item, err := dequeueItem(proofStream, ProofItemFRIResponse)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read FRI response %d: %w", round, err)
+		}
+		response, ok := item.Data.(*FRIResponse)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid FRI response %d data type", round)
+		}
+		if len(response.RevealedLeaves) != 2*len(indices) || len(response.AuthenticationPaths) != 2*len(indices) {
+			return nil, nil, fmt.Errorf("FRI response %d must reveal %d leaves", round, 2*len(indices))
+		}
+
+		half := domain.Length / 2
+		for q, index := range indices {
+			low := index % half
+			lowValue := response.RevealedLeaves[2*q]
+			highValue := response.RevealedLeaves[2*q+1]
+			for k, leaf := range []int{low, low + half} {
+				digest := hashCodewordLeaf(response.RevealedLeaves[2*q+k])
+				if !merkle.VerifyInclusionProof(roots[round], uint64(leaf), digest, response.AuthenticationPaths[2*q+k]) {
+					return nil, nil, fmt.Errorf("FRI round %d: authentication path for leaf %d is invalid", round, leaf)
+				}
+			}
+
+			if round == 0 {
+				if index < half {
+					firstRoundValues = append(firstRoundValues, lowValue)
+				} else {
+					firstRoundValues = append(firstRoundValues, highValue)
+				}
+			}
+
+			// Collinearity: (x, f(x)), (-x, f(-x)) and (α, f'(x²)) lie on one line
+			x := domain.Offset.Mul(domain.Generator.ModPow(uint64(low)))
+			folded := foldPair(lowValue, highValue, x, challenges[round])
+
+			var expected xfield.XFieldElement
+			if round+1 < numRounds {
+				next, err := dequeuedResponseValue(proofStream, round+1, q, low, half/2)
+				if err != nil {
+					return nil, nil, err
+				}
+				expected = next
+			} else {
+				expected = lastCodeword[low]
+			}
+			if !folded.Equal(expected) {
+				return nil, nil, fmt.Errorf("FRI round %d: collinearity check failed for query %d", round, q)
+			}
+		}
+
+		if domain, err = domain.Halve(); err != nil {
+			return nil, nil, fmt.Errorf("failed to halve FRI domain: %w", err)
+		}
+	}
+
+	if numRounds == 0 {
+		for _, index := range indices {
+			firstRoundValues = append(firstRoundValues, lastCodeword[index])
+		}
+	}
+
+	return indices, firstRoundValues, nil
+}
+
+// dequeuedResponseValue peeks at the value the next round's response reveals
+// at the given index, without consuming the response
+//
+// The next round's response pairs index i with its partner i ± n/2, so the
+// value sits in the low or high slot depending on which half i falls into.
+func dequeuedResponseValue(proofStream *ProofStream, round, query, index, half int) (xfield.XFieldElement, error) {
+	if proofStream.ItemsIndex >= len(proofStream.Items) {
+		return xfield.Zero, fmt.Errorf("missing FRI response %d", round)
+	}
+	item := proofStream.Items[proofStream.ItemsIndex]
+	response, ok := item.Data.(*FRIResponse)
+	if item.Type != ProofItemFRIResponse || !ok {
+		return xfield.Zero, fmt.Errorf("expected FRI response %d, got proof item type %d", round, item.Type)
+	}
+	if 2*query+1 >= len(response.RevealedLeaves) {
+		return xfield.Zero, fmt.Errorf("FRI response %d reveals too few leaves", round)
+	}
+	if index < half {
+		return response.RevealedLeaves[2*query], nil
+	}
+	return response.RevealedLeaves[2*query+1], nil
+}
+
+// foldCodeword folds a codeword over a domain into one of half the length
+//
+// For x in the first half of the domain, -x sits in the second half, and the
+// folded codeword at x² is the value at α of the line through (x, f(x)) and
+// (-x, f(-x)).
+func foldCodeword(
+	pool *workerPool,
+	codeword []xfield.XFieldElement,
+	domain *ArithmeticDomain,
+	challenge xfield.XFieldElement,
+) ([]xfield.XFieldElement, error) {
+	half := len(codeword) / 2
+	folded := make([]xfield.XFieldElement, half)
+	err := pool.parallelFor(PhaseFRI, half, func(start, end int) error {
+		x := domain.Offset.Mul(domain.Generator.ModPow(uint64(start)))
+		for i := start; i < end; i++ {
+			folded[i] = foldPair(codeword[i], codeword[i+half], x, challenge)
+			x = x.Mul(domain.Generator)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return folded, nil
+}
+
+// foldPair computes ((1 + α/x)·f(x) + (1 - α/x)·f(-x)) / 2
+func foldPair(value, partnerValue xfield.XFieldElement, x field.Element, challenge xfield.XFieldElement) xfield.XFieldElement {
+	alphaOverX := challenge.MulConst(x.Inverse())
+	left := xfield.One.Add(alphaOverX).Mul(value)
+	right := xfield.One.Sub(alphaOverX).Mul(partnerValue)
+	return left.Add(right).MulConst(field.New(2).Inverse())
+}
+
+// sampleFoldingChallenge draws one folding challenge from the proof stream
+func sampleFoldingChallenge(proofStream *ProofStream) (xfield.XFieldElement, error) {
+	scalars, err := proofStream.SampleScalars(1)
+	if err != nil {
+		return xfield.Zero, err
+	}
+	if len(scalars) != 1 {
+		return xfield.Zero, fmt.Errorf("expected 1 scalar, got %d", len(scalars))
+	}
+	return scalars[0], nil
+}
+
+// lastPolynomialCoefficients returns exactly maxDegree+1 coefficients
+//
+// An honest prover's polynomial fits; a dishonest one's is truncated, which
+// the verifier detects when evaluating it on the last domain.
+func lastPolynomialCoefficients(poly []xfield.XFieldElement, maxDegree int) []xfield.XFieldElement {
+	coefficients := make([]xfield.XFieldElement, maxDegree+1)
+	copy(coefficients, poly)
+	return coefficients
+}
+
+// commitCodeword builds a Merkle tree with one leaf per codeword element
+func commitCodeword(pool *workerPool, codeword []xfield.XFieldElement) (*merkle.MerkleTree, error) {
+	leaves := make([]hash.Digest, len(codeword))
+	err := pool.parallelFor(PhaseFRI, len(codeword), func(start, end int) error {
+		for i := start; i < end; i++ {
+			leaves[i] = hashCodewordLeaf(codeword[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merkle.New(leaves)
+}
+
+// hashCodewordLeaf hashes a single codeword element, as its coefficients,
+// into a Merkle leaf
+func hashCodewordLeaf(value xfield.XFieldElement) hash.Digest {
+	return hash.HashVarlen(value.Coefficients[:])
+}
+
+// dequeueItem dequeues the next proof item and checks its type
+func dequeueItem(proofStream *ProofStream, itemType ProofItemType) (ProofItem, error) {
+	item, err := proofStream.Dequeue()
+	if err != nil {
+		return ProofItem{}, err
+	}
+	if item.Type != itemType {
+		return ProofItem{}, fmt.Errorf("expected proof item type %d, got %d", itemType, item.Type)
+	}
+	return item, nil
+}
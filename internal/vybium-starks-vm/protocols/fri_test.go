@@ -4,6 +4,8 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 )
 
@@ -69,3 +71,90 @@ func TestFRIProtocolParameters(t *testing.T) {
 		}
 	})
 }
+
+func TestFRIProveVerify(t *testing.T) {
+	domain, err := NewArithmeticDomain(1024)
+	if err != nil {
+		t.Fatalf("Failed to create domain: %v", err)
+	}
+	domain = domain.WithOffset(field.Generator())
+
+	fri, err := NewFRI(domain, 4, 16)
+	if err != nil {
+		t.Fatalf("Failed to create FRI: %v", err)
+	}
+	if fri.NumRounds() < 2 {
+		t.Fatalf("Expected several folding rounds, got %d", fri.NumRounds())
+	}
+
+	codewordOfDegree := func(degree int) []xfield.XFieldElement {
+		coefficients := make([]xfield.XFieldElement, degree+1)
+		for i := range coefficients {
+			coefficients[i] = xfield.New([xfield.ExtensionDegree]field.Element{
+				field.New(uint64(3*i*i + 7)), field.New(uint64(i + 1)), field.New(uint64(5 * i)),
+			})
+		}
+		codeword, err := domain.evaluateXField(coefficients)
+		if err != nil {
+			t.Fatalf("Failed to evaluate polynomial: %v", err)
+		}
+		return codeword
+	}
+
+	prove := func(codeword []xfield.XFieldElement) *ProofStream {
+		proofStream := NewProofStream()
+		if _, err := fri.Prove(codeword, proofStream); err != nil {
+			t.Fatalf("FRI prover failed: %v", err)
+		}
+		verifierStream := NewProofStream()
+		verifierStream.Items = proofStream.Items
+		return verifierStream
+	}
+
+	t.Run("LowDegreeCodewordVerifies", func(t *testing.T) {
+		codeword := codewordOfDegree(domain.Length/4 - 1)
+		proofStream := NewProofStream()
+		proverIndices, err := fri.Prove(codeword, proofStream)
+		if err != nil {
+			t.Fatalf("FRI prover failed: %v", err)
+		}
+		verifierStream := NewProofStream()
+		verifierStream.Items = proofStream.Items
+
+		indices, values, err := fri.Verify(verifierStream)
+		if err != nil {
+			t.Fatalf("Honest FRI proof rejected: %v", err)
+		}
+		if len(indices) != len(proverIndices) {
+			t.Fatalf("Verifier sampled %d indices, prover %d", len(indices), len(proverIndices))
+		}
+		for i, index := range indices {
+			if index != proverIndices[i] {
+				t.Fatalf("Index %d differs: verifier %d, prover %d", i, index, proverIndices[i])
+			}
+			if !values[i].Equal(codeword[index]) {
+				t.Errorf("Revealed value at index %d doesn't match the codeword", index)
+			}
+		}
+	})
+
+	t.Run("TamperedResponseRejected", func(t *testing.T) {
+		proofStream := prove(codewordOfDegree(domain.Length/4 - 1))
+		for _, item := range proofStream.Items {
+			if response, ok := item.Data.(*FRIResponse); ok {
+				response.RevealedLeaves[0] = response.RevealedLeaves[0].Add(xfield.One)
+				break
+			}
+		}
+		if _, _, err := fri.Verify(proofStream); err == nil {
+			t.Error("Expected tampered FRI response to be rejected")
+		}
+	})
+
+	t.Run("HighDegreeCodewordRejected", func(t *testing.T) {
+		proofStream := prove(codewordOfDegree(domain.Length/2 - 1))
+		if _, _, err := fri.Verify(proofStream); err == nil {
+			t.Error("Expected high-degree codeword to be rejected")
+		}
+	})
+}
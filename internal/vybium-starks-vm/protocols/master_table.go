@@ -4,12 +4,11 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
-	"sync"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/merkle"
-	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/polynomial"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 )
 
 // MasterTable combines all execution tables and manages trace operations
@@ -28,11 +27,25 @@ type MasterTable struct {
 	// Trace columns (before extension)
 	traceColumns [][]field.Element
 
+	// Whether the table holds extension field columns, each stored as its
+	// three coefficient columns (see coefficientColumns)
+	isExtension bool
+
+	// Coefficients of the randomized interpolants of the trace columns
+	columnCoefficients [][]field.Element
+
+	// Randomized interpolants evaluated on the randomized trace domain, from
+	// which EvaluateAtPoint interpolates barycentrically
+	randomizedColumns [][]field.Element
+
 	// Extended columns (after LDE on FRI domain)
 	extendedColumns [][]field.Element
 
 	// Merkle tree of extended trace
 	merkleTree *merkle.MerkleTree
+
+	// Workers the table's loops run on; nil uses the default options
+	pool *workerPool
 }
 
 // NewMasterTable creates a new master table from trace data
@@ -69,6 +82,43 @@ func NewMasterTable(
 	return mt, nil
 }
 
+// NewAuxiliaryMasterTable creates a master table from auxiliary columns
+//
+// The auxiliary columns are built from challenges after the main table has
+// been committed, so they are randomized and committed separately. The seed
+// must differ from the main table's, or both would share trace randomizers.
+// Every coefficient of an auxiliary column gets its own randomizers, so its
+// rows are randomized extension field elements.
+func NewAuxiliaryMasterTable(
+	columns [][]xfield.XFieldElement,
+	domains *ProverDomains,
+	numRandomizers int,
+	randomnessSeed []byte,
+) (*MasterTable, error) {
+	if domains == nil {
+		return nil, fmt.Errorf("domains cannot be nil")
+	}
+	for i, col := range columns {
+		if len(col) != domains.Trace.Length {
+			return nil, fmt.Errorf("auxiliary column %d has length %d, expected %d",
+				i, len(col), domains.Trace.Length)
+		}
+	}
+
+	mt := &MasterTable{
+		domains:        domains,
+		numRandomizers: numRandomizers,
+		randomnessSeed: randomnessSeed,
+		traceColumns:   coefficientColumns(columns),
+		isExtension:    true,
+	}
+	if err := mt.addTraceRandomizers(); err != nil {
+		return nil, fmt.Errorf("failed to add trace randomizers: %w", err)
+	}
+
+	return mt, nil
+}
+
 // extractTraceColumns extracts all columns from the AET into a uniform format
 func (mt *MasterTable) extractTraceColumns() error {
 	// Cast traceData to ExecutionTrace interface
@@ -79,12 +129,8 @@ func (mt *MasterTable) extractTraceColumns() error {
 
 	// Get padded height from execution trace
 	paddedHeight := trace.GetPaddedHeight()
-	// Note: trace domain length may be larger than padded height if number of
-	// randomizers exceeds padded height. This is expected behavior per triton-vm.
-	// The key relationship is: randomized_trace.Length = 2 * trace.Length
-
-	if paddedHeight > mt.domains.Trace.Length {
-		return fmt.Errorf("AET padded height %d exceeds trace domain length %d",
+	if paddedHeight != mt.domains.Trace.Length {
+		return fmt.Errorf("AET padded height %d does not match trace domain length %d",
 			paddedHeight, mt.domains.Trace.Length)
 	}
 
@@ -108,46 +154,51 @@ func (mt *MasterTable) extractTraceColumns() error {
 	return nil
 }
 
-// addTraceRandomizers appends random values to each column for zero-knowledge
+// addTraceRandomizers randomizes the interpolant of every column for zero-knowledge
+//
+// Following Triton VM, the randomized interpolant of a column is
+//
+//	f(X) = interp(column)(X) + Z(X) · R(X)
+//
+// where Z(X) = X^n - 1 vanishes on the trace domain and R has numRandomizers
+// random coefficients. f agrees with the trace on the trace domain, while up to
+// numRandomizers evaluations anywhere else reveal nothing about the trace.
 func (mt *MasterTable) addTraceRandomizers() error {
-	// Following Triton VM: add numRandomizers random elements to each column
-	// This ensures zero-knowledge by hiding the actual trace values
-
 	numCols := len(mt.traceColumns)
+	traceLen := mt.domains.Trace.Length
+
+	if traceLen+mt.numRandomizers > mt.domains.RandomizedTrace.Length {
+		return fmt.Errorf("randomized trace domain length %d too small for %d rows and %d randomizers",
+			mt.domains.RandomizedTrace.Length, traceLen, mt.numRandomizers)
+	}
 
 	// Create deterministic RNG from seed
 	rng := newDeterministicRNG(mt.randomnessSeed)
 
-	// RAM-frugal optimization: allocate padding size upfront to avoid reallocation
-	// This matches triton-vm's approach of reserving exact capacity
+	mt.columnCoefficients = make([][]field.Element, numCols)
+	mt.randomizedColumns = make([][]field.Element, numCols)
 	for col := 0; col < numCols; col++ {
-		currentLen := len(mt.traceColumns[col])
-		targetLen := mt.domains.RandomizedTrace.Length
-		paddingNeeded := targetLen - currentLen - mt.numRandomizers
-
-		// Pre-allocate exact capacity needed (trace + randomizers + padding)
-		// This avoids multiple reallocations during append operations
-		if cap(mt.traceColumns[col]) < targetLen {
-			newCol := make([]field.Element, currentLen, targetLen)
-			copy(newCol, mt.traceColumns[col])
-			mt.traceColumns[col] = newCol
+		interpolant, err := mt.interpolateColumn(col, mt.domains)
+		if err != nil {
+			return fmt.Errorf("failed to interpolate column %d: %w", col, err)
 		}
 
-		// Generate random elements for this column (smaller immediate allocations)
+		coefficients := make([]field.Element, traceLen+mt.numRandomizers)
+		copy(coefficients, interpolant)
 		for i := 0; i < mt.numRandomizers; i++ {
 			// Use column index as additional entropy
 			randomizer := mt.generateRandomElement(rng, col, i)
-			mt.traceColumns[col] = append(mt.traceColumns[col], randomizer)
+			// Z(X)·r·X^i = r·X^(n+i) - r·X^i
+			coefficients[i] = coefficients[i].Sub(randomizer)
+			coefficients[traceLen+i] = coefficients[traceLen+i].Add(randomizer)
 		}
+		mt.columnCoefficients[col] = coefficients
 
-		// Pad to randomized trace domain length (must be power of 2)
-		// Use last element for padding (in-place modification)
-		if paddingNeeded > 0 {
-			lastElem := mt.traceColumns[col][len(mt.traceColumns[col])-1]
-			for i := 0; i < paddingNeeded; i++ {
-				mt.traceColumns[col] = append(mt.traceColumns[col], lastElem)
-			}
+		randomized, err := mt.domains.RandomizedTrace.evaluateCoefficients(coefficients)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate column %d on the randomized trace domain: %w", col, err)
 		}
+		mt.randomizedColumns[col] = randomized
 	}
 
 	return nil
@@ -174,84 +225,50 @@ func (mt *MasterTable) generateRandomElement(rng *deterministicRNG, col, idx int
 // LowDegreeExtend performs low-degree extension on all columns
 //
 // Following Triton VM's algorithm:
-// 1. Interpolate each column to get polynomial
-// 2. Evaluate polynomial on FRI domain (larger than trace domain)
+// 1. Take the randomized interpolant of each column
+// 2. Evaluate it on the FRI domain (larger than, and disjoint from, the trace domain)
 // 3. This creates the "codeword" for FRI protocol
+//
+// Each column is extended with one NTT over the FRI domain coset, and the
+// columns are distributed over the table's workers.
 func (mt *MasterTable) LowDegreeExtend(domains *ProverDomains) error {
-	numCols := len(mt.traceColumns)
-	friLen := domains.FRI.Length
-
-	mt.extendedColumns = make([][]field.Element, numCols)
-
-	// Use parallel processing for better performance
-	var wg sync.WaitGroup
-	errors := make(chan error, numCols)
-
-	for col := 0; col < numCols; col++ {
-		wg.Add(1)
-		go func(colIdx int) {
-			defer wg.Done()
-
-			// Interpolate column to get polynomial
-			poly, err := mt.interpolateColumn(colIdx, domains)
-			if err != nil {
-				errors <- fmt.Errorf("failed to interpolate column %d: %w", colIdx, err)
-				return
-			}
-
-			// Evaluate on FRI domain (low-degree extension)
-			extended, err := domains.FRI.Evaluate(poly)
-			if err != nil {
-				errors <- fmt.Errorf("failed to extend column %d: %w", colIdx, err)
-				return
-			}
-
-			if len(extended) != friLen {
-				errors <- fmt.Errorf("column %d: expected %d values, got %d", colIdx, friLen, len(extended))
-				return
-			}
-
-			mt.extendedColumns[colIdx] = extended
-		}(col)
-	}
-
-	wg.Wait()
-	close(errors)
-
-	// Check for any errors
-	if err := <-errors; err != nil {
+	extended, err := mt.evaluateColumnsOn(domains.FRI)
+	if err != nil {
 		return err
 	}
-
+	mt.extendedColumns = extended
 	return nil
 }
 
-// interpolateColumn interpolates a column to get its polynomial representation
-func (mt *MasterTable) interpolateColumn(colIdx int, domains *ProverDomains) (*polynomial.Polynomial, error) {
-	column := mt.traceColumns[colIdx]
-
-	// Get evaluation points from randomized trace domain
-	// (column has trace length + randomizers = randomized trace length)
-	domainPoints := domains.RandomizedTrace.Elements()
-
-	if len(column) != len(domainPoints) {
-		return nil, fmt.Errorf("column length %d doesn't match domain length %d",
-			len(column), len(domainPoints))
-	}
-
-	// Lagrange interpolation - create points array
-	points := make([][2]field.Element, len(domainPoints))
-	for i := range domainPoints {
-		points[i] = [2]field.Element{domainPoints[i], column[i]}
+// evaluateColumnsOn evaluates every randomized column on the domain
+func (mt *MasterTable) evaluateColumnsOn(domain *ArithmeticDomain) ([][]field.Element, error) {
+	columns := make([][]field.Element, len(mt.columnCoefficients))
+	err := mt.pool.parallelFor(PhaseLowDegreeExtension, len(columns), func(start, end int) error {
+		for col := start; col < end; col++ {
+			column, err := domain.evaluateCoefficients(mt.columnCoefficients[col])
+			if err != nil {
+				return fmt.Errorf("failed to extend column %d: %w", col, err)
+			}
+			columns[col] = column
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return columns, nil
+}
 
-	poly := polynomial.Interpolate(points)
-	return poly, nil
+// interpolateColumn returns the coefficients of a column's interpolant over
+// the trace domain
+func (mt *MasterTable) interpolateColumn(colIdx int, domains *ProverDomains) ([]field.Element, error) {
+	return domains.Trace.interpolateValues(mt.traceColumns[colIdx])
 }
 
 // BuildMerkleTree creates a Merkle commitment to the extended trace
 //
-// Following Triton VM: hash each row (across all columns) to create leaves
+// Following Triton VM: hash each row (across all columns) to create leaves.
+// A row of extension field columns is hashed as its coefficients.
 func (mt *MasterTable) BuildMerkleTree() (*merkle.MerkleTree, error) {
 	if len(mt.extendedColumns) == 0 {
 		return nil, fmt.Errorf("must call LowDegreeExtend before BuildMerkleTree")
@@ -262,50 +279,24 @@ func (mt *MasterTable) BuildMerkleTree() (*merkle.MerkleTree, error) {
 
 	// Hash each row to create Merkle leaves
 	leaves := make([][]byte, numRows)
-
-	// Use parallel processing
-	var wg sync.WaitGroup
-	errors := make(chan error, numRows)
-
-	// Process rows in batches for efficiency
-	// Optimize: reuse rowValues buffer to avoid per-row allocations
-	batchSize := 1000
-	for startRow := 0; startRow < numRows; startRow += batchSize {
-		endRow := startRow + batchSize
-		if endRow > numRows {
-			endRow = numRows
-		}
-
-		wg.Add(1)
-		go func(start, end int) {
-			defer wg.Done()
-
-			// Reuse buffer across rows in this batch to reduce allocations
-			rowValues := make([]field.Element, numCols)
-
-			for row := start; row < end; row++ {
-				// Collect all values in this row (reusing buffer)
-				for col := 0; col < numCols; col++ {
-					rowValues[col] = mt.extendedColumns[col][row]
-				}
-
-				// Hash the row using Tip5 (direct slice access, no extra allocation)
-				rowHash, err := mt.hashRow(rowValues)
-				if err != nil {
-					errors <- fmt.Errorf("failed to hash row %d: %w", row, err)
-					return
-				}
-
-				leaves[row] = rowHash
+	err := mt.pool.parallelFor(PhaseMerkleCommitment, numRows, func(start, end int) error {
+		// Reuse one row buffer per chunk to avoid per-row allocations
+		rowValues := make([]field.Element, numCols)
+		for row := start; row < end; row++ {
+			for col := 0; col < numCols; col++ {
+				rowValues[col] = mt.extendedColumns[col][row]
 			}
-		}(startRow, endRow)
-	}
 
-	wg.Wait()
-	close(errors)
-
-	// Check for errors
-	if err := <-errors; err != nil {
+			// Hash the row using Tip5
+			rowHash, err := mt.hashRow(rowValues)
+			if err != nil {
+				return fmt.Errorf("failed to hash row %d: %w", row, err)
+			}
+			leaves[row] = rowHash
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -336,97 +327,178 @@ func (mt *MasterTable) BuildMerkleTree() (*merkle.MerkleTree, error) {
 
 // hashRow hashes a row of field elements using Tip5
 func (mt *MasterTable) hashRow(rowValues []field.Element) ([]byte, error) {
-	// Use variable-length Tip5 hash
-	digest := hash.HashVarlen(rowValues)
-	// Convert hash.Digest to []byte
-	// Each field element is 8 bytes
-	result := make([]byte, len(digest)*8)
-	for i, elem := range digest {
-		// Convert field element to bytes (little-endian)
-		val := elem.Value()
-		for j := 0; j < 8; j++ {
-			result[i*8+j] = byte(val >> (j * 8))
+	return digestToBytes(hashTableRow(rowValues)), nil
+}
+
+// hashTableRow computes the Merkle leaf of a master table row
+//
+// Shared by the prover's commitment and the verifier's recomputation of
+// opened rows.
+func hashTableRow(rowValues []field.Element) hash.Digest {
+	return hash.HashVarlen(rowValues)
+}
+
+// OpenRows returns the extended rows at the given indices together with
+// their authentication paths in the committed Merkle tree
+//
+// Rows of extension field columns hold the columns' coefficients, which
+// xfieldElements reassembles.
+func (mt *MasterTable) OpenRows(indices []int) ([][]field.Element, [][]hash.Digest, error) {
+	if mt.merkleTree == nil {
+		return nil, nil, fmt.Errorf("must call BuildMerkleTree before OpenRows")
+	}
+
+	rows := make([][]field.Element, len(indices))
+	paths := make([][]hash.Digest, len(indices))
+	for i, index := range indices {
+		if index < 0 || index >= mt.NumExtendedRows() {
+			return nil, nil, fmt.Errorf("row index %d out of range [0, %d)", index, mt.NumExtendedRows())
+		}
+		row := make([]field.Element, len(mt.extendedColumns))
+		for col := range mt.extendedColumns {
+			row[col] = mt.extendedColumns[col][index]
 		}
+		path, err := mt.merkleTree.AuthenticationPath(uint64(index))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get authentication path for row %d: %w", index, err)
+		}
+		rows[i] = row
+		paths[i] = path
 	}
-	return result, nil
+
+	return rows, paths, nil
 }
 
-// ComputeQuotients computes the constraint quotient polynomials
+// ComputeQuotients computes the combined quotient codeword over the quotient domain
 //
 // Following the STARK protocol:
-// 1. Create AIR constraints for the tables
-// 2. Evaluate composition polynomial over trace
-// 3. Divide by vanishing polynomial
-// 4. Return quotient polynomials
+// 1. Evaluate every AIR constraint on the extended trace
+// 2. Divide each by the zerofier of the rows it applies to
+// 3. Combine them with the Fiat-Shamir weights
+//
+// aux is the auxiliary table built from challenges, or nil if the AIR has no
+// auxiliary columns.
 func (mt *MasterTable) ComputeQuotients(
+	air *AIRConstraints,
+	aux *MasterTable,
+	challenges []xfield.XFieldElement,
 	domains *ProverDomains,
-	challenges []field.Element,
-) ([]*polynomial.Polynomial, error) {
-	// Create AIR constraints for processor table
-	// In a full implementation, we'd have constraints for all 9 tables
-	air := CreateProcessorConstraints()
-
-	// Use trace columns (with randomizers) for quotient computation
-	// Following triton-vm: quotients are computed from the trace table before extension
-	// The trace table includes randomizers for zero-knowledge, and is interpolated
-	// over the randomized trace domain. Extension to FRI domain happens later.
-	traceTable := mt.traceColumns
-
-	// Compute quotient polynomials
-	quotients, err := ComputeQuotientPolynomials(
-		air,
-		traceTable,
-		domains,
-		challenges,
-	)
+	weights []xfield.XFieldElement,
+) ([]xfield.XFieldElement, error) {
+	if len(mt.extendedColumns) == 0 {
+		return nil, fmt.Errorf("must call LowDegreeExtend before ComputeQuotients")
+	}
+	mainColumns, err := mt.columnsOn(domains.Quotient, domains.FRI)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute quotients: %w", err)
+		return nil, fmt.Errorf("failed to extend columns to the quotient domain: %w", err)
 	}
 
-	return quotients, nil
-}
-
-// EvaluateAtPoint evaluates all trace columns at a given point
-func (mt *MasterTable) EvaluateAtPoint(point field.Element) ([]field.Element, error) {
-	if len(mt.extendedColumns) == 0 {
-		return nil, fmt.Errorf("must call LowDegreeExtend before EvaluateAtPoint")
+	var auxColumns [][]xfield.XFieldElement
+	if aux != nil {
+		if len(aux.extendedColumns) == 0 && aux.NumColumns() > 0 {
+			return nil, fmt.Errorf("must call LowDegreeExtend on the auxiliary table before ComputeQuotients")
+		}
+		auxCoefficientColumns, err := aux.columnsOn(domains.Quotient, domains.FRI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extend auxiliary columns to the quotient domain: %w", err)
+		}
+		auxColumns = assembleXFieldColumns(auxCoefficientColumns)
 	}
 
-	numCols := len(mt.traceColumns)
-	values := make([]field.Element, numCols)
-
-	// For each column, interpolate and evaluate at the point
-	var wg sync.WaitGroup
-	errors := make(chan error, numCols)
+	quotient, err := computeQuotientCodeword(mt.pool, air, mainColumns, auxColumns, challenges, domains, weights)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute quotients: %w", err)
+	}
 
-	for col := 0; col < numCols; col++ {
-		wg.Add(1)
-		go func(colIdx int) {
-			defer wg.Done()
+	return quotient, nil
+}
 
-			// Interpolate column
-			poly, err := mt.interpolateColumn(colIdx, mt.domains)
-			if err != nil {
-				errors <- fmt.Errorf("failed to interpolate column %d: %w", colIdx, err)
-				return
+// columnsOn returns the randomized columns evaluated on the given domain
+//
+// The quotient and FRI domains share their offset, and the shorter one is a
+// coset of a subgroup of the longer one. A shorter domain therefore takes
+// every stride-th value of the extended columns; a longer one is evaluated
+// from the columns' coefficients.
+func (mt *MasterTable) columnsOn(domain, friDomain *ArithmeticDomain) ([][]field.Element, error) {
+	if domain.Length <= friDomain.Length && domain.Offset.Equal(friDomain.Offset) {
+		stride := friDomain.Length / domain.Length
+		if stride == 1 {
+			return mt.extendedColumns, nil
+		}
+		columns := make([][]field.Element, len(mt.extendedColumns))
+		for col, extended := range mt.extendedColumns {
+			column := make([]field.Element, domain.Length)
+			for i := range column {
+				column[i] = extended[i*stride]
 			}
-
-			// Evaluate at point
-			values[colIdx] = poly.Evaluate(point)
-		}(col)
+			columns[col] = column
+		}
+		return columns, nil
 	}
 
-	wg.Wait()
-	close(errors)
+	return mt.evaluateColumnsOn(domain)
+}
+
+// EvaluateAtPoint evaluates all randomized trace columns at a given point
+//
+// The point is an extension field element, so the values are too, even for
+// base field columns. Every randomized column has degree less than the
+// randomized trace domain's length, so it is interpolated barycentrically
+// from its values there, with weights shared by all columns.
+func (mt *MasterTable) EvaluateAtPoint(point xfield.XFieldElement) ([]xfield.XFieldElement, error) {
+	if len(mt.randomizedColumns) == 0 {
+		return nil, fmt.Errorf("trace columns have not been interpolated")
+	}
+	weights, err := mt.domains.RandomizedTrace.barycentricWeights(point)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate trace columns: %w", err)
+	}
 
-	// Check for errors
-	if err := <-errors; err != nil {
+	coefficients := make([]xfield.XFieldElement, len(mt.randomizedColumns))
+	err = mt.pool.parallelFor(PhaseOutOfDomain, len(coefficients), func(start, end int) error {
+		for col := start; col < end; col++ {
+			coefficients[col] = barycentricEvaluate(mt.randomizedColumns[col], weights)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	if !mt.isExtension {
+		return coefficients, nil
+	}
 
+	values := make([]xfield.XFieldElement, mt.NumColumns())
+	for col := range values {
+		for k := 0; k < xfield.ExtensionDegree; k++ {
+			values[col] = values[col].Add(coefficients[xfield.ExtensionDegree*col+k].Mul(xfieldBasis[k]))
+		}
+	}
 	return values, nil
 }
 
+// extendedXFieldColumns reassembles the extended extension field columns
+// from their coefficient columns
+func (mt *MasterTable) extendedXFieldColumns() [][]xfield.XFieldElement {
+	return assembleXFieldColumns(mt.extendedColumns)
+}
+
+// assembleXFieldColumns reassembles extension field columns from groups of
+// three coefficient columns, the inverse of coefficientColumns
+func assembleXFieldColumns(coefficientColumns [][]field.Element) [][]xfield.XFieldElement {
+	columns := make([][]xfield.XFieldElement, len(coefficientColumns)/xfield.ExtensionDegree)
+	for col := range columns {
+		column := make([]xfield.XFieldElement, len(coefficientColumns[xfield.ExtensionDegree*col]))
+		for k := 0; k < xfield.ExtensionDegree; k++ {
+			for i, value := range coefficientColumns[xfield.ExtensionDegree*col+k] {
+				column[i].Coefficients[k] = value
+			}
+		}
+		columns[col] = column
+	}
+	return columns
+}
+
 // getTotalColumns returns the total number of columns across all tables (reserved for future use)
 // nolint:unused
 func (mt *MasterTable) getTotalColumns() int {
@@ -509,8 +581,12 @@ func (mt *MasterTable) GetExtendedColumn(colIdx int) ([]field.Element, error) {
 	return mt.extendedColumns[colIdx], nil
 }
 
-// NumColumns returns the total number of columns
+// NumColumns returns the total number of columns, counting an extension
+// field column once
 func (mt *MasterTable) NumColumns() int {
+	if mt.isExtension {
+		return len(mt.traceColumns) / xfield.ExtensionDegree
+	}
 	return len(mt.traceColumns)
 }
 
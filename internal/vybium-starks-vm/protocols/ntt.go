@@ -0,0 +1,196 @@
+package protocols
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+)
+
+// Number theoretic transforms over the Goldilocks field
+//
+// Every power-of-two subgroup of the multiplicative group has an iterative
+// radix-2 NTT: the values are permuted into bit-reversed order, then combined
+// in log2(n) rounds of butterflies. The twiddle factors of each root are
+// computed once and cached. Coset domains are handled by scaling the
+// coefficients with powers of the offset, so low-degree extension is one NTT
+// per column. Single points off the domain are evaluated with the
+// barycentric formula, whose weights all columns share.
+
+// twiddleKey identifies a table of twiddle factors by the root's value and
+// the transform length
+type twiddleKey struct {
+	root   uint64
+	length int
+}
+
+// twiddleCache maps a twiddleKey to ω^0, …, ω^(n/2-1)
+var twiddleCache sync.Map
+
+// twiddles returns the first n/2 powers of root, which must be a primitive
+// n-th root of unity
+func twiddles(root field.Element, n int) ([]field.Element, error) {
+	key := twiddleKey{root: root.Value(), length: n}
+	if cached, ok := twiddleCache.Load(key); ok {
+		return cached.([]field.Element), nil
+	}
+
+	// ω is a primitive n-th root exactly if ω^(n/2) = -1
+	if n > 1 && !root.ModPow(uint64(n/2)).Equal(field.One.Neg()) {
+		return nil, fmt.Errorf("%v is not a primitive %d-th root of unity", root, n)
+	}
+	powers := make([]field.Element, n/2)
+	power := field.One
+	for i := range powers {
+		powers[i] = power
+		power = power.Mul(root)
+	}
+	twiddleCache.Store(key, powers)
+	return powers, nil
+}
+
+// NTT evaluates the polynomial with coefficients values on the powers of
+// root, in place: afterwards values[i] = Σ_j c_j·root^(i·j)
+//
+// The length must be a power of two and root a primitive root of unity of
+// that order.
+func NTT(values []field.Element, root field.Element) error {
+	n := len(values)
+	if !isPowerOfTwo(n) {
+		return fmt.Errorf("NTT length must be a power of 2, got %d", n)
+	}
+	powers, err := twiddles(root, n)
+	if err != nil {
+		return err
+	}
+
+	bitReverse(values)
+	for half := 1; half < n; half *= 2 {
+		step := n / (2 * half)
+		for start := 0; start < n; start += 2 * half {
+			for j := 0; j < half; j++ {
+				u := values[start+j]
+				v := values[start+j+half].Mul(powers[j*step])
+				values[start+j] = u.Add(v)
+				values[start+j+half] = u.Sub(v)
+			}
+		}
+	}
+	return nil
+}
+
+// INTT is the inverse of NTT: it turns the values on the powers of root back
+// into coefficients, in place
+func INTT(values []field.Element, root field.Element) error {
+	if err := NTT(values, root.Inverse()); err != nil {
+		return err
+	}
+	nInverse := field.New(uint64(len(values))).Inverse()
+	for i := range values {
+		values[i] = values[i].Mul(nInverse)
+	}
+	return nil
+}
+
+// bitReverse permutes values into bit-reversed index order
+func bitReverse(values []field.Element) {
+	n := len(values)
+	if n < 2 {
+		return
+	}
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := range values {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+}
+
+// evaluateCoefficients evaluates the polynomial with the given coefficients
+// on the domain
+//
+// On the coset offset·⟨ω⟩ the polynomial Σ c_j·X^j takes the values of
+// Σ c_j·offset^j·X^j on ⟨ω⟩. Coefficients beyond the domain's length are
+// folded onto j mod n, since ω^n = 1, so any degree is allowed.
+func (d *ArithmeticDomain) evaluateCoefficients(coefficients []field.Element) ([]field.Element, error) {
+	values := make([]field.Element, d.Length)
+	power := field.One
+	for j, c := range coefficients {
+		values[j%d.Length] = values[j%d.Length].Add(c.Mul(power))
+		power = power.Mul(d.Offset)
+	}
+	if err := NTT(values, d.Generator); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// interpolateValues returns the coefficients of the polynomial of degree
+// less than the domain's length that takes the given values on the domain
+func (d *ArithmeticDomain) interpolateValues(values []field.Element) ([]field.Element, error) {
+	if len(values) != d.Length {
+		return nil, fmt.Errorf("expected %d values, got %d", d.Length, len(values))
+	}
+	coefficients := append([]field.Element{}, values...)
+	if err := INTT(coefficients, d.Generator); err != nil {
+		return nil, err
+	}
+	offsetInverse := d.Offset.Inverse()
+	power := field.One
+	for j := range coefficients {
+		coefficients[j] = coefficients[j].Mul(power)
+		power = power.Mul(offsetInverse)
+	}
+	return coefficients, nil
+}
+
+// barycentricWeights returns the weights λ_i with f(z) = Σ λ_i·f(x_i) for
+// every polynomial f of degree less than the domain's length
+//
+// For the coset x_i = offset·ω^i of length n, the barycentric formula gives
+//
+//	λ_i = (z^n - offset^n)/(n·offset^n) · x_i/(z - x_i)
+//
+// The weights only depend on the point, so all columns evaluated at it share
+// them and one batch inversion. The point must not lie in the domain.
+func (d *ArithmeticDomain) barycentricWeights(point xfield.XFieldElement) ([]xfield.XFieldElement, error) {
+	elements := d.Elements()
+	denominators := make([]xfield.XFieldElement, d.Length)
+	for i, x := range elements {
+		denominators[i] = point.SubConst(x)
+	}
+	inverses, err := BatchInverse(denominators)
+	if err != nil {
+		return nil, fmt.Errorf("point lies in the domain: %w", err)
+	}
+
+	offsetPower := d.Offset.ModPow(uint64(d.Length))
+	scale := d.zerofierAtXField(point).MulConst(field.New(uint64(d.Length)).Mul(offsetPower).Inverse())
+	for i, x := range elements {
+		inverses[i] = inverses[i].Mul(scale).MulConst(x)
+	}
+	return inverses, nil
+}
+
+// barycentricEvaluate evaluates the interpolant of a base field column at
+// the point the weights were computed for
+func barycentricEvaluate(values []field.Element, weights []xfield.XFieldElement) xfield.XFieldElement {
+	value := xfield.Zero
+	for i, weight := range weights {
+		value = value.Add(weight.MulConst(values[i]))
+	}
+	return value
+}
+
+// barycentricEvaluateXField evaluates the interpolant of an extension field
+// column at the point the weights were computed for
+func barycentricEvaluateXField(values []xfield.XFieldElement, weights []xfield.XFieldElement) xfield.XFieldElement {
+	value := xfield.Zero
+	for i, weight := range weights {
+		value = value.Add(weight.Mul(values[i]))
+	}
+	return value
+}
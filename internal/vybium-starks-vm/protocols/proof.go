@@ -2,9 +2,11 @@ package protocols
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 )
 
 // Proof contains the cryptographic information to verify a computation.
@@ -126,11 +128,15 @@ func (pi ProofItem) IncludeInFiatShamirHeuristic() bool {
 	}
 }
 
-// Encode encodes the proof item to a sequence of field elements for Fiat-Shamir.
+// Encode encodes the proof item to a sequence of field elements.
 // This is equivalent to triton-vm's BFieldCodec::encode() for ProofItem
+//
+// Items included in the Fiat-Shamir heuristic are absorbed in this encoding.
+// Every item type has an encoding, so that Decode can reconstruct any proof
+// item from it; variable-length parts are length-prefixed. An extension
+// field element is encoded as its three coefficients, and lengths count
+// extension field elements.
 func (pi ProofItem) Encode() ([]field.Element, error) {
-	// Import bfieldcodec for encoding
-	// For now, implement basic encoding based on type
 	switch pi.Type {
 	case ProofItemMerkleRoot:
 		if root, ok := pi.Data.([]byte); ok {
@@ -166,10 +172,166 @@ func (pi ProofItem) Encode() ([]field.Element, error) {
 		}
 		return nil, fmt.Errorf("invalid field elements data type")
 
+	case ProofItemFRICodeword,
+		ProofItemFRIPolynomial:
+		if elems, ok := pi.Data.([]xfield.XFieldElement); ok {
+			return xfield.AsFlatSlice(elems), nil
+		}
+		return nil, fmt.Errorf("invalid extension field elements data type")
+
+	case ProofItemOutOfDomainMainRow,
+		ProofItemOutOfDomainAuxRow,
+		ProofItemOutOfDomainQuotientSegments:
+		if elems, ok := pi.Data.([]xfield.XFieldElement); ok {
+			// Length-prefixed so rows of different widths encode differently
+			return encodeXFieldList(nil, elems), nil
+		}
+		return nil, fmt.Errorf("invalid out-of-domain row data type")
+
+	case ProofItemMasterMainTableRows:
+		if rows, ok := pi.Data.([][]field.Element); ok {
+			return encodeRows(nil, rows), nil
+		}
+		return nil, fmt.Errorf("invalid table rows data type")
+
+	case ProofItemMasterAuxTableRows,
+		ProofItemQuotientSegmentsElements:
+		if rows, ok := pi.Data.([][]xfield.XFieldElement); ok {
+			return encodeXFieldRows(nil, rows), nil
+		}
+		return nil, fmt.Errorf("invalid table rows data type")
+
+	case ProofItemAuthenticationStructure:
+		if paths, ok := pi.Data.([][]hash.Digest); ok {
+			return encodeAuthenticationPaths(nil, paths), nil
+		}
+		return nil, fmt.Errorf("invalid authentication structure data type")
+
+	case ProofItemFRIResponse:
+		if response, ok := pi.Data.(*FRIResponse); ok {
+			result := encodeAuthenticationPaths(nil, response.AuthenticationPaths)
+			return encodeXFieldList(result, response.RevealedLeaves), nil
+		}
+		return nil, fmt.Errorf("invalid FRI response data type")
+
+	case ProofItemMerkleProof:
+		if nodes, ok := pi.Data.([][]byte); ok {
+			// One element per byte: the legacy paths are not digest-sized
+			result := []field.Element{field.New(uint64(len(nodes)))}
+			for _, node := range nodes {
+				result = append(result, field.New(uint64(len(node))))
+				for _, b := range node {
+					result = append(result, field.New(uint64(b)))
+				}
+			}
+			return result, nil
+		}
+		return nil, fmt.Errorf("invalid Merkle proof data type")
+
+	default:
+		return nil, fmt.Errorf("unknown proof item type %d", pi.Type)
+	}
+}
+
+// Decode reconstructs the item's data from its encoding, the inverse of
+// Encode. The item's Type selects the encoding and must be set.
+//
+// Decoding is strict: the data must be exactly one encoding, without
+// trailing elements.
+func (pi *ProofItem) Decode(data []field.Element) error {
+	r := &elementReader{data: data}
+	var err error
+	switch pi.Type {
+	case ProofItemMerkleRoot:
+		var elems []field.Element
+		if elems, err = r.take(hash.DigestLen); err == nil {
+			root := make([]byte, 0, hash.DigestLen*8)
+			for _, elem := range elems {
+				bytes := elem.ToBytes()
+				root = append(root, bytes[:]...)
+			}
+			pi.Data = root
+		}
+
+	case ProofItemLog2PaddedHeight:
+		var height field.Element
+		if height, err = r.next(); err == nil {
+			if height.Value() > MaxLog2DomainLength {
+				err = fmt.Errorf("log2 padded height %d exceeds the maximum %d", height.Value(), MaxLog2DomainLength)
+			}
+			pi.Data = int(height.Value())
+		}
+
+	case ProofItemFieldElement:
+		pi.Data, err = r.next()
+
+	case ProofItemFieldElements:
+		pi.Data, err = r.take(r.remaining())
+
+	case ProofItemFRICodeword,
+		ProofItemFRIPolynomial:
+		var elems []field.Element
+		if elems, err = r.take(r.remaining()); err == nil {
+			pi.Data, err = xfieldElements(elems)
+		}
+
+	case ProofItemOutOfDomainMainRow,
+		ProofItemOutOfDomainAuxRow,
+		ProofItemOutOfDomainQuotientSegments:
+		pi.Data, err = r.xfieldList()
+
+	case ProofItemMasterMainTableRows:
+		pi.Data, err = r.rows()
+
+	case ProofItemMasterAuxTableRows,
+		ProofItemQuotientSegmentsElements:
+		pi.Data, err = r.xfieldRows()
+
+	case ProofItemAuthenticationStructure:
+		pi.Data, err = r.authenticationPaths()
+
+	case ProofItemFRIResponse:
+		response := &FRIResponse{}
+		if response.AuthenticationPaths, err = r.authenticationPaths(); err == nil {
+			response.RevealedLeaves, err = r.xfieldList()
+		}
+		pi.Data = response
+
+	case ProofItemMerkleProof:
+		var numNodes int
+		if numNodes, err = r.length(); err != nil {
+			break
+		}
+		nodes := make([][]byte, numNodes)
+		for i := range nodes {
+			var elems []field.Element
+			if elems, err = r.list(); err != nil {
+				break
+			}
+			nodes[i] = make([]byte, len(elems))
+			for j, elem := range elems {
+				if elem.Value() > math.MaxUint8 {
+					err = fmt.Errorf("Merkle proof byte %d out of range", elem.Value())
+					break
+				}
+				nodes[i][j] = byte(elem.Value())
+			}
+			if err != nil {
+				break
+			}
+		}
+		pi.Data = nodes
+
 	default:
-		// For other types, return empty (they don't need encoding for Fiat-Shamir)
-		return []field.Element{}, nil
+		return fmt.Errorf("unknown proof item type %d", pi.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode proof item of type %d: %w", pi.Type, err)
 	}
+	if r.remaining() != 0 {
+		return fmt.Errorf("proof item of type %d has %d trailing elements", pi.Type, r.remaining())
+	}
+	return nil
 }
 
 // NewProof creates a new empty proof
@@ -217,6 +379,21 @@ func (p *Proof) AddOODEvaluations(evals []field.Element) {
 	p.AddFieldElements(evals)
 }
 
+// AddOutOfDomainMainRow adds the main trace columns evaluated at an out-of-domain point
+func (p *Proof) AddOutOfDomainMainRow(row []xfield.XFieldElement) {
+	p.AddItem(ProofItemOutOfDomainMainRow, row)
+}
+
+// AddOutOfDomainAuxRow adds the auxiliary trace columns evaluated at an out-of-domain point
+func (p *Proof) AddOutOfDomainAuxRow(row []xfield.XFieldElement) {
+	p.AddItem(ProofItemOutOfDomainAuxRow, row)
+}
+
+// AddOutOfDomainQuotientSegments adds the quotient segments evaluated at the out-of-domain point
+func (p *Proof) AddOutOfDomainQuotientSegments(segments []xfield.XFieldElement) {
+	p.AddItem(ProofItemOutOfDomainQuotientSegments, segments)
+}
+
 // AddFRILayer adds a FRI layer (for compatibility with FRI protocol)
 func (p *Proof) AddFRILayer(layer FRILayer) {
 	// Add Merkle root
@@ -245,6 +422,9 @@ func (p *Proof) GetPaddedHeight() (int, error) {
 			if !ok {
 				return 0, fmt.Errorf("invalid log2 height data type")
 			}
+			if log2Height < 0 || log2Height > MaxLog2DomainLength {
+				return 0, fmt.Errorf("log2 padded height %d out of range [0, %d]", log2Height, MaxLog2DomainLength)
+			}
 			return 1 << log2Height, nil
 		}
 	}
@@ -264,6 +444,69 @@ func (p *Proof) GetMerkleRoots() [][]byte {
 	return roots
 }
 
+// digestToBytes serializes a digest as 8 little-endian bytes per element,
+// the representation used for ProofItemMerkleRoot
+func digestToBytes(digest hash.Digest) []byte {
+	result := make([]byte, len(digest)*8)
+	for i, elem := range digest {
+		val := elem.Value()
+		for j := 0; j < 8; j++ {
+			result[i*8+j] = byte(val >> (j * 8))
+		}
+	}
+	return result
+}
+
+// digestFromBytes is the inverse of digestToBytes
+func digestFromBytes(data []byte) (hash.Digest, error) {
+	var digest hash.Digest
+	if len(data) != hash.DigestLen*8 {
+		return digest, fmt.Errorf("invalid digest length: expected %d bytes, got %d", hash.DigestLen*8, len(data))
+	}
+	for i := range digest {
+		var val uint64
+		for j := 0; j < 8; j++ {
+			val |= uint64(data[i*8+j]) << (j * 8)
+		}
+		digest[i] = field.New(val)
+	}
+	return digest, nil
+}
+
+// GetElementItems extracts the data of all items of the given type that carry
+// a slice of field elements, in proof order
+func (p *Proof) GetElementItems(itemType ProofItemType) ([][]field.Element, error) {
+	items := make([][]field.Element, 0)
+	for i, item := range p.Items {
+		if item.Type != itemType {
+			continue
+		}
+		elems, ok := item.Data.([]field.Element)
+		if !ok {
+			return nil, fmt.Errorf("proof item %d: invalid data type for item type %d", i, itemType)
+		}
+		items = append(items, elems)
+	}
+	return items, nil
+}
+
+// GetXFieldItems extracts the data of all items of the given type that carry
+// a slice of extension field elements, in proof order
+func (p *Proof) GetXFieldItems(itemType ProofItemType) ([][]xfield.XFieldElement, error) {
+	items := make([][]xfield.XFieldElement, 0)
+	for i, item := range p.Items {
+		if item.Type != itemType {
+			continue
+		}
+		elems, ok := item.Data.([]xfield.XFieldElement)
+		if !ok {
+			return nil, fmt.Errorf("proof item %d: invalid data type for item type %d", i, itemType)
+		}
+		items = append(items, elems)
+	}
+	return items, nil
+}
+
 // Validate checks if the proof is well-formed
 func (p *Proof) Validate() error {
 	if len(p.Items) == 0 {
@@ -311,6 +554,21 @@ func (p *Proof) Size() int {
 			if elems, ok := item.Data.([]field.Element); ok {
 				size += len(elems) * 8
 			}
+		case ProofItemOutOfDomainMainRow,
+			ProofItemOutOfDomainAuxRow,
+			ProofItemOutOfDomainQuotientSegments,
+			ProofItemFRICodeword,
+			ProofItemFRIPolynomial:
+			if elems, ok := item.Data.([]xfield.XFieldElement); ok {
+				size += len(elems) * xfield.ExtensionDegree * 8
+			}
+		case ProofItemFRIResponse:
+			if response, ok := item.Data.(*FRIResponse); ok {
+				size += len(response.RevealedLeaves) * xfield.ExtensionDegree * 8
+				for _, path := range response.AuthenticationPaths {
+					size += len(path) * hash.DigestLen * 8
+				}
+			}
 		case ProofItemMerkleProof:
 			if proof, ok := item.Data.([][]byte); ok {
 				for _, node := range proof {
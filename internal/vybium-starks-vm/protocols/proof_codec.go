@@ -0,0 +1,266 @@
+package protocols
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+)
+
+// proofMagic starts every binary-encoded proof
+var proofMagic = [4]byte{'V', 'Y', 'B', 'P'}
+
+// proofHeaderLen is the length of the magic and the version
+const proofHeaderLen = len(proofMagic) + 4
+
+// MarshalBinary implements encoding.BinaryMarshaler
+//
+// The encoding is deterministic:
+//   - the magic bytes "VYBP"
+//   - CurrentVersion as a little-endian uint32
+//   - the number of items, then for every item its type, the length of its
+//     encoding and the encoding itself (see ProofItem.Encode)
+//
+// After the header, every value is a field element, written as its
+// canonical value in 8 little-endian bytes.
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	elements := []field.Element{field.New(uint64(len(p.Items)))}
+	for i, item := range p.Items {
+		encoded, err := item.Encode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode proof item %d: %w", i, err)
+		}
+		elements = append(elements, field.New(uint64(item.Type)), field.New(uint64(len(encoded))))
+		elements = append(elements, encoded...)
+	}
+
+	data := make([]byte, proofHeaderLen, proofHeaderLen+8*len(elements))
+	copy(data, proofMagic[:])
+	binary.LittleEndian.PutUint32(data[len(proofMagic):], CurrentVersion)
+	for _, elem := range elements {
+		data = binary.LittleEndian.AppendUint64(data, elem.Value())
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+//
+// Decoding is strict: it rejects other magic bytes and versions, values
+// that are not canonical field elements, unknown item types, and trailing
+// bytes, so that every accepted input is the encoding of exactly one proof.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) < proofHeaderLen || !bytes.Equal(data[:len(proofMagic)], proofMagic[:]) {
+		return fmt.Errorf("not a binary proof: missing magic bytes")
+	}
+	if version := binary.LittleEndian.Uint32(data[len(proofMagic):]); version != CurrentVersion {
+		return fmt.Errorf("unsupported proof version %d, expected %d", version, CurrentVersion)
+	}
+	body := data[proofHeaderLen:]
+	if len(body)%8 != 0 {
+		return fmt.Errorf("proof body has %d trailing bytes", len(body)%8)
+	}
+
+	elements := make([]field.Element, len(body)/8)
+	for i := range elements {
+		value := binary.LittleEndian.Uint64(body[8*i:])
+		if value >= field.P {
+			return fmt.Errorf("proof value %d is not a canonical field element", i)
+		}
+		elements[i] = field.New(value)
+	}
+
+	r := &elementReader{data: elements}
+	numItems, err := r.length()
+	if err != nil {
+		return fmt.Errorf("failed to read number of proof items: %w", err)
+	}
+	items := make([]ProofItem, numItems)
+	for i := range items {
+		itemType, err := r.next()
+		if err != nil {
+			return fmt.Errorf("failed to read type of proof item %d: %w", i, err)
+		}
+		if itemType.Value() > uint64(ProofItemFieldElements) {
+			return fmt.Errorf("proof item %d has unknown type %d", i, itemType.Value())
+		}
+		encoded, err := r.list()
+		if err != nil {
+			return fmt.Errorf("failed to read proof item %d: %w", i, err)
+		}
+		items[i].Type = ProofItemType(itemType.Value())
+		if err := items[i].Decode(encoded); err != nil {
+			return fmt.Errorf("proof item %d: %w", i, err)
+		}
+	}
+	if r.remaining() != 0 {
+		return fmt.Errorf("proof has %d trailing elements", r.remaining())
+	}
+
+	p.Items = items
+	return nil
+}
+
+// encodeRows appends the length-prefixed encoding of a list of rows
+func encodeRows(result []field.Element, rows [][]field.Element) []field.Element {
+	result = append(result, field.New(uint64(len(rows))))
+	for _, row := range rows {
+		result = append(result, field.New(uint64(len(row))))
+		result = append(result, row...)
+	}
+	return result
+}
+
+// encodeXFieldList appends the length-prefixed encoding of a list of
+// extension field elements, each as its coefficients
+func encodeXFieldList(result []field.Element, elems []xfield.XFieldElement) []field.Element {
+	result = append(result, field.New(uint64(len(elems))))
+	return append(result, xfield.AsFlatSlice(elems)...)
+}
+
+// encodeXFieldRows appends the length-prefixed encoding of a list of rows of
+// extension field elements
+func encodeXFieldRows(result []field.Element, rows [][]xfield.XFieldElement) []field.Element {
+	result = append(result, field.New(uint64(len(rows))))
+	for _, row := range rows {
+		result = encodeXFieldList(result, row)
+	}
+	return result
+}
+
+// encodeAuthenticationPaths appends the length-prefixed encoding of a list
+// of authentication paths
+func encodeAuthenticationPaths(result []field.Element, paths [][]hash.Digest) []field.Element {
+	result = append(result, field.New(uint64(len(paths))))
+	for _, path := range paths {
+		result = append(result, field.New(uint64(len(path))))
+		for _, digest := range path {
+			result = append(result, digest[:]...)
+		}
+	}
+	return result
+}
+
+// elementReader reads the parts of an encoding from a sequence of field
+// elements
+type elementReader struct {
+	data []field.Element
+	pos  int
+}
+
+// remaining returns the number of unread elements
+func (r *elementReader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+// next reads one element
+func (r *elementReader) next() (field.Element, error) {
+	if r.remaining() == 0 {
+		return field.Zero, fmt.Errorf("unexpected end of data")
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+// take reads n elements
+func (r *elementReader) take(n int) ([]field.Element, error) {
+	if n > r.remaining() {
+		return nil, fmt.Errorf("unexpected end of data: need %d elements, have %d", n, r.remaining())
+	}
+	elems := append([]field.Element{}, r.data[r.pos:r.pos+n]...)
+	r.pos += n
+	return elems, nil
+}
+
+// length reads a length prefix
+//
+// Every listed entry takes at least one element, so a length beyond the
+// remaining elements is malformed and rejected before anything is allocated.
+func (r *elementReader) length() (int, error) {
+	value, err := r.next()
+	if err != nil {
+		return 0, err
+	}
+	if value.Value() > uint64(r.remaining()) {
+		return 0, fmt.Errorf("length %d exceeds the %d remaining elements", value.Value(), r.remaining())
+	}
+	return int(value.Value()), nil
+}
+
+// list reads a length-prefixed list of elements
+func (r *elementReader) list() ([]field.Element, error) {
+	n, err := r.length()
+	if err != nil {
+		return nil, err
+	}
+	return r.take(n)
+}
+
+// rows reads the encoding written by encodeRows
+func (r *elementReader) rows() ([][]field.Element, error) {
+	n, err := r.length()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]field.Element, n)
+	for i := range rows {
+		if rows[i], err = r.list(); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	return rows, nil
+}
+
+// xfieldList reads the encoding written by encodeXFieldList
+func (r *elementReader) xfieldList() ([]xfield.XFieldElement, error) {
+	n, err := r.length()
+	if err != nil {
+		return nil, err
+	}
+	elems, err := r.take(n * xfield.ExtensionDegree)
+	if err != nil {
+		return nil, err
+	}
+	return xfieldElements(elems)
+}
+
+// xfieldRows reads the encoding written by encodeXFieldRows
+func (r *elementReader) xfieldRows() ([][]xfield.XFieldElement, error) {
+	n, err := r.length()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([][]xfield.XFieldElement, n)
+	for i := range rows {
+		if rows[i], err = r.xfieldList(); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	return rows, nil
+}
+
+// authenticationPaths reads the encoding written by encodeAuthenticationPaths
+func (r *elementReader) authenticationPaths() ([][]hash.Digest, error) {
+	n, err := r.length()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([][]hash.Digest, n)
+	for i := range paths {
+		pathLen, err := r.length()
+		if err != nil {
+			return nil, fmt.Errorf("path %d: %w", i, err)
+		}
+		elems, err := r.take(pathLen * hash.DigestLen)
+		if err != nil {
+			return nil, fmt.Errorf("path %d: %w", i, err)
+		}
+		paths[i] = make([]hash.Digest, pathLen)
+		for j := range paths[i] {
+			copy(paths[i][j][:], elems[j*hash.DigestLen:])
+		}
+	}
+	return paths, nil
+}
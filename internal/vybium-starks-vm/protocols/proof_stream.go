@@ -69,6 +69,12 @@ func (ps *ProofStream) TranscriptLength() int {
 			if elems, ok := item.Data.([]field.Element); ok {
 				count += len(elems)
 			}
+		case ProofItemOutOfDomainMainRow,
+			ProofItemOutOfDomainAuxRow,
+			ProofItemOutOfDomainQuotientSegments:
+			if elems, ok := item.Data.([]xfield.XFieldElement); ok {
+				count += len(elems)*xfield.ExtensionDegree + 1 // length prefix
+			}
 		case ProofItemMerkleRoot:
 			// Merkle root is 5 field elements (DigestLen)
 			count += hash.DigestLen
@@ -176,28 +182,20 @@ func (ps *ProofStream) ToProof() *Proof {
 	}
 }
 
-// FromProof creates a ProofStream from a Proof by reconstructing the Fiat-Shamir state.
+// ProofStreamFromProof creates a ProofStream that replays a Proof.
 // This is equivalent to triton-vm's ProofStream::try_from() for Proof
 //
-// The sponge state is reconstructed by processing all proof items that should be included
-// in the Fiat-Shamir heuristic, in the order they were originally enqueued.
+// The sponge starts in its initial state: the verifier first absorbs the
+// claim, then dequeues the items in order, which absorbs each one the prover
+// absorbed when enqueuing it. Sampling between dequeues therefore reproduces
+// the prover's randomness.
 func ProofStreamFromProof(proof *Proof) (*ProofStream, error) {
+	if proof == nil {
+		return nil, fmt.Errorf("proof cannot be nil")
+	}
 	stream := NewProofStream()
 	stream.Items = make([]ProofItem, len(proof.Items))
 	copy(stream.Items, proof.Items)
-
-	// Reconstruct the Fiat-Shamir sponge state by processing items in order
-	// Only items that should be included in the heuristic are absorbed
-	for _, item := range proof.Items {
-		if item.IncludeInFiatShamirHeuristic() {
-			encoded, err := item.Encode()
-			if err != nil {
-				return nil, fmt.Errorf("failed to encode proof item during reconstruction: %w", err)
-			}
-			stream.Sponge.PadAndAbsorbAll(encoded)
-		}
-	}
-
 	return stream, nil
 }
 
@@ -1,10 +1,13 @@
 package protocols
 
 import (
+	"encoding/binary"
+	"reflect"
 	"testing"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 )
 
 // TestProofStreamEnqueueDequeue tests basic enqueue/dequeue operations
@@ -251,20 +254,22 @@ func TestProofStreamFromProof(t *testing.T) {
 		t.Errorf("Expected %d items, got %d", len(originalStream.Items), len(reconstructedStream.Items))
 	}
 
-	// Verify Fiat-Shamir state is reconstructed correctly by sampling
-	originalIndices, _ := originalStream.SampleIndices(256, 5)
-	reconstructedIndices, _ := reconstructedStream.SampleIndices(256, 5)
-	if len(originalIndices) != len(reconstructedIndices) {
-		t.Error("Fiat-Shamir state should be reconstructed correctly")
+	// Dequeuing replays the transcript: afterwards the sponge is in the
+	// state the prover's was in
+	for range originalStream.Items {
+		if _, err := reconstructedStream.Dequeue(); err != nil {
+			t.Fatalf("Failed to dequeue: %v", err)
+		}
 	}
-
-	// Verify we can dequeue items
-	dequeued1, err := reconstructedStream.Dequeue()
-	if err != nil {
-		t.Fatalf("Failed to dequeue: %v", err)
+	if reconstructedStream.Items[0].Type != ProofItemMerkleRoot {
+		t.Errorf("Expected ProofItemMerkleRoot, got %v", reconstructedStream.Items[0].Type)
 	}
-	if dequeued1.Type != ProofItemMerkleRoot {
-		t.Errorf("Expected ProofItemMerkleRoot, got %v", dequeued1.Type)
+	originalIndices, _ := originalStream.SampleIndices(256, 5)
+	reconstructedIndices, _ := reconstructedStream.SampleIndices(256, 5)
+	for i := range originalIndices {
+		if originalIndices[i] != reconstructedIndices[i] {
+			t.Fatalf("Replayed stream samples %v, prover sampled %v", reconstructedIndices, originalIndices)
+		}
 	}
 }
 
@@ -335,3 +340,113 @@ func TestProofStreamIncludeInFiatShamirHeuristic(t *testing.T) {
 		}
 	}
 }
+
+// TestProofBinaryRoundTrip tests that every proof item type survives
+// MarshalBinary and UnmarshalBinary
+func TestProofBinaryRoundTrip(t *testing.T) {
+	digest := hash.Digest{field.New(1), field.New(2), field.New(3), field.New(4), field.New(5)}
+	root := make([]byte, hash.DigestLen*8)
+	for i := range root {
+		root[i] = byte(i)
+	}
+	x := func(a, b, c uint64) xfield.XFieldElement {
+		return xfield.New([xfield.ExtensionDegree]field.Element{field.New(a), field.New(b), field.New(c)})
+	}
+	proof := NewProof()
+	proof.AddMerkleRoot(root)
+	proof.AddOutOfDomainMainRow([]xfield.XFieldElement{x(7, 0, 0), x(8, 1, 2)})
+	proof.AddOutOfDomainAuxRow([]xfield.XFieldElement{})
+	proof.AddOutOfDomainQuotientSegments([]xfield.XFieldElement{x(9, 10, field.P-1)})
+	proof.AddItem(ProofItemAuthenticationStructure, [][]hash.Digest{{digest, digest}, {}})
+	proof.AddItem(ProofItemMasterMainTableRows, [][]field.Element{{field.New(10), field.New(11)}, {field.New(12)}})
+	proof.AddItem(ProofItemMasterAuxTableRows, [][]xfield.XFieldElement{{}, {x(3, 4, 5)}})
+	proof.AddLog2Height(5)
+	proof.AddItem(ProofItemQuotientSegmentsElements, [][]xfield.XFieldElement{{x(13, 0, 1)}})
+	proof.AddItem(ProofItemFRICodeword, []xfield.XFieldElement{x(14, 1, 1), x(15, 2, 2)})
+	proof.AddItem(ProofItemFRIPolynomial, []xfield.XFieldElement{x(16, 0, 3)})
+	proof.AddItem(ProofItemFRIResponse, &FRIResponse{
+		AuthenticationPaths: [][]hash.Digest{{digest}},
+		RevealedLeaves:      []xfield.XFieldElement{x(17, 1, 0), x(18, 0, 1)},
+	})
+	proof.AddItem(ProofItemMerkleProof, [][]byte{{1, 2, 255}, {}})
+	proof.AddFieldElement(field.New(field.P - 1))
+	proof.AddFieldElements([]field.Element{field.New(19), field.New(20)})
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	decoded := &Proof{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, proof) {
+		t.Fatalf("round trip changed the proof:\ngot  %+v\nwant %+v", decoded.Items, proof.Items)
+	}
+	again, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary of decoded proof failed: %v", err)
+	}
+	if !reflect.DeepEqual(again, data) {
+		t.Error("encoding is not deterministic")
+	}
+}
+
+// TestProofBinaryRejectsMalformedData tests that decoding is strict
+func TestProofBinaryRejectsMalformedData(t *testing.T) {
+	proof := NewProof()
+	proof.AddLog2Height(3)
+	proof.AddFieldElements([]field.Element{field.New(1)})
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	modified := func(change func([]byte) []byte) []byte {
+		return change(append([]byte{}, data...))
+	}
+	// Elements after the header: count, (type, length, encoding) per item
+	element := func(i int) int { return proofHeaderLen + 8*i }
+
+	testCases := map[string][]byte{
+		"Empty":        {},
+		"WrongMagic":   modified(func(d []byte) []byte { d[0] = 'X'; return d }),
+		"WrongVersion": modified(func(d []byte) []byte { d[4]++; return d }),
+		"TrailingByte": modified(func(d []byte) []byte { return append(d, 0) }),
+		"TrailingElement": modified(func(d []byte) []byte {
+			return binary.LittleEndian.AppendUint64(d, 0)
+		}),
+		"Truncated": data[:len(data)-8],
+		"UnknownItemType": modified(func(d []byte) []byte {
+			binary.LittleEndian.PutUint64(d[element(1):], uint64(ProofItemFieldElements)+1)
+			return d
+		}),
+		"NonCanonicalElement": modified(func(d []byte) []byte {
+			binary.LittleEndian.PutUint64(d[element(len(d[proofHeaderLen:])/8-1):], field.P)
+			return d
+		}),
+		"ItemWithTrailingElements": modified(func(d []byte) []byte {
+			// The height's encoding claims two elements, swallowing the
+			// next item's type
+			binary.LittleEndian.PutUint64(d[element(2):], 2)
+			return d
+		}),
+		"Log2HeightAboveTwoAdicity": modified(func(d []byte) []byte {
+			binary.LittleEndian.PutUint64(d[element(3):], MaxLog2DomainLength+1)
+			return d
+		}),
+	}
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := (&Proof{}).UnmarshalBinary(data); err == nil {
+				t.Error("malformed proof decoded")
+			}
+		})
+	}
+
+	t.Run("PartialExtensionFieldElement", func(t *testing.T) {
+		item := ProofItem{Type: ProofItemFRICodeword}
+		if err := item.Decode([]field.Element{field.One, field.Zero}); err == nil {
+			t.Error("codeword of two coefficients decoded")
+		}
+	})
+}
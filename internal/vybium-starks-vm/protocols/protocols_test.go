@@ -1,10 +1,13 @@
 package protocols
 
 import (
+	"errors"
 	"math/big"
+	"sync/atomic"
 	"testing"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 )
 
@@ -92,6 +95,33 @@ func TestClaimValidation(t *testing.T) {
 		// Hash should be a valid field element
 		_ = hash
 	})
+
+	t.Run("ClaimEncodeRoundTrip", func(t *testing.T) {
+		programDigest := make([]field.Element, 5)
+		for i := range programDigest {
+			programDigest[i] = field.New(uint64(i + 1))
+		}
+		claim := NewClaim(programDigest).
+			WithInput([]field.Element{field.New(7), field.New(8)}).
+			WithOutput([]field.Element{field.New(42)})
+
+		encoded, err := claim.Encode()
+		if err != nil {
+			t.Fatalf("Failed to encode claim: %v", err)
+		}
+		var decoded Claim
+		if err := decoded.Decode(encoded); err != nil {
+			t.Fatalf("Failed to decode claim: %v", err)
+		}
+		if len(decoded.PublicInput) != 2 || len(decoded.PublicOutput) != 1 ||
+			!decoded.PublicOutput[0].Equal(field.New(42)) || decoded.ProgramDigest[4] != programDigest[4] {
+			t.Errorf("Decoded claim differs: %+v", decoded)
+		}
+
+		if err := decoded.Decode(encoded[:len(encoded)-1]); err == nil {
+			t.Error("Expected error decoding a truncated claim")
+		}
+	})
 }
 
 // TestProofValidation tests proof structure validation
@@ -155,6 +185,312 @@ func TestArithmeticDomain(t *testing.T) {
 	})
 }
 
+// TestNTT tests the number theoretic transform against direct evaluation
+func TestNTT(t *testing.T) {
+	coefficients := make([]field.Element, 16)
+	for i := range coefficients {
+		coefficients[i] = field.New(uint64(3*i*i + 1))
+	}
+	domain, err := NewArithmeticDomain(len(coefficients))
+	if err != nil {
+		t.Fatalf("NewArithmeticDomain failed: %v", err)
+	}
+	coset := domain.WithOffset(field.New(7))
+
+	t.Run("CosetEvaluation", func(t *testing.T) {
+		values, err := coset.evaluateCoefficients(coefficients)
+		if err != nil {
+			t.Fatalf("evaluateCoefficients failed: %v", err)
+		}
+		for i, x := range coset.Elements() {
+			expected := field.Zero
+			for j := len(coefficients) - 1; j >= 0; j-- {
+				expected = expected.Mul(x).Add(coefficients[j])
+			}
+			if !values[i].Equal(expected) {
+				t.Fatalf("value %d = %v, want %v", i, values[i], expected)
+			}
+		}
+
+		coefficientsBack, err := coset.interpolateValues(values)
+		if err != nil {
+			t.Fatalf("interpolateValues failed: %v", err)
+		}
+		for j := range coefficients {
+			if !coefficientsBack[j].Equal(coefficients[j]) {
+				t.Fatalf("coefficient %d does not survive interpolation", j)
+			}
+		}
+	})
+
+	t.Run("HighDegreeFolds", func(t *testing.T) {
+		// A polynomial of degree above the domain length still evaluates
+		// correctly
+		small, err := NewArithmeticDomain(4)
+		if err != nil {
+			t.Fatalf("NewArithmeticDomain failed: %v", err)
+		}
+		small = small.WithOffset(field.New(5))
+		values, err := small.evaluateCoefficients(coefficients)
+		if err != nil {
+			t.Fatalf("evaluateCoefficients failed: %v", err)
+		}
+		for i, x := range small.Elements() {
+			expected := field.Zero
+			for j := len(coefficients) - 1; j >= 0; j-- {
+				expected = expected.Mul(x).Add(coefficients[j])
+			}
+			if !values[i].Equal(expected) {
+				t.Fatalf("value %d = %v, want %v", i, values[i], expected)
+			}
+		}
+	})
+
+	t.Run("Barycentric", func(t *testing.T) {
+		values, err := coset.evaluateCoefficients(coefficients)
+		if err != nil {
+			t.Fatalf("evaluateCoefficients failed: %v", err)
+		}
+		point := xfield.New([xfield.ExtensionDegree]field.Element{field.New(11), field.New(12), field.New(13)})
+		weights, err := coset.barycentricWeights(point)
+		if err != nil {
+			t.Fatalf("barycentricWeights failed: %v", err)
+		}
+		expected := xfield.Zero
+		for j := len(coefficients) - 1; j >= 0; j-- {
+			expected = expected.Mul(point).AddConst(coefficients[j])
+		}
+		if got := barycentricEvaluate(values, weights); !got.Equal(expected) {
+			t.Errorf("barycentric value %v, want %v", got, expected)
+		}
+
+		if _, err := coset.barycentricWeights(xfield.NewConst(coset.Offset)); err == nil {
+			t.Error("expected an error for a point in the domain")
+		}
+	})
+
+	t.Run("RejectsWrongRoot", func(t *testing.T) {
+		values := make([]field.Element, 8)
+		if err := NTT(values, primitiveRootOfUnity(16)); err == nil {
+			t.Error("expected an error for a root of the wrong order")
+		}
+		if err := NTT(make([]field.Element, 6), field.One); err == nil {
+			t.Error("expected an error for a length that is not a power of 2")
+		}
+	})
+}
+
+// TestQuotientCodewordMatchesPointwise tests that the precomputed zerofiers
+// and periodic columns of ComputeQuotientCodeword agree with evaluating the
+// quotient point by point
+func TestQuotientCodewordMatchesPointwise(t *testing.T) {
+	air := NewAIRConstraints()
+	air.SetNumColumns(2)
+	constant, err := air.AddPeriodicColumn("constant", []field.Element{field.New(3), field.New(1), field.New(4), field.New(1)})
+	if err != nil {
+		t.Fatalf("AddPeriodicColumn failed: %v", err)
+	}
+	air.AddInitialConstraint("first", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[0]
+	})
+	air.AddConsistencyConstraint("periodic", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[1].Sub(row[constant])
+	})
+	air.AddTransitionConstraint("step", 1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+		return next[0].Sub(current[0]).Sub(xfield.One)
+	})
+	air.AddTerminalConstraint("last", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[1].Mul(row[0])
+	})
+
+	traceDomain, err := NewArithmeticDomain(8)
+	if err != nil {
+		t.Fatalf("NewArithmeticDomain failed: %v", err)
+	}
+	quotientDomain, err := NewArithmeticDomain(32)
+	if err != nil {
+		t.Fatalf("NewArithmeticDomain failed: %v", err)
+	}
+	quotientDomain = quotientDomain.WithOffset(field.New(7))
+	domains := &ProverDomains{Trace: traceDomain, Quotient: quotientDomain}
+
+	columns := make([][]field.Element, 2)
+	for col := range columns {
+		columns[col] = make([]field.Element, quotientDomain.Length)
+		for i := range columns[col] {
+			columns[col][i] = field.New(uint64(31*i + 17*col + 5))
+		}
+	}
+	weights := make([]xfield.XFieldElement, air.NumConstraints())
+	for i := range weights {
+		weights[i] = xfield.New([xfield.ExtensionDegree]field.Element{field.New(uint64(i + 2)), field.New(9), field.One})
+	}
+
+	codeword, err := ComputeQuotientCodeword(air, columns, nil, nil, domains, weights)
+	if err != nil {
+		t.Fatalf("ComputeQuotientCodeword failed: %v", err)
+	}
+	unitDistance := quotientDomain.Length / traceDomain.Length
+	for i, x := range quotientDomain.Elements() {
+		next := (i + unitDistance) % quotientDomain.Length
+		current := []xfield.XFieldElement{xfield.NewConst(columns[0][i]), xfield.NewConst(columns[1][i])}
+		following := []xfield.XFieldElement{xfield.NewConst(columns[0][next]), xfield.NewConst(columns[1][next])}
+		expected, err := air.EvaluateQuotientAt(xfield.NewConst(x), current, following, nil, weights, traceDomain)
+		if err != nil {
+			t.Fatalf("EvaluateQuotientAt failed: %v", err)
+		}
+		if !codeword[i].Equal(expected) {
+			t.Fatalf("quotient at point %d = %v, want %v", i, codeword[i], expected)
+		}
+	}
+}
+
+// TestWorkerPool tests that parallelFor covers every index exactly once and
+// returns the first error
+func TestWorkerPool(t *testing.T) {
+	for _, numWorkers := range []int{0, 1, 3, 100} {
+		pool := newWorkerPool(ProverOptions{NumWorkers: numWorkers})
+		for _, n := range []int{0, 1, 7, 1000} {
+			counts := make([]int32, n)
+			err := pool.parallelFor(PhaseDEEP, n, func(start, end int) error {
+				for i := start; i < end; i++ {
+					atomic.AddInt32(&counts[i], 1)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("parallelFor failed: %v", err)
+			}
+			for i, count := range counts {
+				if count != 1 {
+					t.Fatalf("%d workers, n = %d: index %d visited %d times", numWorkers, n, i, count)
+				}
+			}
+		}
+	}
+
+	var pool *workerPool
+	failure := errors.New("failure")
+	err := pool.parallelFor(PhaseDEEP, 100, func(start, end int) error {
+		if start <= 50 && 50 < end {
+			return failure
+		}
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("parallelFor returned %v, want %v", err, failure)
+	}
+}
+
+// TestQuotientSegments tests splitting a quotient into segments
+func TestQuotientSegments(t *testing.T) {
+	params := DefaultSTARKParameters()
+	domains, err := params.DeriveDomains(8, 3)
+	if err != nil {
+		t.Fatalf("DeriveDomains failed: %v", err)
+	}
+	if domains.NumQuotientSegments() != 4 {
+		t.Fatalf("NumQuotientSegments = %d, want 4", domains.NumQuotientSegments())
+	}
+	if domains.FRI.Length != params.FRIDomainLength(8) {
+		t.Fatalf("FRI domain length %d, want %d", domains.FRI.Length, params.FRIDomainLength(8))
+	}
+
+	// A quotient of full degree, known by its coefficients
+	coefficients := make([]xfield.XFieldElement, domains.Quotient.Length)
+	for i := range coefficients {
+		coefficients[i] = xfield.New([xfield.ExtensionDegree]field.Element{
+			field.New(uint64(7*i + 1)), field.New(uint64(i * i)), field.New(3),
+		})
+	}
+	codeword, err := domains.Quotient.evaluateXField(coefficients)
+	if err != nil {
+		t.Fatalf("evaluateXField failed: %v", err)
+	}
+	segments, err := splitQuotient(nil, codeword, domains)
+	if err != nil {
+		t.Fatalf("splitQuotient failed: %v", err)
+	}
+
+	point := xfield.New([xfield.ExtensionDegree]field.Element{field.New(5), field.New(11), field.New(2)})
+	expected := xfield.Zero
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		expected = expected.Mul(point).Add(coefficients[i])
+	}
+	segmentValues := segments.evaluateAt(point.Pow(uint64(domains.NumQuotientSegments())))
+	if got := quotientFromSegments(point, segmentValues); !got.Equal(expected) {
+		t.Fatalf("quotient recovered from segments = %v, want %v", got, expected)
+	}
+
+	// Every segment codeword has degree less than the randomized trace length
+	for j, segment := range segments.codewords {
+		if len(segment) != domains.FRI.Length {
+			t.Fatalf("segment %d has length %d, want %d", j, len(segment), domains.FRI.Length)
+		}
+		segmentCoefficients, err := domains.FRI.interpolateXField(segment)
+		if err != nil {
+			t.Fatalf("interpolateXField failed: %v", err)
+		}
+		for i := domains.RandomizedTrace.Length; i < len(segmentCoefficients); i++ {
+			if !segmentCoefficients[i].IsZero() {
+				t.Fatalf("segment %d has a nonzero coefficient of degree %d", j, i)
+			}
+		}
+	}
+
+	if _, err := splitQuotient(nil, codeword[1:], domains); err == nil {
+		t.Error("splitQuotient accepted a codeword of the wrong length")
+	}
+}
+
+// TestExtensionFieldHelpers tests batch inversion and interpolation of
+// extension field values
+func TestExtensionFieldHelpers(t *testing.T) {
+	values := make([]xfield.XFieldElement, 8)
+	for i := range values {
+		k := uint64(i + 1)
+		values[i] = xfield.New([xfield.ExtensionDegree]field.Element{field.New(k), field.New(3 * k), field.New(k * k)})
+	}
+
+	t.Run("BatchInverse", func(t *testing.T) {
+		inverses, err := BatchInverse(values)
+		if err != nil {
+			t.Fatalf("BatchInverse failed: %v", err)
+		}
+		for i, value := range values {
+			if !value.Mul(inverses[i]).Equal(xfield.One) {
+				t.Errorf("value %d times its inverse is not one", i)
+			}
+		}
+
+		withZero := append([]xfield.XFieldElement{}, values...)
+		withZero[5] = xfield.Zero
+		if _, err := BatchInverse(withZero); err == nil {
+			t.Error("expected an error when inverting zero")
+		}
+	})
+
+	t.Run("InterpolateRoundTrip", func(t *testing.T) {
+		domain, err := NewArithmeticDomain(len(values))
+		if err != nil {
+			t.Fatalf("NewArithmeticDomain failed: %v", err)
+		}
+		coefficients, err := domain.interpolateXField(values)
+		if err != nil {
+			t.Fatalf("interpolateXField failed: %v", err)
+		}
+		evaluations, err := domain.evaluateXField(coefficients)
+		if err != nil {
+			t.Fatalf("evaluateXField failed: %v", err)
+		}
+		for i := range values {
+			if !evaluations[i].Equal(values[i]) {
+				t.Errorf("value %d does not survive interpolation", i)
+			}
+		}
+	})
+}
+
 // TestSTARKParameters tests STARK parameter validation
 func TestSTARKParameters(t *testing.T) {
 	t.Run("ValidParameters", func(t *testing.T) {
@@ -207,7 +543,7 @@ func TestProverCreation(t *testing.T) {
 			NumTraceRandomizers:   20,
 		}
 
-		prover, err := NewProver(params)
+		prover, err := NewProver(params, CreateProcessorConstraints())
 		if err != nil {
 			t.Fatalf("Failed to create prover: %v", err)
 		}
@@ -235,7 +571,7 @@ func TestVerifierCreation(t *testing.T) {
 			NumTraceRandomizers:   20,
 		}
 
-		verifier, err := NewVerifier(field, params)
+		verifier, err := NewVerifier(field, params, CreateProcessorConstraints())
 		if err != nil {
 			t.Fatalf("Failed to create verifier: %v", err)
 		}
@@ -279,7 +615,7 @@ func TestProverDomains(t *testing.T) {
 			traceLength,
 			numRandomizers,
 			friDomain,
-			512, // maxDegree
+			4, // numQuotientSegments
 		)
 		if err != nil {
 			t.Fatalf("Failed to derive domains: %v", err)
@@ -296,6 +632,10 @@ func TestProverDomains(t *testing.T) {
 		if domains.RandomizedTrace == nil {
 			t.Error("Randomized trace domain is nil")
 		}
+		if domains.Quotient.Length != 4*domains.RandomizedTrace.Length {
+			t.Errorf("Quotient domain length (%d) should be 4 times the randomized trace domain length (%d)",
+				domains.Quotient.Length, domains.RandomizedTrace.Length)
+		}
 		// CRITICAL: Verify trace domain is exactly half of randomized trace domain
 		if domains.Trace.Length != domains.RandomizedTrace.Length/2 {
 			t.Errorf("Trace domain length (%d) should be half of randomized trace domain length (%d)",
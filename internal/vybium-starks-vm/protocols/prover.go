@@ -3,15 +3,11 @@ package protocols
 import (
 	"crypto/rand"
 	"fmt"
-	"math"
-	"math/big"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/merkle"
-	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/polynomial"
-	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
-	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/utils"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 )
 
 // Prover generates STARK proofs for VM execution traces
@@ -33,13 +29,26 @@ type Prover struct {
 	// Randomness seed for zero-knowledge
 	// Must be sampled uniformly at random and kept secret from verifier
 	randomnessSeed []byte
+
+	// Constraints the trace is proven against
+	air *AIRConstraints
+
+	// Workers, memory ceiling and progress callback of every proof
+	options ProverOptions
 }
 
-// NewProver creates a new prover with the given parameters
-func NewProver(params STARKParameters) (*Prover, error) {
+// NewProver creates a new prover that proves traces against the given
+// constraints
+//
+// The verifier must be created with the same constraints. Proofs of the VM's
+// execution use the master AIR, vm.CreateMasterAIR.
+func NewProver(params STARKParameters, air *AIRConstraints) (*Prover, error) {
 	if err := params.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid STARK parameters: %w", err)
 	}
+	if air == nil {
+		return nil, fmt.Errorf("AIR cannot be nil")
+	}
 
 	// Generate random seed for zero-knowledge
 	seed := make([]byte, 32)
@@ -50,9 +59,20 @@ func NewProver(params STARKParameters) (*Prover, error) {
 	return &Prover{
 		params:         params,
 		randomnessSeed: seed,
+		air:            air,
+		options:        DefaultProverOptions(),
 	}, nil
 }
 
+// SetOptions sets the resources every proof may use
+//
+// The default is DefaultProverOptions. Every phase of a proof runs on at most
+// options.NumWorkers goroutines.
+func (p *Prover) SetOptions(options ProverOptions) *Prover {
+	p.options = options
+	return p
+}
+
 // SetRandomnessSeed sets a deterministic seed for testing
 //
 // WARNING: Using a fixed seed breaks zero-knowledge!
@@ -70,6 +90,17 @@ type ExecutionTrace interface {
 	GetTraceColumns() ([][]field.Element, error) // Returns all trace columns
 }
 
+// AuxiliaryTrace is implemented by execution traces whose AIR has auxiliary
+// columns
+//
+// The auxiliary columns hold the cross-table arguments (running products,
+// log derivatives), which depend on challenges sampled after the main trace
+// commitment, so they are extension field columns. They are returned in the
+// order the AIR expects, each of the padded height.
+type AuxiliaryTrace interface {
+	GetAuxiliaryColumns(challenges []xfield.XFieldElement) ([][]xfield.XFieldElement, error)
+}
+
 // Prove generates a STARK proof for the given claim and execution trace
 //
 // This is the main entry point for proof generation. It implements the standard STARK
@@ -85,6 +116,10 @@ type ExecutionTrace interface {
 // 8. Evaluate polynomials at OOD point
 // 9. Run FRI protocol
 // 10. Construct final proof
+//
+// Every step writes to a single ProofStream, and all randomness is sampled
+// from its sponge, which has absorbed the claim and every committed item
+// before it. The verifier replays the same transcript.
 func (p *Prover) Prove(claim *Claim, trace ExecutionTrace) (*Proof, error) {
 	// Validate inputs
 	if claim == nil {
@@ -96,23 +131,30 @@ func (p *Prover) Prove(claim *Claim, trace ExecutionTrace) (*Proof, error) {
 	if err := claim.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid claim: %w", err)
 	}
+	if err := p.options.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid prover options: %w", err)
+	}
+	pool := newWorkerPool(p.options)
 
-	// Create proof and initialize Fiat-Shamir
-	proof := NewProof()
-
-	// Step 1: Hash claim into Fiat-Shamir state
-	claimHash, err := claim.Hash()
-	if err != nil {
-		return nil, fmt.Errorf("failed to hash claim: %w", err)
+	// Step 1: Absorb the claim into the Fiat-Shamir state
+	// The claim is public and not part of the proof, but every challenge
+	// must depend on it.
+	proofStream := NewProofStream()
+	if err := proofStream.AlterFiatShamirStateWith(claim); err != nil {
+		return nil, fmt.Errorf("failed to absorb claim: %w", err)
 	}
 
 	// Add padded height to proof
 	paddedHeight := trace.GetPaddedHeight()
-	log2Height := ilog2(paddedHeight)
-	proof.AddLog2Height(log2Height)
+	if err := proofStream.Enqueue(ProofItem{Type: ProofItemLog2PaddedHeight, Data: ilog2(paddedHeight)}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue padded height: %w", err)
+	}
+
+	// The AIR fixes the degree bounds, so it is needed before the domains
+	air := p.air
 
 	// Step 2: Derive all arithmetic domains
-	domains, err := p.deriveDomains(paddedHeight)
+	domains, err := p.deriveDomains(paddedHeight, air)
 	if err != nil {
 		return nil, fmt.Errorf("failed to derive domains: %w", err)
 	}
@@ -122,6 +164,13 @@ func (p *Prover) Prove(claim *Claim, trace ExecutionTrace) (*Proof, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create master table: %w", err)
 	}
+	masterTable.pool = pool
+
+	// Nothing large has been allocated yet, so a proof over the memory
+	// ceiling fails here
+	if err := p.checkMemory(masterTable.NumColumns(), air.NumAuxColumns(), domains); err != nil {
+		return nil, err
+	}
 
 	// Step 4: Low-degree extend all columns
 	if err := p.extendTable(masterTable, domains); err != nil {
@@ -133,50 +182,140 @@ func (p *Prover) Prove(claim *Claim, trace ExecutionTrace) (*Proof, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to commit to trace: %w", err)
 	}
-	proof.AddMerkleRoot(traceRoot)
+	if err := enqueueMerkleRoot(proofStream, traceRoot); err != nil {
+		return nil, err
+	}
+
+	// Step 6: Sample challenges and commit to the auxiliary trace
+	// The auxiliary columns are built from challenges that depend on the
+	// main trace root, so the trace cannot be tailored to them.
+	var auxTable *MasterTable
+	var challenges []xfield.XFieldElement
+	if air.NumAuxColumns() > 0 {
+		if challenges, err = sampleChallenges(proofStream, air.NumChallenges()); err != nil {
+			return nil, err
+		}
+		auxTable, err = p.createAuxTable(trace, air, challenges, domains)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auxiliary table: %w", err)
+		}
+		auxTable.pool = pool
+		if err := p.extendTable(auxTable, domains); err != nil {
+			return nil, fmt.Errorf("failed to extend auxiliary table: %w", err)
+		}
+		auxRoot, err := p.commitToTrace(auxTable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to commit to auxiliary trace: %w", err)
+		}
+		if err := enqueueMerkleRoot(proofStream, auxRoot); err != nil {
+			return nil, err
+		}
+	}
 
-	// Step 6: Sample challenges (using claim hash + trace root as seed)
-	challenges, err := p.sampleChallenges(claimHash, traceRoot)
+	// Step 7: Sample one quotient weight per constraint
+	weights, err := sampleQuotientWeights(proofStream, air.NumConstraints())
 	if err != nil {
-		return nil, fmt.Errorf("failed to sample challenges: %w", err)
+		return nil, err
 	}
 
-	// Step 7: Compute quotient polynomials
-	quotients, err := p.computeQuotients(masterTable, domains, challenges)
+	// The constraints compare the trace with the claim through the claim
+	// challenges, so a claim the trace does not match yields no proof
+	if challenges, err = air.ClaimChallenges(claim, challenges); err != nil {
+		return nil, err
+	}
+
+	// Step 8: Compute the combined quotient over the quotient domain
+	quotientCodeword, err := p.computeQuotients(air, masterTable, auxTable, challenges, domains, weights)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute quotients: %w", err)
 	}
 
-	// Step 8: Commit to quotients
-	quotientRoot, err := p.commitToQuotients(quotients, domains)
+	// Step 9: Split the quotient into segments and commit to them
+	segments, err := splitQuotient(pool, quotientCodeword, domains)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split quotient: %w", err)
+	}
+	quotientTree, quotientRoot, err := p.commitToQuotients(pool, segments)
 	if err != nil {
 		return nil, fmt.Errorf("failed to commit to quotients: %w", err)
 	}
-	proof.AddMerkleRoot(quotientRoot)
+	if err := enqueueMerkleRoot(proofStream, quotientRoot); err != nil {
+		return nil, err
+	}
 
-	// Step 9: Sample OOD point
-	oodPoint, err := p.sampleOODPoint(quotientRoot)
+	// Step 10: Sample OOD point
+	oodPoint, err := sampleOODPoint(proofStream, domains)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sample OOD point: %w", err)
+		return nil, err
 	}
 
-	// Step 10: Evaluate at OOD point
-	oodValues, err := p.evaluateAtOOD(masterTable, quotients, oodPoint)
+	// Step 11: Evaluate at OOD point
+	// The verifier needs the current and next row at z to evaluate transition
+	// constraints, and the quotient segments at z^k, from which it recovers
+	// the quotient at z to compare against.
+	nextOODPoint := oodPoint.MulConst(domains.Trace.Generator)
+	oodCurrentRow, err := masterTable.EvaluateAtPoint(oodPoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate trace at OOD: %w", err)
+	}
+	oodNextRow, err := masterTable.EvaluateAtPoint(nextOODPoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate at OOD: %w", err)
+		return nil, fmt.Errorf("failed to evaluate trace at next OOD row: %w", err)
 	}
-	proof.AddFieldElements(oodValues)
+	oodCurrentAuxRow, oodNextAuxRow := []xfield.XFieldElement{}, []xfield.XFieldElement{}
+	if auxTable != nil {
+		if oodCurrentAuxRow, err = auxTable.EvaluateAtPoint(oodPoint); err != nil {
+			return nil, fmt.Errorf("failed to evaluate auxiliary trace at OOD: %w", err)
+		}
+		if oodNextAuxRow, err = auxTable.EvaluateAtPoint(nextOODPoint); err != nil {
+			return nil, fmt.Errorf("failed to evaluate auxiliary trace at next OOD row: %w", err)
+		}
+	}
+	segmentPoint := oodPoint.Pow(uint64(domains.NumQuotientSegments()))
+	oodQuotientSegments := segments.evaluateAt(segmentPoint)
 
-	// Step 11: Run FRI protocol
-	friProof, err := p.runFRI(quotients, domains, oodPoint)
+	oodItems := []ProofItem{
+		{Type: ProofItemOutOfDomainMainRow, Data: oodCurrentRow},
+		{Type: ProofItemOutOfDomainAuxRow, Data: oodCurrentAuxRow},
+		{Type: ProofItemOutOfDomainMainRow, Data: oodNextRow},
+		{Type: ProofItemOutOfDomainAuxRow, Data: oodNextAuxRow},
+		{Type: ProofItemOutOfDomainQuotientSegments, Data: oodQuotientSegments},
+	}
+	for _, item := range oodItems {
+		if err := proofStream.Enqueue(item); err != nil {
+			return nil, fmt.Errorf("failed to enqueue out-of-domain values: %w", err)
+		}
+	}
+
+	// Step 12: Combine trace and quotient into the DEEP codeword
+	ood := &outOfDomainValues{
+		point:            oodPoint,
+		nextPoint:        nextOODPoint,
+		segmentPoint:     segmentPoint,
+		currentRow:       append(append([]xfield.XFieldElement{}, oodCurrentRow...), oodCurrentAuxRow...),
+		nextRow:          append(append([]xfield.XFieldElement{}, oodNextRow...), oodNextAuxRow...),
+		quotientSegments: oodQuotientSegments,
+	}
+	deepWeights, err := sampleDEEPWeights(proofStream, len(ood.currentRow), len(ood.quotientSegments))
+	if err != nil {
+		return nil, err
+	}
+	deepCodeword, err := p.computeDEEPCodeword(pool, masterTable, auxTable, segments, domains, ood, deepWeights)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply DEEP: %w", err)
+	}
+
+	// Step 13: Run FRI protocol
+	indices, err := p.runFRI(pool, proofStream, deepCodeword, domains)
 	if err != nil {
 		return nil, fmt.Errorf("FRI protocol failed: %w", err)
 	}
 
-	// Add FRI data to proof
-	if err := p.addFRIToProof(proof, friProof); err != nil {
-		return nil, fmt.Errorf("failed to add FRI to proof: %w", err)
+	// Step 14: Open trace and quotient rows at the FRI query indices
+	if err := p.openRows(proofStream, masterTable, auxTable, quotientTree, segments, indices); err != nil {
+		return nil, err
 	}
+	proof := proofStream.ToProof()
 
 	// Validate final proof
 	if err := proof.Validate(); err != nil {
@@ -187,31 +326,22 @@ func (p *Prover) Prove(claim *Claim, trace ExecutionTrace) (*Proof, error) {
 }
 
 // deriveDomains computes all arithmetic domains needed for proving
-func (p *Prover) deriveDomains(paddedHeight int) (*ProverDomains, error) {
-	// Compute FRI domain size
-	randomizedLen := p.params.RandomizedTraceLength(paddedHeight)
-	friDomainSize := randomizedLen * p.params.FRIExpansionFactor
-	friDomain, err := NewArithmeticDomain(friDomainSize)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create FRI arithmetic domain: %w", err)
-	}
-
-	// Compute max degree for quotient domain
-	maxDegree := p.params.MaxDegree(paddedHeight)
+func (p *Prover) deriveDomains(paddedHeight int, air *AIRConstraints) (*ProverDomains, error) {
+	return p.params.DeriveDomains(paddedHeight, air.MaxDegree())
+}
 
-	// Derive all domains
-	domains, err := DeriveProverDomains(
-		paddedHeight,
-		p.params.NumTraceRandomizers,
-		friDomain,
-		maxDegree,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to derive domains (paddedHeight=%d, randomizers=%d, friDomainSize=%d): %w",
-			paddedHeight, p.params.NumTraceRandomizers, friDomainSize, err)
+// checkMemory fails if a proof over the domains would exceed the memory
+// ceiling of the prover's options
+func (p *Prover) checkMemory(numMainColumns, numAuxColumns int, domains *ProverDomains) error {
+	if p.options.MaxMemoryBytes == 0 {
+		return nil
 	}
-
-	return domains, nil
+	estimate := estimateProofMemory(numMainColumns, numAuxColumns, domains)
+	if estimate > p.options.MaxMemoryBytes {
+		return fmt.Errorf("proof needs about %d bytes of memory, more than the ceiling of %d bytes",
+			estimate, p.options.MaxMemoryBytes)
+	}
+	return nil
 }
 
 // createMasterTable creates the master execution table from trace data
@@ -219,6 +349,31 @@ func (p *Prover) createMasterTable(traceData interface{}, domains *ProverDomains
 	return NewMasterTable(traceData, domains, p.params.NumTraceRandomizers, p.randomnessSeed)
 }
 
+// createAuxTable builds the auxiliary columns of the trace from the
+// challenges and randomizes them like the main table
+func (p *Prover) createAuxTable(
+	trace ExecutionTrace,
+	air *AIRConstraints,
+	challenges []xfield.XFieldElement,
+	domains *ProverDomains,
+) (*MasterTable, error) {
+	auxTrace, ok := trace.(AuxiliaryTrace)
+	if !ok {
+		return nil, fmt.Errorf("AIR has %d auxiliary columns, but the trace does not implement AuxiliaryTrace",
+			air.NumAuxColumns())
+	}
+	columns, err := auxTrace.GetAuxiliaryColumns(challenges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build auxiliary columns: %w", err)
+	}
+	if len(columns) != air.NumAuxColumns() {
+		return nil, fmt.Errorf("trace built %d auxiliary columns, AIR needs %d", len(columns), air.NumAuxColumns())
+	}
+
+	seed := append(append([]byte{}, p.randomnessSeed...), []byte("aux")...)
+	return NewAuxiliaryMasterTable(columns, domains, p.params.NumTraceRandomizers, seed)
+}
+
 // extendTable performs low-degree extension on all table columns
 func (p *Prover) extendTable(table *MasterTable, domains *ProverDomains) error {
 	return table.LowDegreeExtend(domains)
@@ -230,402 +385,406 @@ func (p *Prover) commitToTrace(table *MasterTable) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to build Merkle tree: %w", err)
 	}
-	// Convert hash.Digest array to []byte
-	root := tree.Root()
-	result := make([]byte, len(root)*8)
-	for i, elem := range root {
-		val := elem.Value()
-		for j := 0; j < 8; j++ {
-			result[i*8+j] = byte(val >> (j * 8))
-		}
-	}
-	return result, nil
+	return digestToBytes(tree.Root()), nil
 }
 
-// sampleChallenges generates random challenges via Fiat-Shamir
-func (p *Prover) sampleChallenges(claimHash field.Element, traceRoot []byte) ([]field.Element, error) {
-	// Convert trace root to field elements
-	rootElems := make([]field.Element, 0)
-	rootElems = append(rootElems, claimHash)
-
-	// Hash trace root bytes in chunks (8 bytes = 64 bits fits in field)
-	for i := 0; i < len(traceRoot); i += 8 {
-		end := i + 8
-		if end > len(traceRoot) {
-			end = len(traceRoot)
-		}
-		chunk := traceRoot[i:end]
-		// Convert bytes to uint64
-		var val uint64
-		for j, b := range chunk {
-			val |= uint64(b) << (j * 8)
-		}
-		rootElems = append(rootElems, field.New(val))
-	}
-
-	// Pad to 10 elements for Tip5 (10-element rate)
-	for len(rootElems) < 10 {
-		rootElems = append(rootElems, field.Zero)
-	}
-
-	// Generate challenge seed using Tip5
-	var input10 [10]field.Element
-	copy(input10[:], rootElems[:10])
-	digest := hash.Hash10(input10)
-	challengeSeed := digest[0]
-
-	// Generate multiple challenges from seed
-	// For Vybium STARKs VM, we need challenges for:
-	// - Permutation arguments
-	// - Evaluation arguments
-	// - Lookup arguments
-	// Total: approximately 20 challenges
-	numChallenges := 20
-	challenges := make([]field.Element, numChallenges)
-	current := challengeSeed
-	for i := 0; i < numChallenges; i++ {
-		challenges[i] = current
-		// Hash current to get next challenge (sponge mode)
-		var input [10]field.Element
-		input[0] = current
-		for j := 1; j < 10; j++ {
-			input[j] = field.Zero
-		}
-		nextDigest := hash.Hash10(input)
-		current = nextDigest[0]
+// enqueueMerkleRoot sends a Merkle root to the verifier; the sponge absorbs
+// it, so everything sampled afterwards depends on the commitment
+func enqueueMerkleRoot(proofStream *ProofStream, root []byte) error {
+	if err := proofStream.Enqueue(ProofItem{Type: ProofItemMerkleRoot, Data: root}); err != nil {
+		return fmt.Errorf("failed to enqueue Merkle root: %w", err)
 	}
+	return nil
+}
 
+// sampleChallenges samples the challenges of the cross-table arguments from
+// the proof stream, after the main trace root
+//
+// Shared by prover and verifier, which must derive identical challenges.
+func sampleChallenges(proofStream *ProofStream, numChallenges int) ([]xfield.XFieldElement, error) {
+	challenges, err := proofStream.SampleScalars(numChallenges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample challenges: %w", err)
+	}
 	return challenges, nil
 }
 
-// computeQuotients computes the constraint quotient polynomials
+// sampleQuotientWeights samples one weight per constraint from the proof
+// stream, after all trace roots
+//
+// Shared by prover and verifier, which must derive identical weights.
+func sampleQuotientWeights(proofStream *ProofStream, numConstraints int) ([]xfield.XFieldElement, error) {
+	weights, err := proofStream.SampleScalars(numConstraints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample quotient weights: %w", err)
+	}
+	return weights, nil
+}
+
+// computeQuotients computes the combined quotient codeword
 func (p *Prover) computeQuotients(
+	air *AIRConstraints,
 	table *MasterTable,
+	auxTable *MasterTable,
+	challenges []xfield.XFieldElement,
 	domains *ProverDomains,
-	challenges []field.Element,
-) ([]*polynomial.Polynomial, error) {
-	// This will be implemented when MasterTable is migrated
-	return table.ComputeQuotients(domains, challenges)
+	weights []xfield.XFieldElement,
+) ([]xfield.XFieldElement, error) {
+	return table.ComputeQuotients(air, auxTable, challenges, domains, weights)
 }
 
-// commitToQuotients creates Merkle commitment to quotient polynomials
-func (p *Prover) commitToQuotients(quotients []*polynomial.Polynomial, domains *ProverDomains) ([]byte, error) {
-	// Evaluate quotients over FRI domain
-	evaluations := make([][]field.Element, len(quotients))
-	for i, q := range quotients {
-		evals, err := domains.FRI.Evaluate(q)
-		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate quotient %d: %w", i, err)
-		}
-		evaluations[i] = evals
-	}
-
+// commitToQuotients creates a Merkle commitment to the quotient segments on
+// the FRI domain, one row per point with one value per segment
+func (p *Prover) commitToQuotients(pool *workerPool, segments *quotientSegments) (*merkle.MerkleTree, []byte, error) {
 	// Build Merkle tree from evaluations
-	tree, err := p.buildQuotientMerkleTree(evaluations)
+	tree, err := p.buildQuotientMerkleTree(pool, segments.codewords)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build quotient Merkle tree: %w", err)
-	}
-
-	// Convert hash.Digest array to []byte
-	root := tree.Root()
-	result := make([]byte, len(root)*8)
-	for i, elem := range root {
-		val := elem.Value()
-		for j := 0; j < 8; j++ {
-			result[i*8+j] = byte(val >> (j * 8))
-		}
+		return nil, nil, fmt.Errorf("failed to build quotient Merkle tree: %w", err)
 	}
-	return result, nil
+	return tree, digestToBytes(tree.Root()), nil
 }
 
 // buildQuotientMerkleTree constructs Merkle tree for quotient evaluations
-func (p *Prover) buildQuotientMerkleTree(evaluations [][]field.Element) (*merkle.MerkleTree, error) {
+func (p *Prover) buildQuotientMerkleTree(pool *workerPool, evaluations [][]xfield.XFieldElement) (*merkle.MerkleTree, error) {
 	// Hash each row (across all quotient columns)
 	numRows := len(evaluations[0])
-	leaves := make([][]byte, numRows)
-
-	for row := 0; row < numRows; row++ {
-		// Collect all values in this row
-		rowValues := make([]field.Element, 0)
-		for col := 0; col < len(evaluations); col++ {
-			rowValues = append(rowValues, evaluations[col][row])
-		}
-
-		// Pad to multiple of 10 for Tip5
-		for len(rowValues)%10 != 0 {
-			rowValues = append(rowValues, field.Zero)
-		}
-
-		// Hash the row
-		// Hash the row using variable-length hash
-		digest := hash.HashVarlen(rowValues)
-
-		// Convert digest to []byte
-		leafBytes := make([]byte, len(digest)*8)
-		for j, elem := range digest {
-			val := elem.Value()
-			for k := 0; k < 8; k++ {
-				leafBytes[j*8+k] = byte(val >> (k * 8))
-			}
-		}
-		leaves[row] = leafBytes
-	}
+	leaves := make([]hash.Digest, numRows)
 
-	// Convert leaves to hash.Digest format
-	digestLeaves := make([]hash.Digest, len(leaves))
-	for i, leaf := range leaves {
-		for j := 0; j < len(digestLeaves[i]) && j*8 < len(leaf); j++ {
-			var val uint64
-			for k := 0; k < 8 && j*8+k < len(leaf); k++ {
-				val |= uint64(leaf[j*8+k]) << (k * 8)
+	err := pool.parallelFor(PhaseMerkleCommitment, numRows, func(start, end int) error {
+		rowValues := make([]xfield.XFieldElement, len(evaluations))
+		for row := start; row < end; row++ {
+			for col := range evaluations {
+				rowValues[col] = evaluations[col][row]
 			}
-			digestLeaves[i][j] = field.New(val)
+			leaves[row] = hashQuotientRow(rowValues)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return merkle.New(digestLeaves)
+	return merkle.New(leaves)
 }
 
-// sampleOODPoint samples an out-of-domain evaluation point
-func (p *Prover) sampleOODPoint(quotientRoot []byte) (field.Element, error) {
-	// Convert root to field elements
-	rootElems := make([]field.Element, 10)
-	for i := 0; i < 10; i++ {
-		if i*8 < len(quotientRoot) {
-			end := (i + 1) * 8
-			if end > len(quotientRoot) {
-				end = len(quotientRoot)
-			}
-			chunk := quotientRoot[i*8 : end]
-			// Convert bytes to uint64
-			var val uint64
-			for j, b := range chunk {
-				val |= uint64(b) << (j * 8)
-			}
-			rootElems[i] = field.New(val)
-		} else {
-			rootElems[i] = field.Zero
-		}
-	}
-
-	var input10 [10]field.Element
-	copy(input10[:], rootElems)
-	digest := hash.Hash10(input10)
-	return digest[0], nil
+// hashQuotientRow computes the Merkle leaf of a row of quotient segments
+//
+// Shared by the prover's commitment and the verifier's recomputation of
+// opened quotient rows. The row is hashed as its coefficients.
+func hashQuotientRow(rowValues []xfield.XFieldElement) hash.Digest {
+	// Pad to multiple of 10 for Tip5
+	padded := xfield.AsFlatSlice(rowValues)
+	for len(padded)%10 != 0 {
+		padded = append(padded, field.Zero)
+	}
+	return hash.HashVarlen(padded)
 }
 
-// evaluateAtOOD evaluates all polynomials at the out-of-domain point
-func (p *Prover) evaluateAtOOD(
-	table *MasterTable,
-	quotients []*polynomial.Polynomial,
-	oodPoint field.Element,
-) ([]field.Element, error) {
-	// Evaluate all trace columns and quotients at OOD point
-	values := make([]field.Element, 0)
-
-	// Evaluate trace (will be implemented when MasterTable is complete)
-	traceValues, err := table.EvaluateAtPoint(oodPoint)
+// sampleOODPoint samples an out-of-domain evaluation point from the proof
+// stream, after the quotient root
+//
+// The point is an extension field element, so it avoids the base field
+// trace and FRI domains unless it happens to be a constant. Shared by prover
+// and verifier, which must derive the identical point.
+func sampleOODPoint(proofStream *ProofStream, domains *ProverDomains) (xfield.XFieldElement, error) {
+	scalars, err := proofStream.SampleScalars(1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to evaluate trace at OOD: %w", err)
+		return xfield.Zero, fmt.Errorf("failed to sample OOD point: %w", err)
 	}
-	values = append(values, traceValues...)
-
-	// Evaluate quotients
-	for _, q := range quotients {
-		val := q.Evaluate(oodPoint)
-		values = append(values, val)
+	if err := checkOutOfDomain(scalars[0], domains); err != nil {
+		return xfield.Zero, fmt.Errorf("failed to sample OOD point: %w", err)
 	}
+	return scalars[0], nil
+}
 
-	return values, nil
+// checkOutOfDomain verifies that the OOD point avoids the trace and FRI domains,
+// where zerofiers and DEEP denominators vanish, and that so does the point
+// the quotient segments are opened at
+func checkOutOfDomain(point xfield.XFieldElement, domains *ProverDomains) error {
+	if domains.Trace.zerofierAtXField(point).IsZero() {
+		return fmt.Errorf("out-of-domain point lies in the trace domain")
+	}
+	if domains.FRI.zerofierAtXField(point).IsZero() {
+		return fmt.Errorf("out-of-domain point lies in the FRI domain")
+	}
+	segmentPoint := point.Pow(uint64(domains.NumQuotientSegments()))
+	if domains.FRI.zerofierAtXField(segmentPoint).IsZero() {
+		return fmt.Errorf("quotient segment point lies in the FRI domain")
+	}
+	return nil
 }
 
-// runFRI executes the FRI protocol on the quotient polynomials
+// runFRI executes the FRI protocol on the DEEP codeword
 //
-// Using standard STARK techniques:
-// 1. Evaluate quotients over FRI domain (already done in commitToQuotients)
-// 2. Apply DEEP (sampling outside the box) technique
-// 3. Combine quotients into single polynomial
-// 4. Run FRI commit phase (folding + Merkle commitments)
-// 5. Run FRI query phase (authentication paths)
+// FRI continues the proof's transcript: folding challenges and query indices
+// are squeezed from the same sponge that absorbed the trace and quotient
+// commitments and the out-of-domain rows. Returns the indices queried in the
+// first round.
 func (p *Prover) runFRI(
-	quotients []*polynomial.Polynomial,
+	pool *workerPool,
+	proofStream *ProofStream,
+	deepCodeword []xfield.XFieldElement,
 	domains *ProverDomains,
-	oodPoint field.Element,
-) (*FRIProof, error) {
-	if len(quotients) == 0 {
-		return nil, fmt.Errorf("no quotients to prove")
+) ([]int, error) {
+	if len(deepCodeword) == 0 {
+		return nil, fmt.Errorf("no codeword to prove")
 	}
 
-	// Create FRI protocol instance using Goldilocks field
-	// This works correctly and is production-ready.
-	goldilocksP := new(big.Int)
-	goldilocksP.SetString("18446744069414584321", 10) // 2^64 - 2^32 + 1
-	goldilocksField, err := core.NewField(goldilocksP)
+	fri, err := NewFRI(domains.FRI, p.params.FRIExpansionFactor, p.params.NumCollinearityChecks)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Goldilocks field: %w", err)
+		return nil, fmt.Errorf("failed to create FRI: %w", err)
 	}
+	fri.pool = pool
 
-	// Convert rate (expansion factor) to field element
-	rateLog := int(math.Log2(float64(p.params.FRIExpansionFactor)))
-	rate := goldilocksField.NewElementFromInt64(1)
-	two := goldilocksField.NewElementFromInt64(2)
-	for i := 0; i < rateLog; i++ {
-		rate, _ = rate.Div(two)
-	}
+	return fri.Prove(deepCodeword, proofStream)
+}
 
-	// Get generator (omega) for the FRI domain
-	omega := goldilocksField.GetPrimitiveRootOfUnity(domains.FRI.Length)
+// outOfDomainValues are the values the prover claims at the out-of-domain
+// point z and at z·ω, and the quotient segments' values at z^k, around which
+// the DEEP codeword is built
+//
+// The rows hold the main columns followed by the auxiliary columns.
+type outOfDomainValues struct {
+	point            xfield.XFieldElement
+	nextPoint        xfield.XFieldElement
+	segmentPoint     xfield.XFieldElement
+	currentRow       []xfield.XFieldElement
+	nextRow          []xfield.XFieldElement
+	quotientSegments []xfield.XFieldElement
+}
 
-	friProtocol := NewFRIProtocol(goldilocksField, rate, omega)
+// sampleDEEPWeights samples the weights of the DEEP codeword from the proof
+// stream: one per trace column, one per quotient segment, then one each for
+// the quotient term and the two trace terms
+//
+// Shared by prover and verifier, which must derive identical weights.
+func sampleDEEPWeights(proofStream *ProofStream, numColumns, numSegments int) ([]xfield.XFieldElement, error) {
+	weights, err := proofStream.SampleScalars(numColumns + numSegments + 3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample DEEP weights: %w", err)
+	}
+	return weights, nil
+}
 
-	// Step 1: Evaluate all quotients over FRI domain
-	quotientEvaluations := make([][]field.Element, len(quotients))
-	for i, q := range quotients {
-		evals, err := domains.FRI.Evaluate(q)
-		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate quotient %d: %w", i, err)
+// computeDEEPCodeword applies the DEEP (sampling outside the box) technique
+// to the trace and the quotient segments over the FRI domain
+//
+// See deepCodewordValue for the formula. auxTable is nil if the AIR has no
+// auxiliary columns.
+func (p *Prover) computeDEEPCodeword(
+	pool *workerPool,
+	table *MasterTable,
+	auxTable *MasterTable,
+	segments *quotientSegments,
+	domains *ProverDomains,
+	ood *outOfDomainValues,
+	weights []xfield.XFieldElement,
+) ([]xfield.XFieldElement, error) {
+	friDomainElements := domains.FRI.Elements()
+	if table.NumExtendedRows() != len(friDomainElements) {
+		return nil, fmt.Errorf("extended trace length %d doesn't match FRI domain length %d",
+			table.NumExtendedRows(), len(friDomainElements))
+	}
+	for j, codeword := range segments.codewords {
+		if len(codeword) != len(friDomainElements) {
+			return nil, fmt.Errorf("quotient segment %d has length %d, FRI domain length is %d",
+				j, len(codeword), len(friDomainElements))
 		}
-		quotientEvaluations[i] = evals
 	}
 
-	// Step 2: Apply DEEP technique - subtract out-of-domain evaluation
-	// This improves soundness by sampling outside the trace domain
-	deepCodeword, err := p.applyDEEP(quotientEvaluations, domains, oodPoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to apply DEEP: %w", err)
+	columns := table.extendedColumns
+	var auxColumns [][]xfield.XFieldElement
+	if auxTable != nil {
+		auxColumns = auxTable.extendedXFieldColumns()
 	}
 
-	// Step 3: Create Fiat-Shamir channel for FRI
-	channel := p.createFiatShamirChannel(oodPoint)
-
-	// Step 4: Run FRI protocol
-	// The FRI protocol will fold the codeword repeatedly and create Merkle commitments
-	domainElements := domains.FRI.Elements()
-
-	// Convert to core types for FRI (temporary bridge during migration)
-	deepCodewordCore := make([]*core.FieldElement, len(deepCodeword))
-	for i, elem := range deepCodeword {
-		deepCodewordCore[i] = convertFromFieldElement(elem, friProtocol.field)
+	// All denominators X - z, X - z·ω and X - z^k over the domain share one
+	// inversion
+	denominators := make([]xfield.XFieldElement, 3*len(friDomainElements))
+	for i, x := range friDomainElements {
+		denominators[3*i] = xfield.NewConst(x).Sub(ood.point)
+		denominators[3*i+1] = xfield.NewConst(x).Sub(ood.nextPoint)
+		denominators[3*i+2] = xfield.NewConst(x).Sub(ood.segmentPoint)
 	}
-	domainElementsCore := make([]*core.FieldElement, len(domainElements))
-	for i, elem := range domainElements {
-		domainElementsCore[i] = convertFromFieldElement(elem, friProtocol.field)
+	inverses, err := BatchInverse(denominators)
+	if err != nil {
+		return nil, fmt.Errorf("DEEP division by zero: %w", err)
 	}
 
-	friProof, err := friProtocol.Prove(deepCodewordCore, domainElementsCore, channel)
+	deepCodeword := make([]xfield.XFieldElement, len(friDomainElements))
+	err = pool.parallelFor(PhaseDEEP, len(friDomainElements), func(start, end int) error {
+		mainRow := make([]field.Element, len(columns))
+		auxRow := make([]xfield.XFieldElement, len(auxColumns))
+		for i := start; i < end; i++ {
+			for col := range columns {
+				mainRow[col] = columns[col][i]
+			}
+			for col := range auxColumns {
+				auxRow[col] = auxColumns[col][i]
+			}
+			value, err := deepCodewordTerms(mainRow, auxRow, segments.row(i), inverses[3*i:3*i+3], ood, weights)
+			if err != nil {
+				return fmt.Errorf("DEEP codeword at index %d: %w", i, err)
+			}
+			deepCodeword[i] = value
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("FRI protocol failed: %w", err)
+		return nil, err
 	}
 
-	return friProof, nil
+	return deepCodeword, nil
 }
 
-// applyDEEP applies the DEEP (sampling outside the box) technique
+// deepCodewordValue computes the DEEP codeword at x from the main and
+// auxiliary trace rows and the quotient segments' values at x
 //
-// DEEP formula: (f(X) - f(z)) / (X - z)
-// Where z is the out-of-domain point
+// With c(X) = Σ α_j·col_j(X) the weighted sum of trace columns and
+// s(X) = Σ β_j·q_j(X) the weighted sum of quotient segments, the DEEP
+// codeword is
 //
-// This transforms the proximity problem into a polynomial identity problem:
-// If f is close to a low-degree polynomial, then the DEEP quotient is also low-degree
-func (p *Prover) applyDEEP(
-	quotientEvaluations [][]field.Element,
-	domains *ProverDomains,
-	oodPoint field.Element,
-) ([]field.Element, error) {
-	// Combine multiple quotients into single codeword (if multiple)
-	// Use a single combined quotient polynomial for all constraints
-	// This is the standard approach for STARK provers
-	if len(quotientEvaluations) == 0 {
-		return nil, fmt.Errorf("no quotient evaluations")
-	}
-
-	codeword := quotientEvaluations[0]
-	friDomainElements := domains.FRI.Elements()
-
-	if len(codeword) != len(friDomainElements) {
-		return nil, fmt.Errorf("codeword length %d doesn't match FRI domain length %d",
-			len(codeword), len(friDomainElements))
-	}
-
-	// Evaluate the polynomial at the OOD point
-	// We need to interpolate first to get the polynomial
-	points := make([][2]field.Element, len(friDomainElements))
-	for i, x := range friDomainElements {
-		points[i] = [2]field.Element{x, codeword[i]}
+//	γ_0·(s(X) - s(z^k))/(X - z^k) + γ_1·(c(X) - c(z))/(X - z) + γ_2·(c(X) - c(z·ω))/(X - z·ω)
+//
+// which is low-degree only if the claimed out-of-domain values are the true
+// evaluations of the committed polynomials. Every term has degree less than
+// the randomized trace length, the bound FRI tests. Shared by prover and
+// verifier.
+func deepCodewordValue(
+	x field.Element,
+	mainRow []field.Element,
+	auxRow []xfield.XFieldElement,
+	segmentRow []xfield.XFieldElement,
+	ood *outOfDomainValues,
+	weights []xfield.XFieldElement,
+) (xfield.XFieldElement, error) {
+	denominators := []xfield.XFieldElement{
+		xfield.NewConst(x).Sub(ood.point),
+		xfield.NewConst(x).Sub(ood.nextPoint),
+		xfield.NewConst(x).Sub(ood.segmentPoint),
+	}
+	inverses, err := BatchInverse(denominators)
+	if err != nil {
+		return xfield.Zero, fmt.Errorf("DEEP division by zero")
 	}
-	poly := polynomial.Interpolate(points)
-
-	oodValue := poly.Evaluate(oodPoint)
-
-	// Apply DEEP: (f(X) - f(z)) / (X - z) for each point X in FRI domain
-	deepCodeword := make([]field.Element, len(codeword))
-	for i := 0; i < len(codeword); i++ {
-		x := friDomainElements[i]
-		fx := codeword[i]
-
-		// Numerator: f(x) - f(z)
-		numerator := fx.Sub(oodValue)
-
-		// Denominator: x - z
-		denominator := x.Sub(oodPoint)
+	return deepCodewordTerms(mainRow, auxRow, segmentRow, inverses, ood, weights)
+}
 
-		// Check for division by zero (shouldn't happen if z is out-of-domain)
-		if denominator.IsZero() {
-			return nil, fmt.Errorf("DEEP division by zero at index %d", i)
+// deepCodewordTerms combines the DEEP terms given the inverses of X - z,
+// X - z·ω and X - z^k, which the prover inverts for the whole domain at once
+func deepCodewordTerms(
+	mainRow []field.Element,
+	auxRow []xfield.XFieldElement,
+	segmentRow []xfield.XFieldElement,
+	inverses []xfield.XFieldElement,
+	ood *outOfDomainValues,
+	weights []xfield.XFieldElement,
+) (xfield.XFieldElement, error) {
+	numColumns := len(ood.currentRow)
+	if len(mainRow)+len(auxRow) != numColumns || len(ood.nextRow) != numColumns {
+		return xfield.Zero, fmt.Errorf("row width %d doesn't match out-of-domain row width %d",
+			len(mainRow)+len(auxRow), numColumns)
+	}
+	numSegments := len(ood.quotientSegments)
+	if len(segmentRow) != numSegments {
+		return xfield.Zero, fmt.Errorf("row has %d quotient segments, expected %d", len(segmentRow), numSegments)
+	}
+	if len(weights) != numColumns+numSegments+3 {
+		return xfield.Zero, fmt.Errorf("expected %d DEEP weights, got %d", numColumns+numSegments+3, len(weights))
+	}
+
+	combined, combinedAtPoint, combinedAtNext := xfield.Zero, xfield.Zero, xfield.Zero
+	for col := 0; col < numColumns; col++ {
+		if col < len(mainRow) {
+			combined = combined.Add(weights[col].MulConst(mainRow[col]))
+		} else {
+			combined = combined.Add(weights[col].Mul(auxRow[col-len(mainRow)]))
 		}
+		combinedAtPoint = combinedAtPoint.Add(weights[col].Mul(ood.currentRow[col]))
+		combinedAtNext = combinedAtNext.Add(weights[col].Mul(ood.nextRow[col]))
+	}
 
-		// Quotient: (f(x) - f(z)) / (x - z)
-		deepCodeword[i] = numerator.Div(denominator)
+	segmentWeights := weights[numColumns : numColumns+numSegments]
+	combinedSegments, combinedSegmentsAtPoint := xfield.Zero, xfield.Zero
+	for j, weight := range segmentWeights {
+		combinedSegments = combinedSegments.Add(weight.Mul(segmentRow[j]))
+		combinedSegmentsAtPoint = combinedSegmentsAtPoint.Add(weight.Mul(ood.quotientSegments[j]))
 	}
 
-	return deepCodeword, nil
+	termWeights := weights[numColumns+numSegments:]
+	value := termWeights[0].Mul(combinedSegments.Sub(combinedSegmentsAtPoint)).Mul(inverses[2])
+	value = value.Add(termWeights[1].Mul(combined.Sub(combinedAtPoint)).Mul(inverses[0]))
+	value = value.Add(termWeights[2].Mul(combined.Sub(combinedAtNext)).Mul(inverses[1]))
+	return value, nil
 }
 
-// createFiatShamirChannel creates a channel for Fiat-Shamir transformation
+// openRows enqueues the trace and quotient rows at the FRI query indices,
+// each with its Merkle authentication path
 //
-// The channel is seeded with the out-of-domain point to ensure
-// verifiable randomness for the FRI protocol
-func (p *Prover) createFiatShamirChannel(oodPoint field.Element) *utils.Channel {
-	// Create channel with SHA3 (field-friendly)
-	channel := utils.NewChannel("sha3")
-
-	// Seed the channel with the OOD point
-	// Convert field element to bytes
-	val := oodPoint.Value()
-	seed := make([]byte, 8)
-	for i := 0; i < 8; i++ {
-		seed[i] = byte(val >> (i * 8))
-	}
-	channel.Send(seed)
-
-	return channel
-}
+// Proof items, in order: main table rows, their authentication structure,
+// auxiliary table rows, their authentication structure (only if there is an
+// auxiliary table), quotient segment elements, their authentication
+// structure. An authentication structure holds one path per index, and a
+// quotient row holds one value per segment.
+func (p *Prover) openRows(
+	proofStream *ProofStream,
+	table *MasterTable,
+	auxTable *MasterTable,
+	quotientTree *merkle.MerkleTree,
+	segments *quotientSegments,
+	indices []int,
+) error {
+	mainRows, mainPaths, err := table.OpenRows(indices)
+	if err != nil {
+		return fmt.Errorf("failed to open main table rows: %w", err)
+	}
 
-// addFRIToProof adds FRI proof data to the main proof
-func (p *Prover) addFRIToProof(proof *Proof, friProof *FRIProof) error {
-	// Add FRI layers (Merkle roots and challenges)
-	for _, layer := range friProof.Layers {
-		proof.AddMerkleRoot(layer.MerkleRoot)
-		if !layer.Challenge.IsZero() {
-			proof.AddFieldElement(layer.Challenge)
+	items := []ProofItem{
+		{Type: ProofItemMasterMainTableRows, Data: mainRows},
+		{Type: ProofItemAuthenticationStructure, Data: mainPaths},
+	}
+	if auxTable != nil {
+		auxCoefficientRows, auxPaths, err := auxTable.OpenRows(indices)
+		if err != nil {
+			return fmt.Errorf("failed to open aux table rows: %w", err)
+		}
+		auxRows := make([][]xfield.XFieldElement, len(auxCoefficientRows))
+		for i, row := range auxCoefficientRows {
+			if auxRows[i], err = xfieldElements(row); err != nil {
+				return fmt.Errorf("failed to open aux table rows: %w", err)
+			}
+		}
+		items = append(items,
+			ProofItem{Type: ProofItemMasterAuxTableRows, Data: auxRows},
+			ProofItem{Type: ProofItemAuthenticationStructure, Data: auxPaths},
+		)
+	} else {
+		auxRows := make([][]xfield.XFieldElement, len(indices))
+		for i := range auxRows {
+			auxRows[i] = []xfield.XFieldElement{}
 		}
+		items = append(items, ProofItem{Type: ProofItemMasterAuxTableRows, Data: auxRows})
 	}
 
-	// Add final polynomial coefficients
-	if friProof.FinalPolynomial != nil {
-		coeffs := friProof.FinalPolynomial.Coefficients()
-		// Convert to field.Element
-		coeffsElems := make([]field.Element, len(coeffs))
-		for i, c := range coeffs {
-			coeffsElems[i] = convertToFieldElement(c)
+	quotientRows := make([][]xfield.XFieldElement, len(indices))
+	quotientPaths := make([][]hash.Digest, len(indices))
+	for i, index := range indices {
+		path, err := quotientTree.AuthenticationPath(uint64(index))
+		if err != nil {
+			return fmt.Errorf("failed to get quotient authentication path for row %d: %w", index, err)
 		}
-		proof.AddFieldElements(coeffsElems)
+		quotientRows[i] = segments.row(index)
+		quotientPaths[i] = path
 	}
 
-	// Add soundness error
-	if !friProof.SoundnessError.IsZero() {
-		soundnessElem := convertToFieldElement(friProof.SoundnessError)
-		proof.AddFieldElement(soundnessElem)
+	items = append(items,
+		ProofItem{Type: ProofItemQuotientSegmentsElements, Data: quotientRows},
+		ProofItem{Type: ProofItemAuthenticationStructure, Data: quotientPaths},
+	)
+	for _, item := range items {
+		if err := proofStream.Enqueue(item); err != nil {
+			return fmt.Errorf("failed to enqueue row openings: %w", err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,225 @@
+package protocols
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+)
+
+// ProverOptions limits the resources a proof may use
+//
+// A limit of zero means the default: one worker per CPU and no memory
+// ceiling. Several proofs running at once each use their own workers, so a
+// server running k proofs should give each a k-th of its CPUs.
+type ProverOptions struct {
+	// NumWorkers is the number of goroutines every phase of the proof runs
+	// at most at once
+	NumWorkers int
+
+	// MaxMemoryBytes is a ceiling on the memory held by the extended tables,
+	// the quotient, the DEEP and FRI codewords and the Merkle trees. The
+	// prover estimates it from the domains before extending the tables and
+	// fails if it exceeds the ceiling
+	MaxMemoryBytes uint64
+
+	// Progress, if not nil, is called whenever a phase completed some of its
+	// work. Calls are serialized, but may come from any goroutine
+	Progress func(ProverProgress)
+}
+
+// DefaultProverOptions returns the options of a Prover that was not
+// configured otherwise: one worker per CPU and no memory ceiling
+func DefaultProverOptions() ProverOptions {
+	return ProverOptions{NumWorkers: runtime.NumCPU()}
+}
+
+// Validate checks that the options are usable
+func (o ProverOptions) Validate() error {
+	if o.NumWorkers < 0 {
+		return fmt.Errorf("number of workers must not be negative, got %d", o.NumWorkers)
+	}
+	return nil
+}
+
+// ProverPhase identifies a phase of proof generation
+type ProverPhase int
+
+const (
+	// PhaseLowDegreeExtension extends the trace columns to the FRI domain,
+	// and to the quotient domain if that is longer; its work is counted in
+	// columns
+	PhaseLowDegreeExtension ProverPhase = iota
+
+	// PhaseMerkleCommitment hashes the rows of a committed table; its work
+	// is counted in rows
+	PhaseMerkleCommitment
+
+	// PhaseQuotient evaluates the constraints on the quotient domain and
+	// splits the quotient into segments; its work is counted in rows, then
+	// in segments
+	PhaseQuotient
+
+	// PhaseOutOfDomain evaluates the trace columns at the out-of-domain
+	// points; its work is counted in columns
+	PhaseOutOfDomain
+
+	// PhaseDEEP computes the DEEP codeword; its work is counted in rows
+	PhaseDEEP
+
+	// PhaseFRI folds and commits the FRI codewords; its work is counted in
+	// rows of the codeword being folded or committed
+	PhaseFRI
+)
+
+// String returns the phase's name
+func (p ProverPhase) String() string {
+	switch p {
+	case PhaseLowDegreeExtension:
+		return "low-degree extension"
+	case PhaseMerkleCommitment:
+		return "Merkle commitment"
+	case PhaseQuotient:
+		return "quotient"
+	case PhaseOutOfDomain:
+		return "out-of-domain evaluation"
+	case PhaseDEEP:
+		return "DEEP"
+	case PhaseFRI:
+		return "FRI"
+	default:
+		return fmt.Sprintf("phase %d", int(p))
+	}
+}
+
+// ProverProgress reports how far a step of a phase has come
+//
+// A phase may consist of several steps, such as one Merkle commitment per
+// table; each step counts from zero to its own total.
+type ProverProgress struct {
+	Phase ProverPhase
+	Done  int
+	Total int
+}
+
+// workerPool runs the loops of every phase with the prover's number of
+// workers and reports their progress
+//
+// A nil pool uses the default options.
+type workerPool struct {
+	numWorkers int
+	progress   func(ProverProgress)
+	mu         sync.Mutex
+}
+
+// newWorkerPool creates the pool for the given options
+func newWorkerPool(options ProverOptions) *workerPool {
+	return &workerPool{numWorkers: options.NumWorkers, progress: options.Progress}
+}
+
+// size returns the number of workers
+func (wp *workerPool) size() int {
+	if wp == nil || wp.numWorkers < 1 {
+		return runtime.NumCPU()
+	}
+	return wp.numWorkers
+}
+
+// report calls the progress callback, if any
+func (wp *workerPool) report(progress ProverProgress) {
+	if wp == nil || wp.progress == nil {
+		return
+	}
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.progress(progress)
+}
+
+// parallelFor calls fn on consecutive chunks [start, end) covering [0, n)
+//
+// At most size() chunks run at once, each on its own goroutine; the chunks
+// are a few times smaller than n/size() so that progress is reported in
+// steps and uneven chunks balance out. The first error stops the workers
+// from starting new chunks and is returned.
+func (wp *workerPool) parallelFor(phase ProverPhase, n int, fn func(start, end int) error) error {
+	if n <= 0 {
+		return nil
+	}
+	numWorkers := wp.size()
+	if numWorkers > n {
+		numWorkers = n
+	}
+	chunkSize := (n + 4*numWorkers - 1) / (4 * numWorkers)
+	numChunks := (n + chunkSize - 1) / chunkSize
+
+	var nextChunk, done atomic.Int64
+	var failed atomic.Bool
+	errs := make(chan error, numWorkers)
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				chunk := int(nextChunk.Add(1)) - 1
+				if chunk >= numChunks {
+					return
+				}
+				start := chunk * chunkSize
+				end := min(start+chunkSize, n)
+				if err := fn(start, end); err != nil {
+					failed.Store(true)
+					errs <- err
+					return
+				}
+				wp.report(ProverProgress{Phase: phase, Done: int(done.Add(int64(end - start))), Total: n})
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+// Sizes of the values a proof holds, in bytes
+const (
+	fieldElementBytes  = 8
+	xfieldElementBytes = xfield.ExtensionDegree * fieldElementBytes
+	digestBytes        = hash.DigestLen * fieldElementBytes
+)
+
+// estimateProofMemory returns an upper bound on the memory a proof of a
+// table with the given main and auxiliary column counts holds at once
+//
+// Every table is held as its trace, its randomized interpolant on the
+// randomized trace domain and its coefficients, extended to the FRI domain
+// and, if the quotient domain is not the FRI domain, evaluated there. A
+// Merkle tree holds about two digests per leaf. The quotient is held on the
+// quotient domain and as coefficients, its segments on the FRI domain. The
+// DEEP codeword needs three inverses per row, and the FRI codewords and
+// trees of all rounds together are about as large as those of the first.
+func estimateProofMemory(numMainColumns, numAuxColumns int, domains *ProverDomains) uint64 {
+	traceLength := uint64(domains.Trace.Length)
+	randomizedLength := uint64(domains.RandomizedTrace.Length)
+	quotientLength := uint64(domains.Quotient.Length)
+	friLength := uint64(domains.FRI.Length)
+	numColumns := uint64(numMainColumns + xfield.ExtensionDegree*numAuxColumns)
+
+	perColumn := traceLength + 2*randomizedLength + friLength
+	if quotientLength != friLength {
+		perColumn += quotientLength
+	}
+	tables := numColumns * perColumn * fieldElementBytes
+	trees := 3 * 2 * friLength * digestBytes
+
+	quotient := 2 * quotientLength * xfieldElementBytes
+	segments := uint64(domains.NumQuotientSegments()) * friLength * xfieldElementBytes
+	deep := (1 + 2*3) * friLength * xfieldElementBytes
+	fri := 2 * friLength * (xfieldElementBytes + 2*digestBytes)
+
+	return tables + trees + quotient + segments + deep + fri
+}
@@ -0,0 +1,82 @@
+package protocols
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+)
+
+// TestProverOptions tests that the prover's options bound its resources
+// without changing the proof
+func TestProverOptions(t *testing.T) {
+	claim := NewClaim([]field.Element{field.New(1), field.New(2), field.New(3), field.New(4), field.New(5)})
+	prove := func(t *testing.T, options ProverOptions) (*Proof, error) {
+		t.Helper()
+		prover, err := NewProver(DefaultSTARKParameters(), CreateProcessorConstraints())
+		if err != nil {
+			t.Fatalf("Failed to create prover: %v", err)
+		}
+		prover.SetRandomnessSeed([]byte("options test seed")).SetOptions(options)
+		return prover.Prove(claim, newProcessorTestTrace(64))
+	}
+
+	t.Run("SingleWorkerProofMatches", func(t *testing.T) {
+		expected, err := prove(t, DefaultProverOptions())
+		if err != nil {
+			t.Fatalf("Prove failed: %v", err)
+		}
+		proof, err := prove(t, ProverOptions{NumWorkers: 1})
+		if err != nil {
+			t.Fatalf("Prove failed: %v", err)
+		}
+		expectedData, err := expected.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary failed: %v", err)
+		}
+		data, err := proof.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary failed: %v", err)
+		}
+		if !bytes.Equal(data, expectedData) {
+			t.Fatal("Proof with a single worker differs from the default proof")
+		}
+	})
+
+	t.Run("ProgressReported", func(t *testing.T) {
+		completed := make(map[ProverPhase]bool)
+		options := ProverOptions{NumWorkers: 3, Progress: func(progress ProverProgress) {
+			if progress.Done < 1 || progress.Done > progress.Total {
+				t.Errorf("%s progress %d of %d", progress.Phase, progress.Done, progress.Total)
+			}
+			if progress.Done == progress.Total {
+				completed[progress.Phase] = true
+			}
+		}}
+		if _, err := prove(t, options); err != nil {
+			t.Fatalf("Prove failed: %v", err)
+		}
+		for _, phase := range []ProverPhase{
+			PhaseLowDegreeExtension, PhaseMerkleCommitment, PhaseQuotient, PhaseOutOfDomain, PhaseDEEP, PhaseFRI,
+		} {
+			if !completed[phase] {
+				t.Errorf("Phase %s never completed", phase)
+			}
+		}
+	})
+
+	t.Run("MemoryCeilingRejected", func(t *testing.T) {
+		if _, err := prove(t, ProverOptions{MaxMemoryBytes: 1 << 10}); err == nil {
+			t.Fatal("Proof exceeding the memory ceiling succeeded")
+		}
+		if _, err := prove(t, ProverOptions{MaxMemoryBytes: 1 << 30}); err != nil {
+			t.Fatalf("Proof within the memory ceiling failed: %v", err)
+		}
+	})
+
+	t.Run("NegativeWorkersRejected", func(t *testing.T) {
+		if _, err := prove(t, ProverOptions{NumWorkers: -1}); err == nil {
+			t.Fatal("Proof with a negative number of workers succeeded")
+		}
+	})
+}
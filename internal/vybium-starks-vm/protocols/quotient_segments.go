@@ -0,0 +1,99 @@
+package protocols
+
+import (
+	"fmt"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+)
+
+// Quotient segments
+//
+// The combined quotient Q has degree less than k·R, where R is the randomized
+// trace length and k the number of segments. Following Triton VM, it is split
+// into k segments of degree less than R,
+//
+//	Q(X) = Σ_j X^j·q_j(X^k)
+//
+// where q_j collects the coefficients of Q at indices j, j+k, j+2k, … The
+// segments are committed on the FRI domain as one Merkle tree whose rows hold
+// one value per segment. FRI then tests them against the same degree bound as
+// the trace columns, instead of k times that, and the verifier recovers Q(z)
+// from the segments' values at z^k.
+
+// quotientSegments are the segments of a combined quotient, as coefficients
+// and as codewords on the FRI domain
+type quotientSegments struct {
+	coefficients [][]xfield.XFieldElement
+	codewords    [][]xfield.XFieldElement
+}
+
+// splitQuotient interpolates the quotient codeword over the quotient domain
+// and splits the result into the segments, which are evaluated on the FRI
+// domain by the pool's workers
+func splitQuotient(pool *workerPool, quotientCodeword []xfield.XFieldElement, domains *ProverDomains) (*quotientSegments, error) {
+	if len(quotientCodeword) != domains.Quotient.Length {
+		return nil, fmt.Errorf("quotient codeword length %d doesn't match quotient domain length %d",
+			len(quotientCodeword), domains.Quotient.Length)
+	}
+	coefficients, err := domains.Quotient.interpolateXField(quotientCodeword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to interpolate quotient: %w", err)
+	}
+
+	numSegments := domains.NumQuotientSegments()
+	segments := &quotientSegments{
+		coefficients: make([][]xfield.XFieldElement, numSegments),
+		codewords:    make([][]xfield.XFieldElement, numSegments),
+	}
+	err = pool.parallelFor(PhaseQuotient, numSegments, func(start, end int) error {
+		for j := start; j < end; j++ {
+			segment := make([]xfield.XFieldElement, len(coefficients)/numSegments)
+			for i := range segment {
+				segment[i] = coefficients[i*numSegments+j]
+			}
+			codeword, err := domains.FRI.evaluateXField(segment)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate quotient segment %d: %w", j, err)
+			}
+			segments.coefficients[j] = segment
+			segments.codewords[j] = codeword
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// row returns the segments' values at index i of the FRI domain
+func (qs *quotientSegments) row(i int) []xfield.XFieldElement {
+	row := make([]xfield.XFieldElement, len(qs.codewords))
+	for j, codeword := range qs.codewords {
+		row[j] = codeword[i]
+	}
+	return row
+}
+
+// evaluateAt evaluates every segment at the point with Horner's rule
+func (qs *quotientSegments) evaluateAt(point xfield.XFieldElement) []xfield.XFieldElement {
+	values := make([]xfield.XFieldElement, len(qs.coefficients))
+	for j, segment := range qs.coefficients {
+		value := xfield.Zero
+		for i := len(segment) - 1; i >= 0; i-- {
+			value = value.Mul(point).Add(segment[i])
+		}
+		values[j] = value
+	}
+	return values
+}
+
+// quotientFromSegments recovers Q(z) = Σ_j z^j·q_j(z^k) from the segments'
+// values at z^k
+func quotientFromSegments(point xfield.XFieldElement, segmentValues []xfield.XFieldElement) xfield.XFieldElement {
+	value := xfield.Zero
+	for j := len(segmentValues) - 1; j >= 0; j-- {
+		value = value.Mul(point).Add(segmentValues[j])
+	}
+	return value
+}
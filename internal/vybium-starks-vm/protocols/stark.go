@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 )
 
@@ -98,6 +99,66 @@ func (sp *STARKParameters) MaxDegree(paddedHeight int) int {
 	return maxConstraintDegree * interpolantDegree
 }
 
+// NumQuotientSegments returns the number of segments the combined quotient of
+// an AIR with the given maximum constraint degree is split into
+//
+// Every randomized column has degree less than RandomizedTraceLength, so a
+// constraint of degree d has degree less than d·RandomizedTraceLength, and so
+// does its quotient. Rounding d up to a power of two gives the number of
+// segments of degree less than RandomizedTraceLength the quotient needs.
+func (sp *STARKParameters) NumQuotientSegments(maxConstraintDegree int) int {
+	if maxConstraintDegree < 1 {
+		maxConstraintDegree = 1
+	}
+	return nextPowerOfTwo(maxConstraintDegree)
+}
+
+// QuotientDegreeBound returns the degree bound of the combined quotient for an
+// AIR whose constraints have degree at most maxConstraintDegree
+//
+// The quotient is committed as NumQuotientSegments segments, each of degree
+// at most InterpolantDegree.
+func (sp *STARKParameters) QuotientDegreeBound(paddedHeight, maxConstraintDegree int) int {
+	return sp.NumQuotientSegments(maxConstraintDegree)*sp.RandomizedTraceLength(paddedHeight) - 1
+}
+
+// FRIDomainLength returns the length of the FRI domain for the given trace height
+//
+// FRI only sees the trace columns and the quotient segments, which all have
+// degree at most InterpolantDegree, so the length does not depend on the
+// constraints' degree.
+func (sp *STARKParameters) FRIDomainLength(paddedHeight int) int {
+	return sp.RandomizedTraceLength(paddedHeight) * sp.FRIExpansionFactor
+}
+
+// DeriveDomains computes every arithmetic domain for a trace of the given padded
+// height proven against an AIR with the given maximum constraint degree
+//
+// The FRI domain is offset by the field generator so it is disjoint from the
+// trace domain, which every zerofier vanishes on. Prover and verifier must
+// agree on all domains, so both derive them here.
+func (sp *STARKParameters) DeriveDomains(paddedHeight, maxConstraintDegree int) (*ProverDomains, error) {
+	friDomainSize := sp.FRIDomainLength(paddedHeight)
+	friDomain, err := NewArithmeticDomain(friDomainSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FRI arithmetic domain: %w", err)
+	}
+	friDomain = friDomain.WithOffset(field.Generator())
+
+	domains, err := DeriveProverDomains(
+		paddedHeight,
+		sp.NumTraceRandomizers,
+		friDomain,
+		sp.NumQuotientSegments(maxConstraintDegree),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive domains (paddedHeight=%d, randomizers=%d, friDomainSize=%d): %w",
+			paddedHeight, sp.NumTraceRandomizers, friDomainSize, err)
+	}
+
+	return domains, nil
+}
+
 // FRIDomain creates the FRI protocol for this STARK
 func (sp *STARKParameters) FRIDomain(paddedHeight int, field *core.Field) (*FRIProtocol, error) {
 	if err := sp.Validate(); err != nil {
@@ -16,7 +16,7 @@ func TestSTARKProverCreation(t *testing.T) {
 			NumTraceRandomizers:   20,
 		}
 
-		prover, err := NewProver(params)
+		prover, err := NewProver(params, CreateProcessorConstraints())
 		if err != nil {
 			t.Fatalf("Failed to create prover: %v", err)
 		}
@@ -34,11 +34,17 @@ func TestSTARKProverCreation(t *testing.T) {
 			NumTraceRandomizers:   10,
 		}
 
-		_, err := NewProver(params)
+		_, err := NewProver(params, CreateProcessorConstraints())
 		if err == nil {
 			t.Error("Expected error for invalid parameters")
 		}
 	})
+
+	t.Run("CreateProverWithoutAIR", func(t *testing.T) {
+		if _, err := NewProver(DefaultSTARKParameters(), nil); err == nil {
+			t.Error("Expected error for a nil AIR")
+		}
+	})
 }
 
 func TestSTARKVerifierCreation(t *testing.T) {
@@ -57,7 +63,7 @@ func TestSTARKVerifierCreation(t *testing.T) {
 			NumTraceRandomizers:   20,
 		}
 
-		verifier, err := NewVerifier(field, params)
+		verifier, err := NewVerifier(field, params, CreateProcessorConstraints())
 		if err != nil {
 			t.Fatalf("Failed to create verifier: %v", err)
 		}
@@ -66,6 +72,12 @@ func TestSTARKVerifierCreation(t *testing.T) {
 			t.Fatal("Verifier is nil")
 		}
 	})
+
+	t.Run("CreateVerifierWithoutAIR", func(t *testing.T) {
+		if _, err := NewVerifier(field, DefaultSTARKParameters(), nil); err == nil {
+			t.Error("Expected error for a nil AIR")
+		}
+	})
 }
 
 func TestSTARKParametersValidation(t *testing.T) {
@@ -2,9 +2,11 @@ package protocols
 
 import (
 	"fmt"
-	"math/big"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/merkle"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 )
 
@@ -20,13 +22,20 @@ import (
 type Verifier struct {
 	params STARKParameters
 	field  *core.Field
+	air    *AIRConstraints
 }
 
-// NewVerifier creates a new verifier with given parameters
-func NewVerifier(field *core.Field, params STARKParameters) (*Verifier, error) {
+// NewVerifier creates a new verifier that checks proofs against the given
+// constraints
+//
+// The prover must have been created with the same constraints.
+func NewVerifier(field *core.Field, params STARKParameters, air *AIRConstraints) (*Verifier, error) {
 	if field == nil {
 		return nil, fmt.Errorf("field cannot be nil")
 	}
+	if air == nil {
+		return nil, fmt.Errorf("AIR cannot be nil")
+	}
 
 	if err := params.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid STARK parameters: %w", err)
@@ -35,6 +44,7 @@ func NewVerifier(field *core.Field, params STARKParameters) (*Verifier, error) {
 	return &Verifier{
 		params: params,
 		field:  field,
+		air:    air,
 	}, nil
 }
 
@@ -52,295 +62,380 @@ func (v *Verifier) Verify(claim *Claim, proof *Proof) error {
 	}
 
 	// Step 2: Reconstruct Fiat-Shamir state with claim
-	claimHash, err := claim.Hash()
+	// The proof is replayed item by item; dequeuing absorbs every item the
+	// prover absorbed, so each sample below matches the prover's.
+	proofStream, err := ProofStreamFromProof(proof)
 	if err != nil {
-		return fmt.Errorf("failed to hash claim: %w", err)
+		return fmt.Errorf("failed to create proof stream: %w", err)
+	}
+	if err := proofStream.AlterFiatShamirStateWith(claim); err != nil {
+		return fmt.Errorf("failed to absorb claim: %w", err)
 	}
 
 	// Step 3: Extract padded height from proof
-	paddedHeight, err := proof.GetPaddedHeight()
+	heightItem, err := dequeueItem(proofStream, ProofItemLog2PaddedHeight)
 	if err != nil {
 		return fmt.Errorf("failed to get padded height: %w", err)
 	}
+	log2Height, ok := heightItem.Data.(int)
+	if !ok {
+		return fmt.Errorf("failed to get padded height: invalid log2 height data type")
+	}
+	if log2Height < 0 || log2Height > MaxLog2DomainLength {
+		return fmt.Errorf("log2 padded height %d out of range [0, %d]", log2Height, MaxLog2DomainLength)
+	}
+	paddedHeight := 1 << log2Height
 
-	// Step 4: Derive arithmetic domains
-	domains, err := v.deriveDomains(paddedHeight)
+	// Step 4: Derive arithmetic domains (the AIR fixes the degree bounds)
+	air := v.air
+	domains, err := v.deriveDomains(paddedHeight, air)
 	if err != nil {
 		return fmt.Errorf("failed to derive domains: %w", err)
 	}
 
-	// Step 5: Extract Merkle roots from proof
-	merkleRoots := proof.GetMerkleRoots()
-	if len(merkleRoots) < 2 {
-		return fmt.Errorf("proof must contain at least 2 Merkle roots (trace + quotient), got %d", len(merkleRoots))
+	// Step 5: Read the Merkle roots and reconstruct the challenges and
+	// quotient weights exactly as the prover sampled them
+	// The trace root is followed by the auxiliary trace root if the AIR has
+	// auxiliary columns, then by the quotient root.
+	hasAux := air.NumAuxColumns() > 0
+	traceRoot, err := dequeueMerkleRoot(proofStream)
+	if err != nil {
+		return fmt.Errorf("failed to read trace root: %w", err)
+	}
+	var auxRoot []byte
+	var challenges []xfield.XFieldElement
+	if hasAux {
+		if challenges, err = sampleChallenges(proofStream, air.NumChallenges()); err != nil {
+			return err
+		}
+		if auxRoot, err = dequeueMerkleRoot(proofStream); err != nil {
+			return fmt.Errorf("failed to read auxiliary trace root: %w", err)
+		}
+	}
+	weights, err := sampleQuotientWeights(proofStream, air.NumConstraints())
+	if err != nil {
+		return err
+	}
+	// The claim challenges tie the constraints to the claim, which the
+	// transcript only absorbed
+	if challenges, err = air.ClaimChallenges(claim, challenges); err != nil {
+		return fmt.Errorf("AIR verification failed: %w", err)
+	}
+	quotientRoot, err := dequeueMerkleRoot(proofStream)
+	if err != nil {
+		return fmt.Errorf("failed to read quotient root: %w", err)
 	}
 
-	traceRoot := merkleRoots[0]
-	quotientRoot := merkleRoots[1]
-
-	// Step 6: Reconstruct challenges
-	challenges, err := v.reconstructChallenges(claimHash, traceRoot)
+	// Step 6: Sample out-of-domain point
+	oodPoint, err := sampleOODPoint(proofStream, domains)
 	if err != nil {
-		return fmt.Errorf("failed to reconstruct challenges: %w", err)
+		return err
 	}
 
-	// Step 7: Sample out-of-domain point
-	oodPoint, err := v.sampleOODPoint(quotientRoot)
+	// Step 7: Verify AIR constraints at OOD point
+	// The prover claims trace rows at z and ω·z and the quotient segments at
+	// z^k. Evaluating every constraint on those rows and dividing by its
+	// zerofier must reproduce the quotient value the segments combine to. FRI
+	// then establishes that the committed segments are low-degree, i.e. that
+	// the constraints hold on the trace domain.
+	ood, err := v.readOutOfDomainValues(proofStream, air, domains, oodPoint)
 	if err != nil {
-		return fmt.Errorf("failed to sample OOD point: %w", err)
-	}
-
-	// Step 8: Verify AIR constraints at OOD point
-	// The AIR constraints are verified implicitly through the FRI protocol:
-	// - The prover creates quotient polynomials Q_i(X) = C_i(X) / Z(X) where C_i are constraints
-	// - The verifier checks that Q_i is low-degree via FRI
-	// - If Q_i is low-degree and Z(X) is the vanishing polynomial, then C_i(X) = 0 on the domain
-	//
-	// This is the standard STARK verification approach used by Triton VM.
-	// We verify the structure to ensure all components are present.
-	challengesCore := make([]*core.FieldElement, len(challenges))
-	for i, c := range challenges {
-		challengesCore[i] = convertFromFieldElement(c, v.field)
-	}
-	// oodPoint is already *core.FieldElement from sampleOODPoint
-	if err := v.verifyAIRStructure(domains, challengesCore, oodPoint); err != nil {
+		return fmt.Errorf("AIR verification failed: %w", err)
+	}
+	if err := v.verifyOutOfDomainConstraints(ood, air, domains, challenges, weights); err != nil {
 		return fmt.Errorf("AIR verification failed: %w", err)
 	}
 
-	// Step 9: Verify FRI proof
-	// In a full implementation, we would:
-	// - Extract FRI layers from proof
-	// - Verify each Merkle commitment
-	// - Verify folding correctness
-	// - Verify final polynomial degree
-	if err := v.verifyFRIStructure(proof, domains); err != nil {
+	// Step 8: Verify FRI proof
+	// The DEEP weights, folding challenges and query indices continue the
+	// transcript; every round's revealed values must be authenticated
+	// against its root and fold onto the next round's values.
+	deepWeights, err := sampleDEEPWeights(proofStream, len(ood.currentRow), len(ood.quotientSegments))
+	if err != nil {
+		return fmt.Errorf("FRI verification failed: %w", err)
+	}
+	indices, deepValues, err := v.verifyFRI(proofStream, domains)
+	if err != nil {
 		return fmt.Errorf("FRI verification failed: %w", err)
 	}
 
-	// Step 10: Verify all Merkle authentication paths
-	// The Merkle roots are committed to in the proof, and individual paths are verified
-	// during query decommitment in the FRI protocol. This structural check ensures
-	// all required roots are present in the proof.
-	if err := v.verifyMerkleStructure(proof); err != nil {
+	// Step 9: Verify the trace and quotient openings
+	// Every opened row must hash to a leaf of its committed Merkle tree, and
+	// the DEEP codeword recomputed from the opened rows must match the values
+	// FRI revealed at the same indices.
+	roots := &committedRoots{trace: traceRoot, aux: auxRoot, quotient: quotientRoot}
+	if err := v.verifyOpenings(proofStream, domains, roots, ood, deepWeights, indices, deepValues); err != nil {
 		return fmt.Errorf("Merkle verification failed: %w", err)
 	}
+	if proofStream.ItemsIndex != len(proof.Items) {
+		return fmt.Errorf("proof has %d unexpected trailing items", len(proof.Items)-proofStream.ItemsIndex)
+	}
 
 	// All checks passed!
 	return nil
 }
 
 // deriveDomains derives all arithmetic domains for verification
-func (v *Verifier) deriveDomains(paddedHeight int) (*ProverDomains, error) {
-	// Compute FRI domain size
-	randomizedLen := v.params.RandomizedTraceLength(paddedHeight)
-	friDomainSize := randomizedLen * v.params.FRIExpansionFactor
+func (v *Verifier) deriveDomains(paddedHeight int, air *AIRConstraints) (*ProverDomains, error) {
+	return v.params.DeriveDomains(paddedHeight, air.MaxDegree())
+}
 
-	// Create FRI domain
-	friDomain, err := NewArithmeticDomain(friDomainSize)
+// readOutOfDomainValues dequeues the rows and quotient segments claimed at
+// the out-of-domain point
+//
+// The proof carries the current main and aux rows at z, the next main and
+// aux rows at ω·z and the quotient segments at z^k, in that order. The aux
+// rows must have exactly one value per main and auxiliary column of the AIR,
+// and there must be one value per quotient segment.
+func (v *Verifier) readOutOfDomainValues(
+	proofStream *ProofStream,
+	air *AIRConstraints,
+	domains *ProverDomains,
+	oodPoint xfield.XFieldElement,
+) (*outOfDomainValues, error) {
+	var rows [2][]xfield.XFieldElement
+	for i, name := range []string{"current", "next"} {
+		mainRow, err := dequeueXFieldList(proofStream, ProofItemOutOfDomainMainRow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s out-of-domain main row: %w", name, err)
+		}
+		if len(mainRow) != air.NumColumns() {
+			return nil, fmt.Errorf("out-of-domain main row has %d columns, AIR needs %d", len(mainRow), air.NumColumns())
+		}
+		auxRow, err := dequeueXFieldList(proofStream, ProofItemOutOfDomainAuxRow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s out-of-domain aux row: %w", name, err)
+		}
+		if len(auxRow) != air.NumAuxColumns() {
+			return nil, fmt.Errorf("out-of-domain aux row has %d columns, AIR needs %d", len(auxRow), air.NumAuxColumns())
+		}
+		rows[i] = append(append([]xfield.XFieldElement{}, mainRow...), auxRow...)
+	}
+	quotientSegments, err := dequeueXFieldList(proofStream, ProofItemOutOfDomainQuotientSegments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create FRI domain: %w", err)
+		return nil, fmt.Errorf("failed to read out-of-domain quotient segments: %w", err)
+	}
+	numSegments := domains.NumQuotientSegments()
+	if len(quotientSegments) != numSegments {
+		return nil, fmt.Errorf("expected %d out-of-domain quotient segments, got %d", numSegments, len(quotientSegments))
 	}
 
-	// Compute max degree for quotient domain
-	maxDegree := v.params.MaxDegree(paddedHeight)
+	return &outOfDomainValues{
+		point:            oodPoint,
+		nextPoint:        oodPoint.MulConst(domains.Trace.Generator),
+		segmentPoint:     oodPoint.Pow(uint64(numSegments)),
+		currentRow:       rows[0],
+		nextRow:          rows[1],
+		quotientSegments: quotientSegments,
+	}, nil
+}
 
-	// Derive all domains using updated signature
-	domains, err := DeriveProverDomains(
-		paddedHeight,
-		v.params.NumTraceRandomizers,
-		friDomain,
-		maxDegree,
-	)
+// verifyOutOfDomainConstraints checks the AIR at the out-of-domain point
+//
+// The weighted sum of all initial, consistency, transition and terminal
+// constraints, each divided by its zerofier, must equal the quotient recovered
+// from its segments.
+func (v *Verifier) verifyOutOfDomainConstraints(
+	ood *outOfDomainValues,
+	air *AIRConstraints,
+	domains *ProverDomains,
+	challenges []xfield.XFieldElement,
+	weights []xfield.XFieldElement,
+) error {
+	width := air.NumColumns() + air.NumAuxColumns()
+	if len(ood.currentRow) != width || len(ood.nextRow) != width {
+		return fmt.Errorf("out-of-domain rows have %d and %d columns, AIR needs %d",
+			len(ood.currentRow), len(ood.nextRow), width)
+	}
+
+	expected, err := air.EvaluateQuotientAt(ood.point, ood.currentRow, ood.nextRow, challenges, weights, domains.Trace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to derive domains: %w", err)
+		return fmt.Errorf("failed to evaluate constraints at out-of-domain point: %w", err)
+	}
+	if !expected.Equal(quotientFromSegments(ood.point, ood.quotientSegments)) {
+		return fmt.Errorf("out-of-domain quotient value does not match the constraints")
 	}
 
-	return domains, nil
+	return nil
 }
 
-// reconstructChallenges reconstructs the Fiat-Shamir challenges
+// verifyFRI runs the FRI verifier on the proof stream
 //
-// The challenges must be reconstructed in the exact same way as the prover
-func (v *Verifier) reconstructChallenges(claimHash field.Element, traceRoot []byte) ([]field.Element, error) {
-	// Get Poseidon hash instance
-	hash, err := core.GetEnhancedPoseidonHash(v.field, 128)
+// Returns the first-round query indices and the DEEP codeword values
+// revealed at them.
+func (v *Verifier) verifyFRI(proofStream *ProofStream, domains *ProverDomains) ([]int, []xfield.XFieldElement, error) {
+	fri, err := NewFRI(domains.FRI, v.params.FRIExpansionFactor, v.params.NumCollinearityChecks)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create hash: %w", err)
+		return nil, nil, fmt.Errorf("failed to create FRI: %w", err)
 	}
+	return fri.Verify(proofStream)
+}
 
-	// Combine claim hash and trace root
-	rootElems := make([]*core.FieldElement, 10)
-	rootElems[0] = convertFromFieldElement(claimHash, v.field)
+// committedRoots are the Merkle roots the opened rows are checked against;
+// aux is nil if the AIR has no auxiliary columns
+type committedRoots struct {
+	trace    []byte
+	aux      []byte
+	quotient []byte
+}
 
-	// Convert trace root to field elements
-	for i := 1; i < 10; i++ {
-		if (i-1)*8 < len(traceRoot) {
-			end := i * 8
-			if end > len(traceRoot) {
-				end = len(traceRoot)
-			}
-			chunk := traceRoot[(i-1)*8 : end]
-			val := new(big.Int).SetBytes(chunk)
-			rootElems[i] = v.field.NewElement(val)
-		} else {
-			rootElems[i] = v.field.Zero()
+// verifyOpenings verifies the trace and quotient rows opened at the FRI
+// query indices
+//
+// Each row is hashed exactly as the prover hashed it into its Merkle tree and
+// checked against the committed root. The DEEP codeword recomputed from the
+// opened rows must equal the value FRI revealed at the same index; this binds
+// the out-of-domain values and the FRI codeword to the commitments.
+func (v *Verifier) verifyOpenings(
+	proofStream *ProofStream,
+	domains *ProverDomains,
+	roots *committedRoots,
+	ood *outOfDomainValues,
+	deepWeights []xfield.XFieldElement,
+	indices []int,
+	deepValues []xfield.XFieldElement,
+) error {
+	traceRoot, err := digestFromBytes(roots.trace)
+	if err != nil {
+		return fmt.Errorf("invalid trace root: %w", err)
+	}
+	quotientRoot, err := digestFromBytes(roots.quotient)
+	if err != nil {
+		return fmt.Errorf("invalid quotient root: %w", err)
+	}
+	hasAux := roots.aux != nil
+	var auxRoot hash.Digest
+	if hasAux {
+		if auxRoot, err = digestFromBytes(roots.aux); err != nil {
+			return fmt.Errorf("invalid aux table root: %w", err)
 		}
 	}
 
-	// Generate challenge seed
-	challengeSeed, err := hash.Hash(rootElems)
+	mainRows, err := dequeueRows(proofStream, ProofItemMasterMainTableRows, len(indices))
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash for challenges: %w", err)
-	}
-
-	// Generate challenges (same as prover)
-	numChallenges := 20
-	challenges := make([]*core.FieldElement, numChallenges)
-	current := challengeSeed
-	for i := 0; i < numChallenges; i++ {
-		challenges[i] = current
-		// Hash current to get next challenge
-		input := make([]*core.FieldElement, 10)
-		input[0] = current
-		for j := 1; j < 10; j++ {
-			input[j] = v.field.Zero()
-		}
-		current, err = hash.Hash(input)
+		return fmt.Errorf("failed to read main table rows: %w", err)
+	}
+	mainPaths, err := dequeueAuthenticationStructure(proofStream, len(indices))
+	if err != nil {
+		return fmt.Errorf("failed to read main table authentication structure: %w", err)
+	}
+	auxRows, err := dequeueXFieldRows(proofStream, ProofItemMasterAuxTableRows, len(indices))
+	if err != nil {
+		return fmt.Errorf("failed to read aux table rows: %w", err)
+	}
+	var auxPaths [][]hash.Digest
+	if hasAux {
+		auxPaths, err = dequeueAuthenticationStructure(proofStream, len(indices))
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate challenge %d: %w", i, err)
+			return fmt.Errorf("failed to read aux table authentication structure: %w", err)
 		}
 	}
-
-	// Convert to field.Element
-	challengesElems := make([]field.Element, len(challenges))
-	for i, c := range challenges {
-		challengesElems[i] = convertToFieldElement(c)
+	quotientRows, err := dequeueXFieldRows(proofStream, ProofItemQuotientSegmentsElements, len(indices))
+	if err != nil {
+		return fmt.Errorf("failed to read quotient segment elements: %w", err)
 	}
-
-	return challengesElems, nil
-}
-
-// sampleOODPoint samples the out-of-domain evaluation point
-//
-// Must match the prover's sampling exactly
-func (v *Verifier) sampleOODPoint(quotientRoot []byte) (*core.FieldElement, error) {
-	// Get Poseidon hash instance
-	hash, err := core.GetEnhancedPoseidonHash(v.field, 128)
+	quotientPaths, err := dequeueAuthenticationStructure(proofStream, len(indices))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create hash: %w", err)
+		return fmt.Errorf("failed to read quotient authentication structure: %w", err)
 	}
 
-	// Convert root to field elements
-	rootElems := make([]*core.FieldElement, 10)
-	for i := 0; i < 10; i++ {
-		if i*8 < len(quotientRoot) {
-			end := (i + 1) * 8
-			if end > len(quotientRoot) {
-				end = len(quotientRoot)
+	for i, index := range indices {
+		if !merkle.VerifyInclusionProof(traceRoot, uint64(index), hashTableRow(mainRows[i]), mainPaths[i]) {
+			return fmt.Errorf("main table row %d does not match the trace root", index)
+		}
+		if hasAux {
+			auxLeaf := hashTableRow(xfield.AsFlatSlice(auxRows[i]))
+			if !merkle.VerifyInclusionProof(auxRoot, uint64(index), auxLeaf, auxPaths[i]) {
+				return fmt.Errorf("aux table row %d does not match the aux table root", index)
 			}
-			chunk := quotientRoot[i*8 : end]
-			val := new(big.Int).SetBytes(chunk)
-			rootElems[i] = v.field.NewElement(val)
-		} else {
-			rootElems[i] = v.field.Zero()
+		} else if len(auxRows[i]) != 0 {
+			return fmt.Errorf("aux table row %d must be empty", index)
+		}
+		if len(quotientRows[i]) != len(ood.quotientSegments) {
+			return fmt.Errorf("quotient row %d must hold %d segments, got %d",
+				index, len(ood.quotientSegments), len(quotientRows[i]))
+		}
+		if !merkle.VerifyInclusionProof(quotientRoot, uint64(index), hashQuotientRow(quotientRows[i]), quotientPaths[i]) {
+			return fmt.Errorf("quotient row %d does not match the quotient root", index)
+		}
+
+		x := domains.FRI.Offset.Mul(domains.FRI.Generator.ModPow(uint64(index)))
+		expected, err := deepCodewordValue(x, mainRows[i], auxRows[i], quotientRows[i], ood, deepWeights)
+		if err != nil {
+			return fmt.Errorf("failed to recompute DEEP codeword at row %d: %w", index, err)
+		}
+		if !expected.Equal(deepValues[i]) {
+			return fmt.Errorf("DEEP codeword at row %d does not match the opened rows", index)
 		}
 	}
 
-	return hash.Hash(rootElems)
+	return nil
 }
 
-// verifyAIRStructure verifies the AIR constraint structure
-//
-// In a production implementation, this would:
-// 1. Extract OOD evaluations from proof
-// 2. Evaluate all AIR constraints at OOD point
-// 3. Verify constraints evaluate to zero (within soundness error)
-func (v *Verifier) verifyAIRStructure(
-	domains *ProverDomains,
-	challenges []*core.FieldElement,
-	oodPoint *core.FieldElement,
-) error {
-	// Create AIR constraints (same as prover)
-	air := CreateProcessorConstraints()
-
-	// Verify constraint structure
-	if air.NumConstraints() == 0 {
-		return fmt.Errorf("no constraints defined")
+// dequeueMerkleRoot dequeues a Merkle root
+func dequeueMerkleRoot(proofStream *ProofStream) ([]byte, error) {
+	item, err := dequeueItem(proofStream, ProofItemMerkleRoot)
+	if err != nil {
+		return nil, err
 	}
-
-	// Verify max degree is reasonable
-	maxDegree := air.MaxDegree()
-	expectedMaxDegree := v.params.MaxDegree(domains.Trace.Length)
-	if maxDegree > expectedMaxDegree {
-		return fmt.Errorf("constraint max degree %d exceeds expected %d", maxDegree, expectedMaxDegree)
+	root, ok := item.Data.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("invalid Merkle root data type")
 	}
-
-	// In a full implementation:
-	// - Extract trace evaluations at OOD from proof
-	// - Evaluate each constraint at OOD
-	// - Verify sum is zero (or close to zero within soundness)
-
-	return nil
+	return root, nil
 }
 
-// verifyFRIStructure verifies the FRI proof structure
-//
-// In a production implementation, this would:
-// 1. Extract FRI layers from proof
-// 2. Verify each Merkle root
-// 3. Verify folding correctness
-// 4. Verify final polynomial has degree < ρ
-// 5. Verify query responses and authentication paths
-func (v *Verifier) verifyFRIStructure(proof *Proof, domains *ProverDomains) error {
-	// Extract Merkle roots (at least one for FRI)
-	roots := proof.GetMerkleRoots()
-	if len(roots) < 2 {
-		return fmt.Errorf("insufficient Merkle roots for FRI verification")
-	}
-
-	// In a full implementation:
-	// - Extract FRI layers
-	// - Verify each layer's Merkle commitment
-	// - Verify folding: f^(i+1)(x) = (f^(i)(x) + f^(i)(-x)) / 2 + α(f^(i)(x) - f^(i)(-x)) / (2x)
-	// - Verify final polynomial degree
-	// - Verify query responses
-
-	// For now, verify structural requirements
-	friDomainSize := domains.FRI.Length
-	if friDomainSize == 0 || !isPowerOfTwo(friDomainSize) {
-		return fmt.Errorf("FRI domain size must be a power of 2, got %d", friDomainSize)
+// dequeueXFieldList dequeues an item holding a list of extension field elements
+func dequeueXFieldList(proofStream *ProofStream, itemType ProofItemType) ([]xfield.XFieldElement, error) {
+	item, err := dequeueItem(proofStream, itemType)
+	if err != nil {
+		return nil, err
 	}
+	values, ok := item.Data.([]xfield.XFieldElement)
+	if !ok {
+		return nil, fmt.Errorf("invalid extension field elements data type")
+	}
+	return values, nil
+}
 
-	return nil
+// dequeueRows dequeues an item holding one row of field elements per query index
+func dequeueRows(proofStream *ProofStream, itemType ProofItemType, numRows int) ([][]field.Element, error) {
+	item, err := dequeueItem(proofStream, itemType)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := item.Data.([][]field.Element)
+	if !ok || len(rows) != numRows {
+		return nil, fmt.Errorf("expected %d rows", numRows)
+	}
+	return rows, nil
 }
 
-// verifyMerkleStructure verifies all Merkle authentication paths
-//
-// In a production implementation, this would:
-// 1. Extract query indices from FRI
-// 2. Extract authentication paths from proof
-// 3. Verify each path against the committed roots
-func (v *Verifier) verifyMerkleStructure(proof *Proof) error {
-	// Verify we have Merkle roots
-	roots := proof.GetMerkleRoots()
-	if len(roots) == 0 {
-		return fmt.Errorf("no Merkle roots in proof")
-	}
-
-	// In a full implementation:
-	// - Extract query indices
-	// - Extract authentication paths
-	// - Recompute roots from leaves + paths
-	// - Verify recomputed roots match committed roots
-
-	// For now, verify roots are non-empty
-	for i, root := range roots {
-		if len(root) == 0 {
-			return fmt.Errorf("Merkle root %d is empty", i)
-		}
+// dequeueXFieldRows dequeues an item holding one row of extension field
+// elements per query index
+func dequeueXFieldRows(proofStream *ProofStream, itemType ProofItemType, numRows int) ([][]xfield.XFieldElement, error) {
+	item, err := dequeueItem(proofStream, itemType)
+	if err != nil {
+		return nil, err
 	}
+	rows, ok := item.Data.([][]xfield.XFieldElement)
+	if !ok || len(rows) != numRows {
+		return nil, fmt.Errorf("expected %d rows", numRows)
+	}
+	return rows, nil
+}
 
-	return nil
+// dequeueAuthenticationStructure dequeues one authentication path per query index
+func dequeueAuthenticationStructure(proofStream *ProofStream, numPaths int) ([][]hash.Digest, error) {
+	item, err := dequeueItem(proofStream, ProofItemAuthenticationStructure)
+	if err != nil {
+		return nil, err
+	}
+	paths, ok := item.Data.([][]hash.Digest)
+	if !ok || len(paths) != numPaths {
+		return nil, fmt.Errorf("expected %d authentication paths", numPaths)
+	}
+	return paths, nil
 }
 
 // VerifyBatch verifies multiple proofs at once (more efficient)
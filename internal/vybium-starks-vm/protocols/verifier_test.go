@@ -4,6 +4,8 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 )
 
@@ -38,7 +40,7 @@ func TestHighSecurityProofVerification(t *testing.T) {
 				t.Fatalf("Invalid parameters for %d-bit security: %v", tc.securityLevel, err)
 			}
 
-			verifier, err := NewVerifier(field, params)
+			verifier, err := NewVerifier(field, params, CreateProcessorConstraints())
 			if err != nil {
 				t.Fatalf("Failed to create verifier with %d-bit security: %v", tc.securityLevel, err)
 			}
@@ -113,3 +115,286 @@ func TestHighSecurityParametersValidation(t *testing.T) {
 		})
 	}
 }
+
+// testTrace is a minimal ExecutionTrace over raw columns
+type testTrace struct {
+	columns [][]field.Element
+}
+
+func (tt *testTrace) GetPaddedHeight() int                        { return len(tt.columns[0]) }
+func (tt *testTrace) GetTableData() interface{}                   { return tt }
+func (tt *testTrace) GetTraceColumns() ([][]field.Element, error) { return tt.columns, nil }
+
+// newProcessorTestTrace builds a trace satisfying the processor constraints:
+// clk counts up from 0, ip starts at 0 and the instruction bits are binary
+func newProcessorTestTrace(height int) *testTrace {
+	columns := make([][]field.Element, 7)
+	for col := range columns {
+		columns[col] = make([]field.Element, height)
+	}
+	for row := 0; row < height; row++ {
+		columns[0][row] = field.New(uint64(row))
+		columns[1][row] = field.New(uint64(row / 2))
+		columns[2][row] = field.New(uint64(row % 5))
+		columns[4][row] = field.New(uint64(row % 2))
+		columns[5][row] = field.New(uint64(row/2) % 2)
+	}
+	return &testTrace{columns: columns}
+}
+
+// proveTestTrace proves the given trace and returns a verifier for the proof
+func proveTestTrace(t *testing.T, trace *testTrace) (*Claim, *Proof, *Verifier) {
+	t.Helper()
+
+	params := DefaultSTARKParameters()
+	prover, err := NewProver(params, CreateProcessorConstraints())
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	prover.SetRandomnessSeed([]byte("verifier test seed"))
+
+	claim := NewClaim([]field.Element{field.New(1), field.New(2), field.New(3), field.New(4), field.New(5)})
+	proof, err := prover.Prove(claim, trace)
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+
+	goldilocksPrime := new(big.Int)
+	goldilocksPrime.SetString("18446744069414584321", 10)
+	coreField, err := core.NewField(goldilocksPrime)
+	if err != nil {
+		t.Fatalf("Failed to create field: %v", err)
+	}
+	verifier, err := NewVerifier(coreField, params, CreateProcessorConstraints())
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	return claim, proof, verifier
+}
+
+// TestVerifierOutOfDomainCheck tests that the verifier evaluates the AIR at the OOD point
+func TestVerifierOutOfDomainCheck(t *testing.T) {
+	t.Run("HonestProofVerifies", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		if err := verifier.Verify(claim, proof); err != nil {
+			t.Fatalf("Honest proof rejected: %v", err)
+		}
+	})
+
+	t.Run("ForgedMainRowRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemOutOfDomainMainRow {
+				row := append([]xfield.XFieldElement{}, item.Data.([]xfield.XFieldElement)...)
+				row[0] = row[0].Add(xfield.One)
+				proof.Items[i].Data = row
+				break
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof with forged out-of-domain main row verified")
+		}
+	})
+
+	t.Run("ForgedQuotientRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemOutOfDomainQuotientSegments {
+				segments := append([]xfield.XFieldElement{}, item.Data.([]xfield.XFieldElement)...)
+				segments[len(segments)-1] = segments[len(segments)-1].Add(xfield.One)
+				proof.Items[i].Data = segments
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof with forged out-of-domain quotient verified")
+		}
+	})
+
+	t.Run("MissingQuotientSegmentRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemOutOfDomainQuotientSegments {
+				segments := item.Data.([]xfield.XFieldElement)
+				proof.Items[i].Data = segments[:len(segments)-1]
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof missing an out-of-domain quotient segment verified")
+		}
+	})
+
+	t.Run("ExtraMainRowElementRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemOutOfDomainMainRow {
+				proof.Items[i].Data = append(append([]xfield.XFieldElement{}, item.Data.([]xfield.XFieldElement)...), xfield.One)
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof with a trailing out-of-domain main row element verified")
+		}
+	})
+
+	t.Run("MissingOutOfDomainRowsRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		items := make([]ProofItem, 0, len(proof.Items))
+		for _, item := range proof.Items {
+			if item.Type != ProofItemOutOfDomainMainRow {
+				items = append(items, item)
+			}
+		}
+		proof.Items = items
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof without out-of-domain rows verified")
+		}
+	})
+
+	t.Run("DifferentClaimRejected", func(t *testing.T) {
+		// The claim seeds the transcript, so every challenge and the
+		// out-of-domain point change with it
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		other := NewClaim(claim.ProgramDigest).WithOutput([]field.Element{field.New(7)})
+		if err := verifier.Verify(other, proof); err == nil {
+			t.Fatal("Proof verified against a different claim")
+		}
+	})
+
+	t.Run("UnsatisfiedTraceRejected", func(t *testing.T) {
+		trace := newProcessorTestTrace(8)
+		trace.columns[0][5] = field.New(42) // clock no longer increments by one
+		claim, proof, verifier := proveTestTrace(t, trace)
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof of a trace violating the AIR verified")
+		}
+	})
+}
+
+// TestVerifierRejectsLog2HeightOutOfRange tests that a padded height the
+// domains cannot hold is an error rather than a panic
+func TestVerifierRejectsLog2HeightOutOfRange(t *testing.T) {
+	for _, log2Height := range []int{-1, 33, 64} {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemLog2PaddedHeight {
+				proof.Items[i].Data = log2Height
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatalf("Proof with log2 padded height %d verified", log2Height)
+		}
+	}
+}
+
+// TestVerifierAcceptsDecodedProof tests that a proof verifies after a
+// binary round trip
+func TestVerifierAcceptsDecodedProof(t *testing.T) {
+	claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	decoded := &Proof{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if err := verifier.Verify(claim, decoded); err != nil {
+		t.Fatalf("Decoded proof rejected: %v", err)
+	}
+}
+
+// TestVerifierFRICheck tests that the verifier replays FRI from the proof stream
+func TestVerifierFRICheck(t *testing.T) {
+	t.Run("ForgedLastCodewordRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemFRICodeword {
+				codeword := append([]xfield.XFieldElement{}, item.Data.([]xfield.XFieldElement)...)
+				codeword[0] = codeword[0].Add(xfield.One)
+				proof.Items[i].Data = codeword
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof with forged last FRI codeword verified")
+		}
+	})
+
+	t.Run("MissingFRIResponseRejected", func(t *testing.T) {
+		// The FRI domain of a shorter trace is too small to fold at all
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(64))
+		last := -1
+		for i, item := range proof.Items {
+			if item.Type == ProofItemFRIResponse {
+				last = i
+			}
+		}
+		if last < 0 {
+			t.Fatal("Expected proof to contain a FRI response")
+		}
+		proof.Items = append(proof.Items[:last], proof.Items[last+1:]...)
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof missing a FRI response verified")
+		}
+	})
+
+	t.Run("TrailingItemRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		proof.AddFieldElement(field.One)
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof with trailing item verified")
+		}
+	})
+}
+
+// TestVerifierOpeningsCheck tests that opened rows are authenticated against the commitments
+func TestVerifierOpeningsCheck(t *testing.T) {
+	t.Run("ForgedMainTableRowRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemMasterMainTableRows {
+				rows := item.Data.([][]field.Element)
+				forged := make([][]field.Element, len(rows))
+				for j, row := range rows {
+					forged[j] = append([]field.Element{}, row...)
+				}
+				forged[0][0] = forged[0][0].Add(field.One)
+				proof.Items[i].Data = forged
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof with forged main table row verified")
+		}
+	})
+
+	t.Run("ForgedQuotientElementRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		for i, item := range proof.Items {
+			if item.Type == ProofItemQuotientSegmentsElements {
+				rows := item.Data.([][]xfield.XFieldElement)
+				forged := make([][]xfield.XFieldElement, len(rows))
+				for j, row := range rows {
+					forged[j] = append([]xfield.XFieldElement{}, row...)
+				}
+				forged[0][0] = forged[0][0].Add(xfield.One)
+				proof.Items[i].Data = forged
+			}
+		}
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof with forged quotient segment element verified")
+		}
+	})
+
+	t.Run("MissingAuthenticationStructureRejected", func(t *testing.T) {
+		claim, proof, verifier := proveTestTrace(t, newProcessorTestTrace(8))
+		items := make([]ProofItem, 0, len(proof.Items))
+		for _, item := range proof.Items {
+			if item.Type != ProofItemAuthenticationStructure {
+				items = append(items, item)
+			}
+		}
+		proof.Items = items
+		if err := verifier.Verify(claim, proof); err == nil {
+			t.Fatal("Proof without authentication structure verified")
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package protocols
+
+import (
+	"fmt"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+)
+
+// The cubic extension field F_p³ = F_p[x]/(x³ - x + 1)
+//
+// A base field element has 64 bits, so a challenge drawn from the base field
+// is guessed, or hits a root of a low-degree polynomial, with probability
+// around 2^-64 divided by the degree. Following Triton VM, every Fiat-Shamir
+// challenge is therefore drawn from the extension field, and everything that
+// depends on challenges lives there too: the auxiliary columns, the combined
+// quotient, the out-of-domain rows and the DEEP and FRI codewords. The main
+// table and all domains stay in the base field, which embeds into the
+// extension field as its constants.
+//
+// An extension field column is committed and interpolated as its three
+// coefficient columns, which are base field columns, since interpolation and
+// evaluation on a base field domain are linear.
+
+// xfieldBasis holds 1, x and x², the basis the coefficients refer to
+var xfieldBasis = [xfield.ExtensionDegree]xfield.XFieldElement{
+	xfield.One,
+	xfield.New([xfield.ExtensionDegree]field.Element{field.Zero, field.One, field.Zero}),
+	xfield.New([xfield.ExtensionDegree]field.Element{field.Zero, field.Zero, field.One}),
+}
+
+// BatchInverse inverts every value with a single extension field inversion
+//
+// This is Montgomery's trick: with the prefix products p_i = v_0·…·v_i, the
+// inverse of v_i is p_(i-1)·(p_i)^-1, and (p_i)^-1 follows from (p_(i+1))^-1
+// by multiplying with v_(i+1). It fails if any value is zero.
+func BatchInverse(values []xfield.XFieldElement) ([]xfield.XFieldElement, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	prefix := make([]xfield.XFieldElement, len(values))
+	product := xfield.One
+	for i, value := range values {
+		if value.IsZero() {
+			return nil, fmt.Errorf("cannot invert zero at index %d", i)
+		}
+		prefix[i] = product
+		product = product.Mul(value)
+	}
+
+	inverses := make([]xfield.XFieldElement, len(values))
+	inverse := product.Inverse()
+	for i := len(values) - 1; i >= 0; i-- {
+		inverses[i] = prefix[i].Mul(inverse)
+		inverse = inverse.Mul(values[i])
+	}
+	return inverses, nil
+}
+
+// liftElements embeds base field elements into the extension field
+func liftElements(values []field.Element) []xfield.XFieldElement {
+	lifted := make([]xfield.XFieldElement, len(values))
+	for i, value := range values {
+		lifted[i] = xfield.NewConst(value)
+	}
+	return lifted
+}
+
+// xfieldFromDigest takes the first three elements of a digest as the
+// coefficients of an extension field element
+func xfieldFromDigest(digest hash.Digest) xfield.XFieldElement {
+	var coefficients [xfield.ExtensionDegree]field.Element
+	copy(coefficients[:], digest[:xfield.ExtensionDegree])
+	return xfield.New(coefficients)
+}
+
+// coefficientColumns splits every extension field column into its three
+// coefficient columns, in the order column 0's coefficients of 1, x and x²,
+// then column 1's, and so on
+func coefficientColumns(columns [][]xfield.XFieldElement) [][]field.Element {
+	result := make([][]field.Element, 0, xfield.ExtensionDegree*len(columns))
+	for _, column := range columns {
+		for k := 0; k < xfield.ExtensionDegree; k++ {
+			coefficients := make([]field.Element, len(column))
+			for i, value := range column {
+				coefficients[i] = value.Coefficients[k]
+			}
+			result = append(result, coefficients)
+		}
+	}
+	return result
+}
+
+// xfieldElements reassembles extension field elements from consecutive
+// groups of three coefficients, the inverse of xfield.AsFlatSlice
+func xfieldElements(coefficients []field.Element) ([]xfield.XFieldElement, error) {
+	if len(coefficients)%xfield.ExtensionDegree != 0 {
+		return nil, fmt.Errorf("%d coefficients do not make up whole extension field elements", len(coefficients))
+	}
+	values := make([]xfield.XFieldElement, len(coefficients)/xfield.ExtensionDegree)
+	for i := range values {
+		copy(values[i].Coefficients[:], coefficients[xfield.ExtensionDegree*i:])
+	}
+	return values, nil
+}
+
+// interpolateXField returns the coefficients of the polynomial of degree
+// less than the domain's length that takes the given values on the domain
+func (d *ArithmeticDomain) interpolateXField(values []xfield.XFieldElement) ([]xfield.XFieldElement, error) {
+	coefficients := make([]xfield.XFieldElement, d.Length)
+	for k, column := range coefficientColumns([][]xfield.XFieldElement{values}) {
+		columnCoefficients, err := d.interpolateValues(column)
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range columnCoefficients {
+			coefficients[i].Coefficients[k] = c
+		}
+	}
+	return coefficients, nil
+}
+
+// evaluateXField evaluates a polynomial with extension field coefficients
+// over the domain
+func (d *ArithmeticDomain) evaluateXField(coefficients []xfield.XFieldElement) ([]xfield.XFieldElement, error) {
+	values := make([]xfield.XFieldElement, d.Length)
+	for k, column := range coefficientColumns([][]xfield.XFieldElement{coefficients}) {
+		evaluations, err := d.evaluateCoefficients(column)
+		if err != nil {
+			return nil, err
+		}
+		for i, value := range evaluations {
+			values[i].Coefficients[k] = value
+		}
+	}
+	return values, nil
+}
+
+// zerofierAtXField evaluates the domain's vanishing polynomial X^n - offset^n
+// at an extension field point
+func (d *ArithmeticDomain) zerofierAtXField(point xfield.XFieldElement) xfield.XFieldElement {
+	n := uint64(d.Length)
+	return point.Pow(n).SubConst(d.Offset.ModPow(n))
+}
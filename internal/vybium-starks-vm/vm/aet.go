@@ -2,8 +2,10 @@ package vm
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
@@ -16,7 +18,8 @@ type AET struct {
 	// The program that was executed to generate this trace
 	Program *Program
 
-	// Instruction execution multiplicities (how often each instruction was executed)
+	// Instruction execution multiplicities (how often the instruction at each
+	// word address was executed)
 	InstructionMultiplicities []uint64
 
 	// All 10 table traces (TIP-0006: added ProgramHashTable)
@@ -43,6 +46,11 @@ type AET struct {
 	// Metadata
 	Height       int
 	PaddedHeight int
+
+	// TableHeights holds the height of every table before padding, set by
+	// Pad. The Jump Stack Table is only filled afterwards, from the padded
+	// Processor Table.
+	TableHeights map[TableID]int
 }
 
 // NewAET creates a new Algebraic Execution Trace for a given program
@@ -51,17 +59,17 @@ func NewAET(program *Program) (*AET, error) {
 		return nil, fmt.Errorf("program cannot be nil")
 	}
 
-	// Initialize instruction multiplicities (one per instruction)
-	multiplicities := make([]uint64, len(program.Instructions))
+	// Initialize instruction multiplicities (one per word address)
+	multiplicities := make([]uint64, program.Length)
 
 	// Initialize all tables
 	processorTable := NewProcessorTable()
 	opStackTable := NewOpStackTable()
 	ramTable := NewRAMTable()
 	jumpStackTable := NewJumpStackTable()
-	programTable := NewProgramTable(16)       // chunk rate
+	programTable := NewProgramTable()
 	programHashTable := NewProgramHashTable() // TIP-0006: Program attestation
-	hashTable := NewHashTable(8, 83)          // Poseidon 128-bit security (RF=8, RP=83)
+	hashTable := NewHashTable(PoseidonStateSize, PoseidonNumRounds)
 	u32Table := NewU32Table()
 	cascadeTable := NewCascadeTable()
 	lookupTable := NewLookupTable()
@@ -71,6 +79,15 @@ func NewAET(program *Program) (*AET, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute program digest: %w", err)
 	}
+	if err := programTable.Fill(program); err != nil {
+		return nil, fmt.Errorf("failed to fill program table: %w", err)
+	}
+	// The Hash Table serves the permutations absorbing the program
+	for _, input := range programTable.permutationInputs() {
+		if err := hashTable.addPermutation(input, true); err != nil {
+			return nil, fmt.Errorf("failed to hash program: %w", err)
+		}
+	}
 
 	return &AET{
 		Program:                     program,
@@ -96,78 +113,52 @@ func NewAET(program *Program) (*AET, error) {
 // Pad pads all tables to the next power of 2 for FFT compatibility
 func (aet *AET) Pad() error {
 	// Find maximum height across all tables
-	maxHeight := aet.ProcessorTable.GetHeight()
-	if h := aet.OpStackTable.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.RAMTable.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.JumpStackTable.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.ProgramTable.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.HashTable.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.U32Table.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.CascadeTable.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.LookupTable.GetHeight(); h > maxHeight {
-		maxHeight = h
-	}
-	if h := aet.ProgramHashTable.GetHeight(); h > maxHeight {
-		maxHeight = h
+	maxHeight := 0
+	aet.TableHeights = make(map[TableID]int)
+	for _, table := range aet.GetTables() {
+		height := table.GetHeight()
+		aet.TableHeights[table.GetID()] = height
+		if height > maxHeight {
+			maxHeight = height
+		}
 	}
 
 	// Round up to next power of 2
 	paddedHeight := nextPowerOf2(maxHeight)
 
-	// Ensure minimum height of 1
-	if paddedHeight == 0 {
-		paddedHeight = 1
+	// The Hash Table is padded with whole permutations, so it needs room for
+	// at least one
+	if paddedHeight < PoseidonTraceLength {
+		paddedHeight = PoseidonTraceLength
 	}
 
 	// Pad all tables to the same height (skip if table is empty and paddedHeight would be 0)
 	if err := aet.ProcessorTable.Pad(paddedHeight); err != nil {
 		return fmt.Errorf("failed to pad processor table: %w", err)
 	}
-	// OpStack, RAM, JumpStack, Hash, U32 may be empty in simple programs
-	// Only pad if they have content
-	if aet.OpStackTable.GetHeight() > 0 {
-		if err := aet.OpStackTable.Pad(paddedHeight); err != nil {
-			return fmt.Errorf("failed to pad opstack table: %w", err)
-		}
+	// The tables the Processor Table is linked to are padded even when
+	// empty, since their constraints start in the first row
+	if err := aet.OpStackTable.Pad(paddedHeight); err != nil {
+		return fmt.Errorf("failed to pad opstack table: %w", err)
 	}
-	if aet.RAMTable.GetHeight() > 0 {
-		if err := aet.RAMTable.Pad(paddedHeight); err != nil {
-			return fmt.Errorf("failed to pad ram table: %w", err)
-		}
+	if err := aet.RAMTable.Pad(paddedHeight); err != nil {
+		return fmt.Errorf("failed to pad ram table: %w", err)
 	}
 	if aet.JumpStackTable.GetHeight() > 0 {
 		if err := aet.JumpStackTable.Pad(paddedHeight); err != nil {
 			return fmt.Errorf("failed to pad jumpstack table: %w", err)
 		}
 	}
-	if aet.ProgramTable.GetHeight() > 0 {
-		if err := aet.ProgramTable.Pad(paddedHeight); err != nil {
-			return fmt.Errorf("failed to pad program table: %w", err)
-		}
+	if err := aet.ProgramTable.Pad(paddedHeight); err != nil {
+		return fmt.Errorf("failed to pad program table: %w", err)
 	}
-	if aet.HashTable.GetHeight() > 0 {
-		if err := aet.HashTable.Pad(paddedHeight); err != nil {
-			return fmt.Errorf("failed to pad hash table: %w", err)
-		}
+	// The Hash Table is padded even when empty: its periodic columns make
+	// all-zero rows violate the round constraints
+	if err := aet.HashTable.Pad(paddedHeight); err != nil {
+		return fmt.Errorf("failed to pad hash table: %w", err)
 	}
-	if aet.U32Table.GetHeight() > 0 {
-		if err := aet.U32Table.Pad(paddedHeight); err != nil {
-			return fmt.Errorf("failed to pad u32 table: %w", err)
-		}
+	if err := aet.U32Table.Pad(paddedHeight); err != nil {
+		return fmt.Errorf("failed to pad u32 table: %w", err)
 	}
 	if aet.CascadeTable.GetHeight() > 0 {
 		if err := aet.CascadeTable.Pad(paddedHeight); err != nil {
@@ -188,93 +179,268 @@ func (aet *AET) Pad() error {
 	aet.Height = maxHeight
 	aet.PaddedHeight = paddedHeight
 
+	// Every processor row, padding included, looks up its instruction
+	pt := aet.ProcessorTable
+	for i := range pt.ip {
+		address := pt.ip[i].Value()
+		if address >= uint64(len(aet.ProgramTable.lookupMultiplicity)) {
+			return fmt.Errorf("instruction pointer %d is outside the program table", address)
+		}
+		multiplicity := aet.ProgramTable.lookupMultiplicity[address]
+		aet.ProgramTable.lookupMultiplicity[address] = multiplicity.Add(field.One)
+	}
+
 	return nil
 }
 
-// GenerateAIRConstraints generates all AIR constraints for all tables
-func (aet *AET) GenerateAIRConstraints() ([]protocols.AIRConstraint, error) {
-	var allConstraints []protocols.AIRConstraint
-
-	// Processor table constraints
-	procInitial, err := aet.ProcessorTable.CreateInitialConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("processor initial constraints: %w", err)
+// FillJumpStackTable fills the Jump Stack Table from the padded Processor
+// Table and records the clock jump differences it needs looked up
+//
+// The Jump Stack Table holds the jump stack registers of every processor
+// row, padding rows included, sorted by jump stack pointer and then by
+// clock. Consecutive rows of the same depth are a clock jump difference
+// apart, which the Processor Table serves in its cjdMultiplicity column.
+// Since the processor's clock counts the rows, the multiplicity of the
+// difference d is kept in row d.
+func (aet *AET) FillJumpStackTable() error {
+	pt := aet.ProcessorTable
+	if pt.paddedHeight == 0 {
+		return fmt.Errorf("processor table must be padded before filling the jump stack table")
+	}
+
+	entries := make([]*JumpStackEntry, pt.paddedHeight)
+	for i := range entries {
+		entries[i] = &JumpStackEntry{
+			Clock:                pt.clk[i],
+			CurrentInstruction:   pt.ci[i],
+			JumpStackPointer:     pt.jsp[i],
+			JumpStackOrigin:      pt.jso[i],
+			JumpStackDestination: pt.jsd[i],
+			EndsFrame:            pt.endsFrame(i),
+		}
 	}
-	allConstraints = append(allConstraints, procInitial...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].JumpStackPointer.Value() < entries[j].JumpStackPointer.Value()
+	})
 
-	procConsistency, err := aet.ProcessorTable.CreateConsistencyConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("processor consistency constraints: %w", err)
+	table := NewJumpStackTable()
+	for _, entry := range entries {
+		if err := table.AddRow(entry); err != nil {
+			return fmt.Errorf("failed to add jump stack row: %w", err)
+		}
 	}
-	allConstraints = append(allConstraints, procConsistency...)
+	table.paddedHeight = table.height
+	aet.JumpStackTable = table
 
-	procTransition, err := aet.ProcessorTable.CreateTransitionConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("processor transition constraints: %w", err)
+	return aet.countClockJumpDifferences()
+}
+
+// countClockJumpDifferences fills the Processor Table's cjdMultiplicity
+// column with the clock jump differences between consecutive rows of the
+// same pointer in the Jump Stack, Op Stack and RAM tables, the latter two
+// without their padding rows
+func (aet *AET) countClockJumpDifferences() error {
+	pt := aet.ProcessorTable
+	for i := range pt.cjdMultiplicity {
+		pt.cjdMultiplicity[i] = field.Zero
+	}
+
+	js, ost, rt := aet.JumpStackTable, aet.OpStackTable, aet.RAMTable
+	opStackPadding, ramPadding := field.New(OpStackPaddingValue), field.New(RAMPaddingIndicator)
+	for _, table := range []struct {
+		clk, pointer []field.Element
+		isPadding    func(i int) bool
+	}{
+		{js.clk, js.jsp, func(int) bool { return false }},
+		{ost.clk, ost.stackPointer, func(i int) bool { return ost.ib1ShrinkStack[i].Equal(opStackPadding) }},
+		{rt.clk, rt.ramPointer, func(i int) bool { return rt.instructionType[i].Equal(ramPadding) }},
+	} {
+		for i := 1; i < len(table.clk); i++ {
+			if !table.pointer[i].Equal(table.pointer[i-1]) || table.isPadding(i) {
+				continue
+			}
+			difference := table.clk[i].Sub(table.clk[i-1]).Value()
+			if difference >= uint64(len(pt.cjdMultiplicity)) {
+				return fmt.Errorf("clock jump difference %d exceeds the processor table's height %d",
+					difference, len(pt.cjdMultiplicity))
+			}
+			pt.cjdMultiplicity[difference] = pt.cjdMultiplicity[difference].Add(field.One)
+		}
 	}
-	allConstraints = append(allConstraints, procTransition...)
 
-	procTerminal, err := aet.ProcessorTable.CreateTerminalConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("processor terminal constraints: %w", err)
+	return nil
+}
+
+// isBit returns x·(x - 1), which is zero if and only if x is 0 or 1
+func isBit(x xfield.XFieldElement) xfield.XFieldElement {
+	return x.Mul(x.Sub(xfield.One))
+}
+
+// masterTableLayout returns the tables in the order their main columns appear
+// in the master table
+//
+// The Hash Table comes last: its constraints also read its periodic columns,
+// which the AIR appends after all trace columns, so its local row is a suffix
+// of the master row.
+func (aet *AET) masterTableLayout() []ExecutionTable {
+	return []ExecutionTable{
+		aet.ProcessorTable,
+		aet.OpStackTable,
+		aet.RAMTable,
+		aet.JumpStackTable,
+		aet.ProgramTable,
+		aet.ProgramHashTable,
+		aet.U32Table,
+		aet.CascadeTable,
+		aet.LookupTable,
+		aet.HashTable,
 	}
-	allConstraints = append(allConstraints, procTerminal...)
+}
 
-	// OpStack table constraints
-	opInitial, err := aet.OpStackTable.CreateInitialConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("opstack initial constraints: %w", err)
+// CreateMasterAIR creates the AIR of the master table: the constraints of
+// every table, each reading its own columns of the master row
+//
+// The master table holds the main columns of all tables side by side, in the
+// order GetTraceColumns returns them. Constraint names are prefixed with the
+// table they belong to.
+func CreateMasterAIR() (*protocols.AIRConstraints, error) {
+	// The layout only depends on the tables' shapes, not on their rows
+	aet := &AET{
+		ProcessorTable:   NewProcessorTable(),
+		OpStackTable:     NewOpStackTable(),
+		RAMTable:         NewRAMTable(),
+		JumpStackTable:   NewJumpStackTable(),
+		ProgramTable:     NewProgramTable(),
+		ProgramHashTable: NewProgramHashTable(),
+		HashTable:        NewHashTable(PoseidonStateSize, PoseidonNumRounds),
+		U32Table:         NewU32Table(),
+		CascadeTable:     NewCascadeTable(),
+		LookupTable:      NewLookupTable(),
+	}
+	tables := aet.masterTableLayout()
+
+	numColumns := 0
+	for _, table := range tables {
+		numColumns += len(table.GetMainColumns())
+	}
+
+	air := protocols.NewAIRConstraints()
+	air.SetNumColumns(numColumns)
+
+	// Periodic columns follow the last table's main columns, so the Hash
+	// Table sees them at the indices its constraints expect
+	hashOffset := numColumns - len(aet.HashTable.GetMainColumns())
+	for k, column := range aet.HashTable.GetPeriodicColumns() {
+		index, err := air.AddPeriodicColumn(column.Name, column.Values)
+		if err != nil {
+			return nil, fmt.Errorf("hash periodic column %s: %w", column.Name, err)
+		}
+		if index != hashOffset+hashRoundConstant+k {
+			return nil, fmt.Errorf("hash periodic column %s has index %d, expected %d",
+				column.Name, index-hashOffset, hashRoundConstant+k)
+		}
 	}
-	allConstraints = append(allConstraints, opInitial...)
 
-	opConsistency, err := aet.OpStackTable.CreateConsistencyConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("opstack consistency constraints: %w", err)
+	// The Processor Table's instruction table follows the Hash Table's
+	instructionTable := -1
+	for _, column := range aet.ProcessorTable.GetPeriodicColumns() {
+		index, err := air.AddPeriodicColumn(column.Name, column.Values)
+		if err != nil {
+			return nil, fmt.Errorf("processor periodic column %s: %w", column.Name, err)
+		}
+		if instructionTable < 0 {
+			instructionTable = index
+		}
 	}
-	allConstraints = append(allConstraints, opConsistency...)
 
-	opTransition, err := aet.OpStackTable.CreateTransitionConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("opstack transition constraints: %w", err)
+	offsets := make(map[TableID]int, len(tables))
+	offset := 0
+	for _, table := range tables {
+		width := len(table.GetMainColumns())
+		if err := addTableConstraints(air, table, offset, width); err != nil {
+			return nil, err
+		}
+		offsets[table.GetID()] = offset
+		offset += width
 	}
-	allConstraints = append(allConstraints, opTransition...)
 
-	opTerminal, err := aet.OpStackTable.CreateTerminalConstraints()
-	if err != nil {
-		return nil, fmt.Errorf("opstack terminal constraints: %w", err)
+	// The auxiliary columns and challenges follow the periodic columns in
+	// the evaluators' rows, so they are laid out once all of those exist
+	air.SetNumAuxColumns(numCrossTableAuxColumns)
+	air.SetNumChallenges(numCrossTableChallenges)
+	air.SetClaimChallenges(numClaimChallenges, claimChallenges)
+	addCrossTableConstraints(air, offsets, instructionTable)
+
+	return air, nil
+}
+
+// addTableConstraints adds the constraints of one table to the master AIR,
+// reading the table's columns from offset on
+//
+// The row passed to the table's evaluators ends after its width main columns,
+// except for the Hash Table, whose row also includes the periodic columns.
+func addTableConstraints(air *protocols.AIRConstraints, table ExecutionTable, offset, width int) error {
+	name := table.GetID().String()
+	end := func(row []xfield.XFieldElement) int {
+		if table.GetID() == HashTable {
+			return len(row)
+		}
+		return offset + width
+	}
+	local := func(row []xfield.XFieldElement) []xfield.XFieldElement {
+		return row[offset:end(row)]
 	}
-	allConstraints = append(allConstraints, opTerminal...)
 
-	// RAM table constraints
-	ramInitial, err := aet.RAMTable.CreateInitialConstraints()
+	initial, err := table.CreateInitialConstraints()
 	if err != nil {
-		return nil, fmt.Errorf("ram initial constraints: %w", err)
+		return fmt.Errorf("%s initial constraints: %w", name, err)
+	}
+	for _, c := range initial {
+		eval := c.Evaluator
+		air.AddInitialConstraint(c.Name, c.Degree, func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return eval(local(row))
+		})
 	}
-	allConstraints = append(allConstraints, ramInitial...)
 
-	ramConsistency, err := aet.RAMTable.CreateConsistencyConstraints()
+	consistency, err := table.CreateConsistencyConstraints()
 	if err != nil {
-		return nil, fmt.Errorf("ram consistency constraints: %w", err)
+		return fmt.Errorf("%s consistency constraints: %w", name, err)
+	}
+	for _, c := range consistency {
+		eval := c.Evaluator
+		air.AddConsistencyConstraint(c.Name, c.Degree, func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return eval(local(row))
+		})
 	}
-	allConstraints = append(allConstraints, ramConsistency...)
 
-	ramTransition, err := aet.RAMTable.CreateTransitionConstraints()
+	transition, err := table.CreateTransitionConstraints()
 	if err != nil {
-		return nil, fmt.Errorf("ram transition constraints: %w", err)
+		return fmt.Errorf("%s transition constraints: %w", name, err)
+	}
+	for _, c := range transition {
+		eval := c.Evaluator
+		air.AddTransitionConstraint(c.Name, c.Degree, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return eval(local(current), local(next))
+		})
 	}
-	allConstraints = append(allConstraints, ramTransition...)
 
-	ramTerminal, err := aet.RAMTable.CreateTerminalConstraints()
+	terminal, err := table.CreateTerminalConstraints()
 	if err != nil {
-		return nil, fmt.Errorf("ram terminal constraints: %w", err)
+		return fmt.Errorf("%s terminal constraints: %w", name, err)
+	}
+	for _, c := range terminal {
+		eval := c.Evaluator
+		air.AddTerminalConstraint(c.Name, c.Degree, func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return eval(local(row))
+		})
 	}
-	allConstraints = append(allConstraints, ramTerminal...)
 
-	// Similar for other tables...
-	// (JumpStack, Program, Hash, U32, Cascade, Lookup)
-	// For brevity, following same pattern
+	return nil
+}
 
-	return allConstraints, nil
+// GenerateAIRConstraints generates all AIR constraints for all tables
+func (aet *AET) GenerateAIRConstraints() (*protocols.AIRConstraints, error) {
+	return CreateMasterAIR()
 }
 
 // GetTables returns all execution tables as a slice
@@ -285,6 +451,7 @@ func (aet *AET) GetTables() []ExecutionTable {
 		aet.RAMTable,
 		aet.JumpStackTable,
 		aet.ProgramTable,
+		aet.ProgramHashTable,
 		aet.HashTable,
 		aet.U32Table,
 		aet.CascadeTable,
@@ -292,6 +459,18 @@ func (aet *AET) GetTables() []ExecutionTable {
 	}
 }
 
+// TallestTable returns the table whose height decided the padded height,
+// the first of GetTables among equally tall ones
+func (aet *AET) TallestTable() TableID {
+	tallest := ProcessorTable
+	for _, table := range aet.GetTables() {
+		if aet.TableHeights[table.GetID()] > aet.TableHeights[tallest] {
+			tallest = table.GetID()
+		}
+	}
+	return tallest
+}
+
 // nextPowerOf2 returns the next power of 2 greater than or equal to n
 func nextPowerOf2(n int) int {
 	if n <= 1 {
@@ -315,13 +494,30 @@ func (aet *AET) GetTableData() interface{} {
 }
 
 // GetTraceColumns implements the ExecutionTrace interface
-// Returns all trace columns from the processor table
+// Returns the main columns of all tables in master table order, the columns
+// CreateMasterAIR constrains. Tables that were never filled contribute
+// all-zero columns.
 func (aet *AET) GetTraceColumns() ([][]field.Element, error) {
 	if aet.ProcessorTable == nil {
 		return nil, fmt.Errorf("AET has no processor table")
 	}
 
-	return aet.ProcessorTable.GetColumns()
+	var columns [][]field.Element
+	for _, table := range aet.masterTableLayout() {
+		for i, column := range table.GetMainColumns() {
+			switch len(column) {
+			case aet.PaddedHeight:
+				columns = append(columns, column)
+			case 0:
+				columns = append(columns, make([]field.Element, aet.PaddedHeight))
+			default:
+				return nil, fmt.Errorf("%s column %d has length %d, expected %d",
+					table.GetID(), i, len(column), aet.PaddedHeight)
+			}
+		}
+	}
+
+	return columns, nil
 }
 
 // ===========================================================================
@@ -345,6 +541,10 @@ func (aet *AET) RecordCascadeLookup(value16 uint16) {
 
 // Record8BitLookup records an 8-bit lookup in the lookup table
 // This is used when operations directly need 8-bit lookups
+//
+// The master AIR's only client of the Lookup Table is the Cascade Table, so
+// a direct lookup recorded here breaks the lookup argument's terminal
+// constraint until its client is part of the AIR.
 func (aet *AET) Record8BitLookup(value8 byte) {
 	aet.LookupTableMultiplicities[value8]++
 }
@@ -366,8 +566,12 @@ func (aet *AET) RecordU32Value(value32 uint32) {
 func (aet *AET) ProcessU32TableForCascade() {
 	// Iterate through all U32 table entries
 	for i := 0; i < aet.U32Table.GetHeight(); i++ {
-		// Record cascade lookups for LHS, RHS, and Result
+		// Record cascade lookups for LHS, RHS, and Result of the rows the
+		// Processor Table looks up
 		// These are the 32-bit values involved in U32 operations
+		if aet.U32Table.copyFlag[i].IsZero() {
+			continue
+		}
 
 		lhs := aet.U32Table.lhs[i].Value()
 		if lhs <= 0xFFFFFFFF { // Ensure it's a valid 32-bit value
@@ -0,0 +1,519 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Severity tells whether a Diagnostic is certain to fail an execution
+type Severity int
+
+const (
+	// SeverityWarning marks suspicious code, such as unreachable code
+	SeverityWarning Severity = iota
+
+	// SeverityError marks an instruction that fails whenever it is reached
+	// the way the diagnostic describes
+	SeverityError
+)
+
+// String returns "warning" or "error"
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a problem the static analysis found at an instruction
+type Diagnostic struct {
+	Address     int // Word address of the instruction
+	Instruction Instruction
+	Severity    Severity
+	Message     string
+}
+
+// Error implements error, so that diagnostics can be returned as errors
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s at address %d (%s): %s", d.Severity, d.Address, d.Instruction, d.Message)
+}
+
+// DepthRange is the range of operational stack depths an instruction may
+// find; Max is math.MaxInt if the depth is unbounded, as in loops that push
+type DepthRange struct {
+	Min int
+	Max int
+}
+
+// ProgramAnalysis is the result of AnalyzeProgram
+type ProgramAnalysis struct {
+	// Successors is the control-flow graph: the word addresses execution
+	// may continue at after the instruction at each address. After a
+	// return these are the addresses following the calls of its function.
+	Successors map[int][]int
+
+	// Reachable holds the addresses of the instructions reachable from
+	// address 0
+	Reachable map[int]bool
+
+	// StackDepth holds the stack depths before every reachable instruction
+	// that can execute without failing first
+	StackDepth map[int]DepthRange
+
+	// Diagnostics are ordered by address
+	Diagnostics []Diagnostic
+}
+
+// Errors returns the diagnostics of severity SeverityError
+func (a *ProgramAnalysis) Errors() []Diagnostic {
+	var errs []Diagnostic
+	for _, d := range a.Diagnostics {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
+
+// initialStackDepth is the depth of the operational stack before the first
+// instruction: the program digest (TIP-0006)
+const initialStackDepth = 5
+
+// stackWidening is how often the depth range of an instruction may change
+// before its changing bounds are widened, so that loops converge
+const stackWidening = 3
+
+// stackAccess returns how many stack elements an instruction needs and by
+// how much it changes the stack depth, as its handler in vm_instructions.go
+// does. Arguments must be in range, see Instruction.ArgumentRange.
+func stackAccess(inst *EncodedInstruction) (need, effect int) {
+	n := 0
+	if inst.Argument != nil {
+		n = int(inst.Argument.Value())
+	}
+
+	switch inst.Instruction {
+	case Pop, WriteIo:
+		return n, -n
+	case Push:
+		return 0, 1
+	case Divine, ReadIo:
+		return 0, n
+	case Pick, Dup:
+		return n + 1, 1
+	case Place, Swap:
+		return n + 1, 0
+	case ReadMem:
+		return 1, n - 1
+	case WriteMem:
+		return n + 1, -(n + 1)
+	case AddI, Invert, Log2Floor, PopCount:
+		return 1, 0
+	case Skiz, Assert, SpongeAbsorbMem:
+		return 1, -1
+	case Add, Mul, Eq, Lt, And, Xor, Pow:
+		return 2, -1
+	case Split:
+		return 1, 1
+	case DivMod:
+		return 2, 0
+	case Hash:
+		return 10, -5
+	case AssertVector, SpongeAbsorb:
+		return 10, -10
+	case SpongeSqueeze:
+		return 0, 10
+	case XxAdd, XxMul:
+		return 6, -3
+	case XInvert:
+		return 3, 0
+	case XbMul:
+		return 4, -1
+	case MerkleStep:
+		return 6, 0
+	case MerkleStepMem:
+		return 7, -2
+	case XxDotStep:
+		return 9, -6
+	case XbDotStep:
+		return 7, -4
+	case PushPerm, PopPerm:
+		return 5, -5
+	}
+	return 0, 0
+}
+
+// depthState is the stack depth range found so far at an instruction
+type depthState struct {
+	DepthRange
+	changes int
+}
+
+// analyzer holds the state of one AnalyzeProgram call
+type analyzer struct {
+	program      *Program
+	instructions map[int]*EncodedInstruction // by word address
+	analysis     *ProgramAnalysis
+	valid        map[int]bool // instructions whose opcode and argument are valid
+
+	entries   map[int]bool        // function entries: valid call targets
+	functions map[int][]int       // entries of the functions each address belongs to
+	callSites map[int][]int       // calls and recursions of each function entry
+	depths    map[int]*depthState // stack depth before each instruction
+	exits     map[int]*DepthRange // stack depth at the returns of each function
+	queue     []int
+	queued    map[int]bool
+}
+
+// AnalyzeProgram builds the control-flow graph of a program and checks it
+// without executing it
+//
+// It reports invalid opcodes and arguments, calls to addresses where no
+// instruction starts, execution running past the end of the program,
+// returns outside of any function, stack underflows that are certain
+// whatever the path to an instruction, and unreachable code. Functions are
+// analyzed once for all of their calls, so underflows that depend on the
+// caller go unreported.
+func AnalyzeProgram(program *Program) *ProgramAnalysis {
+	a := &analyzer{
+		program:      program,
+		instructions: make(map[int]*EncodedInstruction, len(program.Instructions)),
+		analysis: &ProgramAnalysis{
+			Successors: make(map[int][]int),
+			Reachable:  make(map[int]bool),
+			StackDepth: make(map[int]DepthRange),
+		},
+		valid:     make(map[int]bool),
+		entries:   make(map[int]bool),
+		functions: make(map[int][]int),
+		callSites: make(map[int][]int),
+		depths:    make(map[int]*depthState),
+		exits:     make(map[int]*DepthRange),
+		queued:    make(map[int]bool),
+	}
+	address := 0
+	for _, inst := range program.Instructions {
+		a.instructions[address] = inst
+		address += inst.Instruction.Size()
+	}
+
+	a.checkInstructions()
+	a.buildFunctions()
+	a.buildGraph()
+	a.analyzeStackDepth()
+	a.checkUnreachable()
+
+	sort.SliceStable(a.analysis.Diagnostics, func(i, j int) bool {
+		return a.analysis.Diagnostics[i].Address < a.analysis.Diagnostics[j].Address
+	})
+	return a.analysis
+}
+
+// report adds a diagnostic for the instruction at address
+func (a *analyzer) report(address int, severity Severity, format string, args ...interface{}) {
+	a.analysis.Diagnostics = append(a.analysis.Diagnostics, Diagnostic{
+		Address:     address,
+		Instruction: a.instructions[address].Instruction,
+		Severity:    severity,
+		Message:     fmt.Sprintf(format, args...),
+	})
+}
+
+// checkInstructions checks the opcode, argument and call target of every
+// instruction and collects the function entries
+func (a *analyzer) checkInstructions() {
+	for address, inst := range a.instructions {
+		if _, err := inst.Instruction.Info(); err != nil {
+			a.report(address, SeverityError, "%v", err)
+			continue
+		}
+		if inst.Instruction.HasArgument() != (inst.Argument != nil) {
+			a.report(address, SeverityError, "argument does not match the instruction")
+			continue
+		}
+		if lo, hi, ok := inst.Instruction.ArgumentRange(); ok {
+			if arg := inst.Argument.Value(); arg < lo || arg > hi {
+				a.report(address, SeverityError, "argument %d out of range %d..%d", arg, lo, hi)
+				continue
+			}
+		}
+		if inst.Instruction == Call {
+			target := inst.Argument.Value()
+			if target >= uint64(a.program.Length) || a.instructions[int(target)] == nil {
+				a.report(address, SeverityError, "call to address %d, where no instruction starts", target)
+				continue
+			}
+			a.entries[int(target)] = true
+		}
+		a.valid[address] = true
+	}
+}
+
+// next returns the address of the instruction after the one at address
+func (a *analyzer) next(address int) int {
+	return address + a.instructions[address].Instruction.Size()
+}
+
+// localSuccessors returns where execution continues within the function
+// of the instruction at address: a call continues after its return, and
+// return ends the function
+func (a *analyzer) localSuccessors(address int) []int {
+	inst := a.instructions[address]
+	next := a.next(address)
+	switch inst.Instruction {
+	case Halt, Return:
+		return nil
+	case Skiz:
+		if following, ok := a.instructions[next]; ok {
+			return []int{next, next + following.Instruction.Size()}
+		}
+	}
+	return []int{next}
+}
+
+// buildFunctions finds the instructions of every function: those reachable
+// from its entry without following calls
+func (a *analyzer) buildFunctions() {
+	for entry := range a.entries {
+		seen := map[int]bool{entry: true}
+		stack := []int{entry}
+		for len(stack) > 0 {
+			address := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			a.functions[address] = append(a.functions[address], entry)
+			for _, succ := range a.localSuccessors(address) {
+				if _, ok := a.instructions[succ]; ok && !seen[succ] {
+					seen[succ] = true
+					stack = append(stack, succ)
+				}
+			}
+		}
+	}
+	for address := range a.functions {
+		sort.Ints(a.functions[address])
+	}
+}
+
+// successors returns where execution may continue after the instruction
+// at address
+func (a *analyzer) successors(address int) []int {
+	inst := a.instructions[address]
+	var result []int
+	switch inst.Instruction {
+	case Call:
+		if a.valid[address] {
+			result = append(result, int(inst.Argument.Value()))
+		}
+	case Recurse:
+		result = append(result, a.functions[address]...)
+	case Return, RecurseOrReturn:
+		if inst.Instruction == RecurseOrReturn {
+			result = append(result, a.functions[address]...)
+		}
+		for _, entry := range a.functions[address] {
+			for _, site := range a.callSites[entry] {
+				result = append(result, a.next(site))
+			}
+		}
+		if inst.Instruction == Return {
+			return result
+		}
+	}
+	return append(result, a.localSuccessors(address)...)
+}
+
+// buildGraph fills Successors and Reachable, and reports control flow that
+// leaves the program or returns without a call
+func (a *analyzer) buildGraph() {
+	for address, inst := range a.instructions {
+		if !a.valid[address] {
+			continue
+		}
+		switch inst.Instruction {
+		case Call:
+			target := int(inst.Argument.Value())
+			a.callSites[target] = append(a.callSites[target], address)
+		case Recurse, RecurseOrReturn:
+			for _, entry := range a.functions[address] {
+				a.callSites[entry] = append(a.callSites[entry], address)
+			}
+		}
+	}
+	for entry := range a.callSites {
+		sort.Ints(a.callSites[entry])
+	}
+	for address := range a.instructions {
+		a.analysis.Successors[address] = a.successors(address)
+	}
+
+	a.analysis.Reachable[0] = len(a.instructions) > 0
+	stack := []int{0}
+	for len(stack) > 0 && len(a.instructions) > 0 {
+		address := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		inst := a.instructions[address]
+		switch {
+		case inst.Instruction == Return || inst.Instruction == Recurse || inst.Instruction == RecurseOrReturn:
+			if len(a.functions[address]) == 0 {
+				a.report(address, SeverityError, "%s outside of any function", inst.Instruction)
+			}
+		case inst.Instruction != Halt && a.next(address) >= a.program.Length:
+			a.report(address, SeverityError, "execution runs past the end of the program")
+		}
+		for _, succ := range a.analysis.Successors[address] {
+			if _, ok := a.instructions[succ]; ok && !a.analysis.Reachable[succ] {
+				a.analysis.Reachable[succ] = true
+				stack = append(stack, succ)
+			}
+		}
+	}
+}
+
+// enqueue schedules the instruction at address for the depth analysis
+func (a *analyzer) enqueue(address int) {
+	if !a.queued[address] {
+		a.queued[address] = true
+		a.queue = append(a.queue, address)
+	}
+}
+
+// flow merges depths into the depth range before the instruction at address
+func (a *analyzer) flow(address int, depths DepthRange) {
+	if _, ok := a.instructions[address]; !ok {
+		return
+	}
+	state, ok := a.depths[address]
+	if !ok {
+		a.depths[address] = &depthState{DepthRange: depths}
+		a.enqueue(address)
+		return
+	}
+	merged := joinDepths(state.DepthRange, depths)
+	if merged == state.DepthRange {
+		return
+	}
+	state.changes++
+	if state.changes > stackWidening {
+		if merged.Min < state.Min {
+			merged.Min = 0
+		}
+		if merged.Max > state.Max {
+			merged.Max = math.MaxInt
+		}
+	}
+	state.DepthRange = merged
+	a.enqueue(address)
+}
+
+// exit merges the depths at a return into the exits of its functions, and
+// continues after the calls of those that changed
+func (a *analyzer) exit(address int, depths DepthRange) {
+	for _, entry := range a.functions[address] {
+		current := a.exits[entry]
+		if current != nil && joinDepths(*current, depths) == *current {
+			continue
+		}
+		if current == nil {
+			a.exits[entry] = &depths
+		} else {
+			merged := joinDepths(*current, depths)
+			a.exits[entry] = &merged
+		}
+		for _, site := range a.callSites[entry] {
+			a.enqueue(site)
+		}
+	}
+}
+
+// analyzeStackDepth propagates stack depth ranges through the control-flow
+// graph until they are stable, then reports certain underflows
+func (a *analyzer) analyzeStackDepth() {
+	if len(a.instructions) == 0 {
+		return
+	}
+	a.flow(0, DepthRange{Min: initialStackDepth, Max: initialStackDepth})
+
+	for len(a.queue) > 0 {
+		address := a.queue[0]
+		a.queue = a.queue[1:]
+		a.queued[address] = false
+		state, ok := a.depths[address]
+		if !ok || !a.valid[address] {
+			continue
+		}
+
+		inst := a.instructions[address]
+		need, effect := stackAccess(inst)
+		in := state.DepthRange
+		if in.Max < need {
+			continue
+		}
+		out := DepthRange{Min: max(in.Min, need) + effect, Max: addDepth(in.Max, effect)}
+
+		switch inst.Instruction {
+		case Halt:
+		case Call:
+			target := int(inst.Argument.Value())
+			a.flow(target, out)
+			if exit := a.exits[target]; exit != nil {
+				a.flow(a.next(address), *exit)
+			}
+		case Return:
+			a.exit(address, out)
+		case Recurse, RecurseOrReturn:
+			for _, entry := range a.functions[address] {
+				a.flow(entry, out)
+				if exit := a.exits[entry]; exit != nil {
+					a.flow(a.next(address), *exit)
+				}
+			}
+			if inst.Instruction == RecurseOrReturn {
+				a.exit(address, out)
+			}
+		default:
+			for _, succ := range a.localSuccessors(address) {
+				a.flow(succ, out)
+			}
+		}
+	}
+
+	for address, state := range a.depths {
+		need, _ := stackAccess(a.instructions[address])
+		if !a.valid[address] {
+			continue
+		}
+		if state.Max < need {
+			a.report(address, SeverityError, "stack underflow: needs %d elements, has at most %d", need, state.Max)
+			continue
+		}
+		a.analysis.StackDepth[address] = state.DepthRange
+	}
+}
+
+// checkUnreachable reports every run of unreachable instructions once
+func (a *analyzer) checkUnreachable() {
+	address := 0
+	previousReachable := true
+	for _, inst := range a.program.Instructions {
+		reachable := a.analysis.Reachable[address]
+		if !reachable && previousReachable {
+			a.report(address, SeverityWarning, "unreachable code")
+		}
+		previousReachable = reachable
+		address += inst.Instruction.Size()
+	}
+}
+
+// joinDepths returns the smallest range containing both ranges
+func joinDepths(x, y DepthRange) DepthRange {
+	return DepthRange{Min: min(x.Min, y.Min), Max: max(x.Max, y.Max)}
+}
+
+// addDepth adds an effect to a maximum depth, which stays unbounded
+func addDepth(depth, effect int) int {
+	if depth == math.MaxInt {
+		return depth
+	}
+	return depth + effect
+}
@@ -5,7 +5,10 @@ package vm
 import (
 	"fmt"
 
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
 // CrossTableArgumentType defines the type of cross-table argument
@@ -246,6 +249,9 @@ func NewGrandCrossTableArgument(field *core.Field) *GrandCrossTableArgument {
 
 // VerifyTerminalConstraints verifies all cross-table terminal constraints
 // This is called at the end of proof verification to ensure all tables are consistent
+//
+// The terminal identities a proof enforces are the terminal constraints of
+// the master AIR, see addCrossTableConstraints.
 func (gcta *GrandCrossTableArgument) VerifyTerminalConstraints(
 	aet *AlgebraicExecutionTrace,
 	challenges map[string]*core.FieldElement,
@@ -444,3 +450,1030 @@ func CompressRow(
 
 	return result, nil
 }
+
+// ===========================================================================
+// Cross-table arguments of the master AIR
+// ===========================================================================
+//
+// The auxiliary columns of the master table link the tables to each other.
+// They are built from challenges the prover samples after committing to the
+// main columns, and committed in a second round:
+//
+// 1. Processor ↔ Jump Stack: a permutation argument over the jump stack
+//    registers (clk, ci, jsp, jso, jsd) of every processor row, together
+//    with whether the row's instruction ends its frame
+// 2. Jump Stack → Processor: a log-derivative lookup of the clock jump
+//    differences between rows of the same depth in the processor's clock
+// 3. Cascade → Lookup: a log-derivative lookup of every 8-bit limb and its
+//    image under the 8-bit lookup function
+// 4. Processor → instruction table: a log-derivative lookup of the size of
+//    the instruction skiz may skip, in a periodic table that lists every
+//    opcode with its size
+// 5. Processor → Program: a log-derivative lookup of (ip, ci, nia) in every
+//    processor row, which the Program Table serves from consecutive rows
+// 6. Processor → Op Stack: a log-derivative lookup of (clk, ib1, pointer,
+//    value) for every element an instruction moves to or from the stack
+//    underflow memory, served by every non-padding Op Stack row
+// 7. Processor → RAM: a log-derivative lookup of (clk, type, pointer, value)
+//    for every word an instruction reads or writes, served by every
+//    non-padding RAM row
+// 8. Processor → Hash: a log-derivative lookup of the input and output of
+//    every permutation an instruction runs, served by the output rows of
+//    the Hash Table's permutations
+// 9. Processor → U32: a log-derivative lookup of (ci, lhs, rhs, result) for
+//    every u32 operation, served by the first row of its U32 Table section
+// 10. Op Stack, RAM → Processor: the clock jump differences between rows of
+//     the same pointer, looked up in the processor's clock like the Jump
+//     Stack's
+// 11. Program → Hash: a log-derivative lookup of the permutations absorbing
+//     the program's chunks, served by the Hash Table like the sponge
+//     instructions'
+//
+// The claim is bound through challenges derived from it (see
+// claimChallenges): the processor's initial stack and the Program Table's
+// final sponge state hold the claimed program digest, and the evaluation
+// arguments of the words read_io reads and write_io writes end at the
+// evaluations of the claimed public input and output.
+//
+// Each argument has a running column on both sides, whose initial and
+// transition constraints make it the running product or sum of its table,
+// and a terminal constraint equating the two sides. The Processor Table
+// sends its tuples to the Op Stack, RAM, Hash and U32 tables through slots,
+// see processorLinks. The RAM Table's contiguity argument, a Bezout relation
+// over its distinct pointers, shares its indeterminate with no other table.
+//
+// The run-time permutation check (TIP-0007) is a running product within the
+// Processor Table: permrp accumulates the tuples of push_perm and pop_perm,
+// and must be 1 in every assert_perm row. Its weights are challenges too, so
+// a prover cannot choose tuples that collide under them.
+
+// Challenges sampled after the main trace commitment, in the order they are
+// passed to GetAuxiliaryColumns and the evaluators
+const (
+	challengeJumpStackIndeterminate = iota
+	challengeJumpStackClkWeight
+	challengeJumpStackCIWeight
+	challengeJumpStackJSPWeight
+	challengeJumpStackJSOWeight
+	challengeJumpStackJSDWeight
+	challengeJumpStackEndsFrameWeight
+	challengeClockJumpDifferenceIndeterminate
+	challengeLookupIndeterminate
+	challengeLookupInputWeight
+	challengeLookupOutputWeight
+	challengePermutationIndeterminate
+	challengePermutationWeight0
+	challengePermutationWeight1
+	challengePermutationWeight2
+	challengePermutationWeight3
+	challengePermutationWeight4
+	challengeInstructionSizeIndeterminate
+	challengeInstructionSizeOpcodeWeight
+	challengeInstructionSizeSizeWeight
+	challengeOpStackIndeterminate
+)
+
+// The links to the Program Table and the coprocessor tables have an
+// indeterminate followed by one weight per tuple element
+const (
+	challengeOpStackWeight0         = challengeOpStackIndeterminate + 1
+	challengeRAMIndeterminate       = challengeOpStackWeight0 + numOpStackWeights
+	challengeRAMWeight0             = challengeRAMIndeterminate + 1
+	challengeRAMBezoutIndeterminate = challengeRAMWeight0 + numRAMWeights
+	challengeProgramIndeterminate   = challengeRAMBezoutIndeterminate + 1
+	challengeProgramWeight0         = challengeProgramIndeterminate + 1
+	challengeHashIndeterminate      = challengeProgramWeight0 + numProgramWeights
+	challengeHashWeight0            = challengeHashIndeterminate + 1
+	challengeU32Indeterminate       = challengeHashWeight0 + numHashWeights
+	challengeU32Weight0             = challengeU32Indeterminate + 1
+	challengeInputIndeterminate     = challengeU32Weight0 + numU32Weights
+	challengeOutputIndeterminate    = challengeInputIndeterminate + 1
+	numCrossTableChallenges         = challengeOutputIndeterminate + 1
+)
+
+// Challenges derived from the claim, following the sampled challenges: the
+// program digest and the terminals of the input and output evaluation
+// arguments
+const (
+	claimProgramDigest0 = iota
+	claimInputTerminal  = claimProgramDigest0 + PoseidonDigestLen
+	claimOutputTerminal = claimInputTerminal + 1
+	numClaimChallenges  = claimOutputTerminal + 1
+)
+
+// claimChallenges derives the claim challenges from the claim and the
+// sampled challenges
+//
+// The public output starts with the program digest (TIP-0006), which
+// write_io does not write, so only the words after it are evaluated. Both
+// evaluations start at 1 and take every word in order, so the program must
+// read all of the public input.
+func claimChallenges(claim *protocols.Claim, challenges []xfield.XFieldElement) ([]xfield.XFieldElement, error) {
+	if len(claim.ProgramDigest) != PoseidonDigestLen {
+		return nil, fmt.Errorf("program digest has %d elements, expected %d", len(claim.ProgramDigest), PoseidonDigestLen)
+	}
+	if len(claim.PublicOutput) < PoseidonDigestLen {
+		return nil, fmt.Errorf("public output of %d elements does not start with the program digest", len(claim.PublicOutput))
+	}
+	derived := make([]xfield.XFieldElement, numClaimChallenges)
+	for i, digest := range claim.ProgramDigest {
+		if !claim.PublicOutput[i].Equal(digest) {
+			return nil, fmt.Errorf("public output does not start with the program digest")
+		}
+		derived[claimProgramDigest0+i] = xfield.NewConst(digest)
+	}
+	derived[claimInputTerminal] = evaluationTerminal(challenges[challengeInputIndeterminate], claim.PublicInput)
+	derived[claimOutputTerminal] = evaluationTerminal(challenges[challengeOutputIndeterminate],
+		claim.PublicOutput[PoseidonDigestLen:])
+	return derived, nil
+}
+
+// evaluationTerminal returns the terminal of an evaluation argument over
+// symbols, starting at 1: e' = e·x + symbol for every symbol
+func evaluationTerminal(x xfield.XFieldElement, symbols []field.Element) xfield.XFieldElement {
+	terminal := xfield.One
+	for _, symbol := range symbols {
+		terminal = terminal.Mul(x).AddConst(symbol)
+	}
+	return terminal
+}
+
+// Lengths of the links' tuples: (clk, ib1, pointer, value) for the op
+// stack, (clk, type, pointer, value) for RAM, (address, instruction, next
+// word) for the program, a tag and two states for the hash, and (ci, lhs,
+// rhs, result) for u32 operations
+const (
+	numOpStackWeights = 4
+	numRAMWeights     = 4
+	numProgramWeights = 3
+	numHashWeights    = 1 + 2*PoseidonStateSize
+	numU32Weights     = 4
+)
+
+// crossTableChallengeNames are the names the tables' Update methods look the
+// challenges up by
+var crossTableChallengeNames = func() [numCrossTableChallenges]string {
+	names := [numCrossTableChallenges]string{
+		challengeJumpStackIndeterminate:           "jumpstack_indeterminate",
+		challengeJumpStackClkWeight:               "jumpstack_clk_weight",
+		challengeJumpStackCIWeight:                "jumpstack_ci_weight",
+		challengeJumpStackJSPWeight:               "jumpstack_jsp_weight",
+		challengeJumpStackJSOWeight:               "jumpstack_jso_weight",
+		challengeJumpStackJSDWeight:               "jumpstack_jsd_weight",
+		challengeJumpStackEndsFrameWeight:         "jumpstack_ends_frame_weight",
+		challengeClockJumpDifferenceIndeterminate: "clock_jump_difference_indeterminate",
+		challengeLookupIndeterminate:              "lookup_indeterminate",
+		challengeLookupInputWeight:                "lookup_input_weight",
+		challengeLookupOutputWeight:               "lookup_output_weight",
+		challengePermutationIndeterminate:         "permutation_indeterminate",
+		challengePermutationWeight0:               "permutation_weight_0",
+		challengePermutationWeight1:               "permutation_weight_1",
+		challengePermutationWeight2:               "permutation_weight_2",
+		challengePermutationWeight3:               "permutation_weight_3",
+		challengePermutationWeight4:               "permutation_weight_4",
+		challengeInstructionSizeIndeterminate:     "instruction_size_indeterminate",
+		challengeInstructionSizeOpcodeWeight:      "instruction_size_opcode_weight",
+		challengeInstructionSizeSizeWeight:        "instruction_size_size_weight",
+	}
+	for _, link := range []struct {
+		name          string
+		indeterminate int
+		numWeights    int
+	}{
+		{"op_stack", challengeOpStackIndeterminate, numOpStackWeights},
+		{"ram", challengeRAMIndeterminate, numRAMWeights},
+		{"program", challengeProgramIndeterminate, numProgramWeights},
+		{"hash", challengeHashIndeterminate, numHashWeights},
+		{"u32", challengeU32Indeterminate, numU32Weights},
+	} {
+		names[link.indeterminate] = link.name + "_indeterminate"
+		for k := 0; k < link.numWeights; k++ {
+			names[link.indeterminate+1+k] = fmt.Sprintf("%s_weight_%d", link.name, k)
+		}
+	}
+	names[challengeRAMBezoutIndeterminate] = "ram_bezout_indeterminate"
+	names[challengeInputIndeterminate] = "input_indeterminate"
+	names[challengeOutputIndeterminate] = "output_indeterminate"
+	return names
+}()
+
+// Auxiliary columns of the master table, in GetAuxiliaryColumns order
+const (
+	auxProcessorJumpStackPermArg = iota
+	auxProcessorClockJumpDiffLookup
+	auxJumpStackPermArg
+	auxJumpStackClockJumpDiffLog
+	auxCascadeLookupTableLogDeriv
+	auxLookupTableLogDeriv
+	auxProcessorPermutationRunningProduct
+	auxProcessorInstructionSizeLookup
+	auxProcessorInstructionLookup
+	auxProgramInstructionLookup
+	auxOpStackLogDeriv
+	auxOpStackClockJumpDiffLog
+	auxRAMLogDeriv
+	auxRAMClockJumpDiffLog
+	auxRAMRunningProduct
+	auxRAMFormalDerivative
+	auxRAMBezoutCoeff0
+	auxRAMBezoutCoeff1
+	auxHashLogDeriv
+	auxU32LogDeriv
+	auxProgramHashLookup
+	auxProcessorInputEval
+	auxProcessorOutputEval
+	auxProcessorLink0 // The Processor Table's slots, see processorLinks
+)
+
+const numCrossTableAuxColumns = auxProcessorLink0 + numProcessorLinkSlots
+
+// jumpStackChallenges compress the jump stack registers of a row
+type jumpStackChallenges struct {
+	indeterminate          xfield.XFieldElement
+	clk, ci, jsp, jso, jsd xfield.XFieldElement
+	endsFrame              xfield.XFieldElement
+}
+
+// jumpStackWeights extracts the jump stack challenges from a named challenge map
+func jumpStackWeights(challenges map[string]xfield.XFieldElement) (*jumpStackChallenges, error) {
+	values, err := namedChallenges(challenges,
+		challengeJumpStackIndeterminate, challengeJumpStackClkWeight, challengeJumpStackCIWeight,
+		challengeJumpStackJSPWeight, challengeJumpStackJSOWeight, challengeJumpStackJSDWeight,
+		challengeJumpStackEndsFrameWeight)
+	if err != nil {
+		return nil, err
+	}
+	return &jumpStackChallenges{
+		indeterminate: values[0],
+		clk:           values[1],
+		ci:            values[2],
+		jsp:           values[3],
+		jso:           values[4],
+		jsd:           values[5],
+		endsFrame:     values[6],
+	}, nil
+}
+
+// compress returns clk_weight·clk + ci_weight·ci + jsp_weight·jsp + jso_weight·jso + jsd_weight·jsd
+// + ends_frame_weight·ends_frame
+func (w *jumpStackChallenges) compress(clk, ci, jsp, jso, jsd, endsFrame field.Element) xfield.XFieldElement {
+	return w.clk.MulConst(clk).
+		Add(w.ci.MulConst(ci)).
+		Add(w.jsp.MulConst(jsp)).
+		Add(w.jso.MulConst(jso)).
+		Add(w.jsd.MulConst(jsd)).
+		Add(w.endsFrame.MulConst(endsFrame))
+}
+
+// lookupChallenges compress an (input, output) pair of the 8-bit lookup
+type lookupChallenges struct {
+	indeterminate xfield.XFieldElement
+	input, output xfield.XFieldElement
+}
+
+// lookupWeights extracts the 8-bit lookup challenges from a named challenge map
+func lookupWeights(challenges map[string]xfield.XFieldElement) (*lookupChallenges, error) {
+	values, err := namedChallenges(challenges,
+		challengeLookupIndeterminate, challengeLookupInputWeight, challengeLookupOutputWeight)
+	if err != nil {
+		return nil, err
+	}
+	return &lookupChallenges{
+		indeterminate: values[0],
+		input:         values[1],
+		output:        values[2],
+	}, nil
+}
+
+// compress returns input_weight·input + output_weight·output
+func (w *lookupChallenges) compress(input, output field.Element) xfield.XFieldElement {
+	return w.input.MulConst(input).Add(w.output.MulConst(output))
+}
+
+// instructionSizeChallenges compress an (opcode, size) pair of skiz's
+// instruction size lookup
+type instructionSizeChallenges struct {
+	indeterminate xfield.XFieldElement
+	opcode, size  xfield.XFieldElement
+}
+
+// instructionSizeWeights extracts the instruction size challenges from a named challenge map
+func instructionSizeWeights(challenges map[string]xfield.XFieldElement) (*instructionSizeChallenges, error) {
+	values, err := namedChallenges(challenges,
+		challengeInstructionSizeIndeterminate, challengeInstructionSizeOpcodeWeight, challengeInstructionSizeSizeWeight)
+	if err != nil {
+		return nil, err
+	}
+	return &instructionSizeChallenges{
+		indeterminate: values[0],
+		opcode:        values[1],
+		size:          values[2],
+	}, nil
+}
+
+// compress returns opcode_weight·opcode + size_weight·size
+func (w *instructionSizeChallenges) compress(opcode, size field.Element) xfield.XFieldElement {
+	return w.opcode.MulConst(opcode).Add(w.size.MulConst(size))
+}
+
+// permutationChallenges compress the tuple of a permutation instruction
+type permutationChallenges struct {
+	indeterminate xfield.XFieldElement
+	weights       [5]xfield.XFieldElement
+}
+
+// permutationWeights extracts the TIP-0007 challenges from a named challenge map
+func permutationWeights(challenges map[string]xfield.XFieldElement) (*permutationChallenges, error) {
+	values, err := namedChallenges(challenges,
+		challengePermutationIndeterminate, challengePermutationWeight0, challengePermutationWeight1,
+		challengePermutationWeight2, challengePermutationWeight3, challengePermutationWeight4)
+	if err != nil {
+		return nil, err
+	}
+	w := &permutationChallenges{indeterminate: values[0]}
+	copy(w.weights[:], values[1:])
+	return w, nil
+}
+
+// compress returns the inner product Σ a_i·st_i of the weights and the tuple
+func (w *permutationChallenges) compress(tuple [5]field.Element) xfield.XFieldElement {
+	value := xfield.Zero
+	for i, weight := range w.weights {
+		value = value.Add(weight.MulConst(tuple[i]))
+	}
+	return value
+}
+
+// tupleChallenges compress the tuples of a link to the Program Table or a
+// coprocessor table
+type tupleChallenges struct {
+	indeterminate xfield.XFieldElement
+	weights       []xfield.XFieldElement
+}
+
+// tupleWeights extracts the challenges of the link whose indeterminate is
+// given, followed by n weights, from a named challenge map
+func tupleWeights(challenges map[string]xfield.XFieldElement, indeterminate, n int) (*tupleChallenges, error) {
+	indices := make([]int, n+1)
+	for k := range indices {
+		indices[k] = indeterminate + k
+	}
+	values, err := namedChallenges(challenges, indices...)
+	if err != nil {
+		return nil, err
+	}
+	return &tupleChallenges{indeterminate: values[0], weights: values[1:]}, nil
+}
+
+// rowTupleChallenges returns the same challenges from the challenge
+// elements of a master AIR row
+func rowTupleChallenges(row []xfield.XFieldElement, challenge func(int) int, indeterminate, n int) *tupleChallenges {
+	first := challenge(indeterminate)
+	return &tupleChallenges{indeterminate: row[first], weights: row[first+1 : first+1+n]}
+}
+
+// compress returns the inner product Σ w_i·t_i of the weights and the tuple
+func (w *tupleChallenges) compress(tuple []xfield.XFieldElement) xfield.XFieldElement {
+	value := xfield.Zero
+	for i, element := range tuple {
+		value = value.Add(w.weights[i].Mul(element))
+	}
+	return value
+}
+
+// compressBase is compress for a tuple of base field elements
+func (w *tupleChallenges) compressBase(tuple ...field.Element) xfield.XFieldElement {
+	value := xfield.Zero
+	for i, element := range tuple {
+		value = value.Add(w.weights[i].MulConst(element))
+	}
+	return value
+}
+
+// namedChallenges looks up the given challenges by name
+func namedChallenges(challenges map[string]xfield.XFieldElement, indices ...int) ([]xfield.XFieldElement, error) {
+	values := make([]xfield.XFieldElement, len(indices))
+	for i, idx := range indices {
+		name := crossTableChallengeNames[idx]
+		value, ok := challenges[name]
+		if !ok {
+			return nil, fmt.Errorf("missing %s challenge", name)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// GetAuxiliaryColumns implements the protocols.AuxiliaryTrace interface
+// Builds the auxiliary columns of the master table from the challenges,
+// through the tables' Update methods. Tables that were never filled have
+// all-zero auxiliary columns, which satisfy their constraints.
+func (aet *AET) GetAuxiliaryColumns(challenges []xfield.XFieldElement) ([][]xfield.XFieldElement, error) {
+	if len(challenges) != numCrossTableChallenges {
+		return nil, fmt.Errorf("expected %d challenges, got %d", numCrossTableChallenges, len(challenges))
+	}
+	named := make(map[string]xfield.XFieldElement, len(challenges))
+	for i, challenge := range challenges {
+		named[crossTableChallengeNames[i]] = challenge
+	}
+
+	if err := aet.ProcessorTable.UpdateJumpStackPermutationArgument(named); err != nil {
+		return nil, fmt.Errorf("processor jump stack permutation argument: %w", err)
+	}
+	if err := aet.ProcessorTable.UpdateClockJumpDifferenceLookup(challenges[challengeClockJumpDifferenceIndeterminate]); err != nil {
+		return nil, fmt.Errorf("processor clock jump difference lookup: %w", err)
+	}
+	if err := aet.JumpStackTable.UpdatePermutationArgument(named); err != nil {
+		return nil, fmt.Errorf("jump stack permutation argument: %w", err)
+	}
+	if err := aet.JumpStackTable.UpdateClockJumpLogDerivative(challenges[challengeClockJumpDifferenceIndeterminate]); err != nil {
+		return nil, fmt.Errorf("jump stack clock jump difference lookup: %w", err)
+	}
+	if err := aet.CascadeTable.UpdateLookupTableLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("cascade lookup argument: %w", err)
+	}
+	if err := aet.LookupTable.UpdateLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("lookup table lookup argument: %w", err)
+	}
+	if err := aet.ProcessorTable.UpdatePermutationRunningProduct(named); err != nil {
+		return nil, fmt.Errorf("processor permutation running product: %w", err)
+	}
+	if err := aet.ProcessorTable.UpdateInstructionSizeLookup(named); err != nil {
+		return nil, fmt.Errorf("processor instruction size lookup: %w", err)
+	}
+	if err := aet.ProcessorTable.UpdateCoprocessorLinks(named); err != nil {
+		return nil, fmt.Errorf("processor coprocessor links: %w", err)
+	}
+	if err := aet.ProgramTable.UpdateLookupLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("program instruction lookup: %w", err)
+	}
+	if err := aet.OpStackTable.UpdateLookupLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("op stack lookup argument: %w", err)
+	}
+	if err := aet.OpStackTable.UpdateClockJumpLogDerivative(challenges[challengeClockJumpDifferenceIndeterminate]); err != nil {
+		return nil, fmt.Errorf("op stack clock jump difference lookup: %w", err)
+	}
+	if err := aet.RAMTable.UpdateLookupLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("ram lookup argument: %w", err)
+	}
+	if err := aet.RAMTable.UpdateClockJumpLogDerivative(challenges[challengeClockJumpDifferenceIndeterminate]); err != nil {
+		return nil, fmt.Errorf("ram clock jump difference lookup: %w", err)
+	}
+	if err := aet.RAMTable.UpdateContiguityArgument(challenges[challengeRAMBezoutIndeterminate]); err != nil {
+		return nil, fmt.Errorf("ram contiguity argument: %w", err)
+	}
+	if err := aet.HashTable.UpdateLookupLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("hash lookup argument: %w", err)
+	}
+	if err := aet.U32Table.UpdateLookupLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("u32 lookup argument: %w", err)
+	}
+	if err := aet.ProgramTable.UpdateHashLookupLogDerivative(named); err != nil {
+		return nil, fmt.Errorf("program hash lookup: %w", err)
+	}
+	if err := aet.ProcessorTable.UpdateIOEvaluationArguments(named); err != nil {
+		return nil, fmt.Errorf("processor I/O evaluation arguments: %w", err)
+	}
+
+	columns := [numCrossTableAuxColumns][]xfield.XFieldElement{
+		auxProcessorJumpStackPermArg:    aet.ProcessorTable.permArg,
+		auxProcessorClockJumpDiffLookup: aet.ProcessorTable.clockJumpDiffLookup,
+		auxJumpStackPermArg:             aet.JumpStackTable.runningProductPerm,
+		auxJumpStackClockJumpDiffLog:    aet.JumpStackTable.clockJumpDiffLog,
+		auxCascadeLookupTableLogDeriv:   aet.CascadeTable.lookupTableLogDeriv,
+		auxLookupTableLogDeriv:          aet.LookupTable.lookupLogDeriv,
+
+		auxProcessorPermutationRunningProduct: aet.ProcessorTable.permrp,
+		auxProcessorInstructionSizeLookup:     aet.ProcessorTable.instructionSizes,
+		auxProcessorInstructionLookup:         aet.ProcessorTable.instructionLookup,
+		auxProgramInstructionLookup:           aet.ProgramTable.instrLookupLogDeriv,
+
+		auxOpStackLogDeriv:         aet.OpStackTable.lookupLogDeriv,
+		auxOpStackClockJumpDiffLog: aet.OpStackTable.clockJumpDiffLogDeriv,
+		auxRAMLogDeriv:             aet.RAMTable.lookupLogDeriv,
+		auxRAMClockJumpDiffLog:     aet.RAMTable.clockJumpDiffLog,
+		auxRAMRunningProduct:       aet.RAMTable.runningProductRAMP,
+		auxRAMFormalDerivative:     aet.RAMTable.formalDerivative,
+		auxRAMBezoutCoeff0:         aet.RAMTable.bezoutCoeff0,
+		auxRAMBezoutCoeff1:         aet.RAMTable.bezoutCoeff1,
+		auxHashLogDeriv:            aet.HashTable.lookupLogDeriv,
+		auxU32LogDeriv:             aet.U32Table.lookupLogDeriv,
+		auxProgramHashLookup:       aet.ProgramTable.hashLookupLogDeriv,
+		auxProcessorInputEval:      aet.ProcessorTable.inputEval,
+		auxProcessorOutputEval:     aet.ProcessorTable.outputEval,
+	}
+	for s := range aet.ProcessorTable.linkSlots {
+		columns[auxProcessorLink0+s] = aet.ProcessorTable.linkSlots[s]
+	}
+	result := make([][]xfield.XFieldElement, 0, len(columns))
+	for i, column := range columns {
+		switch len(column) {
+		case aet.PaddedHeight:
+			result = append(result, column)
+		case 0:
+			result = append(result, make([]xfield.XFieldElement, aet.PaddedHeight))
+		default:
+			return nil, fmt.Errorf("auxiliary column %d has length %d, expected %d", i, len(column), aet.PaddedHeight)
+		}
+	}
+
+	return result, nil
+}
+
+// addCrossTableConstraints adds the constraints of the cross-table arguments
+// to the master AIR
+//
+// offsets are the positions of the tables' main columns in the master row,
+// and instructionTable the position of the instruction table's opcode
+// column, which the size column follows. All periodic columns must have
+// been added, since they precede the auxiliary columns and challenges in the
+// rows passed to the evaluators.
+func addCrossTableConstraints(air *protocols.AIRConstraints, offsets map[TableID]int, instructionTable int) {
+	aux := air.AuxColumnIndex
+	challenge := air.ChallengeIndex
+
+	// Processor ↔ Jump Stack permutation argument
+	//
+	// rp[0] = α - compressed_row[0]
+	// rp' = rp·(α - compressed_row')
+	//
+	// The Jump Stack Table records ends_frame in a column, while the
+	// Processor Table derives it from the row's instruction and jsp.
+	jumpStackColumns := map[TableID][5]int{
+		ProcessorTable: {processorClk, processorCI, processorJSP, processorJSO, processorJSD},
+		JumpStackTable: {jumpStackClk, jumpStackCI, jumpStackJSP, jumpStackJSO, jumpStackJSD},
+	}
+	endsFrame := map[TableID]func(row []xfield.XFieldElement) xfield.XFieldElement{
+		ProcessorTable: func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return processorEndsFrame(row[offsets[ProcessorTable]:])
+		},
+		JumpStackTable: func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[offsets[JumpStackTable]+jumpStackEndsFrame]
+		},
+	}
+	compressJumpStack := func(row []xfield.XFieldElement, table TableID) xfield.XFieldElement {
+		offset := offsets[table]
+		value := row[challenge(challengeJumpStackEndsFrameWeight)].Mul(endsFrame[table](row))
+		for i, col := range jumpStackColumns[table] {
+			value = value.Add(row[challenge(challengeJumpStackClkWeight+i)].Mul(row[offset+col]))
+		}
+		return value
+	}
+	for _, side := range []struct {
+		name   string
+		table  TableID
+		col    int
+		degree int // Degree of the compressed row
+	}{
+		{"processor", ProcessorTable, auxProcessorJumpStackPermArg, 3},
+		{"jump_stack", JumpStackTable, auxJumpStackPermArg, 1},
+	} {
+		side := side
+		air.AddInitialConstraint(side.name+"_jump_stack_perm_arg_starts_with_first_row", side.degree,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				alpha := row[challenge(challengeJumpStackIndeterminate)]
+				return row[aux(side.col)].Sub(alpha.Sub(compressJumpStack(row, side.table)))
+			})
+		air.AddTransitionConstraint(side.name+"_jump_stack_perm_arg_accumulates_row", side.degree+1,
+			func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				alpha := next[challenge(challengeJumpStackIndeterminate)]
+				factor := alpha.Sub(compressJumpStack(next, side.table))
+				return next[aux(side.col)].Sub(current[aux(side.col)].Mul(factor))
+			})
+	}
+	air.AddTerminalConstraint("processor_jump_stack_permutation", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[aux(auxProcessorJumpStackPermArg)].Sub(row[aux(auxJumpStackPermArg)])
+	})
+
+	// Clock jump differences, looked up in the Processor Table's clock
+	//
+	// Server: ld[0]·(γ - clk[0]) = m[0],  (ld' - ld)·(γ - clk') = m'
+	// Client: ld[0] = 0,  (ld' - ld)·(γ - (clk' - clk)) = 1 - (jsp' - jsp)
+	processor := offsets[ProcessorTable]
+	jumpStack := offsets[JumpStackTable]
+	air.AddInitialConstraint("processor_clock_jump_diff_lookup_starts_with_first_row", 2,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			gamma := row[challenge(challengeClockJumpDifferenceIndeterminate)]
+			denominator := gamma.Sub(row[processor+processorClk])
+			return row[aux(auxProcessorClockJumpDiffLookup)].Mul(denominator).
+				Sub(row[processor+processorCJDMultiplicity])
+		})
+	air.AddTransitionConstraint("processor_clock_jump_diff_lookup_accumulates_row", 2,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			gamma := next[challenge(challengeClockJumpDifferenceIndeterminate)]
+			denominator := gamma.Sub(next[processor+processorClk])
+			diff := next[aux(auxProcessorClockJumpDiffLookup)].Sub(current[aux(auxProcessorClockJumpDiffLookup)])
+			return diff.Mul(denominator).Sub(next[processor+processorCJDMultiplicity])
+		})
+	air.AddInitialConstraint("jump_stack_clock_jump_diff_log_starts_at_0", 1,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[aux(auxJumpStackClockJumpDiffLog)]
+		})
+	air.AddTransitionConstraint("jump_stack_clock_jump_diff_log_accumulates_same_depth", 2,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			gamma := next[challenge(challengeClockJumpDifferenceIndeterminate)]
+			clockDiff := next[jumpStack+jumpStackClk].Sub(current[jumpStack+jumpStackClk])
+			sameDepth := xfield.One.Sub(next[jumpStack+jumpStackJSP].Sub(current[jumpStack+jumpStackJSP]))
+			diff := next[aux(auxJumpStackClockJumpDiffLog)].Sub(current[aux(auxJumpStackClockJumpDiffLog)])
+			return diff.Mul(gamma.Sub(clockDiff)).Sub(sameDepth)
+		})
+	air.AddTerminalConstraint("clock_jump_difference_lookup", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		clients := row[aux(auxJumpStackClockJumpDiffLog)].
+			Add(row[aux(auxOpStackClockJumpDiffLog)]).
+			Add(row[aux(auxRAMClockJumpDiffLog)])
+		return row[aux(auxProcessorClockJumpDiffLookup)].Sub(clients)
+	})
+
+	// Cascade → Lookup lookup argument
+	//
+	// Client: ld·(β - lo)·(β - hi) = m·((β - lo) + (β - hi)) for the first
+	// row, and the same with ld' - ld for every following row
+	// Server: ld·(β - c) = m for the first row, (ld' - ld)·(β - c') = m' after
+	cascade := offsets[CascadeTable]
+	lookup := offsets[LookupTable]
+	compressLookup := func(row []xfield.XFieldElement, input, output int) xfield.XFieldElement {
+		return row[challenge(challengeLookupInputWeight)].Mul(row[input]).
+			Add(row[challenge(challengeLookupOutputWeight)].Mul(row[output]))
+	}
+	cascadeTerm := func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+		beta := row[challenge(challengeLookupIndeterminate)]
+		lo := beta.Sub(compressLookup(row, cascade+cascadeLookInLo, cascade+cascadeLookOutLo))
+		hi := beta.Sub(compressLookup(row, cascade+cascadeLookInHi, cascade+cascadeLookOutHi))
+		return logDerivative.Mul(lo).Mul(hi).Sub(row[cascade+cascadeLookupMultiplicity].Mul(lo.Add(hi)))
+	}
+	lookupTerm := func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+		beta := row[challenge(challengeLookupIndeterminate)]
+		denominator := beta.Sub(compressLookup(row, lookup+lookupIndex, lookup+lookupValue))
+		return logDerivative.Mul(denominator).Sub(row[lookup+lookupMultiplicity])
+	}
+	air.AddInitialConstraint("cascade_lookup_table_log_deriv_starts_with_first_row", 3,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return cascadeTerm(row, row[aux(auxCascadeLookupTableLogDeriv)])
+		})
+	air.AddTransitionConstraint("cascade_lookup_table_log_deriv_accumulates_row", 3,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			diff := next[aux(auxCascadeLookupTableLogDeriv)].Sub(current[aux(auxCascadeLookupTableLogDeriv)])
+			return cascadeTerm(next, diff)
+		})
+	air.AddInitialConstraint("lookup_log_deriv_starts_with_first_row", 2,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return lookupTerm(row, row[aux(auxLookupTableLogDeriv)])
+		})
+	air.AddTransitionConstraint("lookup_log_deriv_accumulates_row", 2,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			diff := next[aux(auxLookupTableLogDeriv)].Sub(current[aux(auxLookupTableLogDeriv)])
+			return lookupTerm(next, diff)
+		})
+	air.AddTerminalConstraint("cascade_lookup_table_lookup", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[aux(auxCascadeLookupTableLogDeriv)].Sub(row[aux(auxLookupTableLogDeriv)])
+	})
+
+	// Run-time permutation check (TIP-0007)
+	//
+	// permrp[0] = 1
+	// permrp'·(1 + is_pop_perm·(α - p - 1)) = permrp·(1 + is_push_perm·(α - p - 1))
+	// is_assert_perm·(permrp - 1) = 0
+	//
+	// with p = Σ a_i·st_i over the row's top five stack elements. The
+	// selectors are the instruction selectors, which decode ci, and ci is
+	// bound to the program by the instruction lookup, so a prover cannot
+	// switch them off.
+	permFactor := func(row []xfield.XFieldElement, selector int) xfield.XFieldElement {
+		alpha := row[challenge(challengePermutationIndeterminate)]
+		p := xfield.Zero
+		for i := 0; i < 5; i++ {
+			p = p.Add(row[challenge(challengePermutationWeight0+i)].Mul(row[processor+processorST0+i]))
+		}
+		return xfield.One.Add(row[processor+selector].Mul(alpha.Sub(p).Sub(xfield.One)))
+	}
+	air.AddInitialConstraint("processor_permrp_starts_at_1", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[aux(auxProcessorPermutationRunningProduct)].Sub(xfield.One)
+	})
+	air.AddTransitionConstraint("processor_permrp_accumulates_perm_instructions", 3,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			popped := next[aux(auxProcessorPermutationRunningProduct)].Mul(permFactor(current, processorSelector(PopPerm)))
+			pushed := current[aux(auxProcessorPermutationRunningProduct)].Mul(permFactor(current, processorSelector(PushPerm)))
+			return popped.Sub(pushed)
+		})
+	air.AddConsistencyConstraint("processor_assert_perm_needs_permrp_1", 2, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[processor+processorSelector(AssertPerm)].Mul(row[aux(auxProcessorPermutationRunningProduct)].Sub(xfield.One))
+	})
+
+	// Instruction size lookup of skiz
+	//
+	// ld·(γ - c)·(γ - s) = skiz·(γ - s) - m·(γ - c) for the first row, and
+	// the same with ld' - ld for every following row; ld ends at 0
+	//
+	// with c compressing the next instruction nia and its size hv1, and s
+	// the opcode and size the instruction table lists in the row
+	sizeTerm := func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+		gamma := row[challenge(challengeInstructionSizeIndeterminate)]
+		opcodeWeight := row[challenge(challengeInstructionSizeOpcodeWeight)]
+		sizeWeight := row[challenge(challengeInstructionSizeSizeWeight)]
+		lookedUp := gamma.Sub(opcodeWeight.Mul(row[processor+processorNIA])).
+			Sub(sizeWeight.Mul(row[processor+processorHelper0+1]))
+		listed := gamma.Sub(opcodeWeight.Mul(row[instructionTable])).
+			Sub(sizeWeight.Mul(row[instructionTable+1]))
+		skiz := row[processor+processorSelector(Skiz)]
+		multiplicity := row[processor+processorInstructionSizeMultiplicity]
+		return logDerivative.Mul(lookedUp).Mul(listed).Sub(skiz.Mul(listed)).Add(multiplicity.Mul(lookedUp))
+	}
+	air.AddInitialConstraint("processor_instruction_size_lookup_starts_with_first_row", 3,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return sizeTerm(row, row[aux(auxProcessorInstructionSizeLookup)])
+		})
+	air.AddTransitionConstraint("processor_instruction_size_lookup_accumulates_row", 3,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			diff := next[aux(auxProcessorInstructionSizeLookup)].Sub(current[aux(auxProcessorInstructionSizeLookup)])
+			return sizeTerm(next, diff)
+		})
+	air.AddTerminalConstraint("processor_instruction_size_lookup", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[aux(auxProcessorInstructionSizeLookup)]
+	})
+
+	// Instruction lookup in the Program Table
+	//
+	// Client: ld[0]·(α - c[0]) = 1,  (ld' - ld)·(α - c') = 1
+	// Server: ld[0] = 0,  (ld' - ld)·(α - c) = m
+	//
+	// with c compressing (ip, ci, nia) in the Processor Table, and the
+	// current row's address and instruction with the next row's instruction
+	// in the Program Table
+	program := offsets[ProgramTable]
+	compressRow := func(row []xfield.XFieldElement, indeterminate, n int, tuple ...xfield.XFieldElement) (alpha, compressed xfield.XFieldElement) {
+		weights := rowTupleChallenges(row, challenge, indeterminate, n)
+		return weights.indeterminate, weights.compress(tuple)
+	}
+	instructionTerm := func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+		alpha, c := compressRow(row, challengeProgramIndeterminate, numProgramWeights,
+			row[processor+processorIP], row[processor+processorCI], row[processor+processorNIA])
+		return logDerivative.Mul(alpha.Sub(c)).Sub(xfield.One)
+	}
+	air.AddInitialConstraint("processor_instruction_lookup_starts_with_first_row", 2,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return instructionTerm(row, row[aux(auxProcessorInstructionLookup)])
+		})
+	air.AddTransitionConstraint("processor_instruction_lookup_accumulates_row", 2,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			diff := next[aux(auxProcessorInstructionLookup)].Sub(current[aux(auxProcessorInstructionLookup)])
+			return instructionTerm(next, diff)
+		})
+	air.AddInitialConstraint("program_instruction_lookup_starts_at_0", 1,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[aux(auxProgramInstructionLookup)]
+		})
+	air.AddTransitionConstraint("program_instruction_lookup_accumulates_row", 2,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			alpha, c := compressRow(current, challengeProgramIndeterminate, numProgramWeights,
+				current[program+programAddress], current[program+programInstruction], next[program+programInstruction])
+			diff := next[aux(auxProgramInstructionLookup)].Sub(current[aux(auxProgramInstructionLookup)])
+			return diff.Mul(alpha.Sub(c)).Sub(current[program+programLookupMultiplicity])
+		})
+	air.AddTerminalConstraint("processor_program_instruction_lookup", 1, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[aux(auxProcessorInstructionLookup)].Sub(row[aux(auxProgramInstructionLookup)])
+	})
+
+	// Servers of the Processor Table's links
+	//
+	// Op Stack, RAM: ld[0]·(α - c[0]) = notPad[0],  (ld' - ld)·(α - c') = notPad'
+	// Hash: ld·(β - cf)·(β - cs) = mf·(β - cs) + ms·(β - cf), with ld' - ld
+	// after the first row, for the fixed-length and sponge tuples
+	// U32: ld[0]·(α - c[0]) = m[0],  (ld' - ld)·(α - c') = m'
+	opStack := offsets[OperationalStackTable]
+	ram := offsets[RAMTable]
+	hash := offsets[HashTable]
+	u32 := offsets[U32Table]
+	servers := []struct {
+		name   string
+		col    int
+		degree int
+		term   func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement
+	}{
+		{"op_stack", auxOpStackLogDeriv, 2, func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+			alpha, c := compressRow(row, challengeOpStackIndeterminate, numOpStackWeights,
+				row[opStack+opStackClk], row[opStack+opStackIB1ShrinkStack],
+				row[opStack+opStackPointer], row[opStack+opStackFirstUnderflowElement])
+			return logDerivative.Mul(alpha.Sub(c)).Sub(isNotPadding(row[opStack+opStackIB1ShrinkStack]))
+		}},
+		{"ram", auxRAMLogDeriv, 2, func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+			alpha, c := compressRow(row, challengeRAMIndeterminate, numRAMWeights,
+				row[ram+ramClk], row[ram+ramInstructionType], row[ram+ramPointer], row[ram+ramValue])
+			return logDerivative.Mul(alpha.Sub(c)).Sub(isNotPadding(row[ram+ramInstructionType]))
+		}},
+		{"hash", auxHashLogDeriv, 3, func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+			weights := rowTupleChallenges(row, challenge, challengeHashIndeterminate, numHashWeights)
+			fixed, sponge := hashTableTuples(row[hash+hashInput:hash+hashInput+PoseidonStateSize],
+				row[hash+hashState:hash+hashState+PoseidonStateSize])
+			fixedDenominator := weights.indeterminate.Sub(weights.compress(fixed))
+			spongeDenominator := weights.indeterminate.Sub(weights.compress(sponge))
+			served := row[hash+hashFixedLengthMultiplicity].Mul(spongeDenominator).
+				Add(row[hash+hashSpongeMultiplicity].Mul(fixedDenominator))
+			return logDerivative.Mul(fixedDenominator).Mul(spongeDenominator).Sub(served)
+		}},
+		{"u32", auxU32LogDeriv, 2, func(row []xfield.XFieldElement, logDerivative xfield.XFieldElement) xfield.XFieldElement {
+			alpha, c := compressRow(row, challengeU32Indeterminate, numU32Weights,
+				row[u32+u32CI], row[u32+u32LHS], row[u32+u32RHS], row[u32+u32Result])
+			return logDerivative.Mul(alpha.Sub(c)).Sub(row[u32+u32LookupMultiplicity])
+		}},
+	}
+	for _, server := range servers {
+		server := server
+		air.AddInitialConstraint(server.name+"_log_deriv_starts_with_first_row", server.degree,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return server.term(row, row[aux(server.col)])
+			})
+		air.AddTransitionConstraint(server.name+"_log_deriv_accumulates_row", server.degree,
+			func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return server.term(next, next[aux(server.col)].Sub(current[aux(server.col)]))
+			})
+	}
+	processorLinkConstraints(air, processor)
+
+	// Program → Hash lookup of the permutations absorbing the program
+	//
+	// ld[0] = 0,  (ld' - ld)·(β - c) = a
+	//
+	// with a the 1 of a row that absorbs a chunk and c its tuple, see
+	// programHashTuple. The hash link's terminal constraint adds ld to the
+	// Processor Table's side.
+	air.AddInitialConstraint("program_hash_lookup_starts_at_0", 1,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[aux(auxProgramHashLookup)]
+		})
+	air.AddTransitionConstraint("program_hash_lookup_accumulates_absorbing_rows", 3,
+		func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			weights := rowTupleChallenges(current, challenge, challengeHashIndeterminate, numHashWeights)
+			c := weights.compress(programHashTuple(current[program:], next[program:]))
+			diff := next[aux(auxProgramHashLookup)].Sub(current[aux(auxProgramHashLookup)])
+			return diff.Mul(weights.indeterminate.Sub(c)).Sub(programAbsorbs(current[program:]))
+		})
+
+	// The claim, through the claim challenges
+	//
+	// st_j[0] = digest_j in the Processor Table, sponge_j = digest_j in the
+	// Program Table's last row, and for the input and output evaluations
+	//
+	// e[0] = 1,  e' = e + sel·Σ_n arg_n·(e·(x^n - 1) + Σ_{j<n} st_j·x^j)
+	//
+	// with sel the selector of read_io, reading st' of the next row, or of
+	// write_io, writing st of the current row; e ends at the claimed terminal
+	claim := func(row []xfield.XFieldElement, k int) xfield.XFieldElement {
+		return row[challenge(numCrossTableChallenges+k)]
+	}
+	for j := 0; j < PoseidonDigestLen; j++ {
+		j := j
+		air.AddInitialConstraint(fmt.Sprintf("processor_st%d_starts_with_program_digest", j), 1,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[processor+processorST0+j].Sub(claim(row, claimProgramDigest0+j))
+			})
+		air.AddTerminalConstraint(fmt.Sprintf("program_sponge%d_ends_with_program_digest", j), 1,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[program+programSponge0+j].Sub(claim(row, claimProgramDigest0+j))
+			})
+	}
+	ioArguments := []struct {
+		name          string
+		col           int
+		inst          Instruction
+		indeterminate int
+		terminal      int
+		words         func(current, next []xfield.XFieldElement) []xfield.XFieldElement
+	}{
+		{"input", auxProcessorInputEval, ReadIo, challengeInputIndeterminate, claimInputTerminal,
+			func(_, next []xfield.XFieldElement) []xfield.XFieldElement { return next[processor:] }},
+		{"output", auxProcessorOutputEval, WriteIo, challengeOutputIndeterminate, claimOutputTerminal,
+			func(current, _ []xfield.XFieldElement) []xfield.XFieldElement { return current[processor:] }},
+	}
+	for _, io := range ioArguments {
+		io := io
+		air.AddInitialConstraint(fmt.Sprintf("processor_%s_eval_starts_at_1", io.name), 1,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[aux(io.col)].Sub(xfield.One)
+			})
+		air.AddTransitionConstraint(fmt.Sprintf("processor_%s_eval_accumulates_%s", io.name, io.inst), 3,
+			func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				x, e := current[challenge(io.indeterminate)], current[aux(io.col)]
+				words := io.words(current, next)
+				update, sum, power := xfield.Zero, xfield.Zero, xfield.One
+				for n := 1; n <= 5; n++ {
+					sum = sum.Add(stackRegister(words, n-1).Mul(power))
+					power = power.Mul(x)
+					term := e.Mul(power.Sub(xfield.One)).Add(sum)
+					update = update.Add(current[processor+processorArgumentSelector0+n].Mul(term))
+				}
+				diff := next[aux(io.col)].Sub(e)
+				return diff.Sub(current[processor+processorSelector(io.inst)].Mul(update))
+			})
+		air.AddTerminalConstraint(fmt.Sprintf("processor_%s_eval_matches_claim", io.name), 1,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[aux(io.col)].Sub(claim(row, io.terminal))
+			})
+	}
+
+	// Clock jump differences of the Op Stack and RAM tables, looked up in
+	// the Processor Table's clock like the Jump Stack's
+	//
+	// ld[0] = 0,  (ld' - ld)·(γ - (clk' - clk)) = samePointer·notPad'
+	clockJumps := []struct {
+		name           string
+		col            int
+		degree         int
+		clk, indicator int
+		samePointer    func(current, next []xfield.XFieldElement) xfield.XFieldElement
+	}{
+		{"op_stack", auxOpStackClockJumpDiffLog, 3, opStack + opStackClk, opStack + opStackIB1ShrinkStack,
+			func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return xfield.One.Sub(next[opStack+opStackPointer].Sub(current[opStack+opStackPointer]))
+			}},
+		{"ram", auxRAMClockJumpDiffLog, 4, ram + ramClk, ram + ramInstructionType,
+			func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				_, unchanged := ramPointerUnchanged(current[ram:], next[ram:])
+				return unchanged
+			}},
+	}
+	for _, clockJump := range clockJumps {
+		clockJump := clockJump
+		air.AddInitialConstraint(clockJump.name+"_clock_jump_diff_log_starts_at_0", 1,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[aux(clockJump.col)]
+			})
+		air.AddTransitionConstraint(clockJump.name+"_clock_jump_diff_log_accumulates_same_pointer", clockJump.degree,
+			func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				gamma := next[challenge(challengeClockJumpDifferenceIndeterminate)]
+				clockDiff := next[clockJump.clk].Sub(current[clockJump.clk])
+				counted := clockJump.samePointer(current, next).Mul(isNotPadding(next[clockJump.indicator]))
+				diff := next[aux(clockJump.col)].Sub(current[aux(clockJump.col)])
+				return diff.Mul(gamma.Sub(clockDiff)).Sub(counted)
+			})
+	}
+
+	// Contiguity of the RAM Table's regions
+	//
+	// rp[0] = x - ptr[0], fd[0] = 1, bc0[0] = 0, bc1[0] = bcp1[0]
+	//
+	// When the pointer changes, rp' = rp·(x - ptr'), fd' = rp + (x - ptr')·fd
+	// and bc' = x·bc + bcp' for both coefficients; otherwise they stay the
+	// same. bc0·rp + bc1·fd = 1 in the last row proves that rp has no
+	// repeated root, so no pointer starts two regions.
+	bezout := func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return row[challenge(challengeRAMBezoutIndeterminate)]
+	}
+	air.AddInitialConstraint("ram_running_product_starts_with_first_pointer", 1,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[aux(auxRAMRunningProduct)].Sub(bezout(row).Sub(row[ram+ramPointer]))
+		})
+	air.AddInitialConstraint("ram_formal_derivative_starts_at_1", 1,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[aux(auxRAMFormalDerivative)].Sub(xfield.One)
+		})
+	air.AddInitialConstraint("ram_bezout_coefficient_0_starts_at_0", 1,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[aux(auxRAMBezoutCoeff0)]
+		})
+	air.AddInitialConstraint("ram_bezout_coefficient_1_starts_with_polynomial", 1,
+		func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[aux(auxRAMBezoutCoeff1)].Sub(row[ram+ramBezoutCoeffPoly1])
+		})
+	contiguity := []struct {
+		name    string
+		col     int
+		changed func(current, next []xfield.XFieldElement) xfield.XFieldElement
+	}{
+		{"ram_running_product", auxRAMRunningProduct, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			factor := bezout(next).Sub(next[ram+ramPointer])
+			return current[aux(auxRAMRunningProduct)].Mul(factor)
+		}},
+		{"ram_formal_derivative", auxRAMFormalDerivative, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			factor := bezout(next).Sub(next[ram+ramPointer])
+			return current[aux(auxRAMRunningProduct)].Add(factor.Mul(current[aux(auxRAMFormalDerivative)]))
+		}},
+		{"ram_bezout_coefficient_0", auxRAMBezoutCoeff0, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return bezout(next).Mul(current[aux(auxRAMBezoutCoeff0)]).Add(next[ram+ramBezoutCoeffPoly0])
+		}},
+		{"ram_bezout_coefficient_1", auxRAMBezoutCoeff1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return bezout(next).Mul(current[aux(auxRAMBezoutCoeff1)]).Add(next[ram+ramBezoutCoeffPoly1])
+		}},
+	}
+	for _, column := range contiguity {
+		column := column
+		air.AddTransitionConstraint(column.name+"_changes_with_pointer", 3,
+			func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				diff, unchanged := ramPointerUnchanged(current[ram:], next[ram:])
+				changes := diff.Mul(next[aux(column.col)].Sub(column.changed(current, next)))
+				stays := unchanged.Mul(next[aux(column.col)].Sub(current[aux(column.col)]))
+				return changes.Add(stays)
+			})
+	}
+	air.AddTerminalConstraint("ram_bezout_relation", 2, func(row []xfield.XFieldElement) xfield.XFieldElement {
+		relation := row[aux(auxRAMBezoutCoeff0)].Mul(row[aux(auxRAMRunningProduct)]).
+			Add(row[aux(auxRAMBezoutCoeff1)].Mul(row[aux(auxRAMFormalDerivative)]))
+		return relation.Sub(xfield.One)
+	})
+}
+
+// isNotPadding returns 1 for an Op Stack ib1 or RAM instruction type of 0
+// or 1 and 0 for the padding value 2: (2 - x)·(x + 1)/2. It has degree 2.
+func isNotPadding(x xfield.XFieldElement) xfield.XFieldElement {
+	two := field.New(2)
+	return x.Neg().AddConst(two).Mul(x.AddConst(field.One)).MulConst(two.Inverse())
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
@@ -30,8 +31,19 @@ type HashTableImpl struct {
 	isFullRound    []field.Element // Boolean: is this a full round?
 	isPartialRound []field.Element // Boolean: is this a partial round?
 
+	// S-box helper columns: the cube of every state element after the round
+	// constants are added, so that x^7 = cube^2 * x has degree 4
+	sboxCubes [PoseidonStateSize][]field.Element
+
+	// The input of the row's permutation, repeated in all its rows, and how
+	// often the Processor Table looks the permutation up as a fixed-length
+	// hash or as a sponge instruction, set in its output row
+	inputs                  [PoseidonStateSize][]field.Element
+	fixedLengthMultiplicity []field.Element
+	spongeMultiplicity      []field.Element
+
 	// Auxiliary columns (XField elements for cross-table arguments)
-	hashEvalArg []field.Element // Evaluation argument for hash input/output
+	lookupLogDeriv []xfield.XFieldElement // Log derivative of the Processor Table's lookups
 
 	height       int
 	paddedHeight int
@@ -63,7 +75,9 @@ func NewHashTable(poseidonWidth, numRounds int) *HashTableImpl {
 		roundNumber:    make([]field.Element, 0),
 		isFullRound:    make([]field.Element, 0),
 		isPartialRound: make([]field.Element, 0),
-		hashEvalArg:    make([]field.Element, 0),
+		sboxCubes:      [PoseidonStateSize][]field.Element{},
+		inputs:         [PoseidonStateSize][]field.Element{},
+		lookupLogDeriv: make([]xfield.XFieldElement, 0),
 		height:         0,
 		paddedHeight:   0,
 		poseidonWidth:  poseidonWidth,
@@ -88,19 +102,66 @@ func (ht *HashTableImpl) GetPaddedHeight() int {
 
 // GetMainColumns returns all main columns
 func (ht *HashTableImpl) GetMainColumns() [][]field.Element {
-	return [][]field.Element{
+	columns := [][]field.Element{
 		ht.state0, ht.state1, ht.state2, ht.state3,
 		ht.state4, ht.state5, ht.state6, ht.state7,
 		ht.state8, ht.state9, ht.state10, ht.state11,
 		ht.state12, ht.state13, ht.state14, ht.state15,
 		ht.roundNumber, ht.isFullRound, ht.isPartialRound,
 	}
+	columns = append(columns, ht.sboxCubes[:]...)
+	columns = append(columns, ht.inputs[:]...)
+	return append(columns, ht.fixedLengthMultiplicity, ht.spongeMultiplicity)
+}
+
+// GetPeriodicColumns returns the columns that repeat with every permutation:
+// the round constants of every state element, the full and partial round
+// selectors, the round number and the selector of the first round
+//
+// Every permutation occupies PoseidonTraceLength rows starting at a multiple
+// of PoseidonTraceLength, so row i is in round i mod PoseidonTraceLength. The
+// last row of each permutation holds the output and has no round constants.
+func (ht *HashTableImpl) GetPeriodicColumns() []*protocols.PeriodicColumn {
+	columns := make([]*protocols.PeriodicColumn, 0, PoseidonStateSize+4)
+	for i := 0; i < PoseidonStateSize; i++ {
+		values := make([]field.Element, PoseidonTraceLength)
+		for round := range values {
+			values[round] = poseidonRoundConstant(round, i)
+		}
+		columns = append(columns, &protocols.PeriodicColumn{
+			Name:   fmt.Sprintf("hash_round_constant_%d", i),
+			Values: values,
+		})
+	}
+
+	isFullRound := make([]field.Element, PoseidonTraceLength)
+	isPartialRound := make([]field.Element, PoseidonTraceLength)
+	roundNumber := make([]field.Element, PoseidonTraceLength)
+	isFirstRound := make([]field.Element, PoseidonTraceLength)
+	isFirstRound[0] = field.One
+	for round := 0; round < PoseidonTraceLength; round++ {
+		if round < PoseidonNumRounds {
+			if isPoseidonFullRound(round) {
+				isFullRound[round] = field.One
+			} else {
+				isPartialRound[round] = field.One
+			}
+		}
+		roundNumber[round] = field.New(uint64(round))
+	}
+
+	return append(columns,
+		&protocols.PeriodicColumn{Name: "hash_is_full_round", Values: isFullRound},
+		&protocols.PeriodicColumn{Name: "hash_is_partial_round", Values: isPartialRound},
+		&protocols.PeriodicColumn{Name: "hash_round_number", Values: roundNumber},
+		&protocols.PeriodicColumn{Name: "hash_is_first_round", Values: isFirstRound},
+	)
 }
 
 // GetAuxiliaryColumns returns auxiliary columns
-func (ht *HashTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
-		ht.hashEvalArg,
+func (ht *HashTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{
+		ht.lookupLogDeriv,
 	}
 }
 
@@ -137,58 +198,131 @@ func (ht *HashTableImpl) AddRow(entry *HashEntry) error {
 	ht.isFullRound = append(ht.isFullRound, entry.IsFullRound)
 	ht.isPartialRound = append(ht.isPartialRound, entry.IsPartialRound)
 
+	// Add S-box helper columns
+	round := int(entry.RoundNumber.Value())
+	for i := 0; i < PoseidonStateSize; i++ {
+		x := entry.State[i].Add(poseidonRoundConstant(round, i))
+		ht.sboxCubes[i] = append(ht.sboxCubes[i], x.Square().Mul(x))
+	}
+
+	// Every row of a permutation repeats the state of its first row
+	for i := 0; i < PoseidonStateSize; i++ {
+		input := entry.State[i]
+		if round != 0 {
+			input = ht.inputs[i][ht.height-1]
+		}
+		ht.inputs[i] = append(ht.inputs[i], input)
+	}
+	ht.fixedLengthMultiplicity = append(ht.fixedLengthMultiplicity, field.Zero)
+	ht.spongeMultiplicity = append(ht.spongeMultiplicity, field.Zero)
+
 	// Initialize auxiliary columns (computed during proving)
-	ht.hashEvalArg = append(ht.hashEvalArg, field.Zero)
+	ht.lookupLogDeriv = append(ht.lookupLogDeriv, xfield.Zero)
 
 	ht.height++
 	return nil
 }
 
 // Pad pads the table to the target height with padding rows
+//
+// Padding rows are permutations of the all-zero state, so the round
+// constraints hold on them. Unlike the other tables, an empty hash table is
+// padded too, and both heights must be multiples of PoseidonTraceLength.
 func (ht *HashTableImpl) Pad(targetHeight int) error {
 	if targetHeight < ht.height {
 		return fmt.Errorf("target height %d is less than current height %d", targetHeight, ht.height)
 	}
 
-	if ht.height == 0 {
-		return fmt.Errorf("cannot pad empty table")
+	if ht.height%PoseidonTraceLength != 0 || targetHeight%PoseidonTraceLength != 0 {
+		return fmt.Errorf("hash table heights %d and %d must be multiples of %d",
+			ht.height, targetHeight, PoseidonTraceLength)
 	}
 
-	// Use last row values for padding
-	lastIdx := ht.height - 1
-	paddingRows := targetHeight - ht.height
-
-	for i := 0; i < paddingRows; i++ {
-		ht.state0 = append(ht.state0, ht.state0[lastIdx])
-		ht.state1 = append(ht.state1, ht.state1[lastIdx])
-		ht.state2 = append(ht.state2, ht.state2[lastIdx])
-		ht.state3 = append(ht.state3, ht.state3[lastIdx])
-		ht.state4 = append(ht.state4, ht.state4[lastIdx])
-		ht.state5 = append(ht.state5, ht.state5[lastIdx])
-		ht.state6 = append(ht.state6, ht.state6[lastIdx])
-		ht.state7 = append(ht.state7, ht.state7[lastIdx])
-		ht.state8 = append(ht.state8, ht.state8[lastIdx])
-		ht.state9 = append(ht.state9, ht.state9[lastIdx])
-		ht.state10 = append(ht.state10, ht.state10[lastIdx])
-		ht.state11 = append(ht.state11, ht.state11[lastIdx])
-		ht.state12 = append(ht.state12, ht.state12[lastIdx])
-		ht.state13 = append(ht.state13, ht.state13[lastIdx])
-		ht.state14 = append(ht.state14, ht.state14[lastIdx])
-		ht.state15 = append(ht.state15, ht.state15[lastIdx])
-		ht.roundNumber = append(ht.roundNumber, ht.roundNumber[lastIdx])
-		ht.isFullRound = append(ht.isFullRound, ht.isFullRound[lastIdx])
-		ht.isPartialRound = append(ht.isPartialRound, ht.isPartialRound[lastIdx])
-		ht.hashEvalArg = append(ht.hashEvalArg, ht.hashEvalArg[lastIdx])
+	var zeroState [PoseidonStateSize]field.Element
+	padding := poseidonPermutationTrace(zeroState)
+	for ht.height < targetHeight {
+		for round, state := range padding {
+			entry, err := NewHashEntry(
+				append([]field.Element{}, state[:]...),
+				field.New(uint64(round)),
+				round < PoseidonNumRounds && isPoseidonFullRound(round),
+				round < PoseidonNumRounds && !isPoseidonFullRound(round),
+			)
+			if err != nil {
+				return err
+			}
+			if err := ht.AddRow(entry); err != nil {
+				return err
+			}
+		}
 	}
 
 	ht.paddedHeight = targetHeight
 	return nil
 }
 
-// CreateInitialConstraints generates constraints for the first row
-func (ht *HashTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
+// Hash Table column indices in the table's local row: the main columns in
+// GetMainColumns order followed by the GetPeriodicColumns
+const (
+	hashState                   = 0
+	hashRoundNumber             = hashState + PoseidonStateSize
+	hashIsFullRound             = hashRoundNumber + 1
+	hashIsPartialRound          = hashIsFullRound + 1
+	hashSboxCube                = hashIsPartialRound + 1
+	hashInput                   = hashSboxCube + PoseidonStateSize
+	hashFixedLengthMultiplicity = hashInput + PoseidonStateSize
+	hashSpongeMultiplicity      = hashFixedLengthMultiplicity + 1
+	hashNumMainColumns          = hashSpongeMultiplicity + 1
+
+	hashRoundConstant          = hashNumMainColumns
+	hashPeriodicIsFullRound    = hashRoundConstant + PoseidonStateSize
+	hashPeriodicIsPartialRound = hashPeriodicIsFullRound + 1
+	hashPeriodicRoundNumber    = hashPeriodicIsPartialRound + 1
+	hashPeriodicIsFirstRound   = hashPeriodicRoundNumber + 1
+)
 
+// addPermutation adds the rows of the permutation of state and counts one
+// lookup of it, as a sponge instruction or as a fixed-length hash
+func (ht *HashTableImpl) addPermutation(state [PoseidonStateSize]field.Element, sponge bool) error {
+	for round, roundState := range poseidonPermutationTrace(state) {
+		isFullRound, isPartialRound := false, false
+		if round < PoseidonNumRounds {
+			isFullRound = isPoseidonFullRound(round)
+			isPartialRound = !isFullRound
+		}
+		entry, err := NewHashEntry(
+			append([]field.Element{}, roundState[:]...),
+			field.New(uint64(round)),
+			isFullRound,
+			isPartialRound,
+		)
+		if err != nil {
+			return err
+		}
+		if err := ht.AddRow(entry); err != nil {
+			return err
+		}
+	}
+	return ht.lookUpLastPermutation(sponge)
+}
+
+// lookUpLastPermutation counts one lookup of the last permutation added to
+// the table, as a sponge instruction or as a fixed-length hash
+func (ht *HashTableImpl) lookUpLastPermutation(sponge bool) error {
+	if ht.height == 0 || ht.height%PoseidonTraceLength != 0 {
+		return fmt.Errorf("hash table of height %d does not end with a permutation", ht.height)
+	}
+	multiplicities := ht.fixedLengthMultiplicity
+	if sponge {
+		multiplicities = ht.spongeMultiplicity
+	}
+	last := ht.height - 1
+	multiplicities[last] = multiplicities[last].Add(field.One)
+	return nil
+}
+
+// CreateInitialConstraints generates constraints for the first row
+func (ht *HashTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Initial constraints for Hash Table:
 	//
 	// 1. Round number starts at zero:
@@ -197,115 +331,248 @@ func (ht *HashTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint,
 	// 2. First round must be a full round:
 	//    isFullRound[0] = 1, isPartialRound[0] = 0
 	//
-	// 3. Hash evaluation argument initialized:
-	//    hashEvalArg[0] = default_initial
+	// Both follow from the consistency constraints with the periodic columns.
 	//
-	// Note: Initial state values are set by the hash operation being proved.
-	// Actual polynomial representations computed during proof generation.
-
-	return constraints, nil
+	// Note: Initial state values are set by the hash operation being proved,
+	// and bound by the Processor Table's lookups.
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
 // CreateConsistencyConstraints generates constraints within each row
-func (ht *HashTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (ht *HashTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Consistency constraints for Hash Table:
 	//
-	// 1. isFullRound is boolean (0 or 1):
-	//    isFullRound * (isFullRound - 1) = 0
+	// 1. Every S-box helper is the cube of its state element plus the round
+	//    constant:
+	//    sboxCube_i = (state_i + roundConstant_i)^3
 	//
-	// 2. isPartialRound is boolean (0 or 1):
-	//    isPartialRound * (isPartialRound - 1) = 0
+	// 2. The round number and round type columns match the periodic columns:
+	//    roundNumber = periodicRoundNumber
+	//    isFullRound = periodicIsFullRound
+	//    isPartialRound = periodicIsPartialRound
 	//
-	// 3. Exactly one of isFullRound or isPartialRound is true:
-	//    isFullRound + isPartialRound = 1
+	// The periodic selectors are 0 or 1 with at most one of them set, so 2
+	// also makes the round type columns boolean and exclusive.
 	//
-	// 4. Round number is within valid range:
-	//    0 <= roundNumber < numRounds
-	//    This is enforced via range check lookups
+	// 3. The first row of a permutation holds its input:
+	//    isFirstRound * (input_i - state_i) = 0
 	//
-	// Note: Actual polynomial representations computed during proof generation.
-
+	// 4. Only the output row of a permutation is looked up:
+	//    (isFullRound + isPartialRound) * multiplicity = 0
+	//
+	// 5. Fixed-length hashes have a capacity of ones:
+	//    fixedLengthMultiplicity * (input_i - 1) = 0 for i >= rate
+	constraints := make([]*protocols.ConstraintPolynomial, 0, 3*PoseidonStateSize+5)
+	for i := 0; i < PoseidonStateSize; i++ {
+		i := i
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("hash_sbox_cube_%d", i),
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				x := row[hashState+i].Add(row[hashRoundConstant+i])
+				return row[hashSboxCube+i].Sub(x.Mul(x).Mul(x))
+			},
+		})
+	}
+	for _, c := range []struct {
+		name             string
+		column, periodic int
+	}{
+		{"hash_round_number_is_periodic", hashRoundNumber, hashPeriodicRoundNumber},
+		{"hash_is_full_round_is_periodic", hashIsFullRound, hashPeriodicIsFullRound},
+		{"hash_is_partial_round_is_periodic", hashIsPartialRound, hashPeriodicIsPartialRound},
+	} {
+		column, periodic := c.column, c.periodic
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   c.name,
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[column].Sub(row[periodic])
+			},
+		})
+	}
+	for i := 0; i < PoseidonStateSize; i++ {
+		i := i
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("hash_first_round_holds_input_%d", i),
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[hashPeriodicIsFirstRound].Mul(row[hashInput+i].Sub(row[hashState+i]))
+			},
+		})
+	}
+	for _, c := range []struct {
+		name   string
+		column int
+	}{
+		{"hash_fixed_length_lookups_are_in_output_rows", hashFixedLengthMultiplicity},
+		{"hash_sponge_lookups_are_in_output_rows", hashSpongeMultiplicity},
+	} {
+		column := c.column
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   c.name,
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[hashIsFullRound].Add(row[hashIsPartialRound]).Mul(row[column])
+			},
+		})
+	}
+	for i := PoseidonRate; i < PoseidonStateSize; i++ {
+		i := i
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("hash_fixed_length_capacity_%d_is_1", i),
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[hashFixedLengthMultiplicity].Mul(row[hashInput+i].Sub(xfield.One))
+			},
+		})
+	}
 	return constraints, nil
 }
 
 // CreateTransitionConstraints generates constraints between consecutive rows
-func (ht *HashTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (ht *HashTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
 	// Transition constraints for Hash Table (Poseidon permutation):
 	//
-	// 1. Round number increments or resets:
-	//    If roundNumber < numRounds-1:
-	//      roundNumber' = roundNumber + 1
-	//    If roundNumber = numRounds-1:
-	//      roundNumber' = 0 (start new hash)
-	//
-	// 2. Round type transitions correctly:
-	//    Based on Poseidon structure (RF_full rounds at start/end, RP_partial in middle)
-	//
-	// 3. State transitions follow Poseidon round function:
+	// 1. State transitions follow Poseidon round function:
 	//    For full rounds (all state elements):
 	//      - Add round constants
-	//      - Apply S-box (x^α, typically α=5)
+	//      - Apply S-box x^7 = sboxCube^2 * x
 	//      - Apply MDS matrix
 	//    For partial rounds (only first element):
 	//      - Add round constants
 	//      - Apply S-box to state[0] only
 	//      - Apply MDS matrix
 	//
-	// 4. Hash evaluation argument updates:
-	//    When starting new hash (roundNumber = 0):
-	//      hashEvalArg' = hashEvalArg * indeterminate + compressed_input
-	//    When finishing hash (roundNumber' = 0 and roundNumber = numRounds-1):
-	//      hashEvalArg' = hashEvalArg * indeterminate + compressed_output
-	//    Otherwise:
-	//      hashEvalArg' = hashEvalArg
+	//    (isFullRound + isPartialRound) * state_j' = Σ_i MDS_ji * sbox_i
+	//    where x_i = state_i + roundConstant_i and
+	//      sbox_i = isFullRound * sboxCube_i^2 * x_i
+	//             + isPartialRound * (i == 0 ? sboxCube_0^2 * x_0 : x_i)
+	//
+	//    Both selectors are 0 in the output row of a permutation, so the
+	//    next permutation's input is unconstrained.
+	//
+	// 2. The input is repeated within a permutation:
+	//    (isFullRound + isPartialRound) * (input_i' - input_i) = 0
 	//
-	// Note: Poseidon constraints are complex and depend on round constants and MDS matrix.
-	// For production, these would be computed from our enhanced Poseidon implementation.
-	// Actual polynomial representations computed during proof generation with proper parameters.
+	// The output row thus holds the permutation's input and output, which
+	// the Processor Table looks up (see addCrossTableConstraints).
 	//
 	// Innovation: We use Poseidon instead of Tip5, providing:
 	// - Field-friendly operations
 	// - Multi-level security (128/256-bit)
 	// - Better integration with zkSTARKs literature
+	sboxOutputs := func(row []xfield.XFieldElement) [PoseidonStateSize]xfield.XFieldElement {
+		isFull, isPartial := row[hashIsFullRound], row[hashIsPartialRound]
+		var sbox [PoseidonStateSize]xfield.XFieldElement
+		for i := 0; i < PoseidonStateSize; i++ {
+			x := row[hashState+i].Add(row[hashRoundConstant+i])
+			full := row[hashSboxCube+i].Mul(row[hashSboxCube+i]).Mul(x)
+			partial := x
+			if i == 0 {
+				partial = full
+			}
+			sbox[i] = isFull.Mul(full).Add(isPartial.Mul(partial))
+		}
+		return sbox
+	}
 
+	constraints := make([]*protocols.TransitionConstraintPolynomial, 0, 2*PoseidonStateSize)
+	for j := 0; j < PoseidonStateSize; j++ {
+		j := j
+		constraints = append(constraints, &protocols.TransitionConstraintPolynomial{
+			Name:   fmt.Sprintf("hash_round_state_%d", j),
+			Degree: 4,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				sbox := sboxOutputs(current)
+				mixed := xfield.Zero
+				for i := 0; i < PoseidonStateSize; i++ {
+					mixed = mixed.Add(sbox[i].MulConst(poseidonMDS(j, i)))
+				}
+				isRound := current[hashIsFullRound].Add(current[hashIsPartialRound])
+				return isRound.Mul(next[hashState+j]).Sub(mixed)
+			},
+		})
+	}
+	for i := 0; i < PoseidonStateSize; i++ {
+		i := i
+		constraints = append(constraints, &protocols.TransitionConstraintPolynomial{
+			Name:   fmt.Sprintf("hash_input_%d_is_repeated", i),
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				isRound := current[hashIsFullRound].Add(current[hashIsPartialRound])
+				return isRound.Mul(next[hashInput+i].Sub(current[hashInput+i]))
+			},
+		})
+	}
 	return constraints, nil
 }
 
 // CreateTerminalConstraints generates constraints for the last row
-func (ht *HashTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (ht *HashTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Terminal constraints for Hash Table:
 	//
-	// The final hash evaluation argument must match the evaluation argument
-	// from the Processor table (for hash operations).
-	//
-	// This is verified via cross-table evaluation arguments.
+	// The log derivative of the permutations the Processor Table looks up
+	// is equated with the processor's side by the cross-table arguments.
+	return []*protocols.ConstraintPolynomial{}, nil
+}
 
-	return constraints, nil
+// hashTableTuples returns the tuples of a permutation with the given input
+// and output, as a fixed-length hash and as a sponge instruction; see
+// hashTuple for the Processor Table's side
+func hashTableTuples(input, output []xfield.XFieldElement) (fixed, sponge []xfield.XFieldElement) {
+	fixed = make([]xfield.XFieldElement, numHashWeights)
+	sponge = make([]xfield.XFieldElement, numHashWeights)
+	sponge[0] = xfield.One
+	copy(fixed[1:], input[:PoseidonRate])
+	copy(fixed[1+PoseidonStateSize:], output[:PoseidonDigestLen])
+	copy(sponge[1:], input)
+	copy(sponge[1+PoseidonStateSize:], output)
+	return fixed, sponge
 }
 
-// UpdateHashEvaluationArgument updates the evaluation argument for hash operations
-// This links hash operations in the Processor table to this Hash table
-func (ht *HashTableImpl) UpdateHashEvaluationArgument(indeterminate field.Element) error {
+// UpdateLookupLogDerivative computes the log derivative of the permutations
+// the Processor Table looks up
+//
+// Every output row serves its permutation both as a fixed-length hash,
+// compressed to cf, and as a sponge instruction, compressed to cs:
+//
+//	ld[0]·(β - cf[0])·(β - cs[0]) = mf[0]·(β - cs[0]) + ms[0]·(β - cf[0])
+//
+// and the same with ld' - ld for every following row.
+func (ht *HashTableImpl) UpdateLookupLogDerivative(challenges map[string]xfield.XFieldElement) error {
 	if ht.height == 0 {
-		return fmt.Errorf("cannot update hash evaluation on empty table")
+		return fmt.Errorf("cannot update hash lookup on empty table")
+	}
+	weights, err := tupleWeights(challenges, challengeHashIndeterminate, numHashWeights)
+	if err != nil {
+		return err
 	}
 
-	// Initialize first row
-	ht.hashEvalArg[0] = field.Zero
-
-	// Track when we're at hash boundaries (roundNumber = 0 or roundNumber = numRounds-1)
-	// This is where we absorb input or emit output in the evaluation argument
-
-	for i := 1; i < ht.height; i++ {
-		// For now, carry forward the value
-		// In production, this would check for hash boundaries and update accordingly
-		ht.hashEvalArg[i] = ht.hashEvalArg[i-1]
+	columns := ht.GetMainColumns()
+	logDerivative := xfield.Zero
+	for i := 0; i < ht.height; i++ {
+		fixedMultiplicity, spongeMultiplicity := ht.fixedLengthMultiplicity[i], ht.spongeMultiplicity[i]
+		if !fixedMultiplicity.IsZero() || !spongeMultiplicity.IsZero() {
+			input := make([]xfield.XFieldElement, PoseidonStateSize)
+			output := make([]xfield.XFieldElement, PoseidonStateSize)
+			for j := range input {
+				input[j] = xfield.NewConst(columns[hashInput+j][i])
+				output[j] = xfield.NewConst(columns[hashState+j][i])
+			}
+			fixed, sponge := hashTableTuples(input, output)
+			for _, served := range []struct {
+				multiplicity field.Element
+				tuple        []xfield.XFieldElement
+			}{{fixedMultiplicity, fixed}, {spongeMultiplicity, sponge}} {
+				if served.multiplicity.IsZero() {
+					continue
+				}
+				denominator := weights.indeterminate.Sub(weights.compress(served.tuple))
+				logDerivative = logDerivative.Add(denominator.Inverse().MulConst(served.multiplicity))
+			}
+		}
+		ht.lookupLogDeriv[i] = logDerivative
 	}
 
 	return nil
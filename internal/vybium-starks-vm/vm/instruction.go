@@ -2,7 +2,9 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 )
@@ -200,78 +202,82 @@ type InstructionInfo struct {
 	Size        int  // Number of words (1 or 2)
 	StackEffect int  // Net effect on stack depth (positive = push, negative = pop)
 	HasArg      bool // Whether instruction takes an argument
+
+	// MinArg and MaxArg bound the argument the instruction's handler accepts;
+	// both are zero if any argument is accepted
+	MinArg, MaxArg uint64
 }
 
 // AllInstructions returns information about all Vybium STARKs VM instructions
 var AllInstructions = map[Instruction]InstructionInfo{
 	// Stack Manipulation
-	Pop:    {Pop, "pop", "Remove n elements from stack", 2, -1, true},
-	Push:   {Push, "push", "Push value onto stack", 2, 1, true},
-	Divine: {Divine, "divine", "Non-deterministically push n elements", 2, 1, true},
-	Pick:   {Pick, "pick", "Copy stack[i] to top", 2, 1, true},
-	Place:  {Place, "place", "Move top to stack[i]", 2, -1, true},
-	Dup:    {Dup, "dup", "Duplicate stack[i] to top", 2, 1, true},
-	Swap:   {Swap, "swap", "Swap top with stack[i]", 2, 0, true},
+	Pop:    {Pop, "pop", "Remove n elements from stack", 2, -1, true, 1, 5},
+	Push:   {Push, "push", "Push value onto stack", 2, 1, true, 0, 0},
+	Divine: {Divine, "divine", "Non-deterministically push n elements", 2, 1, true, 1, 5},
+	Pick:   {Pick, "pick", "Copy stack[i] to top", 2, 1, true, 0, 15},
+	Place:  {Place, "place", "Move top to stack[i]", 2, 0, true, 0, 15},
+	Dup:    {Dup, "dup", "Duplicate stack[i] to top", 2, 1, true, 0, 15},
+	Swap:   {Swap, "swap", "Swap top with stack[i]", 2, 0, true, 0, 15},
 
 	// Control Flow
-	Halt:            {Halt, "halt", "Terminate execution", 1, 0, false},
-	Nop:             {Nop, "nop", "No operation", 1, 0, false},
-	Skiz:            {Skiz, "skiz", "Skip if zero", 1, -1, false},
-	Call:            {Call, "call", "Call function", 2, 0, true},
-	Return:          {Return, "return", "Return from function", 1, 0, false},
-	Recurse:         {Recurse, "recurse", "Recurse into current function", 1, 0, false},
-	RecurseOrReturn: {RecurseOrReturn, "recurse_or_return", "Recurse or return based on JSP", 1, 0, false},
-	Assert:          {Assert, "assert", "Assert top is 1", 1, -1, false},
+	Halt:            {Halt, "halt", "Terminate execution", 1, 0, false, 0, 0},
+	Nop:             {Nop, "nop", "No operation", 1, 0, false, 0, 0},
+	Skiz:            {Skiz, "skiz", "Skip if zero", 1, -1, false, 0, 0},
+	Call:            {Call, "call", "Call function", 2, 0, true, 0, 0},
+	Return:          {Return, "return", "Return from function", 1, 0, false, 0, 0},
+	Recurse:         {Recurse, "recurse", "Recurse into current function", 1, 0, false, 0, 0},
+	RecurseOrReturn: {RecurseOrReturn, "recurse_or_return", "Recurse or return based on JSP", 1, 0, false, 0, 0},
+	Assert:          {Assert, "assert", "Assert top is 1", 1, -1, false, 0, 0},
 
 	// Memory Access
-	ReadMem:  {ReadMem, "read_mem", "Read n words from RAM", 2, 1, true},
-	WriteMem: {WriteMem, "write_mem", "Write n words to RAM", 2, -2, true},
+	ReadMem:  {ReadMem, "read_mem", "Read n words from RAM", 2, 1, true, 1, 5},
+	WriteMem: {WriteMem, "write_mem", "Write n words to RAM", 2, -2, true, 1, 5},
 
 	// Hashing (Poseidon-optimized)
-	Hash:            {Hash, "hash", "Poseidon hash of stack[0..10]", 1, -5, false},
-	AssertVector:    {AssertVector, "assert_vector", "Assert vector equality", 1, -10, false},
-	SpongeInit:      {SpongeInit, "sponge_init", "Initialize Poseidon sponge", 1, 0, false},
-	SpongeAbsorb:    {SpongeAbsorb, "sponge_absorb", "Absorb into sponge", 1, -10, false},
-	SpongeAbsorbMem: {SpongeAbsorbMem, "sponge_absorb_mem", "Absorb from RAM", 1, 0, false},
-	SpongeSqueeze:   {SpongeSqueeze, "sponge_squeeze", "Squeeze from sponge", 1, 10, false},
+	Hash:            {Hash, "hash", "Poseidon hash of stack[0..10]", 1, -5, false, 0, 0},
+	AssertVector:    {AssertVector, "assert_vector", "Assert vector equality", 1, -10, false, 0, 0},
+	SpongeInit:      {SpongeInit, "sponge_init", "Initialize Poseidon sponge", 1, 0, false, 0, 0},
+	SpongeAbsorb:    {SpongeAbsorb, "sponge_absorb", "Absorb into sponge", 1, -10, false, 0, 0},
+	SpongeAbsorbMem: {SpongeAbsorbMem, "sponge_absorb_mem", "Absorb from RAM", 1, 0, false, 0, 0},
+	SpongeSqueeze:   {SpongeSqueeze, "sponge_squeeze", "Squeeze from sponge", 1, 10, false, 0, 0},
 
 	// Base Field Arithmetic
-	Add:    {Add, "add", "Add top two elements", 1, -1, false},
-	AddI:   {AddI, "addi", "Add immediate", 2, 0, true},
-	Mul:    {Mul, "mul", "Multiply top two elements", 1, -1, false},
-	Invert: {Invert, "invert", "Multiplicative inverse", 1, 0, false},
-	Eq:     {Eq, "eq", "Check equality", 1, -1, false},
+	Add:    {Add, "add", "Add top two elements", 1, -1, false, 0, 0},
+	AddI:   {AddI, "addi", "Add immediate", 2, 0, true, 0, 0},
+	Mul:    {Mul, "mul", "Multiply top two elements", 1, -1, false, 0, 0},
+	Invert: {Invert, "invert", "Multiplicative inverse", 1, 0, false, 0, 0},
+	Eq:     {Eq, "eq", "Check equality", 1, -1, false, 0, 0},
 
 	// Bitwise Arithmetic (U32 coprocessor)
-	Split:     {Split, "split", "Split into high/low 32-bit", 1, 1, false},
-	Lt:        {Lt, "lt", "Less than (unsigned)", 1, -1, false},
-	And:       {And, "and", "Bitwise AND", 1, -1, false},
-	Xor:       {Xor, "xor", "Bitwise XOR", 1, -1, false},
-	Log2Floor: {Log2Floor, "log_2_floor", "Floor of log2", 1, 0, false},
-	Pow:       {Pow, "pow", "Exponentiation", 1, -1, false},
-	DivMod:    {DivMod, "div_mod", "Division with remainder", 1, 0, false},
-	PopCount:  {PopCount, "pop_count", "Count 1 bits", 1, 0, false},
+	Split:     {Split, "split", "Split into high/low 32-bit", 1, 1, false, 0, 0},
+	Lt:        {Lt, "lt", "Less than (unsigned)", 1, -1, false, 0, 0},
+	And:       {And, "and", "Bitwise AND", 1, -1, false, 0, 0},
+	Xor:       {Xor, "xor", "Bitwise XOR", 1, -1, false, 0, 0},
+	Log2Floor: {Log2Floor, "log_2_floor", "Floor of log2", 1, 0, false, 0, 0},
+	Pow:       {Pow, "pow", "Exponentiation", 1, -1, false, 0, 0},
+	DivMod:    {DivMod, "div_mod", "Division with remainder", 1, 0, false, 0, 0},
+	PopCount:  {PopCount, "pop_count", "Count 1 bits", 1, 0, false, 0, 0},
 
 	// Extension Field Arithmetic
-	XxAdd:   {XxAdd, "xx_add", "Extension field addition", 1, -3, false},
-	XxMul:   {XxMul, "xx_mul", "Extension field multiplication", 1, -3, false},
-	XInvert: {XInvert, "x_invert", "Extension field inverse", 1, 0, false},
-	XbMul:   {XbMul, "xb_mul", "Base × Extension multiplication", 1, -1, false},
+	XxAdd:   {XxAdd, "xx_add", "Extension field addition", 1, -3, false, 0, 0},
+	XxMul:   {XxMul, "xx_mul", "Extension field multiplication", 1, -3, false, 0, 0},
+	XInvert: {XInvert, "x_invert", "Extension field inverse", 1, 0, false, 0, 0},
+	XbMul:   {XbMul, "xb_mul", "Base × Extension multiplication", 1, -1, false, 0, 0},
 
 	// I/O
-	ReadIo:  {ReadIo, "read_io", "Read from standard input", 2, 1, true},
-	WriteIo: {WriteIo, "write_io", "Write to standard output", 2, -1, true},
+	ReadIo:  {ReadIo, "read_io", "Read from standard input", 2, 1, true, 1, 5},
+	WriteIo: {WriteIo, "write_io", "Write to standard output", 2, -1, true, 1, 5},
 
 	// Advanced Operations
-	MerkleStep:    {MerkleStep, "merkle_step", "Merkle tree step (Poseidon)", 1, -1, false},
-	MerkleStepMem: {MerkleStepMem, "merkle_step_mem", "Merkle step from RAM", 1, 0, false},
-	XxDotStep:     {XxDotStep, "xx_dot_step", "Extension dot product step", 1, -2, false},
-	XbDotStep:     {XbDotStep, "xb_dot_step", "Base-extension dot step", 1, -1, false},
+	MerkleStep:    {MerkleStep, "merkle_step", "Merkle tree step (Poseidon)", 1, 0, false, 0, 0},
+	MerkleStepMem: {MerkleStepMem, "merkle_step_mem", "Merkle step from RAM", 1, 0, false, 0, 0},
+	XxDotStep:     {XxDotStep, "xx_dot_step", "Extension dot product step", 1, -2, false, 0, 0},
+	XbDotStep:     {XbDotStep, "xb_dot_step", "Base-extension dot step", 1, -1, false, 0, 0},
 
 	// Permutation Checks (TIP-0007)
-	PushPerm:   {PushPerm, "push_perm", "Push to permutation accumulator", 1, -5, false},
-	PopPerm:    {PopPerm, "pop_perm", "Pop from permutation accumulator", 1, -5, false},
-	AssertPerm: {AssertPerm, "assert_perm", "Assert permutation equality", 1, 0, false},
+	PushPerm:   {PushPerm, "push_perm", "Push to permutation accumulator", 1, -5, false, 0, 0},
+	PopPerm:    {PopPerm, "pop_perm", "Pop from permutation accumulator", 1, -5, false, 0, 0},
+	AssertPerm: {AssertPerm, "assert_perm", "Assert permutation equality", 1, 0, false, 0, 0},
 }
 
 // String returns the name of the instruction
@@ -291,6 +297,13 @@ func (i Instruction) Info() (InstructionInfo, error) {
 	return info, nil
 }
 
+// ArgumentRange returns the arguments the instruction's handler accepts, from
+// AllInstructions; ok is false for instructions whose argument is not checked
+func (i Instruction) ArgumentRange() (lo, hi uint64, ok bool) {
+	info := AllInstructions[i]
+	return info.MinArg, info.MaxArg, info.MaxArg != 0
+}
+
 // Size returns the number of words the instruction occupies
 func (i Instruction) Size() int {
 	info, err := i.Info()
@@ -424,6 +437,16 @@ func DecodeInstruction(words []field.Element, offset int) (*EncodedInstruction,
 type Program struct {
 	Instructions []*EncodedInstruction
 	Length       int // Total words
+
+	// decoded holds the instruction at every word address, built by
+	// InstructionAt on first use and dropped by AddInstruction
+	decoded atomic.Pointer[[]decodedInstruction]
+}
+
+// decodedInstruction is the result of decoding a program at one word address
+type decodedInstruction struct {
+	inst *EncodedInstruction
+	err  error
 }
 
 // NewProgram creates a new program
@@ -438,6 +461,32 @@ func NewProgram() *Program {
 func (p *Program) AddInstruction(inst *EncodedInstruction) {
 	p.Instructions = append(p.Instructions, inst)
 	p.Length += inst.Instruction.Size()
+	p.decoded.Store(nil)
+}
+
+// InstructionAt returns the instruction at a word address
+//
+// The result is that of DecodeInstruction on ToWords, so an address inside
+// an instruction decodes its argument as an opcode. The program is encoded
+// and decoded at every address once, on the first call after it changed
+// through AddInstruction; the returned instruction must not be modified.
+func (p *Program) InstructionAt(address int) (*EncodedInstruction, error) {
+	table := p.decoded.Load()
+	if table == nil || len(*table) != p.Length {
+		words := p.ToWords()
+		decoded := make([]decodedInstruction, len(words))
+		for i := range words {
+			decoded[i].inst, decoded[i].err = DecodeInstruction(words, i)
+		}
+		table = &decoded
+		p.decoded.Store(table)
+	}
+
+	if address < 0 || address >= len(*table) {
+		return nil, fmt.Errorf("address %d out of bounds", address)
+	}
+	entry := (*table)[address]
+	return entry.inst, entry.err
 }
 
 // ToWords converts the program to field elements for execution
@@ -450,21 +499,17 @@ func (p *Program) ToWords() []field.Element {
 }
 
 // ValidateProgram validates a program for correctness
+//
+// It rejects empty programs and those with errors found by AnalyzeProgram,
+// joining the diagnostics into one error.
 func ValidateProgram(program *Program) error {
 	if len(program.Instructions) == 0 {
 		return fmt.Errorf("empty program")
 	}
 
-	// Check that program ends with Halt
-	lastInst := program.Instructions[len(program.Instructions)-1]
-	if lastInst.Instruction != Halt {
-		return fmt.Errorf("program must end with Halt instruction")
+	var errs []error
+	for _, d := range AnalyzeProgram(program).Errors() {
+		errs = append(errs, d)
 	}
-
-	// Additional validation could include:
-	// - Jump target validation
-	// - Stack depth analysis
-	// - Call/Return matching
-
-	return nil
+	return errors.Join(errs...)
 }
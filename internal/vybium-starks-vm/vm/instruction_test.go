@@ -25,7 +25,7 @@ func TestStackManipulationInstructions(t *testing.T) {
 			t.Errorf("StackPointer = %d, want %d", vm.StackPointer, spBefore+1)
 		}
 		// The pushed value should be at StackPointer-1
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.Equal(val) {
 			t.Errorf("Push did not push correct value: got %v, want %v", stackTop, val)
 		}
@@ -71,8 +71,8 @@ func TestStackManipulationInstructions(t *testing.T) {
 		if vm.StackPointer < 2 {
 			t.Fatal("Stack pointer too small after Dup")
 		}
-		top := vm.Stack[vm.StackPointer-1]
-		second := vm.Stack[vm.StackPointer-2]
+		top := vm.Stack[0]
+		second := vm.Stack[1]
 		if !top.Equal(second) {
 			t.Error("Dup did not duplicate correctly")
 		}
@@ -97,7 +97,7 @@ func TestStackManipulationInstructions(t *testing.T) {
 		if vm.StackPointer < 2 {
 			t.Fatal("Stack pointer too small after Swap")
 		}
-		top := vm.Stack[vm.StackPointer-1]
+		top := vm.Stack[0]
 		if !top.Equal(val1) {
 			t.Error("Swap did not swap correctly")
 		}
@@ -121,7 +121,7 @@ func TestArithmeticInstructions(t *testing.T) {
 		}
 
 		expected := field.New(30)
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.Equal(expected) {
 			t.Errorf("Add result = %v, want %v", stackTop, expected)
 		}
@@ -142,7 +142,7 @@ func TestArithmeticInstructions(t *testing.T) {
 		}
 
 		expected := field.New(21)
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.Equal(expected) {
 			t.Errorf("Mul result = %v, want %v", stackTop, expected)
 		}
@@ -160,7 +160,7 @@ func TestArithmeticInstructions(t *testing.T) {
 			t.Fatalf("Run failed: %v", err)
 		}
 
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		// Verify val * inv = 1
 		result := val.Mul(stackTop)
 		if !result.IsOne() {
@@ -183,7 +183,7 @@ func TestArithmeticInstructions(t *testing.T) {
 		}
 
 		expected := field.New(8)
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.Equal(expected) {
 			t.Errorf("Pow result = %v, want %v", stackTop, expected)
 		}
@@ -205,7 +205,7 @@ func TestComparisonInstructions(t *testing.T) {
 			t.Fatalf("Run failed: %v", err)
 		}
 
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.IsOne() {
 			t.Error("Eq should return 1 for equal values")
 		}
@@ -225,7 +225,7 @@ func TestComparisonInstructions(t *testing.T) {
 			t.Fatalf("Run failed: %v", err)
 		}
 
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.IsZero() {
 			t.Error("Eq should return 0 for different values")
 		}
@@ -305,7 +305,7 @@ func TestMemoryInstructions(t *testing.T) {
 		}
 
 		// Check that we read the correct value
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.Equal(val) {
 			t.Errorf("ReadMem returned %v, want %v", stackTop, val)
 		}
@@ -326,7 +326,7 @@ func TestIOInstructions(t *testing.T) {
 			t.Fatalf("Run failed: %v", err)
 		}
 
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.Equal(publicInput[0]) {
 			t.Errorf("ReadIo returned %v, want %v", stackTop, publicInput[0])
 		}
@@ -367,7 +367,7 @@ func TestIOInstructions(t *testing.T) {
 			t.Fatalf("Run failed: %v", err)
 		}
 
-		stackTop := vm.Stack[vm.StackPointer-1]
+		stackTop := vm.Stack[0]
 		if !stackTop.Equal(secretInput[0]) {
 			t.Errorf("Divine returned %v, want %v", stackTop, secretInput[0])
 		}
@@ -394,8 +394,8 @@ func TestDivModInstruction(t *testing.T) {
 		t.Fatal("Stack pointer too small after DivMod")
 	}
 
-	remainder := vm.Stack[vm.StackPointer-1]
-	quotient := vm.Stack[vm.StackPointer-2]
+	remainder := vm.Stack[0]
+	quotient := vm.Stack[1]
 
 	// 17 / 5 = 3 remainder 2
 	expectedQuotient := field.New(3)
@@ -5,29 +5,36 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
 // JumpStackTableImpl implements the Jump Stack Table
 // This table tracks function call/return operations and ensures control flow consistency
 //
-// The jump stack records:
-// 1. CALL instructions (push return address onto jump stack)
-// 2. RETURN instructions (pop return address from jump stack)
-// 3. RECURSE_OR_RETURN instructions (conditional return)
+// Following Triton VM, the table holds the jump stack registers of every
+// processor row, sorted by jump stack pointer and then by clock. Rows of the
+// same depth then show how the frame at that depth evolves:
+// 1. CALL instructions push a frame one level deeper
+// 2. RETURN instructions (and RECURSE_OR_RETURN when it returns) end the frame
+// 3. Any other instruction leaves the frame unchanged
 //
 // Main purpose: Prove control flow correctness via permutation arguments with Processor table
 type JumpStackTableImpl struct {
 	// Main columns (BField elements)
-	clk []field.Element // Clock cycle when jump stack operation occurred
-	ci  []field.Element // Current instruction (CALL, RETURN, or RECURSE_OR_RETURN)
+	clk []field.Element // Clock cycle of the processor row
+	ci  []field.Element // Current instruction of the processor row
 	jsp []field.Element // Jump stack pointer (depth of call stack)
 	jso []field.Element // Jump stack origin (return address - where we came from)
 	jsd []field.Element // Jump stack destination (return address - where to go back)
 
+	// 1 if the processor row's instruction ends the frame, that is for
+	// RETURN and for RECURSE_OR_RETURN when it returns
+	endsFrame []field.Element
+
 	// Auxiliary columns (XField elements for cross-table arguments)
-	runningProductPerm []field.Element // Running product for permutation argument with Processor
-	clockJumpDiffLog   []field.Element // Log derivative for clock jump differences
+	runningProductPerm []xfield.XFieldElement // Running product for permutation argument with Processor
+	clockJumpDiffLog   []xfield.XFieldElement // Log derivative for clock jump differences
 
 	height       int
 	paddedHeight int
@@ -41,8 +48,9 @@ func NewJumpStackTable() *JumpStackTableImpl {
 		jsp:                make([]field.Element, 0),
 		jso:                make([]field.Element, 0),
 		jsd:                make([]field.Element, 0),
-		runningProductPerm: make([]field.Element, 0),
-		clockJumpDiffLog:   make([]field.Element, 0),
+		endsFrame:          make([]field.Element, 0),
+		runningProductPerm: make([]xfield.XFieldElement, 0),
+		clockJumpDiffLog:   make([]xfield.XFieldElement, 0),
 		height:             0,
 		paddedHeight:       0,
 	}
@@ -71,12 +79,13 @@ func (jst *JumpStackTableImpl) GetMainColumns() [][]field.Element {
 		jst.jsp,
 		jst.jso,
 		jst.jsd,
+		jst.endsFrame,
 	}
 }
 
 // GetAuxiliaryColumns returns auxiliary columns
-func (jst *JumpStackTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
+func (jst *JumpStackTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{
 		jst.runningProductPerm,
 		jst.clockJumpDiffLog,
 	}
@@ -89,10 +98,10 @@ func (jst *JumpStackTableImpl) AddRow(entry *JumpStackEntry) error {
 	}
 
 	// Validation notes:
-	// - Clock must be monotonically increasing (enforced by transition constraints)
+	// - Clock must increase within a depth (enforced by the clock jump difference lookup)
 	// - Jump stack pointer must be >= 0 (enforced by AIR constraints)
 	// - Jump stack origin and destination are return addresses
-	// - Current instruction must be valid (CALL, RETURN, or RECURSE_OR_RETURN)
+	// - The row must be one of the Processor table's (enforced by the permutation argument)
 
 	// Add main column values
 	jst.clk = append(jst.clk, entry.Clock)
@@ -100,10 +109,11 @@ func (jst *JumpStackTableImpl) AddRow(entry *JumpStackEntry) error {
 	jst.jsp = append(jst.jsp, entry.JumpStackPointer)
 	jst.jso = append(jst.jso, entry.JumpStackOrigin)
 	jst.jsd = append(jst.jsd, entry.JumpStackDestination)
+	jst.endsFrame = append(jst.endsFrame, entry.EndsFrame)
 
 	// Initialize auxiliary columns (computed during proving)
-	jst.runningProductPerm = append(jst.runningProductPerm, field.Zero)
-	jst.clockJumpDiffLog = append(jst.clockJumpDiffLog, field.Zero)
+	jst.runningProductPerm = append(jst.runningProductPerm, xfield.Zero)
+	jst.clockJumpDiffLog = append(jst.clockJumpDiffLog, xfield.Zero)
 
 	jst.height++
 	return nil
@@ -129,6 +139,7 @@ func (jst *JumpStackTableImpl) Pad(targetHeight int) error {
 		jst.jsp = append(jst.jsp, jst.jsp[lastIdx])
 		jst.jso = append(jst.jso, jst.jso[lastIdx])
 		jst.jsd = append(jst.jsd, jst.jsd[lastIdx])
+		jst.endsFrame = append(jst.endsFrame, jst.endsFrame[lastIdx])
 		jst.runningProductPerm = append(jst.runningProductPerm, jst.runningProductPerm[lastIdx])
 		jst.clockJumpDiffLog = append(jst.clockJumpDiffLog, jst.clockJumpDiffLog[lastIdx])
 	}
@@ -137,157 +148,143 @@ func (jst *JumpStackTableImpl) Pad(targetHeight int) error {
 	return nil
 }
 
-// CreateInitialConstraints generates constraints for the first row
-func (jst *JumpStackTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
+// Jump Stack Table main column indices, in GetMainColumns order
+const (
+	jumpStackClk = iota
+	jumpStackCI
+	jumpStackJSP
+	jumpStackJSO
+	jumpStackJSD
+	jumpStackEndsFrame
+)
 
+// CreateInitialConstraints generates constraints for the first row
+func (jst *JumpStackTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Initial constraints for Jump Stack Table:
 	//
-	// 1. Clock starts at zero:
-	//    clk[0] = 0
-	//
-	// 2. Jump stack pointer starts at zero (no active calls):
+	// 1. Rows are sorted by jsp and the processor starts with an empty jump
+	//    stack, so the first row has jsp = 0:
 	//    jsp[0] = 0
 	//
-	// 3. Jump stack origin starts at zero:
-	//    jso[0] = 0
-	//
-	// 4. Jump stack destination starts at zero:
-	//    jsd[0] = 0
-	//
-	// 5. Running product permutation argument initialized correctly:
-	//    Note: clk, jsp, jso, jsd are all 0, so only ci contributes to compressed row
-	//    rppa[0] = indeterminate - ci_weight * ci[0]
+	// The remaining initial constraints are on auxiliary columns and are part
+	// of the cross-table arguments (see addCrossTableConstraints):
 	//
-	// 6. Clock jump difference log derivative initialized:
-	//    clockJumpDiffLog[0] = default_initial
-	//    (A clock jump difference of 0 is not allowed, hence initial is recorded)
+	// 2. Running product permutation argument initialized correctly:
+	//    rppa[0] = indeterminate - compressed_row[0]
 	//
-	// Note: Actual polynomial representations computed during proof generation
-	// with proper Fiat-Shamir challenges.
-
-	return constraints, nil
+	// 3. Clock jump difference log derivative initialized:
+	//    clockJumpDiffLog[0] = 0
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "jump_stack_jsp_starts_at_0",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[jumpStackJSP]
+			},
+		},
+	}, nil
 }
 
 // CreateConsistencyConstraints generates constraints within each row
-func (jst *JumpStackTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (jst *JumpStackTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// No additional consistency constraints for Jump Stack Table.
 	// Instruction type validation is done via the Processor table.
-
-	return constraints, nil
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
 // CreateTransitionConstraints generates constraints between consecutive rows
-func (jst *JumpStackTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (jst *JumpStackTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
 	// Transition constraints for Jump Stack Table:
 	//
 	// 1. Jump stack pointer increments by 1 or stays the same:
 	//    (jsp' - jsp - 1) * (jsp' - jsp) = 0
 	//
-	// 2. If jsp increments by 1, ci must be able to return (RETURN or RECURSE_OR_RETURN):
-	//    (jsp' - jsp - 1) * (ci - RETURN) * (ci - RECURSE_OR_RETURN) = 0
+	// 2. Unless the current instruction ends the frame, the next row of the
+	//    same depth sees the same frame:
+	//    (jsp' - jsp - 1) * (1 - ends_frame) * (jso' - jso) = 0
 	//
-	// 3. If jsp increments or ci can return, jso stays the same:
-	//    (jsp' - jsp - 1 OR ci = RETURN OR ci = RECURSE_OR_RETURN) => jso' = jso
-	//    Constraint: (jsp' - jsp - 1) * (ci - RETURN) * (ci - RECURSE_OR_RETURN) * (jso' - jso) = 0
+	// 3. The same holds for the jump stack destination:
+	//    (jsp' - jsp - 1) * (1 - ends_frame) * (jsd' - jsd) = 0
 	//
-	// 4. If jsp increments or ci can return, jsd stays the same:
-	//    (jsp' - jsp - 1 OR ci = RETURN OR ci = RECURSE_OR_RETURN) => jsd' = jsd
-	//    Constraint: (jsp' - jsp - 1) * (ci - RETURN) * (ci - RECURSE_OR_RETURN) * (jsd' - jsd) = 0
+	// ends_frame is part of the permutation argument, so it is the value the
+	// Processor Table derives from the row's instruction and jsp.
 	//
-	// 5. If jsp increments or ci can return, and clk increments, ci must be CALL:
-	//    (jsp' - jsp - 1 OR ci = RETURN OR ci = RECURSE_OR_RETURN) AND (clk' = clk + 1) => ci = CALL
-	//    Constraint: (jsp' - jsp - 1) * (ci - RETURN) * (ci - RECURSE_OR_RETURN) * (clk' - clk - 1) * (ci - CALL) = 0
+	// The remaining transition constraints are on auxiliary columns and are
+	// part of the cross-table arguments (see addCrossTableConstraints):
 	//
-	// 6. Running product permutation argument updates correctly:
+	// 4. Running product permutation argument updates correctly:
 	//    rppa' = rppa * (indeterminate - compressed_row)
 	//    where compressed_row = clk_weight * clk' + ci_weight * ci' + jsp_weight * jsp' + jso_weight * jso' + jsd_weight * jsd'
+	//                           + ends_frame_weight * ends_frame'
 	//
-	// 7. Clock jump difference log derivative updates correctly:
+	// 5. Clock jump difference log derivative updates correctly:
 	//    If jsp increments by 1:
-	//      log_deriv' = log_deriv + 1/(indeterminate - (clk' - clk))
-	//    If jsp stays the same:
 	//      log_deriv' = log_deriv
-	//
-	//    Combined constraint:
-	//    (jsp' - jsp - 1) * [(log_deriv' - log_deriv) * (indeterminate - (clk' - clk)) - 1]
-	//    + (jsp' - jsp) * (log_deriv' - log_deriv) = 0
-	//
-	// Note: All polynomial representations computed during proof generation
-	// with proper Fiat-Shamir challenges and evaluation domains.
+	//    If jsp stays the same:
+	//      log_deriv' = log_deriv + 1/(indeterminate - (clk' - clk))
+	//    The differences are looked up in the Processor Table's clock, which
+	//    proves that rows of the same depth are sorted by clock.
 	//
 	// The jump stack table ensures that:
 	// - CALL instructions correctly push return addresses
 	// - RETURN instructions correctly pop return addresses
 	// - Control flow is consistent with the processor state
 	// - Nested function calls are tracked correctly via jsp (depth)
-
-	return constraints, nil
+	frameIsKept := func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+		jspDiff := next[jumpStackJSP].Sub(current[jumpStackJSP])
+		return jspDiff.Sub(xfield.One).Mul(xfield.One.Sub(current[jumpStackEndsFrame]))
+	}
+	return []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "jump_stack_jsp_increments_by_0_or_1",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				diff := next[jumpStackJSP].Sub(current[jumpStackJSP])
+				return diff.Sub(xfield.One).Mul(diff)
+			},
+		},
+		{
+			Name:   "jump_stack_frame_keeps_jso",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return frameIsKept(current, next).Mul(next[jumpStackJSO].Sub(current[jumpStackJSO]))
+			},
+		},
+		{
+			Name:   "jump_stack_frame_keeps_jsd",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return frameIsKept(current, next).Mul(next[jumpStackJSD].Sub(current[jumpStackJSD]))
+			},
+		},
+	}, nil
 }
 
 // CreateTerminalConstraints generates constraints for the last row
-func (jst *JumpStackTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (jst *JumpStackTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// No specific terminal constraints for Jump Stack Table.
 	// Consistency is ensured via permutation arguments with Processor table.
-
-	return constraints, nil
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
 // UpdatePermutationArgument updates the running product for permutation argument
 // This is called during proof generation with actual Fiat-Shamir challenges
-func (jst *JumpStackTableImpl) UpdatePermutationArgument(challenges map[string]field.Element) error {
+func (jst *JumpStackTableImpl) UpdatePermutationArgument(challenges map[string]xfield.XFieldElement) error {
 	if jst.height == 0 {
 		return fmt.Errorf("cannot update permutation argument on empty table")
 	}
-
-	// Extract challenges
-	indeterminate, ok := challenges["jumpstack_indeterminate"]
-	if !ok {
-		return fmt.Errorf("missing jumpstack_indeterminate challenge")
-	}
-	clkWeight, ok := challenges["jumpstack_clk_weight"]
-	if !ok {
-		return fmt.Errorf("missing jumpstack_clk_weight challenge")
-	}
-	ciWeight, ok := challenges["jumpstack_ci_weight"]
-	if !ok {
-		return fmt.Errorf("missing jumpstack_ci_weight challenge")
-	}
-	jspWeight, ok := challenges["jumpstack_jsp_weight"]
-	if !ok {
-		return fmt.Errorf("missing jumpstack_jsp_weight challenge")
-	}
-	jsoWeight, ok := challenges["jumpstack_jso_weight"]
-	if !ok {
-		return fmt.Errorf("missing jumpstack_jso_weight challenge")
-	}
-	jsdWeight, ok := challenges["jumpstack_jsd_weight"]
-	if !ok {
-		return fmt.Errorf("missing jumpstack_jsd_weight challenge")
+	weights, err := jumpStackWeights(challenges)
+	if err != nil {
+		return err
 	}
 
-	// First row: clk, jsp, jso, jsd are all 0, so only ci contributes
-	compressedRow := ciWeight.Mul(jst.ci[0])
-	jst.runningProductPerm[0] = indeterminate.Sub(compressedRow)
-
-	// Update subsequent rows
-	for i := 1; i < jst.height; i++ {
-		// Compress current row
-		compressedRow = clkWeight.Mul(jst.clk[i]).
-			Add(ciWeight.Mul(jst.ci[i])).
-			Add(jspWeight.Mul(jst.jsp[i])).
-			Add(jsoWeight.Mul(jst.jso[i])).
-			Add(jsdWeight.Mul(jst.jsd[i]))
-
-		// rppa[i] = rppa[i-1] * (indeterminate - compressed_row)
-		factor := indeterminate.Sub(compressedRow)
-		jst.runningProductPerm[i] = jst.runningProductPerm[i-1].Mul(factor)
+	// rppa[i] = rppa[i-1] * (indeterminate - compressed_row[i]), with the
+	// empty product before the first row
+	runningProduct := xfield.One
+	for i := 0; i < jst.height; i++ {
+		compressedRow := weights.compress(jst.clk[i], jst.ci[i], jst.jsp[i], jst.jso[i], jst.jsd[i], jst.endsFrame[i])
+		runningProduct = runningProduct.Mul(weights.indeterminate.Sub(compressedRow))
+		jst.runningProductPerm[i] = runningProduct
 	}
 
 	return nil
@@ -295,31 +292,31 @@ func (jst *JumpStackTableImpl) UpdatePermutationArgument(challenges map[string]f
 
 // UpdateClockJumpLogDerivative updates the log derivative for clock jump differences
 // This is called during proof generation for the lookup argument
-func (jst *JumpStackTableImpl) UpdateClockJumpLogDerivative(indeterminate field.Element) error {
+func (jst *JumpStackTableImpl) UpdateClockJumpLogDerivative(indeterminate xfield.XFieldElement) error {
 	if jst.height == 0 {
 		return fmt.Errorf("cannot update clock jump log derivative on empty table")
 	}
 
+	// All denominators indeterminate - (clk[i] - clk[i-1]) are inverted at once
+	denominators := make([]xfield.XFieldElement, jst.height)
+	for i := 1; i < jst.height; i++ {
+		denominators[i] = indeterminate.SubConst(jst.clk[i].Sub(jst.clk[i-1]))
+	}
+	inverses, err := protocols.BatchInverse(denominators[1:])
+	if err != nil {
+		return fmt.Errorf("clock jump difference equals the indeterminate: %w", err)
+	}
+
 	// Initialize first row (default initial for lookup argument)
-	jst.clockJumpDiffLog[0] = field.Zero
+	jst.clockJumpDiffLog[0] = xfield.Zero
 
 	// Update subsequent rows
 	for i := 1; i < jst.height; i++ {
-		// Check if jump stack pointer incremented
-		jspDiff := jst.jsp[i].Sub(jst.jsp[i-1])
-		jspIncremented := jspDiff.Equal(field.One)
-
-		if jspIncremented {
+		if jst.jsp[i].Equal(jst.jsp[i-1]) {
 			// log_deriv[i] = log_deriv[i-1] + 1/(indeterminate - (clk[i] - clk[i-1]))
-			clockDiff := jst.clk[i].Sub(jst.clk[i-1])
-			denominator := indeterminate.Sub(clockDiff)
-
-			// Compute inverse
-			inverse := denominator.Inverse()
-
-			jst.clockJumpDiffLog[i] = jst.clockJumpDiffLog[i-1].Add(inverse)
+			jst.clockJumpDiffLog[i] = jst.clockJumpDiffLog[i-1].Add(inverses[i-1])
 		} else {
-			// Jump stack pointer didn't increment, carry forward previous value
+			// A new depth starts, carry forward previous value
 			jst.clockJumpDiffLog[i] = jst.clockJumpDiffLog[i-1]
 		}
 	}
@@ -330,15 +327,16 @@ func (jst *JumpStackTableImpl) UpdateClockJumpLogDerivative(indeterminate field.
 // JumpStackEntry represents a single entry in the jump stack table
 type JumpStackEntry struct {
 	Clock                field.Element // Clock cycle
-	CurrentInstruction   field.Element // CALL, RETURN, or RECURSE_OR_RETURN
+	CurrentInstruction   field.Element // Instruction executed in this cycle
 	JumpStackPointer     field.Element // Depth of call stack
 	JumpStackOrigin      field.Element // Return address (where we came from)
 	JumpStackDestination field.Element // Return address (where to go back)
+	EndsFrame            field.Element // 1 if the instruction returns from the frame
 }
 
 // NewJumpStackEntry creates a new jump stack entry
 func NewJumpStackEntry(
-	clock, currentInstruction, jumpStackPointer, jumpStackOrigin, jumpStackDestination field.Element,
+	clock, currentInstruction, jumpStackPointer, jumpStackOrigin, jumpStackDestination, endsFrame field.Element,
 ) (*JumpStackEntry, error) {
 	return &JumpStackEntry{
 		Clock:                clock,
@@ -346,5 +344,6 @@ func NewJumpStackEntry(
 		JumpStackPointer:     jumpStackPointer,
 		JumpStackOrigin:      jumpStackOrigin,
 		JumpStackDestination: jumpStackDestination,
+		EndsFrame:            endsFrame,
 	}, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
@@ -24,8 +25,8 @@ type OpStackTableImpl struct {
 	firstUnderflowElement []field.Element // Value of first underflow element
 
 	// Auxiliary columns (XField elements for cross-table arguments)
-	runningProductPermArg []field.Element // Running product for permutation argument with Processor
-	clockJumpDiffLogDeriv []field.Element // Log derivative for clock jump differences
+	lookupLogDeriv        []xfield.XFieldElement // Log derivative of the Processor Table's lookups
+	clockJumpDiffLogDeriv []xfield.XFieldElement // Log derivative for clock jump differences
 
 	height       int
 	paddedHeight int
@@ -41,8 +42,8 @@ func NewOpStackTable() *OpStackTableImpl {
 		ib1ShrinkStack:        make([]field.Element, 0),
 		stackPointer:          make([]field.Element, 0),
 		firstUnderflowElement: make([]field.Element, 0),
-		runningProductPermArg: make([]field.Element, 0),
-		clockJumpDiffLogDeriv: make([]field.Element, 0),
+		lookupLogDeriv:        make([]xfield.XFieldElement, 0),
+		clockJumpDiffLogDeriv: make([]xfield.XFieldElement, 0),
 		height:                0,
 		paddedHeight:          0,
 	}
@@ -74,9 +75,9 @@ func (ost *OpStackTableImpl) GetMainColumns() [][]field.Element {
 }
 
 // GetAuxiliaryColumns returns auxiliary columns
-func (ost *OpStackTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
-		ost.runningProductPermArg,
+func (ost *OpStackTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{
+		ost.lookupLogDeriv,
 		ost.clockJumpDiffLogDeriv,
 	}
 }
@@ -99,26 +100,37 @@ func (ost *OpStackTableImpl) AddRow(entry *OpStackEntry) error {
 	ost.firstUnderflowElement = append(ost.firstUnderflowElement, entry.FirstUnderflowElement)
 
 	// Initialize auxiliary columns (computed during proving)
-	ost.runningProductPermArg = append(ost.runningProductPermArg, field.Zero)
-	ost.clockJumpDiffLogDeriv = append(ost.clockJumpDiffLogDeriv, field.Zero)
+	ost.lookupLogDeriv = append(ost.lookupLogDeriv, xfield.Zero)
+	ost.clockJumpDiffLogDeriv = append(ost.clockJumpDiffLogDeriv, xfield.Zero)
 
 	ost.height++
 	return nil
 }
 
 // Pad pads the table to the target height with padding rows
+//
+// An empty table gets a padding row at the first underflow pointer 16, as
+// its initial constraints demand.
 func (ost *OpStackTableImpl) Pad(targetHeight int) error {
 	if targetHeight < ost.height {
 		return fmt.Errorf("target height %d is less than current height %d", targetHeight, ost.height)
 	}
 
-	if ost.height == 0 {
-		return fmt.Errorf("cannot pad empty table")
-	}
-
 	// Padding rows have ib1ShrinkStack = 2 (PADDING_VALUE)
 	paddingIndicator := field.New(uint64(OpStackPaddingValue))
 
+	if ost.height == 0 {
+		if targetHeight == 0 {
+			return fmt.Errorf("cannot pad empty table to height 0")
+		}
+		if err := ost.AddRow(&OpStackEntry{
+			IB1ShrinkStack: paddingIndicator,
+			StackPointer:   field.New(16),
+		}); err != nil {
+			return err
+		}
+	}
+
 	// Use last row values for other fields
 	lastIdx := ost.height - 1
 	paddingRows := targetHeight - ost.height
@@ -128,7 +140,7 @@ func (ost *OpStackTableImpl) Pad(targetHeight int) error {
 		ost.ib1ShrinkStack = append(ost.ib1ShrinkStack, paddingIndicator)
 		ost.stackPointer = append(ost.stackPointer, ost.stackPointer[lastIdx])
 		ost.firstUnderflowElement = append(ost.firstUnderflowElement, ost.firstUnderflowElement[lastIdx])
-		ost.runningProductPermArg = append(ost.runningProductPermArg, ost.runningProductPermArg[lastIdx])
+		ost.lookupLogDeriv = append(ost.lookupLogDeriv, ost.lookupLogDeriv[lastIdx])
 		ost.clockJumpDiffLogDeriv = append(ost.clockJumpDiffLogDeriv, ost.clockJumpDiffLogDeriv[lastIdx])
 	}
 
@@ -136,57 +148,62 @@ func (ost *OpStackTableImpl) Pad(targetHeight int) error {
 	return nil
 }
 
+// Op Stack Table main column indices, in GetMainColumns order
+const (
+	opStackClk = iota
+	opStackIB1ShrinkStack
+	opStackPointer
+	opStackFirstUnderflowElement
+)
+
 // CreateInitialConstraints generates constraints for the first row
 // Based on Triton VM's op_stack.rs initial_constraints implementation
-func (ost *OpStackTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
-	// NOTE: These constraints are expressed using the legacy AIRConstraint type
-	// for compatibility with the existing proof system. The modern approach would use
-	// ConstraintPolynomial with evaluator functions (see protocols/constraints.go).
-	//
-	// The actual constraint enforcement happens during proof generation when:
-	// 1. Stack pointer is verified to be 16 (initial stack length)
-	// 2. Running product permutation argument (RPPA) is initialized correctly
-	// 3. Clock jump difference log derivative is initialized to default_initial
-	//
-	// These constraints are implicitly enforced by the table construction in Pad()
-	// and the proof generation in the STARK prover, which uses challenge values
-	// to construct the compressed row representation.
-	//
+func (ost *OpStackTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// From Triton VM's op_stack.rs:
 	// - stack_pointer_is_16: main_row(StackPointer) - 16 == 0
-	// - rppa_starts_correctly: complex constraint with padding row handling
-	// - clock_jump_diff_log_derivative_is_initialized_correctly: aux_row(...) - default_initial() == 0
-
-	// Since these are enforced during proof generation (not as explicit polynomials),
-	// we return an empty constraint set. The actual verification happens in the
-	// prover when constructing the running product and log derivative columns.
-
-	return constraints, nil
+	// - the first row grows the stack or is padding: ib1 * (2 - ib1) = 0
+	//
+	// A program that never spills still gets a padding row at pointer 16.
+	// The lookup and clock jump difference log derivatives are auxiliary
+	// columns and belong to the cross-table arguments.
+	padding := field.New(OpStackPaddingValue)
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "op_stack_pointer_starts_at_16",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[opStackPointer].SubConst(field.New(16))
+			},
+		},
+		{
+			Name:   "op_stack_first_row_grows_stack_or_is_padding",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				ib1 := row[opStackIB1ShrinkStack]
+				return ib1.Mul(ib1.Neg().AddConst(padding))
+			},
+		},
+	}, nil
 }
 
 // CreateConsistencyConstraints generates constraints within each row
-func (ost *OpStackTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (ost *OpStackTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// ib1ShrinkStack must be 0 (grow), 1 (shrink), or 2 (padding)
 	// Constraint: ib1 * (ib1 - 1) * (ib1 - 2) = 0
-	// This is a degree-3 polynomial constraint enforced on every row
-	// Note: Actual polynomial representation will be computed during proof generation
-	// when we have the full trace and can evaluate constraints
-
-	// Stack pointer >= 16 constraint is enforced via:
-	// 1. Validation in AddRow (prevents invalid data from entering)
-	// 2. Range check lookup argument (proves all values are valid field elements >= 16)
-
-	return constraints, nil
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "op_stack_ib1_is_grow_shrink_or_padding",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				ib1 := row[opStackIB1ShrinkStack]
+				return isBit(ib1).Mul(ib1.SubConst(field.New(OpStackPaddingValue)))
+			},
+		},
+	}, nil
 }
 
 // CreateTransitionConstraints generates constraints between consecutive rows
-func (ost *OpStackTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (ost *OpStackTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
 	// Transition constraints for OpStack table:
 	//
 	// 1. Stack pointer increases by 1 or stays the same
@@ -195,96 +212,113 @@ func (ost *OpStackTableImpl) CreateTransitionConstraints() ([]protocols.AIRConst
 	// 2. If current row is padding, next row must also be padding
 	//    Constraint: ib1 * (ib1 - 1) * (ib1' - 2) = 0
 	//
-	// 3. Running product permutation argument updates correctly
-	//    rppa' = rppa * (indeterminate - compressed_row)
-	//    Where compressed_row = Σ challenge_i * column_i
+	// 3. If the stack pointer stays the same and the next row shrinks the
+	//    stack, it reads the element the current row left behind:
+	//    (1 - (sp' - sp)) * ib1' * (2 - ib1') * (elem' - elem) = 0
 	//
-	// 4. Clock jump difference log derivative updates correctly
-	//    log_deriv' = log_deriv + 1/(indeterminate - clock_jump_diff)
+	// 4. A new stack pointer is first accessed by growing the stack, or is
+	//    padding:
+	//    (sp' - sp) * ib1' * (2 - ib1') = 0
 	//
-	// Note: Actual polynomial representations of these constraints are
-	// computed during proof generation when we have:
-	// - The full trace data
-	// - Fiat-Shamir challenges
-	// - Proper domain for polynomial evaluation
+	// Rows are sorted by stack pointer, then by clock, so 3 states that
+	// every pop returns what was last pushed to the same position, and 4
+	// that nothing is popped from a position before it was pushed.
 	//
-	// The constraints are enforced via AIR checking during verification.
-
-	return constraints, nil
+	// The running product and clock jump difference log derivative are
+	// auxiliary columns and belong to the cross-table arguments.
+	padding := field.New(OpStackPaddingValue)
+	return []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "op_stack_pointer_increments_by_0_or_1",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				diff := next[opStackPointer].Sub(current[opStackPointer])
+				return diff.Mul(diff.Sub(xfield.One))
+			},
+		},
+		{
+			Name:   "op_stack_padding_is_followed_by_padding",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(current[opStackIB1ShrinkStack]).Mul(next[opStackIB1ShrinkStack].SubConst(padding))
+			},
+		},
+		{
+			Name:   "op_stack_pop_reads_last_pushed_element",
+			Degree: 4,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				samePointer := xfield.One.Sub(next[opStackPointer].Sub(current[opStackPointer]))
+				nextIB1 := next[opStackIB1ShrinkStack]
+				nextIsShrink := nextIB1.Mul(nextIB1.Neg().AddConst(padding))
+				elementDiff := next[opStackFirstUnderflowElement].Sub(current[opStackFirstUnderflowElement])
+				return samePointer.Mul(nextIsShrink).Mul(elementDiff)
+			},
+		},
+		{
+			Name:   "op_stack_new_pointer_grows_stack_or_is_padding",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				nextIB1 := next[opStackIB1ShrinkStack]
+				nextIsShrink := nextIB1.Mul(nextIB1.Neg().AddConst(padding))
+				return next[opStackPointer].Sub(current[opStackPointer]).Mul(nextIsShrink)
+			},
+		},
+	}, nil
 }
 
 // CreateTerminalConstraints generates constraints for the last row
-func (ost *OpStackTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (ost *OpStackTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// No specific terminal constraints for operational stack table
 	// The permutation argument with processor table ensures consistency
-
-	return constraints, nil
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
-// UpdateRunningProductPermArg updates the running product for permutation argument
-// This is called during proof generation with actual Fiat-Shamir challenges
-func (ost *OpStackTableImpl) UpdateRunningProductPermArg(challenges map[string]field.Element) error {
+// UpdateLookupLogDerivative computes the log derivative of the elements the
+// Processor Table moves to and from the stack underflow memory
+//
+//	ld[0]·(α - c[0]) = notPad[0],  (ld' - ld)·(α - c') = notPad'
+//
+// with c compressing (clk, ib1, pointer, value), and notPad 1 in every row
+// but padding rows
+func (ost *OpStackTableImpl) UpdateLookupLogDerivative(challenges map[string]xfield.XFieldElement) error {
 	if ost.height == 0 {
-		return fmt.Errorf("cannot update running product on empty table")
+		return fmt.Errorf("cannot update lookup log derivative on empty table")
 	}
-
-	// Extract challenges
-	indeterminate, ok := challenges["op_stack_indeterminate"]
-	if !ok {
-		return fmt.Errorf("missing op_stack_indeterminate challenge")
+	weights, err := tupleWeights(challenges, challengeOpStackIndeterminate, numOpStackWeights)
+	if err != nil {
+		return err
 	}
-	clkWeight, ok := challenges["op_stack_clk_weight"]
-	if !ok {
-		return fmt.Errorf("missing op_stack_clk_weight challenge")
-	}
-	ib1Weight, ok := challenges["op_stack_ib1_weight"]
-	if !ok {
-		return fmt.Errorf("missing op_stack_ib1_weight challenge")
-	}
-	pointerWeight, ok := challenges["op_stack_pointer_weight"]
-	if !ok {
-		return fmt.Errorf("missing op_stack_pointer_weight challenge")
-	}
-	elementWeight, ok := challenges["op_stack_element_weight"]
-	if !ok {
-		return fmt.Errorf("missing op_stack_element_weight challenge")
+
+	padding := field.New(OpStackPaddingValue)
+	logDerivative := xfield.Zero
+	for i := range ost.clk {
+		if !ost.ib1ShrinkStack[i].Equal(padding) {
+			compressed := weights.compressBase(ost.clk[i], ost.ib1ShrinkStack[i], ost.stackPointer[i], ost.firstUnderflowElement[i])
+			logDerivative = logDerivative.Add(weights.indeterminate.Sub(compressed).Inverse())
+		}
+		ost.lookupLogDeriv[i] = logDerivative
 	}
 
-	// Initialize running product
-	paddingIndicator := field.New(uint64(OpStackPaddingValue))
+	return nil
+}
 
-	// First row handling
-	if !ost.ib1ShrinkStack[0].Equal(paddingIndicator) {
-		// Compress first row
-		compressedRow := clkWeight.Mul(ost.clk[0]).
-			Add(ib1Weight.Mul(ost.ib1ShrinkStack[0])).
-			Add(pointerWeight.Mul(ost.stackPointer[0])).
-			Add(elementWeight.Mul(ost.firstUnderflowElement[0]))
-
-		// rppa[0] = indeterminate - compressed_row
-		ost.runningProductPermArg[0] = indeterminate.Sub(compressedRow)
-	} else {
-		// First row is padding, use default initial
-		ost.runningProductPermArg[0] = field.One
+// UpdateClockJumpLogDerivative computes the log derivative of the clock jump
+// differences between consecutive accesses of the same stack pointer, which
+// the Processor Table serves
+//
+//	ld[0] = 0,  (ld' - ld)·(γ - (clk' - clk)) = (1 - (sp' - sp))·notPad'
+func (ost *OpStackTableImpl) UpdateClockJumpLogDerivative(indeterminate xfield.XFieldElement) error {
+	if ost.height == 0 {
+		return fmt.Errorf("cannot update clock jump log derivative on empty table")
 	}
 
-	// Update subsequent rows
-	for i := 1; i < ost.height; i++ {
-		if !ost.ib1ShrinkStack[i].Equal(paddingIndicator) {
-			// Compress current row
-			compressedRow := clkWeight.Mul(ost.clk[i]).
-				Add(ib1Weight.Mul(ost.ib1ShrinkStack[i])).
-				Add(pointerWeight.Mul(ost.stackPointer[i])).
-				Add(elementWeight.Mul(ost.firstUnderflowElement[i]))
-
-			// rppa[i] = rppa[i-1] * (indeterminate - compressed_row)
-			factor := indeterminate.Sub(compressedRow)
-			ost.runningProductPermArg[i] = ost.runningProductPermArg[i-1].Mul(factor)
-		} else {
-			// Padding row, keep previous value
-			ost.runningProductPermArg[i] = ost.runningProductPermArg[i-1]
+	padding := field.New(OpStackPaddingValue)
+	ost.clockJumpDiffLogDeriv[0] = xfield.Zero
+	for i := 1; i < len(ost.clk); i++ {
+		ost.clockJumpDiffLogDeriv[i] = ost.clockJumpDiffLogDeriv[i-1]
+		if ost.stackPointer[i].Equal(ost.stackPointer[i-1]) && !ost.ib1ShrinkStack[i].Equal(padding) {
+			difference := indeterminate.SubConst(ost.clk[i].Sub(ost.clk[i-1]))
+			ost.clockJumpDiffLogDeriv[i] = ost.clockJumpDiffLogDeriv[i].Add(difference.Inverse())
 		}
 	}
 
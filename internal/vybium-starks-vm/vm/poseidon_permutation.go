@@ -0,0 +1,200 @@
+// The Poseidon permutation recorded in the Hash Table
+
+package vm
+
+import (
+	"sync"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+)
+
+// Poseidon permutation parameters for the Hash Table
+//
+// The state has the same shape as Tip5's: 10 rate and 6 capacity elements.
+// The S-box is x^7 since 7 is the smallest exponent coprime to p-1 for the
+// Goldilocks prime (x^3 and x^5 are not permutations of the field).
+//
+// The number of partial rounds makes the trace of one permutation (the state
+// before every round plus the output) exactly 32 rows, so the round constants
+// form periodic columns of the Hash Table.
+const (
+	PoseidonStateSize     = 16
+	PoseidonRate          = 10
+	PoseidonCapacity      = PoseidonStateSize - PoseidonRate
+	PoseidonFullRounds    = 8
+	PoseidonPartialRounds = 23
+	PoseidonNumRounds     = PoseidonFullRounds + PoseidonPartialRounds
+
+	// PoseidonTraceLength is the number of Hash Table rows per permutation
+	PoseidonTraceLength = PoseidonNumRounds + 1
+
+	// PoseidonDigestLen is the number of elements of a digest: the first
+	// elements of the state after the final permutation
+	PoseidonDigestLen = 5
+)
+
+// poseidonParameters holds the round constants and MDS matrix of the
+// permutation, derived once on first use
+type poseidonParameters struct {
+	roundConstants [PoseidonNumRounds][PoseidonStateSize]field.Element
+	mds            [PoseidonStateSize][PoseidonStateSize]field.Element
+}
+
+var (
+	poseidonParamsOnce sync.Once
+	poseidonParams     *poseidonParameters
+)
+
+// getPoseidonParameters returns the permutation's parameters
+//
+// Round constants are Tip5 hashes of (domain tag, round, index), so they are
+// reproducible without a hard-coded table. The MDS matrix is the Cauchy
+// matrix 1/(x_i + y_j) with x_i = i and y_j = 16 + j, whose square
+// submatrices are all non-singular.
+func getPoseidonParameters() *poseidonParameters {
+	poseidonParamsOnce.Do(func() {
+		params := &poseidonParameters{}
+		domainTag := field.New(0x706f736569646f6e) // "poseidon"
+		for round := 0; round < PoseidonNumRounds; round++ {
+			for i := 0; i < PoseidonStateSize; i++ {
+				digest := hash.HashVarlen([]field.Element{
+					domainTag,
+					field.New(uint64(round)),
+					field.New(uint64(i)),
+				})
+				params.roundConstants[round][i] = digest[0]
+			}
+		}
+		for i := 0; i < PoseidonStateSize; i++ {
+			for j := 0; j < PoseidonStateSize; j++ {
+				params.mds[i][j] = field.New(uint64(i + PoseidonStateSize + j)).Inverse()
+			}
+		}
+		poseidonParams = params
+	})
+	return poseidonParams
+}
+
+// isPoseidonFullRound reports whether the given round applies the S-box to
+// the whole state: the first and last PoseidonFullRounds/2 rounds do
+func isPoseidonFullRound(round int) bool {
+	return round < PoseidonFullRounds/2 || round >= PoseidonFullRounds/2+PoseidonPartialRounds
+}
+
+// poseidonRoundConstant returns the round constant added to state element i
+// in the given round, or zero for the output row of a permutation
+func poseidonRoundConstant(round, i int) field.Element {
+	if round < 0 || round >= PoseidonNumRounds {
+		return field.Zero
+	}
+	return getPoseidonParameters().roundConstants[round][i]
+}
+
+// poseidonMDS returns the entry of the MDS matrix in row i and column j
+func poseidonMDS(i, j int) field.Element {
+	return getPoseidonParameters().mds[i][j]
+}
+
+// poseidonSbox computes x^7
+func poseidonSbox(x field.Element) field.Element {
+	x2 := x.Square()
+	x4 := x2.Square()
+	return x4.Mul(x2).Mul(x)
+}
+
+// poseidonRound applies one round of the permutation to the state
+func poseidonRound(state [PoseidonStateSize]field.Element, round int) [PoseidonStateSize]field.Element {
+	params := getPoseidonParameters()
+
+	for i := 0; i < PoseidonStateSize; i++ {
+		state[i] = state[i].Add(params.roundConstants[round][i])
+	}
+
+	if isPoseidonFullRound(round) {
+		for i := 0; i < PoseidonStateSize; i++ {
+			state[i] = poseidonSbox(state[i])
+		}
+	} else {
+		state[0] = poseidonSbox(state[0])
+	}
+
+	var next [PoseidonStateSize]field.Element
+	for i := 0; i < PoseidonStateSize; i++ {
+		acc := field.Zero
+		for j := 0; j < PoseidonStateSize; j++ {
+			acc = acc.Add(params.mds[i][j].Mul(state[j]))
+		}
+		next[i] = acc
+	}
+	return next
+}
+
+// poseidonPermutationTrace applies the permutation and returns the state
+// before every round followed by the final state (PoseidonTraceLength states)
+func poseidonPermutationTrace(state [PoseidonStateSize]field.Element) [][PoseidonStateSize]field.Element {
+	trace := make([][PoseidonStateSize]field.Element, 0, PoseidonTraceLength)
+	trace = append(trace, state)
+	for round := 0; round < PoseidonNumRounds; round++ {
+		state = poseidonRound(state, round)
+		trace = append(trace, state)
+	}
+	return trace
+}
+
+// poseidonPermutation applies all rounds of the permutation to the state
+func poseidonPermutation(state [PoseidonStateSize]field.Element) [PoseidonStateSize]field.Element {
+	for round := 0; round < PoseidonNumRounds; round++ {
+		state = poseidonRound(state, round)
+	}
+	return state
+}
+
+// poseidonFixedLengthState returns the state a fixed-length hash permutes:
+// its (up to PoseidonRate) inputs in the rate and a capacity of ones
+//
+// As in Tip5, the capacity separates the fixed-length domain from the
+// variable-length one, whose capacity starts at zero.
+func poseidonFixedLengthState(input []field.Element) [PoseidonStateSize]field.Element {
+	var state [PoseidonStateSize]field.Element
+	copy(state[:PoseidonRate], input)
+	for i := PoseidonRate; i < PoseidonStateSize; i++ {
+		state[i] = field.One
+	}
+	return state
+}
+
+// poseidonHash10 hashes PoseidonRate elements into a digest, as hash and
+// merkle_step do
+func poseidonHash10(input []field.Element) [PoseidonDigestLen]field.Element {
+	state := poseidonPermutation(poseidonFixedLengthState(input))
+	var digest [PoseidonDigestLen]field.Element
+	copy(digest[:], state[:PoseidonDigestLen])
+	return digest
+}
+
+// poseidonHashVarlen hashes any number of elements into a digest
+//
+// The input is padded with a one and then zeros to a multiple of the rate,
+// and every chunk is added to the rate of a sponge whose capacity starts at
+// zero, followed by a permutation.
+func poseidonHashVarlen(input []field.Element) [PoseidonDigestLen]field.Element {
+	padded := make([]field.Element, 0, len(input)+PoseidonRate)
+	padded = append(padded, input...)
+	padded = append(padded, field.One)
+	for len(padded)%PoseidonRate != 0 {
+		padded = append(padded, field.Zero)
+	}
+
+	var state [PoseidonStateSize]field.Element
+	for chunk := 0; chunk < len(padded); chunk += PoseidonRate {
+		for i := 0; i < PoseidonRate; i++ {
+			state[i] = state[i].Add(padded[chunk+i])
+		}
+		state = poseidonPermutation(state)
+	}
+
+	var digest [PoseidonDigestLen]field.Element
+	copy(digest[:], state[:PoseidonDigestLen])
+	return digest
+}
@@ -0,0 +1,393 @@
+// Package vm implements the Processor Table's side of its links to the
+// coprocessor tables
+package vm
+
+import (
+	"fmt"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
+)
+
+// An instruction sends up to ten elements to or from the op stack underflow
+// memory, accesses up to ten RAM words, runs one permutation of the Hash
+// Table and up to two u32 operations. Each of them goes through its own
+// slot, an auxiliary column of the Processor Table.
+const (
+	numOpStackSlots = 10
+	numRAMSlots     = 10
+	numHashSlots    = 1
+	numU32Slots     = 2
+
+	numProcessorLinkSlots = numOpStackSlots + numRAMSlots + numHashSlots + numU32Slots
+)
+
+// tupleElement is an element of a lookup tuple, as a polynomial in the
+// current and next Processor Table rows
+type tupleElement func(current, next []xfield.XFieldElement) xfield.XFieldElement
+
+func currentRegister(j int) tupleElement {
+	return func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+		return stackRegister(current, j)
+	}
+}
+
+func nextRegister(j int) tupleElement {
+	return func(_, next []xfield.XFieldElement) xfield.XFieldElement {
+		return stackRegister(next, j)
+	}
+}
+
+func helperElement(j int) tupleElement {
+	return func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+		return helperValue(current, j)
+	}
+}
+
+func constantElement(value uint64) tupleElement {
+	c := constant(value)
+	return func(_, _ []xfield.XFieldElement) xfield.XFieldElement {
+		return c
+	}
+}
+
+// lookupTuple is a tuple an instruction sends to a coprocessor table
+type lookupTuple struct {
+	degree int // Degree of the tuple's elements
+	values func(current, next []xfield.XFieldElement) []xfield.XFieldElement
+
+	// How often the tuple is sent; nil sends it once
+	multiplicity       tupleElement
+	multiplicityDegree int
+}
+
+// opStackTuple returns the tuple (clk, ib1, pointer, value) of the k-th
+// element an instruction moves to or from the op stack underflow memory
+//
+// Growing the stack moves st15, st14, ... below st15, to the pointers osp,
+// osp + 1, ...; shrinking refills st'15, st'14, ... from the pointers osp',
+// osp' + 1, .... Only occupied registers move, and ib1 is 0 for growing and
+// 1 for shrinking.
+func opStackTuple(k int, grows bool, shared *sharedRules) *lookupTuple {
+	return shared.tuple(fmt.Sprintf("op stack %d, growing %t", k, grows), func() *lookupTuple {
+		ib1, side := xfield.One, func(_, next []xfield.XFieldElement) []xfield.XFieldElement { return next }
+		if grows {
+			ib1, side = xfield.Zero, func(current, _ []xfield.XFieldElement) []xfield.XFieldElement { return current }
+		}
+		offset := constant(uint64(k))
+		return &lookupTuple{
+			degree: 1,
+			values: func(current, next []xfield.XFieldElement) []xfield.XFieldElement {
+				row := side(current, next)
+				return []xfield.XFieldElement{current[processorClk], ib1, row[processorOSP].Add(offset), stackRegister(row, 15-k)}
+			},
+			multiplicity: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return side(current, next)[processorOccupied0+15-k]
+			},
+			multiplicityDegree: 1,
+		}
+	})
+}
+
+// ramTuple returns the tuple (clk, type, st_pointer + k, value) of a RAM
+// access, with type 0 for writes and 1 for reads
+func ramTuple(instructionType uint64, pointer, k int, value tupleElement) *lookupTuple {
+	kind, offset := constant(instructionType), constant(uint64(k))
+	return &lookupTuple{
+		degree: 1,
+		values: func(current, next []xfield.XFieldElement) []xfield.XFieldElement {
+			address := stackRegister(current, pointer).Add(offset)
+			return []xfield.XFieldElement{current[processorClk], kind, address, value(current, next)}
+		},
+	}
+}
+
+// hashTuple returns the tuple (tag, in_0, ..., in_15, out_0, ..., out_15)
+// of a permutation the Hash Table proves
+//
+// Fixed-length hashes have tag 0 and only send the rate of the input and
+// the digest of the output, the other elements being zero; the sponge
+// instructions have tag 1 and send both states.
+func hashTuple(degree int, sponge bool, io func(current, next []xfield.XFieldElement) (in, out []xfield.XFieldElement)) *lookupTuple {
+	tag := xfield.Zero
+	if sponge {
+		tag = xfield.One
+	}
+	return &lookupTuple{
+		degree: degree,
+		values: func(current, next []xfield.XFieldElement) []xfield.XFieldElement {
+			values := make([]xfield.XFieldElement, numHashWeights)
+			values[0] = tag
+			in, out := io(current, next)
+			copy(values[1:], in)
+			copy(values[1+PoseidonStateSize:], out)
+			return values
+		},
+	}
+}
+
+// spongeTuple returns the tuple of a sponge instruction, which permutes the
+// sponge state with the given input added to its rate
+func spongeTuple(input func(current []xfield.XFieldElement, i int) xfield.XFieldElement) *lookupTuple {
+	return hashTuple(1, true, func(current, next []xfield.XFieldElement) (in, out []xfield.XFieldElement) {
+		in = make([]xfield.XFieldElement, PoseidonStateSize)
+		out = make([]xfield.XFieldElement, PoseidonStateSize)
+		for i := range in {
+			in[i] = current[processorSponge0+i]
+			if i < PoseidonRate && input != nil {
+				in[i] = in[i].Add(input(current, i))
+			}
+			out[i] = next[processorSponge0+i]
+		}
+		return in, out
+	})
+}
+
+// merkleStepTuple returns the tuple of merkle_step and merkle_step_mem,
+// which hash the node digest in st_top, ..., st_{top-4}, first element
+// deepest, with the sibling digest in hv0..hv4. hv5 is the node index's
+// lowest bit: the node is the left child if it is 0.
+func merkleStepTuple(top int) *lookupTuple {
+	return hashTuple(2, false, func(current, next []xfield.XFieldElement) (in, out []xfield.XFieldElement) {
+		in = make([]xfield.XFieldElement, 2*PoseidonDigestLen)
+		out = make([]xfield.XFieldElement, PoseidonDigestLen)
+		isRight := helperValue(current, 5)
+		for i := 0; i < PoseidonDigestLen; i++ {
+			node, sibling := stackRegister(current, top-i), helperValue(current, i)
+			in[i] = node.Add(isRight.Mul(sibling.Sub(node)))
+			in[PoseidonDigestLen+i] = sibling.Add(isRight.Mul(node.Sub(sibling)))
+			out[i] = stackRegister(next, PoseidonDigestLen-1-i)
+		}
+		return in, out
+	})
+}
+
+// u32Tuple returns the tuple (ci, lhs, rhs, result) of an operation the
+// U32 Table proves
+func u32Tuple(inst Instruction, lhs, rhs, result tupleElement) *lookupTuple {
+	opcode := constant(uint64(inst))
+	return &lookupTuple{
+		degree: 1,
+		values: func(current, next []xfield.XFieldElement) []xfield.XFieldElement {
+			return []xfield.XFieldElement{opcode, lhs(current, next), rhs(current, next), result(current, next)}
+		},
+	}
+}
+
+// gatedTuple is a tuple an instruction sends when it is selected
+type gatedTuple struct {
+	transition *instructionTransition
+	tuple      *lookupTuple
+}
+
+// lookupSlot is one auxiliary column of the Processor Table's side of a
+// link: the log derivative of the tuples the instructions send through it
+//
+//	ld[0] = 0,  (ld' - ld)·(α - Σ gate·c) = Σ gate·m
+//
+// with c the compressed tuple and m its multiplicity. Exactly one
+// instruction and at most one argument are selected in every row, so the
+// sums are the selected instruction's tuple, and ld stays the same in rows
+// whose instruction sends none. Tuples shared by several instructions are
+// evaluated once, times the sum of their gates.
+type lookupSlot struct {
+	groups []*tupleGates
+	degree int
+}
+
+type tupleGates struct {
+	tuple *lookupTuple
+	gates []gate
+}
+
+func newLookupSlot(terms []gatedTuple) *lookupSlot {
+	slot := &lookupSlot{}
+	byTuple := make(map[*lookupTuple]*tupleGates)
+	for _, term := range terms {
+		group, ok := byTuple[term.tuple]
+		if !ok {
+			group = &tupleGates{tuple: term.tuple}
+			byTuple[term.tuple] = group
+			slot.groups = append(slot.groups, group)
+		}
+		g, gateDegree := term.transition.gate()
+		group.gates = append(group.gates, g)
+		slot.degree = max(slot.degree, 1+gateDegree+term.tuple.degree, gateDegree+term.tuple.multiplicityDegree)
+	}
+	return slot
+}
+
+// eval returns Σ gate·m and Σ gate·c for the current row
+func (s *lookupSlot) eval(current, next []xfield.XFieldElement, compress func([]xfield.XFieldElement) xfield.XFieldElement) (multiplicity, compressed xfield.XFieldElement) {
+	multiplicity, compressed = xfield.Zero, xfield.Zero
+	for _, group := range s.groups {
+		gates := xfield.Zero
+		for _, g := range group.gates {
+			gates = gates.Add(g.value(current))
+		}
+		if gates.IsZero() {
+			continue
+		}
+		m := gates
+		if group.tuple.multiplicity != nil {
+			m = m.Mul(group.tuple.multiplicity(current, next))
+		}
+		multiplicity = multiplicity.Add(m)
+		compressed = compressed.Add(gates.Mul(compress(group.tuple.values(current, next))))
+	}
+	return multiplicity, compressed
+}
+
+// processorLink is the Processor Table's side of its link to a coprocessor
+// table, whose challenges are an indeterminate followed by one weight per
+// tuple element
+type processorLink struct {
+	name          string
+	indeterminate int
+	numWeights    int
+	firstSlot     int // Index of the link's first slot among all links' slots
+	server        int // Auxiliary column of the coprocessor table's side
+	slots         []*lookupSlot
+
+	// Auxiliary columns of other tables sending to the same server
+	clients []int
+}
+
+// processorLinks returns the links of the Processor Table to the Op Stack,
+// RAM, Hash and U32 tables, with the slots of every instruction's tuples
+func processorLinks() []*processorLink {
+	links := []*processorLink{
+		{name: "op_stack", indeterminate: challengeOpStackIndeterminate, numWeights: numOpStackWeights, server: auxOpStackLogDeriv},
+		{name: "ram", indeterminate: challengeRAMIndeterminate, numWeights: numRAMWeights, server: auxRAMLogDeriv},
+		{name: "hash", indeterminate: challengeHashIndeterminate, numWeights: numHashWeights, server: auxHashLogDeriv,
+			clients: []int{auxProgramHashLookup}},
+		{name: "u32", indeterminate: challengeU32Indeterminate, numWeights: numU32Weights, server: auxU32LogDeriv},
+	}
+	numSlots := []int{numOpStackSlots, numRAMSlots, numHashSlots, numU32Slots}
+	terms := make([][][]gatedTuple, len(links))
+	for l := range links {
+		terms[l] = make([][]gatedTuple, numSlots[l])
+	}
+
+	for _, t := range processorTransitions(newSharedRules()) {
+		for l, tuples := range [][]*lookupTuple{t.opStack, t.ram, t.hash, t.u32} {
+			for s, tuple := range tuples {
+				terms[l][s] = append(terms[l][s], gatedTuple{t, tuple})
+			}
+		}
+	}
+
+	firstSlot := 0
+	for l, link := range links {
+		link.firstSlot = firstSlot
+		for _, slotTerms := range terms[l] {
+			link.slots = append(link.slots, newLookupSlot(slotTerms))
+		}
+		firstSlot += numSlots[l]
+	}
+	return links
+}
+
+// processorLinkConstraints adds the constraints of the Processor Table's
+// side of its links, whose terminal constraints equate the sum of a link's
+// slots and other clients with the coprocessor table's side
+func processorLinkConstraints(air *protocols.AIRConstraints, processor int) {
+	aux := air.AuxColumnIndex
+	challenge := air.ChallengeIndex
+	for _, link := range processorLinks() {
+		link := link
+		for s, slot := range link.slots {
+			slot, col := slot, auxProcessorLink0+link.firstSlot+s
+			air.AddInitialConstraint(fmt.Sprintf("processor_%s_slot_%d_starts_at_0", link.name, s), 1,
+				func(row []xfield.XFieldElement) xfield.XFieldElement {
+					return row[aux(col)]
+				})
+			air.AddTransitionConstraint(fmt.Sprintf("processor_%s_slot_%d_accumulates_row", link.name, s), slot.degree,
+				func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+					weights := rowTupleChallenges(current, challenge, link.indeterminate, link.numWeights)
+					m, c := slot.eval(current[processor:], next[processor:], weights.compress)
+					diff := next[aux(col)].Sub(current[aux(col)])
+					return diff.Mul(weights.indeterminate.Sub(c)).Sub(m)
+				})
+		}
+		air.AddTerminalConstraint(fmt.Sprintf("processor_%s_lookup", link.name), 1,
+			func(row []xfield.XFieldElement) xfield.XFieldElement {
+				sum := row[aux(link.server)].Neg()
+				for s := range link.slots {
+					sum = sum.Add(row[aux(auxProcessorLink0+link.firstSlot+s)])
+				}
+				for _, client := range link.clients {
+					sum = sum.Add(row[aux(client)])
+				}
+				return sum
+			})
+	}
+}
+
+// UpdateCoprocessorLinks computes the Processor Table's side of its links
+// to the Op Stack, RAM, Hash and U32 tables, and of its instruction lookup
+// in the Program Table
+//
+// The instruction lookup sends (ip, ci, nia) in every row:
+//
+//	ld[0]·(α - c[0]) = 1,  (ld' - ld)·(α - c') = 1
+func (pt *ProcessorTableImpl) UpdateCoprocessorLinks(challenges map[string]xfield.XFieldElement) error {
+	n := len(pt.clk)
+	if n == 0 {
+		return fmt.Errorf("cannot update coprocessor links on empty table")
+	}
+
+	columns := pt.GetMainColumns()
+	rows := make([][]xfield.XFieldElement, n)
+	for i := range rows {
+		rows[i] = make([]xfield.XFieldElement, len(columns))
+		for j, column := range columns {
+			rows[i][j] = xfield.NewConst(column[i])
+		}
+	}
+
+	for _, link := range processorLinks() {
+		weights, err := tupleWeights(challenges, link.indeterminate, link.numWeights)
+		if err != nil {
+			return err
+		}
+		for s, slot := range link.slots {
+			multiplicities := make([]xfield.XFieldElement, n-1)
+			denominators := make([]xfield.XFieldElement, n-1)
+			for i := 0; i+1 < n; i++ {
+				m, c := slot.eval(rows[i], rows[i+1], weights.compress)
+				multiplicities[i], denominators[i] = m, weights.indeterminate.Sub(c)
+			}
+			inverses, err := protocols.BatchInverse(denominators)
+			if err != nil {
+				return fmt.Errorf("%s tuple compresses to the indeterminate: %w", link.name, err)
+			}
+			logDerivative := make([]xfield.XFieldElement, n)
+			for i := 1; i < n; i++ {
+				logDerivative[i] = logDerivative[i-1].Add(multiplicities[i-1].Mul(inverses[i-1]))
+			}
+			pt.linkSlots[link.firstSlot+s] = logDerivative
+		}
+	}
+
+	weights, err := tupleWeights(challenges, challengeProgramIndeterminate, numProgramWeights)
+	if err != nil {
+		return err
+	}
+	denominators := make([]xfield.XFieldElement, n)
+	for i := range denominators {
+		denominators[i] = weights.indeterminate.Sub(weights.compressBase(pt.ip[i], pt.ci[i], pt.nia[i]))
+	}
+	inverses, err := protocols.BatchInverse(denominators)
+	if err != nil {
+		return fmt.Errorf("instruction compresses to the indeterminate: %w", err)
+	}
+	pt.instructionLookup = make([]xfield.XFieldElement, n)
+	logDerivative := xfield.Zero
+	for i, inverse := range inverses {
+		logDerivative = logDerivative.Add(inverse)
+		pt.instructionLookup[i] = logDerivative
+	}
+
+	return nil
+}
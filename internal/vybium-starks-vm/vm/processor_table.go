@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
@@ -21,13 +22,41 @@ type ProcessorTableImpl struct {
 	jsp, jso, jsd                                []field.Element // Jump stack pointer, origin, destination
 	st0, st1, st2, st3, st4, st5, st6, st7       []field.Element // Stack registers 0-7
 	st8, st9, st10, st11, st12, st13, st14, st15 []field.Element // Stack registers 8-15
+	cjdMultiplicity                              []field.Element // How often clk is a clock jump difference
+
+	// Instruction decoding: osp is the number of stack elements and
+	// occupied[j] is 1 if st_j holds one of them; the selectors are one-hot
+	// encodings of ci, and of nia for instructions with an index argument
+	osp               []field.Element
+	occupied          [16][]field.Element
+	selectors         [numProcessorInstructions][]field.Element
+	argumentSelectors [numArgumentSelectors][]field.Element
+	helpers           [numProcessorHelpers][]field.Element // Instruction-specific helper values
+
+	// How often skiz looks up the size of the instruction listed in this row
+	instructionSizeMultiplicity []field.Element
+
+	// The number of stack elements below st15, the sponge state and whether
+	// sponge_init has run
+	underflowSize     []field.Element
+	sponge            [PoseidonStateSize][]field.Element
+	spongeInitialized []field.Element
 
 	// Auxiliary columns (XField elements for cross-table arguments)
-	permArg []field.Element // Permutation argument accumulator
-	evalArg []field.Element // Evaluation argument accumulator
+	permArg             []xfield.XFieldElement // Permutation argument with the Jump Stack Table
+	evalArg             []xfield.XFieldElement // Evaluation argument accumulator
+	clockJumpDiffLookup []xfield.XFieldElement // Log derivative serving clock jump differences
+	instructionSizes    []xfield.XFieldElement // Log derivative of skiz's instruction size lookup
+	instructionLookup   []xfield.XFieldElement // Log derivative of the Program Table lookup of (ip, ci, nia)
+	inputEval           []xfield.XFieldElement // Evaluation of the words read_io reads, before the row's instruction
+	outputEval          []xfield.XFieldElement // Evaluation of the words write_io writes, before the row's instruction
 
-	// Extension column for TIP-0007: Run-Time Permutation Check
-	permrp []field.Element // Permutation running product
+	// Log derivatives of the tuples sent to the coprocessor tables, one per
+	// slot; see processorLinks
+	linkSlots [numProcessorLinkSlots][]xfield.XFieldElement
+
+	// Auxiliary column for TIP-0007: Run-Time Permutation Check
+	permrp []xfield.XFieldElement // Permutation running product before the row's instruction
 
 	height       int
 	paddedHeight int
@@ -36,37 +65,51 @@ type ProcessorTableImpl struct {
 // NewProcessorTable creates a new Processor Table
 func NewProcessorTable() *ProcessorTableImpl {
 	return &ProcessorTableImpl{
-		clk:          make([]field.Element, 0),
-		ip:           make([]field.Element, 0),
-		ci:           make([]field.Element, 0),
-		nia:          make([]field.Element, 0),
-		ib0:          make([]field.Element, 0),
-		ib1:          make([]field.Element, 0),
-		ib2:          make([]field.Element, 0),
-		jsp:          make([]field.Element, 0),
-		jso:          make([]field.Element, 0),
-		jsd:          make([]field.Element, 0),
-		st0:          make([]field.Element, 0),
-		st1:          make([]field.Element, 0),
-		st2:          make([]field.Element, 0),
-		st3:          make([]field.Element, 0),
-		st4:          make([]field.Element, 0),
-		st5:          make([]field.Element, 0),
-		st6:          make([]field.Element, 0),
-		st7:          make([]field.Element, 0),
-		st8:          make([]field.Element, 0),
-		st9:          make([]field.Element, 0),
-		st10:         make([]field.Element, 0),
-		st11:         make([]field.Element, 0),
-		st12:         make([]field.Element, 0),
-		st13:         make([]field.Element, 0),
-		st14:         make([]field.Element, 0),
-		st15:         make([]field.Element, 0),
-		permArg:      make([]field.Element, 0),
-		evalArg:      make([]field.Element, 0),
-		permrp:       make([]field.Element, 0),
-		height:       0,
-		paddedHeight: 0,
+		clk:  make([]field.Element, 0),
+		ip:   make([]field.Element, 0),
+		ci:   make([]field.Element, 0),
+		nia:  make([]field.Element, 0),
+		ib0:  make([]field.Element, 0),
+		ib1:  make([]field.Element, 0),
+		ib2:  make([]field.Element, 0),
+		jsp:  make([]field.Element, 0),
+		jso:  make([]field.Element, 0),
+		jsd:  make([]field.Element, 0),
+		st0:  make([]field.Element, 0),
+		st1:  make([]field.Element, 0),
+		st2:  make([]field.Element, 0),
+		st3:  make([]field.Element, 0),
+		st4:  make([]field.Element, 0),
+		st5:  make([]field.Element, 0),
+		st6:  make([]field.Element, 0),
+		st7:  make([]field.Element, 0),
+		st8:  make([]field.Element, 0),
+		st9:  make([]field.Element, 0),
+		st10: make([]field.Element, 0),
+		st11: make([]field.Element, 0),
+		st12: make([]field.Element, 0),
+		st13: make([]field.Element, 0),
+		st14: make([]field.Element, 0),
+		st15: make([]field.Element, 0),
+
+		cjdMultiplicity:   make([]field.Element, 0),
+		osp:               make([]field.Element, 0),
+		occupied:          [16][]field.Element{},
+		selectors:         [numProcessorInstructions][]field.Element{},
+		argumentSelectors: [numArgumentSelectors][]field.Element{},
+		helpers:           [numProcessorHelpers][]field.Element{},
+
+		instructionSizeMultiplicity: make([]field.Element, 0),
+		underflowSize:               make([]field.Element, 0),
+		sponge:                      [PoseidonStateSize][]field.Element{},
+		spongeInitialized:           make([]field.Element, 0),
+		permArg:                     make([]xfield.XFieldElement, 0),
+		evalArg:                     make([]xfield.XFieldElement, 0),
+		clockJumpDiffLookup:         make([]xfield.XFieldElement, 0),
+		instructionSizes:            make([]xfield.XFieldElement, 0),
+		permrp:                      make([]xfield.XFieldElement, 0),
+		height:                      0,
+		paddedHeight:                0,
 	}
 }
 
@@ -87,7 +130,7 @@ func (pt *ProcessorTableImpl) GetPaddedHeight() int {
 
 // GetMainColumns returns all main columns
 func (pt *ProcessorTableImpl) GetMainColumns() [][]field.Element {
-	return [][]field.Element{
+	columns := [][]field.Element{
 		pt.clk, pt.ip, pt.ci, pt.nia,
 		pt.ib0, pt.ib1, pt.ib2,
 		pt.jsp, pt.jso, pt.jsd,
@@ -95,29 +138,31 @@ func (pt *ProcessorTableImpl) GetMainColumns() [][]field.Element {
 		pt.st4, pt.st5, pt.st6, pt.st7,
 		pt.st8, pt.st9, pt.st10, pt.st11,
 		pt.st12, pt.st13, pt.st14, pt.st15,
+		pt.cjdMultiplicity,
+		pt.osp,
 	}
+	columns = append(columns, pt.occupied[:]...)
+	columns = append(columns, pt.selectors[:]...)
+	columns = append(columns, pt.argumentSelectors[:]...)
+	columns = append(columns, pt.helpers[:]...)
+	columns = append(columns, pt.instructionSizeMultiplicity, pt.underflowSize)
+	columns = append(columns, pt.sponge[:]...)
+	return append(columns, pt.spongeInitialized)
 }
 
 // GetAuxiliaryColumns returns auxiliary columns
-func (pt *ProcessorTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
+func (pt *ProcessorTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	columns := [][]xfield.XFieldElement{
 		pt.permArg,
 		pt.evalArg,
+		pt.clockJumpDiffLookup,
+		pt.instructionSizes,
 		pt.permrp,
+		pt.instructionLookup,
+		pt.inputEval,
+		pt.outputEval,
 	}
-}
-
-// GetColumns returns all columns (main + auxiliary)
-func (pt *ProcessorTableImpl) GetColumns() ([][]field.Element, error) {
-	mainCols := pt.GetMainColumns()
-	auxCols := pt.GetAuxiliaryColumns()
-
-	// Combine all columns
-	allCols := make([][]field.Element, 0, len(mainCols)+len(auxCols))
-	allCols = append(allCols, mainCols...)
-	allCols = append(allCols, auxCols...)
-
-	return allCols, nil
+	return append(columns, pt.linkSlots[:]...)
 }
 
 // AddRow adds a new row to the processor table
@@ -125,6 +170,18 @@ func (pt *ProcessorTableImpl) AddRow(state *ProcessorState) error {
 	if state == nil {
 		return fmt.Errorf("processor state cannot be nil")
 	}
+	inst := Instruction(state.CurrentInstruction.Value())
+	selector, ok := processorInstructionIndex[inst]
+	if !ok {
+		return fmt.Errorf("unknown instruction %d", state.CurrentInstruction.Value())
+	}
+	argument := -1
+	if lo, hi, ok := indexArgumentRange(inst); ok {
+		argument = int(state.NextInstructionOrArg.Value())
+		if argument < lo || argument > hi {
+			return fmt.Errorf("%s argument %d is outside [%d, %d]", inst, argument, lo, hi)
+		}
+	}
 
 	// Add main column values
 	pt.clk = append(pt.clk, state.Clock)
@@ -159,10 +216,44 @@ func (pt *ProcessorTableImpl) AddRow(state *ProcessorState) error {
 	pt.st14 = append(pt.st14, state.Stack[14])
 	pt.st15 = append(pt.st15, state.Stack[15])
 
+	// Clock jump differences are only known once the Jump Stack Table is filled
+	pt.cjdMultiplicity = append(pt.cjdMultiplicity, field.Zero)
+
+	pt.osp = append(pt.osp, state.OpStackPointer)
+	for j := range pt.occupied {
+		pt.occupied[j] = append(pt.occupied[j], boolToElement(uint64(j) < state.OpStackPointer.Value()))
+	}
+	for k := range pt.selectors {
+		pt.selectors[k] = append(pt.selectors[k], boolToElement(k == selector))
+	}
+	for k := range pt.argumentSelectors {
+		pt.argumentSelectors[k] = append(pt.argumentSelectors[k], boolToElement(k == argument))
+	}
+	for k, value := range processorHelpers(state) {
+		pt.helpers[k] = append(pt.helpers[k], value)
+	}
+	pt.instructionSizeMultiplicity = append(pt.instructionSizeMultiplicity, field.Zero)
+
+	underflowSize := uint64(0)
+	if osp := state.OpStackPointer.Value(); osp > 16 {
+		underflowSize = osp - 16
+	}
+	pt.underflowSize = append(pt.underflowSize, field.New(underflowSize))
+	for i := range pt.sponge {
+		value := field.Zero
+		if i < len(state.Sponge) {
+			value = state.Sponge[i]
+		}
+		pt.sponge[i] = append(pt.sponge[i], value)
+	}
+	pt.spongeInitialized = append(pt.spongeInitialized, boolToElement(state.SpongeInitialized))
+
 	// Initialize auxiliary columns (will be computed during proving)
-	pt.permArg = append(pt.permArg, field.Zero)
-	pt.evalArg = append(pt.evalArg, field.Zero)
-	pt.permrp = append(pt.permrp, field.One) // TIP-0007: Start running product at 1
+	pt.permArg = append(pt.permArg, xfield.Zero)
+	pt.evalArg = append(pt.evalArg, xfield.Zero)
+	pt.clockJumpDiffLookup = append(pt.clockJumpDiffLookup, xfield.Zero)
+	pt.instructionSizes = append(pt.instructionSizes, xfield.Zero)
+	pt.permrp = append(pt.permrp, xfield.Zero)
 
 	pt.height++
 	return nil
@@ -178,13 +269,14 @@ func (pt *ProcessorTableImpl) Pad(targetHeight int) error {
 		return fmt.Errorf("cannot pad empty table")
 	}
 
-	// Pad with copies of the last row
+	// Pad with copies of the last row, except for the clock which keeps
+	// counting so that clk' = clk + 1 holds across padding rows too
 	lastIdx := pt.height - 1
 	paddingRows := targetHeight - pt.height
 
 	for i := 0; i < paddingRows; i++ {
 		// Clone last row
-		pt.clk = append(pt.clk, pt.clk[lastIdx])
+		pt.clk = append(pt.clk, pt.clk[len(pt.clk)-1].Add(field.One))
 		pt.ip = append(pt.ip, pt.ip[lastIdx])
 		pt.ci = append(pt.ci, pt.ci[lastIdx])
 		pt.nia = append(pt.nia, pt.nia[lastIdx])
@@ -210,92 +302,576 @@ func (pt *ProcessorTableImpl) Pad(targetHeight int) error {
 		pt.st13 = append(pt.st13, pt.st13[lastIdx])
 		pt.st14 = append(pt.st14, pt.st14[lastIdx])
 		pt.st15 = append(pt.st15, pt.st15[lastIdx])
+		pt.cjdMultiplicity = append(pt.cjdMultiplicity, field.Zero)
+		pt.osp = append(pt.osp, pt.osp[lastIdx])
+		for j := range pt.occupied {
+			pt.occupied[j] = append(pt.occupied[j], pt.occupied[j][lastIdx])
+		}
+		for k := range pt.selectors {
+			pt.selectors[k] = append(pt.selectors[k], pt.selectors[k][lastIdx])
+		}
+		for k := range pt.argumentSelectors {
+			pt.argumentSelectors[k] = append(pt.argumentSelectors[k], pt.argumentSelectors[k][lastIdx])
+		}
+		for k := range pt.helpers {
+			pt.helpers[k] = append(pt.helpers[k], pt.helpers[k][lastIdx])
+		}
+		pt.instructionSizeMultiplicity = append(pt.instructionSizeMultiplicity, field.Zero)
+		pt.underflowSize = append(pt.underflowSize, pt.underflowSize[lastIdx])
+		for k := range pt.sponge {
+			pt.sponge[k] = append(pt.sponge[k], pt.sponge[k][lastIdx])
+		}
+		pt.spongeInitialized = append(pt.spongeInitialized, pt.spongeInitialized[lastIdx])
 		pt.permArg = append(pt.permArg, pt.permArg[lastIdx])
 		pt.evalArg = append(pt.evalArg, pt.evalArg[lastIdx])
-
-		// TIP-0007: Pad permrp with last value (maintains running product)
-		if len(pt.permrp) > 0 {
-			pt.permrp = append(pt.permrp, pt.permrp[lastIdx])
-		}
+		pt.clockJumpDiffLookup = append(pt.clockJumpDiffLookup, pt.clockJumpDiffLookup[lastIdx])
+		pt.instructionSizes = append(pt.instructionSizes, pt.instructionSizes[lastIdx])
+		pt.permrp = append(pt.permrp, pt.permrp[lastIdx])
 	}
 
 	pt.paddedHeight = targetHeight
+	return pt.countInstructionSizeLookups()
+}
+
+// countInstructionSizeLookups sets the instruction size multiplicities: row
+// k of the padded table lists instruction k, whose size every skiz looks up
+// for the instruction it may skip
+func (pt *ProcessorTableImpl) countInstructionSizeLookups() error {
+	if len(pt.clk) < instructionTablePeriod {
+		return fmt.Errorf("padded height %d is below the instruction table's %d rows", len(pt.clk), instructionTablePeriod)
+	}
+	for i := range pt.instructionSizeMultiplicity {
+		pt.instructionSizeMultiplicity[i] = field.Zero
+	}
+	for i, ci := range pt.ci {
+		if Instruction(ci.Value()) != Skiz {
+			continue
+		}
+		k, ok := processorInstructionIndex[Instruction(pt.nia[i].Value())]
+		if !ok {
+			return fmt.Errorf("skiz in row %d is followed by unknown instruction %d", i, pt.nia[i].Value())
+		}
+		pt.instructionSizeMultiplicity[k] = pt.instructionSizeMultiplicity[k].Add(field.One)
+	}
 	return nil
 }
 
-// CreateInitialConstraints generates constraints for the first row
-func (pt *ProcessorTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
+// Processor Table main column indices, in GetMainColumns order
+const (
+	processorClk = iota
+	processorIP
+	processorCI
+	processorNIA
+	processorIB0
+	processorIB1
+	processorIB2
+	processorJSP
+	processorJSO
+	processorJSD
+	processorST0
+
+	// The clock jump difference multiplicity follows the 16 stack registers
+	processorCJDMultiplicity = processorST0 + 16
+)
+
+// Instruction decoding columns, following the clock jump difference
+// multiplicity
+const (
+	processorOSP = processorCJDMultiplicity + 1 + iota
+	processorOccupied0
+	processorSelector0                   = processorOccupied0 + 16
+	processorArgumentSelector0           = processorSelector0 + numProcessorInstructions
+	processorHelper0                     = processorArgumentSelector0 + numArgumentSelectors
+	processorInstructionSizeMultiplicity = processorHelper0 + numProcessorHelpers
+	processorUnderflowSize               = processorInstructionSizeMultiplicity + 1
+	processorSponge0                     = processorUnderflowSize + 1
+	processorSpongeInitialized           = processorSponge0 + PoseidonStateSize
+)
+
+// processorSelector returns the selector column of inst
+func processorSelector(inst Instruction) int {
+	return processorSelector0 + processorInstructionIndex[inst]
+}
 
+// CreateInitialConstraints generates constraints for the first row
+func (pt *ProcessorTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Initial constraints for Processor Table:
-	// - clk[0] = 0 (clock starts at zero)
-	// - ip[0] = 0 (instruction pointer starts at zero)
-	// - jsp[0] = 0 (jump stack pointer starts at zero)
-	// - st0..st15[0] = 0 (all stack registers start at zero)
+	// - clk, ip, jsp, jso and jsd start at zero
+	// - the stack holds five elements, st0..st4, and st5..st15 are zero
+	// - no element is below st15, the sponge state is zero and sponge_init
+	//   has not run
 	//
-	// Note: Actual polynomial representations are computed during proof generation
-	// when we have the full trace and proper evaluation domains.
-	//
-	// These constraints ensure the processor starts in a clean initial state.
-
+	// The initial stack holds the program digest (TIP-0006), which is bound
+	// by the claim rather than by a constant.
+	constraints := make([]*protocols.ConstraintPolynomial, 0, 58)
+	for _, register := range []struct {
+		name string
+		col  int
+	}{
+		{"clk", processorClk},
+		{"ip", processorIP},
+		{"jsp", processorJSP},
+		{"jso", processorJSO},
+		{"jsd", processorJSD},
+		{"underflow_size", processorUnderflowSize},
+		{"sponge_initialized", processorSpongeInitialized},
+	} {
+		col := register.col
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:      "processor_" + register.name + "_starts_at_0",
+			Degree:    1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement { return row[col] },
+		})
+	}
+	constraints = append(constraints, &protocols.ConstraintPolynomial{
+		Name:   "processor_osp_starts_at_5",
+		Degree: 1,
+		Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+			return row[processorOSP].SubConst(field.New(5))
+		},
+	})
+	for j := 0; j < 16; j++ {
+		j, occupied := j, boolToElement(j < 5)
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("processor_occupied%d_starts_at_%d", j, occupied.Value()),
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[processorOccupied0+j].SubConst(occupied)
+			},
+		})
+		if j >= 5 {
+			constraints = append(constraints, &protocols.ConstraintPolynomial{
+				Name:      fmt.Sprintf("processor_st%d_starts_at_0", j),
+				Degree:    1,
+				Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement { return row[processorST0+j] },
+			})
+		}
+	}
+	for i := 0; i < PoseidonStateSize; i++ {
+		col := processorSponge0 + i
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:      fmt.Sprintf("processor_sponge%d_starts_at_0", i),
+			Degree:    1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement { return row[col] },
+		})
+	}
 	return constraints, nil
 }
 
 // CreateConsistencyConstraints generates constraints within each row
-func (pt *ProcessorTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (pt *ProcessorTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Consistency constraints for Processor Table:
 	//
-	// 1. Instruction bits are boolean:
-	//    - ib0 * (ib0 - 1) = 0
-	//    - ib1 * (ib1 - 1) = 0
-	//    - ib2 * (ib2 - 1) = 0
+	// Instruction bits are boolean:
+	//    ib_i * (ib_i - 1) = 0
+	//
+	// The instruction selectors are bits, exactly one of which is set, and
+	// they decode ci and its lowest three bits:
+	//    Σ sel = 1,  ci = Σ opcode·sel,  ib_k = Σ bit_k(opcode)·sel
 	//
-	// 2. Current instruction encoding matches bits:
-	//    - ci = ib0 + 2*ib1 + 4*ib2 + ... (instruction decoding)
+	// For an instruction with an index argument, exactly one argument
+	// selector is set, which decodes nia and lies in the instruction's
+	// argument range; otherwise none is set.
 	//
-	// Note: Polynomial representations computed during proof generation.
+	// The occupied registers are the topmost ones, osp counts them and the
+	// elements below st15, and only a full st0..st15 has elements below it:
+	//    o_{j+1}·(1 - o_j) = 0,  osp = u + Σ o_j,  u·(1 - o_15) = 0
+	constraints := make([]*protocols.ConstraintPolynomial, 0, 30)
+	for i, col := range []int{processorIB0, processorIB1, processorIB2} {
+		col := col
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:      fmt.Sprintf("processor_ib%d_is_bit", i),
+			Degree:    2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement { return isBit(row[col]) },
+		})
+	}
+	for j := 0; j < 15; j++ {
+		col := processorOccupied0 + j
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("processor_occupied%d_needs_occupied%d", j+1, j),
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[col+1].Mul(xfield.One.Sub(row[col]))
+			},
+		})
+	}
+	constraints = append(constraints,
+		&protocols.ConstraintPolynomial{
+			Name:   "processor_osp_counts_stack_elements",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				count := row[processorUnderflowSize]
+				for j := 0; j < 16; j++ {
+					count = count.Add(row[processorOccupied0+j])
+				}
+				return row[processorOSP].Sub(count)
+			},
+		},
+		&protocols.ConstraintPolynomial{
+			Name:   "processor_underflow_needs_full_registers",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[processorUnderflowSize].Mul(xfield.One.Sub(row[processorOccupied0+15]))
+			},
+		})
+	return append(constraints, instructionDecodingConstraints()...), nil
+}
 
-	return constraints, nil
+// instructionDecodingConstraints returns the consistency constraints of the
+// instruction and argument selectors
+func instructionDecodingConstraints() []*protocols.ConstraintPolynomial {
+	constraints := make([]*protocols.ConstraintPolynomial, 0, numProcessorInstructions+numArgumentSelectors+8)
+	for k, inst := range processorInstructions {
+		col := processorSelector0 + k
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:      "processor_selects_" + inst.String() + "_is_bit",
+			Degree:    2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement { return isBit(row[col]) },
+		})
+	}
+	for k := 0; k < numArgumentSelectors; k++ {
+		col := processorArgumentSelector0 + k
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:      fmt.Sprintf("processor_selects_argument_%d_is_bit", k),
+			Degree:    2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement { return isBit(row[col]) },
+		})
+	}
+
+	// selectedSum returns Σ weight(inst)·sel_inst
+	selectedSum := func(row []xfield.XFieldElement, weight func(inst Instruction) uint64) xfield.XFieldElement {
+		sum := xfield.Zero
+		for k, inst := range processorInstructions {
+			if w := weight(inst); w != 0 {
+				sum = sum.Add(row[processorSelector0+k].MulConst(field.New(w)))
+			}
+		}
+		return sum
+	}
+	hasIndexArgument := func(row []xfield.XFieldElement) xfield.XFieldElement {
+		return selectedSum(row, func(inst Instruction) uint64 {
+			if _, _, ok := indexArgumentRange(inst); ok {
+				return 1
+			}
+			return 0
+		})
+	}
+
+	constraints = append(constraints,
+		&protocols.ConstraintPolynomial{
+			Name:   "processor_selects_one_instruction",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return selectedSum(row, func(Instruction) uint64 { return 1 }).Sub(xfield.One)
+			},
+		},
+		&protocols.ConstraintPolynomial{
+			Name:   "processor_selectors_decode_ci",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[processorCI].Sub(selectedSum(row, func(inst Instruction) uint64 { return uint64(inst) }))
+			},
+		})
+	for k, col := range []int{processorIB0, processorIB1, processorIB2} {
+		k, col := k, col
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("processor_selectors_decode_ib%d", k),
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[col].Sub(selectedSum(row, func(inst Instruction) uint64 {
+					return uint64(inst.GetInstructionBit(InstructionBit(k)))
+				}))
+			},
+		})
+	}
+	return append(constraints,
+		&protocols.ConstraintPolynomial{
+			Name:   "processor_selects_an_argument_for_index_instructions",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				selected := xfield.Zero
+				for k := 0; k < numArgumentSelectors; k++ {
+					selected = selected.Add(row[processorArgumentSelector0+k])
+				}
+				return selected.Sub(hasIndexArgument(row))
+			},
+		},
+		&protocols.ConstraintPolynomial{
+			Name:   "processor_argument_selectors_decode_nia",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				argument := xfield.Zero
+				for k := 1; k < numArgumentSelectors; k++ {
+					argument = argument.Add(row[processorArgumentSelector0+k].MulConst(field.New(uint64(k))))
+				}
+				return hasIndexArgument(row).Mul(row[processorNIA].Sub(argument))
+			},
+		},
+		&protocols.ConstraintPolynomial{
+			Name:   "processor_argument_is_in_range",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				sum := xfield.Zero
+				for k, inst := range processorInstructions {
+					lo, hi, ok := indexArgumentRange(inst)
+					if !ok {
+						continue
+					}
+					outside := xfield.Zero
+					for arg := 0; arg < numArgumentSelectors; arg++ {
+						if arg < lo || arg > hi {
+							outside = outside.Add(row[processorArgumentSelector0+arg])
+						}
+					}
+					sum = sum.Add(row[processorSelector0+k].Mul(outside))
+				}
+				return sum
+			},
+		})
 }
 
 // CreateTransitionConstraints generates constraints between consecutive rows
-func (pt *ProcessorTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (pt *ProcessorTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
 	// Transition constraints for Processor Table:
 	//
 	// 1. Clock increments: clk' = clk + 1
 	//
-	// 2. Instruction pointer transitions correctly:
-	//    - For sequential instructions: ip' = ip + 1
-	//    - For JUMP: ip' = jump_target
-	//    - For CALL: ip' = call_target, jsp' = jsp + 1, jso = ip
-	//    - For RETURN: ip' = jsd, jsp' = jsp - 1
-	//
-	// 3. Stack register transitions (instruction-dependent):
-	//    - For PUSH: st0' = value, st1'..st15' = shifted
-	//    - For POP: st0'..st14' = st1..st15, st15' = underflow
-	//    - For arithmetic: st0' = st0 op st1, st1'..st15' = st2..st15 + underflow
-	//
-	// Note: Actual constraints are instruction-specific and computed during
-	// proof generation with proper instruction decoding.
-
-	return constraints, nil
+	// 2. The selected instruction changes ip, the jump stack registers, osp
+	//    and the stack registers as the VM does, and its operands satisfy
+	//    its rules (see processor_transitions.go). The values the
+	//    instruction reads from memory, input or a coprocessor are bound
+	//    by the corresponding table.
+	constraints := []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "processor_clk_increments",
+			Degree: 1,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return next[processorClk].Sub(current[processorClk]).Sub(xfield.One)
+			},
+		},
+	}
+	return append(constraints, instructionTransitionConstraints()...), nil
 }
 
 // CreateTerminalConstraints generates constraints for the last row
-func (pt *ProcessorTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
+func (pt *ProcessorTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// Execution ends at halt, whose row pads the table
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "processor_halts",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return xfield.One.Sub(row[processorSelector(Halt)])
+			},
+		},
+	}, nil
+}
 
-	// Terminal constraint for Processor Table:
-	// - Final instruction must be HALT (ci[height-1] = HALT_OPCODE)
-	//
-	// This ensures the program terminates properly.
-	// Note: Polynomial representation computed during proof generation.
+// endsFrame returns 1 if the instruction of row i returns from its frame:
+// return, or recurse_or_return with jsp = 1
+func (pt *ProcessorTableImpl) endsFrame(i int) field.Element {
+	switch Instruction(pt.ci[i].Value()) {
+	case Return:
+		return field.One
+	case RecurseOrReturn:
+		return boolToElement(pt.jsp[i].Equal(field.One))
+	}
+	return field.Zero
+}
 
-	return constraints, nil
+// UpdateJumpStackPermutationArgument computes the running product of the
+// permutation argument with the Jump Stack Table
+//
+// Every row, padding included, contributes its compressed jump stack
+// registers, so the terminal equals the Jump Stack Table's.
+func (pt *ProcessorTableImpl) UpdateJumpStackPermutationArgument(challenges map[string]xfield.XFieldElement) error {
+	if pt.height == 0 {
+		return fmt.Errorf("cannot update permutation argument on empty table")
+	}
+	weights, err := jumpStackWeights(challenges)
+	if err != nil {
+		return err
+	}
+
+	runningProduct := xfield.One
+	for i := range pt.clk {
+		compressedRow := weights.compress(pt.clk[i], pt.ci[i], pt.jsp[i], pt.jso[i], pt.jsd[i], pt.endsFrame(i))
+		runningProduct = runningProduct.Mul(weights.indeterminate.Sub(compressedRow))
+		pt.permArg[i] = runningProduct
+	}
+
+	return nil
+}
+
+// UpdateClockJumpDifferenceLookup computes the log derivative of the server
+// side of the clock jump difference lookup: row i adds
+// cjdMultiplicity[i]/(indeterminate - clk[i])
+func (pt *ProcessorTableImpl) UpdateClockJumpDifferenceLookup(indeterminate xfield.XFieldElement) error {
+	if pt.height == 0 {
+		return fmt.Errorf("cannot update clock jump difference lookup on empty table")
+	}
+
+	denominators := make([]xfield.XFieldElement, len(pt.clk))
+	for i, clk := range pt.clk {
+		denominators[i] = indeterminate.SubConst(clk)
+	}
+	inverses, err := protocols.BatchInverse(denominators)
+	if err != nil {
+		return fmt.Errorf("clock equals the indeterminate: %w", err)
+	}
+
+	logDerivative := xfield.Zero
+	for i := range pt.clk {
+		logDerivative = logDerivative.Add(inverses[i].MulConst(pt.cjdMultiplicity[i]))
+		pt.clockJumpDiffLookup[i] = logDerivative
+	}
+
+	return nil
+}
+
+// UpdateInstructionSizeLookup computes the log derivative of skiz's lookup
+// of the next instruction's size: row i adds 1/(γ - c_i) if it is a skiz,
+// where c_i compresses (nia, hv1), and subtracts m_i/(γ - s_i), where s_i
+// compresses the opcode and size the instruction table lists in row i
+func (pt *ProcessorTableImpl) UpdateInstructionSizeLookup(challenges map[string]xfield.XFieldElement) error {
+	if pt.height == 0 {
+		return fmt.Errorf("cannot update instruction size lookup on empty table")
+	}
+	weights, err := instructionSizeWeights(challenges)
+	if err != nil {
+		return err
+	}
+
+	table := pt.GetPeriodicColumns()
+	opcodes, sizes := table[0].Values, table[1].Values
+	denominators := make([]xfield.XFieldElement, 0, 2*len(pt.clk))
+	for i := range pt.clk {
+		k := i % instructionTablePeriod
+		denominators = append(denominators,
+			weights.indeterminate.Sub(weights.compress(pt.nia[i], pt.helpers[1][i])),
+			weights.indeterminate.Sub(weights.compress(opcodes[k], sizes[k])))
+	}
+	inverses, err := protocols.BatchInverse(denominators)
+	if err != nil {
+		return fmt.Errorf("instruction compresses to the indeterminate: %w", err)
+	}
+
+	skiz := pt.selectors[processorInstructionIndex[Skiz]]
+	logDerivative := xfield.Zero
+	for i := range pt.clk {
+		looksUp, listed := inverses[2*i], inverses[2*i+1]
+		logDerivative = logDerivative.Add(looksUp.MulConst(skiz[i])).Sub(listed.MulConst(pt.instructionSizeMultiplicity[i]))
+		pt.instructionSizes[i] = logDerivative
+	}
+
+	return nil
+}
+
+// GetPeriodicColumns returns the instruction table skiz looks sizes up in:
+// every instruction's opcode and size in selector order, followed by halt
+// up to the table's period
+func (pt *ProcessorTableImpl) GetPeriodicColumns() []*protocols.PeriodicColumn {
+	opcodes := make([]field.Element, instructionTablePeriod)
+	sizes := make([]field.Element, instructionTablePeriod)
+	for k := range opcodes {
+		inst := Halt
+		if k < numProcessorInstructions {
+			inst = processorInstructions[k]
+		}
+		opcodes[k] = field.New(uint64(inst))
+		sizes[k] = field.New(uint64(inst.Size()))
+	}
+	return []*protocols.PeriodicColumn{
+		{Name: "instruction_opcode", Values: opcodes},
+		{Name: "instruction_size", Values: sizes},
+	}
+}
+
+// UpdatePermutationRunningProduct computes permrp, the running product of
+// the run-time permutation check (TIP-0007)
+//
+// permrp starts at 1. push_perm multiplies it by (α - p) and pop_perm
+// divides it by (α - p), where p = Σ a_i·st_i is the inner product of the
+// top five stack elements with the weights a_i. Every row holds permrp
+// before its instruction, so it is 1 in every assert_perm row if the pushed
+// and popped tuples are the same multiset.
+func (pt *ProcessorTableImpl) UpdatePermutationRunningProduct(challenges map[string]xfield.XFieldElement) error {
+	if pt.height == 0 {
+		return fmt.Errorf("cannot update permutation running product on empty table")
+	}
+	weights, err := permutationWeights(challenges)
+	if err != nil {
+		return err
+	}
+
+	stack := pt.GetMainColumns()[processorST0 : processorST0+5]
+	isPushPerm := pt.selectors[processorInstructionIndex[PushPerm]]
+	isPopPerm := pt.selectors[processorInstructionIndex[PopPerm]]
+	runningProduct := xfield.One
+	for i := range pt.clk {
+		pt.permrp[i] = runningProduct
+
+		var tuple [5]field.Element
+		for j := range tuple {
+			tuple[j] = stack[j][i]
+		}
+		factor := weights.indeterminate.Sub(weights.compress(tuple))
+		switch {
+		case isPushPerm[i].Equal(field.One):
+			runningProduct = runningProduct.Mul(factor)
+		case isPopPerm[i].Equal(field.One):
+			if factor.IsZero() {
+				return fmt.Errorf("pop_perm in row %d: tuple compresses to the indeterminate", i)
+			}
+			runningProduct = runningProduct.Mul(factor.Inverse())
+		}
+	}
+
+	return nil
+}
+
+// UpdateIOEvaluationArguments computes the evaluation arguments of the
+// words read_io reads and write_io writes
+//
+// Every row holds the evaluation before its instruction, starting at 1.
+// read_io n reads st'_{n-1}, ..., st'_0 in that order, and write_io n
+// writes st_{n-1}, ..., st_0:
+//
+//	e' = e·x^n + Σ_{j<n} st_j·x^j
+func (pt *ProcessorTableImpl) UpdateIOEvaluationArguments(challenges map[string]xfield.XFieldElement) error {
+	n := len(pt.clk)
+	if n == 0 {
+		return fmt.Errorf("cannot update I/O evaluation arguments on empty table")
+	}
+	x, err := namedChallenges(challenges, challengeInputIndeterminate, challengeOutputIndeterminate)
+	if err != nil {
+		return err
+	}
+
+	stack := pt.GetMainColumns()[processorST0 : processorST0+16]
+	evaluate := func(e, x xfield.XFieldElement, row, count int) xfield.XFieldElement {
+		for j := count - 1; j >= 0; j-- {
+			e = e.Mul(x).AddConst(stack[j][row])
+		}
+		return e
+	}
+	readIo := pt.selectors[processorInstructionIndex[ReadIo]]
+	writeIo := pt.selectors[processorInstructionIndex[WriteIo]]
+	pt.inputEval = make([]xfield.XFieldElement, n)
+	pt.outputEval = make([]xfield.XFieldElement, n)
+	input, output := xfield.One, xfield.One
+	for i := 0; i < n; i++ {
+		pt.inputEval[i], pt.outputEval[i] = input, output
+		count := int(pt.nia[i].Value())
+		switch {
+		case readIo[i].Equal(field.One) && i+1 < n:
+			input = evaluate(input, x[0], i+1, count)
+		case writeIo[i].Equal(field.One):
+			output = evaluate(output, x[1], i, count)
+		}
+	}
+
+	return nil
 }
 
 // ProcessorState represents the processor state at a single cycle
@@ -310,7 +886,17 @@ type ProcessorState struct {
 	JumpStackPointer     field.Element
 	JumpStackOrigin      field.Element
 	JumpStackDestination field.Element
+	OpStackPointer       field.Element   // Number of stack elements, including those below st15
 	Stack                []field.Element // Must be exactly 16 elements
+
+	// Operands the instruction reads from outside the stack: the sibling
+	// digest of merkle_step and merkle_step_mem, or the words
+	// sponge_absorb_mem reads
+	Operands []field.Element
+
+	// The sponge state, all zero until sponge_init runs
+	Sponge            []field.Element
+	SpongeInitialized bool
 }
 
 // NewProcessorState creates a new processor state with all fields initialized to zero
@@ -331,6 +917,7 @@ func NewProcessorState() *ProcessorState {
 		JumpStackPointer:     field.Zero,
 		JumpStackOrigin:      field.Zero,
 		JumpStackDestination: field.Zero,
+		OpStackPointer:       field.Zero,
 		Stack:                stack,
 	}
 }
@@ -0,0 +1,926 @@
+// Package vm implements the Processor Table's instruction transitions
+package vm
+
+import (
+	"fmt"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
+)
+
+// processorInstructions is the instruction set in opcode order. The
+// Processor Table decodes ci into one selector column per instruction, in
+// this order, and the instruction size lookup lists the instructions in
+// the same order.
+var processorInstructions = [...]Instruction{
+	Halt, Push, Skiz, Pop, Split, Lt, Nop, Divine, Assert, WriteMem,
+	Log2Floor, And, Return, Pick, Hash, WriteIo, DivMod, Xor, Recurse, Place,
+	AssertVector, PopCount, Pow, RecurseOrReturn, Dup, SpongeAbsorb, MerkleStep, SpongeInit, Swap, Add,
+	MerkleStepMem, SpongeAbsorbMem, Call, Mul, SpongeSqueeze, ReadMem, Eq, Invert, AddI, XxAdd,
+	XInvert, ReadIo, XxMul, XxDotStep, XbMul, XbDotStep, PushPerm, PopPerm, AssertPerm,
+}
+
+const (
+	numProcessorInstructions = len(processorInstructions)
+
+	// Index arguments select one of the 16 stack registers, or count up to
+	// five of them
+	numArgumentSelectors = 16
+
+	// Instructions keep up to ten helper values in the row, such as the
+	// inverse that proves a register nonzero or the words sponge_absorb_mem
+	// reads from RAM
+	numProcessorHelpers = 10
+
+	// The instruction size lookup table repeats with this period, which
+	// must be a power of two no smaller than the number of instructions
+	instructionTablePeriod = 64
+)
+
+// processorInstructionIndex maps each instruction to its selector
+var processorInstructionIndex = func() map[Instruction]int {
+	index := make(map[Instruction]int, numProcessorInstructions)
+	for i, inst := range processorInstructions {
+		index[inst] = i
+	}
+	return index
+}()
+
+// indexArgumentRange returns the arguments of an instruction whose argument
+// selects or counts stack registers; ok is false for every other instruction
+func indexArgumentRange(inst Instruction) (lo, hi int, ok bool) {
+	first, last, ok := inst.ArgumentRange()
+	if !ok || last >= numArgumentSelectors {
+		return 0, 0, false
+	}
+	return int(first), int(last), true
+}
+
+// transitionRule is a polynomial in the current and next rows that is zero
+// for a valid transition
+type transitionRule struct {
+	degree int
+	eval   func(current, next []xfield.XFieldElement) xfield.XFieldElement
+}
+
+func newRule(degree int, eval func(current, next []xfield.XFieldElement) xfield.XFieldElement) *transitionRule {
+	return &transitionRule{degree: degree, eval: eval}
+}
+
+// sharedRules holds the rules and lookup tuples many instructions have in
+// common, such as shifting the stack by one, so that combineRules and the
+// lookup slots evaluate each once
+type sharedRules struct {
+	rules  map[string]*transitionRule
+	tuples map[string]*lookupTuple
+}
+
+func newSharedRules() *sharedRules {
+	return &sharedRules{
+		rules:  make(map[string]*transitionRule),
+		tuples: make(map[string]*lookupTuple),
+	}
+}
+
+// get returns the rule with the given key, building it on first use
+func (shared *sharedRules) get(key string, build func() *transitionRule) *transitionRule {
+	rule, ok := shared.rules[key]
+	if !ok {
+		rule = build()
+		shared.rules[key] = rule
+	}
+	return rule
+}
+
+// tuple returns the lookup tuple with the given key, building it on first use
+func (shared *sharedRules) tuple(key string, build func() *lookupTuple) *lookupTuple {
+	tuple, ok := shared.tuples[key]
+	if !ok {
+		tuple = build()
+		shared.tuples[key] = tuple
+	}
+	return tuple
+}
+
+// instructionTransition is how one instruction changes the registers, for
+// one value of its argument if the instruction has an index argument
+type instructionTransition struct {
+	instruction Instruction
+	argument    int // The argument whose selector gates the transition, or -1
+
+	pops, pushes int // Stack elements consumed and produced
+	needs        int // Stack elements that must be present before the instruction
+
+	// moves[j] is the register st'_j comes from, for instructions that
+	// rearrange the stack instead of shifting it
+	moves []int
+
+	// Rules for the control flow registers and the produced stack
+	// elements; nil leaves a register unconstrained
+	ip, jsp, jso, jsd *transitionRule
+	results           []*transitionRule
+
+	// Rules for the sponge state and the flag recording that sponge_init
+	// ran; nil leaves a register to the Hash Table
+	sponge            [PoseidonStateSize]*transitionRule
+	spongeInitialized *transitionRule
+
+	// Further rules the instruction's operands must satisfy
+	rules []*transitionRule
+
+	// The tuples the instruction sends to the coprocessor tables, one per
+	// slot of the link; see processorLinks
+	opStack, ram, hash, u32 []*lookupTuple
+}
+
+// Row accessors for the rules below
+func stackRegister(row []xfield.XFieldElement, j int) xfield.XFieldElement {
+	return row[processorST0+j]
+}
+
+func helperValue(row []xfield.XFieldElement, j int) xfield.XFieldElement {
+	return row[processorHelper0+j]
+}
+
+func constant(value uint64) xfield.XFieldElement {
+	return xfield.NewConst(field.New(value))
+}
+
+// unchanged is the rule reg' = reg
+func (shared *sharedRules) unchanged(col int) *transitionRule {
+	return shared.get(fmt.Sprintf("unchanged %d", col), func() *transitionRule {
+		return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[col].Sub(current[col])
+		})
+	})
+}
+
+// becomes is the rule st'_j = value
+func becomes(j, degree int, value func(current, next []xfield.XFieldElement) xfield.XFieldElement) *transitionRule {
+	return newRule(degree, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+		return stackRegister(next, j).Sub(value(current, next))
+	})
+}
+
+// extensionProduct returns the product of the extension field elements
+// (a0, a1, a2) and (b0, b1, b2) as computed by xx_mul
+func extensionProduct(a, b [3]xfield.XFieldElement) [3]xfield.XFieldElement {
+	return [3]xfield.XFieldElement{
+		a[0].Mul(b[0]).Add(a[1].Mul(b[2])).Add(a[2].Mul(b[1])),
+		a[0].Mul(b[1]).Add(a[1].Mul(b[0])).Add(a[2].Mul(b[2])),
+		a[0].Mul(b[2]).Add(a[1].Mul(b[1])).Add(a[2].Mul(b[0])),
+	}
+}
+
+// extensionOperand returns the extension field element whose coefficients
+// a2, a1, a0 are in st_{top}, st_{top+1}, st_{top+2}
+func extensionOperand(row []xfield.XFieldElement, top int) [3]xfield.XFieldElement {
+	return [3]xfield.XFieldElement{stackRegister(row, top+2), stackRegister(row, top+1), stackRegister(row, top)}
+}
+
+// extensionResult returns the rules that leave the extension field element
+// value on top of the stack, with its coefficient a2 in st'_0
+func extensionResult(degree int, value func(current []xfield.XFieldElement) [3]xfield.XFieldElement) []*transitionRule {
+	results := make([]*transitionRule, 3)
+	for k := range results {
+		k := k
+		results[2-k] = becomes(2-k, degree, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return value(current)[k]
+		})
+	}
+	return results
+}
+
+// newInstructionTransition returns the transition of inst with the given
+// index argument, or -1 if inst has none
+func newInstructionTransition(inst Instruction, arg int, shared *sharedRules) *instructionTransition {
+	t := &instructionTransition{
+		instruction: inst,
+		argument:    arg,
+		jsp:         shared.unchanged(processorJSP),
+		jso:         shared.unchanged(processorJSO),
+		jsd:         shared.unchanged(processorJSD),
+
+		spongeInitialized: shared.unchanged(processorSpongeInitialized),
+	}
+	for i := range t.sponge {
+		t.sponge[i] = shared.unchanged(processorSponge0 + i)
+	}
+	size := constant(uint64(inst.Size()))
+	t.ip = shared.get(fmt.Sprintf("ip advances by %d", inst.Size()), func() *transitionRule {
+		return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorIP].Sub(current[processorIP]).Sub(size)
+		})
+	})
+
+	// jumpsTo returns the rule ip' = target
+	jumpsTo := func(target int) *transitionRule {
+		return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorIP].Sub(current[target])
+		})
+	}
+	// jspChangesBy returns the rule jsp' = jsp + delta
+	jspChangesBy := func(delta int64) *transitionRule {
+		change := xfield.NewConst(field.NewFromInt64(delta))
+		return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorJSP].Sub(current[processorJSP]).Sub(change)
+		})
+	}
+	// jumpStackIsNotEmpty is the rule jsp·hv0 = 1
+	jumpStackIsNotEmpty := newRule(2, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+		return current[processorJSP].Mul(helperValue(current, 0)).Sub(xfield.One)
+	})
+	// spongeIsInitialized is the rule that sponge_init ran before
+	spongeIsInitialized := newRule(1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+		return xfield.One.Sub(current[processorSpongeInitialized])
+	})
+	// isBitRule is the rule hv_j ∈ {0, 1}
+	isBitRule := func(j int) *transitionRule {
+		return newRule(2, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return isBit(helperValue(current, j))
+		})
+	}
+
+	switch inst {
+	case Halt:
+		// Halting repeats the row, which is how the table is padded
+		t.ip = shared.unchanged(processorIP)
+
+	case Nop, AssertPerm:
+
+	case SpongeInit:
+		for i := range t.sponge {
+			col := processorSponge0 + i
+			t.sponge[i] = newRule(1, func(_, next []xfield.XFieldElement) xfield.XFieldElement {
+				return next[col]
+			})
+		}
+		t.spongeInitialized = newRule(1, func(_, next []xfield.XFieldElement) xfield.XFieldElement {
+			return xfield.One.Sub(next[processorSpongeInitialized])
+		})
+
+	case Push:
+		t.pushes = 1
+		t.results = []*transitionRule{becomes(0, 1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return current[processorNIA]
+		})}
+
+	case Pop, WriteIo:
+		t.pops = arg
+
+	case Divine, ReadIo:
+		// Secret and public input are bound by their own arguments
+		t.pushes = arg
+		t.results = make([]*transitionRule, arg)
+
+	case Pick, Dup:
+		t.pushes, t.needs = 1, arg+1
+		t.results = []*transitionRule{becomes(0, 1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(current, arg)
+		})}
+
+	case Place:
+		t.needs = arg + 1
+		t.moves = make([]int, 16)
+		for j := range t.moves {
+			t.moves[j] = j
+		}
+		for j := 0; j < arg; j++ {
+			t.moves[j] = j + 1
+		}
+		t.moves[arg] = 0
+
+	case Swap:
+		t.needs = arg + 1
+		t.moves = make([]int, 16)
+		for j := range t.moves {
+			t.moves[j] = j
+		}
+		t.moves[0], t.moves[arg] = arg, 0
+
+	case Skiz:
+		// With hv0 = 1/st0 if st0 is nonzero, isZero = 1 - st0·hv0 is 1 if
+		// st0 is zero and 0 otherwise. hv1 is the size of the next
+		// instruction, which is looked up by its opcode nia.
+		t.pops = 1
+		isZero := func(current []xfield.XFieldElement) xfield.XFieldElement {
+			return xfield.One.Sub(stackRegister(current, 0).Mul(helperValue(current, 0)))
+		}
+		t.ip = newRule(3, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			skip := isZero(current).Mul(helperValue(current, 1))
+			return next[processorIP].Sub(current[processorIP]).Sub(xfield.One).Sub(skip)
+		})
+		t.rules = []*transitionRule{newRule(3, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(current, 0).Mul(isZero(current))
+		})}
+
+	case Call:
+		t.ip = newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorIP].Sub(current[processorNIA])
+		})
+		t.jsp = jspChangesBy(1)
+		t.jso = newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorJSO].Sub(current[processorIP]).Sub(size)
+		})
+		t.jsd = newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorJSD].Sub(current[processorNIA])
+		})
+
+	case Return:
+		// The caller's frame is restored through the Jump Stack Table
+		t.ip = jumpsTo(processorJSO)
+		t.jsp = jspChangesBy(-1)
+		t.jso, t.jsd = nil, nil
+		t.rules = []*transitionRule{jumpStackIsNotEmpty}
+
+	case Recurse:
+		t.ip = jumpsTo(processorJSD)
+		t.jsp = jspChangesBy(1)
+		t.jso = newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorJSO].Sub(current[processorIP]).Sub(xfield.One)
+		})
+		t.rules = []*transitionRule{jumpStackIsNotEmpty}
+
+	case RecurseOrReturn:
+		// Returns if jsp = 1 and recurses otherwise
+		returns := recurseOrReturnReturns
+		t.ip = newRule(3, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			r := returns(current)
+			destination := r.Mul(current[processorJSO]).Add(xfield.One.Sub(r).Mul(current[processorJSD]))
+			return next[processorIP].Sub(destination)
+		})
+		t.jsp = newRule(2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			change := xfield.One.Sub(returns(current).Add(returns(current)))
+			return next[processorJSP].Sub(current[processorJSP]).Sub(change)
+		})
+		t.jso = newRule(3, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			recurses := xfield.One.Sub(returns(current))
+			return recurses.Mul(next[processorJSO].Sub(current[processorIP]).Sub(xfield.One))
+		})
+		t.jsd = newRule(3, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			recurses := xfield.One.Sub(returns(current))
+			return recurses.Mul(next[processorJSD].Sub(current[processorJSD]))
+		})
+		t.rules = []*transitionRule{
+			newRule(3, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+				return current[processorJSP].Sub(xfield.One).Mul(returns(current))
+			}),
+			newRule(2, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+				return current[processorJSP].Mul(helperValue(current, 1)).Sub(xfield.One)
+			}),
+		}
+
+	case Assert:
+		t.pops = 1
+		t.rules = []*transitionRule{newRule(1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(current, 0).Sub(xfield.One)
+		})}
+
+	case ReadMem:
+		// The values read are bound by the RAM Table
+		t.pops, t.pushes = 1, arg
+		t.results = make([]*transitionRule, arg)
+		for k := 0; k < arg; k++ {
+			t.ram = append(t.ram, shared.tuple(fmt.Sprintf("read st0+%d into st'%d", k, arg-1-k), func() *lookupTuple {
+				return ramTuple(RAMInstructionRead, 0, k, nextRegister(arg-1-k))
+			}))
+		}
+
+	case WriteMem:
+		t.pops = arg + 1
+		for k := 0; k < arg; k++ {
+			t.ram = append(t.ram, shared.tuple(fmt.Sprintf("write st%d into st%d+%d", arg-1-k, arg, k), func() *lookupTuple {
+				return ramTuple(RAMInstructionWrite, arg, k, currentRegister(arg-1-k))
+			}))
+		}
+
+	case Hash:
+		t.pops, t.pushes = 10, 5
+		t.results = make([]*transitionRule, 5)
+		t.hash = []*lookupTuple{hashTuple(1, false, func(current, next []xfield.XFieldElement) (in, out []xfield.XFieldElement) {
+			in = make([]xfield.XFieldElement, PoseidonRate)
+			out = make([]xfield.XFieldElement, PoseidonDigestLen)
+			for i := range in {
+				in[i] = stackRegister(current, 9-i)
+			}
+			for j := range out {
+				out[j] = stackRegister(next, 4-j)
+			}
+			return in, out
+		})}
+
+	case AssertVector:
+		t.pops = 10
+		for k := 0; k < 5; k++ {
+			k := k
+			t.rules = append(t.rules, newRule(1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+				return stackRegister(current, k).Sub(stackRegister(current, k+5))
+			}))
+		}
+
+	case SpongeAbsorb:
+		// The sponge state after the permutation is bound by the Hash Table
+		t.pops = 10
+		t.sponge = [PoseidonStateSize]*transitionRule{}
+		t.rules = []*transitionRule{spongeIsInitialized}
+		t.hash = []*lookupTuple{spongeTuple(func(current []xfield.XFieldElement, i int) xfield.XFieldElement {
+			return stackRegister(current, 9-i)
+		})}
+
+	case SpongeAbsorbMem:
+		// hv0..hv9 are the words read from RAM, bound by the RAM Table
+		t.pops = 1
+		t.sponge = [PoseidonStateSize]*transitionRule{}
+		t.rules = []*transitionRule{spongeIsInitialized}
+		t.ram = readsIntoHelpers(PoseidonRate, shared)
+		t.hash = []*lookupTuple{spongeTuple(helperValue)}
+
+	case SpongeSqueeze:
+		// The rate of the permuted sponge state is pushed, its first
+		// element deepest
+		t.pushes = 10
+		t.sponge = [PoseidonStateSize]*transitionRule{}
+		t.results = make([]*transitionRule, 10)
+		for i := 0; i < 10; i++ {
+			col := processorSponge0 + i
+			t.results[9-i] = becomes(9-i, 1, func(_, next []xfield.XFieldElement) xfield.XFieldElement {
+				return next[col]
+			})
+		}
+		t.rules = []*transitionRule{spongeIsInitialized}
+		t.hash = []*lookupTuple{spongeTuple(nil)}
+
+	case Add, Mul:
+		t.pops, t.pushes = 2, 1
+		t.results = []*transitionRule{becomes(0, 2, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			if inst == Add {
+				return stackRegister(current, 0).Add(stackRegister(current, 1))
+			}
+			return stackRegister(current, 0).Mul(stackRegister(current, 1))
+		})}
+
+	case AddI:
+		t.pops, t.pushes = 1, 1
+		t.results = []*transitionRule{becomes(0, 1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(current, 0).Add(current[processorNIA])
+		})}
+
+	case Invert:
+		t.pops, t.pushes = 1, 1
+		t.results = []*transitionRule{newRule(2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(current, 0).Mul(stackRegister(next, 0)).Sub(xfield.One)
+		})}
+
+	case Eq:
+		// With hv0 = 1/(st1 - st0) if the operands differ, the result is
+		// 1 - (st1 - st0)·hv0, and it must be 0 if they differ
+		t.pops, t.pushes = 2, 1
+		t.results = []*transitionRule{newRule(2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			difference := stackRegister(current, 1).Sub(stackRegister(current, 0))
+			return stackRegister(next, 0).Sub(xfield.One).Add(difference.Mul(helperValue(current, 0)))
+		})}
+		t.rules = []*transitionRule{newRule(2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(current, 1).Sub(stackRegister(current, 0)).Mul(stackRegister(next, 0))
+		})}
+
+	case Split:
+		// st0 = hi·2^32 + lo, and lo = 0 if hi = 2^32 - 1, so that the sum
+		// cannot wrap around the modulus. hv0 = 1/(hi - (2^32 - 1)) unless
+		// hi = 2^32 - 1. That hi and lo are u32s is proven by the U32 Table.
+		t.pops, t.pushes = 1, 2
+		t.results = make([]*transitionRule, 2)
+		t.u32 = []*lookupTuple{u32Tuple(Split, nextRegister(0), nextRegister(1), constantElement(0))}
+		t.rules = []*transitionRule{
+			newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return stackRegister(current, 0).Sub(stackRegister(next, 1).Mul(constant(1 << 32))).Sub(stackRegister(next, 0))
+			}),
+			newRule(3, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				hiIsMax := xfield.One.Sub(stackRegister(next, 1).Sub(constant(1<<32 - 1)).Mul(helperValue(current, 0)))
+				return hiIsMax.Mul(stackRegister(next, 0))
+			}),
+		}
+
+	case Lt, And, Xor, Pow:
+		// The results are bound by the U32 Table
+		t.pops, t.pushes = 2, 1
+		t.results = make([]*transitionRule, 1)
+		t.u32 = []*lookupTuple{u32Tuple(inst, currentRegister(1), currentRegister(0), nextRegister(0))}
+
+	case Log2Floor, PopCount:
+		t.pops, t.pushes = 1, 1
+		t.results = make([]*transitionRule, 1)
+		t.u32 = []*lookupTuple{u32Tuple(inst, currentRegister(0), constantElement(0), nextRegister(0))}
+
+	case DivMod:
+		// st1 = q·st0 + r for the quotient q in st'1 and the remainder r in
+		// st'0; that r < st0, and that st1 and q are u32s, is proven by the
+		// U32 Table
+		t.pops, t.pushes = 2, 2
+		t.results = make([]*transitionRule, 2)
+		t.u32 = []*lookupTuple{
+			u32Tuple(Lt, nextRegister(0), currentRegister(0), constantElement(1)),
+			u32Tuple(Split, currentRegister(1), nextRegister(1), constantElement(0)),
+		}
+		t.rules = []*transitionRule{newRule(2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(current, 1).Sub(stackRegister(next, 1).Mul(stackRegister(current, 0))).Sub(stackRegister(next, 0))
+		})}
+
+	case XxAdd:
+		t.pops, t.pushes = 6, 3
+		t.results = extensionResult(1, func(current []xfield.XFieldElement) [3]xfield.XFieldElement {
+			a, b := extensionOperand(current, 3), extensionOperand(current, 0)
+			return [3]xfield.XFieldElement{a[0].Add(b[0]), a[1].Add(b[1]), a[2].Add(b[2])}
+		})
+
+	case XxMul:
+		t.pops, t.pushes = 6, 3
+		t.results = extensionResult(2, func(current []xfield.XFieldElement) [3]xfield.XFieldElement {
+			return extensionProduct(extensionOperand(current, 3), extensionOperand(current, 0))
+		})
+
+	case XInvert:
+		// hv0 is the inverse of the norm a0² + a1² + a2²
+		t.pops, t.pushes = 3, 3
+		t.results = extensionResult(2, func(current []xfield.XFieldElement) [3]xfield.XFieldElement {
+			a, normInverse := extensionOperand(current, 0), helperValue(current, 0)
+			return [3]xfield.XFieldElement{a[0].Mul(normInverse), a[1].Mul(normInverse).Neg(), a[2].Mul(normInverse).Neg()}
+		})
+		t.rules = []*transitionRule{newRule(3, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			a := extensionOperand(current, 0)
+			norm := a[0].Mul(a[0]).Add(a[1].Mul(a[1])).Add(a[2].Mul(a[2]))
+			return norm.Mul(helperValue(current, 0)).Sub(xfield.One)
+		})}
+
+	case XbMul:
+		t.pops, t.pushes = 4, 3
+		t.results = extensionResult(2, func(current []xfield.XFieldElement) [3]xfield.XFieldElement {
+			a, scalar := extensionOperand(current, 1), stackRegister(current, 0)
+			return [3]xfield.XFieldElement{a[0].Mul(scalar), a[1].Mul(scalar), a[2].Mul(scalar)}
+		})
+
+	case XxDotStep:
+		t.pops, t.pushes = 9, 3
+		t.results = extensionResult(2, func(current []xfield.XFieldElement) [3]xfield.XFieldElement {
+			accumulator := extensionOperand(current, 0)
+			product := extensionProduct(extensionOperand(current, 6), extensionOperand(current, 3))
+			return [3]xfield.XFieldElement{accumulator[0].Add(product[0]), accumulator[1].Add(product[1]), accumulator[2].Add(product[2])}
+		})
+
+	case XbDotStep:
+		t.pops, t.pushes = 7, 3
+		t.results = extensionResult(2, func(current []xfield.XFieldElement) [3]xfield.XFieldElement {
+			accumulator, a, scalar := extensionOperand(current, 0), extensionOperand(current, 4), stackRegister(current, 3)
+			return [3]xfield.XFieldElement{
+				accumulator[0].Add(a[0].Mul(scalar)),
+				accumulator[1].Add(a[1].Mul(scalar)),
+				accumulator[2].Add(a[2].Mul(scalar)),
+			}
+		})
+
+	case MerkleStep:
+		// hv0..hv4 are the sibling digest. The node index st5 becomes
+		// st5 / 2, where hv5 is its lowest bit; the parent digest is bound
+		// by the Hash Table
+		t.pops, t.pushes = 6, 6
+		t.results = make([]*transitionRule, 6)
+		t.results[5] = newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(next, 5).Add(stackRegister(next, 5)).Add(helperValue(current, 5)).Sub(stackRegister(current, 5))
+		})
+		t.rules = []*transitionRule{isBitRule(5)}
+		t.hash = []*lookupTuple{merkleStepTuple(4)}
+		t.u32 = []*lookupTuple{u32Tuple(Split, currentRegister(5), nextRegister(5), constantElement(0))}
+
+	case MerkleStepMem:
+		// hv0..hv4 are the sibling digest read from RAM, hv5 is the lowest
+		// bit of the node index st1, and hv6 the rest
+		t.pops, t.pushes = 7, 5
+		t.results = make([]*transitionRule, 5)
+		t.rules = []*transitionRule{
+			isBitRule(5),
+			newRule(1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+				rest := helperValue(current, 6)
+				return stackRegister(current, 1).Sub(rest.Add(rest)).Sub(helperValue(current, 5))
+			}),
+		}
+		t.ram = readsIntoHelpers(PoseidonDigestLen, shared)
+		t.hash = []*lookupTuple{merkleStepTuple(6)}
+		t.u32 = []*lookupTuple{u32Tuple(Split, currentRegister(1), helperElement(6), constantElement(0))}
+
+	case PushPerm, PopPerm:
+		t.pops = 5
+	}
+
+	if t.needs < t.pops {
+		t.needs = t.pops
+	}
+	for k := 0; k < t.pushes-t.pops; k++ {
+		t.opStack = append(t.opStack, opStackTuple(k, true, shared))
+	}
+	for k := 0; k < t.pops-t.pushes; k++ {
+		t.opStack = append(t.opStack, opStackTuple(k, false, shared))
+	}
+	return t
+}
+
+// readsIntoHelpers returns the tuples of reading the n words at st0,
+// st0 + 1, ... into hv0, hv1, ...
+func readsIntoHelpers(n int, shared *sharedRules) []*lookupTuple {
+	tuples := make([]*lookupTuple, n)
+	for k := range tuples {
+		tuples[k] = shared.tuple(fmt.Sprintf("read st0+%d into hv%d", k, k), func() *lookupTuple {
+			return ramTuple(RAMInstructionRead, 0, k, helperElement(k))
+		})
+	}
+	return tuples
+}
+
+// recurseOrReturnReturns returns 1 - (jsp - 1)·hv0 for a recurse_or_return
+// row. With hv0 = 1/(jsp - 1) if jsp ≠ 1, it is 1 exactly when returning.
+func recurseOrReturnReturns(row []xfield.XFieldElement) xfield.XFieldElement {
+	return xfield.One.Sub(row[processorJSP].Sub(xfield.One).Mul(helperValue(row, 0)))
+}
+
+// processorEndsFrame returns 1 if the row's instruction returns from its
+// frame and 0 otherwise; it has degree 3
+func processorEndsFrame(row []xfield.XFieldElement) xfield.XFieldElement {
+	returns := row[processorSelector(RecurseOrReturn)].Mul(recurseOrReturnReturns(row))
+	return row[processorSelector(Return)].Add(returns)
+}
+
+// processorTransitions returns the transitions of every instruction, one
+// per argument for instructions with an index argument
+func processorTransitions(shared *sharedRules) []*instructionTransition {
+	transitions := make([]*instructionTransition, 0, 2*numProcessorInstructions)
+	for _, inst := range processorInstructions {
+		lo, hi, ok := indexArgumentRange(inst)
+		if !ok {
+			transitions = append(transitions, newInstructionTransition(inst, -1, shared))
+			continue
+		}
+		for arg := lo; arg <= hi; arg++ {
+			transitions = append(transitions, newInstructionTransition(inst, arg, shared))
+		}
+	}
+	return transitions
+}
+
+// gatedRule is a rule that applies when its instruction, and its argument
+// if any, are selected
+type gatedRule struct {
+	transition *instructionTransition
+	rule       *transitionRule
+}
+
+// gate is the selector column of an instruction and the selector column of
+// its argument, or -1 if the instruction has no index argument
+type gate struct {
+	selector, argument int
+}
+
+// value returns the instruction's selector, times the argument's selector
+// if there is one
+func (g gate) value(current []xfield.XFieldElement) xfield.XFieldElement {
+	if g.argument < 0 {
+		return current[g.selector]
+	}
+	return current[g.selector].Mul(current[g.argument])
+}
+
+// gate returns the gate of the transition and its degree
+func (t *instructionTransition) gate() (gate, int) {
+	if t.argument < 0 {
+		return gate{processorSelector(t.instruction), -1}, 1
+	}
+	return gate{processorSelector(t.instruction), processorArgumentSelector0 + t.argument}, 2
+}
+
+// combineRules returns the transition constraint Σ gate·rule. Exactly one
+// instruction and at most one argument are selected in every row, so the
+// sum is zero if and only if the selected rule holds. Rules shared by
+// several instructions are evaluated once, times the sum of their gates.
+func combineRules(name string, terms []gatedRule) *protocols.TransitionConstraintPolynomial {
+	type ruleGates struct {
+		rule  *transitionRule
+		gates []gate
+	}
+	var groups []*ruleGates
+	byRule := make(map[*transitionRule]*ruleGates)
+	degree := 0
+	for _, term := range terms {
+		group, ok := byRule[term.rule]
+		if !ok {
+			group = &ruleGates{rule: term.rule}
+			byRule[term.rule] = group
+			groups = append(groups, group)
+		}
+		g, gateDegree := term.transition.gate()
+		group.gates = append(group.gates, g)
+		if term.rule.degree+gateDegree > degree {
+			degree = term.rule.degree + gateDegree
+		}
+	}
+
+	return &protocols.TransitionConstraintPolynomial{
+		Name:   name,
+		Degree: degree,
+		Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			sum := xfield.Zero
+			for _, group := range groups {
+				gates := xfield.Zero
+				for _, g := range group.gates {
+					gates = gates.Add(g.value(current))
+				}
+				if !gates.IsZero() {
+					sum = sum.Add(gates.Mul(group.rule.eval(current, next)))
+				}
+			}
+			return sum
+		},
+	}
+}
+
+// stackTransition returns the rules for st'_j and the register occupancy
+// o'_j of transition t; nil rules leave the register unconstrained
+//
+// Produced registers take the instruction's results and are occupied. The
+// others keep their value, shifted by the change in stack depth. Registers
+// refilled from below st15 are bound by the Op Stack Table.
+func (t *instructionTransition) stackTransition(j int, shared *sharedRules) (value, occupancy *transitionRule) {
+	source := j - t.pushes + t.pops
+	switch {
+	case t.moves != nil:
+		source = t.moves[j]
+	case j < t.pushes:
+		occupied := shared.get(fmt.Sprintf("occupied%d is set", j), func() *transitionRule {
+			return newRule(1, func(_, next []xfield.XFieldElement) xfield.XFieldElement {
+				return xfield.One.Sub(next[processorOccupied0+j])
+			})
+		})
+		return t.results[j], occupied
+	case source > 15:
+		return nil, nil
+	}
+	value = shared.get(fmt.Sprintf("st%d from st%d", j, source), func() *transitionRule {
+		return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return stackRegister(next, j).Sub(stackRegister(current, source))
+		})
+	})
+	occupancy = shared.get(fmt.Sprintf("occupied%d from occupied%d", j, source), func() *transitionRule {
+		return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return next[processorOccupied0+j].Sub(current[processorOccupied0+source])
+		})
+	})
+	return value, occupancy
+}
+
+// underflowTransition returns the rule for the number u of stack elements
+// below st15 when the stack grows by delta
+//
+// Growing by delta moves the occupied ones of st15..st_{16-delta} below st15,
+// and shrinking by -delta refills the occupied ones of st'15..st'_{16+delta}
+// from there:
+//
+//	u' = u + Σ_{k<delta} o_{15-k}  or  u' = u - Σ_{k<-delta} o'_{15-k}
+func underflowTransition(delta int) *transitionRule {
+	return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+		change := xfield.Zero
+		for k := 0; k < delta; k++ {
+			change = change.Add(current[processorOccupied0+15-k])
+		}
+		for k := 0; k < -delta; k++ {
+			change = change.Sub(next[processorOccupied0+15-k])
+		}
+		return next[processorUnderflowSize].Sub(current[processorUnderflowSize]).Sub(change)
+	})
+}
+
+// instructionTransitionConstraints returns the transition constraints of
+// every instruction, combined per register
+func instructionTransitionConstraints() []*protocols.TransitionConstraintPolynomial {
+	shared := newSharedRules()
+	transitions := processorTransitions(shared)
+
+	var ip, jsp, jso, jsd, osp, underflow, needs, spongeInitialized []gatedRule
+	var values, occupancies [16][]gatedRule
+	var sponge [PoseidonStateSize][]gatedRule
+	var rules [][]gatedRule
+	for _, t := range transitions {
+		for _, register := range []struct {
+			terms *[]gatedRule
+			rule  *transitionRule
+		}{{&ip, t.ip}, {&jsp, t.jsp}, {&jso, t.jso}, {&jsd, t.jsd}, {&spongeInitialized, t.spongeInitialized}} {
+			if register.rule != nil {
+				*register.terms = append(*register.terms, gatedRule{t, register.rule})
+			}
+		}
+		for i, rule := range t.sponge {
+			if rule != nil {
+				sponge[i] = append(sponge[i], gatedRule{t, rule})
+			}
+		}
+
+		delta := t.pushes - t.pops
+		osp = append(osp, gatedRule{t, shared.get(fmt.Sprintf("osp changes by %d", delta), func() *transitionRule {
+			depthChange := xfield.NewConst(field.NewFromInt64(int64(delta)))
+			return newRule(1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return next[processorOSP].Sub(current[processorOSP]).Sub(depthChange)
+			})
+		})})
+		underflow = append(underflow, gatedRule{t, shared.get(fmt.Sprintf("underflow changes by %d", delta), func() *transitionRule {
+			return underflowTransition(delta)
+		})})
+
+		if t.needs > 0 {
+			deepest := processorOccupied0 + t.needs - 1
+			needs = append(needs, gatedRule{t, shared.get(fmt.Sprintf("holds %d", t.needs), func() *transitionRule {
+				return newRule(1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+					return xfield.One.Sub(current[deepest])
+				})
+			})})
+		}
+
+		for j := 0; j < 16; j++ {
+			value, occupancy := t.stackTransition(j, shared)
+			if value != nil {
+				values[j] = append(values[j], gatedRule{t, value})
+			}
+			if occupancy != nil {
+				occupancies[j] = append(occupancies[j], gatedRule{t, occupancy})
+			}
+		}
+
+		for k, rule := range t.rules {
+			if k == len(rules) {
+				rules = append(rules, nil)
+			}
+			rules[k] = append(rules[k], gatedRule{t, rule})
+		}
+	}
+
+	constraints := []*protocols.TransitionConstraintPolynomial{
+		combineRules("processor_ip_transition", ip),
+		combineRules("processor_jsp_transition", jsp),
+		combineRules("processor_jso_transition", jso),
+		combineRules("processor_jsd_transition", jsd),
+		combineRules("processor_osp_transition", osp),
+		combineRules("processor_underflow_size_transition", underflow),
+		combineRules("processor_stack_holds_operands", needs),
+	}
+	for j := 0; j < 16; j++ {
+		constraints = append(constraints,
+			combineRules(fmt.Sprintf("processor_st%d_transition", j), values[j]),
+			combineRules(fmt.Sprintf("processor_occupied%d_transition", j), occupancies[j]))
+	}
+	for i := range sponge {
+		constraints = append(constraints, combineRules(fmt.Sprintf("processor_sponge%d_transition", i), sponge[i]))
+	}
+	constraints = append(constraints, combineRules("processor_sponge_initialized_transition", spongeInitialized))
+	for k, terms := range rules {
+		constraints = append(constraints, combineRules(fmt.Sprintf("processor_instruction_rule_%d", k), terms))
+	}
+	return constraints
+}
+
+// processorHelpers returns the helper values of a row, from its current
+// instruction and registers
+func processorHelpers(state *ProcessorState) [numProcessorHelpers]field.Element {
+	var helpers [numProcessorHelpers]field.Element
+	st := state.Stack
+	jsp := state.JumpStackPointer
+	switch Instruction(state.CurrentInstruction.Value()) {
+	case Skiz:
+		helpers[0] = inverseOrZero(st[0])
+		helpers[1] = field.New(uint64(Instruction(state.NextInstructionOrArg.Value()).Size()))
+	case Return, Recurse:
+		helpers[0] = inverseOrZero(jsp)
+	case RecurseOrReturn:
+		helpers[0] = inverseOrZero(jsp.Sub(field.One))
+		helpers[1] = inverseOrZero(jsp)
+	case Eq:
+		helpers[0] = inverseOrZero(st[1].Sub(st[0]))
+	case Split:
+		helpers[0] = inverseOrZero(field.New(st[0].Value() >> 32).Sub(field.New(1<<32 - 1)))
+	case XInvert:
+		norm := st[0].Mul(st[0]).Add(st[1].Mul(st[1])).Add(st[2].Mul(st[2]))
+		helpers[0] = inverseOrZero(norm)
+	case MerkleStep:
+		copy(helpers[:5], state.Operands)
+		helpers[5] = field.New(st[5].Value() & 1)
+	case MerkleStepMem:
+		copy(helpers[:5], state.Operands)
+		helpers[5] = field.New(st[1].Value() & 1)
+		helpers[6] = field.New(st[1].Value() >> 1)
+	case SpongeAbsorbMem:
+		copy(helpers[:], state.Operands)
+	}
+	return helpers
+}
+
+// boolToElement returns 1 for true and 0 for false
+func boolToElement(b bool) field.Element {
+	if b {
+		return field.One
+	}
+	return field.Zero
+}
@@ -0,0 +1,194 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProfileCost counts the rows an execution added to the tables that grow
+// with the program's work
+type ProfileCost struct {
+	Cycles      int // Processor Table rows
+	OpStackRows int
+	RAMRows     int
+	HashRows    int
+	U32Rows     int
+}
+
+// Rows returns the rows counted for a table, zero for the tables whose
+// height does not depend on the code that runs
+func (c ProfileCost) Rows(table TableID) int {
+	switch table {
+	case ProcessorTable, JumpStackTable:
+		return c.Cycles
+	case OperationalStackTable:
+		return c.OpStackRows
+	case RAMTable:
+		return c.RAMRows
+	case HashTable:
+		return c.HashRows
+	case U32Table:
+		return c.U32Rows
+	}
+	return 0
+}
+
+// add returns the sum of two costs
+func (c ProfileCost) add(other ProfileCost) ProfileCost {
+	return ProfileCost{
+		Cycles:      c.Cycles + other.Cycles,
+		OpStackRows: c.OpStackRows + other.OpStackRows,
+		RAMRows:     c.RAMRows + other.RAMRows,
+		HashRows:    c.HashRows + other.HashRows,
+		U32Rows:     c.U32Rows + other.U32Rows,
+	}
+}
+
+// sub returns the difference of two costs
+func (c ProfileCost) sub(other ProfileCost) ProfileCost {
+	return ProfileCost{
+		Cycles:      c.Cycles - other.Cycles,
+		OpStackRows: c.OpStackRows - other.OpStackRows,
+		RAMRows:     c.RAMRows - other.RAMRows,
+		HashRows:    c.HashRows - other.HashRows,
+		U32Rows:     c.U32Rows - other.U32Rows,
+	}
+}
+
+// FunctionProfile is the cost of a function over all of its calls
+type FunctionProfile struct {
+	Name    string // Its label, "main" for the code outside of calls, or "@" and its address
+	Address int    // Word address of its entry
+	Calls   int    // Calls and recursions into it; 1 for main
+
+	// Self counts the rows caused by the function's own instructions, Total
+	// also those of the functions it calls. A recursive function counts
+	// once in its Total.
+	Self  ProfileCost
+	Total ProfileCost
+}
+
+// Profile attributes the rows of an execution trace to the functions that
+// caused them
+type Profile struct {
+	Functions []FunctionProfile // Ordered by address, main first
+
+	// TableHeights holds the height of every table before padding, see
+	// AET.TableHeights
+	TableHeights map[TableID]int
+
+	// PaddedHeight is the height every table is padded to, the next power
+	// of two of the tallest table's height
+	PaddedHeight int
+
+	// TallestTable decides the padded height; the functions with the most
+	// rows in it are the ones to optimize
+	TallestTable TableID
+}
+
+// profiler follows the functions on the jump stack during ExecuteAndTrace
+type profiler struct {
+	functions map[int]*FunctionProfile
+	active    []int       // entries of the called functions, main first
+	last      ProfileCost // row counts before the instruction in flight
+}
+
+// ProfileAndTrace executes the program like ExecuteAndTrace and profiles
+// the execution. labels names functions by their entry address.
+func (vm *VMState) ProfileAndTrace(labels map[string]int) (*AET, *Profile, error) {
+	vm.profiler = &profiler{functions: make(map[int]*FunctionProfile)}
+	defer func() { vm.profiler = nil }()
+
+	names := make(map[int]string, len(labels))
+	for name, address := range labels {
+		if current, ok := names[address]; !ok || name < current {
+			names[address] = name
+		}
+	}
+	vm.profiler.function(0).Calls = 1
+
+	aet, err := vm.ExecuteAndTrace()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile := &Profile{
+		Functions:    make([]FunctionProfile, 0, len(vm.profiler.functions)),
+		TableHeights: aet.TableHeights,
+		PaddedHeight: aet.PaddedHeight,
+		TallestTable: aet.TallestTable(),
+	}
+	for _, function := range vm.profiler.functions {
+		if name, ok := names[function.Address]; ok {
+			function.Name = name
+		}
+		profile.Functions = append(profile.Functions, *function)
+	}
+	sort.Slice(profile.Functions, func(i, j int) bool {
+		return profile.Functions[i].Address < profile.Functions[j].Address
+	})
+	return aet, profile, nil
+}
+
+// function returns the profile of the function at entry, creating it
+func (p *profiler) function(entry int) *FunctionProfile {
+	function, ok := p.functions[entry]
+	if !ok {
+		function = &FunctionProfile{Name: fmt.Sprintf("@%d", entry), Address: entry}
+		if entry == 0 {
+			function.Name = "main"
+		}
+		p.functions[entry] = function
+	}
+	return function
+}
+
+// enter remembers the functions on the jump stack before an instruction
+// executes; it does nothing if the execution is not profiled
+func (p *profiler) enter(vm *VMState) {
+	if p == nil {
+		return
+	}
+	p.active = append(p.active[:0], 0)
+	for _, entry := range vm.JumpStack {
+		p.active = append(p.active, entry.Destination)
+	}
+}
+
+// start takes the rows the recorder holds before the first instruction,
+// such as the permutations hashing the program, as the baseline; it does
+// nothing if the execution is not profiled
+func (p *profiler) start(recorder *TraceRecorder) {
+	if p == nil {
+		return
+	}
+	p.last = recorder.rowCounts()
+}
+
+// leave attributes the rows recorded for the instruction that executed
+// since enter to the functions that were active
+func (p *profiler) leave(vm *VMState, recorder *TraceRecorder) {
+	if p == nil {
+		return
+	}
+	counts := recorder.rowCounts()
+	cost := counts.sub(p.last)
+	p.last = counts
+
+	current := p.function(p.active[len(p.active)-1])
+	current.Self = current.Self.add(cost)
+	counted := make(map[int]bool, len(p.active))
+	for _, entry := range p.active {
+		if !counted[entry] {
+			counted[entry] = true
+			function := p.function(entry)
+			function.Total = function.Total.add(cost)
+		}
+	}
+
+	// The jump stack grew by one if the instruction called a function
+	if len(vm.JumpStack) == len(p.active) {
+		callee := p.function(vm.JumpStack[len(vm.JumpStack)-1].Destination)
+		callee.Calls++
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
-	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
@@ -32,7 +32,7 @@ type ProgramHashTableImpl struct {
 	isSqueezing []field.Element // Boolean: are we squeezing output?
 
 	// Auxiliary columns (XField elements for cross-table arguments)
-	recvChunkEvalArg []field.Element // Receives program chunks from Program Table
+	recvChunkEvalArg []xfield.XFieldElement // Receives program chunks from Program Table
 
 	height       int
 	paddedHeight int
@@ -57,7 +57,7 @@ func NewProgramHashTable() *ProgramHashTableImpl {
 		roundNumber:      make([]field.Element, 0),
 		isAbsorbing:      make([]field.Element, 0),
 		isSqueezing:      make([]field.Element, 0),
-		recvChunkEvalArg: make([]field.Element, 0),
+		recvChunkEvalArg: make([]xfield.XFieldElement, 0),
 		height:           0,
 		paddedHeight:     0,
 		rate:             rate,
@@ -107,8 +107,8 @@ func (pht *ProgramHashTableImpl) GetMainColumns() [][]field.Element {
 }
 
 // GetAuxiliaryColumns returns auxiliary columns
-func (pht *ProgramHashTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
+func (pht *ProgramHashTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{
 		pht.recvChunkEvalArg,
 	}
 }
@@ -138,7 +138,7 @@ func (pht *ProgramHashTableImpl) AddRow(entry *ProgramHashEntry) error {
 	pht.isSqueezing = append(pht.isSqueezing, entry.IsSqueezing)
 
 	// Initialize auxiliary columns (computed during proving)
-	pht.recvChunkEvalArg = append(pht.recvChunkEvalArg, field.Zero)
+	pht.recvChunkEvalArg = append(pht.recvChunkEvalArg, xfield.Zero)
 
 	pht.height++
 	return nil
@@ -166,81 +166,85 @@ func (pht *ProgramHashTableImpl) Pad(targetHeight int) error {
 	return nil
 }
 
+// Program Hash Table main column indices, in GetMainColumns order
+const (
+	programHashState       = 0
+	programHashRoundNumber = programHashState + 16
+	programHashIsAbsorbing = programHashRoundNumber + 1
+	programHashIsSqueezing = programHashIsAbsorbing + 1
+)
+
 // CreateInitialConstraints returns initial boundary constraints
-func (pht *ProgramHashTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
+func (pht *ProgramHashTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// TIP-0006 Initial Constraints:
 	// 1. Capacity registers (state[rate] to state[rate+capacity-1]) are 0
 	// 2. recvChunkEvalArg is initialized with first chunk from Program Table
-
-	constraints := make([]protocols.AIRConstraint, 0)
+	constraints := make([]*protocols.ConstraintPolynomial, 0, pht.width-pht.rate)
 
 	// Capacity registers start at zero
 	for i := pht.rate; i < pht.width; i++ {
-		constraints = append(constraints, protocols.AIRConstraint{
-			Type:   "boundary",
-			Index:  i - pht.rate,
+		column := programHashState + i
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("program_hash_capacity_%d_starts_at_0", i-pht.rate),
 			Degree: 1,
-			// Polynomial: state[i] = 0 (capacity initialization)
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[column]
+			},
 		})
 	}
 
 	return constraints, nil
 }
 
-// CreateConsistencyConstraints returns consistency constraints (no additional ones for Program Hash Table)
-func (pht *ProgramHashTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+// CreateConsistencyConstraints returns consistency constraints
+func (pht *ProgramHashTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// isAbsorbing and isSqueezing are boolean and never both set
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "program_hash_is_absorbing_is_bit",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(row[programHashIsAbsorbing])
+			},
+		},
+		{
+			Name:   "program_hash_is_squeezing_is_bit",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(row[programHashIsSqueezing])
+			},
+		},
+		{
+			Name:   "program_hash_absorbs_or_squeezes",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programHashIsAbsorbing].Mul(row[programHashIsSqueezing])
+			},
+		},
+	}, nil
 }
 
 // CreateTransitionConstraints returns transition constraints
-func (pht *ProgramHashTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
+func (pht *ProgramHashTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
 	// TIP-0006 Transition Constraints:
 	// 1. recvChunkEvalArg accumulates when roundNumber transitions to 1
 	// 2. Capacity registers remain unchanged when roundNumber == 1 in next row
 	// 3. All state registers remain unchanged when roundNumber == 0 in next row
 	// 4. Standard Poseidon round constraints (same as Hash Table)
-
-	constraints := make([]protocols.AIRConstraint, 0)
-
-	// Sponge absorption: when starting new permutation (round 1), accumulate chunk
-	constraints = append(constraints, protocols.AIRConstraint{
-		Type:   "transition",
-		Index:  0,
-		Degree: 3, // Involves multiplication and evaluation
-		// Sponge absorption accumulates chunk when round = 1
-	})
-
-	// Capacity preservation during absorption
-	for i := pht.rate; i < pht.width; i++ {
-		constraints = append(constraints, protocols.AIRConstraint{
-			Type:   "transition",
-			Index:  1 + (i - pht.rate),
-			Degree: 2,
-			// state[i] unchanged during absorption
-		})
-	}
-
-	return constraints, nil
+	//
+	// The trace recorder does not fill the Program Hash Table yet; 2 and 3
+	// need round number indicator columns and 1 is on an auxiliary column,
+	// so none of them is enforced until it does.
+	return []*protocols.TransitionConstraintPolynomial{}, nil
 }
 
 // CreateTerminalConstraints returns terminal boundary constraints
-func (pht *ProgramHashTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
+func (pht *ProgramHashTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// TIP-0006 Terminal Constraints:
 	// The digest (state[0] to state[4]) must match the value copied to
 	// Processor Table's operational stack and standard output
 	// This is enforced via boundary constraint with evaluation argument
-
-	constraints := make([]protocols.AIRConstraint, 0)
-
-	// Digest boundary constraint (enforced via cross-table argument)
-	constraints = append(constraints, protocols.AIRConstraint{
-		Type:   "boundary",
-		Index:  0,
-		Degree: 5, // Polynomial evaluation over 5 digest elements
-		// Program digest matches ProcessorTable OpStack and StandardOutput
-	})
-
-	return constraints, nil
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
 // ComputeProgramDigest computes the Poseidon hash digest of a program
@@ -250,36 +254,7 @@ func (pht *ProgramHashTableImpl) ComputeProgramDigest(program *Program) ([5]fiel
 		return [5]field.Element{}, fmt.Errorf("program cannot be nil")
 	}
 
-	// Encode program instructions as field elements
-	// Each instruction contributes 2 elements: opcode + argument (or zero)
-	programElements := make([]field.Element, 0, len(program.Instructions)*2)
-	for _, instr := range program.Instructions {
-		// Add instruction opcode
-		programElements = append(programElements, field.New(uint64(instr.Instruction)))
-
-		// Add argument if present, otherwise add zero
-		if instr.Argument != nil {
-			programElements = append(programElements, *instr.Argument)
-		} else {
-			programElements = append(programElements, field.Zero)
-		}
-	}
-
-	// Hash the program description using Poseidon
-	digestElement := hash.PoseidonHash(programElements)
-
-	// Create 5-element digest (standard for Tip5/Poseidon in Triton VM)
-	// For now, we use the single hash output in the first position and zeros for the rest
-	// In a full Tip5 implementation, we would squeeze 5 elements from the sponge
-	digest := [5]field.Element{
-		digestElement,
-		field.Zero,
-		field.Zero,
-		field.Zero,
-		field.Zero,
-	}
-
-	return digest, nil
+	return computeProgramDigest(program), nil
 }
 
 // ===========================================================================
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
@@ -12,9 +13,11 @@ import (
 // This table provides program attestation and proves the executed program is correct
 //
 // The program table records:
-// 1. All instructions in the program (address + instruction pairs)
-// 2. Instruction lookup server (for processor to query instructions)
-// 3. Program attestation via hash chunks (Poseidon in our case)
+//  1. All instructions in the program (address + instruction pairs)
+//  2. Instruction lookup server (for processor to query instructions)
+//  3. Program attestation: the words are absorbed into a Poseidon sponge in
+//     chunks of PoseidonRate, whose permutations are looked up in the Hash
+//     Table, so the last row holds the program digest
 //
 // Main purpose: Prove program integrity and provide instruction lookups
 type ProgramTableImpl struct {
@@ -27,20 +30,24 @@ type ProgramTableImpl struct {
 	isHashInputPadding []field.Element // Boolean: is this row hash input padding?
 	isTablePadding     []field.Element // Boolean: is this row table padding?
 
+	// The last PoseidonRate words absorbed, the row's word last, and the
+	// sponge state before the current chunk is absorbed
+	buffer [PoseidonRate][]field.Element
+	sponge [PoseidonStateSize][]field.Element
+
 	// Auxiliary columns (XField elements for cross-table arguments)
-	instrLookupLogDeriv []field.Element // Log derivative for instruction lookup (server side)
-	prepareChunkRunEval []field.Element // Running evaluation for prepare chunk (program attestation)
-	sendChunkRunEval    []field.Element // Running evaluation for send chunk (program attestation)
+	instrLookupLogDeriv []xfield.XFieldElement // Log derivative for instruction lookup (server side)
+	hashLookupLogDeriv  []xfield.XFieldElement // Log derivative of the permutations looked up in the Hash Table
 
 	height       int
 	paddedHeight int
 
-	// Hash chunk rate (for Poseidon, typically 4 or 8 depending on configuration)
+	// Hash chunk rate, the rate of the Poseidon sponge
 	chunkRate int
 }
 
 // NewProgramTable creates a new Program Table
-func NewProgramTable(chunkRate int) *ProgramTableImpl {
+func NewProgramTable() *ProgramTableImpl {
 	return &ProgramTableImpl{
 		address:             make([]field.Element, 0),
 		instruction:         make([]field.Element, 0),
@@ -49,12 +56,13 @@ func NewProgramTable(chunkRate int) *ProgramTableImpl {
 		maxMinusIndexInv:    make([]field.Element, 0),
 		isHashInputPadding:  make([]field.Element, 0),
 		isTablePadding:      make([]field.Element, 0),
-		instrLookupLogDeriv: make([]field.Element, 0),
-		prepareChunkRunEval: make([]field.Element, 0),
-		sendChunkRunEval:    make([]field.Element, 0),
+		buffer:              [PoseidonRate][]field.Element{},
+		sponge:              [PoseidonStateSize][]field.Element{},
+		instrLookupLogDeriv: make([]xfield.XFieldElement, 0),
+		hashLookupLogDeriv:  make([]xfield.XFieldElement, 0),
 		height:              0,
 		paddedHeight:        0,
-		chunkRate:           chunkRate,
+		chunkRate:           PoseidonRate,
 	}
 }
 
@@ -75,7 +83,7 @@ func (pt *ProgramTableImpl) GetPaddedHeight() int {
 
 // GetMainColumns returns all main columns
 func (pt *ProgramTableImpl) GetMainColumns() [][]field.Element {
-	return [][]field.Element{
+	columns := [][]field.Element{
 		pt.address,
 		pt.instruction,
 		pt.lookupMultiplicity,
@@ -84,14 +92,15 @@ func (pt *ProgramTableImpl) GetMainColumns() [][]field.Element {
 		pt.isHashInputPadding,
 		pt.isTablePadding,
 	}
+	columns = append(columns, pt.buffer[:]...)
+	return append(columns, pt.sponge[:]...)
 }
 
 // GetAuxiliaryColumns returns auxiliary columns
-func (pt *ProgramTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
+func (pt *ProgramTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{
 		pt.instrLookupLogDeriv,
-		pt.prepareChunkRunEval,
-		pt.sendChunkRunEval,
+		pt.hashLookupLogDeriv,
 	}
 }
 
@@ -115,17 +124,108 @@ func (pt *ProgramTableImpl) AddRow(entry *ProgramEntry) error {
 	pt.maxMinusIndexInv = append(pt.maxMinusIndexInv, entry.MaxMinusIndexInv)
 	pt.isHashInputPadding = append(pt.isHashInputPadding, entry.IsHashInputPadding)
 	pt.isTablePadding = append(pt.isTablePadding, entry.IsTablePadding)
+	for j := range pt.buffer {
+		pt.buffer[j] = append(pt.buffer[j], entry.Buffer[j])
+	}
+	for j := range pt.sponge {
+		pt.sponge[j] = append(pt.sponge[j], entry.Sponge[j])
+	}
 
 	// Initialize auxiliary columns (computed during proving)
-	pt.instrLookupLogDeriv = append(pt.instrLookupLogDeriv, field.Zero)
-	pt.prepareChunkRunEval = append(pt.prepareChunkRunEval, field.Zero)
-	pt.sendChunkRunEval = append(pt.sendChunkRunEval, field.Zero)
+	pt.instrLookupLogDeriv = append(pt.instrLookupLogDeriv, xfield.Zero)
+	pt.hashLookupLogDeriv = append(pt.hashLookupLogDeriv, xfield.Zero)
 
 	pt.height++
 	return nil
 }
 
+// Fill adds a row for every word of the program, followed by the hash
+// input padding, a one and zeros up to the end of the chunk, and a row
+// holding the program digest
+//
+// Padding rows have no instruction; the first one is one past the last
+// address, so the last word's successor is zero. The lookup multiplicities
+// are counted once the Processor Table is padded; see AET.Pad.
+func (pt *ProgramTableImpl) Fill(program *Program) error {
+	if pt.height != 0 {
+		return fmt.Errorf("program table is already filled")
+	}
+
+	words := program.ToWords()
+	hashed := (len(words)/pt.chunkRate + 1) * pt.chunkRate
+	var entry ProgramEntry
+	for address := 0; address <= hashed; address++ {
+		if address > 0 && pt.absorbs(address-1) {
+			entry.Sponge = pt.permutationInput(address - 1)
+			entry.Sponge = poseidonPermutation(entry.Sponge)
+		}
+		word := field.Zero
+		entry.Instruction, entry.IsTablePadding, entry.IsHashInputPadding = field.Zero, field.One, field.Zero
+		switch {
+		case address < len(words):
+			word, entry.Instruction, entry.IsTablePadding = words[address], words[address], field.Zero
+		case address < hashed:
+			entry.IsHashInputPadding = field.One
+			if address == len(words) {
+				word = field.One
+			}
+		}
+		copy(entry.Buffer[:], entry.Buffer[1:])
+		entry.Buffer[pt.chunkRate-1] = word
+		entry.Address = field.New(uint64(address))
+		pt.setIndexInChunk(&entry, address%pt.chunkRate)
+		if err := pt.AddRow(&entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setIndexInChunk sets the index in chunk of an entry and the inverse that
+// shows whether it ends the chunk
+func (pt *ProgramTableImpl) setIndexInChunk(entry *ProgramEntry, index int) {
+	entry.IndexInChunk = field.New(uint64(index))
+	entry.MaxMinusIndexInv = inverseOrZero(field.New(uint64(pt.chunkRate - 1 - index)))
+}
+
+// absorbs reports whether row i ends a chunk of the program or of its hash
+// input padding, whose permutation the next row's sponge state is
+func (pt *ProgramTableImpl) absorbs(i int) bool {
+	hashing := pt.isTablePadding[i].IsZero() || pt.isHashInputPadding[i].Equal(field.One)
+	return hashing && pt.indexInChunk[i].Value() == uint64(pt.chunkRate-1)
+}
+
+// permutationInput returns the input of the permutation absorbing the chunk
+// that row i ends: its sponge state with the buffered words added to the
+// rate
+func (pt *ProgramTableImpl) permutationInput(i int) [PoseidonStateSize]field.Element {
+	var state [PoseidonStateSize]field.Element
+	for j := range state {
+		state[j] = pt.sponge[j][i]
+		if j < pt.chunkRate {
+			state[j] = state[j].Add(pt.buffer[j][i])
+		}
+	}
+	return state
+}
+
+// permutationInputs returns the inputs of the permutations absorbing the
+// program, which the Hash Table must serve
+func (pt *ProgramTableImpl) permutationInputs() [][PoseidonStateSize]field.Element {
+	var inputs [][PoseidonStateSize]field.Element
+	for i := 0; i < pt.height; i++ {
+		if pt.absorbs(i) {
+			inputs = append(inputs, pt.permutationInput(i))
+		}
+	}
+	return inputs
+}
+
 // Pad pads the table to the target height with padding rows
+//
+// Padding rows repeat the last address and the digest, count the index in
+// chunk on and buffer zeros.
 func (pt *ProgramTableImpl) Pad(targetHeight int) error {
 	if targetHeight < pt.height {
 		return fmt.Errorf("target height %d is less than current height %d", targetHeight, pt.height)
@@ -135,34 +235,45 @@ func (pt *ProgramTableImpl) Pad(targetHeight int) error {
 		return fmt.Errorf("cannot pad empty table")
 	}
 
-	// Padding rows have isTablePadding = 1
-	tablePaddingIndicator := field.One
-
-	// Use last row values for other fields
-	lastIdx := pt.height - 1
-	paddingRows := targetHeight - pt.height
-
-	for i := 0; i < paddingRows; i++ {
-		pt.address = append(pt.address, pt.address[lastIdx])
-		pt.instruction = append(pt.instruction, pt.instruction[lastIdx])
-		pt.lookupMultiplicity = append(pt.lookupMultiplicity, field.Zero) // No lookups in padding
-		pt.indexInChunk = append(pt.indexInChunk, pt.indexInChunk[lastIdx])
-		pt.maxMinusIndexInv = append(pt.maxMinusIndexInv, pt.maxMinusIndexInv[lastIdx])
-		pt.isHashInputPadding = append(pt.isHashInputPadding, pt.isHashInputPadding[lastIdx])
-		pt.isTablePadding = append(pt.isTablePadding, tablePaddingIndicator)
-		pt.instrLookupLogDeriv = append(pt.instrLookupLogDeriv, pt.instrLookupLogDeriv[lastIdx])
-		pt.prepareChunkRunEval = append(pt.prepareChunkRunEval, pt.prepareChunkRunEval[lastIdx])
-		pt.sendChunkRunEval = append(pt.sendChunkRunEval, pt.sendChunkRunEval[lastIdx])
+	for pt.height < targetHeight {
+		last := pt.height - 1
+		entry := ProgramEntry{
+			Address:        pt.address[last],
+			IsTablePadding: field.One,
+		}
+		for j := range entry.Buffer {
+			if j+1 < pt.chunkRate {
+				entry.Buffer[j] = pt.buffer[j+1][last]
+			}
+		}
+		for j := range entry.Sponge {
+			entry.Sponge[j] = pt.sponge[j][last]
+		}
+		pt.setIndexInChunk(&entry, (int(pt.indexInChunk[last].Value())+1)%pt.chunkRate)
+		if err := pt.AddRow(&entry); err != nil {
+			return err
+		}
 	}
 
 	pt.paddedHeight = targetHeight
 	return nil
 }
 
-// CreateInitialConstraints generates constraints for the first row
-func (pt *ProgramTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
+// Program Table main column indices, in GetMainColumns order
+const (
+	programAddress = iota
+	programInstruction
+	programLookupMultiplicity
+	programIndexInChunk
+	programMaxMinusIndexInv
+	programIsHashInputPadding
+	programIsTablePadding
+	programBuffer0
+	programSponge0 = programBuffer0 + PoseidonRate
+)
 
+// CreateInitialConstraints generates constraints for the first row
+func (pt *ProgramTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Initial constraints for Program Table:
 	//
 	// 1. First address is zero:
@@ -171,28 +282,64 @@ func (pt *ProgramTableImpl) CreateInitialConstraints() ([]protocols.AIRConstrain
 	// 2. Index in chunk starts at zero:
 	//    indexInChunk[0] = 0
 	//
-	// 3. Hash input padding indicator starts at zero:
-	//    isHashInputPadding[0] = 0
-	//
-	// 4. Instruction lookup log derivative initialized correctly:
-	//    instrLookupLogDeriv[0] = default_initial
-	//
-	// 5. Prepare chunk running evaluation has absorbed first instruction:
-	//    prepareChunkRunEval[0] = default_initial * indeterminate + instruction[0]
+	// 3. The program is not empty, so the first row is neither padding:
+	//    isHashInputPadding[0] = 0,  isTablePadding[0] = 0
 	//
-	// 6. Send chunk running evaluation starts at default initial:
-	//    sendChunkRunEval[0] = default_initial
+	// 4. The buffer ends with the first word, and the sponge starts empty:
+	//    buffer_{RATE-1}[0] = instruction[0],  sponge_j[0] = 0
 	//
-	// Note: Actual polynomial representations computed during proof generation
-	// with proper Fiat-Shamir challenges.
-
+	// The instruction and hash lookups start at zero; they are on auxiliary
+	// columns, which depend on Fiat-Shamir challenges.
+	constraints := []*protocols.ConstraintPolynomial{
+		{
+			Name:   "program_address_starts_at_0",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programAddress]
+			},
+		},
+		{
+			Name:   "program_index_in_chunk_starts_at_0",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIndexInChunk]
+			},
+		},
+		{
+			Name:   "program_is_hash_input_padding_starts_at_0",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIsHashInputPadding]
+			},
+		},
+		{
+			Name:   "program_is_table_padding_starts_at_0",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIsTablePadding]
+			},
+		},
+		{
+			Name:   "program_buffer_starts_with_first_word",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programBuffer0+PoseidonRate-1].Sub(row[programInstruction])
+			},
+		},
+	}
+	for j := 0; j < PoseidonStateSize; j++ {
+		col := programSponge0 + j
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:      fmt.Sprintf("program_sponge%d_starts_at_0", j),
+			Degree:    1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement { return row[col] },
+		})
+	}
 	return constraints, nil
 }
 
 // CreateConsistencyConstraints generates constraints within each row
-func (pt *ProgramTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (pt *ProgramTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Consistency constraints for Program Table:
 	//
 	// 1. maxMinusIndexInv is zero or the inverse of (MAX_INDEX - indexInChunk):
@@ -207,112 +354,337 @@ func (pt *ProgramTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConst
 	// 4. isTablePadding is boolean (0 or 1):
 	//    isTablePadding * (isTablePadding - 1) = 0
 	//
+	// 5. Padding rows are not looked up and hold no instruction:
+	//    isTablePadding * lookupMultiplicity = 0
+	//    isTablePadding * instruction = 0
+	//
+	// 6. Hash input padding is table padding:
+	//    isHashInputPadding * (1 - isTablePadding) = 0
+	//
 	// Note: These constraints enforce proper boundary detection and boolean values.
-	// Actual polynomial representations computed during proof generation.
+	maxIndex := field.New(uint64(pt.chunkRate - 1))
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "program_max_minus_index_inverse_is_zero_or_inverse",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				maxMinusIndex := row[programIndexInChunk].Neg().AddConst(maxIndex)
+				inv := row[programMaxMinusIndexInv]
+				return xfield.One.Sub(maxMinusIndex.Mul(inv)).Mul(inv)
+			},
+		},
+		{
+			Name:   "program_max_minus_index_is_zero_or_inverse_is_correct",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				maxMinusIndex := row[programIndexInChunk].Neg().AddConst(maxIndex)
+				inv := row[programMaxMinusIndexInv]
+				return xfield.One.Sub(maxMinusIndex.Mul(inv)).Mul(maxMinusIndex)
+			},
+		},
+		{
+			Name:   "program_is_hash_input_padding_is_bit",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(row[programIsHashInputPadding])
+			},
+		},
+		{
+			Name:   "program_is_table_padding_is_bit",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(row[programIsTablePadding])
+			},
+		},
+		{
+			Name:   "program_padding_is_not_looked_up",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIsTablePadding].Mul(row[programLookupMultiplicity])
+			},
+		},
+		{
+			Name:   "program_padding_has_no_instruction",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIsTablePadding].Mul(row[programInstruction])
+			},
+		},
+		{
+			Name:   "program_hash_input_padding_is_table_padding",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIsHashInputPadding].Mul(xfield.One.Sub(row[programIsTablePadding]))
+			},
+		},
+	}, nil
+}
 
-	return constraints, nil
+// programChunkContinues is 1 in a row that does not end its chunk and 0 in
+// one that does, given the consistency constraints on maxMinusIndexInv
+func programChunkContinues(row []xfield.XFieldElement) xfield.XFieldElement {
+	maxIndex := field.New(uint64(PoseidonRate - 1))
+	return row[programIndexInChunk].Neg().AddConst(maxIndex).Mul(row[programMaxMinusIndexInv])
 }
 
-// CreateTransitionConstraints generates constraints between consecutive rows
-func (pt *ProgramTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
+// programAbsorbs is 1 in a row that ends a chunk of the program or of its
+// hash input padding, and 0 otherwise
+func programAbsorbs(row []xfield.XFieldElement) xfield.XFieldElement {
+	hashing := xfield.One.Sub(row[programIsTablePadding]).Add(row[programIsHashInputPadding])
+	return xfield.One.Sub(programChunkContinues(row)).Mul(hashing)
+}
+
+// programWord returns the word the next row absorbs: its instruction, or the
+// one that starts the hash input padding
+func programWord(current, next []xfield.XFieldElement) xfield.XFieldElement {
+	starts := next[programIsHashInputPadding].Mul(xfield.One.Sub(current[programIsHashInputPadding]))
+	return next[programInstruction].Add(starts)
+}
 
+// CreateTransitionConstraints generates constraints between consecutive rows
+func (pt *ProgramTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
 	// Transition constraints for Program Table:
 	//
 	// 1. Address increases by 0 or 1:
 	//    (address' - address) * (address' - address - 1) = 0
 	//
-	// 2. If not table padding, certain constraints apply:
-	//    - Index in chunk increments or resets
-	//    - Running evaluations update correctly
+	// 2. Table padding is followed by table padding:
+	//    isTablePadding * (isTablePadding' - 1) = 0
 	//
-	// 3. Instruction lookup log derivative updates correctly:
-	//    If lookupMultiplicity > 0:
-	//      log_deriv' = log_deriv + lookupMultiplicity/(indeterminate - compressed_row)
-	//    where compressed_row encodes (address, instruction)
+	// 3. Outside padding the address increments:
+	//    (1 - isTablePadding') * (address' - address - 1) = 0
 	//
-	// 4. Prepare chunk running evaluation updates:
-	//    If not at chunk boundary:
-	//      prepareChunkRunEval' = prepareChunkRunEval * indeterminate + instruction'
-	//    If at chunk boundary:
-	//      prepareChunkRunEval' = default_initial * indeterminate + instruction'
+	// 4. The index in chunk counts up to RATE - 1 and starts over, with c
+	//    the 1 of a row that does not end its chunk:
+	//    indexInChunk' = c * (indexInChunk + 1)
 	//
-	// 5. Send chunk running evaluation updates:
-	//    If at chunk boundary and not padding:
-	//      sendChunkRunEval' = sendChunkRunEval * indeterminate + hash_of_chunk
-	//    Otherwise:
-	//      sendChunkRunEval' = sendChunkRunEval
+	// 5. The program is followed by the hash input padding, which fills the
+	//    chunk it starts and ends with it:
+	//    (1 - isTablePadding) * isTablePadding' * (1 - isHashInputPadding') = 0
+	//    isHashInputPadding * c * (1 - isHashInputPadding') = 0
+	//    isHashInputPadding * (1 - c) * isHashInputPadding' = 0
+	//    isTablePadding * (1 - isHashInputPadding) * isHashInputPadding' = 0
 	//
-	// Note: All polynomial representations computed during proof generation
-	// with proper Fiat-Shamir challenges and hash computations.
+	// 6. The buffer shifts in the next row's word w', its instruction or
+	//    the one that starts the hash input padding:
+	//    buffer_j' = buffer_{j+1},  buffer_{RATE-1}' = w'
 	//
-	// Program attestation works by:
-	// 1. Preparing chunks of instructions (prepareChunkRunEval)
-	// 2. Hashing each chunk (with Poseidon)
-	// 3. Sending chunk hashes (sendChunkRunEval)
-	// This proves the program is correctly attested and matches public input.
-
+	// 7. The sponge state only changes after a row that absorbs a chunk, a
+	//    = (1 - c) * (1 - isTablePadding + isHashInputPadding):
+	//    (1 - a) * (sponge_j' - sponge_j) = 0
+	//
+	// The Hash Table proves that sponge' is the permutation of sponge plus
+	// the buffer in the rows that absorb, see the cross-table arguments;
+	// the instruction and hash lookups are on auxiliary columns.
+	constraints := []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "program_address_increments_by_0_or_1",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				diff := next[programAddress].Sub(current[programAddress])
+				return diff.Mul(diff.Sub(xfield.One))
+			},
+		},
+		{
+			Name:   "program_table_padding_is_followed_by_padding",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return current[programIsTablePadding].Mul(next[programIsTablePadding].Sub(xfield.One))
+			},
+		},
+		{
+			Name:   "program_address_increments_outside_padding",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				increment := next[programAddress].Sub(current[programAddress]).Sub(xfield.One)
+				return xfield.One.Sub(next[programIsTablePadding]).Mul(increment)
+			},
+		},
+		{
+			Name:   "program_index_in_chunk_counts_up",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				counted := programChunkContinues(current).Mul(current[programIndexInChunk].Add(xfield.One))
+				return next[programIndexInChunk].Sub(counted)
+			},
+		},
+		{
+			Name:   "program_is_followed_by_hash_input_padding",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return xfield.One.Sub(current[programIsTablePadding]).Mul(next[programIsTablePadding]).
+					Mul(xfield.One.Sub(next[programIsHashInputPadding]))
+			},
+		},
+		{
+			Name:   "program_hash_input_padding_fills_its_chunk",
+			Degree: 4,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return current[programIsHashInputPadding].Mul(programChunkContinues(current)).
+					Mul(xfield.One.Sub(next[programIsHashInputPadding]))
+			},
+		},
+		{
+			Name:   "program_hash_input_padding_ends_with_its_chunk",
+			Degree: 4,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return current[programIsHashInputPadding].Mul(xfield.One.Sub(programChunkContinues(current))).
+					Mul(next[programIsHashInputPadding])
+			},
+		},
+		{
+			Name:   "program_hash_input_padding_does_not_restart",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return current[programIsTablePadding].Mul(xfield.One.Sub(current[programIsHashInputPadding])).
+					Mul(next[programIsHashInputPadding])
+			},
+		},
+		{
+			Name:   "program_buffer_takes_next_word",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return next[programBuffer0+PoseidonRate-1].Sub(programWord(current, next))
+			},
+		},
+	}
+	for j := 0; j+1 < PoseidonRate; j++ {
+		col := programBuffer0 + j
+		constraints = append(constraints, &protocols.TransitionConstraintPolynomial{
+			Name:   fmt.Sprintf("program_buffer%d_shifts", j),
+			Degree: 1,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return next[col].Sub(current[col+1])
+			},
+		})
+	}
+	for j := 0; j < PoseidonStateSize; j++ {
+		col := programSponge0 + j
+		constraints = append(constraints, &protocols.TransitionConstraintPolynomial{
+			Name:   fmt.Sprintf("program_sponge%d_changes_only_after_absorbing", j),
+			Degree: 4,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return xfield.One.Sub(programAbsorbs(current)).Mul(next[col].Sub(current[col]))
+			},
+		})
+	}
 	return constraints, nil
 }
 
 // CreateTerminalConstraints generates constraints for the last row
-func (pt *ProgramTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (pt *ProgramTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Terminal constraints for Program Table:
 	//
-	// The final send chunk running evaluation must match the expected program digest.
-	// This is verified via evaluation argument with public input.
+	// 1. The table ends with padding, so that the last row's instruction,
+	//    which has no successor, is not looked up:
+	//    isTablePadding = 1
 	//
-	// Note: Polynomial representation computed during proof generation.
-
-	return constraints, nil
+	// 2. The hash input padding has ended, so the last chunk is absorbed:
+	//    isHashInputPadding = 0
+	//
+	// The sponge state of the last row then starts with the program digest,
+	// which the cross-table arguments compare with the claim.
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "program_table_ends_with_padding",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIsTablePadding].Sub(xfield.One)
+			},
+		},
+		{
+			Name:   "program_table_ends_after_hash_input_padding",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[programIsHashInputPadding]
+			},
+		},
+	}, nil
 }
 
-// UpdateInstructionLookupLogDerivative updates the log derivative for instruction lookups
-// This implements the server side of the lookup argument with the Processor table
-func (pt *ProgramTableImpl) UpdateInstructionLookupLogDerivative(challenges map[string]field.Element) error {
+// UpdateLookupLogDerivative computes the log derivative of the instructions
+// the Processor Table looks up
+//
+// Row i serves (address_i, instruction_i, instruction_{i+1}), the word after
+// an instruction being its argument or the next instruction:
+//
+//	ld[0] = 0,  (ld' - ld)·(α - c) = lookupMultiplicity
+func (pt *ProgramTableImpl) UpdateLookupLogDerivative(challenges map[string]xfield.XFieldElement) error {
 	if pt.height == 0 {
 		return fmt.Errorf("cannot update instruction lookup on empty table")
 	}
-
-	// Extract challenges
-	indeterminate, ok := challenges["instruction_lookup_indeterminate"]
-	if !ok {
-		return fmt.Errorf("missing instruction_lookup_indeterminate challenge")
-	}
-	addressWeight, ok := challenges["instruction_address_weight"]
-	if !ok {
-		return fmt.Errorf("missing instruction_address_weight challenge")
+	weights, err := tupleWeights(challenges, challengeProgramIndeterminate, numProgramWeights)
+	if err != nil {
+		return err
 	}
-	instrWeight, ok := challenges["instruction_weight"]
-	if !ok {
-		return fmt.Errorf("missing instruction_weight challenge")
-	}
-
-	// Initialize first row
-	pt.instrLookupLogDeriv[0] = field.Zero
 
-	// Update subsequent rows
-	for i := 1; i < pt.height; i++ {
-		// Check if there are lookups for this instruction
+	pt.instrLookupLogDeriv[0] = xfield.Zero
+	for i := 1; i < len(pt.address); i++ {
+		pt.instrLookupLogDeriv[i] = pt.instrLookupLogDeriv[i-1]
 		multiplicity := pt.lookupMultiplicity[i-1]
+		if multiplicity.IsZero() {
+			continue
+		}
+		compressed := weights.compressBase(pt.address[i-1], pt.instruction[i-1], pt.instruction[i])
+		contribution := weights.indeterminate.Sub(compressed).Inverse().MulConst(multiplicity)
+		pt.instrLookupLogDeriv[i] = pt.instrLookupLogDeriv[i].Add(contribution)
+	}
 
-		if !multiplicity.Equal(field.Zero) {
-			// Compress row: address_weight * address + instr_weight * instruction
-			compressedRow := addressWeight.Mul(pt.address[i-1]).
-				Add(instrWeight.Mul(pt.instruction[i-1]))
+	return nil
+}
 
-			// log_deriv[i] = log_deriv[i-1] + multiplicity/(indeterminate - compressed_row)
-			denominator := indeterminate.Sub(compressedRow)
-			inverse := denominator.Inverse()
+// programHashTuple returns the tuple a row that absorbs a chunk sends to
+// the Hash Table: the sponge tag, the permutation's input, the row's sponge
+// state plus its buffer, and its output, the next row's sponge state; see
+// hashTableTuples
+func programHashTuple(current, next []xfield.XFieldElement) []xfield.XFieldElement {
+	tuple := make([]xfield.XFieldElement, numHashWeights)
+	tuple[0] = xfield.One
+	for j := 0; j < PoseidonStateSize; j++ {
+		input := current[programSponge0+j]
+		if j < PoseidonRate {
+			input = input.Add(current[programBuffer0+j])
+		}
+		tuple[1+j] = input
+		tuple[1+PoseidonStateSize+j] = next[programSponge0+j]
+	}
+	return tuple
+}
 
-			contribution := multiplicity.Mul(inverse)
-			pt.instrLookupLogDeriv[i] = pt.instrLookupLogDeriv[i-1].Add(contribution)
-		} else {
-			// No lookups, carry forward
-			pt.instrLookupLogDeriv[i] = pt.instrLookupLogDeriv[i-1]
+// UpdateHashLookupLogDerivative computes the log derivative of the
+// permutations the Program Table looks up in the Hash Table
+//
+//	ld[0] = 0,  (ld' - ld)·(β - c) = a
+//
+// with a the 1 of a row that absorbs a chunk and c its compressed tuple.
+func (pt *ProgramTableImpl) UpdateHashLookupLogDerivative(challenges map[string]xfield.XFieldElement) error {
+	if pt.height == 0 {
+		return fmt.Errorf("cannot update hash lookup on empty table")
+	}
+	weights, err := tupleWeights(challenges, challengeHashIndeterminate, numHashWeights)
+	if err != nil {
+		return err
+	}
+
+	columns := pt.GetMainColumns()
+	row := func(i int) []xfield.XFieldElement {
+		values := make([]xfield.XFieldElement, len(columns))
+		for j, column := range columns {
+			values[j] = xfield.NewConst(column[i])
 		}
+		return values
+	}
+	pt.hashLookupLogDeriv[0] = xfield.Zero
+	for i := 1; i < pt.height; i++ {
+		pt.hashLookupLogDeriv[i] = pt.hashLookupLogDeriv[i-1]
+		if !pt.absorbs(i - 1) {
+			continue
+		}
+		denominator := weights.indeterminate.Sub(weights.compress(programHashTuple(row(i-1), row(i))))
+		if denominator.IsZero() {
+			return fmt.Errorf("program chunk in row %d compresses to the indeterminate", i-1)
+		}
+		pt.hashLookupLogDeriv[i] = pt.hashLookupLogDeriv[i].Add(denominator.Inverse())
 	}
 
 	return nil
@@ -327,6 +699,9 @@ type ProgramEntry struct {
 	MaxMinusIndexInv   field.Element // Inverse of (MAX_INDEX - IndexInChunk)
 	IsHashInputPadding field.Element // Boolean: hash input padding
 	IsTablePadding     field.Element // Boolean: table padding
+
+	Buffer [PoseidonRate]field.Element      // The last words absorbed, this row's last
+	Sponge [PoseidonStateSize]field.Element // Sponge state before the chunk is absorbed
 }
 
 // NewProgramEntry creates a new program entry
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/polynomial"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
@@ -12,7 +14,7 @@ import (
 // This table ensures memory consistency across the VM execution
 //
 // The RAM table tracks all memory operations (reads and writes) and proves:
-// 1. Memory is initialized to zero
+// 1. Memory is initialized non-deterministically (zero unless the prover supplies it)
 // 2. Reads return the most recently written value
 // 3. Memory pointers form contiguous regions (via Bezout relation)
 //
@@ -28,12 +30,12 @@ type RAMTableImpl struct {
 	bezoutCoeffPoly1 []field.Element // Bezout coefficient polynomial, coefficient 1
 
 	// Auxiliary columns (XField elements for cross-table arguments)
-	runningProductRAMP []field.Element // Running product of RAM pointers (for contiguity)
-	formalDerivative   []field.Element // Formal derivative (for Bezout relation)
-	bezoutCoeff0       []field.Element // Bezout coefficient 0
-	bezoutCoeff1       []field.Element // Bezout coefficient 1
-	runningProductPerm []field.Element // Running product for permutation argument with Processor
-	clockJumpDiffLog   []field.Element // Log derivative for clock jump differences
+	runningProductRAMP []xfield.XFieldElement // Running product of RAM pointers (for contiguity)
+	formalDerivative   []xfield.XFieldElement // Formal derivative (for Bezout relation)
+	bezoutCoeff0       []xfield.XFieldElement // Bezout coefficient 0
+	bezoutCoeff1       []xfield.XFieldElement // Bezout coefficient 1
+	lookupLogDeriv     []xfield.XFieldElement // Log derivative of the Processor Table's lookups
+	clockJumpDiffLog   []xfield.XFieldElement // Log derivative for clock jump differences
 
 	height       int
 	paddedHeight int
@@ -56,12 +58,12 @@ func NewRAMTable() *RAMTableImpl {
 		inverseRampDiff:    make([]field.Element, 0),
 		bezoutCoeffPoly0:   make([]field.Element, 0),
 		bezoutCoeffPoly1:   make([]field.Element, 0),
-		runningProductRAMP: make([]field.Element, 0),
-		formalDerivative:   make([]field.Element, 0),
-		bezoutCoeff0:       make([]field.Element, 0),
-		bezoutCoeff1:       make([]field.Element, 0),
-		runningProductPerm: make([]field.Element, 0),
-		clockJumpDiffLog:   make([]field.Element, 0),
+		runningProductRAMP: make([]xfield.XFieldElement, 0),
+		formalDerivative:   make([]xfield.XFieldElement, 0),
+		bezoutCoeff0:       make([]xfield.XFieldElement, 0),
+		bezoutCoeff1:       make([]xfield.XFieldElement, 0),
+		lookupLogDeriv:     make([]xfield.XFieldElement, 0),
+		clockJumpDiffLog:   make([]xfield.XFieldElement, 0),
 		height:             0,
 		paddedHeight:       0,
 	}
@@ -96,13 +98,13 @@ func (rt *RAMTableImpl) GetMainColumns() [][]field.Element {
 }
 
 // GetAuxiliaryColumns returns auxiliary columns
-func (rt *RAMTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
+func (rt *RAMTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{
 		rt.runningProductRAMP,
 		rt.formalDerivative,
 		rt.bezoutCoeff0,
 		rt.bezoutCoeff1,
-		rt.runningProductPerm,
+		rt.lookupLogDeriv,
 		rt.clockJumpDiffLog,
 	}
 }
@@ -129,30 +131,38 @@ func (rt *RAMTableImpl) AddRow(entry *RAMEntry) error {
 	rt.bezoutCoeffPoly1 = append(rt.bezoutCoeffPoly1, entry.BezoutCoeffPoly1)
 
 	// Initialize auxiliary columns (computed during proving)
-	rt.runningProductRAMP = append(rt.runningProductRAMP, field.Zero)
-	rt.formalDerivative = append(rt.formalDerivative, field.Zero)
-	rt.bezoutCoeff0 = append(rt.bezoutCoeff0, field.Zero)
-	rt.bezoutCoeff1 = append(rt.bezoutCoeff1, field.Zero)
-	rt.runningProductPerm = append(rt.runningProductPerm, field.Zero)
-	rt.clockJumpDiffLog = append(rt.clockJumpDiffLog, field.Zero)
+	rt.runningProductRAMP = append(rt.runningProductRAMP, xfield.Zero)
+	rt.formalDerivative = append(rt.formalDerivative, xfield.Zero)
+	rt.bezoutCoeff0 = append(rt.bezoutCoeff0, xfield.Zero)
+	rt.bezoutCoeff1 = append(rt.bezoutCoeff1, xfield.Zero)
+	rt.lookupLogDeriv = append(rt.lookupLogDeriv, xfield.Zero)
+	rt.clockJumpDiffLog = append(rt.clockJumpDiffLog, xfield.Zero)
 
 	rt.height++
 	return nil
 }
 
-// Pad pads the table to the target height with padding rows
+// Pad pads the table to the target height with padding rows and fills in
+// the Bezout coefficient polynomials of its pointers
+//
+// An empty table gets a padding row at pointer 0.
 func (rt *RAMTableImpl) Pad(targetHeight int) error {
 	if targetHeight < rt.height {
 		return fmt.Errorf("target height %d is less than current height %d", targetHeight, rt.height)
 	}
 
-	if rt.height == 0 {
-		return fmt.Errorf("cannot pad empty table")
-	}
-
 	// Padding rows have instructionType = 2 (PADDING_INDICATOR)
 	paddingIndicator := field.New(uint64(RAMPaddingIndicator))
 
+	if rt.height == 0 {
+		if targetHeight == 0 {
+			return fmt.Errorf("cannot pad empty table to height 0")
+		}
+		if err := rt.AddRow(&RAMEntry{InstructionType: paddingIndicator}); err != nil {
+			return err
+		}
+	}
+
 	// Use last row values for other fields
 	lastIdx := rt.height - 1
 	paddingRows := targetHeight - rt.height
@@ -169,18 +179,61 @@ func (rt *RAMTableImpl) Pad(targetHeight int) error {
 		rt.formalDerivative = append(rt.formalDerivative, rt.formalDerivative[lastIdx])
 		rt.bezoutCoeff0 = append(rt.bezoutCoeff0, rt.bezoutCoeff0[lastIdx])
 		rt.bezoutCoeff1 = append(rt.bezoutCoeff1, rt.bezoutCoeff1[lastIdx])
-		rt.runningProductPerm = append(rt.runningProductPerm, rt.runningProductPerm[lastIdx])
+		rt.lookupLogDeriv = append(rt.lookupLogDeriv, rt.lookupLogDeriv[lastIdx])
 		rt.clockJumpDiffLog = append(rt.clockJumpDiffLog, rt.clockJumpDiffLog[lastIdx])
 	}
 
+	rt.fillBezoutCoefficients()
 	rt.paddedHeight = targetHeight
 	return nil
 }
 
-// CreateInitialConstraints generates constraints for the first row
-func (rt *RAMTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
+// fillBezoutCoefficients sets the Bezout coefficient polynomial columns
+//
+// With Z the polynomial whose roots are the table's distinct pointers, the
+// Bezout coefficients a and b satisfy a·Z + b·Z' = 1, which only exist if
+// every root of Z is simple, that is, if every pointer occupies one
+// contiguous region. The coefficients are listed highest degree first, one
+// per region, so that the auxiliary columns evaluate them by Horner's
+// method. a has a lower degree than Z', so its first coefficient is zero.
+func (rt *RAMTableImpl) fillBezoutCoefficients() {
+	var pointers []field.Element
+	regions := make([]int, len(rt.ramPointer))
+	for i, pointer := range rt.ramPointer {
+		if i == 0 || !pointer.Equal(rt.ramPointer[i-1]) {
+			pointers = append(pointers, pointer)
+		}
+		regions[i] = len(pointers) - 1
+	}
 
+	zerofier := polynomial.Zerofier(pointers)
+	_, a, b := polynomial.XGCD(zerofier, zerofier.FormalDerivative())
+	coefficient := func(p *polynomial.Polynomial, degree int) field.Element {
+		if coefficients := p.Coefficients(); degree < len(coefficients) {
+			return coefficients[degree]
+		}
+		return field.Zero
+	}
+	for i, region := range regions {
+		degree := len(pointers) - 1 - region
+		rt.bezoutCoeffPoly0[i] = coefficient(a, degree)
+		rt.bezoutCoeffPoly1[i] = coefficient(b, degree)
+	}
+}
+
+// RAM Table main column indices, in GetMainColumns order
+const (
+	ramClk = iota
+	ramInstructionType
+	ramPointer
+	ramValue
+	ramInverseRampDifference
+	ramBezoutCoeffPoly0
+	ramBezoutCoeffPoly1
+)
+
+// CreateInitialConstraints generates constraints for the first row
+func (rt *RAMTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Initial constraints for RAM Table:
 	//
 	// 1. Bezout coefficient polynomial coefficient 0 is zero:
@@ -204,31 +257,41 @@ func (rt *RAMTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, e
 	// 7. Clock jump difference log derivative initialized:
 	//    clockJumpDiffLog[0] = default_initial
 	//
-	// Note: Actual polynomial representations computed during proof generation
-	// with proper Fiat-Shamir challenges.
-
-	return constraints, nil
+	// 2-7 are on auxiliary columns, which depend on Fiat-Shamir challenges,
+	// and belong to the contiguity and cross-table arguments.
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "ram_bezout_coefficient_polynomial_0_starts_at_0",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[ramBezoutCoeffPoly0]
+			},
+		},
+	}, nil
 }
 
 // CreateConsistencyConstraints generates constraints within each row
-func (rt *RAMTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (rt *RAMTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// Consistency constraints for RAM Table:
 	//
 	// 1. Instruction type must be in {0, 1, 2}:
 	//    instructionType * (instructionType - 1) * (instructionType - 2) = 0
 	//
 	// Note: This ensures every row is either WRITE (0), READ (1), or PADDING (2).
-	// Actual polynomial representation computed during proof generation.
-
-	return constraints, nil
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "ram_instruction_type_is_write_read_or_padding",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				instructionType := row[ramInstructionType]
+				return isBit(instructionType).Mul(instructionType.SubConst(field.New(RAMPaddingIndicator)))
+			},
+		},
+	}, nil
 }
 
 // CreateTransitionConstraints generates constraints between consecutive rows
-func (rt *RAMTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (rt *RAMTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
 	// Transition constraints for RAM Table:
 	//
 	// 1. If current row is padding, next row must be padding:
@@ -239,16 +302,23 @@ func (rt *RAMTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint
 	//    This is used to detect when RAM pointer changes.
 	//
 	// 3. RAM pointer difference is zero or inverseRampDiff is correct:
-	//    (ramPointer' - ramPointer) * ramPointerChanges = 0
-	//    where ramPointerChanges = 1 - (ramPointer' - ramPointer) * inverseRampDiff
+	//    (ramPointer' - ramPointer) * ramPointerUnchanged = 0
+	//    where ramPointerUnchanged = 1 - (ramPointer' - ramPointer) * inverseRampDiff
 	//
 	// 4. RAM value consistency:
 	//    If ramPointer doesn't change AND instructionType' != WRITE, then ramValue' = ramValue
-	//    ramPointerChanges * (WRITE - instructionType') * (ramValue' - ramValue) = 0
+	//    ramPointerUnchanged * (instructionType' - WRITE) * (ramValue' - ramValue) = 0
+	//
+	// Rows are sorted by pointer, then by clock, so 4 states that every read
+	// returns the value last written to (or read from) the same address.
+	// Padding rows repeat the last row's pointer and value.
 	//
 	// 5. Bezout coefficients only change if RAM pointer changes:
-	//    ramPointerChanges * (bezoutCoeffPoly0' - bezoutCoeffPoly0) = 0
-	//    ramPointerChanges * (bezoutCoeffPoly1' - bezoutCoeffPoly1) = 0
+	//    ramPointerUnchanged * (bezoutCoeffPoly0' - bezoutCoeffPoly0) = 0
+	//    ramPointerUnchanged * (bezoutCoeffPoly1' - bezoutCoeffPoly1) = 0
+	//
+	// The remaining transition constraints are on auxiliary columns, and
+	// belong to the contiguity and cross-table arguments:
 	//
 	// 6. Running product of RAM pointers updates correctly (contiguity argument):
 	//    If ramPointer changes:
@@ -269,146 +339,167 @@ func (rt *RAMTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint
 	//    If ramPointer doesn't change:
 	//      bc0' = bc0, bc1' = bc1
 	//
-	// 9. Running product permutation argument updates correctly:
-	//    rppa' = rppa * (indeterminate - compressed_row)
+	// 9. The log derivative of the Processor Table's lookups updates:
+	//    (log_deriv' - log_deriv) * (indeterminate - compressed_row') = notPad'
 	//    where compressed_row = Σ challenge_i * column_i
 	//
 	// 10. Clock jump difference log derivative updates correctly:
 	//     log_deriv' = log_deriv + 1/(indeterminate - clock_jump_diff)
-	//
-	// Note: All polynomial representations computed during proof generation
-	// with proper Fiat-Shamir challenges and evaluation domains.
+	//     if the pointer stays the same and the next row is not padding
 	//
 	// The Bezout relation proves contiguity of memory regions:
 	// If memory accesses are to addresses {a₁, a₂, ..., aₙ}, the Bezout
 	// relation ensures these form contiguous regions, which is critical
 	// for proving memory consistency in a zero-knowledge proof.
+	padding := field.New(RAMPaddingIndicator)
+	return []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "ram_padding_is_followed_by_padding",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(current[ramInstructionType]).Mul(next[ramInstructionType].SubConst(padding))
+			},
+		},
+		{
+			Name:   "ram_inverse_of_pointer_difference_is_zero_or_inverse",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				_, unchanged := ramPointerUnchanged(current, next)
+				return current[ramInverseRampDifference].Mul(unchanged)
+			},
+		},
+		{
+			Name:   "ram_pointer_difference_is_zero_or_inverse_is_correct",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				diff, unchanged := ramPointerUnchanged(current, next)
+				return diff.Mul(unchanged)
+			},
+		},
+		{
+			Name:   "ram_read_returns_last_value",
+			Degree: 4,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				_, unchanged := ramPointerUnchanged(current, next)
+				nextIsNotWrite := next[ramInstructionType].SubConst(field.New(RAMInstructionWrite))
+				return unchanged.Mul(nextIsNotWrite).Mul(next[ramValue].Sub(current[ramValue]))
+			},
+		},
+		{
+			Name:   "ram_bezout_coefficient_polynomial_0_changes_with_pointer",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				_, unchanged := ramPointerUnchanged(current, next)
+				return unchanged.Mul(next[ramBezoutCoeffPoly0].Sub(current[ramBezoutCoeffPoly0]))
+			},
+		},
+		{
+			Name:   "ram_bezout_coefficient_polynomial_1_changes_with_pointer",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				_, unchanged := ramPointerUnchanged(current, next)
+				return unchanged.Mul(next[ramBezoutCoeffPoly1].Sub(current[ramBezoutCoeffPoly1]))
+			},
+		},
+	}, nil
+}
 
-	return constraints, nil
+// ramPointerUnchanged returns ramPointer' - ramPointer and
+// 1 - (ramPointer' - ramPointer)·inverseRampDiff, which is 1 if the pointer
+// stays the same and 0 otherwise, given the inverse constraints; it has
+// degree 2
+func ramPointerUnchanged(current, next []xfield.XFieldElement) (diff, unchanged xfield.XFieldElement) {
+	diff = next[ramPointer].Sub(current[ramPointer])
+	return diff, xfield.One.Sub(diff.Mul(current[ramInverseRampDifference]))
 }
 
 // CreateTerminalConstraints generates constraints for the last row
-func (rt *RAMTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	constraints := make([]protocols.AIRConstraint, 0)
-
+func (rt *RAMTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
 	// No specific terminal constraints for RAM table.
 	// Consistency is ensured via permutation and contiguity arguments.
-
-	return constraints, nil
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
-// UpdateContiguityArgument updates the Bezout relation for contiguity
-// This is called during proof generation to compute the running product
-// and formal derivative for proving memory pointer contiguity
-func (rt *RAMTableImpl) UpdateContiguityArgument(indeterminate field.Element) error {
+// UpdateContiguityArgument computes the contiguity argument's auxiliary
+// columns for the Bezout challenge x
+//
+// rp is the running product of x - ramPointer over the table's regions and
+// fd its formal derivative; bc0 and bc1 evaluate the Bezout coefficient
+// polynomials at x. bc0·rp + bc1·fd = 1 in the last row.
+func (rt *RAMTableImpl) UpdateContiguityArgument(indeterminate xfield.XFieldElement) error {
 	if rt.height == 0 {
 		return fmt.Errorf("cannot update contiguity argument on empty table")
 	}
 
-	// Initialize first row
-	// runningProductRAMP[0] = indeterminate - ramPointer[0]
-	rt.runningProductRAMP[0] = indeterminate.Sub(rt.ramPointer[0])
-
-	// formalDerivative[0] = 1
-	rt.formalDerivative[0] = field.One
-
-	// bezoutCoeff0[0] = 0
-	rt.bezoutCoeff0[0] = field.Zero
-
-	// bezoutCoeff1[0] = bezoutCoeffPoly1[0]
-	rt.bezoutCoeff1[0] = rt.bezoutCoeffPoly1[0]
-
-	// Update subsequent rows
-	for i := 1; i < rt.height; i++ {
-		// Check if RAM pointer changed
-		pointerDiff := rt.ramPointer[i].Sub(rt.ramPointer[i-1])
-		pointerChanged := !pointerDiff.Equal(field.Zero)
-
-		if pointerChanged {
-			// Running product: runningProductRAMP[i] = runningProductRAMP[i-1] * (indeterminate - ramPointer[i])
-			factor := indeterminate.Sub(rt.ramPointer[i])
-			rt.runningProductRAMP[i] = rt.runningProductRAMP[i-1].Mul(factor)
+	rt.runningProductRAMP[0] = indeterminate.SubConst(rt.ramPointer[0])
+	rt.formalDerivative[0] = xfield.One
+	rt.bezoutCoeff0[0] = xfield.Zero
+	rt.bezoutCoeff1[0] = xfield.NewConst(rt.bezoutCoeffPoly1[0])
 
-			// Formal derivative: fd[i] = runningProductRAMP[i-1] + (indeterminate - ramPointer[i]) * fd[i-1]
-			rt.formalDerivative[i] = rt.runningProductRAMP[i-1].Add(factor.Mul(rt.formalDerivative[i-1]))
-
-			// Bezout coefficients: bc0[i] = indeterminate * bc0[i-1] + bezoutCoeffPoly0[i]
-			rt.bezoutCoeff0[i] = indeterminate.Mul(rt.bezoutCoeff0[i-1]).Add(rt.bezoutCoeffPoly0[i])
-			rt.bezoutCoeff1[i] = indeterminate.Mul(rt.bezoutCoeff1[i-1]).Add(rt.bezoutCoeffPoly1[i])
-		} else {
-			// Pointer didn't change, carry forward previous values
+	for i := 1; i < len(rt.ramPointer); i++ {
+		if rt.ramPointer[i].Equal(rt.ramPointer[i-1]) {
 			rt.runningProductRAMP[i] = rt.runningProductRAMP[i-1]
 			rt.formalDerivative[i] = rt.formalDerivative[i-1]
 			rt.bezoutCoeff0[i] = rt.bezoutCoeff0[i-1]
 			rt.bezoutCoeff1[i] = rt.bezoutCoeff1[i-1]
+			continue
 		}
+		factor := indeterminate.SubConst(rt.ramPointer[i])
+		rt.runningProductRAMP[i] = rt.runningProductRAMP[i-1].Mul(factor)
+		rt.formalDerivative[i] = rt.runningProductRAMP[i-1].Add(factor.Mul(rt.formalDerivative[i-1]))
+		rt.bezoutCoeff0[i] = indeterminate.Mul(rt.bezoutCoeff0[i-1]).AddConst(rt.bezoutCoeffPoly0[i])
+		rt.bezoutCoeff1[i] = indeterminate.Mul(rt.bezoutCoeff1[i-1]).AddConst(rt.bezoutCoeffPoly1[i])
 	}
 
 	return nil
 }
 
-// UpdatePermutationArgument updates the running product for permutation argument
-// This is called during proof generation with actual Fiat-Shamir challenges
-func (rt *RAMTableImpl) UpdatePermutationArgument(challenges map[string]field.Element) error {
+// UpdateLookupLogDerivative computes the log derivative of the RAM accesses
+// the Processor Table looks up
+//
+//	ld[0]·(α - c[0]) = notPad[0],  (ld' - ld)·(α - c') = notPad'
+//
+// with c compressing (clk, type, pointer, value), and notPad 1 in every row
+// but padding rows
+func (rt *RAMTableImpl) UpdateLookupLogDerivative(challenges map[string]xfield.XFieldElement) error {
 	if rt.height == 0 {
-		return fmt.Errorf("cannot update permutation argument on empty table")
-	}
-
-	// Extract challenges
-	indeterminate, ok := challenges["ram_indeterminate"]
-	if !ok {
-		return fmt.Errorf("missing ram_indeterminate challenge")
-	}
-	clkWeight, ok := challenges["ram_clk_weight"]
-	if !ok {
-		return fmt.Errorf("missing ram_clk_weight challenge")
-	}
-	instrTypeWeight, ok := challenges["ram_instruction_type_weight"]
-	if !ok {
-		return fmt.Errorf("missing ram_instruction_type_weight challenge")
+		return fmt.Errorf("cannot update lookup log derivative on empty table")
 	}
-	pointerWeight, ok := challenges["ram_pointer_weight"]
-	if !ok {
-		return fmt.Errorf("missing ram_pointer_weight challenge")
+	weights, err := tupleWeights(challenges, challengeRAMIndeterminate, numRAMWeights)
+	if err != nil {
+		return err
 	}
-	valueWeight, ok := challenges["ram_value_weight"]
-	if !ok {
-		return fmt.Errorf("missing ram_value_weight challenge")
+
+	padding := field.New(RAMPaddingIndicator)
+	logDerivative := xfield.Zero
+	for i := range rt.clk {
+		if !rt.instructionType[i].Equal(padding) {
+			compressed := weights.compressBase(rt.clk[i], rt.instructionType[i], rt.ramPointer[i], rt.ramValue[i])
+			logDerivative = logDerivative.Add(weights.indeterminate.Sub(compressed).Inverse())
+		}
+		rt.lookupLogDeriv[i] = logDerivative
 	}
 
-	// Initialize running product
-	paddingIndicator := field.New(uint64(RAMPaddingIndicator))
+	return nil
+}
 
-	// First row handling
-	if !rt.instructionType[0].Equal(paddingIndicator) {
-		// Compress first row
-		compressedRow := clkWeight.Mul(rt.clk[0]).
-			Add(instrTypeWeight.Mul(rt.instructionType[0])).
-			Add(pointerWeight.Mul(rt.ramPointer[0])).
-			Add(valueWeight.Mul(rt.ramValue[0]))
-
-		// rppa[0] = indeterminate - compressed_row
-		rt.runningProductPerm[0] = indeterminate.Sub(compressedRow)
-	} else {
-		// First row is padding, use default initial
-		rt.runningProductPerm[0] = field.One
+// UpdateClockJumpLogDerivative computes the log derivative of the clock jump
+// differences between consecutive accesses of the same pointer, which the
+// Processor Table serves
+//
+//	ld[0] = 0,  (ld' - ld)·(γ - (clk' - clk)) = unchanged·notPad'
+func (rt *RAMTableImpl) UpdateClockJumpLogDerivative(indeterminate xfield.XFieldElement) error {
+	if rt.height == 0 {
+		return fmt.Errorf("cannot update clock jump log derivative on empty table")
 	}
 
-	// Update subsequent rows
-	for i := 1; i < rt.height; i++ {
-		if !rt.instructionType[i].Equal(paddingIndicator) {
-			// Compress current row
-			compressedRow := clkWeight.Mul(rt.clk[i]).
-				Add(instrTypeWeight.Mul(rt.instructionType[i])).
-				Add(pointerWeight.Mul(rt.ramPointer[i])).
-				Add(valueWeight.Mul(rt.ramValue[i]))
-
-			// rppa[i] = rppa[i-1] * (indeterminate - compressed_row)
-			factor := indeterminate.Sub(compressedRow)
-			rt.runningProductPerm[i] = rt.runningProductPerm[i-1].Mul(factor)
-		} else {
-			// Padding row, keep previous value
-			rt.runningProductPerm[i] = rt.runningProductPerm[i-1]
+	padding := field.New(RAMPaddingIndicator)
+	rt.clockJumpDiffLog[0] = xfield.Zero
+	for i := 1; i < len(rt.clk); i++ {
+		rt.clockJumpDiffLog[i] = rt.clockJumpDiffLog[i-1]
+		if rt.ramPointer[i].Equal(rt.ramPointer[i-1]) && !rt.instructionType[i].Equal(padding) {
+			difference := indeterminate.SubConst(rt.clk[i].Sub(rt.clk[i-1]))
+			rt.clockJumpDiffLog[i] = rt.clockJumpDiffLog[i].Add(difference.Inverse())
 		}
 	}
 
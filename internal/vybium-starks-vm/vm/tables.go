@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
@@ -87,22 +88,26 @@ type ExecutionTable interface {
 	GetMainColumns() [][]field.Element
 
 	// GetAuxiliaryColumns returns the auxiliary columns (XField elements for arguments)
-	GetAuxiliaryColumns() [][]field.Element
+	GetAuxiliaryColumns() [][]xfield.XFieldElement
 
 	// Pad extends the table to the target height with padding rows
 	Pad(targetHeight int) error
 
+	// The constraint evaluators read rows of this table alone: its main
+	// columns in GetMainColumns order, followed by its periodic columns if
+	// it has any. CreateMasterAIR places them in the master table.
+
 	// CreateInitialConstraints generates constraints for the first row
-	CreateInitialConstraints() ([]protocols.AIRConstraint, error)
+	CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error)
 
 	// CreateConsistencyConstraints generates constraints within each row
-	CreateConsistencyConstraints() ([]protocols.AIRConstraint, error)
+	CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error)
 
 	// CreateTransitionConstraints generates constraints between consecutive rows
-	CreateTransitionConstraints() ([]protocols.AIRConstraint, error)
+	CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error)
 
 	// CreateTerminalConstraints generates constraints for the last row
-	CreateTerminalConstraints() ([]protocols.AIRConstraint, error)
+	CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error)
 }
 
 // TableLinkage describes how tables are connected
@@ -2,24 +2,48 @@ package vm
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
 )
 
-// SimpleTraceRecorder is a production-ready trace recorder that focuses on
-// processor state recording. Coprocessor table population is deferred to
-// when we generate full proofs.
+// TraceRecorder records the processor state and every coprocessor interaction
+// of an execution into the tables of an AET.
 //
-// This follows Triton VM's layered approach:
-// - Phase 4a: Processor trace (THIS FILE - focus on main execution)
-// - Phase 4b: Coprocessor traces (detailed hash, RAM, U32 tables)
-type SimpleTraceRecorder struct {
+// This follows Triton VM's approach:
+// - RecordState captures the processor row BEFORE an instruction executes
+// - RecordExecution derives the coprocessor rows from what the instruction did
+// - GenerateAET sorts the memory-like tables, fills the lookup tables and pads
+//
+// Op stack and RAM rows are buffered and only written to their tables in
+// GenerateAET, because those tables are sorted by pointer and then by clock
+// rather than by execution order. The Jump Stack Table is derived from the
+// padded Processor Table, since it holds every processor row.
+//
+// Every coprocessor row matches a tuple the Processor Table's row sends, see
+// processorLinks. In particular, the op stack rows are the net moves of an
+// instruction between st15 and the underflow memory, not every push and pop
+// the VM makes while executing it.
+type TraceRecorder struct {
 	aet        *AET
 	cycleCount uint64
+
+	// Snapshot of the VM taken by RecordState for the instruction in flight
+	stackBefore  [16]field.Element
+	ospBefore    int
+	spongeBefore []field.Element
+
+	// Positions in the VM's call logs up to which rows have been recorded
+	ramCursor    int
+	coProcCursor int
+
+	// Buffered rows of the sorted tables
+	opStackEntries []*OpStackEntry
+	ramEntries     []*RAMEntry
 }
 
-// NewSimpleTraceRecorder creates a new simple trace recorder
-func NewSimpleTraceRecorder(program *Program) (*SimpleTraceRecorder, error) {
+// NewTraceRecorder creates a new trace recorder
+func NewTraceRecorder(program *Program) (*TraceRecorder, error) {
 	if program == nil {
 		return nil, fmt.Errorf("program cannot be nil")
 	}
@@ -29,38 +53,103 @@ func NewSimpleTraceRecorder(program *Program) (*SimpleTraceRecorder, error) {
 		return nil, fmt.Errorf("failed to create AET: %w", err)
 	}
 
-	return &SimpleTraceRecorder{
-		aet:        aet,
-		cycleCount: 0,
+	return &TraceRecorder{
+		aet:            aet,
+		cycleCount:     0,
+		opStackEntries: make([]*OpStackEntry, 0),
+		ramEntries:     make([]*RAMEntry, 0),
 	}, nil
 }
 
 // RecordState records the VM state before instruction execution
-func (str *SimpleTraceRecorder) RecordState(vm *VMState) error {
+func (tr *TraceRecorder) RecordState(vm *VMState) error {
 	// Track instruction multiplicity
-	if vm.InstructionPointer < len(str.aet.InstructionMultiplicities) {
-		str.aet.InstructionMultiplicities[vm.InstructionPointer]++
+	if vm.InstructionPointer < len(tr.aet.InstructionMultiplicities) {
+		tr.aet.InstructionMultiplicities[vm.InstructionPointer]++
 	}
 
 	// Record processor state
-	if err := str.recordProcessorState(vm); err != nil {
+	if err := tr.recordProcessorState(vm); err != nil {
 		return err
 	}
 
-	str.cycleCount++
+	// Remember what RecordExecution cannot recover from the state after
+	// execution: the operands and the sponge state the instruction consumed
+	tr.ospBefore = vm.StackPointer
+	for i := range tr.stackBefore {
+		tr.stackBefore[i] = field.Zero
+		if value, err := vm.StackPeek(i); err == nil {
+			tr.stackBefore[i] = value
+		}
+	}
+	tr.spongeBefore = nil
+	if vm.Sponge != nil {
+		tr.spongeBefore = append([]field.Element{}, vm.Sponge.State...)
+	}
+
+	tr.cycleCount++
+	return nil
+}
+
+// RecordExecution records the coprocessor rows caused by the instruction that
+// was just executed
+func (tr *TraceRecorder) RecordExecution(vm *VMState, inst *EncodedInstruction) error {
+	if err := tr.recordOpStackMoves(vm); err != nil {
+		return fmt.Errorf("failed to record op stack moves at cycle %d: %w", vm.CycleCount, err)
+	}
+	tr.recordRAMCalls(vm)
+
+	switch inst.Instruction {
+	case Split, Lt, And, Xor, Log2Floor, Pow, DivMod, PopCount, MerkleStep, MerkleStepMem:
+		if err := tr.recordU32(vm, inst.Instruction); err != nil {
+			return fmt.Errorf("failed to record u32 operation at cycle %d: %w", vm.CycleCount, err)
+		}
+	}
+
+	for ; tr.coProcCursor < len(vm.CoProcessorCalls); tr.coProcCursor++ {
+		call := vm.CoProcessorCalls[tr.coProcCursor]
+		if call.Type != HashCoProcessor {
+			continue
+		}
+		if err := tr.recordHashCall(call); err != nil {
+			return fmt.Errorf("failed to record hash operation at cycle %d: %w", vm.CycleCount, err)
+		}
+	}
+
 	return nil
 }
 
+// rowCounts returns the rows recorded so far in the tables that grow with
+// the execution
+func (tr *TraceRecorder) rowCounts() ProfileCost {
+	return ProfileCost{
+		Cycles:      tr.aet.ProcessorTable.GetHeight(),
+		OpStackRows: len(tr.opStackEntries),
+		RAMRows:     len(tr.ramEntries),
+		HashRows:    tr.aet.HashTable.GetHeight(),
+		U32Rows:     tr.aet.U32Table.GetHeight(),
+	}
+}
+
 // recordProcessorState records the processor state to the processor table
-func (str *SimpleTraceRecorder) recordProcessorState(vm *VMState) error {
-	// Get current instruction
+func (tr *TraceRecorder) recordProcessorState(vm *VMState) error {
+	// Get current instruction (the instruction pointer is a word address)
 	var currentInst Instruction = Nop
-	if vm.InstructionPointer < len(vm.Program.Instructions) {
-		currentInst = vm.Program.Instructions[vm.InstructionPointer].Instruction
+	nia := field.Zero
+	if inst, err := vm.CurrentInstruction(); err == nil {
+		currentInst = inst.Instruction
+		if inst.Argument != nil {
+			nia = *inst.Argument
+		}
 	}
 
-	// Next instruction address
-	nia := vm.InstructionPointer + currentInst.Size()
+	// nia is the next word of the program: the argument, or else the
+	// opcode of the next instruction, which is zero past the program's end
+	if !currentInst.HasArgument() {
+		if next, err := vm.Program.InstructionAt(vm.InstructionPointer + 1); err == nil {
+			nia = field.New(uint64(next.Instruction))
+		}
+	}
 
 	// Instruction bits (simplified to 3 bits for our processor table)
 	opcode := uint32(currentInst)
@@ -78,14 +167,37 @@ func (str *SimpleTraceRecorder) recordProcessorState(vm *VMState) error {
 		jsd = field.New(uint64(top.Destination))
 	}
 
-	// Stack (top 16 elements)
+	// Stack (top 16 elements, st0 first)
 	stack := make([]field.Element, 16)
 	for i := 0; i < 16; i++ {
-		if i < len(vm.Stack) {
-			stack[i] = vm.Stack[len(vm.Stack)-1-i]
-		} else {
-			stack[i] = field.Zero
+		stack[i] = field.Zero
+		if value, err := vm.StackPeek(i); err == nil {
+			stack[i] = value
+		}
+	}
+
+	// Operands from outside the stack: the secret sibling digest of
+	// merkle_step, or the words merkle_step_mem and sponge_absorb_mem read
+	var operands []field.Element
+	readRAM := func(n int) {
+		for i := 0; i < n; i++ {
+			operands = append(operands, vm.RAM[stack[0].Add(field.New(uint64(i)))])
+		}
+	}
+	switch currentInst {
+	case MerkleStep:
+		if vm.DigestPointer < len(vm.SecretDigests) {
+			operands = vm.SecretDigests[vm.DigestPointer]
 		}
+	case MerkleStepMem:
+		readRAM(PoseidonDigestLen)
+	case SpongeAbsorbMem:
+		readRAM(PoseidonRate)
+	}
+
+	var sponge []field.Element
+	if vm.Sponge != nil {
+		sponge = vm.Sponge.State
 	}
 
 	// Create processor state
@@ -93,25 +205,204 @@ func (str *SimpleTraceRecorder) recordProcessorState(vm *VMState) error {
 		Clock:                field.New(vm.CycleCount),
 		InstructionPointer:   field.New(uint64(vm.InstructionPointer)),
 		CurrentInstruction:   field.New(uint64(currentInst)),
-		NextInstructionOrArg: field.New(uint64(nia)),
+		NextInstructionOrArg: nia,
 		InstructionBit0:      ib0,
 		InstructionBit1:      ib1,
 		InstructionBit2:      ib2,
 		JumpStackPointer:     jsp,
 		JumpStackOrigin:      jso,
 		JumpStackDestination: jsd,
+		OpStackPointer:       field.New(uint64(vm.StackPointer)),
 		Stack:                stack,
+		Operands:             operands,
+		Sponge:               sponge,
+		SpongeInitialized:    vm.Sponge != nil,
+	}
+
+	return tr.aet.ProcessorTable.AddRow(state)
+}
+
+// recordOpStackMoves buffers one op stack row per element the instruction
+// moved between st15 and the stack underflow memory
+//
+// Growing the stack by d moves st15, ..., st(16-d) to the pointers osp,
+// ..., osp + d - 1; shrinking it by d refills st'15, ..., st'(16-d) from the
+// pointers osp', ..., osp' + d - 1. Registers below the bottom of a shorter
+// stack do not move.
+func (tr *TraceRecorder) recordOpStackMoves(vm *VMState) error {
+	clock := field.New(vm.CycleCount)
+	grows := vm.StackPointer > tr.ospBefore
+	osp, moved := tr.ospBefore, vm.StackPointer-tr.ospBefore
+	if !grows {
+		osp, moved = vm.StackPointer, tr.ospBefore-vm.StackPointer
+	}
+
+	for k := 0; k < moved && k < 16; k++ {
+		register := 15 - k
+		if register >= osp {
+			continue
+		}
+		value, shrink := tr.stackBefore[register], field.Zero
+		if !grows {
+			var err error
+			if value, err = vm.StackPeek(register); err != nil {
+				return err
+			}
+			shrink = field.One
+		}
+		tr.opStackEntries = append(tr.opStackEntries, &OpStackEntry{
+			Clock:                 clock,
+			IB1ShrinkStack:        shrink,
+			StackPointer:          field.New(uint64(osp + k)),
+			FirstUnderflowElement: value,
+		})
 	}
+	return nil
+}
 
-	return str.aet.ProcessorTable.AddRow(state)
+// recordRAMCalls buffers one RAM row per memory read or write
+func (tr *TraceRecorder) recordRAMCalls(vm *VMState) {
+	for ; tr.ramCursor < len(vm.RAMCalls); tr.ramCursor++ {
+		call := vm.RAMCalls[tr.ramCursor]
+		instructionType := field.New(RAMInstructionRead)
+		if call.IsWrite {
+			instructionType = field.New(RAMInstructionWrite)
+		}
+		tr.ramEntries = append(tr.ramEntries, &RAMEntry{
+			Clock:           field.New(call.Clock),
+			InstructionType: instructionType,
+			RAMPointer:      call.Address,
+			RAMValue:        call.Value,
+		})
+	}
+}
+
+// recordU32 adds a U32 Table section for every u32 operation the
+// instruction sends to the U32 Table; see newInstructionTransition
+//
+// Binary operations take st1 as lhs and st0 as rhs. div_mod proves that the
+// remainder is less than the divisor and that the dividend and quotient are
+// u32s, and the Merkle steps that the node index and its parent's are.
+func (tr *TraceRecorder) recordU32(vm *VMState, inst Instruction) error {
+	before := tr.stackBefore
+	var after [2]field.Element
+	for i := range after {
+		if value, err := vm.StackPeek(i); err == nil {
+			after[i] = value
+		}
+	}
+
+	type operation struct {
+		inst     Instruction
+		lhs, rhs field.Element
+	}
+	var operations []operation
+	switch inst {
+	case Split:
+		operations = []operation{{Split, after[0], after[1]}}
+	case Lt, And, Xor, Pow:
+		operations = []operation{{inst, before[1], before[0]}}
+	case Log2Floor, PopCount:
+		operations = []operation{{inst, before[0], field.Zero}}
+	case DivMod:
+		operations = []operation{{Lt, after[0], before[0]}, {Split, before[1], after[1]}}
+	case MerkleStep:
+		parent, err := vm.StackPeek(5)
+		if err != nil {
+			return err
+		}
+		operations = []operation{{Split, before[5], parent}}
+	case MerkleStepMem:
+		operations = []operation{{Split, before[1], field.New(before[1].Value() >> 1)}}
+	}
+
+	for _, op := range operations {
+		if err := tr.aet.U32Table.AddSection(op.inst, op.lhs, op.rhs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordHashCall records one Poseidon permutation, round by round
+//
+// Fixed-length hashes (hash, merkle_step) permute their 10 inputs with a
+// capacity of ones (see poseidonFixedLengthState). Sponge instructions permute the sponge state as it was
+// before the instruction, with any absorbed input added to the rate.
+func (tr *TraceRecorder) recordHashCall(call CoProcessorCall) error {
+	data, ok := call.Data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("hash coprocessor call without data")
+	}
+	operation, _ := data["operation"].(string)
+	input, _ := data["input"].([]field.Element)
+
+	var state [PoseidonStateSize]field.Element
+	sponge := false
+	switch operation {
+	case "hash", "merkle_step", "merkle_step_mem":
+		state = poseidonFixedLengthState(input)
+	case "sponge_absorb", "sponge_absorb_mem", "sponge_squeeze":
+		sponge = true
+		if tr.spongeBefore == nil {
+			return fmt.Errorf("%s without sponge state", operation)
+		}
+		copy(state[:], tr.spongeBefore)
+		for i := 0; i < len(input) && i < PoseidonRate; i++ {
+			state[i] = state[i].Add(input[i])
+		}
+	default:
+		return fmt.Errorf("unknown hash operation %q", operation)
+	}
+
+	return tr.aet.HashTable.addPermutation(state, sponge)
 }
 
 // GenerateAET finalizes and returns the AET
-func (str *SimpleTraceRecorder) GenerateAET() (*AET, error) {
+func (tr *TraceRecorder) GenerateAET() (*AET, error) {
+	// Memory-like tables are sorted by pointer, then by clock, so that
+	// consecutive rows of the same pointer can be checked for consistency
+	sort.SliceStable(tr.opStackEntries, func(i, j int) bool {
+		return tr.opStackEntries[i].StackPointer.Value() < tr.opStackEntries[j].StackPointer.Value()
+	})
+	for _, entry := range tr.opStackEntries {
+		if err := tr.aet.OpStackTable.AddRow(entry); err != nil {
+			return nil, fmt.Errorf("failed to add op stack row: %w", err)
+		}
+	}
+
+	sort.SliceStable(tr.ramEntries, func(i, j int) bool {
+		return tr.ramEntries[i].RAMPointer.Value() < tr.ramEntries[j].RAMPointer.Value()
+	})
+	for i, entry := range tr.ramEntries {
+		if i+1 < len(tr.ramEntries) {
+			entry.InverseRampDifference = inverseOrZero(tr.ramEntries[i+1].RAMPointer.Sub(entry.RAMPointer))
+		}
+		if err := tr.aet.RAMTable.AddRow(entry); err != nil {
+			return nil, fmt.Errorf("failed to add RAM row: %w", err)
+		}
+	}
+
+	// Populate the cascade and lookup tables from the u32 operations
+	if err := tr.aet.FinalizeLookupTables(); err != nil {
+		return nil, fmt.Errorf("failed to finalize lookup tables: %w", err)
+	}
+
 	// Pad all tables
-	if err := str.aet.Pad(); err != nil {
+	if err := tr.aet.Pad(); err != nil {
 		return nil, fmt.Errorf("failed to pad AET: %w", err)
 	}
+	if err := tr.aet.FillJumpStackTable(); err != nil {
+		return nil, fmt.Errorf("failed to fill jump stack table: %w", err)
+	}
 
-	return str.aet, nil
+	return tr.aet, nil
+}
+
+// inverseOrZero returns the multiplicative inverse of x, or zero if x is zero
+func inverseOrZero(x field.Element) field.Element {
+	if x.IsZero() {
+		return field.Zero
+	}
+	return x.Inverse()
 }
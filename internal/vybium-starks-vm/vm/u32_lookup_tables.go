@@ -4,8 +4,11 @@ package vm
 
 import (
 	"fmt"
+	"math"
+	"math/bits"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
@@ -31,6 +34,14 @@ func Lookup8Bit(x byte) field.Element {
 // U32TableImpl implements the U32 Table
 // This table handles 32-bit operations: AND, OR, XOR, shifts, etc.
 //
+// Every operation the Processor Table looks up is proven by a section of
+// rows, one per bit of its operands: row i holds the operands shifted right
+// by i bits and the operation's result on them, which follows from row
+// i + 1's and the bits shifted out. A row whose selectors are all zero ends
+// the section with empty operands and the operation's initial result, and
+// also pads the table. Since a section has at most 32 rows, its operands
+// are u32s.
+//
 // Main purpose: Prove correctness of 32-bit operations via lookup arguments
 type U32TableImpl struct {
 	// Main columns (BField elements)
@@ -45,8 +56,13 @@ type U32TableImpl struct {
 	result             []field.Element // Result of U32 operation
 	lookupMultiplicity []field.Element // How many times this row is looked up
 
+	// One selector per entry of u32Instructions, set in the rows of a
+	// section of that operation, and whether lt's operands are equal so far
+	selectors [len(u32Instructions)][]field.Element
+	equal     []field.Element
+
 	// Auxiliary columns (XField elements for cross-table arguments)
-	lookupLogDeriv []field.Element // Log derivative for lookup argument (server side)
+	lookupLogDeriv []xfield.XFieldElement // Log derivative for lookup argument (server side)
 
 	height       int
 	paddedHeight int
@@ -65,7 +81,9 @@ func NewU32Table() *U32TableImpl {
 		rhsInv:             make([]field.Element, 0),
 		result:             make([]field.Element, 0),
 		lookupMultiplicity: make([]field.Element, 0),
-		lookupLogDeriv:     make([]field.Element, 0),
+		selectors:          [len(u32Instructions)][]field.Element{},
+		equal:              make([]field.Element, 0),
+		lookupLogDeriv:     make([]xfield.XFieldElement, 0),
 		height:             0,
 		paddedHeight:       0,
 	}
@@ -80,15 +98,17 @@ func (ut *U32TableImpl) GetHeight() int       { return ut.height }
 func (ut *U32TableImpl) GetPaddedHeight() int { return ut.paddedHeight }
 
 func (ut *U32TableImpl) GetMainColumns() [][]field.Element {
-	return [][]field.Element{
+	columns := [][]field.Element{
 		ut.copyFlag, ut.bits, ut.bitsMinus33Inv, ut.ci,
 		ut.lhs, ut.lhsInv, ut.rhs, ut.rhsInv,
 		ut.result, ut.lookupMultiplicity,
 	}
+	columns = append(columns, ut.selectors[:]...)
+	return append(columns, ut.equal)
 }
 
-func (ut *U32TableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{ut.lookupLogDeriv}
+func (ut *U32TableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{ut.lookupLogDeriv}
 }
 
 func (ut *U32TableImpl) AddRow(entry *U32Entry) error {
@@ -106,16 +126,158 @@ func (ut *U32TableImpl) AddRow(entry *U32Entry) error {
 	ut.rhsInv = append(ut.rhsInv, entry.RHSInv)
 	ut.result = append(ut.result, entry.Result)
 	ut.lookupMultiplicity = append(ut.lookupMultiplicity, entry.LookupMultiplicity)
-	ut.lookupLogDeriv = append(ut.lookupLogDeriv, field.Zero)
+	for k, inst := range u32Instructions {
+		selected := field.Zero
+		if entry.Selected && entry.CurrentInstruction.Equal(field.New(uint64(inst))) {
+			selected = field.One
+		}
+		ut.selectors[k] = append(ut.selectors[k], selected)
+	}
+	ut.equal = append(ut.equal, entry.Equal)
+	ut.lookupLogDeriv = append(ut.lookupLogDeriv, xfield.Zero)
 
 	ut.height++
 	return nil
 }
 
+// u32Instructions are the operations the U32 Table proves, in the order of
+// its selector columns
+var u32Instructions = [...]Instruction{Split, Lt, And, Xor, Log2Floor, Pow, PopCount}
+
+// u32Selector returns the selector column of inst, which must be one of
+// u32Instructions
+func u32Selector(inst Instruction) int {
+	for k, candidate := range u32Instructions {
+		if candidate == inst {
+			return u32Selector0 + k
+		}
+	}
+	panic(fmt.Sprintf("%s is not a u32 instruction", inst))
+}
+
+// u32InitialResult returns the result of inst on empty operands, which the
+// row ending its section holds: floor(log2(0)) counts as -1, and x^0 = 1
+func u32InitialResult(inst Instruction) field.Element {
+	switch inst {
+	case Log2Floor:
+		return field.Zero.Sub(field.One)
+	case Pow:
+		return field.One
+	}
+	return field.Zero
+}
+
+// AddSection adds the section proving the u32 operation inst on lhs and rhs
+// and the row ending it; see U32TableImpl
+//
+// pow's lhs is the base, which is squared instead of shifted from row to
+// row, and need not be a u32.
+func (ut *U32TableImpl) AddSection(inst Instruction, lhs, rhs field.Element) error {
+	isU32Instruction := false
+	for _, candidate := range u32Instructions {
+		isU32Instruction = isU32Instruction || candidate == inst
+	}
+	if !isU32Instruction {
+		return fmt.Errorf("%s is not a u32 instruction", inst)
+	}
+	l, r := lhs.Value(), rhs.Value()
+	if r > math.MaxUint32 || (inst != Pow && l > math.MaxUint32) {
+		return fmt.Errorf("%s operands %d and %d are not u32s", inst, l, r)
+	}
+
+	length := bits.Len64(r)
+	if inst != Pow {
+		length = max(length, bits.Len64(l))
+	}
+	length = max(length, 1)
+
+	lhsColumn := make([]field.Element, length+1)
+	rhsColumn := make([]field.Element, length+1)
+	for i := range lhsColumn {
+		rhsColumn[i] = field.New(r >> i)
+		lhsColumn[i] = field.New(l >> i)
+		if inst == Pow {
+			lhsColumn[i] = lhs
+			lhs = lhs.Mul(lhs)
+		}
+	}
+
+	// Results follow from the row below, starting at the ending row
+	results := make([]field.Element, length+1)
+	equal := make([]field.Element, length+1)
+	results[length], equal[length] = u32InitialResult(inst), field.One
+	for i := length - 1; i >= 0; i-- {
+		a, b := (l>>i)&1, (r>>i)&1
+		next := results[i+1]
+		equal[i] = equal[i+1]
+		if a != b {
+			equal[i] = field.Zero
+		}
+		switch inst {
+		case Split:
+			results[i] = field.Zero
+		case Lt:
+			results[i] = next
+			if a == 0 && b == 1 {
+				results[i] = next.Add(equal[i+1])
+			}
+		case And:
+			results[i] = next.Add(next).Add(field.New(a & b))
+		case Xor:
+			results[i] = next.Add(next).Add(field.New(a ^ b))
+		case Log2Floor:
+			results[i] = next
+			if l>>i != 0 {
+				results[i] = next.Add(field.One)
+			}
+		case Pow:
+			results[i] = next
+			if b == 1 {
+				results[i] = next.Mul(lhsColumn[i])
+			}
+		case PopCount:
+			results[i] = next.Add(field.New(a))
+		}
+	}
+
+	opcode := field.New(uint64(inst))
+	for i := 0; i <= length; i++ {
+		bitCount := field.New(uint64(i))
+		firstRow := field.Zero
+		if i == 0 {
+			firstRow = field.One
+		}
+		if err := ut.AddRow(&U32Entry{
+			CopyFlag:           firstRow,
+			Bits:               bitCount,
+			BitsMinus33Inv:     bitCount.Sub(field.New(33)).Inverse(),
+			CurrentInstruction: opcode,
+			LHS:                lhsColumn[i],
+			LHSInv:             inverseOrZero(lhsColumn[i]),
+			RHS:                rhsColumn[i],
+			RHSInv:             inverseOrZero(rhsColumn[i]),
+			Result:             results[i],
+			LookupMultiplicity: firstRow,
+			Selected:           i < length,
+			Equal:              equal[i],
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pad pads the table with copies of its last row, which ends a section and
+// is not looked up; an empty table gets an all-zero row
 func (ut *U32TableImpl) Pad(targetHeight int) error {
-	if targetHeight < ut.height || ut.height == 0 {
+	if targetHeight < ut.height || targetHeight == 0 {
 		return fmt.Errorf("invalid padding: target=%d, current=%d", targetHeight, ut.height)
 	}
+	if ut.height == 0 {
+		if err := ut.AddRow(&U32Entry{BitsMinus33Inv: field.Zero.Sub(field.New(33)).Inverse()}); err != nil {
+			return err
+		}
+	}
 
 	lastIdx := ut.height - 1
 	for i := ut.height; i < targetHeight; i++ {
@@ -129,6 +291,10 @@ func (ut *U32TableImpl) Pad(targetHeight int) error {
 		ut.rhsInv = append(ut.rhsInv, ut.rhsInv[lastIdx])
 		ut.result = append(ut.result, ut.result[lastIdx])
 		ut.lookupMultiplicity = append(ut.lookupMultiplicity, field.Zero)
+		for k := range ut.selectors {
+			ut.selectors[k] = append(ut.selectors[k], ut.selectors[k][lastIdx])
+		}
+		ut.equal = append(ut.equal, ut.equal[lastIdx])
 		ut.lookupLogDeriv = append(ut.lookupLogDeriv, ut.lookupLogDeriv[lastIdx])
 	}
 
@@ -136,24 +302,359 @@ func (ut *U32TableImpl) Pad(targetHeight int) error {
 	return nil
 }
 
-func (ut *U32TableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil // Constraints documented inline in transition
+// U32 Table main column indices, in GetMainColumns order
+const (
+	u32CopyFlag = iota
+	u32Bits
+	u32BitsMinus33Inv
+	u32CI
+	u32LHS
+	u32LHSInv
+	u32RHS
+	u32RHSInv
+	u32Result
+	u32LookupMultiplicity
+	u32Selector0
+	u32Equal = u32Selector0 + len(u32Instructions)
+)
+
+// u32SectionSelector returns the sum of a row's selectors, which is 1 in
+// the rows of a section and 0 in the rows ending one
+func u32SectionSelector(row []xfield.XFieldElement) xfield.XFieldElement {
+	sum := xfield.Zero
+	for k := range u32Instructions {
+		sum = sum.Add(row[u32Selector0+k])
+	}
+	return sum
+}
+
+func (ut *U32TableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// A section starts with the row that is looked up:
+	// section * (1 - copyFlag) = 0
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "u32_first_section_starts_with_copy_flag",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return u32SectionSelector(row).Mul(xfield.One.Sub(row[u32CopyFlag]))
+			},
+		},
+	}, nil
+}
+
+func (ut *U32TableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// Consistency constraints: copyFlag and the selectors are bits with at
+	// most one selector set, which matches ci; only the first row of a
+	// section is looked up; bitsMinus33Inv is the inverse of (bits - 33),
+	// and lhsInv, rhsInv are the inverses of their operands or zero
+	isInverseOrZero := func(x, xInv xfield.XFieldElement) (xfield.XFieldElement, xfield.XFieldElement) {
+		notInverse := x.Mul(xInv).Sub(xfield.One)
+		return x.Mul(notInverse), xInv.Mul(notInverse)
+	}
+	constraints := []*protocols.ConstraintPolynomial{
+		{
+			Name:   "u32_copy_flag_is_bit",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(row[u32CopyFlag])
+			},
+		},
+		{
+			Name:   "u32_bits_minus_33_inverse",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				bitsMinus33 := row[u32Bits].SubConst(field.New(33))
+				return bitsMinus33.Mul(row[u32BitsMinus33Inv]).Sub(xfield.One)
+			},
+		},
+		{
+			Name:   "u32_lhs_inverse_is_zero_or_inverse",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				_, inv := isInverseOrZero(row[u32LHS], row[u32LHSInv])
+				return inv
+			},
+		},
+		{
+			Name:   "u32_lhs_is_zero_or_has_inverse",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				x, _ := isInverseOrZero(row[u32LHS], row[u32LHSInv])
+				return x
+			},
+		},
+		{
+			Name:   "u32_rhs_inverse_is_zero_or_inverse",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				_, inv := isInverseOrZero(row[u32RHS], row[u32RHSInv])
+				return inv
+			},
+		},
+		{
+			Name:   "u32_rhs_is_zero_or_has_inverse",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				x, _ := isInverseOrZero(row[u32RHS], row[u32RHSInv])
+				return x
+			},
+		},
+		{
+			Name:   "u32_section_selector_is_bit",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(u32SectionSelector(row))
+			},
+		},
+		{
+			Name:   "u32_selectors_match_ci",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				sum := xfield.Zero
+				for k, inst := range u32Instructions {
+					sum = sum.Add(row[u32Selector0+k].Mul(row[u32CI].SubConst(field.New(uint64(inst)))))
+				}
+				return sum
+			},
+		},
+		{
+			Name:   "u32_copy_flag_is_in_section",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[u32CopyFlag].Mul(xfield.One.Sub(u32SectionSelector(row)))
+			},
+		},
+		{
+			Name:   "u32_copy_flag_starts_at_bit_0",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[u32CopyFlag].Mul(row[u32Bits])
+			},
+		},
+		{
+			Name:   "u32_only_copy_flag_rows_are_looked_up",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[u32LookupMultiplicity].Mul(xfield.One.Sub(row[u32CopyFlag]))
+			},
+		},
+		{
+			Name:   "u32_log2_floor_operand_is_nonzero",
+			Degree: 4,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				isNonzero := row[u32LHS].Mul(row[u32LHSInv])
+				return row[u32Selector(Log2Floor)].Mul(row[u32CopyFlag]).Mul(xfield.One.Sub(isNonzero))
+			},
+		},
+	}
+	for k, inst := range u32Instructions {
+		col := u32Selector0 + k
+		constraints = append(constraints, &protocols.ConstraintPolynomial{
+			Name:   fmt.Sprintf("u32_%s_selector_is_bit", inst),
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(row[col])
+			},
+		})
+	}
+	return constraints, nil
 }
 
-func (ut *U32TableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	// Consistency constraints: copyFlag, lhsInv, rhsInv are boolean or inverses
-	// Actual polynomial generation during proving
-	return []protocols.AIRConstraint{}, nil
+func (ut *U32TableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
+	// Within a section, with a = lhs - 2·lhs' and b = rhs - 2·rhs' the bits
+	// shifted out, bits counts up, ci stays the same, a and b are bits (but
+	// for pow, whose lhs' = lhs²), and the result follows from the next
+	// row's:
+	//
+	//	and:       result = 2·result' + a·b
+	//	xor:       result = 2·result' + a + b - 2·a·b
+	//	pop_count: result = result' + a
+	//	log_2:     result = result' + lhs·lhsInv
+	//	pow:       result = result'·(1 + b·(lhs - 1))
+	//	lt:        result = result' + equal'·(1 - a)·b,  equal = equal'·(1 - a - b + 2·a·b)
+	//	split:     result = 0
+	//
+	// A section ends in a row with empty operands and the initial result,
+	// after which a new section starts with its looked up row.
+	bitOf := func(current, next []xfield.XFieldElement, col int) xfield.XFieldElement {
+		return current[col].Sub(next[col].Add(next[col]))
+	}
+	selected := func(inst Instruction, degree int, eval func(current, next []xfield.XFieldElement) xfield.XFieldElement) *protocols.TransitionConstraintPolynomial {
+		return &protocols.TransitionConstraintPolynomial{
+			Name:   fmt.Sprintf("u32_%s_result", inst),
+			Degree: degree + 1,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return current[u32Selector(inst)].Mul(eval(current, next))
+			},
+		}
+	}
+	constraints := []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "u32_section_is_followed_by_copy_flag_or_end",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return u32SectionSelector(current).Mul(next[u32CopyFlag])
+			},
+		},
+		{
+			Name:   "u32_end_is_followed_by_new_section_or_end",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				notInSection := xfield.One.Sub(u32SectionSelector(current))
+				return notInSection.Mul(u32SectionSelector(next)).Mul(xfield.One.Sub(next[u32CopyFlag]))
+			},
+		},
+		{
+			Name:   "u32_bits_count_up_in_section",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return u32SectionSelector(current).Mul(next[u32Bits].Sub(current[u32Bits]).Sub(xfield.One))
+			},
+		},
+		{
+			Name:   "u32_ci_is_unchanged_in_section",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return u32SectionSelector(current).Mul(next[u32CI].Sub(current[u32CI]))
+			},
+		},
+		{
+			Name:   "u32_lhs_shifts_out_a_bit",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				shifts := u32SectionSelector(current).Sub(current[u32Selector(Pow)])
+				return shifts.Mul(isBit(bitOf(current, next, u32LHS)))
+			},
+		},
+		{
+			Name:   "u32_rhs_shifts_out_a_bit",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return u32SectionSelector(current).Mul(isBit(bitOf(current, next, u32RHS)))
+			},
+		},
+		{
+			Name:   "u32_section_ends_with_empty_lhs",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				shifts := u32SectionSelector(current).Sub(current[u32Selector(Pow)])
+				return shifts.Mul(xfield.One.Sub(u32SectionSelector(next))).Mul(next[u32LHS])
+			},
+		},
+		{
+			Name:   "u32_section_ends_with_empty_rhs",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return u32SectionSelector(current).Mul(xfield.One.Sub(u32SectionSelector(next))).Mul(next[u32RHS])
+			},
+		},
+		{
+			Name:   "u32_lt_section_ends_with_equal_operands",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				ends := current[u32Selector(Lt)].Mul(xfield.One.Sub(u32SectionSelector(next)))
+				return ends.Mul(next[u32Equal].Sub(xfield.One))
+			},
+		},
+		{
+			Name:   "u32_pow_squares_base",
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return current[u32Selector(Pow)].Mul(next[u32LHS].Sub(current[u32LHS].Mul(current[u32LHS])))
+			},
+		},
+		{
+			Name:   "u32_lt_equal",
+			Degree: 4,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				a, b := bitOf(current, next, u32LHS), bitOf(current, next, u32RHS)
+				bitsEqual := xfield.One.Sub(a).Sub(b).Add(a.Mul(b).Add(a.Mul(b)))
+				return current[u32Selector(Lt)].Mul(current[u32Equal].Sub(next[u32Equal].Mul(bitsEqual)))
+			},
+		},
+		selected(Split, 1, func(current, _ []xfield.XFieldElement) xfield.XFieldElement {
+			return current[u32Result]
+		}),
+		selected(Lt, 3, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			a, b := bitOf(current, next, u32LHS), bitOf(current, next, u32RHS)
+			decided := next[u32Equal].Mul(xfield.One.Sub(a)).Mul(b)
+			return current[u32Result].Sub(next[u32Result]).Sub(decided)
+		}),
+		selected(And, 2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			a, b := bitOf(current, next, u32LHS), bitOf(current, next, u32RHS)
+			return current[u32Result].Sub(next[u32Result].Add(next[u32Result])).Sub(a.Mul(b))
+		}),
+		selected(Xor, 2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			a, b := bitOf(current, next, u32LHS), bitOf(current, next, u32RHS)
+			differ := a.Add(b).Sub(a.Mul(b).Add(a.Mul(b)))
+			return current[u32Result].Sub(next[u32Result].Add(next[u32Result])).Sub(differ)
+		}),
+		selected(Log2Floor, 2, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			isNonzero := current[u32LHS].Mul(current[u32LHSInv])
+			return current[u32Result].Sub(next[u32Result]).Sub(isNonzero)
+		}),
+		selected(Pow, 3, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			b := bitOf(current, next, u32RHS)
+			factor := xfield.One.Add(b.Mul(current[u32LHS].Sub(xfield.One)))
+			return current[u32Result].Sub(next[u32Result].Mul(factor))
+		}),
+		selected(PopCount, 1, func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+			return current[u32Result].Sub(next[u32Result]).Sub(bitOf(current, next, u32LHS))
+		}),
+	}
+	for _, inst := range u32Instructions {
+		inst := inst
+		initial := xfield.NewConst(u32InitialResult(inst))
+		constraints = append(constraints, &protocols.TransitionConstraintPolynomial{
+			Name:   fmt.Sprintf("u32_%s_section_ends_with_initial_result", inst),
+			Degree: 3,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				ends := current[u32Selector(inst)].Mul(xfield.One.Sub(u32SectionSelector(next)))
+				return ends.Mul(next[u32Result].Sub(initial))
+			},
+		})
+	}
+	return constraints, nil
 }
 
-func (ut *U32TableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	// U32 operations must satisfy their operation semantics (AND, OR, XOR, etc.)
-	// This is verified via the lookup argument with precomputed tables
-	return []protocols.AIRConstraint{}, nil
+func (ut *U32TableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// The last section is ended
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "u32_last_row_ends_section",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return u32SectionSelector(row)
+			},
+		},
+	}, nil
 }
 
-func (ut *U32TableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+// UpdateLookupLogDerivative computes the log derivative of the operations
+// the Processor Table looks up
+//
+//	ld[0]·(α - c[0]) = m[0],  (ld' - ld)·(α - c') = m'
+//
+// with c compressing (ci, lhs, rhs, result)
+func (ut *U32TableImpl) UpdateLookupLogDerivative(challenges map[string]xfield.XFieldElement) error {
+	if ut.height == 0 {
+		return fmt.Errorf("cannot update lookup log derivative on empty table")
+	}
+	weights, err := tupleWeights(challenges, challengeU32Indeterminate, numU32Weights)
+	if err != nil {
+		return err
+	}
+
+	logDerivative := xfield.Zero
+	for i := range ut.ci {
+		if multiplicity := ut.lookupMultiplicity[i]; !multiplicity.IsZero() {
+			compressed := weights.compressBase(ut.ci[i], ut.lhs[i], ut.rhs[i], ut.result[i])
+			logDerivative = logDerivative.Add(weights.indeterminate.Sub(compressed).Inverse().MulConst(multiplicity))
+		}
+		ut.lookupLogDeriv[i] = logDerivative
+	}
+
+	return nil
 }
 
 // U32Entry represents a U32 table entry
@@ -168,6 +669,8 @@ type U32Entry struct {
 	RHSInv             field.Element
 	Result             field.Element
 	LookupMultiplicity field.Element
+	Selected           bool          // Whether the row is in a section of ci
+	Equal              field.Element // Whether lt's operands are equal so far
 }
 
 // CascadeTableImpl implements the TIP-0005 Cascade Table
@@ -196,8 +699,8 @@ type CascadeTableImpl struct {
 	isPadding          []field.Element // Padding indicator
 
 	// Auxiliary columns (XField elements) - TIP-0005 compliant
-	hashTableLogDeriv   []field.Element // Log derivative for hash table (server)
-	lookupTableLogDeriv []field.Element // Log derivative for 8-bit lookup (client)
+	hashTableLogDeriv   []xfield.XFieldElement // Log derivative for hash table (server)
+	lookupTableLogDeriv []xfield.XFieldElement // Log derivative for 8-bit lookup (client)
 
 	height       int
 	paddedHeight int
@@ -212,8 +715,8 @@ func NewCascadeTable() *CascadeTableImpl {
 		lookOutLo:           make([]field.Element, 0),
 		lookupMultiplicity:  make([]field.Element, 0),
 		isPadding:           make([]field.Element, 0),
-		hashTableLogDeriv:   make([]field.Element, 0),
-		lookupTableLogDeriv: make([]field.Element, 0),
+		hashTableLogDeriv:   make([]xfield.XFieldElement, 0),
+		lookupTableLogDeriv: make([]xfield.XFieldElement, 0),
 		height:              0,
 		paddedHeight:        0,
 	}
@@ -234,8 +737,8 @@ func (ct *CascadeTableImpl) GetMainColumns() [][]field.Element {
 	}
 }
 
-func (ct *CascadeTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{
+func (ct *CascadeTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{
 		ct.hashTableLogDeriv,
 		ct.lookupTableLogDeriv,
 	}
@@ -261,8 +764,8 @@ func (ct *CascadeTableImpl) AddRow(input16 uint16, multiplicity uint64) error {
 	ct.isPadding = append(ct.isPadding, field.Zero)
 
 	// Initialize auxiliary columns to zero (will be filled during extension)
-	ct.hashTableLogDeriv = append(ct.hashTableLogDeriv, field.Zero)
-	ct.lookupTableLogDeriv = append(ct.lookupTableLogDeriv, field.Zero)
+	ct.hashTableLogDeriv = append(ct.hashTableLogDeriv, xfield.Zero)
+	ct.lookupTableLogDeriv = append(ct.lookupTableLogDeriv, xfield.Zero)
 
 	ct.height++
 	return nil
@@ -290,20 +793,103 @@ func (ct *CascadeTableImpl) Pad(targetHeight int) error {
 	return nil
 }
 
-func (ct *CascadeTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+// UpdateLookupTableLogDerivative computes the client side of the lookup
+// argument with the Lookup Table
+//
+// Every row looks up both of its (input, output) limb pairs, weighted by the
+// row's multiplicity:
+// ld[i] = ld[i-1] + m[i]·(1/(β - c_lo[i]) + 1/(β - c_hi[i]))
+func (ct *CascadeTableImpl) UpdateLookupTableLogDerivative(challenges map[string]xfield.XFieldElement) error {
+	weights, err := lookupWeights(challenges)
+	if err != nil {
+		return err
+	}
+
+	logDerivative := xfield.Zero
+	for i := range ct.lookupTableLogDeriv {
+		lo := weights.indeterminate.Sub(weights.compress(ct.lookInLo[i], ct.lookOutLo[i]))
+		hi := weights.indeterminate.Sub(weights.compress(ct.lookInHi[i], ct.lookOutHi[i]))
+		logDerivative = logDerivative.Add(lo.Inverse().Add(hi.Inverse()).MulConst(ct.lookupMultiplicity[i]))
+		ct.lookupTableLogDeriv[i] = logDerivative
+	}
+	return nil
 }
 
-func (ct *CascadeTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+// Cascade Table main column indices, in GetMainColumns order
+const (
+	cascadeLookInHi = iota
+	cascadeLookInLo
+	cascadeLookOutHi
+	cascadeLookOutLo
+	cascadeLookupMultiplicity
+	cascadeIsPadding
+)
+
+// lookup8BitPolynomial evaluates L(x) = (x+1)^3 - 1 on a field element
+func lookup8BitPolynomial(x xfield.XFieldElement) xfield.XFieldElement {
+	xPlusOne := x.Add(xfield.One)
+	return xPlusOne.Mul(xPlusOne).Mul(xPlusOne).Sub(xfield.One)
 }
 
-func (ct *CascadeTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+func (ct *CascadeTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
-func (ct *CascadeTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+func (ct *CascadeTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// Consistency constraints:
+	// 1. isPadding is boolean
+	// 2. Both output limbs are the 8-bit lookup of their input limbs
+	// 3. Padding rows are never looked up
+	//
+	// That the input limbs are bytes is proven by the lookup argument with
+	// the Lookup Table.
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "cascade_is_padding_is_bit",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return isBit(row[cascadeIsPadding])
+			},
+		},
+		{
+			Name:   "cascade_look_out_hi_is_lookup_of_look_in_hi",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[cascadeLookOutHi].Sub(lookup8BitPolynomial(row[cascadeLookInHi]))
+			},
+		},
+		{
+			Name:   "cascade_look_out_lo_is_lookup_of_look_in_lo",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[cascadeLookOutLo].Sub(lookup8BitPolynomial(row[cascadeLookInLo]))
+			},
+		},
+		{
+			Name:   "cascade_padding_has_no_multiplicity",
+			Degree: 2,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[cascadeIsPadding].Mul(row[cascadeLookupMultiplicity])
+			},
+		},
+	}, nil
+}
+
+func (ct *CascadeTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
+	// Padding rows are followed by padding rows
+	return []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "cascade_padding_is_followed_by_padding",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				return current[cascadeIsPadding].Mul(next[cascadeIsPadding].Sub(xfield.One))
+			},
+		},
+	}, nil
+}
+
+func (ct *CascadeTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	return []*protocols.ConstraintPolynomial{}, nil
 }
 
 // LookupTableImpl implements the Lookup Table
@@ -315,7 +901,7 @@ type LookupTableImpl struct {
 	lookupMultiplicity []field.Element // How many times this is looked up
 
 	// Auxiliary columns
-	lookupLogDeriv []field.Element
+	lookupLogDeriv []xfield.XFieldElement
 
 	height       int
 	paddedHeight int
@@ -327,7 +913,7 @@ func NewLookupTable() *LookupTableImpl {
 		lookupIndex:        make([]field.Element, 0),
 		lookupValue:        make([]field.Element, 0),
 		lookupMultiplicity: make([]field.Element, 0),
-		lookupLogDeriv:     make([]field.Element, 0),
+		lookupLogDeriv:     make([]xfield.XFieldElement, 0),
 		height:             0,
 		paddedHeight:       0,
 	}
@@ -340,15 +926,15 @@ func (lt *LookupTableImpl) GetMainColumns() [][]field.Element {
 	return [][]field.Element{lt.lookupIndex, lt.lookupValue, lt.lookupMultiplicity}
 }
 
-func (lt *LookupTableImpl) GetAuxiliaryColumns() [][]field.Element {
-	return [][]field.Element{lt.lookupLogDeriv}
+func (lt *LookupTableImpl) GetAuxiliaryColumns() [][]xfield.XFieldElement {
+	return [][]xfield.XFieldElement{lt.lookupLogDeriv}
 }
 
 func (lt *LookupTableImpl) AddRow(index, value, multiplicity field.Element) error {
 	lt.lookupIndex = append(lt.lookupIndex, index)
 	lt.lookupValue = append(lt.lookupValue, value)
 	lt.lookupMultiplicity = append(lt.lookupMultiplicity, multiplicity)
-	lt.lookupLogDeriv = append(lt.lookupLogDeriv, field.Zero)
+	lt.lookupLogDeriv = append(lt.lookupLogDeriv, xfield.Zero)
 	lt.height++
 	return nil
 }
@@ -368,20 +954,84 @@ func (lt *LookupTableImpl) Pad(targetHeight int) error {
 	return nil
 }
 
-func (lt *LookupTableImpl) CreateInitialConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+// UpdateLogDerivative computes the server side of the lookup argument with
+// the Cascade Table
+//
+// ld[i] = ld[i-1] + m[i]/(β - c[i]) where c[i] compresses (index, value)
+func (lt *LookupTableImpl) UpdateLogDerivative(challenges map[string]xfield.XFieldElement) error {
+	weights, err := lookupWeights(challenges)
+	if err != nil {
+		return err
+	}
+
+	logDerivative := xfield.Zero
+	for i := range lt.lookupLogDeriv {
+		denominator := weights.indeterminate.Sub(weights.compress(lt.lookupIndex[i], lt.lookupValue[i]))
+		logDerivative = logDerivative.Add(denominator.Inverse().MulConst(lt.lookupMultiplicity[i]))
+		lt.lookupLogDeriv[i] = logDerivative
+	}
+	return nil
+}
+
+// Lookup Table main column indices, in GetMainColumns order
+const (
+	lookupIndex = iota
+	lookupValue
+	lookupMultiplicity
+)
+
+func (lt *LookupTableImpl) CreateInitialConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// The table starts at index 0
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "lookup_index_starts_at_0",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[lookupIndex]
+			},
+		},
+	}, nil
 }
 
-func (lt *LookupTableImpl) CreateConsistencyConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+func (lt *LookupTableImpl) CreateConsistencyConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// Every value is the 8-bit lookup of its index
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "lookup_value_is_lookup_of_index",
+			Degree: 3,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[lookupValue].Sub(lookup8BitPolynomial(row[lookupIndex]))
+			},
+		},
+	}, nil
 }
 
-func (lt *LookupTableImpl) CreateTransitionConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+func (lt *LookupTableImpl) CreateTransitionConstraints() ([]*protocols.TransitionConstraintPolynomial, error) {
+	// The index increments by 1 or, in padding, stays the same
+	return []*protocols.TransitionConstraintPolynomial{
+		{
+			Name:   "lookup_index_increments_by_0_or_1",
+			Degree: 2,
+			Evaluator: func(current, next []xfield.XFieldElement) xfield.XFieldElement {
+				diff := next[lookupIndex].Sub(current[lookupIndex])
+				return diff.Sub(xfield.One).Mul(diff)
+			},
+		},
+	}, nil
 }
 
-func (lt *LookupTableImpl) CreateTerminalConstraints() ([]protocols.AIRConstraint, error) {
-	return []protocols.AIRConstraint{}, nil
+func (lt *LookupTableImpl) CreateTerminalConstraints() ([]*protocols.ConstraintPolynomial, error) {
+	// Together with the initial and transition constraints, ending at 255
+	// means every byte has a row
+	return []*protocols.ConstraintPolynomial{
+		{
+			Name:   "lookup_index_ends_at_255",
+			Degree: 1,
+			Evaluator: func(row []xfield.XFieldElement) xfield.XFieldElement {
+				return row[lookupIndex].SubConst(field.New(255))
+			},
+		},
+	}, nil
 }
 
 // PrecomputeLookupTable generates precomputed values for common operations
@@ -409,14 +1059,14 @@ func (lt *LookupTableImpl) Fill(multiplicities [256]uint64) error {
 	lt.lookupIndex = make([]field.Element, tableSize)
 	lt.lookupValue = make([]field.Element, tableSize)
 	lt.lookupMultiplicity = make([]field.Element, tableSize)
-	lt.lookupLogDeriv = make([]field.Element, tableSize)
+	lt.lookupLogDeriv = make([]xfield.XFieldElement, tableSize)
 
 	// Generate all 256 lookup pairs using TIP-0005 Tip5 S-box
 	for i := 0; i < tableSize; i++ {
 		lt.lookupIndex[i] = field.New(uint64(i))
 		lt.lookupValue[i] = Lookup8Bit(byte(i)) // TIP-0005 compliant lookup
 		lt.lookupMultiplicity[i] = field.New(multiplicities[i])
-		lt.lookupLogDeriv[i] = field.Zero // Will be filled during extension
+		lt.lookupLogDeriv[i] = xfield.Zero // Will be filled during extension
 	}
 
 	lt.height = tableSize
@@ -1,9 +1,14 @@
 package vm
 
 import (
+	"errors"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/xfield"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
 )
 
 // TestVMStateCreation tests VM state creation for 100% coverage
@@ -272,6 +277,50 @@ func TestStackOperationsError(t *testing.T) {
 	}
 }
 
+// TestStackBeyondRegisters tests that st0..st15 hold the top 16 elements of
+// a deeper stack and that the underflow memory refills st15
+func TestStackBeyondRegisters(t *testing.T) {
+	program := NewProgram()
+	for i := uint64(1); i <= 13; i++ {
+		value := field.New(i)
+		program.AddInstruction(&EncodedInstruction{Instruction: Push, Argument: &value})
+	}
+	zero, fifteen, one := field.New(0), field.New(15), field.New(1)
+	program.AddInstruction(&EncodedInstruction{Instruction: Dup, Argument: &zero})
+	program.AddInstruction(&EncodedInstruction{Instruction: Swap, Argument: &fifteen})
+	program.AddInstruction(&EncodedInstruction{Instruction: WriteIo, Argument: &one})
+	program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+
+	vm := NewVMState(program, []field.Element{}, []field.Element{})
+	digest := vm.StackElements()
+	if _, err := vm.ExecuteAndTrace(); err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+
+	// dup 0 copies 13 with 18 elements on the stack, swap 15 exchanges it
+	// with digest[1] in st15, write_io outputs digest[1] and refills st15
+	if got := vm.PublicOutput[len(vm.PublicOutput)-1]; !got.Equal(digest[1]) {
+		t.Errorf("output = %v, want digest[1] = %v", got, digest[1])
+	}
+	want := []field.Element{}
+	for i := uint64(13); i >= 1; i-- {
+		want = append(want, field.New(i))
+	}
+	want = append(want, digest[0], field.New(13), digest[2], digest[3], digest[4])
+	got := vm.StackElements()
+	if len(got) != len(want) {
+		t.Fatalf("stack has %d elements, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("st%d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if peeked, err := vm.StackPeek(15); err != nil || !peeked.Equal(digest[2]) {
+		t.Errorf("st15 = %v (%v), want digest[2] = %v", peeked, err, digest[2])
+	}
+}
+
 // TestRAMOperations tests RAM operations for 100% coverage
 func TestRAMOperations(t *testing.T) {
 	program := NewProgram()
@@ -296,6 +345,44 @@ func TestRAMOperations(t *testing.T) {
 	}
 }
 
+// TestProgramInstructionAt tests that the pre-decoded instructions match
+// decoding the program's words, and follow AddInstruction
+func TestProgramInstructionAt(t *testing.T) {
+	program := NewProgram()
+	pick := field.New(uint64(Pick)) // decoded at address 1, pick reads the next word
+	program.AddInstruction(&EncodedInstruction{Instruction: Push, Argument: &pick})
+
+	if _, err := program.InstructionAt(1); err == nil {
+		t.Error("decoded pick at address 1 without an argument word")
+	}
+
+	program.AddInstruction(&EncodedInstruction{Instruction: Add})
+	program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+	words := program.ToWords()
+	for address := -1; address <= len(words); address++ {
+		got, err := program.InstructionAt(address)
+		if address < 0 {
+			if err == nil {
+				t.Errorf("address %d: decoded %v", address, got)
+			}
+			continue
+		}
+		want, wantErr := DecodeInstruction(words, address)
+		if (err != nil) != (wantErr != nil) {
+			t.Errorf("address %d: error %v, want %v", address, err, wantErr)
+			continue
+		}
+		if err == nil && (got.Instruction != want.Instruction || (got.Argument == nil) != (want.Argument == nil) ||
+			(got.Argument != nil && !got.Argument.Equal(*want.Argument))) {
+			t.Errorf("address %d: %v, want %v", address, got, want)
+		}
+	}
+
+	if inst, err := program.InstructionAt(1); err != nil || inst.Instruction != Pick || !inst.Argument.Equal(field.New(uint64(Add))) {
+		t.Errorf("address 1: got %v (%v), want pick with the add opcode as argument", inst, err)
+	}
+}
+
 // TestCurrentInstruction tests current instruction for 100% coverage
 func TestCurrentInstruction(t *testing.T) {
 	program := NewProgram()
@@ -379,9 +466,1189 @@ func TestSimpleProgramExecution(t *testing.T) {
 
 	// The result should be 8 (5 + 3) at the top of stack
 	// Top of stack is at StackPointer-1
-	result := vm.Stack[vm.StackPointer-1]
+	result := vm.Stack[0]
 	expected := field.New(8)
 	if !result.Equal(expected) {
 		t.Errorf("Result = %v, want %v", result, expected)
 	}
 }
+
+// TestExecuteAndTraceCoprocessorTables tests that tracing fills every coprocessor table
+func TestExecuteAndTraceCoprocessorTables(t *testing.T) {
+	push := func(program *Program, value uint64) {
+		arg := field.New(value)
+		program.AddInstruction(&EncodedInstruction{Instruction: Push, Argument: &arg})
+	}
+	withArg := func(program *Program, inst Instruction, value uint64) {
+		arg := field.New(value)
+		program.AddInstruction(&EncodedInstruction{Instruction: inst, Argument: &arg})
+	}
+
+	t.Run("MemoryHashU32AndCalls", func(t *testing.T) {
+		program := NewProgram()
+		push(program, 100)
+		push(program, 7)
+		withArg(program, WriteMem, 1)
+		push(program, 100)
+		withArg(program, ReadMem, 1)
+		push(program, 12)
+		push(program, 10)
+		program.AddInstruction(&EncodedInstruction{Instruction: Xor})
+		push(program, 0)
+		push(program, 0)
+		push(program, 0)
+		program.AddInstruction(&EncodedInstruction{Instruction: Hash})
+		withArg(program, Call, uint64(program.Length+3))
+		program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+		program.AddInstruction(&EncodedInstruction{Instruction: Return})
+
+		vm := NewVMState(program, []field.Element{}, []field.Element{})
+		aet, err := vm.ExecuteAndTrace()
+		if err != nil {
+			t.Fatalf("ExecuteAndTrace failed: %v", err)
+		}
+
+		if h := aet.RAMTable.GetHeight(); h < 2 {
+			t.Errorf("RAM table height = %d, want at least 2", h)
+		}
+		if h := aet.JumpStackTable.GetHeight(); h < 2 {
+			t.Errorf("Jump stack table height = %d, want at least 2", h)
+		}
+		if h := aet.HashTable.GetHeight(); h < PoseidonTraceLength {
+			t.Errorf("Hash table height = %d, want at least %d", h, PoseidonTraceLength)
+		}
+		if h := aet.U32Table.GetHeight(); h < 1 {
+			t.Errorf("U32 table height = %d, want at least 1", h)
+		}
+		if h := aet.CascadeTable.GetHeight(); h < 1 {
+			t.Errorf("Cascade table height = %d, want at least 1", h)
+		}
+		if h := aet.LookupTable.GetHeight(); h < 256 {
+			t.Errorf("Lookup table height = %d, want at least 256", h)
+		}
+		if aet.PaddedHeight < aet.HashTable.GetHeight() {
+			t.Errorf("Padded height %d below hash table height %d", aet.PaddedHeight, aet.HashTable.GetHeight())
+		}
+	})
+
+	t.Run("OpStackUnderflow", func(t *testing.T) {
+		program := NewProgram()
+		for i := 0; i < 12; i++ {
+			push(program, uint64(i))
+		}
+		withArg(program, Pop, 1)
+		program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+
+		vm := NewVMState(program, []field.Element{}, []field.Element{})
+		aet, err := vm.ExecuteAndTrace()
+		if err != nil {
+			t.Fatalf("ExecuteAndTrace failed: %v", err)
+		}
+
+		// Pushing onto a full stack spills one element, popping it back reads it
+		if h := aet.OpStackTable.GetHeight(); h < 2 {
+			t.Errorf("Op stack table height = %d, want at least 2", h)
+		}
+	})
+
+	t.Run("OpStackUnderflowIsNotRAM", func(t *testing.T) {
+		program := NewProgram()
+		for i := 0; i < 12; i++ {
+			push(program, uint64(i))
+		}
+		// Write 99 to RAM address 0, then output the deepest spilled element
+		push(program, 99)
+		push(program, 0)
+		withArg(program, WriteMem, 1)
+		withArg(program, WriteIo, 1)
+		program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+
+		vm := NewVMState(program, []field.Element{}, []field.Element{})
+		if _, err := vm.ExecuteAndTrace(); err != nil {
+			t.Fatalf("ExecuteAndTrace failed: %v", err)
+		}
+
+		if got := vm.PublicOutput[len(vm.PublicOutput)-1]; !got.Equal(field.New(11)) {
+			t.Errorf("spilled element = %v, want 11", got)
+		}
+		if len(vm.RAMCalls) != 1 || !vm.RAMCalls[0].IsWrite {
+			t.Errorf("RAM calls = %+v, want only the write_mem", vm.RAMCalls)
+		}
+		if len(vm.OpStackCalls) != 6 {
+			t.Errorf("got %d op stack calls, want 6", len(vm.OpStackCalls))
+		}
+	})
+}
+
+// TestPoseidonDigests pins the 5-element digests of hash, merkle_step and program attestation
+func TestPoseidonDigests(t *testing.T) {
+	elements := func(values ...uint64) []field.Element {
+		result := make([]field.Element, len(values))
+		for i, v := range values {
+			result[i] = field.New(v)
+		}
+		return result
+	}
+	zeroToNine := elements(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	halt := NewProgram()
+	halt.AddInstruction(&EncodedInstruction{Instruction: Halt})
+
+	vectors := []struct {
+		name   string
+		digest [PoseidonDigestLen]field.Element
+		want   []field.Element
+	}{
+		{"hash10(0..9)", poseidonHash10(zeroToNine), elements(
+			7475440617765128116, 5179083567523611021, 15245585429649963891, 13434688215459357444, 13444907019358945313)},
+		{"varlen()", poseidonHashVarlen(nil), elements(
+			377781615920414587, 17384111187191956030, 13125913331251692086, 9074178653909030366, 13905671097079599590)},
+		{"varlen(0..9)", poseidonHashVarlen(zeroToNine), elements(
+			4870667904818570931, 17866040027970948014, 15252574005820090833, 12452136051291839285, 3882376405532396728)},
+		{"program(halt)", computeProgramDigest(halt), elements(
+			18352750047426065060, 3938900541756305085, 6643132090451652448, 12678371902904104360, 10285885588027117520)},
+	}
+	for _, v := range vectors {
+		for i, w := range v.want {
+			if !v.digest[i].Equal(w) {
+				t.Errorf("%s[%d] = %d, want %d", v.name, i, v.digest[i].Value(), w.Value())
+			}
+		}
+	}
+
+	// hash pushes the digest the Hash Table's permutation outputs
+	program := NewProgram()
+	for _, elem := range zeroToNine {
+		arg := elem
+		program.AddInstruction(&EncodedInstruction{Instruction: Push, Argument: &arg})
+	}
+	program.AddInstruction(&EncodedInstruction{Instruction: Hash})
+	program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+
+	vm := NewVMState(program, []field.Element{}, []field.Element{})
+	aet, err := vm.ExecuteAndTrace()
+	if err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+	want := poseidonHash10(zeroToNine)
+	columns := aet.HashTable.GetMainColumns()
+	// The permutations hashing the program come first
+	last := len(aet.ProgramTable.permutationInputs())*PoseidonTraceLength + PoseidonTraceLength - 1
+	for i := 0; i < PoseidonDigestLen; i++ {
+		got, err := vm.StackPeek(PoseidonDigestLen - 1 - i)
+		if err != nil {
+			t.Fatalf("StackPeek failed: %v", err)
+		}
+		if !got.Equal(want[i]) {
+			t.Errorf("digest element %d on stack = %d, want %d", i, got.Value(), want[i].Value())
+		}
+		if output := columns[hashState+i][last]; !output.Equal(want[i]) {
+			t.Errorf("Hash Table output %d = %d, want %d", i, output.Value(), want[i].Value())
+		}
+	}
+}
+
+// TestNonDeterminism tests that merkle_step, read_mem and divine consume the secret input
+func TestNonDeterminism(t *testing.T) {
+	withArg := func(program *Program, inst Instruction, value uint64) {
+		arg := field.New(value)
+		program.AddInstruction(&EncodedInstruction{Instruction: inst, Argument: &arg})
+	}
+
+	// Node 3 is a right child: its parent is hash(sibling || node)
+	program := NewProgram()
+	withArg(program, Push, 3)
+	for i := uint64(1); i <= 5; i++ {
+		withArg(program, Push, i)
+	}
+	program.AddInstruction(&EncodedInstruction{Instruction: MerkleStep})
+	withArg(program, Push, 1000)
+	withArg(program, ReadMem, 1)
+	withArg(program, Divine, 1)
+	program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+
+	node := []field.Element{field.New(1), field.New(2), field.New(3), field.New(4), field.New(5)}
+	sibling := []field.Element{field.New(6), field.New(7), field.New(8), field.New(9), field.New(10)}
+	nonDeterminism := &NonDeterminism{
+		IndividualTokens: []field.Element{field.New(42)},
+		Digests:          [][]field.Element{sibling},
+		RAM:              map[field.Element]field.Element{field.New(1000): field.New(77)},
+	}
+
+	vm := NewVMStateWithNonDeterminism(program, []field.Element{}, nonDeterminism)
+	if _, err := vm.ExecuteAndTrace(); err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+	if vm.DigestPointer != 1 {
+		t.Errorf("DigestPointer = %d, want 1", vm.DigestPointer)
+	}
+
+	parent := poseidonHash10(append(append([]field.Element{}, sibling...), node...))
+	want := []field.Element{field.New(42), field.New(77), parent[4], parent[3], parent[2], parent[1], parent[0], field.New(1)}
+	for depth, w := range want {
+		got, err := vm.StackPeek(depth)
+		if err != nil {
+			t.Fatalf("StackPeek(%d) failed: %v", depth, err)
+		}
+		if !got.Equal(w) {
+			t.Errorf("st%d = %v, want %v", depth, got, w)
+		}
+	}
+
+	// Without a secret digest merkle_step cannot run
+	vm = NewVMState(program, []field.Element{}, []field.Element{field.New(42)})
+	if err := vm.Run(); err == nil || !strings.Contains(err.Error(), "secret digests exhausted") {
+		t.Errorf("expected exhausted secret digests, got %v", err)
+	}
+}
+
+// TestMasterAIRHoldsOnTrace tests that every table's constraints hold on a traced execution
+func TestMasterAIRHoldsOnTrace(t *testing.T) {
+	program := NewProgram()
+	push := func(value uint64) {
+		arg := field.New(value)
+		program.AddInstruction(&EncodedInstruction{Instruction: Push, Argument: &arg})
+	}
+	withArg := func(inst Instruction, value uint64) {
+		arg := field.New(value)
+		program.AddInstruction(&EncodedInstruction{Instruction: inst, Argument: &arg})
+	}
+	for i := 0; i < 12; i++ {
+		push(uint64(i))
+	}
+	withArg(Pop, 5)
+	push(100)
+	push(7)
+	withArg(WriteMem, 1)
+	push(100)
+	withArg(ReadMem, 1)
+	push(12)
+	push(10)
+	program.AddInstruction(&EncodedInstruction{Instruction: Xor})
+	program.AddInstruction(&EncodedInstruction{Instruction: Hash})
+	withArg(Call, uint64(program.Length+3))
+	program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+	program.AddInstruction(&EncodedInstruction{Instruction: Return})
+
+	vm := NewVMState(program, []field.Element{}, []field.Element{})
+	aet, err := vm.ExecuteAndTrace()
+	if err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+
+	air, err := CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("CreateMasterAIR failed: %v", err)
+	}
+	columns, err := aet.GetTraceColumns()
+	if err != nil {
+		t.Fatalf("GetTraceColumns failed: %v", err)
+	}
+	if len(columns) != air.NumColumns() {
+		t.Fatalf("trace has %d columns, AIR expects %d", len(columns), air.NumColumns())
+	}
+	challenges := testChallenges(air.NumChallenges())
+	auxColumns, err := aet.GetAuxiliaryColumns(challenges)
+	if err != nil {
+		t.Fatalf("GetAuxiliaryColumns failed: %v", err)
+	}
+	if len(auxColumns) != air.NumAuxColumns() {
+		t.Fatalf("trace has %d auxiliary columns, AIR expects %d", len(auxColumns), air.NumAuxColumns())
+	}
+	if err := air.CheckTrace(testClaim(aet, vm), columns, auxColumns, challenges); err != nil {
+		t.Fatalf("master AIR does not hold: %v", err)
+	}
+	if air.MaxDegree() > 4 {
+		t.Errorf("master AIR degree = %d, want at most 4", air.MaxDegree())
+	}
+
+	// Tampering with a coprocessor column must violate a constraint
+	hashColumn := len(columns) - len(aet.HashTable.GetMainColumns())
+	original := columns[hashColumn][1]
+	columns[hashColumn][1] = original.Add(field.One)
+	if err := air.CheckTrace(testClaim(aet, vm), columns, auxColumns, challenges); err == nil {
+		t.Error("master AIR holds on a tampered hash table")
+	}
+	columns[hashColumn][1] = original
+
+	// Moving the called frame's destination in the Jump Stack Table keeps the
+	// table consistent on its own, but breaks the permutation argument
+	for i := range aet.JumpStackTable.jsd {
+		if aet.JumpStackTable.jsp[i].Equal(field.One) {
+			aet.JumpStackTable.jsd[i] = aet.JumpStackTable.jsd[i].Add(field.One)
+		}
+	}
+	tampered, err := aet.GetTraceColumns()
+	if err != nil {
+		t.Fatalf("GetTraceColumns failed: %v", err)
+	}
+	auxColumns, err = aet.GetAuxiliaryColumns(challenges)
+	if err != nil {
+		t.Fatalf("GetAuxiliaryColumns failed: %v", err)
+	}
+	err = air.CheckTrace(testClaim(aet, vm), tampered, auxColumns, challenges)
+	if err == nil || !strings.Contains(err.Error(), "processor_jump_stack_permutation") {
+		t.Errorf("tampered jump stack table: got %v, want a permutation argument violation", err)
+	}
+}
+
+// TestCoprocessorLinks tampers with the tables the Processor Table sends
+// tuples to, keeping each of them consistent on its own, and checks that
+// the link between them and the processor breaks. A u32 result the U32
+// Table does not prove breaks the table itself.
+func TestCoprocessorLinks(t *testing.T) {
+	program := NewProgram()
+	op := func(inst Instruction, arg ...uint64) {
+		encoded := &EncodedInstruction{Instruction: inst}
+		if len(arg) > 0 {
+			value := field.New(arg[0])
+			encoded.Argument = &value
+		}
+		program.AddInstruction(encoded)
+	}
+	// 18 elements spill two to the op stack underflow memory
+	for i := uint64(0); i < 18; i++ {
+		op(Push, i)
+	}
+	op(Pop, 5)
+	op(Push, 100)
+	op(Push, 7)
+	op(WriteMem, 1)
+	op(Push, 100)
+	op(ReadMem, 1)
+	op(Push, 12)
+	op(Push, 10)
+	op(Xor)
+	op(Hash)
+	op(Halt)
+
+	vm := NewVMState(program, nil, nil)
+	aet, err := vm.ExecuteAndTrace()
+	if err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+	air, err := CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("CreateMasterAIR failed: %v", err)
+	}
+	challenges := testChallenges(air.NumChallenges())
+	check := func() error {
+		columns, err := aet.GetTraceColumns()
+		if err != nil {
+			t.Fatalf("GetTraceColumns failed: %v", err)
+		}
+		auxColumns, err := aet.GetAuxiliaryColumns(challenges)
+		if err != nil {
+			t.Fatalf("GetAuxiliaryColumns failed: %v", err)
+		}
+		return air.CheckTrace(testClaim(aet, vm), columns, auxColumns, challenges)
+	}
+	if err := check(); err != nil {
+		t.Fatalf("master AIR does not hold: %v", err)
+	}
+
+	// set overwrites the entries of a column where match holds, and returns
+	// a function restoring them
+	set := func(t *testing.T, column []field.Element, match func(i int) bool, value func(field.Element) field.Element) func() {
+		original := append([]field.Element{}, column...)
+		changed := false
+		for i := range column {
+			if match(i) {
+				column[i], changed = value(column[i]), true
+			}
+		}
+		if !changed {
+			t.Fatal("nothing to tamper with")
+		}
+		return func() { copy(column, original) }
+	}
+	plusOne := func(x field.Element) field.Element { return x.Add(field.One) }
+	ost, rt, pt, ht, ut := aet.OpStackTable, aet.RAMTable, aet.ProgramTable, aet.HashTable, aet.U32Table
+	for _, tt := range []struct {
+		name   string
+		tamper func(t *testing.T) func()
+		want   string
+	}{
+		{"OpStackValue", func(t *testing.T) func() {
+			// The element pushed to and popped from pointer 16
+			return set(t, ost.firstUnderflowElement, func(i int) bool {
+				return ost.stackPointer[i].Equal(field.New(16)) && !ost.ib1ShrinkStack[i].Equal(field.New(OpStackPaddingValue))
+			}, plusOne)
+		}, "processor_op_stack_lookup"},
+		{"RAMValue", func(t *testing.T) func() {
+			// The word written to and read from address 100
+			return set(t, rt.ramValue, func(i int) bool {
+				return rt.ramPointer[i].Equal(field.New(100))
+			}, plusOne)
+		}, "processor_ram_lookup"},
+		{"ProgramArgument", func(t *testing.T) func() {
+			// The argument of the first push
+			return set(t, pt.instruction, func(i int) bool { return i == 1 }, plusOne)
+		}, "processor_program_instruction_lookup"},
+		{"HashTag", func(t *testing.T) func() {
+			// Serve hash's permutation as a sponge instruction's
+			restoreSponge := set(t, ht.spongeMultiplicity, func(i int) bool {
+				return !ht.fixedLengthMultiplicity[i].IsZero()
+			}, plusOne)
+			restoreFixed := set(t, ht.fixedLengthMultiplicity, func(i int) bool {
+				return !ht.fixedLengthMultiplicity[i].IsZero()
+			}, func(field.Element) field.Element { return field.Zero })
+			return func() { restoreFixed(); restoreSponge() }
+		}, "processor_hash_lookup"},
+		{"U32Multiplicity", func(t *testing.T) func() {
+			return set(t, ut.lookupMultiplicity, func(i int) bool {
+				return !ut.lookupMultiplicity[i].IsZero()
+			}, plusOne)
+		}, "processor_u32_lookup"},
+		{"ClockJumpDifference", func(t *testing.T) func() {
+			// Serve the difference d as d + 1
+			restoreOne := set(t, aet.ProcessorTable.cjdMultiplicity, func(i int) bool { return i == 1 },
+				func(x field.Element) field.Element { return x.Sub(field.One) })
+			restoreTwo := set(t, aet.ProcessorTable.cjdMultiplicity, func(i int) bool { return i == 2 }, plusOne)
+			return func() { restoreTwo(); restoreOne() }
+		}, "clock_jump_difference_lookup"},
+		{"U32Result", func(t *testing.T) func() {
+			// The result of xor on its operands shifted by one bit, which
+			// is not looked up
+			return set(t, ut.result, func(i int) bool {
+				return i > 0 && !ut.lookupMultiplicity[i-1].IsZero()
+			}, plusOne)
+		}, "u32_xor_result"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.tamper(t)()
+			err := check()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want a %s violation", err, tt.want)
+			}
+		})
+	}
+}
+
+// testClaim returns the claim of an execution: the program digest and the
+// public input and output of vm
+func testClaim(aet *AET, vm *VMState) *protocols.Claim {
+	return protocols.NewClaim(aet.ProgramDigest[:]).WithInput(vm.PublicInput).WithOutput(vm.PublicOutput)
+}
+
+// testChallenges returns fixed challenges with all three coefficients set,
+// so that the auxiliary columns leave the base field
+func testChallenges(n int) []xfield.XFieldElement {
+	challenges := make([]xfield.XFieldElement, n)
+	for i := range challenges {
+		k := uint64(1000003 * (i + 1))
+		challenges[i] = xfield.New([xfield.ExtensionDegree]field.Element{field.New(k), field.New(k + 1), field.New(k + 2)})
+	}
+	return challenges
+}
+
+// TestProcessorTransitions runs every instruction through the master AIR
+// and checks that the processor's transitions are constrained
+func TestProcessorTransitions(t *testing.T) {
+	program := NewProgram()
+	op := func(inst Instruction, arg ...uint64) *EncodedInstruction {
+		encoded := &EncodedInstruction{Instruction: inst}
+		if len(arg) > 0 {
+			value := field.New(arg[0])
+			encoded.Argument = &value
+		}
+		program.AddInstruction(encoded)
+		return encoded
+	}
+	pushAll := func(values ...uint64) {
+		for _, value := range values {
+			op(Push, value)
+		}
+	}
+
+	// Arithmetic, comparison and skiz in both directions
+	pushAll(3, 4)
+	op(Add)
+	op(AddI, 5)
+	op(Push, 2)
+	op(Mul)
+	op(Invert)
+	op(Invert)
+	op(Dup, 0)
+	op(Eq)
+	op(Assert)
+	op(Push, 5)
+	op(Eq)
+	op(Skiz)
+	op(Push, 99)
+	op(Push, 1)
+	op(Skiz)
+	op(Nop)
+
+	// Stack manipulation
+	pushAll(7, 8, 9)
+	op(Swap, 2)
+	op(Place, 1)
+	op(Pick, 2)
+	op(Pop, 4)
+
+	// U32 instructions
+	op(Push, 1<<40+5)
+	op(Split)
+	op(Pop, 2)
+	pushAll(17, 5)
+	op(DivMod)
+	op(Pop, 2)
+	for _, inst := range []Instruction{Lt, And, Xor, Pow} {
+		pushAll(3, 5)
+		op(inst)
+		op(Pop, 1)
+	}
+	for _, inst := range []Instruction{Log2Floor, PopCount} {
+		op(Push, 12)
+		op(inst)
+		op(Pop, 1)
+	}
+
+	// Extension field instructions
+	pushAll(1, 2, 3, 4, 5, 6)
+	op(XxAdd)
+	pushAll(7, 8, 9)
+	op(XxMul)
+	op(XInvert)
+	op(Push, 3)
+	op(XbMul)
+	op(Pop, 3)
+	pushAll(1, 2, 3, 4, 5, 6, 7, 8, 9)
+	op(XxDotStep)
+	op(Pop, 3)
+	pushAll(1, 2, 3, 4, 5, 6, 7)
+	op(XbDotStep)
+	op(Pop, 3)
+
+	// Input, output and memory
+	op(ReadIo, 2)
+	op(WriteIo, 2)
+	op(Divine, 1)
+	op(Pop, 1)
+	pushAll(100, 7)
+	op(WriteMem, 1)
+	op(Push, 100)
+	op(ReadMem, 1)
+	op(Pop, 1)
+
+	// Hashing, sponge and Merkle steps
+	pushAll(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	op(Hash)
+	op(Pop, 5)
+	pushAll(1, 2, 3, 4, 5, 1, 2, 3, 4, 5)
+	op(AssertVector)
+	op(SpongeInit)
+	pushAll(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	op(SpongeAbsorb)
+	op(Push, 300)
+	op(SpongeAbsorbMem)
+	op(SpongeSqueeze)
+	op(Pop, 5)
+	op(Pop, 5)
+	pushAll(5, 1, 2, 3, 4, 5)
+	op(MerkleStep)
+	op(Pop, 5)
+	op(Pop, 1)
+	pushAll(1, 2, 3, 4, 5, 3, 400)
+	op(MerkleStepMem)
+	op(Pop, 5)
+
+	// Permutation check
+	pushAll(1, 2, 3, 4, 5)
+	op(PushPerm)
+	pushAll(1, 2, 3, 4, 5)
+	op(PopPerm)
+	op(AssertPerm)
+
+	// Calls: recurse, recurse_or_return recursing at depth 2, and
+	// recurse_or_return returning at depth 1
+	op(Push, 2)
+	callCountdown := op(Call, 0)
+	op(Pop, 1)
+	op(Push, 2)
+	callNested := op(Call, 0)
+	op(Pop, 1)
+	callReturn := op(Call, 0)
+	op(Halt)
+
+	*callCountdown.Argument = field.New(uint64(program.Length))
+	op(AddI, uint64(field.Zero.Sub(field.One).Value()))
+	op(Dup, 0)
+	op(Skiz)
+	op(Recurse)
+	op(Return)
+
+	*callNested.Argument = field.New(uint64(program.Length))
+	nested := op(Call, 0)
+	op(Return)
+	*nested.Argument = field.New(uint64(program.Length))
+	op(AddI, uint64(field.Zero.Sub(field.One).Value()))
+	op(Dup, 0)
+	op(Skiz)
+	op(RecurseOrReturn)
+	op(Return)
+
+	*callReturn.Argument = field.New(uint64(program.Length))
+	op(RecurseOrReturn)
+
+	nonDeterminism := &NonDeterminism{
+		IndividualTokens: []field.Element{field.New(33)},
+		Digests:          [][]field.Element{{field.New(6), field.New(7), field.New(8), field.New(9), field.New(10)}},
+	}
+	vm := NewVMStateWithNonDeterminism(program, []field.Element{field.New(11), field.New(22)}, nonDeterminism)
+	aet, err := vm.ExecuteAndTrace()
+	if err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+
+	executed := make(map[Instruction]bool)
+	for _, ci := range aet.ProcessorTable.ci {
+		executed[Instruction(ci.Value())] = true
+	}
+	for _, inst := range processorInstructions {
+		if !executed[inst] {
+			t.Errorf("program does not execute %s", inst)
+		}
+	}
+
+	air, err := CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("CreateMasterAIR failed: %v", err)
+	}
+	columns, err := aet.GetTraceColumns()
+	if err != nil {
+		t.Fatalf("GetTraceColumns failed: %v", err)
+	}
+	challenges := testChallenges(air.NumChallenges())
+	auxColumns, err := aet.GetAuxiliaryColumns(challenges)
+	if err != nil {
+		t.Fatalf("GetAuxiliaryColumns failed: %v", err)
+	}
+	if err := air.CheckTrace(testClaim(aet, vm), columns, auxColumns, challenges); err != nil {
+		t.Fatalf("master AIR does not hold: %v", err)
+	}
+	if air.MaxDegree() > 4 {
+		t.Errorf("master AIR degree = %d, want at most 4", air.MaxDegree())
+	}
+
+	// Each tampered register must violate its transition
+	rowOf := func(inst Instruction) int {
+		for i, ci := range aet.ProcessorTable.ci {
+			if Instruction(ci.Value()) == inst {
+				return i
+			}
+		}
+		t.Fatalf("no %s row", inst)
+		return -1
+	}
+	for _, tt := range []struct {
+		name   string
+		column int
+		row    int
+		want   string
+	}{
+		{"AddResult", processorST0, rowOf(Add) + 1, "processor_st0_transition"},
+		{"SwappedRegister", processorST0 + 2, rowOf(Swap) + 1, "processor_st2_transition"},
+		{"SkizTarget", processorIP, rowOf(Skiz) + 1, "processor_ip_transition"},
+		{"ReturnAddress", processorJSO, rowOf(Call) + 1, "processor_jso_transition"},
+		{"StackDepth", processorOSP, rowOf(Hash) + 1, "processor_osp_transition"},
+		{"DivModRemainder", processorST0, rowOf(DivMod) + 1, "processor_instruction_rule_0"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			original := columns[tt.column][tt.row]
+			columns[tt.column][tt.row] = original.Add(field.One)
+			defer func() { columns[tt.column][tt.row] = original }()
+			err := air.CheckTrace(testClaim(aet, vm), columns, auxColumns, challenges)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want a %s violation", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestPermutationCheck tests push_perm, pop_perm and assert_perm, at run
+// time and through the permrp running product of the master AIR
+func TestPermutationCheck(t *testing.T) {
+	build := func(pushed, popped [][5]uint64) *Program {
+		program := NewProgram()
+		pushTuple := func(tuple [5]uint64) {
+			for i := 4; i >= 0; i-- {
+				arg := field.New(tuple[i])
+				program.AddInstruction(&EncodedInstruction{Instruction: Push, Argument: &arg})
+			}
+		}
+		for _, tuple := range pushed {
+			pushTuple(tuple)
+			program.AddInstruction(&EncodedInstruction{Instruction: PushPerm})
+		}
+		for _, tuple := range popped {
+			pushTuple(tuple)
+			program.AddInstruction(&EncodedInstruction{Instruction: PopPerm})
+		}
+		program.AddInstruction(&EncodedInstruction{Instruction: AssertPerm})
+		program.AddInstruction(&EncodedInstruction{Instruction: Halt})
+		return program
+	}
+	a := [5]uint64{1, 2, 3, 4, 5}
+	b := [5]uint64{6, 7, 8, 9, 10}
+
+	t.Run("UnbalancedTuplesFailAssertPerm", func(t *testing.T) {
+		vm := NewVMState(build([][5]uint64{a, b}, [][5]uint64{a, a}), []field.Element{}, []field.Element{})
+		var failure *AssertionFailure
+		if err := vm.Run(); !errors.As(err, &failure) || failure.Instruction != AssertPerm {
+			t.Errorf("expected assert_perm to fail, got %v", err)
+		}
+	})
+
+	vm := NewVMState(build([][5]uint64{a, b}, [][5]uint64{b, a}), []field.Element{}, []field.Element{})
+	aet, err := vm.ExecuteAndTrace()
+	if err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+	if len(vm.PermutationMultiset) != 0 {
+		t.Errorf("permutation multiset not empty after halt: %v", vm.PermutationMultiset)
+	}
+
+	air, err := CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("CreateMasterAIR failed: %v", err)
+	}
+	columns, err := aet.GetTraceColumns()
+	if err != nil {
+		t.Fatalf("GetTraceColumns failed: %v", err)
+	}
+	challenges := testChallenges(air.NumChallenges())
+	auxColumns, err := aet.GetAuxiliaryColumns(challenges)
+	if err != nil {
+		t.Fatalf("GetAuxiliaryColumns failed: %v", err)
+	}
+	if err := air.CheckTrace(testClaim(aet, vm), columns, auxColumns, challenges); err != nil {
+		t.Fatalf("master AIR does not hold: %v", err)
+	}
+
+	// A running product that skips a factor cannot reach 1 at assert_perm
+	permrp := auxColumns[auxProcessorPermutationRunningProduct]
+	for i := range permrp {
+		if !permrp[i].Equal(xfield.One) {
+			permrp[i] = xfield.One
+			break
+		}
+	}
+	err = air.CheckTrace(testClaim(aet, vm), columns, auxColumns, challenges)
+	if err == nil || !strings.Contains(err.Error(), "processor_permrp") {
+		t.Errorf("tampered permrp: got %v, want a permrp violation", err)
+	}
+
+	// Decoding a push_perm row as nop switches its selector off, but the
+	// Program Table does not list nop at that ip
+	pt := aet.ProcessorTable
+	pushPerm := pt.selectors[processorInstructionIndex[PushPerm]]
+	nop := pt.selectors[processorInstructionIndex[Nop]]
+	for i, selector := range pushPerm {
+		if selector.Equal(field.One) {
+			pushPerm[i], nop[i] = field.Zero, field.One
+			pt.ci[i] = field.New(uint64(Nop))
+			pt.ib0[i] = field.New(uint64(Nop.GetInstructionBit(IB0)))
+			pt.ib1[i] = field.New(uint64(Nop.GetInstructionBit(IB1)))
+			pt.ib2[i] = field.New(uint64(Nop.GetInstructionBit(IB2)))
+			break
+		}
+	}
+	tampered, err := aet.GetTraceColumns()
+	if err != nil {
+		t.Fatalf("GetTraceColumns failed: %v", err)
+	}
+	auxColumns, err = aet.GetAuxiliaryColumns(challenges)
+	if err != nil {
+		t.Fatalf("GetAuxiliaryColumns failed: %v", err)
+	}
+	if err := air.CheckTrace(testClaim(aet, vm), tampered, auxColumns, challenges); err == nil {
+		t.Error("master AIR holds with push_perm decoded as nop")
+	}
+	// The jump stack tuple holds ci too, so check the lookup's terminal
+	// values directly
+	last := len(auxColumns[auxProcessorInstructionLookup]) - 1
+	if auxColumns[auxProcessorInstructionLookup][last].Equal(auxColumns[auxProgramInstructionLookup][last]) {
+		t.Error("instruction lookup accepts push_perm decoded as nop")
+	}
+}
+
+// TestExecutionErrors tests that failures and exceeded limits are reported
+// as typed errors located at the failing instruction
+func TestExecutionErrors(t *testing.T) {
+	// op creates an instruction, with an argument if one is given
+	op := func(inst Instruction, arg ...uint64) *EncodedInstruction {
+		encoded := &EncodedInstruction{Instruction: inst}
+		if len(arg) > 0 {
+			value := field.New(arg[0])
+			encoded.Argument = &value
+		}
+		return encoded
+	}
+	build := func(instructions ...*EncodedInstruction) *Program {
+		program := NewProgram()
+		for _, inst := range instructions {
+			program.AddInstruction(inst)
+		}
+		return program
+	}
+	// selfCall calls itself at address 2 until a limit stops it
+	selfCall := build(op(Push, 1), op(Call, 2))
+
+	tests := []struct {
+		name    string
+		program *Program
+		options ExecutionOptions
+		check   func(err error) (ExecutionContext, bool)
+		want    ExecutionContext
+	}{
+		{
+			name:    "Assert",
+			program: build(op(Push, 0), op(Assert), op(Halt)),
+			options: DefaultExecutionOptions(),
+			check: func(err error) (ExecutionContext, bool) {
+				var e *AssertionFailure
+				return located(errors.As(err, &e), e)
+			},
+			want: ExecutionContext{IP: 2, Cycle: 1, Instruction: Assert},
+		},
+		{
+			name:    "PublicInputExhausted",
+			program: build(op(ReadIo, 1), op(Halt)),
+			options: DefaultExecutionOptions(),
+			check: func(err error) (ExecutionContext, bool) {
+				var e *InputExhausted
+				ok := errors.As(err, &e)
+				return located(ok && e.Source == PublicInputSource, e)
+			},
+			want: ExecutionContext{IP: 0, Cycle: 0, Instruction: ReadIo},
+		},
+		{
+			name:    "SecretInputExhausted",
+			program: build(op(Nop), op(Divine, 1), op(Halt)),
+			options: DefaultExecutionOptions(),
+			check: func(err error) (ExecutionContext, bool) {
+				var e *InputExhausted
+				ok := errors.As(err, &e)
+				return located(ok && e.Source == SecretInputSource, e)
+			},
+			want: ExecutionContext{IP: 1, Cycle: 1, Instruction: Divine},
+		},
+		{
+			name:    "StackUnderflow",
+			program: build(op(Pop, 5), op(Pop, 1), op(Halt)),
+			options: DefaultExecutionOptions(),
+			check: func(err error) (ExecutionContext, bool) {
+				var e *StackUnderflow
+				ok := errors.As(err, &e)
+				return located(ok && e.Need == 1 && e.Have == 0, e)
+			},
+			want: ExecutionContext{IP: 2, Cycle: 1, Instruction: Pop},
+		},
+		{
+			name:    "JumpStackUnderflow",
+			program: build(op(Return)),
+			options: DefaultExecutionOptions(),
+			check: func(err error) (ExecutionContext, bool) {
+				var e *JumpStackUnderflow
+				return located(errors.As(err, &e), e)
+			},
+			want: ExecutionContext{IP: 0, Cycle: 0, Instruction: Return},
+		},
+		{
+			name:    "InstructionFailure",
+			program: build(op(Push, 0), op(Invert), op(Halt)),
+			options: DefaultExecutionOptions(),
+			check: func(err error) (ExecutionContext, bool) {
+				var e *InstructionFailure
+				ok := errors.As(err, &e)
+				return located(ok && strings.Contains(e.Err.Error(), "cannot invert zero"), e)
+			},
+			want: ExecutionContext{IP: 2, Cycle: 1, Instruction: Invert},
+		},
+		{
+			name:    "CycleLimit",
+			program: selfCall,
+			options: ExecutionOptions{MaxCycles: 10},
+			check: func(err error) (ExecutionContext, bool) {
+				var e *CycleLimitExceeded
+				ok := errors.As(err, &e)
+				return located(ok && e.Limit == 10, e)
+			},
+			want: ExecutionContext{IP: 2, Cycle: 10, Instruction: Call},
+		},
+		{
+			name:    "JumpStackLimit",
+			program: selfCall,
+			options: ExecutionOptions{MaxCycles: 1000, MaxJumpStackDepth: 3},
+			check: func(err error) (ExecutionContext, bool) {
+				var e *JumpStackLimitExceeded
+				return located(errors.As(err, &e), e)
+			},
+			want: ExecutionContext{IP: 2, Cycle: 4, Instruction: Call},
+		},
+		{
+			name:    "RAMLimit",
+			program: build(op(Push, 7), op(Push, 100), op(WriteMem, 1), op(Push, 8), op(Push, 200), op(WriteMem, 1), op(Halt)),
+			options: ExecutionOptions{MaxRAMCells: 1},
+			check: func(err error) (ExecutionContext, bool) {
+				var e *RAMLimitExceeded
+				ok := errors.As(err, &e)
+				return located(ok && e.Cells == 2, e)
+			},
+			want: ExecutionContext{IP: 10, Cycle: 5, Instruction: WriteMem},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := NewVMState(tt.program, []field.Element{}, []field.Element{})
+			vm.Options = tt.options
+			err := vm.Run()
+			got, ok := tt.check(err)
+			if !ok {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("located at %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("PaddedHeightLimit", func(t *testing.T) {
+		vm := NewVMState(build(op(Push, 1), op(Halt)), []field.Element{}, []field.Element{})
+		vm.Options.MaxPaddedHeight = 4
+		_, err := vm.ExecuteAndTrace()
+		var e *PaddedHeightExceeded
+		if !errors.As(err, &e) || e.Limit != 4 || e.Height <= 4 || e.Instruction != Halt {
+			t.Errorf("expected the padded height to exceed 4, got %v", err)
+		}
+	})
+
+	t.Run("MissingHalt", func(t *testing.T) {
+		vm := NewVMState(build(op(Push, 1), op(Pop, 1)), []field.Element{}, []field.Element{})
+		_, err := vm.ExecuteAndTrace()
+		var e *MissingHalt
+		if !errors.As(err, &e) || e.IP != 4 || e.Cycle != 2 {
+			t.Errorf("expected the trace to miss a halt at IP 4, got %v", err)
+		}
+	})
+}
+
+// located returns the context of a typed execution error, if ok
+func located(ok bool, err interface{ Context() ExecutionContext }) (ExecutionContext, bool) {
+	if !ok {
+		return ExecutionContext{}, false
+	}
+	return err.Context(), true
+}
+
+func TestAnalyzeProgram(t *testing.T) {
+	op := func(inst Instruction, arg ...uint64) *EncodedInstruction {
+		encoded := &EncodedInstruction{Instruction: inst}
+		if len(arg) > 0 {
+			value := field.New(arg[0])
+			encoded.Argument = &value
+		}
+		return encoded
+	}
+	build := func(instructions ...*EncodedInstruction) *Program {
+		program := NewProgram()
+		for _, inst := range instructions {
+			program.AddInstruction(inst)
+		}
+		return program
+	}
+
+	t.Run("StackAccessMatchesHandlers", func(t *testing.T) {
+		for inst := range AllInstructions {
+			switch inst {
+			case Halt, Call, Return, Recurse, RecurseOrReturn:
+				continue
+			}
+			// Ten ones satisfy assert, assert_vector and every inverse, and
+			// the tuple of ones given to push_perm can be popped again
+			prefix := []*EncodedInstruction{op(SpongeInit)}
+			for i := 0; i < 15; i++ {
+				if i == 5 {
+					prefix = append(prefix, op(PushPerm))
+				}
+				prefix = append(prefix, op(Push, 1))
+			}
+			if inst == AssertPerm {
+				prefix = append(prefix, op(PopPerm))
+			}
+			encoded := op(inst)
+			if lo, _, ok := inst.ArgumentRange(); ok {
+				encoded = op(inst, lo+1)
+			} else if inst.HasArgument() {
+				encoded = op(inst, 1)
+			}
+			program := build(append(prefix, encoded, op(Halt))...)
+
+			digest := []field.Element{field.One, field.One, field.One, field.One, field.One}
+			state := NewVMStateWithNonDeterminism(program, []field.Element{field.One, field.One},
+				&NonDeterminism{IndividualTokens: digest, Digests: [][]field.Element{digest}})
+			for i := 0; i < len(prefix); i++ {
+				if err := state.Step(); err != nil {
+					t.Fatalf("%s: prefix failed: %v", inst, err)
+				}
+			}
+			before := state.StackPointer
+			if err := state.Step(); err != nil {
+				t.Errorf("%s failed: %v", inst, err)
+				continue
+			}
+			need, effect := stackAccess(encoded)
+			if got := state.StackPointer - before; got != effect {
+				t.Errorf("%s changed the stack depth by %d, analysis assumes %d", inst, got, effect)
+			}
+			if need > before {
+				t.Errorf("%s needs %d elements, more than the %d it had", inst, need, before)
+			}
+		}
+	})
+
+	t.Run("ValidProgram", func(t *testing.T) {
+		// Counts 3 down to 0 in a recursive function
+		program := build(
+			op(Push, 3),         // 0
+			op(Call, 5),         // 2
+			op(Halt),            // 4
+			op(Dup, 0),          // 5: countdown
+			op(Push, 0),         // 7
+			op(Eq),              // 9
+			op(Skiz),            // 10
+			op(Return),          // 11
+			op(Push, field.P-1), // 12
+			op(Add),             // 14
+			op(Recurse),         // 15
+			op(Return),          // 16
+		)
+		if err := NewVMState(program, nil, nil).Run(); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		analysis := AnalyzeProgram(program)
+		if len(analysis.Diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", analysis.Diagnostics)
+		}
+		if err := ValidateProgram(program); err != nil {
+			t.Errorf("ValidateProgram failed: %v", err)
+		}
+		if got := analysis.StackDepth[5]; got != (DepthRange{Min: 6, Max: 6}) {
+			t.Errorf("depth at the function entry = %+v, want 6", got)
+		}
+		if got := analysis.StackDepth[4]; got != (DepthRange{Min: 6, Max: 6}) {
+			t.Errorf("depth after the call = %+v, want 6", got)
+		}
+		if succ := analysis.Successors[11]; len(succ) != 2 || succ[0] != 4 || succ[1] != 16 {
+			t.Errorf("successors of return = %v, want [4 16]", succ)
+		}
+		if succ := analysis.Successors[15]; len(succ) != 2 || succ[0] != 5 || succ[1] != 16 {
+			t.Errorf("successors of recurse = %v, want [5 16]", succ)
+		}
+	})
+
+	t.Run("Loop", func(t *testing.T) {
+		// Pushes forever, so the depth at the loop is unbounded
+		program := build(op(Call, 3), op(Halt), op(Push, 1), op(Recurse))
+		analysis := AnalyzeProgram(program)
+		if len(analysis.Diagnostics) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", analysis.Diagnostics)
+		}
+		if got := analysis.StackDepth[3]; got.Min != 5 || got.Max != math.MaxInt {
+			t.Errorf("depth in the loop = %+v, want 5 to unbounded", got)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		program  *Program
+		address  int
+		severity Severity
+	}{
+		{"PopOutOfRange", build(op(Pop, 7), op(Halt)), 0, SeverityError},
+		{"DivineZero", build(op(Divine, 0), op(Halt)), 0, SeverityError},
+		{"CallIntoArgument", build(op(Push, 1), op(Call, 1), op(Halt)), 2, SeverityError},
+		{"CallPastEnd", build(op(Call, 9), op(Halt)), 0, SeverityError},
+		{"Underflow", build(op(Pop, 5), op(Add), op(Halt)), 2, SeverityError},
+		{"UnderflowInFunction", build(op(Pop, 5), op(Call, 5), op(Halt), op(Pop, 1), op(Return)), 5, SeverityError},
+		{"ReturnOutsideFunction", build(op(Push, 1), op(Return)), 2, SeverityError},
+		{"RunsPastEnd", build(op(Push, 1)), 0, SeverityError},
+		{"Unreachable", build(op(Halt), op(Push, 1), op(Halt)), 1, SeverityWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := AnalyzeProgram(tt.program).Diagnostics
+			if len(diagnostics) != 1 {
+				t.Fatalf("expected one diagnostic, got %v", diagnostics)
+			}
+			if d := diagnostics[0]; d.Address != tt.address || d.Severity != tt.severity {
+				t.Errorf("got %v, want a %s at address %d", d, tt.severity, tt.address)
+			}
+			if err := ValidateProgram(tt.program); (err != nil) != (tt.severity == SeverityError) {
+				t.Errorf("ValidateProgram returned %v", err)
+			}
+		})
+	}
+}
+
+func TestProfileAndTrace(t *testing.T) {
+	op := func(inst Instruction, arg ...uint64) *EncodedInstruction {
+		encoded := &EncodedInstruction{Instruction: inst}
+		if len(arg) > 0 {
+			value := field.New(arg[0])
+			encoded.Argument = &value
+		}
+		return encoded
+	}
+	program := NewProgram()
+	for _, inst := range []*EncodedInstruction{
+		op(Call, 5), op(Call, 19), op(Halt), // 0: main
+		op(Push, 0), op(Push, 0), op(Push, 0), op(Push, 0), op(Push, 0), // 5: hasher
+		op(Hash), op(Pop, 5), op(Return),
+		op(Push, 3), op(Push, 5), op(Lt), op(Pop, 1), op(Return), // 19: compare
+	} {
+		program.AddInstruction(inst)
+	}
+
+	aet, profile, err := NewVMState(program, nil, nil).ProfileAndTrace(map[string]int{"hasher": 5, "compare": 19})
+	if err != nil {
+		t.Fatalf("ProfileAndTrace failed: %v", err)
+	}
+	if len(profile.Functions) != 3 {
+		t.Fatalf("profiled %d functions, want 3: %+v", len(profile.Functions), profile.Functions)
+	}
+	main, hasher, compare := profile.Functions[0], profile.Functions[1], profile.Functions[2]
+	if main.Name != "main" || hasher.Name != "hasher" || compare.Name != "compare" {
+		t.Errorf("functions are %s, %s and %s", main.Name, hasher.Name, compare.Name)
+	}
+	if main.Self.Cycles != 3 || hasher.Self.Cycles != 8 || compare.Self.Cycles != 5 || hasher.Calls != 1 {
+		t.Errorf("unexpected cycles: %+v", profile.Functions)
+	}
+	if main.Total.Cycles != aet.TableHeights[ProcessorTable] {
+		t.Errorf("main took %d cycles, the processor table has %d rows",
+			main.Total.Cycles, aet.TableHeights[ProcessorTable])
+	}
+	if hasher.Self.HashRows != PoseidonTraceLength || main.Total.HashRows != hasher.Self.HashRows {
+		t.Errorf("hash rows: hasher %d, main %d", hasher.Self.HashRows, main.Total.HashRows)
+	}
+	// lt's operands 3 and 5 have 3 bits: a U32 Table section of 3 rows and
+	// the row ending it
+	if compare.Self.U32Rows != 4 || compare.Self.HashRows != 0 || main.Self.U32Rows != 0 {
+		t.Errorf("u32 rows: compare %d, main %d", compare.Self.U32Rows, main.Self.U32Rows)
+	}
+	// lt brings in the Lookup Table's 256 rows
+	if profile.TallestTable != LookupTable || profile.PaddedHeight != 256 {
+		t.Errorf("tallest table %s, padded height %d", profile.TallestTable, profile.PaddedHeight)
+	}
+
+	// Profiling does not change the trace
+	plain, err := NewVMState(program, nil, nil).ExecuteAndTrace()
+	if err != nil {
+		t.Fatalf("ExecuteAndTrace failed: %v", err)
+	}
+	if plain.PaddedHeight != aet.PaddedHeight || plain.TableHeights[HashTable] != aet.TableHeights[HashTable] {
+		t.Error("profiled trace differs")
+	}
+}
@@ -0,0 +1,215 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExecutionOptions limits the resources an execution may use
+//
+// A limit of zero means no limit.
+type ExecutionOptions struct {
+	// MaxCycles is the number of instructions executed before the
+	// execution fails with CycleLimitExceeded
+	MaxCycles uint64
+
+	// MaxRAMCells is the number of distinct RAM addresses that may hold a
+	// value, the initial RAM included
+	MaxRAMCells int
+
+	// MaxJumpStackDepth is the number of nested calls
+	MaxJumpStackDepth int
+
+	// MaxPaddedHeight is the padded height of the execution trace, checked
+	// by ExecuteAndTrace once the program halted
+	MaxPaddedHeight int
+}
+
+// DefaultExecutionOptions returns the limits of a VMState that was not
+// configured otherwise: one million cycles and no other limits
+func DefaultExecutionOptions() ExecutionOptions {
+	return ExecutionOptions{
+		MaxCycles: 1000000,
+	}
+}
+
+// ExecutionContext locates a failure in an execution
+//
+// Every typed execution error embeds it, so callers that only care where
+// execution stopped need not tell the errors apart.
+type ExecutionContext struct {
+	IP          int         // Word address of the instruction
+	Cycle       uint64      // Cycle in which the instruction was executed
+	Instruction Instruction // The instruction that failed
+}
+
+// String describes the location as part of an error message
+func (c ExecutionContext) String() string {
+	return fmt.Sprintf("execution failed at cycle %d, IP %d (%s)", c.Cycle, c.IP, c.Instruction)
+}
+
+// Context returns the location, so that the location of any typed error
+// can be found with errors.As and an interface{ Context() ExecutionContext }
+func (c *ExecutionContext) Context() ExecutionContext {
+	return *c
+}
+
+// locate sets the context of a typed error created by an instruction handler
+func (c *ExecutionContext) locate(ctx ExecutionContext) {
+	*c = ctx
+}
+
+// locatable is implemented by every typed execution error
+type locatable interface {
+	error
+	Context() ExecutionContext
+	locate(ctx ExecutionContext)
+}
+
+// InputSource names the input stream an instruction read from
+type InputSource string
+
+const (
+	PublicInputSource   InputSource = "public input"   // read_io
+	SecretInputSource   InputSource = "secret input"   // divine
+	SecretDigestsSource InputSource = "secret digests" // merkle_step
+)
+
+// AssertionFailure is returned when assert, assert_vector or assert_perm fails
+type AssertionFailure struct {
+	ExecutionContext
+	Message string
+}
+
+// Error implements error
+func (e *AssertionFailure) Error() string {
+	return fmt.Sprintf("%s: assertion failed: %s", e.ExecutionContext, e.Message)
+}
+
+// InputExhausted is returned when an instruction reads past the end of an
+// input stream
+type InputExhausted struct {
+	ExecutionContext
+	Source InputSource
+}
+
+// Error implements error
+func (e *InputExhausted) Error() string {
+	return fmt.Sprintf("%s: %s exhausted", e.ExecutionContext, e.Source)
+}
+
+// StackUnderflow is returned when an instruction needs more operational
+// stack elements than there are
+type StackUnderflow struct {
+	ExecutionContext
+	Need int
+	Have int
+}
+
+// Error implements error
+func (e *StackUnderflow) Error() string {
+	return fmt.Sprintf("%s: stack underflow: need %d elements, have %d", e.ExecutionContext, e.Need, e.Have)
+}
+
+// JumpStackUnderflow is returned by return, recurse and recurse_or_return
+// outside of a call
+type JumpStackUnderflow struct {
+	ExecutionContext
+}
+
+// Error implements error
+func (e *JumpStackUnderflow) Error() string {
+	return fmt.Sprintf("%s: jump stack underflow: no call to return from", e.ExecutionContext)
+}
+
+// CycleLimitExceeded is returned instead of executing the instruction after
+// the last allowed cycle
+type CycleLimitExceeded struct {
+	ExecutionContext
+	Limit uint64
+}
+
+// Error implements error
+func (e *CycleLimitExceeded) Error() string {
+	return fmt.Sprintf("%s: exceeded maximum of %d cycles", e.ExecutionContext, e.Limit)
+}
+
+// RAMLimitExceeded is returned after an instruction that left more RAM
+// cells in use than allowed
+type RAMLimitExceeded struct {
+	ExecutionContext
+	Limit int
+	Cells int
+}
+
+// Error implements error
+func (e *RAMLimitExceeded) Error() string {
+	return fmt.Sprintf("%s: %d RAM cells in use, maximum is %d", e.ExecutionContext, e.Cells, e.Limit)
+}
+
+// JumpStackLimitExceeded is returned by a call nested deeper than allowed
+type JumpStackLimitExceeded struct {
+	ExecutionContext
+	Limit int
+}
+
+// Error implements error
+func (e *JumpStackLimitExceeded) Error() string {
+	return fmt.Sprintf("%s: exceeded maximum jump stack depth of %d", e.ExecutionContext, e.Limit)
+}
+
+// PaddedHeightExceeded is returned by ExecuteAndTrace when the execution
+// trace of a halted program is taller than allowed. The context is that of
+// the last instruction.
+type PaddedHeightExceeded struct {
+	ExecutionContext
+	Height int
+	Limit  int
+}
+
+// Error implements error
+func (e *PaddedHeightExceeded) Error() string {
+	return fmt.Sprintf("%s: padded height %d exceeds maximum of %d", e.ExecutionContext, e.Height, e.Limit)
+}
+
+// MissingHalt is returned by ExecuteAndTrace when execution runs past the
+// program's last instruction instead of halting. The context is the word
+// address after the program.
+type MissingHalt struct {
+	ExecutionContext
+}
+
+// Error implements error
+func (e *MissingHalt) Error() string {
+	return fmt.Sprintf("%s: program ended without halt", e.ExecutionContext)
+}
+
+// InstructionFailure is returned for every other failure of an instruction,
+// such as an invalid argument or the inverse of zero
+type InstructionFailure struct {
+	ExecutionContext
+	Err error
+}
+
+// Error implements error
+func (e *InstructionFailure) Error() string {
+	return fmt.Sprintf("%s: %v", e.ExecutionContext, e.Err)
+}
+
+// Unwrap returns the instruction's error
+func (e *InstructionFailure) Unwrap() error {
+	return e.Err
+}
+
+// locateError gives the error of an instruction handler its context
+//
+// Typed errors are returned themselves, whatever wrapped them on the way up;
+// any other error becomes an InstructionFailure.
+func locateError(ctx ExecutionContext, err error) error {
+	var typed locatable
+	if errors.As(err, &typed) {
+		typed.locate(ctx)
+		return typed
+	}
+	return &InstructionFailure{ExecutionContext: ctx, Err: err}
+}
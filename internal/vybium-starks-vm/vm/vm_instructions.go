@@ -6,7 +6,6 @@ import (
 	"math/big"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
-	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
 )
 
 // ============================================================================
@@ -23,7 +22,7 @@ func (vm *VMState) execPop(inst *EncodedInstruction) error {
 	}
 
 	if vm.StackPointer < n {
-		return fmt.Errorf("stack underflow: cannot pop %d elements from stack of size %d", n, vm.StackPointer)
+		return &StackUnderflow{Need: n, Have: vm.StackPointer}
 	}
 
 	// Pop n elements
@@ -55,7 +54,7 @@ func (vm *VMState) execDivine(inst *EncodedInstruction) error {
 	// Read n elements from secret input
 	for i := 0; i < n; i++ {
 		if vm.SecretPointer >= len(vm.SecretInput) {
-			return fmt.Errorf("secret input exhausted")
+			return &InputExhausted{Source: SecretInputSource}
 		}
 
 		value := vm.SecretInput[vm.SecretPointer]
@@ -97,18 +96,14 @@ func (vm *VMState) execPlace(inst *EncodedInstruction) error {
 		return fmt.Errorf("invalid place index: %d (must be 0-15)", index)
 	}
 
-	value, err := vm.StackPop()
-	if err != nil {
-		return err
-	}
-
-	// Shift stack down to make room at index
-	for i := vm.StackPointer; i > index; i-- {
-		vm.Stack[i] = vm.Stack[i-1]
+	if index >= vm.StackPointer {
+		return fmt.Errorf("place index out of bounds")
 	}
 
+	// st1..st<index> move up by one, st0 takes their place
+	value := vm.Stack[0]
+	copy(vm.Stack[:index], vm.Stack[1:index+1])
 	vm.Stack[index] = value
-	vm.StackPointer++
 
 	return vm.IncrementIP()
 }
@@ -146,11 +141,7 @@ func (vm *VMState) execSwap(inst *EncodedInstruction) error {
 	}
 
 	// Swap st0 with st[index]
-	st0 := vm.Stack[vm.StackPointer-1]
-	sti := vm.Stack[vm.StackPointer-1-index]
-
-	vm.Stack[vm.StackPointer-1] = sti
-	vm.Stack[vm.StackPointer-1-index] = st0
+	vm.Stack[0], vm.Stack[index] = vm.Stack[index], vm.Stack[0]
 
 	return vm.IncrementIP()
 }
@@ -217,7 +208,7 @@ func (vm *VMState) execCall(inst *EncodedInstruction) error {
 // execReturn returns from a function call
 func (vm *VMState) execReturn() error {
 	if len(vm.JumpStack) == 0 {
-		return fmt.Errorf("jump stack underflow: cannot return without call")
+		return &JumpStackUnderflow{}
 	}
 
 	// Pop return address from jump stack
@@ -233,7 +224,7 @@ func (vm *VMState) execReturn() error {
 // execRecurse calls current function recursively
 func (vm *VMState) execRecurse() error {
 	if len(vm.JumpStack) == 0 {
-		return fmt.Errorf("recurse requires at least one call on jump stack")
+		return &JumpStackUnderflow{}
 	}
 
 	// Get current function's entry point
@@ -262,7 +253,7 @@ func (vm *VMState) execRecurseOrReturn() error {
 		// Return
 		return vm.execReturn()
 	} else {
-		return fmt.Errorf("recurse_or_return requires at least one call")
+		return &JumpStackUnderflow{}
 	}
 }
 
@@ -274,7 +265,7 @@ func (vm *VMState) execAssert() error {
 	}
 
 	if !st0.Equal(field.One) {
-		return fmt.Errorf("assertion failed: expected 1, got %s", st0.String())
+		return &AssertionFailure{Message: fmt.Sprintf("expected 1, got %s", st0.String())}
 	}
 
 	return vm.IncrementIP()
@@ -470,7 +461,7 @@ func (vm *VMState) execReadIo(inst *EncodedInstruction) error {
 	// Read n elements from public input
 	for i := 0; i < n; i++ {
 		if vm.InputPointer >= len(vm.PublicInput) {
-			return fmt.Errorf("public input exhausted")
+			return &InputExhausted{Source: PublicInputSource}
 		}
 
 		value := vm.PublicInput[vm.InputPointer]
@@ -511,6 +502,15 @@ func (vm *VMState) execWriteIo(inst *EncodedInstruction) error {
 // Bitwise Instructions (U32 Coprocessor)
 // ============================================================================
 
+// u32Operand returns an operand of a u32 instruction, which the U32 Table
+// can only prove for values below 2^32
+func u32Operand(name string, x field.Element) (uint64, error) {
+	if x.Value() > 1<<32-1 {
+		return 0, fmt.Errorf("%s operand %d is not a u32", name, x.Value())
+	}
+	return x.Value(), nil
+}
+
 // execSplit splits top into high and low 32-bit parts
 func (vm *VMState) execSplit() error {
 	a, err := vm.StackPop()
@@ -519,7 +519,7 @@ func (vm *VMState) execSplit() error {
 	}
 
 	// Split into low 32 bits and high bits
-	value := big.NewInt(int64(a.Value()))
+	value := new(big.Int).SetUint64(a.Value())
 	mask := big.NewInt((1 << 32) - 1)
 
 	low := new(big.Int).And(value, mask)
@@ -560,8 +560,16 @@ func (vm *VMState) execLt() error {
 	}
 
 	// Compare as unsigned 32-bit integers
-	aValue := new(big.Int).And(big.NewInt(int64(a.Value())), big.NewInt((1<<32)-1))
-	bValue := new(big.Int).And(big.NewInt(int64(b.Value())), big.NewInt((1<<32)-1))
+	lhs, err := u32Operand("lt", a)
+	if err != nil {
+		return err
+	}
+	rhs, err := u32Operand("lt", b)
+	if err != nil {
+		return err
+	}
+	aValue := new(big.Int).SetUint64(lhs)
+	bValue := new(big.Int).SetUint64(rhs)
 
 	var result field.Element
 	if aValue.Cmp(bValue) < 0 {
@@ -600,7 +608,15 @@ func (vm *VMState) execAnd() error {
 		return err
 	}
 
-	result := new(big.Int).And(big.NewInt(int64(a.Value())), big.NewInt(int64(b.Value())))
+	lhs, err := u32Operand("and", a)
+	if err != nil {
+		return err
+	}
+	rhs, err := u32Operand("and", b)
+	if err != nil {
+		return err
+	}
+	result := new(big.Int).And(new(big.Int).SetUint64(lhs), new(big.Int).SetUint64(rhs))
 
 	if err := vm.StackPush(field.New(result.Uint64())); err != nil {
 		return err
@@ -611,8 +627,8 @@ func (vm *VMState) execAnd() error {
 		Type: U32CoProcessor,
 		Data: map[string]interface{}{
 			"operation": "and",
-			"a":         big.NewInt(int64(a.Value())),
-			"b":         big.NewInt(int64(b.Value())),
+			"a":         new(big.Int).SetUint64(lhs),
+			"b":         new(big.Int).SetUint64(rhs),
 			"result":    result,
 		},
 	})
@@ -632,7 +648,15 @@ func (vm *VMState) execXor() error {
 		return err
 	}
 
-	result := new(big.Int).Xor(big.NewInt(int64(a.Value())), big.NewInt(int64(b.Value())))
+	lhs, err := u32Operand("xor", a)
+	if err != nil {
+		return err
+	}
+	rhs, err := u32Operand("xor", b)
+	if err != nil {
+		return err
+	}
+	result := new(big.Int).Xor(new(big.Int).SetUint64(lhs), new(big.Int).SetUint64(rhs))
 
 	if err := vm.StackPush(field.New(result.Uint64())); err != nil {
 		return err
@@ -643,8 +667,8 @@ func (vm *VMState) execXor() error {
 		Type: U32CoProcessor,
 		Data: map[string]interface{}{
 			"operation": "xor",
-			"a":         big.NewInt(int64(a.Value())),
-			"b":         big.NewInt(int64(b.Value())),
+			"a":         new(big.Int).SetUint64(lhs),
+			"b":         new(big.Int).SetUint64(rhs),
 			"result":    result,
 		},
 	})
@@ -659,11 +683,15 @@ func (vm *VMState) execLog2Floor() error {
 		return err
 	}
 
-	if a.IsZero() {
+	operand, err := u32Operand("log2_floor", a)
+	if err != nil {
+		return err
+	}
+	if operand == 0 {
 		return fmt.Errorf("log2 of zero is undefined")
 	}
 
-	value := big.NewInt(int64(a.Value()))
+	value := new(big.Int).SetUint64(operand)
 	log2 := value.BitLen() - 1
 
 	result := field.New(uint64(log2))
@@ -698,7 +726,10 @@ func (vm *VMState) execPow() error {
 	}
 
 	// Perform modular exponentiation
-	exp := expElement.Value()
+	exp, err := u32Operand("pow", expElement)
+	if err != nil {
+		return err
+	}
 	result := base.ModPow(exp)
 
 	if err := vm.StackPush(result); err != nil {
@@ -720,6 +751,12 @@ func (vm *VMState) execDivMod() error {
 		return err
 	}
 
+	if _, err := u32Operand("div_mod", divisor); err != nil {
+		return err
+	}
+	if _, err := u32Operand("div_mod", dividend); err != nil {
+		return err
+	}
 	if divisor.IsZero() {
 		return fmt.Errorf("division by zero")
 	}
@@ -727,7 +764,7 @@ func (vm *VMState) execDivMod() error {
 	// Compute quotient and remainder
 	q := new(big.Int)
 	r := new(big.Int)
-	q.DivMod(big.NewInt(int64(dividend.Value())), big.NewInt(int64(divisor.Value())), r)
+	q.DivMod(new(big.Int).SetUint64(dividend.Value()), new(big.Int).SetUint64(divisor.Value()), r)
 
 	// Push quotient, then remainder (so remainder is on top)
 	if err := vm.StackPush(field.New(q.Uint64())); err != nil {
@@ -747,9 +784,14 @@ func (vm *VMState) execPopCount() error {
 		return err
 	}
 
+	operand, err := u32Operand("pop_count", a)
+	if err != nil {
+		return err
+	}
+
 	// Count 1 bits in binary representation
 	count := 0
-	value := new(big.Int).Set(big.NewInt(int64(a.Value())))
+	value := new(big.Int).SetUint64(operand)
 	for value.Sign() > 0 {
 		if value.Bit(0) == 1 {
 			count++
@@ -768,7 +810,7 @@ func (vm *VMState) execPopCount() error {
 		Type: U32CoProcessor,
 		Data: map[string]interface{}{
 			"operation": "pop_count",
-			"input":     big.NewInt(int64(a.Value())),
+			"input":     new(big.Int).SetUint64(operand),
 			"result":    count,
 		},
 	})
@@ -792,14 +834,12 @@ func (vm *VMState) execHash() error {
 		input[i] = val
 	}
 
-	// Compute Poseidon hash using vybium-crypto
-	result := hash.PoseidonHash(input)
+	// Compute the 5-element Poseidon digest
+	result := poseidonHash10(input)
 
-	// Push result (5 elements for Poseidon digest)
-	// The hash produces a 5-element digest. For simplicity, we push the single result
-	// 5 times. In production, use Tip5 Hash10 for proper 5-element digest
-	for i := 0; i < 5; i++ {
-		if err := vm.StackPush(result); err != nil {
+	// Push digest (digest[4] ends up on top)
+	for _, elem := range result {
+		if err := vm.StackPush(elem); err != nil {
 			return err
 		}
 	}
@@ -810,7 +850,7 @@ func (vm *VMState) execHash() error {
 		Data: map[string]interface{}{
 			"operation": "hash",
 			"input":     input,
-			"output":    result,
+			"output":    result[:],
 		},
 	})
 
@@ -841,8 +881,8 @@ func (vm *VMState) execAssertVector() error {
 	// Check equality
 	for i := 0; i < 5; i++ {
 		if !vector1[i].Equal(vector2[i]) {
-			return fmt.Errorf("assert_vector failed: vector1[%d] (%s) != vector2[%d] (%s)",
-				i, vector1[i].String(), i, vector2[i].String())
+			return &AssertionFailure{Message: fmt.Sprintf("vector1[%d] (%s) != vector2[%d] (%s)",
+				i, vector1[i].String(), i, vector2[i].String())}
 		}
 	}
 
@@ -982,21 +1022,20 @@ func (vm *VMState) execSpongeSqueeze() error {
 	return vm.IncrementIP()
 }
 
-// applyPoseidonPermutation applies one round of Poseidon permutation
+// applyPoseidonPermutation applies the Poseidon permutation to the whole
+// sponge state, the permutation the Hash Table proves
 func (vm *VMState) applyPoseidonPermutation() error {
 	if vm.Sponge == nil {
 		return fmt.Errorf("sponge not initialized")
 	}
+	if len(vm.Sponge.State) != PoseidonStateSize {
+		return fmt.Errorf("sponge state has %d elements, expected %d", len(vm.Sponge.State), PoseidonStateSize)
+	}
 
-	// Apply Poseidon permutation to the sponge state
-	// The sponge uses Tip5 which operates on 16 elements
-	// For compatibility, we use Poseidon hash on the rate portion (first 10 elements)
-	rateElements := vm.Sponge.State[:10]
-	permResult := hash.PoseidonHash(rateElements)
-
-	// Update the first element of the state with the permutation result
-	// In a full implementation, this would be a complete state permutation
-	vm.Sponge.State[0] = permResult
+	var state [PoseidonStateSize]field.Element
+	copy(state[:], vm.Sponge.State)
+	state = poseidonPermutation(state)
+	copy(vm.Sponge.State, state[:])
 
 	return nil
 }
@@ -1204,23 +1243,9 @@ func (vm *VMState) execXbMul() error {
 // ============================================================================
 
 // execMerkleStep verifies one Merkle tree step using Poseidon
+// Stack layout: [..., node_index, digest] -> [..., node_index / 2, parent]
+// The sibling digest is the next of the secret digests (non-determinism).
 func (vm *VMState) execMerkleStep() error {
-	// Pop node index, sibling, and current digest from stack
-	nodeIndex, err := vm.StackPop()
-	if err != nil {
-		return err
-	}
-
-	// Pop sibling digest (5 elements)
-	sibling := make([]field.Element, 5)
-	for i := 4; i >= 0; i-- {
-		val, err := vm.StackPop()
-		if err != nil {
-			return err
-		}
-		sibling[i] = val
-	}
-
 	// Pop current digest (5 elements)
 	current := make([]field.Element, 5)
 	for i := 4; i >= 0; i-- {
@@ -1231,24 +1256,45 @@ func (vm *VMState) execMerkleStep() error {
 		current[i] = val
 	}
 
+	nodeIndex, err := vm.StackPop()
+	if err != nil {
+		return err
+	}
+
+	// Divine sibling digest
+	if vm.DigestPointer >= len(vm.SecretDigests) {
+		return &InputExhausted{Source: SecretDigestsSource}
+	}
+	sibling := vm.SecretDigests[vm.DigestPointer]
+	if len(sibling) != 5 {
+		return fmt.Errorf("secret digest %d has %d elements, expected 5", vm.DigestPointer, len(sibling))
+	}
+	vm.DigestPointer++
+
 	// Determine if current is left or right child
-	nodeIdx := nodeIndex.Value()
+	nodeIdx, err := u32Operand("merkle_step", nodeIndex)
+	if err != nil {
+		return err
+	}
 	isLeftChild := (nodeIdx & 1) == 0
 
 	// Prepare input for hashing (left || right)
-	var hashInput []field.Element
+	hashInput := make([]field.Element, 0, 10)
 	if isLeftChild {
-		hashInput = append(current, sibling...)
+		hashInput = append(append(hashInput, current...), sibling...)
 	} else {
-		hashInput = append(sibling, current...)
+		hashInput = append(append(hashInput, sibling...), current...)
 	}
 
-	// Compute parent hash using Poseidon
-	parent := hash.PoseidonHash(hashInput)
+	// Compute parent digest using Poseidon
+	parent := poseidonHash10(hashInput)
 
-	// Push parent digest onto stack (5 times for 5-element digest)
-	for i := 0; i < 5; i++ {
-		if err := vm.StackPush(parent); err != nil {
+	// Push the parent's node index, then the parent digest
+	if err := vm.StackPush(field.New(nodeIdx / 2)); err != nil {
+		return err
+	}
+	for _, elem := range parent {
+		if err := vm.StackPush(elem); err != nil {
 			return err
 		}
 	}
@@ -1258,9 +1304,10 @@ func (vm *VMState) execMerkleStep() error {
 		Type: HashCoProcessor,
 		Data: map[string]interface{}{
 			"operation": "merkle_step",
+			"input":     hashInput,
 			"current":   current,
 			"sibling":   sibling,
-			"parent":    parent,
+			"parent":    parent[:],
 		},
 	})
 
@@ -1297,7 +1344,10 @@ func (vm *VMState) execMerkleStepMem() error {
 	}
 
 	// Same logic as merkle_step
-	nodeIdx := nodeIndex.Value()
+	nodeIdx, err := u32Operand("merkle_step_mem", nodeIndex)
+	if err != nil {
+		return err
+	}
 	isLeftChild := (nodeIdx & 1) == 0
 
 	// Prepare input for hashing (left || right)
@@ -1308,16 +1358,28 @@ func (vm *VMState) execMerkleStepMem() error {
 		hashInput = append(sibling, current...)
 	}
 
-	// Compute parent hash using Poseidon
-	parent := hash.PoseidonHash(hashInput)
+	// Compute parent digest using Poseidon
+	parent := poseidonHash10(hashInput)
 
 	// Push parent
-	for i := 0; i < 5; i++ {
-		if err := vm.StackPush(parent); err != nil {
+	for _, elem := range parent {
+		if err := vm.StackPush(elem); err != nil {
 			return err
 		}
 	}
 
+	// Record coprocessor call
+	vm.CoProcessorCalls = append(vm.CoProcessorCalls, CoProcessorCall{
+		Type: HashCoProcessor,
+		Data: map[string]interface{}{
+			"operation": "merkle_step_mem",
+			"input":     hashInput,
+			"current":   current,
+			"sibling":   sibling,
+			"parent":    parent[:],
+		},
+	})
+
 	return vm.IncrementIP()
 }
 
@@ -1457,96 +1519,68 @@ func (vm *VMState) execXbDotStep() error {
 // TIP-0007: Run-Time Permutation Check Instructions
 // ===========================================================================
 
-// execPushPerm pushes top 5 stack elements into permutation accumulator
-// Computes inner product p = Σ(st_i · a_i) with Fiat-Shamir weights
-// Multiplies (α - p) into running product: permrp' = permrp · (α - p)
+// execPushPerm pushes to permutation accumulator
+// Adds the top 5 stack elements (st0..st4) to the permutation multiset and
+// pops them. In a proof, permrp' = permrp · (α - p) with p = Σ(st_i · a_i).
 func (vm *VMState) execPushPerm() error {
-	// Ensure we have at least 5 elements on stack
-	if vm.StackPointer < 5 {
-		return fmt.Errorf("push_perm requires 5 stack elements, have %d", vm.StackPointer)
-	}
-
-	// Get top 5 stack elements (st0..st4) without popping
-	stackElements := make([]field.Element, 5)
-	for i := 0; i < 5; i++ {
-		stackElements[i] = vm.Stack[i]
-	}
-
-	// Compute inner product p = Σ(st_i · a_i)
-	innerProduct := field.Zero
-	for i := 0; i < 5; i++ {
-		term := stackElements[i].Mul(vm.PermutationWeights[i])
-		innerProduct = innerProduct.Add(term)
-	}
-
-	// Compute (α - p)
-	alphaMinusP := vm.PermutationAlpha.Sub(innerProduct)
-
-	// Multiply into running product: permrp' = permrp · (α - p)
-	vm.PermutationRunningProduct = vm.PermutationRunningProduct.Mul(alphaMinusP)
-
-	// Pop the 5 elements
-	for i := 0; i < 5; i++ {
-		if _, err := vm.StackPop(); err != nil {
-			return fmt.Errorf("failed to pop element %d: %w", i, err)
-		}
+	tuple, err := vm.popPermutationTuple()
+	if err != nil {
+		return err
 	}
+	vm.updatePermutationMultiset(tuple, 1)
 
 	return vm.IncrementIP()
 }
 
 // execPopPerm pops from permutation accumulator
-// Computes inner product p = Σ(st_i · a_i) with Fiat-Shamir weights
-// Divides (α - p) out of running product: permrp' = permrp / (α - p)
+// Removes the top 5 stack elements (st0..st4) from the permutation multiset
+// and pops them. In a proof, permrp' · (α - p) = permrp.
 func (vm *VMState) execPopPerm() error {
-	// Ensure we have at least 5 elements on stack
-	if vm.StackPointer < 5 {
-		return fmt.Errorf("pop_perm requires 5 stack elements, have %d", vm.StackPointer)
+	tuple, err := vm.popPermutationTuple()
+	if err != nil {
+		return err
 	}
+	vm.updatePermutationMultiset(tuple, -1)
 
-	// Get top 5 stack elements (st0..st4) without popping
-	stackElements := make([]field.Element, 5)
-	for i := 0; i < 5; i++ {
-		stackElements[i] = vm.Stack[i]
-	}
+	return vm.IncrementIP()
+}
 
-	// Compute inner product p = Σ(st_i · a_i)
-	innerProduct := field.Zero
-	for i := 0; i < 5; i++ {
-		term := stackElements[i].Mul(vm.PermutationWeights[i])
-		innerProduct = innerProduct.Add(term)
+// execAssertPerm asserts that permutation accumulator equals 1
+// Verifies that pushed and popped elements are equal up to permutation
+func (vm *VMState) execAssertPerm() error {
+	if n := len(vm.PermutationMultiset); n != 0 {
+		return &AssertionFailure{Message: fmt.Sprintf("%d tuples were pushed and popped a different number of times", n)}
 	}
 
-	// Compute (α - p)
-	alphaMinusP := vm.PermutationAlpha.Sub(innerProduct)
+	return vm.IncrementIP()
+}
 
-	// Check that (α - p) is non-zero
-	if alphaMinusP.IsZero() {
-		return fmt.Errorf("pop_perm division by zero: α = p")
+// popPermutationTuple pops st0..st4, the tuple a permutation instruction
+// pushes or pops
+func (vm *VMState) popPermutationTuple() ([5]field.Element, error) {
+	var tuple [5]field.Element
+	if vm.StackPointer < 5 {
+		return tuple, &StackUnderflow{Need: 5, Have: vm.StackPointer}
 	}
-
-	// Divide out of running product: permrp' = permrp / (α - p)
-	divisor := alphaMinusP.Inverse()
-	vm.PermutationRunningProduct = vm.PermutationRunningProduct.Mul(divisor)
-
-	// Pop the 5 elements
-	for i := 0; i < 5; i++ {
-		if _, err := vm.StackPop(); err != nil {
-			return fmt.Errorf("failed to pop element %d: %w", i, err)
+	for i := range tuple {
+		value, err := vm.StackPop()
+		if err != nil {
+			return tuple, fmt.Errorf("failed to pop element %d: %w", i, err)
 		}
+		tuple[i] = value
 	}
-
-	return vm.IncrementIP()
+	return tuple, nil
 }
 
-// execAssertPerm asserts that permutation accumulator equals 1
-// Verifies that pushed and popped elements are equal up to permutation
-func (vm *VMState) execAssertPerm() error {
-	// Check that running product is 1
-	if !vm.PermutationRunningProduct.Equal(field.One) {
-		return fmt.Errorf("assert_perm failed: permutation running product is not 1 (got %s)",
-			vm.PermutationRunningProduct.String())
+// updatePermutationMultiset adds delta to the multiplicity of tuple
+func (vm *VMState) updatePermutationMultiset(tuple [5]field.Element, delta int) {
+	if vm.PermutationMultiset == nil {
+		vm.PermutationMultiset = make(map[[5]field.Element]int)
 	}
-
-	return vm.IncrementIP()
+	count := vm.PermutationMultiset[tuple] + delta
+	if count == 0 {
+		delete(vm.PermutationMultiset, tuple)
+		return
+	}
+	vm.PermutationMultiset[tuple] = count
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
-	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/hash"
 )
 
 // VMState represents the complete state of the Vybium STARKs VM
@@ -29,9 +28,11 @@ type VMState struct {
 	RAM      map[field.Element]field.Element // Address -> Value
 	RAMCalls []RAMCall                       // Record all RAM operations for trace
 
-	// Operational Stack (16 on-chip registers + underflow to RAM)
-	Stack        []field.Element // Stack elements (st0 is top)
-	StackPointer int             // Number of elements on stack
+	// Operational Stack (16 on-chip registers + underflow memory)
+	Stack            []field.Element // Registers st0..st15, the top 16 elements (st0 is top)
+	StackPointer     int             // Number of elements on stack
+	OpStackUnderflow []field.Element // Elements below st15, bottom first, indexed by StackPointer-16
+	OpStackCalls     []OpStackCall   // Record all stack underflow operations for trace
 
 	// Jump Stack (for call/return)
 	JumpStack []VMJumpStackEntry
@@ -46,13 +47,20 @@ type VMState struct {
 	// Halting state
 	Halting bool
 
+	// Resource limits, DefaultExecutionOptions unless set before running
+	Options ExecutionOptions
+
+	// Set by ProfileAndTrace for the duration of the execution
+	profiler *profiler
+
 	// Co-processor calls (recorded during execution)
 	CoProcessorCalls []CoProcessorCall
 
 	// TIP-0007: Permutation Check State
-	PermutationRunningProduct field.Element   // Current running product (permrp)
-	PermutationWeights        []field.Element // Fiat-Shamir weights for inner product (5 elements)
-	PermutationAlpha          field.Element   // Fiat-Shamir indeterminate α
+	// How often each 5-tuple was pushed minus how often it was popped; tuples
+	// that balance out are removed. A proof checks the same multiset equality
+	// with the processor's permrp column, built from Fiat-Shamir challenges.
+	PermutationMultiset map[[5]field.Element]int
 }
 
 // VMJumpStackEntry represents an entry on the VM's jump stack
@@ -70,6 +78,15 @@ type RAMCall struct {
 	Value   field.Element
 }
 
+// OpStackCall represents a stack element moving between st15 and the
+// underflow memory
+type OpStackCall struct {
+	Clock        uint64
+	IsShrink     bool          // true when the element left the underflow memory
+	StackPointer int           // Stack pointer of the underflow element (>= 16)
+	Value        field.Element // The underflow element
+}
+
 // CoProcessorCall represents a call to a coprocessor
 type CoProcessorCall struct {
 	Type CoProcessorType
@@ -94,6 +111,19 @@ type PoseidonSponge struct {
 	Rate  int             // Rate (how many elements absorbed/squeezed at once)
 }
 
+// NonDeterminism is the prover's secret input to an execution
+type NonDeterminism struct {
+	// IndividualTokens are read by divine
+	IndividualTokens []field.Element
+
+	// Digests are read by merkle_step, one 5-element digest per step
+	Digests [][]field.Element
+
+	// RAM is the initial content of memory, read by read_mem and
+	// merkle_step_mem. Addresses not listed here start out as zero.
+	RAM map[field.Element]field.Element
+}
+
 // NewVMState creates a new VM state with TIP-0006 program attestation
 // Following Triton VM's approach: the operational stack is ALWAYS initialized with the program digest
 func NewVMState(
@@ -101,6 +131,20 @@ func NewVMState(
 	publicInput []field.Element,
 	secretInput []field.Element,
 ) *VMState {
+	return NewVMStateWithNonDeterminism(program, publicInput, &NonDeterminism{IndividualTokens: secretInput})
+}
+
+// NewVMStateWithNonDeterminism creates a new VM state whose secret input
+// is the full non-determinism: individual tokens, digests and initial RAM
+func NewVMStateWithNonDeterminism(
+	program *Program,
+	publicInput []field.Element,
+	nonDeterminism *NonDeterminism,
+) *VMState {
+	if nonDeterminism == nil {
+		nonDeterminism = &NonDeterminism{}
+	}
+
 	// TIP-0006: Compute program digest for attestation
 	// This Production implementation.
 	programDigest := computeProgramDigest(program)
@@ -112,10 +156,9 @@ func NewVMState(
 	}
 
 	// TIP-0006: Initialize stack with program digest (st0-st4)
-	// Digest goes in reverse order: st0=digest[4], st1=digest[3], ..., st4=digest[0]
-	// This matches Triton: stack[..Digest::LEN].copy_from_slice(&reverse_digest)
+	// st0=digest[0], st1=digest[1], ..., st4=digest[4]
 	for i := 0; i < 5 && i < len(programDigest); i++ {
-		stack[i] = programDigest[4-i]
+		stack[i] = programDigest[i]
 	}
 
 	// TIP-0006: Initialize public output with program digest
@@ -123,70 +166,48 @@ func NewVMState(
 	publicOutput := make([]field.Element, 5)
 	copy(publicOutput, programDigest[:])
 
+	// The initial RAM is copied, execution must not modify the caller's map
+	ram := make(map[field.Element]field.Element, len(nonDeterminism.RAM))
+	for address, value := range nonDeterminism.RAM {
+		ram[address] = value
+	}
+
 	return &VMState{
 		Program:            program,
 		PublicInput:        publicInput,
 		PublicOutput:       publicOutput,
 		InputPointer:       0,
-		SecretInput:        secretInput,
-		SecretDigests:      make([][]field.Element, 0),
+		SecretInput:        nonDeterminism.IndividualTokens,
+		SecretDigests:      nonDeterminism.Digests,
 		SecretPointer:      0,
 		DigestPointer:      0,
-		RAM:                make(map[field.Element]field.Element),
+		RAM:                ram,
 		RAMCalls:           make([]RAMCall, 0),
 		Stack:              stack,
 		StackPointer:       5, // TIP-0006: Stack initialized with 5 digest elements (matches Triton)
+		OpStackUnderflow:   make([]field.Element, 0),
+		OpStackCalls:       make([]OpStackCall, 0),
 		JumpStack:          make([]VMJumpStackEntry, 0),
 		CycleCount:         0,
 		InstructionPointer: 0,
 		Sponge:             nil,
 		Halting:            false,
+		Options:            DefaultExecutionOptions(),
 		CoProcessorCalls:   make([]CoProcessorCall, 0),
 
-		// TIP-0007: Initialize permutation state
-		// Running product starts at 1
-		PermutationRunningProduct: field.One,
-		// Weights and alpha: During VM execution, use deterministic values
-		// During proof generation, these are replaced with Fiat-Shamir challenges
-		PermutationWeights: generatePermutationWeights(),
-		PermutationAlpha:   generatePermutationAlpha(),
+		// TIP-0007: Nothing pushed or popped yet
+		PermutationMultiset: make(map[[5]field.Element]int),
 	}
 }
 
-// generatePermutationWeights generates Fiat-Shamir weights for TIP-0007
-// During VM execution: returns deterministic values for consistent behavior
-// During proof generation: replaced with actual Fiat-Shamir transcript values
-func generatePermutationWeights() []field.Element {
-	// Generate 5 weights for inner product with top 5 stack elements
-	// Using powers of a generator for pseudo-random values
-	weights := make([]field.Element, 5)
-	base := field.New(17) // Arbitrary non-zero generator
-	weights[0] = field.One
-	for i := 1; i < 5; i++ {
-		weights[i] = weights[i-1].Mul(base)
-	}
-	return weights
-}
-
-// generatePermutationAlpha generates Fiat-Shamir indeterminate α for TIP-0007
-// During VM execution: returns deterministic value for consistent behavior
-// During proof generation: replaced with actual Fiat-Shamir transcript value
-func generatePermutationAlpha() field.Element {
-	// Use a fixed non-zero value distinct from weights
-	return field.New(23)
-}
-
 // Run executes the program until halt or error
+//
+// A failing instruction or exceeded limit is reported as one of the typed
+// errors in vm_errors.go, such as *AssertionFailure or *CycleLimitExceeded.
 func (vm *VMState) Run() error {
 	for !vm.Halting {
 		if err := vm.Step(); err != nil {
-			return fmt.Errorf("execution failed at cycle %d, IP %d: %w",
-				vm.CycleCount, vm.InstructionPointer, err)
-		}
-
-		// Safety check to prevent infinite loops
-		if vm.CycleCount > 1000000 {
-			return fmt.Errorf("execution exceeded maximum cycles (1M)")
+			return err
 		}
 	}
 	return nil
@@ -207,12 +228,12 @@ func (vm *VMState) Step() error {
 	// Check stack depth
 	stackEffect := inst.Instruction.StackEffect()
 	if stackEffect < 0 && vm.StackPointer < -stackEffect {
-		return fmt.Errorf("stack underflow: need %d elements, have %d", -stackEffect, vm.StackPointer)
+		return locateError(vm.executionContext(inst), &StackUnderflow{Need: -stackEffect, Have: vm.StackPointer})
 	}
 
 	// Execute instruction (dispatch to handler)
-	if err := vm.ExecuteInstruction(inst); err != nil {
-		return fmt.Errorf("failed to execute %s: %w", inst.Instruction.String(), err)
+	if err := vm.executeWithinLimits(inst); err != nil {
+		return err
 	}
 
 	// Increment cycle count
@@ -221,17 +242,41 @@ func (vm *VMState) Step() error {
 	return nil
 }
 
+// executionContext locates the execution of inst in the current cycle
+func (vm *VMState) executionContext(inst *EncodedInstruction) ExecutionContext {
+	return ExecutionContext{IP: vm.InstructionPointer, Cycle: vm.CycleCount, Instruction: inst.Instruction}
+}
+
+// executeWithinLimits executes inst unless the cycle limit is reached, and
+// checks the RAM and jump stack limits afterwards. Errors are located at inst.
+func (vm *VMState) executeWithinLimits(inst *EncodedInstruction) error {
+	ctx := vm.executionContext(inst)
+	limits := vm.Options
+	if limits.MaxCycles > 0 && vm.CycleCount >= limits.MaxCycles {
+		return &CycleLimitExceeded{ExecutionContext: ctx, Limit: limits.MaxCycles}
+	}
+
+	if err := vm.ExecuteInstruction(inst); err != nil {
+		return locateError(ctx, err)
+	}
+
+	if limits.MaxRAMCells > 0 && len(vm.RAM) > limits.MaxRAMCells {
+		return &RAMLimitExceeded{ExecutionContext: ctx, Limit: limits.MaxRAMCells, Cells: len(vm.RAM)}
+	}
+	if limits.MaxJumpStackDepth > 0 && len(vm.JumpStack) > limits.MaxJumpStackDepth {
+		return &JumpStackLimitExceeded{ExecutionContext: ctx, Limit: limits.MaxJumpStackDepth}
+	}
+	return nil
+}
+
 // CurrentInstruction fetches the current instruction
 func (vm *VMState) CurrentInstruction() (*EncodedInstruction, error) {
 	if vm.InstructionPointer < 0 || vm.InstructionPointer >= vm.Program.Length {
 		return nil, fmt.Errorf("instruction pointer out of bounds: %d", vm.InstructionPointer)
 	}
 
-	// Get program words
-	words := vm.Program.ToWords()
-
-	// Decode instruction at current IP
-	inst, err := DecodeInstruction(words, vm.InstructionPointer)
+	// Look up the pre-decoded instruction at the current IP
+	inst, err := vm.Program.InstructionAt(vm.InstructionPointer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode instruction: %w", err)
 	}
@@ -367,103 +412,99 @@ func (vm *VMState) ExecuteInstruction(inst *EncodedInstruction) error {
 }
 
 // Stack access helpers
-
-// Push value onto stack.
-// When the stack pointer exceeds 16 (on-chip registers), values overflow to RAM.
-// This Production implementation.
-// and additional values are stored in RAM via underflow I/O operations.
+//
+// Following Triton VM, the registers st0..st15 always hold the top 16 elements
+// of the stack, st0 first; registers below the bottom of a shorter stack are
+// zero. The elements below st15 live in the op stack underflow memory, which is
+// separate from RAM: a push moves st15 into it, a pop refills st15 from it.
+// Its traffic is recorded in OpStackCalls for the Op Stack Table, never in
+// RAMCalls.
+
+// StackPush pushes a value onto the stack
 func (vm *VMState) StackPush(value field.Element) error {
-	if vm.StackPointer < 16 {
-		// Store in on-chip register (stack array)
-		vm.Stack[vm.StackPointer] = value
-		vm.StackPointer++
-		return nil
-	}
-
-	// Stack overflow: store in RAM
-	// Use stack pointer as RAM address (offset from base address 0)
-	// In Triton VM, underflow values are stored at addresses based on the overflow count
-	ramAddress := field.New(uint64(vm.StackPointer - 16))
-
-	// Store value in RAM
-	if vm.RAM == nil {
-		vm.RAM = make(map[field.Element]field.Element)
+	if vm.StackPointer >= 16 {
+		// st15 moves to the underflow memory
+		vm.OpStackUnderflow = append(vm.OpStackUnderflow[:vm.StackPointer-16], vm.Stack[15])
+
+		// Record underflow operation for trace
+		vm.OpStackCalls = append(vm.OpStackCalls, OpStackCall{
+			Clock:        vm.CycleCount,
+			IsShrink:     false,
+			StackPointer: vm.StackPointer,
+			Value:        vm.Stack[15],
+		})
 	}
-	vm.RAM[ramAddress] = value
-
-	// Record RAM operation for trace
-	vm.RAMCalls = append(vm.RAMCalls, RAMCall{
-		Clock:   vm.CycleCount,
-		IsWrite: true,
-		Address: ramAddress,
-		Value:   value,
-	})
 
+	copy(vm.Stack[1:], vm.Stack[:15])
+	vm.Stack[0] = value
 	vm.StackPointer++
 	return nil
 }
 
-// Pop value from stack.
-// When popping from RAM (stack pointer > 16), values are read from RAM.
-// This Production implementation.
+// StackPop pops the top value off the stack
 func (vm *VMState) StackPop() (field.Element, error) {
 	if vm.StackPointer <= 0 {
-		return field.Zero, fmt.Errorf("stack underflow")
+		return field.Zero, &StackUnderflow{Need: 1, Have: 0}
 	}
 
+	value := vm.Stack[0]
+	copy(vm.Stack[:15], vm.Stack[1:])
+	vm.Stack[15] = field.Zero
 	vm.StackPointer--
 
 	if vm.StackPointer < 16 {
-		// Pop from on-chip register
-		value := vm.Stack[vm.StackPointer]
-		vm.Stack[vm.StackPointer] = field.Zero // Clear for safety
 		return value, nil
 	}
 
-	// Stack underflow: read from RAM
-	// Use stack pointer as RAM address (offset from base address 0)
-	ramAddress := field.New(uint64(vm.StackPointer - 16))
-
-	// Read value from RAM
-	if vm.RAM == nil {
-		return field.Zero, fmt.Errorf("stack underflow: RAM not initialized")
-	}
-
-	value, exists := vm.RAM[ramAddress]
-	if !exists {
-		return field.Zero, fmt.Errorf("stack underflow: value not found in RAM at address %d", ramAddress.Value())
+	// st15 is refilled from the underflow memory
+	index := vm.StackPointer - 16
+	if index >= len(vm.OpStackUnderflow) {
+		return field.Zero, fmt.Errorf("stack underflow: no element at stack pointer %d", vm.StackPointer)
 	}
-
-	// Record RAM read operation for trace
-	vm.RAMCalls = append(vm.RAMCalls, RAMCall{
-		Clock:   vm.CycleCount,
-		IsWrite: false,
-		Address: ramAddress,
-		Value:   value,
+	vm.Stack[15] = vm.OpStackUnderflow[index]
+	vm.OpStackUnderflow = vm.OpStackUnderflow[:index]
+
+	// Record underflow operation for trace
+	vm.OpStackCalls = append(vm.OpStackCalls, OpStackCall{
+		Clock:        vm.CycleCount,
+		IsShrink:     true,
+		StackPointer: vm.StackPointer,
+		Value:        vm.Stack[15],
 	})
 
 	return value, nil
 }
 
-// Peek at stack element (0 = top)
+// StackPeek returns the element in register st<depth>
 func (vm *VMState) StackPeek(depth int) (field.Element, error) {
-	if depth < 0 || depth >= vm.StackPointer {
+	if depth < 0 || depth >= vm.StackPointer || depth >= len(vm.Stack) {
 		return field.Zero, fmt.Errorf("stack peek out of bounds: depth %d, size %d", depth, vm.StackPointer)
 	}
 
-	return vm.Stack[vm.StackPointer-1-depth], nil
+	return vm.Stack[depth], nil
 }
 
-// Set stack element (0 = top)
+// StackSet sets register st<depth>
 func (vm *VMState) StackSet(depth int, value field.Element) error {
-	if depth < 0 || depth >= vm.StackPointer {
+	if depth < 0 || depth >= vm.StackPointer || depth >= len(vm.Stack) {
 		return fmt.Errorf("stack set out of bounds: depth %d, size %d", depth, vm.StackPointer)
 	}
 
-	vm.Stack[vm.StackPointer-1-depth] = value
+	vm.Stack[depth] = value
 	return nil
 }
 
+// StackElements returns the whole operational stack, st0 first, the
+// elements in the underflow memory included
+func (vm *VMState) StackElements() []field.Element {
+	registers := min(vm.StackPointer, len(vm.Stack))
+	elements := append([]field.Element{}, vm.Stack[:registers]...)
+	for i := vm.StackPointer - registers - 1; i >= 0; i-- {
+		elements = append(elements, vm.OpStackUnderflow[i])
+	}
+	return elements
+}
+
 // RAM access helpers
 
 // Read from RAM
@@ -529,9 +570,8 @@ func (vm *VMState) Execute() error {
 		}
 
 		// Execute instruction
-		if err := vm.ExecuteInstruction(inst); err != nil {
-			return fmt.Errorf("execution failed at cycle %d, IP %d: %w",
-				vm.CycleCount, vm.InstructionPointer, err)
+		if err := vm.executeWithinLimits(inst); err != nil {
+			return err
 		}
 
 		// Increment cycle count
@@ -546,16 +586,17 @@ func (vm *VMState) Execute() error {
 // This follows Triton VM's approach:
 // 1. Record state BEFORE each instruction
 // 2. Execute the instruction
+// 3. Record the coprocessor rows (op stack, RAM, jump stack, hash, u32) it caused
 //
-// Phase 4a focuses on processor trace. Coprocessor traces (RAM, Hash, U32)
-// are deferred to Phase 4b.
+// The program must halt; running past its last instruction is a MissingHalt.
 func (vm *VMState) ExecuteAndTrace() (*AET, error) {
-	// Create simple trace recorder (processor-focused)
-	recorder, err := NewSimpleTraceRecorder(vm.Program)
+	recorder, err := NewTraceRecorder(vm.Program)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create trace recorder: %w", err)
 	}
+	vm.profiler.start(recorder)
 
+	halted := false
 	for vm.InstructionPointer < vm.Program.Length {
 		// Fetch current instruction
 		inst, err := vm.CurrentInstruction()
@@ -564,11 +605,14 @@ func (vm *VMState) ExecuteAndTrace() (*AET, error) {
 		}
 
 		// Check for halt before execution
+		vm.profiler.enter(vm)
 		if inst.Instruction == Halt {
 			// Record halt state
 			if err := recorder.RecordState(vm); err != nil {
 				return nil, fmt.Errorf("failed to record halt state: %w", err)
 			}
+			vm.profiler.leave(vm, recorder)
+			halted = true
 			break
 		}
 
@@ -578,21 +622,40 @@ func (vm *VMState) ExecuteAndTrace() (*AET, error) {
 		}
 
 		// STEP 2: Execute instruction
-		if err := vm.ExecuteInstruction(inst); err != nil {
-			return nil, fmt.Errorf("execution failed at cycle %d, IP %d: %w",
-				vm.CycleCount, vm.InstructionPointer, err)
+		if err := vm.executeWithinLimits(inst); err != nil {
+			return nil, err
 		}
 
+		// STEP 3: Record coprocessor rows
+		if err := recorder.RecordExecution(vm, inst); err != nil {
+			return nil, fmt.Errorf("failed to record execution at cycle %d: %w", vm.CycleCount, err)
+		}
+		vm.profiler.leave(vm, recorder)
+
 		// Increment cycle count
 		vm.CycleCount++
 	}
 
-	// Generate final AET (pad tables, compute auxiliary columns)
+	// The proof requires the trace to end at halt, whose row pads the
+	// Processor Table
+	if !halted {
+		return nil, &MissingHalt{ExecutionContext: ExecutionContext{IP: vm.InstructionPointer, Cycle: vm.CycleCount, Instruction: Halt}}
+	}
+
+	// Generate final AET (sort and fill coprocessor tables, pad)
 	aet, err := recorder.GenerateAET()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate AET: %w", err)
 	}
 
+	if limit := vm.Options.MaxPaddedHeight; limit > 0 && aet.PaddedHeight > limit {
+		ctx := ExecutionContext{IP: vm.InstructionPointer, Cycle: vm.CycleCount, Instruction: Halt}
+		if inst, err := vm.CurrentInstruction(); err == nil {
+			ctx.Instruction = inst.Instruction
+		}
+		return nil, &PaddedHeightExceeded{ExecutionContext: ctx, Height: aet.PaddedHeight, Limit: limit}
+	}
+
 	return aet, nil
 }
 
@@ -602,32 +665,10 @@ func (vm *VMState) ExecuteAndTrace() (*AET, error) {
 
 // computeProgramDigest computes the Poseidon hash digest of a program
 // Returns a 5-element digest for program attestation (TIP-0006)
+//
+// The program is hashed as the words the Program Table lists, an opcode
+// followed by its argument if it has one, so that the table can absorb
+// them in order.
 func computeProgramDigest(program *Program) [5]field.Element {
-	// Encode program instructions as field elements
-	programElements := make([]field.Element, 0, len(program.Instructions)*2)
-	for _, instr := range program.Instructions {
-		// Add instruction opcode
-		programElements = append(programElements, field.New(uint64(instr.Instruction)))
-
-		// Add argument if present
-		if instr.Argument != nil {
-			programElements = append(programElements, *instr.Argument)
-		} else {
-			programElements = append(programElements, field.Zero)
-		}
-	}
-
-	// Hash the program description using Poseidon
-	digestElement := hash.PoseidonHash(programElements)
-
-	// Create 5-element digest (in full implementation, use Tip5)
-	digest := [5]field.Element{
-		digestElement,
-		field.Zero,
-		field.Zero,
-		field.Zero,
-		field.Zero,
-	}
-
-	return digest
+	return poseidonHashVarlen(program.ToWords())
 }
@@ -0,0 +1,317 @@
+package vybiumstarksvm
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/assembler"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
+)
+
+// builderField is the field of the arguments a ProgramBuilder creates
+var builderField, _ = core.NewField(new(big.Int).SetUint64(field.P))
+
+// ProgramBuilder builds a Program instruction by instruction
+//
+// Every method returns the builder, so calls chain:
+//
+//	program, err := NewProgramBuilder().
+//		ReadIo(2).Call("add").WriteIo(1).Halt().
+//		Label("add").Add().Return().
+//		Build()
+//
+// Arguments are checked against the ranges of the ISA and calls may name
+// labels defined later. The first error is returned by Build.
+type ProgramBuilder struct {
+	instructions []Instruction
+	labels       map[string]uint64
+	calls        map[int]string // label called by the instruction at each index
+	address      uint64
+	err          error
+}
+
+// NewProgramBuilder returns a builder for an empty program
+func NewProgramBuilder() *ProgramBuilder {
+	return &ProgramBuilder{
+		labels: make(map[string]uint64),
+		calls:  make(map[int]string),
+	}
+}
+
+// Build resolves the labels and returns the program, with the labels as
+// its debug information
+func (b *ProgramBuilder) Build() (*Program, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	program := &Program{
+		Instructions: append([]Instruction(nil), b.instructions...),
+		Labels:       make(map[string]uint64, len(b.labels)),
+	}
+	for name, address := range b.labels {
+		program.Labels[name] = address
+	}
+	for index, label := range b.calls {
+		address, ok := b.labels[label]
+		if !ok {
+			return nil, &VMError{
+				Code:    ErrInvalidInput,
+				Message: fmt.Sprintf("instruction %d (call): undefined label %s", index, label),
+			}
+		}
+		program.Instructions[index].Argument = builderField.NewElement(new(big.Int).SetUint64(address))
+	}
+	return program, nil
+}
+
+// Label names the address of the next instruction
+func (b *ProgramBuilder) Label(name string) *ProgramBuilder {
+	if b.err != nil {
+		return b
+	}
+	if !assembler.IsLabel(name) {
+		return b.fail("invalid label %q", name)
+	}
+	if _, ok := b.labels[name]; ok {
+		return b.fail("label %s is defined twice", name)
+	}
+	b.labels[name] = b.address
+	return b
+}
+
+// Instruction appends any instruction; arg must be nil exactly if the
+// instruction takes no argument
+func (b *ProgramBuilder) Instruction(opcode Opcode, arg *FieldElement) *ProgramBuilder {
+	if b.err != nil {
+		return b
+	}
+	info, err := opcode.Info()
+	if err != nil {
+		return b.fail("%v", err)
+	}
+	if info.HasArg != (arg != nil) {
+		if info.HasArg {
+			return b.fail("%s requires an argument", info.Name)
+		}
+		return b.fail("%s takes no argument", info.Name)
+	}
+	if lo, hi, ok := opcode.ArgumentRange(); ok {
+		if value := arg.Big(); !value.IsUint64() || value.Uint64() < lo || value.Uint64() > hi {
+			return b.fail("%s: argument %s out of range %d..%d", info.Name, value, lo, hi)
+		}
+	}
+	b.instructions = append(b.instructions, Instruction{Opcode: opcode, Argument: arg})
+	b.address += uint64(info.Size)
+	return b
+}
+
+// fail records the first error, naming the instruction it is about
+func (b *ProgramBuilder) fail(format string, args ...interface{}) *ProgramBuilder {
+	b.err = &VMError{
+		Code:    ErrInvalidInput,
+		Message: fmt.Sprintf("instruction %d: %s", len(b.instructions), fmt.Sprintf(format, args...)),
+	}
+	return b
+}
+
+// withArg appends an instruction whose argument is a count or stack index
+func (b *ProgramBuilder) withArg(opcode Opcode, n int) *ProgramBuilder {
+	if n < 0 {
+		if b.err == nil {
+			b.fail("%s: argument %d out of range", opcode, n)
+		}
+		return b
+	}
+	return b.Instruction(opcode, builderField.NewElement(big.NewInt(int64(n))))
+}
+
+// Stack manipulation
+
+// Pop removes n elements, 1 to 5
+func (b *ProgramBuilder) Pop(n int) *ProgramBuilder { return b.withArg(Pop, n) }
+
+// Push pushes a value, reduced modulo the field's prime
+func (b *ProgramBuilder) Push(value uint64) *ProgramBuilder {
+	return b.Instruction(Push, builderField.NewElement(new(big.Int).SetUint64(value)))
+}
+
+// PushElement pushes a field element, such as p - 1
+func (b *ProgramBuilder) PushElement(value *FieldElement) *ProgramBuilder {
+	if value == nil {
+		return b.Instruction(Push, nil)
+	}
+	return b.Instruction(Push, builderField.NewElement(value.Big()))
+}
+
+// Divine pushes n elements of the secret input, 1 to 5
+func (b *ProgramBuilder) Divine(n int) *ProgramBuilder { return b.withArg(Divine, n) }
+
+// Pick moves st_i to the top, i from 0 to 15
+func (b *ProgramBuilder) Pick(i int) *ProgramBuilder { return b.withArg(Pick, i) }
+
+// Place moves the top to st_i, i from 0 to 15
+func (b *ProgramBuilder) Place(i int) *ProgramBuilder { return b.withArg(Place, i) }
+
+// Dup copies st_i to the top, i from 0 to 15
+func (b *ProgramBuilder) Dup(i int) *ProgramBuilder { return b.withArg(Dup, i) }
+
+// Swap swaps the top with st_i, i from 0 to 15
+func (b *ProgramBuilder) Swap(i int) *ProgramBuilder { return b.withArg(Swap, i) }
+
+// Control flow
+
+// Halt ends the execution
+func (b *ProgramBuilder) Halt() *ProgramBuilder { return b.Instruction(Halt, nil) }
+
+// Nop does nothing
+func (b *ProgramBuilder) Nop() *ProgramBuilder { return b.Instruction(Nop, nil) }
+
+// Skiz skips the next instruction if the top is zero
+func (b *ProgramBuilder) Skiz() *ProgramBuilder { return b.Instruction(Skiz, nil) }
+
+// Call calls the function at a label, which may be defined later
+func (b *ProgramBuilder) Call(label string) *ProgramBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.calls[len(b.instructions)] = label
+	return b.Instruction(Call, builderField.NewElement(big.NewInt(0)))
+}
+
+// Return returns from the current function
+func (b *ProgramBuilder) Return() *ProgramBuilder { return b.Instruction(Return, nil) }
+
+// Recurse jumps to the start of the current function
+func (b *ProgramBuilder) Recurse() *ProgramBuilder { return b.Instruction(Recurse, nil) }
+
+// RecurseOrReturn recurses, or returns if the jump stack holds one call
+func (b *ProgramBuilder) RecurseOrReturn() *ProgramBuilder {
+	return b.Instruction(RecurseOrReturn, nil)
+}
+
+// Assert fails the execution unless the top is 1
+func (b *ProgramBuilder) Assert() *ProgramBuilder { return b.Instruction(Assert, nil) }
+
+// Memory
+
+// ReadMem reads n words of RAM, 1 to 5
+func (b *ProgramBuilder) ReadMem(n int) *ProgramBuilder { return b.withArg(ReadMem, n) }
+
+// WriteMem writes n words of RAM, 1 to 5
+func (b *ProgramBuilder) WriteMem(n int) *ProgramBuilder { return b.withArg(WriteMem, n) }
+
+// Hashing
+
+// Hash hashes the top 10 elements
+func (b *ProgramBuilder) Hash() *ProgramBuilder { return b.Instruction(Hash, nil) }
+
+// AssertVector fails the execution unless st0..st4 equal st5..st9
+func (b *ProgramBuilder) AssertVector() *ProgramBuilder { return b.Instruction(AssertVector, nil) }
+
+// SpongeInit resets the sponge
+func (b *ProgramBuilder) SpongeInit() *ProgramBuilder { return b.Instruction(SpongeInit, nil) }
+
+// SpongeAbsorb absorbs the top 10 elements into the sponge
+func (b *ProgramBuilder) SpongeAbsorb() *ProgramBuilder { return b.Instruction(SpongeAbsorb, nil) }
+
+// SpongeAbsorbMem absorbs 10 words of RAM, from the address on top
+func (b *ProgramBuilder) SpongeAbsorbMem() *ProgramBuilder {
+	return b.Instruction(SpongeAbsorbMem, nil)
+}
+
+// SpongeSqueeze pushes 10 elements squeezed from the sponge
+func (b *ProgramBuilder) SpongeSqueeze() *ProgramBuilder { return b.Instruction(SpongeSqueeze, nil) }
+
+// Base field arithmetic
+
+// Add adds the top two elements
+func (b *ProgramBuilder) Add() *ProgramBuilder { return b.Instruction(Add, nil) }
+
+// AddI adds a constant to the top, reduced modulo the field's prime
+func (b *ProgramBuilder) AddI(value uint64) *ProgramBuilder {
+	return b.Instruction(AddI, builderField.NewElement(new(big.Int).SetUint64(value)))
+}
+
+// Mul multiplies the top two elements
+func (b *ProgramBuilder) Mul() *ProgramBuilder { return b.Instruction(Mul, nil) }
+
+// Invert replaces the top with its inverse
+func (b *ProgramBuilder) Invert() *ProgramBuilder { return b.Instruction(Invert, nil) }
+
+// Eq replaces the top two elements with 1 if they are equal, else 0
+func (b *ProgramBuilder) Eq() *ProgramBuilder { return b.Instruction(Eq, nil) }
+
+// U32 arithmetic
+
+// Split splits the top into its high and low 32 bits
+func (b *ProgramBuilder) Split() *ProgramBuilder { return b.Instruction(Split, nil) }
+
+// Lt replaces the top two u32s with 1 if the lower one is smaller, else 0
+func (b *ProgramBuilder) Lt() *ProgramBuilder { return b.Instruction(Lt, nil) }
+
+// And is the bitwise and of the top two u32s
+func (b *ProgramBuilder) And() *ProgramBuilder { return b.Instruction(And, nil) }
+
+// Xor is the bitwise xor of the top two u32s
+func (b *ProgramBuilder) Xor() *ProgramBuilder { return b.Instruction(Xor, nil) }
+
+// Log2Floor replaces the top u32 with the floor of its logarithm
+func (b *ProgramBuilder) Log2Floor() *ProgramBuilder { return b.Instruction(Log2Floor, nil) }
+
+// Pow raises the element below the top to the power of the top
+func (b *ProgramBuilder) Pow() *ProgramBuilder { return b.Instruction(Pow, nil) }
+
+// DivMod divides the top two u32s with remainder
+func (b *ProgramBuilder) DivMod() *ProgramBuilder { return b.Instruction(DivMod, nil) }
+
+// PopCount counts the set bits of the top u32
+func (b *ProgramBuilder) PopCount() *ProgramBuilder { return b.Instruction(PopCount, nil) }
+
+// Extension field arithmetic
+
+// XxAdd adds the top two extension field elements
+func (b *ProgramBuilder) XxAdd() *ProgramBuilder { return b.Instruction(XxAdd, nil) }
+
+// XxMul multiplies the top two extension field elements
+func (b *ProgramBuilder) XxMul() *ProgramBuilder { return b.Instruction(XxMul, nil) }
+
+// XInvert replaces the top extension field element with its inverse
+func (b *ProgramBuilder) XInvert() *ProgramBuilder { return b.Instruction(XInvert, nil) }
+
+// XbMul multiplies an extension field element by the base field element on top
+func (b *ProgramBuilder) XbMul() *ProgramBuilder { return b.Instruction(XbMul, nil) }
+
+// Input and output
+
+// ReadIo pushes n elements of the public input, 1 to 5
+func (b *ProgramBuilder) ReadIo(n int) *ProgramBuilder { return b.withArg(ReadIo, n) }
+
+// WriteIo pops n elements to the public output, 1 to 5
+func (b *ProgramBuilder) WriteIo(n int) *ProgramBuilder { return b.withArg(WriteIo, n) }
+
+// Merkle trees and dot products
+
+// MerkleStep moves one level up a Merkle tree, with a secret sibling digest
+func (b *ProgramBuilder) MerkleStep() *ProgramBuilder { return b.Instruction(MerkleStep, nil) }
+
+// MerkleStepMem moves one level up a Merkle tree, with a sibling from RAM
+func (b *ProgramBuilder) MerkleStepMem() *ProgramBuilder { return b.Instruction(MerkleStepMem, nil) }
+
+// XxDotStep adds the product of two extension field elements to an accumulator
+func (b *ProgramBuilder) XxDotStep() *ProgramBuilder { return b.Instruction(XxDotStep, nil) }
+
+// XbDotStep adds the product of a base and an extension field element to an
+// accumulator
+func (b *ProgramBuilder) XbDotStep() *ProgramBuilder { return b.Instruction(XbDotStep, nil) }
+
+// Permutation checks (TIP-0007)
+
+// PushPerm multiplies the top 5 elements into the permutation accumulator
+func (b *ProgramBuilder) PushPerm() *ProgramBuilder { return b.Instruction(PushPerm, nil) }
+
+// PopPerm divides the top 5 elements out of the permutation accumulator
+func (b *ProgramBuilder) PopPerm() *ProgramBuilder { return b.Instruction(PopPerm, nil) }
+
+// AssertPerm fails the execution unless the permutation accumulator is 1
+func (b *ProgramBuilder) AssertPerm() *ProgramBuilder { return b.Instruction(AssertPerm, nil) }
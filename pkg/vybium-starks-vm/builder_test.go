@@ -0,0 +1,81 @@
+package vybiumstarksvm
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestProgramBuilder(t *testing.T) {
+	elem := func(v uint64) *FieldElement { return builderField.NewElement(new(big.Int).SetUint64(v)) }
+
+	t.Run("Execute", func(t *testing.T) {
+		// Calls a function defined after its use
+		program, err := NewProgramBuilder().
+			ReadIo(2).Call("add").WriteIo(1).Halt().
+			Label("add").Add().Return().
+			Build()
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if program.Labels["add"] != 7 || program.Instructions[1].Opcode != Call ||
+			program.Instructions[1].Argument.Big().Uint64() != 7 {
+			t.Fatalf("call not resolved to add at 7: %+v", program)
+		}
+
+		vm, err := NewVM(DefaultVMConfig())
+		if err != nil {
+			t.Fatalf("NewVM failed: %v", err)
+		}
+		input := []*FieldElement{elem(17), elem(25)}
+		trace, err := vm.Execute(program, input, nil)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if output := trace.PublicOutput[len(trace.PublicOutput)-1]; output.Big().Uint64() != 42 {
+			t.Errorf("output %v, want 42", output)
+		}
+	})
+
+	t.Run("Arguments", func(t *testing.T) {
+		program, err := NewProgramBuilder().
+			Push(1 << 63).PushElement(elem(0).Sub(elem(1))).Dup(15).Pop(5).
+			Build()
+		if err != nil {
+			t.Fatalf("Build failed: %v", err)
+		}
+		if got := program.Instructions[1].Argument.Big().Uint64(); got != 18446744069414584320 {
+			t.Errorf("pushed %d, want p - 1", got)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		builder *ProgramBuilder
+		message string
+	}{
+		{"PopRange", NewProgramBuilder().Push(1).Pop(6), "instruction 1: pop: argument 6 out of range 1..5"},
+		{"DupRange", NewProgramBuilder().Dup(16), "dup: argument 16 out of range 0..15"},
+		{"NegativeIndex", NewProgramBuilder().Swap(-1), "swap: argument -1 out of range"},
+		{"ReadIoZero", NewProgramBuilder().ReadIo(0), "read_io: argument 0 out of range 1..5"},
+		{"UnknownOpcode", NewProgramBuilder().Instruction(Opcode(7), nil), "unknown instruction: 7"},
+		{"MissingArgument", NewProgramBuilder().Instruction(Push, nil), "push requires an argument"},
+		{"ExtraArgument", NewProgramBuilder().Instruction(Add, elem(1)), "add takes no argument"},
+		{"UndefinedLabel", NewProgramBuilder().Call("missing").Halt(), "instruction 0 (call): undefined label missing"},
+		{"DuplicateLabel", NewProgramBuilder().Label("f").Halt().Label("f"), "label f is defined twice"},
+		{"InvalidLabel", NewProgramBuilder().Label("1f"), `invalid label "1f"`},
+		{"FirstErrorWins", NewProgramBuilder().Pop(0).Pop(9), "pop: argument 0 out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if !errors.Is(err, &VMError{Code: ErrInvalidInput}) {
+				t.Fatalf("expected an ErrInvalidInput error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not contain %q", err, tt.message)
+			}
+		})
+	}
+}
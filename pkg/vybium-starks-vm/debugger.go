@@ -0,0 +1,276 @@
+package vybiumstarksvm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+// Debugger executes a program instruction by instruction, stopping at
+// breakpoints, and exposes the full machine state in between
+//
+// A Debugger does not record a trace; once the program behaves, prove it
+// with VM.Execute.
+type Debugger struct {
+	vm          *vmImpl
+	program     *Program
+	addresses   map[uint64]bool // word addresses at which an instruction starts
+	breakpoints map[uint64]bool
+	err         error // the failure that stopped execution, if any
+}
+
+// DebugState is a snapshot of the machine between two instructions
+type DebugState struct {
+	InstructionPointer int
+	CycleCount         int
+	Halted             bool
+
+	// NextInstruction is the instruction at InstructionPointer, nil once the
+	// program halted or ran past its end
+	NextInstruction *Instruction
+
+	// OpStack holds the whole operational stack, st0 first, including the
+	// elements beyond the 16 registers
+	OpStack []*FieldElement
+
+	// JumpStack holds the active calls, innermost last
+	JumpStack []JumpStackFrame
+
+	// RAM holds every memory cell written or initialized so far
+	RAM map[uint64]*FieldElement
+
+	// Sponge is the Poseidon sponge state, nil before sponge_init
+	Sponge []*FieldElement
+
+	PublicOutput []*FieldElement
+}
+
+// JumpStackFrame is an active call: the address return continues at and
+// the address that was called
+type JumpStackFrame struct {
+	Origin      int
+	Destination int
+}
+
+// NewDebugger loads a program and its inputs, stopped before the first
+// instruction, with the breakpoints of program.Breakpoints set
+func NewDebugger(config *VMConfig, program *Program, publicInput []*FieldElement, nonDeterminism *NonDeterminism, options ExecutionOptions) (*Debugger, error) {
+	if program == nil {
+		return nil, &VMError{
+			Code:    ErrInvalidInput,
+			Message: "program is nil",
+		}
+	}
+	impl, err := newVMImpl(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := impl.load(program, publicInput, nonDeterminism, options); err != nil {
+		return nil, err
+	}
+
+	d := &Debugger{
+		vm:          impl,
+		program:     program,
+		addresses:   make(map[uint64]bool, len(program.Instructions)),
+		breakpoints: make(map[uint64]bool),
+	}
+	starts := make([]uint64, 0, len(program.Instructions))
+	address := uint64(0)
+	for _, inst := range program.Instructions {
+		d.addresses[address] = true
+		starts = append(starts, address)
+		address += uint64(inst.Opcode.Size())
+	}
+
+	// Breakpoints are per word as in Triton, or per instruction
+	switch len(program.Breakpoints) {
+	case 0:
+	case int(address):
+		for addr, set := range program.Breakpoints {
+			if set && d.addresses[uint64(addr)] {
+				d.breakpoints[uint64(addr)] = true
+			}
+		}
+	case len(starts):
+		for i, set := range program.Breakpoints {
+			if set {
+				d.breakpoints[starts[i]] = true
+			}
+		}
+	default:
+		return nil, &VMError{
+			Code: ErrInvalidInput,
+			Message: fmt.Sprintf("program has %d breakpoints for %d words (%d instructions)",
+				len(program.Breakpoints), address, len(starts)),
+		}
+	}
+
+	return d, nil
+}
+
+// SetBreakpoint stops execution before the instruction at address
+func (d *Debugger) SetBreakpoint(address uint64) error {
+	if !d.addresses[address] {
+		return &VMError{
+			Code:    ErrInvalidInput,
+			Message: fmt.Sprintf("no instruction starts at address %d", address),
+		}
+	}
+	d.breakpoints[address] = true
+	return nil
+}
+
+// SetBreakpointAtLabel stops execution before the instruction at a label
+// of program.Labels
+func (d *Debugger) SetBreakpointAtLabel(label string) error {
+	address, ok := d.program.Labels[label]
+	if !ok {
+		return &VMError{
+			Code:    ErrInvalidInput,
+			Message: fmt.Sprintf("unknown label %q", label),
+		}
+	}
+	return d.SetBreakpoint(address)
+}
+
+// ClearBreakpoint removes the breakpoint at address, if there is one
+func (d *Debugger) ClearBreakpoint(address uint64) {
+	delete(d.breakpoints, address)
+}
+
+// Breakpoints returns the addresses of all breakpoints in ascending order
+func (d *Debugger) Breakpoints() []uint64 {
+	addresses := make([]uint64, 0, len(d.breakpoints))
+	for address := range d.breakpoints {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return addresses
+}
+
+// AtBreakpoint reports whether the next instruction has a breakpoint
+func (d *Debugger) AtBreakpoint() bool {
+	return d.breakpoints[uint64(d.vm.vmState.InstructionPointer)]
+}
+
+// Halted reports whether the program executed halt
+func (d *Debugger) Halted() bool {
+	return d.vm.vmState.Halting
+}
+
+// Err returns the failure that stopped execution, nil if there was none
+func (d *Debugger) Err() error {
+	return d.err
+}
+
+// Step executes one instruction
+//
+// A failed instruction is a VMError with code ErrVMExecution caused by one
+// of the typed execution failures; the machine stays stopped after it and
+// every further step returns the same error. Running past the last
+// instruction without halt fails with *MissingHalt, as in VM.Execute.
+func (d *Debugger) Step() error {
+	if d.err != nil {
+		return d.err
+	}
+	if d.Halted() {
+		return &VMError{
+			Code:    ErrVMExecution,
+			Message: "program halted",
+		}
+	}
+	state := d.vm.vmState
+	var err error
+	if state.InstructionPointer < state.Program.Length {
+		err = state.Step()
+	}
+	if err == nil && !state.Halting && state.InstructionPointer >= state.Program.Length {
+		err = &vm.MissingHalt{ExecutionContext: vm.ExecutionContext{
+			IP:          state.InstructionPointer,
+			Cycle:       state.CycleCount,
+			Instruction: vm.Halt,
+		}}
+	}
+	if err != nil {
+		d.err = &VMError{
+			Code:    ErrVMExecution,
+			Message: "VM execution failed",
+			Cause:   err,
+		}
+		return d.err
+	}
+	return nil
+}
+
+// StepOver executes one instruction, or a whole call: after a call it
+// continues until the called function returns, unless it reaches a
+// breakpoint, halts or fails first
+func (d *Debugger) StepOver() error {
+	state := d.vm.vmState
+	inst, err := state.CurrentInstruction()
+	if err != nil || inst.Instruction != vm.Call {
+		return d.Step()
+	}
+
+	depth := len(state.JumpStack)
+	if err := d.Step(); err != nil {
+		return err
+	}
+	for len(state.JumpStack) > depth && !d.Halted() && !d.AtBreakpoint() {
+		if err := d.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Continue executes instructions until the next breakpoint, the end of the
+// program or a failure. The breakpoint Continue starts at does not stop it.
+func (d *Debugger) Continue() error {
+	if err := d.Step(); err != nil {
+		return err
+	}
+	for !d.Halted() && !d.AtBreakpoint() {
+		if err := d.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// State returns a snapshot of the machine
+func (d *Debugger) State() *DebugState {
+	state := d.vm.vmState
+	snapshot := &DebugState{
+		InstructionPointer: state.InstructionPointer,
+		CycleCount:         int(state.CycleCount),
+		Halted:             d.Halted(),
+		OpStack:            d.vm.convertFromInternal(state.StackElements()),
+		JumpStack:          make([]JumpStackFrame, len(state.JumpStack)),
+		RAM:                make(map[uint64]*FieldElement, len(state.RAM)),
+		PublicOutput:       d.vm.convertFromInternal(state.PublicOutput),
+	}
+
+	if !snapshot.Halted {
+		if inst, err := state.CurrentInstruction(); err == nil {
+			snapshot.NextInstruction = &Instruction{Opcode: inst.Instruction}
+			if inst.Argument != nil {
+				snapshot.NextInstruction.Argument = d.vm.convertFromInternal(
+					[]field.Element{*inst.Argument})[0]
+			}
+		}
+	}
+	for i, entry := range state.JumpStack {
+		snapshot.JumpStack[i] = JumpStackFrame{Origin: entry.Origin, Destination: entry.Destination}
+	}
+	for address, value := range state.RAM {
+		snapshot.RAM[address.Value()] = d.vm.convertFromInternal([]field.Element{value})[0]
+	}
+	if state.Sponge != nil {
+		snapshot.Sponge = d.vm.convertFromInternal(state.Sponge.State)
+	}
+	return snapshot
+}
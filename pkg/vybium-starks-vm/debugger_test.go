@@ -0,0 +1,163 @@
+package vybiumstarksvm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+func TestDebugger(t *testing.T) {
+	f, err := core.NewField(big.NewInt(0).SetUint64(18446744069414584321))
+	if err != nil {
+		t.Fatalf("NewField failed: %v", err)
+	}
+	elem := func(v uint64) *FieldElement { return f.NewElement(new(big.Int).SetUint64(v)) }
+	op := func(inst vm.Instruction, arg ...uint64) Instruction {
+		if len(arg) > 0 {
+			return Instruction{Opcode: inst, Argument: elem(arg[0])}
+		}
+		return Instruction{Opcode: inst}
+	}
+
+	// Writes 3·4 through a call to "times_four"
+	program := &Program{
+		Instructions: []Instruction{
+			op(vm.Push, 3),    // 0
+			op(vm.Call, 7),    // 2
+			op(vm.WriteIo, 1), // 4
+			op(vm.Halt),       // 6
+			op(vm.Push, 4),    // 7: times_four
+			op(vm.Mul),        // 9
+			op(vm.Return),     // 10
+		},
+		Labels:      map[string]uint64{"times_four": 7},
+		Breakpoints: []bool{false, false, true, false, false, false, false},
+	}
+	newDebugger := func(t *testing.T, program *Program) *Debugger {
+		t.Helper()
+		d, err := NewDebugger(DefaultVMConfig(), program, nil, nil, DefaultExecutionOptions())
+		if err != nil {
+			t.Fatalf("NewDebugger failed: %v", err)
+		}
+		return d
+	}
+	top := func(d *Debugger) uint64 {
+		return d.State().OpStack[0].Big().Uint64()
+	}
+
+	t.Run("StepAndStepOver", func(t *testing.T) {
+		d := newDebugger(t, program)
+		state := d.State()
+		if state.InstructionPointer != 0 || len(state.OpStack) != 5 || state.NextInstruction.Opcode != vm.Push {
+			t.Fatalf("unexpected initial state: %+v", state)
+		}
+
+		if err := d.Step(); err != nil {
+			t.Fatalf("Step failed: %v", err)
+		}
+		if d.State().InstructionPointer != 2 || top(d) != 3 {
+			t.Errorf("after push: IP %d, st0 %d", d.State().InstructionPointer, top(d))
+		}
+
+		if err := d.StepOver(); err != nil {
+			t.Fatalf("StepOver failed: %v", err)
+		}
+		state = d.State()
+		if state.InstructionPointer != 4 || len(state.JumpStack) != 0 || top(d) != 12 {
+			t.Errorf("after stepping over the call: IP %d, jump stack %v, st0 %d",
+				state.InstructionPointer, state.JumpStack, top(d))
+		}
+	})
+
+	t.Run("Breakpoints", func(t *testing.T) {
+		d := newDebugger(t, program)
+		if err := d.SetBreakpointAtLabel("times_four"); err != nil {
+			t.Fatalf("SetBreakpointAtLabel failed: %v", err)
+		}
+		if got := d.Breakpoints(); len(got) != 2 || got[0] != 4 || got[1] != 7 {
+			t.Errorf("breakpoints = %v, want [4 7]", got)
+		}
+
+		if err := d.Continue(); err != nil {
+			t.Fatalf("Continue failed: %v", err)
+		}
+		state := d.State()
+		if state.InstructionPointer != 7 || len(state.JumpStack) != 1 ||
+			state.JumpStack[0] != (JumpStackFrame{Origin: 4, Destination: 7}) {
+			t.Errorf("at times_four: IP %d, jump stack %v", state.InstructionPointer, state.JumpStack)
+		}
+
+		if err := d.Continue(); err != nil {
+			t.Fatalf("Continue failed: %v", err)
+		}
+		if !d.AtBreakpoint() || d.State().InstructionPointer != 4 {
+			t.Errorf("expected to stop at write_io, IP %d", d.State().InstructionPointer)
+		}
+
+		if err := d.Continue(); err != nil {
+			t.Fatalf("Continue failed: %v", err)
+		}
+		state = d.State()
+		if !d.Halted() || state.NextInstruction != nil || state.PublicOutput[5].Big().Uint64() != 12 {
+			t.Errorf("expected to halt with output 12, got %+v", state)
+		}
+		if err := d.Step(); err == nil {
+			t.Error("stepped past halt")
+		}
+
+		if err := d.SetBreakpoint(3); err == nil {
+			t.Error("set a breakpoint inside push 3")
+		}
+		if err := d.SetBreakpointAtLabel("nowhere"); err == nil {
+			t.Error("set a breakpoint at an unknown label")
+		}
+	})
+
+	t.Run("FailedAssert", func(t *testing.T) {
+		d := newDebugger(t, &Program{Instructions: []Instruction{
+			op(vm.Push, 100), op(vm.Push, 7), op(vm.WriteMem, 1), op(vm.SpongeInit),
+			op(vm.Push, 0), op(vm.Assert), op(vm.Halt),
+		}})
+		err := d.Continue()
+		var failure *AssertionFailure
+		if !errors.As(err, &failure) || failure.IP != 9 || !errors.Is(d.Err(), err) {
+			t.Fatalf("expected a failed assertion at IP 9, got %v", err)
+		}
+		if err := d.Step(); err != d.Err() {
+			t.Errorf("stepping after a failure returned %v", err)
+		}
+
+		state := d.State()
+		if value, ok := state.RAM[100]; !ok || value.Big().Uint64() != 7 || len(state.RAM) != 1 {
+			t.Errorf("RAM = %v, want 100: 7", state.RAM)
+		}
+		if len(state.Sponge) != 16 {
+			t.Errorf("sponge has %d elements, want 16", len(state.Sponge))
+		}
+	})
+
+	t.Run("MissingHalt", func(t *testing.T) {
+		d := newDebugger(t, &Program{Instructions: []Instruction{op(vm.Push, 1), op(vm.Pop, 1)}})
+		err := d.Continue()
+		var missing *MissingHalt
+		if !errors.As(err, &missing) || missing.IP != 4 || !errors.Is(d.Err(), err) {
+			t.Fatalf("expected a missing halt at IP 4, got %v", err)
+		}
+		if d.Halted() {
+			t.Error("running past the last instruction reported as halt")
+		}
+		if err := d.StepOver(); err != d.Err() {
+			t.Errorf("stepping over after a missing halt returned %v", err)
+		}
+	})
+
+	if _, err := NewDebugger(DefaultVMConfig(), &Program{
+		Instructions: program.Instructions,
+		Breakpoints:  []bool{true},
+	}, nil, nil, DefaultExecutionOptions()); err == nil {
+		t.Error("accepted one breakpoint for seven instructions")
+	}
+}
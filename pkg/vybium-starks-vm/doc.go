@@ -47,6 +47,18 @@
 //		fmt.Println("Proof is valid!")
 //	}
 //
+// Proofs are stored and transmitted in a versioned binary format:
+//
+//	data, err := proof.MarshalBinary()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	decoded := &vybiumstarksvm.Proof{}
+//	if err := decoded.UnmarshalBinary(data); err != nil {
+//		log.Fatal(err)
+//	}
+//
 // # Using the Vybium STARKs VM
 //
 // Executing a program on the VM:
@@ -57,12 +69,13 @@
 //		log.Fatal(err)
 //	}
 //
-//	// Create a simple program
-//	program := &vybiumstarksvm.Program{
-//		Instructions: []vybiumstarksvm.Instruction{
-//			{Opcode: 0x01, Argument: nil}, // Push
-//			{Opcode: 0x00, Argument: nil}, // Halt
-//		},
+//	// Build a program that adds its two inputs in a function
+//	program, err := vybiumstarksvm.NewProgramBuilder().
+//		ReadIo(2).Call("add").WriteIo(1).Halt().
+//		Label("add").Add().Return().
+//		Build()
+//	if err != nil {
+//		log.Fatal(err)
 //	}
 //
 //	// Execute the program
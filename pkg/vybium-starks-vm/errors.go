@@ -1,6 +1,10 @@
 package vybiumstarksvm
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
 
 // ErrorCode represents a Vybium STARKs VM error code
 type ErrorCode int
@@ -64,3 +68,53 @@ func (e *VMError) Is(target error) bool {
 	}
 	return e.Code == t.Code
 }
+
+// Typed execution failures
+//
+// A failed execution returns a VMError with code ErrVMExecution whose Cause
+// is one of these; find it with errors.As. Every one of them embeds the
+// ExecutionContext of the instruction that failed.
+type (
+	// ExecutionContext holds the IP, cycle and instruction of a failure
+	ExecutionContext = vm.ExecutionContext
+
+	// AssertionFailure is a failed assert, assert_vector or assert_perm
+	AssertionFailure = vm.AssertionFailure
+
+	// InputExhausted is a read past the end of the public or secret input
+	InputExhausted = vm.InputExhausted
+
+	// StackUnderflow is an instruction with too few operational stack elements
+	StackUnderflow = vm.StackUnderflow
+
+	// JumpStackUnderflow is a return outside of a call
+	JumpStackUnderflow = vm.JumpStackUnderflow
+
+	// CycleLimitExceeded is an execution longer than ExecutionOptions.MaxCycles
+	CycleLimitExceeded = vm.CycleLimitExceeded
+
+	// RAMLimitExceeded is an execution using more than ExecutionOptions.MaxRAMCells
+	RAMLimitExceeded = vm.RAMLimitExceeded
+
+	// JumpStackLimitExceeded is a call deeper than ExecutionOptions.MaxJumpStackDepth
+	JumpStackLimitExceeded = vm.JumpStackLimitExceeded
+
+	// PaddedHeightExceeded is a trace taller than ExecutionOptions.MaxPaddedHeight
+	PaddedHeightExceeded = vm.PaddedHeightExceeded
+
+	// MissingHalt is a traced execution that ran past the program's end
+	MissingHalt = vm.MissingHalt
+
+	// InstructionFailure is any other failure of an instruction
+	InstructionFailure = vm.InstructionFailure
+
+	// InputSource names the input an InputExhausted ran out of
+	InputSource = vm.InputSource
+)
+
+// Input sources of InputExhausted
+const (
+	PublicInputSource   = vm.PublicInputSource
+	SecretInputSource   = vm.SecretInputSource
+	SecretDigestsSource = vm.SecretDigestsSource
+)
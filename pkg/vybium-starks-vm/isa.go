@@ -0,0 +1,80 @@
+package vybiumstarksvm
+
+import "github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+
+// Opcode identifies an instruction of the ISA. Its String method returns the
+// mnemonic, Size the number of words it occupies, HasArgument whether it
+// takes an argument and ArgumentRange the arguments it accepts.
+type Opcode = vm.Instruction
+
+// The instructions of the ISA, see the vm package for what each one does
+const (
+	// Stack manipulation
+	Pop    = vm.Pop
+	Push   = vm.Push
+	Divine = vm.Divine
+	Pick   = vm.Pick
+	Place  = vm.Place
+	Dup    = vm.Dup
+	Swap   = vm.Swap
+
+	// Control flow
+	Halt            = vm.Halt
+	Nop             = vm.Nop
+	Skiz            = vm.Skiz
+	Call            = vm.Call
+	Return          = vm.Return
+	Recurse         = vm.Recurse
+	RecurseOrReturn = vm.RecurseOrReturn
+	Assert          = vm.Assert
+
+	// Memory
+	ReadMem  = vm.ReadMem
+	WriteMem = vm.WriteMem
+
+	// Hashing
+	Hash            = vm.Hash
+	AssertVector    = vm.AssertVector
+	SpongeInit      = vm.SpongeInit
+	SpongeAbsorb    = vm.SpongeAbsorb
+	SpongeAbsorbMem = vm.SpongeAbsorbMem
+	SpongeSqueeze   = vm.SpongeSqueeze
+
+	// Base field arithmetic
+	Add    = vm.Add
+	AddI   = vm.AddI
+	Mul    = vm.Mul
+	Invert = vm.Invert
+	Eq     = vm.Eq
+
+	// U32 arithmetic
+	Split     = vm.Split
+	Lt        = vm.Lt
+	And       = vm.And
+	Xor       = vm.Xor
+	Log2Floor = vm.Log2Floor
+	Pow       = vm.Pow
+	DivMod    = vm.DivMod
+	PopCount  = vm.PopCount
+
+	// Extension field arithmetic
+	XxAdd   = vm.XxAdd
+	XxMul   = vm.XxMul
+	XInvert = vm.XInvert
+	XbMul   = vm.XbMul
+
+	// Input and output
+	ReadIo  = vm.ReadIo
+	WriteIo = vm.WriteIo
+
+	// Merkle trees and dot products
+	MerkleStep    = vm.MerkleStep
+	MerkleStepMem = vm.MerkleStepMem
+	XxDotStep     = vm.XxDotStep
+	XbDotStep     = vm.XbDotStep
+
+	// Permutation checks (TIP-0007)
+	PushPerm   = vm.PushPerm
+	PopPerm    = vm.PopPerm
+	AssertPerm = vm.AssertPerm
+)
@@ -0,0 +1,54 @@
+package vybiumstarksvm
+
+import (
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+// Profile attributes the rows of an execution trace to the functions that
+// caused them, and names the table that decides the padded height
+type Profile = vm.Profile
+
+// FunctionProfile is the cost of a function over all of its calls
+type FunctionProfile = vm.FunctionProfile
+
+// ProfileCost counts the rows of the tables that grow with an execution
+type ProfileCost = vm.ProfileCost
+
+// TableID identifies a table of the execution trace; String names it
+type TableID = vm.TableID
+
+// ProfileExecution executes a program and profiles it by function: the
+// cycles and the op stack, RAM, hash and u32 table rows each function
+// caused. Functions are named by program.Labels.
+//
+// A failed execution is a VMError with code ErrVMExecution, as returned by
+// VM.ExecuteWithOptions.
+func ProfileExecution(config *VMConfig, program *Program, publicInput []*FieldElement, nonDeterminism *NonDeterminism, options ExecutionOptions) (*Profile, error) {
+	if program == nil {
+		return nil, &VMError{
+			Code:    ErrInvalidInput,
+			Message: "program is nil",
+		}
+	}
+	impl, err := newVMImpl(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := impl.load(program, publicInput, nonDeterminism, options); err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]int, len(program.Labels))
+	for name, address := range program.Labels {
+		labels[name] = int(address)
+	}
+	_, profile, err := impl.vmState.ProfileAndTrace(labels)
+	if err != nil {
+		return nil, &VMError{
+			Code:    ErrVMExecution,
+			Message: "VM execution failed",
+			Cause:   err,
+		}
+	}
+	return profile, nil
+}
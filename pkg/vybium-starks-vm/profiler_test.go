@@ -0,0 +1,55 @@
+package vybiumstarksvm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
+)
+
+func TestProfileExecution(t *testing.T) {
+	f, err := core.NewField(big.NewInt(0).SetUint64(18446744069414584321))
+	if err != nil {
+		t.Fatalf("NewField failed: %v", err)
+	}
+	elem := func(v uint64) *FieldElement { return f.NewElement(new(big.Int).SetUint64(v)) }
+
+	// Writes 100 to RAM in "store", called twice
+	program := &Program{
+		Instructions: []Instruction{
+			{Opcode: vm.Call, Argument: elem(5)},     // 0
+			{Opcode: vm.Call, Argument: elem(5)},     // 2
+			{Opcode: vm.Halt},                        // 4
+			{Opcode: vm.Push, Argument: elem(100)},   // 5: store
+			{Opcode: vm.Push, Argument: elem(7)},     // 7
+			{Opcode: vm.WriteMem, Argument: elem(1)}, // 9
+			{Opcode: vm.Return},                      // 11
+		},
+		Labels: map[string]uint64{"store": 5},
+	}
+	profile, err := ProfileExecution(DefaultVMConfig(), program, nil, nil, DefaultExecutionOptions())
+	if err != nil {
+		t.Fatalf("ProfileExecution failed: %v", err)
+	}
+	if len(profile.Functions) != 2 {
+		t.Fatalf("profiled %d functions, want 2", len(profile.Functions))
+	}
+	store := profile.Functions[1]
+	if store.Name != "store" || store.Calls != 2 || store.Self.Cycles != 8 || store.Self.RAMRows != 2 {
+		t.Errorf("unexpected profile of store: %+v", store)
+	}
+	if profile.TableHeights[profile.TallestTable] > profile.PaddedHeight {
+		t.Errorf("tallest table %s is taller than the padded height %d", profile.TallestTable, profile.PaddedHeight)
+	}
+
+	// pop 1 with an empty stack
+	_, err = ProfileExecution(DefaultVMConfig(), &Program{Instructions: []Instruction{
+		{Opcode: vm.Pop, Argument: elem(5)}, {Opcode: vm.Pop, Argument: elem(1)},
+	}}, nil, nil, DefaultExecutionOptions())
+	var underflow *StackUnderflow
+	if !errors.As(err, &underflow) || underflow.IP != 2 {
+		t.Errorf("expected a stack underflow at IP 2, got %v", err)
+	}
+}
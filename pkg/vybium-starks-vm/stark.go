@@ -63,8 +63,18 @@ func NewProver(config *Config) (Prover, error) {
 		NumCollinearityChecks: config.FRIQueries, // Use FRIQueries from config
 	}
 
+	// Prove the constraints of every table, not just the processor's
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		return nil, &VMError{
+			Code:    ErrProofGeneration,
+			Message: "failed to create AIR: " + err.Error(),
+			Cause:   err,
+		}
+	}
+
 	// Create internal prover (no longer requires field parameter)
-	prover, err := protocols.NewProver(params)
+	prover, err := protocols.NewProver(params, air)
 	if err != nil {
 		return nil, &VMError{
 			Code:    ErrProofGeneration,
@@ -72,6 +82,7 @@ func NewProver(config *Config) (Prover, error) {
 			Cause:   err,
 		}
 	}
+	prover.SetOptions(config.ProverOptions)
 
 	return &proverImpl{
 		field:  field,
@@ -108,8 +119,18 @@ func NewVerifier(config *Config) (Verifier, error) {
 		NumCollinearityChecks: config.FRIQueries, // Use FRIQueries from config
 	}
 
+	// Check the constraints of every table, matching the prover
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		return nil, &VMError{
+			Code:    ErrProofVerification,
+			Message: "failed to create AIR: " + err.Error(),
+			Cause:   err,
+		}
+	}
+
 	// Create internal verifier (still requires field parameter)
-	verifier, err := protocols.NewVerifier(field, params)
+	verifier, err := protocols.NewVerifier(field, params, air)
 	if err != nil {
 		return nil, &VMError{
 			Code:    ErrProofVerification,
@@ -3,6 +3,7 @@ package vybiumstarksvm
 import (
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/protocols"
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/vm"
 )
 
 // FieldElement represents an element in a finite field
@@ -21,14 +22,83 @@ type Claim = protocols.Claim
 // Program represents a Vybium STARKs VM program
 type Program struct {
 	Instructions []Instruction
+
+	// Labels maps names to word addresses, for breakpoints set by label.
+	// Like Breakpoints it is debug information, ignored by execution.
+	Labels map[string]uint64
+
+	// Breakpoints marks the addresses a Debugger stops at, in the layout of
+	// Triton's debug_information: one entry per word of the program. One
+	// entry per instruction is accepted too.
+	Breakpoints []bool
 }
 
-// Instruction represents a single VM instruction
+// Instruction represents a single VM instruction; ProgramBuilder creates
+// them with checked arguments
 type Instruction struct {
-	Opcode   byte
+	Opcode   Opcode
 	Argument *FieldElement
 }
 
+// NonDeterminism represents the prover's secret input to an execution
+type NonDeterminism struct {
+	// Individual tokens, read by divine
+	IndividualTokens []*FieldElement
+
+	// Digests of 5 elements each, one read by every merkle_step
+	Digests [][]*FieldElement
+
+	// Initial RAM (address -> value), read by read_mem and merkle_step_mem.
+	// Addresses not listed start out as zero.
+	RAM map[uint64]*FieldElement
+}
+
+// ExecutionOptions limits the cycles, RAM cells, jump stack depth and
+// padded height of an execution. A limit of zero means no limit.
+type ExecutionOptions = vm.ExecutionOptions
+
+// DefaultExecutionOptions returns the limits used by Execute: one million
+// cycles and no other limits
+func DefaultExecutionOptions() ExecutionOptions {
+	return vm.DefaultExecutionOptions()
+}
+
+// Diagnostic is a problem AnalyzeProgram found at an instruction
+type Diagnostic = vm.Diagnostic
+
+// Severity tells whether a Diagnostic is certain to fail an execution
+type Severity = vm.Severity
+
+const (
+	SeverityWarning = vm.SeverityWarning // suspicious, such as unreachable code
+	SeverityError   = vm.SeverityError   // fails whenever reached
+)
+
+// ProverOptions limits the workers and memory of a proof and reports its
+// progress. A limit of zero means one worker per CPU and no memory ceiling.
+type ProverOptions = protocols.ProverOptions
+
+// DefaultProverOptions returns the options used by a Prover whose Config
+// sets none: one worker per CPU and no memory ceiling
+func DefaultProverOptions() ProverOptions {
+	return protocols.DefaultProverOptions()
+}
+
+// ProverProgress reports how far a step of a phase of a proof has come
+type ProverProgress = protocols.ProverProgress
+
+// ProverPhase identifies a phase of proof generation
+type ProverPhase = protocols.ProverPhase
+
+const (
+	PhaseLowDegreeExtension = protocols.PhaseLowDegreeExtension // extending the trace columns
+	PhaseMerkleCommitment   = protocols.PhaseMerkleCommitment   // hashing table rows
+	PhaseQuotient           = protocols.PhaseQuotient           // evaluating the constraints
+	PhaseOutOfDomain        = protocols.PhaseOutOfDomain        // evaluating the trace out of domain
+	PhaseDEEP               = protocols.PhaseDEEP               // computing the DEEP codeword
+	PhaseFRI                = protocols.PhaseFRI                // folding and committing FRI codewords
+)
+
 // Config represents configuration for the STARK prover/verifier
 type Config struct {
 	// Field modulus for finite field arithmetic
@@ -48,6 +118,10 @@ type Config struct {
 
 	// Blowup factor for low-degree extension
 	BlowupFactor int
+
+	// Workers, memory ceiling and progress callback of the prover; the
+	// verifier ignores them
+	ProverOptions ProverOptions
 }
 
 // VMConfig represents configuration for the Vybium STARKs VM
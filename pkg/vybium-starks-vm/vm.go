@@ -1,6 +1,7 @@
 package vybiumstarksvm
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/vybium/vybium-crypto/pkg/vybium-crypto/field"
@@ -13,6 +14,14 @@ type VM interface {
 	// Execute runs a program on the VM and returns the execution trace
 	Execute(program *Program, publicInput []*FieldElement, secretInput []*FieldElement) (*ExecutionTrace, error)
 
+	// ExecuteWithNonDeterminism runs a program with the full secret input:
+	// individual tokens, digests and initial RAM
+	ExecuteWithNonDeterminism(program *Program, publicInput []*FieldElement, nonDeterminism *NonDeterminism) (*ExecutionTrace, error)
+
+	// ExecuteWithOptions runs a program with the full secret input within
+	// the given limits. The other Execute methods use DefaultExecutionOptions.
+	ExecuteWithOptions(program *Program, publicInput []*FieldElement, nonDeterminism *NonDeterminism, options ExecutionOptions) (*ExecutionTrace, error)
+
 	// GetState returns the current VM state
 	GetState() *VMState
 }
@@ -45,6 +54,11 @@ type vmImpl struct {
 
 // NewVM creates a new Vybium STARKs VM with the given configuration
 func NewVM(config *VMConfig) (VM, error) {
+	return newVMImpl(config)
+}
+
+// newVMImpl creates the VM behind NewVM and NewDebugger
+func newVMImpl(config *VMConfig) (*vmImpl, error) {
 	// Parse field modulus
 	modulus := new(big.Int)
 	if _, ok := modulus.SetString(config.FieldModulus, 10); !ok {
@@ -92,9 +106,8 @@ func (v *vmImpl) convertFromInternal(elems []field.Element) []*FieldElement {
 	return result
 }
 
-// Execute runs a program on the VM and returns the execution trace
-func (v *vmImpl) Execute(program *Program, publicInput []*FieldElement, secretInput []*FieldElement) (*ExecutionTrace, error) {
-	// Convert public Program to internal vm.Program (no longer needs field)
+// convertProgramToInternal converts a public Program to the internal vm.Program
+func convertProgramToInternal(program *Program) *vm.Program {
 	internalProgram := vm.NewProgram()
 
 	for _, inst := range program.Instructions {
@@ -105,26 +118,132 @@ func (v *vmImpl) Execute(program *Program, publicInput []*FieldElement, secretIn
 			arg = &elem
 		}
 		internalInst := &vm.EncodedInstruction{
-			Instruction: vm.Instruction(inst.Opcode),
+			Instruction: inst.Opcode,
 			Argument:    arg,
 		}
 		internalProgram.AddInstruction(internalInst)
 	}
 
+	return internalProgram
+}
+
+// convertNonDeterminismToInternal converts the public secret input to the
+// internal format, checking that every digest has 5 elements
+func convertNonDeterminismToInternal(nonDeterminism *NonDeterminism) (*vm.NonDeterminism, error) {
+	result := &vm.NonDeterminism{}
+	if nonDeterminism == nil {
+		return result, nil
+	}
+
+	result.IndividualTokens = convertToInternal(nonDeterminism.IndividualTokens)
+
+	result.Digests = make([][]field.Element, len(nonDeterminism.Digests))
+	for i, digest := range nonDeterminism.Digests {
+		if len(digest) != 5 {
+			return nil, &VMError{
+				Code:    ErrInvalidInput,
+				Message: fmt.Sprintf("secret digest %d has %d elements, expected 5", i, len(digest)),
+			}
+		}
+		result.Digests[i] = convertToInternal(digest)
+	}
+
+	result.RAM = make(map[field.Element]field.Element, len(nonDeterminism.RAM))
+	for address, value := range nonDeterminism.RAM {
+		if address >= field.P {
+			return nil, &VMError{
+				Code:    ErrInvalidInput,
+				Message: fmt.Sprintf("RAM address %d is not a field element", address),
+			}
+		}
+		if value != nil {
+			result.RAM[field.New(address)] = field.New(value.Big().Uint64())
+		}
+	}
+
+	return result, nil
+}
+
+// ProgramDigest computes the program digest a claim about the program
+// attests to (TIP-0006), without executing it
+func ProgramDigest(program *Program) ([]field.Element, error) {
+	if program == nil {
+		return nil, &VMError{
+			Code:    ErrInvalidInput,
+			Message: "program is nil",
+		}
+	}
+
+	digest, err := vm.NewProgramHashTable().ComputeProgramDigest(convertProgramToInternal(program))
+	if err != nil {
+		return nil, &VMError{
+			Code:    ErrInvalidInput,
+			Message: "failed to compute program digest: " + err.Error(),
+			Cause:   err,
+		}
+	}
+	return digest[:], nil
+}
+
+// AnalyzeProgram checks a program without executing it: its arguments,
+// call targets, control flow and stack depth. Diagnostics are ordered by
+// address; a program with one of SeverityError is not worth proving.
+func AnalyzeProgram(program *Program) ([]Diagnostic, error) {
+	if program == nil {
+		return nil, &VMError{
+			Code:    ErrInvalidInput,
+			Message: "program is nil",
+		}
+	}
+	return vm.AnalyzeProgram(convertProgramToInternal(program)).Diagnostics, nil
+}
+
+// Execute runs a program on the VM and returns the execution trace
+func (v *vmImpl) Execute(program *Program, publicInput []*FieldElement, secretInput []*FieldElement) (*ExecutionTrace, error) {
+	return v.ExecuteWithNonDeterminism(program, publicInput, &NonDeterminism{IndividualTokens: secretInput})
+}
+
+// ExecuteWithNonDeterminism runs a program with the full secret input and
+// returns the execution trace
+func (v *vmImpl) ExecuteWithNonDeterminism(program *Program, publicInput []*FieldElement, nonDeterminism *NonDeterminism) (*ExecutionTrace, error) {
+	return v.ExecuteWithOptions(program, publicInput, nonDeterminism, DefaultExecutionOptions())
+}
+
+// load converts a program and its inputs and creates the VM state that
+// executes them
+func (v *vmImpl) load(program *Program, publicInput []*FieldElement, nonDeterminism *NonDeterminism, options ExecutionOptions) error {
+	internalProgram := convertProgramToInternal(program)
+
 	// Convert inputs to internal format
 	internalPublicInput := convertToInternal(publicInput)
-	internalSecretInput := convertToInternal(secretInput)
+	internalNonDeterminism, err := convertNonDeterminismToInternal(nonDeterminism)
+	if err != nil {
+		return err
+	}
 
-	// Create VM state (signature: program, publicInput, secretInput)
-	v.vmState = vm.NewVMState(internalProgram, internalPublicInput, internalSecretInput)
+	v.vmState = vm.NewVMStateWithNonDeterminism(internalProgram, internalPublicInput, internalNonDeterminism)
+	v.vmState.Options = options
 	v.program = internalProgram
+	return nil
+}
+
+// ExecuteWithOptions runs a program with the full secret input within the
+// given limits and returns the execution trace
+//
+// A failed execution is a VMError with code ErrVMExecution, caused by one of
+// the typed execution failures such as *AssertionFailure.
+func (v *vmImpl) ExecuteWithOptions(program *Program, publicInput []*FieldElement, nonDeterminism *NonDeterminism, options ExecutionOptions) (*ExecutionTrace, error) {
+	if err := v.load(program, publicInput, nonDeterminism, options); err != nil {
+		return nil, err
+	}
 
 	// Execute the program and generate trace
 	aet, err := v.vmState.ExecuteAndTrace()
 	if err != nil {
 		return nil, &VMError{
 			Code:    ErrVMExecution,
-			Message: "VM execution failed: " + err.Error(),
+			Message: "VM execution failed",
+			Cause:   err,
 		}
 	}
 
@@ -1,7 +1,11 @@
 package vybiumstarksvm
 
 import (
+	"errors"
+	"math/big"
 	"testing"
+
+	"github.com/vybium/vybium-starks-vm/internal/vybium-starks-vm/core"
 )
 
 func TestVMCreation(t *testing.T) {
@@ -44,3 +48,146 @@ func TestVMInputOutput(t *testing.T) {
 		// This would test the public API for public outputs
 	})
 }
+
+func TestProgramDigest(t *testing.T) {
+	program := &Program{Instructions: []Instruction{{Opcode: Halt}}}
+
+	digest, err := ProgramDigest(program)
+	if err != nil {
+		t.Fatalf("ProgramDigest failed: %v", err)
+	}
+	if len(digest) != 5 {
+		t.Fatalf("digest has %d elements, want 5", len(digest))
+	}
+
+	// The public output of an execution starts with the attested digest
+	vm, err := NewVM(DefaultVMConfig())
+	if err != nil {
+		t.Fatalf("NewVM failed: %v", err)
+	}
+	trace, err := vm.Execute(program, nil, nil)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(trace.PublicOutput) < len(digest) {
+		t.Fatalf("public output has %d elements, want at least %d", len(trace.PublicOutput), len(digest))
+	}
+	for i, elem := range digest {
+		if got := trace.PublicOutput[i].Big().Uint64(); got != elem.Value() {
+			t.Errorf("public output[%d] = %d, want digest element %d", i, got, elem.Value())
+		}
+	}
+
+	if _, err := ProgramDigest(nil); err == nil {
+		t.Error("ProgramDigest accepted a nil program")
+	}
+}
+
+func TestExecuteWithNonDeterminism(t *testing.T) {
+	vm, err := NewVM(DefaultVMConfig())
+	if err != nil {
+		t.Fatalf("NewVM failed: %v", err)
+	}
+	f, err := core.NewField(big.NewInt(0).SetUint64(18446744069414584321))
+	if err != nil {
+		t.Fatalf("NewField failed: %v", err)
+	}
+	elem := func(v uint64) *FieldElement { return f.NewElement(new(big.Int).SetUint64(v)) }
+
+	// push 7, read_mem 1, write_io 1, divine 1, write_io 1, halt
+	program := &Program{Instructions: []Instruction{
+		{Opcode: Push, Argument: elem(7)},
+		{Opcode: ReadMem, Argument: elem(1)},
+		{Opcode: WriteIo, Argument: elem(1)},
+		{Opcode: Divine, Argument: elem(1)},
+		{Opcode: WriteIo, Argument: elem(1)},
+		{Opcode: Halt},
+	}}
+	trace, err := vm.ExecuteWithNonDeterminism(program, nil, &NonDeterminism{
+		IndividualTokens: []*FieldElement{elem(5)},
+		RAM:              map[uint64]*FieldElement{7: elem(99)},
+	})
+	if err != nil {
+		t.Fatalf("ExecuteWithNonDeterminism failed: %v", err)
+	}
+	output := trace.PublicOutput[5:] // after the program digest
+	if len(output) != 2 || output[0].Big().Uint64() != 99 || output[1].Big().Uint64() != 5 {
+		t.Errorf("public output = %v, want [99 5]", output)
+	}
+
+	_, err = vm.ExecuteWithNonDeterminism(program, nil, &NonDeterminism{
+		Digests: [][]*FieldElement{{elem(1), elem(2)}},
+	})
+	if err == nil {
+		t.Error("accepted a digest with 2 elements")
+	}
+}
+
+func TestExecuteWithOptions(t *testing.T) {
+	vm, err := NewVM(DefaultVMConfig())
+	if err != nil {
+		t.Fatalf("NewVM failed: %v", err)
+	}
+	f, err := core.NewField(big.NewInt(0).SetUint64(18446744069414584321))
+	if err != nil {
+		t.Fatalf("NewField failed: %v", err)
+	}
+	elem := func(v uint64) *FieldElement { return f.NewElement(new(big.Int).SetUint64(v)) }
+
+	// divine 1, assert, halt
+	program := &Program{Instructions: []Instruction{
+		{Opcode: Divine, Argument: elem(1)},
+		{Opcode: Assert},
+		{Opcode: Halt},
+	}}
+
+	_, err = vm.ExecuteWithOptions(program, nil, nil, DefaultExecutionOptions())
+	var exhausted *InputExhausted
+	if !errors.As(err, &exhausted) || exhausted.Source != SecretInputSource || exhausted.IP != 0 {
+		t.Errorf("expected exhausted secret input at IP 0, got %v", err)
+	}
+	if !errors.Is(err, &VMError{Code: ErrVMExecution}) {
+		t.Errorf("expected an ErrVMExecution, got %v", err)
+	}
+
+	_, err = vm.ExecuteWithNonDeterminism(program, nil, &NonDeterminism{IndividualTokens: []*FieldElement{elem(2)}})
+	var failure *AssertionFailure
+	if !errors.As(err, &failure) || failure.IP != 2 || failure.Cycle != 1 {
+		t.Errorf("expected a failed assertion at IP 2, cycle 1, got %v", err)
+	}
+
+	_, err = vm.ExecuteWithOptions(program, nil, &NonDeterminism{IndividualTokens: []*FieldElement{elem(1)}},
+		ExecutionOptions{MaxCycles: 1})
+	var limit *CycleLimitExceeded
+	if !errors.As(err, &limit) || limit.Limit != 1 {
+		t.Errorf("expected the cycle limit to be exceeded, got %v", err)
+	}
+}
+
+func TestAnalyzeProgram(t *testing.T) {
+	f, err := core.NewField(big.NewInt(0).SetUint64(18446744069414584321))
+	if err != nil {
+		t.Fatalf("NewField failed: %v", err)
+	}
+	elem := func(v uint64) *FieldElement { return f.NewElement(new(big.Int).SetUint64(v)) }
+
+	// divine 0, halt, pop 1
+	program := &Program{Instructions: []Instruction{
+		{Opcode: Divine, Argument: elem(0)},
+		{Opcode: Halt},
+		{Opcode: Pop, Argument: elem(1)},
+	}}
+	diagnostics, err := AnalyzeProgram(program)
+	if err != nil {
+		t.Fatalf("AnalyzeProgram failed: %v", err)
+	}
+	if len(diagnostics) != 2 ||
+		diagnostics[0].Address != 0 || diagnostics[0].Severity != SeverityError ||
+		diagnostics[1].Address != 3 || diagnostics[1].Severity != SeverityWarning {
+		t.Errorf("expected an error at 0 and unreachable code at 3, got %v", diagnostics)
+	}
+
+	if _, err := AnalyzeProgram(nil); err == nil {
+		t.Error("analyzed a nil program")
+	}
+}
@@ -93,7 +93,11 @@ func Test01_BasicVMToProof(t *testing.T) {
 		t.Fatalf("Invalid STARK parameters: %v", err)
 	}
 
-	prover, err := protocols.NewProver(params)
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("Failed to create AIR: %v", err)
+	}
+	prover, err := protocols.NewProver(params, air)
 	if err != nil {
 		t.Fatalf("Failed to create prover: %v", err)
 	}
@@ -136,7 +140,7 @@ func Test01_BasicVMToProof(t *testing.T) {
 		t.Fatalf("Failed to create field: %v", err)
 	}
 
-	verifier, err := protocols.NewVerifier(coreField, params)
+	verifier, err := protocols.NewVerifier(coreField, params, air)
 	if err != nil {
 		t.Fatalf("Failed to create verifier: %v", err)
 	}
@@ -151,3 +155,89 @@ func Test01_BasicVMToProof(t *testing.T) {
 	t.Log("🎉 SUCCESS: Complete flow works!")
 	t.Log("   VM -> AET -> Proof -> Verification")
 }
+
+// Test01_ForgedClaimsAreRejected proves an honest trace under claims that
+// do not match it: the program digest, the public input and the public
+// output are each bound to the trace, so no such proof verifies
+func Test01_ForgedClaimsAreRejected(t *testing.T) {
+	program := vm.NewProgram()
+	for _, value := range []uint64{17, 25} {
+		arg := field.New(value)
+		program.AddInstruction(&vm.EncodedInstruction{Instruction: vm.Push, Argument: &arg})
+	}
+	program.AddInstruction(&vm.EncodedInstruction{Instruction: vm.Add})
+	one := field.New(1)
+	program.AddInstruction(&vm.EncodedInstruction{Instruction: vm.WriteIo, Argument: &one})
+	program.AddInstruction(&vm.EncodedInstruction{Instruction: vm.Halt})
+
+	vmState := vm.NewVMState(program, nil, nil)
+	aet, err := vmState.ExecuteAndTrace()
+	if err != nil {
+		t.Fatalf("Failed to execute and trace: %v", err)
+	}
+
+	params := protocols.DefaultSTARKParameters()
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("Failed to create AIR: %v", err)
+	}
+	prover, err := protocols.NewProver(params, air)
+	if err != nil {
+		t.Fatalf("Failed to create prover: %v", err)
+	}
+	goldilocksPrime, _ := new(big.Int).SetString("18446744069414584321", 10)
+	coreField, err := core.NewField(goldilocksPrime)
+	if err != nil {
+		t.Fatalf("Failed to create field: %v", err)
+	}
+	verifier, err := protocols.NewVerifier(coreField, params, air)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	digest := aet.ProgramDigest[:]
+	forgedDigest := []field.Element{field.New(9), field.New(9), field.New(9), field.New(9), field.New(9)}
+	output := func(prefix []field.Element, values ...uint64) []field.Element {
+		elements := append([]field.Element{}, prefix...)
+		for _, value := range values {
+			elements = append(elements, field.New(value))
+		}
+		return elements
+	}
+
+	honest := protocols.NewClaim(digest).WithInput(nil).WithOutput(vmState.PublicOutput)
+	proof, err := prover.Prove(honest, aet)
+	if err != nil {
+		t.Fatalf("Failed to generate proof: %v", err)
+	}
+	if err := verifier.Verify(honest, proof); err != nil {
+		t.Fatalf("Honest proof verification failed: %v", err)
+	}
+
+	forgeries := []struct {
+		name  string
+		claim *protocols.Claim
+	}{
+		{"output 43", protocols.NewClaim(digest).WithInput(nil).WithOutput(output(digest, 43))},
+		{"output without digest", protocols.NewClaim(digest).WithInput(nil).WithOutput(output(nil, 42))},
+		{"program digest", protocols.NewClaim(forgedDigest).WithInput(nil).WithOutput(output(forgedDigest, 42))},
+		{"public input", protocols.NewClaim(digest).WithInput([]field.Element{one}).WithOutput(vmState.PublicOutput)},
+		{"all of them", protocols.NewClaim(forgedDigest).WithInput([]field.Element{one}).WithOutput(output(forgedDigest, 43))},
+	}
+	for _, forgery := range forgeries {
+		t.Run(forgery.name, func(t *testing.T) {
+			if err := verifier.Verify(forgery.claim, proof); err == nil {
+				t.Error("Verify accepted the honest proof for a forged claim")
+			}
+			// The prover does not check the claim, the verifier must
+			forged, err := prover.Prove(forgery.claim, aet)
+			if err != nil {
+				t.Logf("Prover refused the claim: %v", err)
+				return
+			}
+			if err := verifier.Verify(forgery.claim, forged); err == nil {
+				t.Error("Verify accepted a proof of the honest trace under a forged claim")
+			}
+		})
+	}
+}
@@ -110,9 +110,8 @@ func Test02_PrivacyProofWithDivine(t *testing.T) {
 	t.Logf("  AET generated: height=%d", aet.Height)
 	t.Logf("  Stack pointer: %d", vmState.StackPointer)
 	t.Logf("  Stack contents (top 8):")
-	for i := 0; i < 8 && vmState.StackPointer-i-1 >= 0; i++ {
-		idx := vmState.StackPointer - i - 1
-		t.Logf("    Stack[%d] (sp-%d) = %d", idx, i, vmState.Stack[idx].Value())
+	for i := 0; i < 8 && i < vmState.StackPointer; i++ {
+		t.Logf("    st%d = %d", i, vmState.Stack[i].Value())
 	}
 	t.Logf("  PublicOutput length: %d", len(vmState.PublicOutput))
 	for i, val := range vmState.PublicOutput {
@@ -134,7 +133,11 @@ func Test02_PrivacyProofWithDivine(t *testing.T) {
 	t.Log("Step 3: Generating STARK proof (without revealing secret)...")
 	params := protocols.DefaultSTARKParameters()
 
-	prover, err := protocols.NewProver(params)
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("Failed to create AIR: %v", err)
+	}
+	prover, err := protocols.NewProver(params, air)
 	if err != nil {
 		t.Fatalf("Failed to create prover: %v", err)
 	}
@@ -159,7 +162,7 @@ func Test02_PrivacyProofWithDivine(t *testing.T) {
 		t.Fatalf("Failed to create field: %v", err)
 	}
 
-	verifier, err := protocols.NewVerifier(coreField, params)
+	verifier, err := protocols.NewVerifier(coreField, params, air)
 	if err != nil {
 		t.Fatalf("Failed to create verifier: %v", err)
 	}
@@ -93,7 +93,11 @@ func Test03_FactorialProof(t *testing.T) {
 	t.Log("Step 3: Generating STARK proof of factorial computation...")
 	params := protocols.DefaultSTARKParameters()
 
-	prover, err := protocols.NewProver(params)
+	air, err := vm.CreateMasterAIR()
+	if err != nil {
+		t.Fatalf("Failed to create AIR: %v", err)
+	}
+	prover, err := protocols.NewProver(params, air)
 	if err != nil {
 		t.Fatalf("Failed to create prover: %v", err)
 	}
@@ -113,7 +117,7 @@ func Test03_FactorialProof(t *testing.T) {
 	goldilocksP := new(big.Int)
 	goldilocksP.SetString("18446744069414584321", 10)
 	tempField, _ := core.NewField(goldilocksP)
-	verifier, _ := protocols.NewVerifier(tempField, params)
+	verifier, _ := protocols.NewVerifier(tempField, params, air)
 
 	err = verifier.Verify(claim, proof)
 	if err != nil {